@@ -0,0 +1,59 @@
+// zinx_bench load tests a zinx server: it opens -c connections over -rampup, sends them the -mix of messages for
+// -d at -rate messages per second per connection, then prints the throughput, the errors and the latency percentiles
+//
+// usage:
+//
+//	go run ./examples/zinx_bench -addr 127.0.0.1:8999 -c 100 -d 30s -rampup 5s -mix 1:3:16-64:1,2:1:512
+//
+// the -mix is comma separated msgID[:weight[:size[:replyMsgID]]], see zbench.ParseMix
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aceld/zinx/zbench"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8999", "the TCP server")
+	datapack := flag.String("datapack", ziface.ZinxDataPack, "the datapack of the server")
+	conns := flag.Int("c", 10, "the concurrent connections")
+	duration := flag.Duration("d", 10*time.Second, "the duration after the ramp-up")
+	rampUp := flag.Duration("rampup", 0, "the time over which the connections start")
+	rate := flag.Float64("rate", 0, "the messages per second of each connection, 0 sends on every reply")
+	timeout := flag.Duration("timeout", 5*time.Second, "the time to wait for a reply")
+	mix := flag.String("mix", "1:1:64:1", "the messages sent, msgID[:weight[:size[:replyMsgID]]] comma separated")
+	flag.Parse()
+
+	messages, err := zbench.ParseMix(*mix)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Ctrl-C ends the run early and still prints the report (Ctrl-C提前结束压测并仍然打印报告)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := zbench.Run(ctx, zbench.Config{
+		Addr:     *addr,
+		Packet:   zpack.Factory().NewPack(*datapack),
+		Conns:    *conns,
+		Duration: *duration,
+		RampUp:   *rampUp,
+		Rate:     *rate,
+		Timeout:  *timeout,
+		Mix:      messages,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Print(report)
+}
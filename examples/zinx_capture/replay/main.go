@@ -0,0 +1,66 @@
+// replay prints a capture file recorded by a zinx server, or replays its inbound messages to a server
+//
+// usage:
+//
+//	go run ./examples/zinx_capture/replay -file capture.zcap
+//	go run ./examples/zinx_capture/replay -file capture.zcap -addr 127.0.0.1:8999 -speed 1 -msgids 1,100
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aceld/zinx/zcapture"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+func main() {
+	file := flag.String("file", "capture.zcap", "the capture file")
+	addr := flag.String("addr", "", "the TCP server the inbound messages are replayed to, empty prints the capture")
+	datapack := flag.String("datapack", ziface.ZinxDataPack, "the datapack of the server")
+	speed := flag.Float64("speed", 0, "1 replays in real time, 0 as fast as possible")
+	msgIDs := flag.String("msgids", "", "comma separated msgIDs to replay, empty for all")
+	flag.Parse()
+
+	opts := zcapture.ReplayOptions{Speed: *speed}
+	for _, s := range strings.Split(*msgIDs, ",") {
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid msgID %q\n", s)
+			os.Exit(2)
+		}
+		opts.Filter.MsgIDs = append(opts.Filter.MsgIDs, uint32(id))
+	}
+
+	var err error
+	if *addr != "" {
+		err = zcapture.ReplayFile(*file, *addr, zpack.Factory().NewPack(*datapack), opts)
+	} else {
+		err = dump(*file, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func dump(file string, opts zcapture.ReplayOptions) error {
+	r, err := zcapture.Open(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return zcapture.Replay(r, opts, func(record *zcapture.Record) error {
+		fmt.Printf("%s %-8s connID=%d msgID=%d len=%d %s\n", record.Time.Format("15:04:05.000000"),
+			record.Direction, record.ConnID, record.MsgID, len(record.Data), hex.EncodeToString(record.Data))
+		return nil
+	})
+}
@@ -0,0 +1,22 @@
+// A server recording its messages into capture.zcap, replay them with examples/zinx_capture/replay
+// (将消息记录到capture.zcap的服务，使用examples/zinx_capture/replay回放)
+package main
+
+import (
+	"github.com/aceld/zinx/examples/zinx_server/s_router"
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/znet"
+)
+
+func main() {
+	s := znet.NewUserConfServer(&zconf.Config{
+		Name:        "capture",
+		TCPPort:     8999,
+		CaptureFile: "capture.zcap",
+	})
+
+	s.AddRouter(100, &s_router.PingRouter{})
+	s.AddRouter(1, &s_router.HelloZinxRouter{})
+
+	s.Serve()
+}
@@ -0,0 +1,109 @@
+// Code generated by zinx-gen from game.zinx. DO NOT EDIT.
+
+package game
+
+import (
+	"context"
+
+	"github.com/aceld/zinx/zcodec"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+)
+
+// The MsgIDs of the protocol (协议的MsgID)
+const (
+	MsgIDLogin      uint32 = 1
+	MsgIDLoginReply uint32 = 2
+	MsgIDMove       uint32 = 3
+	MsgIDMoved      uint32 = 4
+)
+
+// Codec encodes the payloads of the protocol (协议消息体的编解码器)
+var Codec = zcodec.JSON
+
+// Login is the payload of MsgIDLogin
+type Login struct {
+	Token  string `json:"token"`
+	Device string `json:"device"`
+}
+
+// LoginReply is the payload of MsgIDLoginReply
+type LoginReply struct {
+	UserID uint64   `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+// Move is the payload of MsgIDMove
+type Move struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
+// Moved is the payload of MsgIDMoved
+type Moved struct {
+	UserID   uint64 `json:"user_id"`
+	Position Move   `json:"position"`
+}
+
+// Handler handles the routed messages on the server (在服务端处理被路由的消息)
+type Handler interface {
+	// Login handles MsgIDLogin, the returned LoginReply is replied as MsgIDLoginReply
+	Login(ctx context.Context, conn ziface.IConnection, req *Login) (*LoginReply, error)
+	// Move handles MsgIDMove
+	Move(ctx context.Context, conn ziface.IConnection, req *Move) error
+}
+
+// RegisterHandler registers the routers of h on s (在s上注册h的路由)
+func RegisterHandler(s ziface.IServer, h Handler) {
+	znet.Handle(s, MsgIDLogin, h.Login, znet.WithCodec(Codec), znet.WithReplyMsgID(MsgIDLoginReply))
+	znet.Handle(s, MsgIDMove, func(ctx context.Context, conn ziface.IConnection, req *Move) (struct{}, error) {
+		return struct{}{}, h.Move(ctx, conn, req)
+	}, znet.WithCodec(Codec), znet.WithNoReply())
+}
+
+// ClientHandler handles the messages sent by the server, the replies and the pushes, on the client
+// (在客户端处理服务发送的消息，包括回复与推送)
+type ClientHandler interface {
+	// LoginReply handles MsgIDLoginReply
+	LoginReply(ctx context.Context, conn ziface.IConnection, msg *LoginReply) error
+	// Moved handles MsgIDMoved
+	Moved(ctx context.Context, conn ziface.IConnection, msg *Moved) error
+}
+
+// RegisterClientHandler registers the routers of h on c (在c上注册h的路由)
+func RegisterClientHandler(c ziface.IClient, h ClientHandler) {
+	c.AddRouter(MsgIDLoginReply, znet.TypedRouter(func(ctx context.Context, conn ziface.IConnection, msg *LoginReply) (struct{}, error) {
+		return struct{}{}, h.LoginReply(ctx, conn, msg)
+	}, znet.WithCodec(Codec), znet.WithNoReply()))
+	c.AddRouter(MsgIDMoved, znet.TypedRouter(func(ctx context.Context, conn ziface.IConnection, msg *Moved) (struct{}, error) {
+		return struct{}{}, h.Moved(ctx, conn, msg)
+	}, znet.WithCodec(Codec), znet.WithNoReply()))
+}
+
+// SendLogin sends msg as MsgIDLogin on conn (在conn上以MsgIDLogin发送msg)
+func SendLogin(conn ziface.IConnection, msg *Login) error {
+	return send(conn, MsgIDLogin, msg)
+}
+
+// SendLoginReply sends msg as MsgIDLoginReply on conn (在conn上以MsgIDLoginReply发送msg)
+func SendLoginReply(conn ziface.IConnection, msg *LoginReply) error {
+	return send(conn, MsgIDLoginReply, msg)
+}
+
+// SendMove sends msg as MsgIDMove on conn (在conn上以MsgIDMove发送msg)
+func SendMove(conn ziface.IConnection, msg *Move) error {
+	return send(conn, MsgIDMove, msg)
+}
+
+// SendMoved sends msg as MsgIDMoved on conn (在conn上以MsgIDMoved发送msg)
+func SendMoved(conn ziface.IConnection, msg *Moved) error {
+	return send(conn, MsgIDMoved, msg)
+}
+
+func send(conn ziface.IConnection, msgID uint32, msg interface{}) error {
+	data, err := Codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.SendMsg(msgID, data)
+}
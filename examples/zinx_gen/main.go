@@ -0,0 +1,53 @@
+// zinx_gen generates the MsgID constants, the payload types, the typed handler interfaces with their router
+// registration and the client call wrappers of a protocol definition file, see the zgen package for its syntax
+//
+// usage:
+//
+//	go run ./examples/zinx_gen -in examples/zinx_gen/game/game.zinx
+//	go run ./examples/zinx_gen -in game.zinx -out protocol.go
+//
+// the output defaults to the input file with .go appended
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aceld/zinx/zgen"
+)
+
+func main() {
+	in := flag.String("in", "", "the protocol definition file")
+	out := flag.String("out", "", "the generated Go file, the input file with .go appended if empty")
+	flag.Parse()
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		*out = *in + ".go"
+	}
+
+	if err := generate(*in, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(in, out string) error {
+	src, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	f, err := zgen.Parse(src)
+	if err != nil {
+		return err
+	}
+	code, err := zgen.Generate(f, filepath.Base(in))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, code, 0644)
+}
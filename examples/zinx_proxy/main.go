@@ -0,0 +1,34 @@
+// zinx_proxy sits between a client and a zinx server, relaying the bytes unchanged while logging the decoded frames of
+// both directions and recording them into proxy.zcap, replay them with examples/zinx_capture/replay
+// (位于客户端与zinx服务之间，原样转发字节，同时输出双向解码后的帧并记录到proxy.zcap，使用examples/zinx_capture/replay回放)
+//
+// usage:
+//
+//	go run ./examples/zinx_proxy -listen 9999 -upstream 127.0.0.1:8999
+package main
+
+import (
+	"flag"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+)
+
+func main() {
+	port := flag.Int("listen", 9999, "the TCP port the clients connect to")
+	upstream := flag.String("upstream", "127.0.0.1:8999", "the server the connections are relayed to")
+	datapack := flag.String("datapack", ziface.ZinxDataPack, "the datapack of the frames")
+	capture := flag.String("capture", "proxy.zcap", "the capture file, empty to only log the frames")
+	flag.Parse()
+
+	s := znet.NewUserConfServer(&zconf.Config{
+		Name:          "proxy",
+		TCPPort:       *port,
+		Mode:          zconf.ServerModeProxy,
+		ProxyUpstream: *upstream,
+		DataPack:      *datapack,
+		CaptureFile:   *capture,
+	})
+	s.Serve()
+}
@@ -0,0 +1,23 @@
+// A process serving TCP on 8999 and websocket on 9000, each server with its own config, sharing one worker pool
+// (一个进程在8999提供TCP服务、在9000提供websocket服务，各服务使用自己的配置并共享一个worker池)
+package main
+
+import (
+	"github.com/aceld/zinx/examples/zinx_server/s_router"
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+)
+
+func main() {
+	tcp := znet.NewServerWithOptions(znet.WithName("tcp"), znet.WithMode(zconf.ServerModeTcp), znet.WithPort(8999),
+		znet.WithWorkerPool(16, 1024))
+	ws := znet.NewServerWithOptions(znet.WithName("websocket"), znet.WithMode(zconf.ServerModeWebsocket), znet.WithWsPort(9000))
+
+	for _, s := range []ziface.IServer{tcp, ws} {
+		s.AddRouter(100, &s_router.PingRouter{})
+		s.AddRouter(1, &s_router.HelloZinxRouter{})
+	}
+
+	znet.NewServerGroup([]ziface.IServer{tcp, ws}, znet.WithSharedWorkerPool()).Serve()
+}
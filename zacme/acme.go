@@ -0,0 +1,132 @@
+// Package zacme serves the certificates of a zinx server from an ACME CA such as Let's Encrypt, they are obtained on
+// the first handshake and renewed before they expire without a restart.
+//
+//	m := zacme.New(zacme.Config{Hosts: []string{"game.example.com"}, Email: "ops@example.com", CacheDir: "certs"})
+//	_ = m.Start() // Answers the http-01 challenges on HTTPAddr
+//	s := znet.NewServer(znet.WithTLSConfig(m.TLSConfig()))
+//
+// The tls-alpn-01 challenges are answered by the server itself, its port must then be 443.
+// (tls-alpn-01验证由服务本身应答，此时其端口必须为443)
+//
+// (zacme从Let's Encrypt等ACME CA获取zinx服务的证书，证书在首次握手时获取，并在过期前无需重启自动续期)
+package zacme
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Config configures a Manager (Manager的配置)
+type Config struct {
+	// The host names certificates are obtained for, required (获取证书的主机名，必填)
+	Hosts []string
+	// The contact of the account, notified about problems with the certificates (账户的联系邮箱，证书出现问题时收到通知)
+	Email string
+	// The directory the account key and certificates are kept in, required so that they survive restarts
+	// (保存账户密钥与证书的目录，必填，以便重启后保留)
+	CacheDir string
+	// The directory of the CA, defaults to Let's Encrypt, e.g. its staging directory while testing
+	// (CA的目录地址，默认为Let's Encrypt，例如测试时使用其staging目录)
+	DirectoryURL string
+	// The address the http-01 challenges are answered on by Start, defaults to ":80", "-" disables them
+	// (Start应答http-01验证的地址，默认为":80"，"-"表示禁用)
+	HTTPAddr string
+	// How long before they expire the certificates are renewed, defaults to 30 days (证书在过期前多久续期，默认为30天)
+	RenewBefore time.Duration
+}
+
+// Manager obtains and renews the certificates of Config.Hosts (获取并续期Config.Hosts的证书)
+type Manager struct {
+	config  Config
+	manager *autocert.Manager
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+// New creates a Manager, it panics without Hosts or CacheDir (创建Manager，没有Hosts或CacheDir时panic)
+func New(config Config) *Manager {
+	if len(config.Hosts) == 0 {
+		panic("zacme: no Hosts")
+	}
+	if config.CacheDir == "" {
+		panic("zacme: no CacheDir")
+	}
+	if config.HTTPAddr == "" {
+		config.HTTPAddr = ":80"
+	}
+	m := &Manager{
+		config: config,
+		manager: &autocert.Manager{
+			Prompt:      autocert.AcceptTOS,
+			Cache:       autocert.DirCache(config.CacheDir),
+			HostPolicy:  autocert.HostWhitelist(config.Hosts...),
+			Email:       config.Email,
+			RenewBefore: config.RenewBefore,
+		},
+	}
+	if config.DirectoryURL != "" {
+		m.manager.Client = &acme.Client{DirectoryURL: config.DirectoryURL}
+	}
+	return m
+}
+
+// TLSConfig returns the config serving the certificates, for znet.WithTLSConfig (返回提供证书的配置，用于znet.WithTLSConfig)
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: m.manager.GetCertificate,
+		NextProtos:     []string{acme.ALPNProto},
+		MinVersion:     tls.VersionTLS12,
+	}
+}
+
+// HTTPHandler answers the http-01 challenges and passes the other requests to fallback, for a server already
+// listening on port 80, nil redirects them to https (应答http-01验证并将其他请求交给fallback，用于已监听80端口的服务，
+// 为nil时重定向到https)
+func (m *Manager) HTTPHandler(fallback http.Handler) http.Handler {
+	return m.manager.HTTPHandler(fallback)
+}
+
+// Start answers the http-01 challenges on Config.HTTPAddr (在Config.HTTPAddr上应答http-01验证)
+func (m *Manager) Start() error {
+	if m.config.HTTPAddr == "-" {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.server != nil {
+		return errors.New("zacme: already started")
+	}
+	listener, err := net.Listen("tcp", m.config.HTTPAddr)
+	if err != nil {
+		return err
+	}
+	m.server = &http.Server{Handler: m.HTTPHandler(nil), ReadHeaderTimeout: 10 * time.Second}
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zlog.Ins().ErrorF("zacme: http-01 server err: %v", err)
+		}
+	}(m.server)
+	zlog.Ins().InfoF("zacme: answering http-01 challenges on %s", listener.Addr())
+	return nil
+}
+
+// Close stops answering the http-01 challenges (停止应答http-01验证)
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.server == nil {
+		return nil
+	}
+	err := m.server.Close()
+	m.server = nil
+	return err
+}
@@ -0,0 +1,21 @@
+package zacme
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/acme"
+)
+
+func TestManager(t *testing.T) {
+	assert.Panics(t, func() { New(Config{CacheDir: t.TempDir()}) })
+	assert.Panics(t, func() { New(Config{Hosts: []string{"example.com"}}) })
+
+	m := New(Config{Hosts: []string{"example.com"}, CacheDir: t.TempDir(), HTTPAddr: "127.0.0.1:0"})
+	assert.Contains(t, m.TLSConfig().NextProtos, acme.ALPNProto)
+
+	assert.Nil(t, m.Start())
+	assert.NotNil(t, m.Start())
+	assert.Nil(t, m.Close())
+	assert.Nil(t, m.Close())
+}
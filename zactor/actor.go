@@ -0,0 +1,258 @@
+// Package zactor is an optional actor layer: entities such as players or devices are registered with a
+// single-threaded mailbox and the messages routed to an entity are processed one at a time, in the order
+// they were sent, whichever connections they came from.
+//
+//	actors := zactor.New(zactor.WithSpawn(func(entityID string) zactor.Actor {
+//		return newPlayer(entityID)
+//	}))
+//	s.AddRouter(100, actors.Router(func(request ziface.IRequest) string {
+//		return playerID(request.GetConnection())
+//	}))
+//	actors.Tell("player:42", levelUp{})
+//
+// (可选的Actor层：为玩家、设备等实体注册单线程邮箱，路由到同一实体的消息无论来自哪个连接，都按发送顺序逐条处理)
+package zactor
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+)
+
+var (
+	ErrEntityExists   = errors.New("zactor: entity already registered")
+	ErrEntityNotFound = errors.New("zactor: entity not registered")
+	ErrMailboxFull    = errors.New("zactor: mailbox full")
+)
+
+// Message is a message in the mailbox of an entity (实体邮箱中的消息)
+type Message struct {
+	// Conn, MsgID and Data are set for the messages routed from a connection, Data is a copy of the request data
+	// (由连接路由而来的消息设置Conn、MsgID和Data，Data是请求数据的副本)
+	Conn  ziface.IConnection
+	MsgID uint32
+	Data  []byte
+	// Value is set for the messages sent by Tell (由Tell发送的消息设置Value)
+	Value interface{}
+}
+
+// Actor processes the messages of an entity, Receive is never called concurrently for the same entity
+// (处理实体的消息，同一实体的Receive不会被并发调用)
+type Actor interface {
+	Receive(entityID string, msg *Message)
+}
+
+// ActorFunc adapts a function to an Actor (将函数适配为Actor)
+type ActorFunc func(entityID string, msg *Message)
+
+func (f ActorFunc) Receive(entityID string, msg *Message) {
+	f(entityID, msg)
+}
+
+type mailbox struct {
+	entityID string
+	actor    Actor
+	ch       chan *Message
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func (m *mailbox) send(msg *Message) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return fmt.Errorf("%w: %s", ErrEntityNotFound, m.entityID)
+	}
+	select {
+	case m.ch <- msg:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrMailboxFull, m.entityID)
+	}
+}
+
+func (m *mailbox) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		close(m.ch)
+	}
+}
+
+func (m *mailbox) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	for msg := range m.ch {
+		m.receive(msg)
+	}
+}
+
+// receive isolates the panics of the actor, the next messages are still processed
+// (隔离Actor中的panic，后续消息仍会被处理)
+func (m *mailbox) receive(msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			zlog.Ins().ErrorF("zactor: entity %s msgID = %d panic: %v\n%s", m.entityID, msg.MsgID, err, debug.Stack())
+		}
+	}()
+	m.actor.Receive(m.entityID, msg)
+}
+
+// System routes messages to the mailboxes of the registered entities (将消息路由到已注册实体的邮箱)
+type System struct {
+	opts *options
+	wg   sync.WaitGroup
+
+	mu        sync.RWMutex
+	mailboxes map[string]*mailbox
+}
+
+// New creates a System (创建System)
+func New(opts ...Option) *System {
+	return &System{
+		opts:      newOptions(opts),
+		mailboxes: make(map[string]*mailbox),
+	}
+}
+
+// Register starts the mailbox of the entity entityID processed by actor
+// (启动实体entityID的邮箱，由actor处理其消息)
+func (s *System) Register(entityID string, actor Actor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.mailboxes[entityID]; ok {
+		return fmt.Errorf("%w: %s", ErrEntityExists, entityID)
+	}
+	s.start(entityID, actor)
+	return nil
+}
+
+// start runs the mailbox of entityID, s.mu is held (运行entityID的邮箱，调用时持有s.mu)
+func (s *System) start(entityID string, actor Actor) *mailbox {
+	m := &mailbox{entityID: entityID, actor: actor, ch: make(chan *Message, s.opts.mailboxSize)}
+	s.mailboxes[entityID] = m
+	s.wg.Add(1)
+	go m.run(&s.wg)
+	return m
+}
+
+// Unregister removes the entity, the messages already in its mailbox are still processed
+// (移除实体，其邮箱中已有的消息仍会被处理)
+func (s *System) Unregister(entityID string) error {
+	s.mu.Lock()
+	m, ok := s.mailboxes[entityID]
+	delete(s.mailboxes, entityID)
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrEntityNotFound, entityID)
+	}
+	m.close()
+	return nil
+}
+
+// Entities returns the IDs of the registered entities, sorted (返回已注册实体的ID，已排序)
+func (s *System) Entities() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.mailboxes))
+	for id := range s.mailboxes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stop unregisters every entity and waits for their mailboxes to be processed
+// (移除所有实体，并等待其邮箱中的消息处理完毕)
+func (s *System) Stop() {
+	s.mu.Lock()
+	mailboxes := s.mailboxes
+	s.mailboxes = make(map[string]*mailbox)
+	s.mu.Unlock()
+	for _, m := range mailboxes {
+		m.close()
+	}
+	s.wg.Wait()
+}
+
+// Tell puts value into the mailbox of the entity entityID (将value放入实体entityID的邮箱)
+func (s *System) Tell(entityID string, value interface{}) error {
+	return s.send(entityID, &Message{Value: value})
+}
+
+func (s *System) send(entityID string, msg *Message) error {
+	s.mu.RLock()
+	m, ok := s.mailboxes[entityID]
+	s.mu.RUnlock()
+	if !ok {
+		if m = s.spawn(entityID); m == nil {
+			return fmt.Errorf("%w: %s", ErrEntityNotFound, entityID)
+		}
+	}
+	return m.send(msg)
+}
+
+func (s *System) spawn(entityID string) *mailbox {
+	if s.opts.spawn == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.mailboxes[entityID]; ok {
+		return m
+	}
+	actor := s.opts.spawn(entityID)
+	if actor == nil {
+		return nil
+	}
+	return s.start(entityID, actor)
+}
+
+// Handler returns a router handler putting the requests into the mailbox of the entity returned by entityOf,
+// see AddRouterSlices (返回将请求放入entityOf所返回实体邮箱的路由处理函数，见AddRouterSlices)
+func (s *System) Handler(entityOf EntityFunc) ziface.RouterHandler {
+	return func(request ziface.IRequest) {
+		s.route(entityOf, request)
+	}
+}
+
+// Router returns a router putting the requests into the mailbox of the entity returned by entityOf,
+// see AddRouter (返回将请求放入entityOf所返回实体邮箱的路由，见AddRouter)
+func (s *System) Router(entityOf EntityFunc) ziface.IRouter {
+	return &router{system: s, entityOf: entityOf}
+}
+
+type router struct {
+	znet.BaseRouter
+	system   *System
+	entityOf EntityFunc
+}
+
+func (r *router) Handle(request ziface.IRequest) {
+	r.system.route(r.entityOf, request)
+}
+
+// route copies the request into a message, the request is recycled once the handler returns
+// (将请求复制为消息，处理函数返回后请求会被回收)
+func (s *System) route(entityOf EntityFunc, request ziface.IRequest) {
+	entityID := entityOf(request)
+	if entityID == "" {
+		zlog.Ins().DebugF("zactor: msgID = %d has no entity, dropped", request.GetMsgID())
+		return
+	}
+	msg := &Message{
+		Conn:  request.GetConnection(),
+		MsgID: request.GetMsgID(),
+		Data:  append([]byte(nil), request.GetData()...),
+	}
+	if err := s.send(entityID, msg); err != nil {
+		zlog.Ins().ErrorF("zactor: route msgID = %d err: %v", msg.MsgID, err)
+	}
+}
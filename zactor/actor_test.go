@@ -0,0 +1,99 @@
+package zactor
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemOrder(t *testing.T) {
+	s := New()
+	var mu sync.Mutex
+	received := map[string][]int{}
+	actor := ActorFunc(func(entityID string, msg *Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[entityID] = append(received[entityID], msg.Value.(int))
+	})
+	assert.Nil(t, s.Register("a", actor))
+	assert.Nil(t, s.Register("b", actor))
+	assert.ErrorIs(t, s.Register("a", actor), ErrEntityExists)
+	assert.ErrorIs(t, s.Tell("c", 0), ErrEntityNotFound)
+
+	var wg sync.WaitGroup
+	for _, id := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				assert.Nil(t, s.Tell(id, i))
+			}
+		}(id)
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"a", "b"}, s.Entities())
+	s.Stop()
+
+	for _, id := range []string{"a", "b"} {
+		assert.Len(t, received[id], 500)
+		for i, v := range received[id] {
+			assert.Equal(t, i, v)
+		}
+	}
+	assert.Empty(t, s.Entities())
+}
+
+func TestSystemRoute(t *testing.T) {
+	var mu sync.Mutex
+	var received []*Message
+	s := New(WithMailboxSize(4), WithSpawn(func(entityID string) Actor {
+		if entityID == "nobody" {
+			return nil
+		}
+		return ActorFunc(func(entityID string, msg *Message) {
+			if string(msg.Data) == "xpanic" {
+				panic("boom")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, msg)
+		})
+	}))
+
+	entityOf := func(request ziface.IRequest) string {
+		return string(request.GetData()[:1])
+	}
+	handler := s.Handler(entityOf)
+	router := s.Router(entityOf)
+	for i, data := range []string{"xpanic", "x1", "x2"} {
+		if i%2 == 0 {
+			handler(znet.NewRequest(nil, zpack.NewMsgPackage(uint32(i), []byte(data))))
+		} else {
+			router.Handle(znet.NewRequest(nil, zpack.NewMsgPackage(uint32(i), []byte(data))))
+		}
+	}
+	assert.ErrorIs(t, s.Tell("nobody", 1), ErrEntityNotFound)
+
+	// A full mailbox refuses the messages (邮箱已满时拒绝消息)
+	block := make(chan struct{})
+	assert.Nil(t, s.Register("slow", ActorFunc(func(string, *Message) { <-block })))
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = s.Tell("slow", strconv.Itoa(i))
+	}
+	assert.ErrorIs(t, err, ErrMailboxFull)
+	close(block)
+
+	assert.Nil(t, s.Unregister("slow"))
+	assert.ErrorIs(t, s.Unregister("slow"), ErrEntityNotFound)
+	s.Stop()
+
+	assert.Len(t, received, 2)
+	assert.Equal(t, uint32(1), received[0].MsgID)
+	assert.Equal(t, []byte("x2"), received[1].Data)
+}
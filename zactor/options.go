@@ -0,0 +1,46 @@
+package zactor
+
+import "github.com/aceld/zinx/ziface"
+
+// EntityFunc returns the ID of the entity a request is for, an empty ID drops the request
+// (返回请求所属实体的ID，返回空ID时丢弃该请求)
+type EntityFunc func(request ziface.IRequest) string
+
+// SpawnFunc creates the actor of an entity not registered yet, return nil to drop its messages
+// (为尚未注册的实体创建Actor，返回nil时丢弃其消息)
+type SpawnFunc func(entityID string) Actor
+
+type options struct {
+	mailboxSize int
+	spawn       SpawnFunc
+}
+
+// Option configures a System (System的配置项)
+type Option func(o *options)
+
+// WithMailboxSize bounds the messages queued per entity, 1024 by default, a message sent to a full mailbox
+// is refused with ErrMailboxFull (每个实体排队消息的上限，默认1024，发往已满邮箱的消息以ErrMailboxFull拒绝)
+func WithMailboxSize(n int) Option {
+	return func(o *options) {
+		o.mailboxSize = n
+	}
+}
+
+// WithSpawn creates the actors of the entities on their first message instead of refusing it with
+// ErrEntityNotFound (在实体收到第一条消息时创建其Actor，而不是以ErrEntityNotFound拒绝)
+func WithSpawn(spawn SpawnFunc) Option {
+	return func(o *options) {
+		o.spawn = spawn
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{mailboxSize: 1024}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.mailboxSize <= 0 {
+		panic("zactor: mailbox size must be positive")
+	}
+	return o
+}
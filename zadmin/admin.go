@@ -0,0 +1,135 @@
+// Package zadmin serves the admin endpoints of a running zinx process: net/http/pprof, the runtime and GC stats,
+// the framework internals of the started servers, the metrics and the health probes, so that production profiling
+// doesn't require code changes, and an authenticated API to inspect and control the servers, it is enabled by
+// zconf.Config.AdminAddr
+// (zadmin提供运行中zinx进程的管理端点：net/http/pprof、运行时与GC统计、已启动服务的框架内部状态、指标以及健康探针，
+// 在生产环境中分析性能无需修改代码，以及查看和控制服务的认证接口，由zconf.Config.AdminAddr开启)
+package zadmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+// servers are the started servers, by registration order (已启动的服务，按注册顺序)
+var servers struct {
+	sync.RWMutex
+	list []ziface.IServer
+}
+
+// AddServer shows server in the admin endpoints until remove is called, servers add themselves when started
+// (在管理端点中展示server直到调用remove，服务启动时会自动添加)
+func AddServer(server ziface.IServer) (remove func()) {
+	servers.Lock()
+	servers.list = append(servers.list, server)
+	servers.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			servers.Lock()
+			defer servers.Unlock()
+			for i, s := range servers.list {
+				if s == server {
+					servers.list = append(servers.list[:i:i], servers.list[i+1:]...)
+					return
+				}
+			}
+		})
+	}
+}
+
+// Servers returns the servers shown in the admin endpoints (返回管理端点中展示的服务)
+func Servers() []ziface.IServer {
+	servers.RLock()
+	defer servers.RUnlock()
+	return append([]ziface.IServer(nil), servers.list...)
+}
+
+// Handler returns the handler of the admin endpoints (返回管理端点的处理器):
+//
+//	/debug/pprof/   the profiles of net/http/pprof (net/http/pprof的性能分析)
+//	/debug/runtime  goroutines, memory and GC stats (协程、内存与GC统计)
+//	/debug/zinx     connections and workers of every server (每个服务的连接与worker)
+//	/metrics        the metrics of zmetrics (zmetrics的指标)
+//	/healthz        the liveness probe, 503 when a server is stopped (存活探针，有服务停止时为503)
+//	/readyz         the readiness probe, 503 when a server is not ready, see ziface.HealthStatus
+//	                (就绪探针，有服务未就绪时为503，见ziface.HealthStatus)
+//	/admin/         the inspection and control API, see SetToken (查看与控制接口，见SetToken)
+//
+// Every endpoint but the probes requires the token once it is set (设置令牌后除探针外的所有端点都需要令牌)
+func Handler() http.Handler {
+	return newHandler(getToken)
+}
+
+// HandlerWithToken returns the handler of the admin endpoints like Handler, requiring token instead of the one of
+// SetToken (与Handler一样返回管理端点的处理器，但要求token而不是SetToken设置的令牌)
+func HandlerWithToken(token string) http.Handler {
+	return newHandler(func() string { return token })
+}
+
+func newHandler(token func() string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ReadRuntimeStats())
+	})
+	mux.HandleFunc("/debug/zinx", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ReadServerStats())
+	})
+	mux.Handle("/metrics", zmetrics.Handler())
+	registerProbes(mux)
+	registerAPI(mux)
+	return authorize(mux, token)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		zlog.Ins().ErrorF("zadmin: write response err: %v", err)
+	}
+}
+
+var listeners sync.Map
+
+// ListenAndServe serves the admin endpoints on addr in the background, it does nothing when addr is already served,
+// so that servers configured with the same address share it. The endpoints expose the internals of the process,
+// addr should only be reachable by the operators, e.g. "127.0.0.1:6060"
+// (在后台于addr上提供管理端点，addr已经在提供时不做任何事，以便配置相同地址的多个服务共用。
+// 管理端点暴露进程内部状态，addr应只对运维人员可达，例如"127.0.0.1:6060")
+func ListenAndServe(addr string) {
+	listenAndServe(addr, Handler)
+}
+
+// ListenAndServeWithToken serves the admin endpoints on addr like ListenAndServe, they require token instead of
+// the one of SetToken, the token of the first call for addr is kept
+// (与ListenAndServe一样在addr上提供管理端点，要求token而不是SetToken设置的令牌，保留addr第一次调用时的令牌)
+func ListenAndServeWithToken(addr, token string) {
+	listenAndServe(addr, func() http.Handler { return HandlerWithToken(token) })
+}
+
+func listenAndServe(addr string, newHandler func() http.Handler) {
+	if _, loaded := listeners.LoadOrStore(addr, struct{}{}); loaded {
+		return
+	}
+	handler := newHandler()
+	go func() {
+		zlog.Ins().InfoF("admin endpoints are served on %s", addr)
+		if err := http.ListenAndServe(addr, handler); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zlog.Ins().ErrorF("serve admin endpoints on %s err: %v", addr, err)
+			listeners.Delete(addr)
+		}
+	}()
+}
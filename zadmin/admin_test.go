@@ -0,0 +1,155 @@
+package zadmin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zadmin"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	s := znet.NewServer()
+	remove := zadmin.AddServer(s)
+	srv := httptest.NewServer(zadmin.Handler())
+	defer srv.Close()
+
+	var servers []zadmin.ServerStats
+	get(t, srv.URL+"/debug/zinx", &servers)
+	assert.Len(t, servers, 1)
+	assert.Equal(t, s.ServerName(), servers[0].Name)
+	assert.Equal(t, s.GetMsgHandler().GetWorkerPoolSize(), servers[0].WorkerPoolSize)
+
+	var runtimeStats zadmin.RuntimeStats
+	get(t, srv.URL+"/debug/runtime", &runtimeStats)
+	assert.True(t, runtimeStats.Goroutines > 0)
+	assert.True(t, runtimeStats.HeapAlloc > 0)
+
+	resp, err := http.Get(srv.URL + "/debug/pprof/goroutine?debug=1")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	remove()
+	remove()
+	assert.Empty(t, zadmin.Servers())
+}
+
+func get(t *testing.T, url string, v interface{}) {
+	resp, err := http.Get(url)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+}
+
+func TestAPI(t *testing.T) {
+	s := znet.NewServer()
+	defer zadmin.AddServer(s)()
+	srv := httptest.NewServer(zadmin.Handler())
+	defer srv.Close()
+
+	assert.Equal(t, http.StatusForbidden, call(t, http.MethodGet, srv.URL+"/admin/connections", "", nil))
+
+	zadmin.SetToken("secret")
+	defer zadmin.SetToken("")
+	assert.Equal(t, http.StatusUnauthorized, call(t, http.MethodGet, srv.URL+"/admin/connections", "wrong", nil))
+	assert.Equal(t, http.StatusUnauthorized, call(t, http.MethodGet, srv.URL+"/debug/runtime", "", nil))
+
+	// The probes are open, the server is not started (探针是开放的，服务未启动)
+	var statuses []ziface.HealthStatus
+	assert.Equal(t, http.StatusServiceUnavailable, call(t, http.MethodGet, srv.URL+"/healthz", "", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, call(t, http.MethodGet, srv.URL+"/readyz?server=other", "", nil))
+	resp, err := http.Get(srv.URL + "/readyz")
+	assert.Nil(t, err)
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&statuses))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "stopped", statuses[0].State)
+
+	var conns []zadmin.ConnInfo
+	assert.Equal(t, http.StatusOK, call(t, http.MethodGet, srv.URL+"/admin/connections", "secret", &conns))
+	assert.Empty(t, conns)
+	var traffic ziface.TrafficSnapshot
+	assert.Equal(t, http.StatusOK, call(t, http.MethodGet, srv.URL+"/admin/traffic", "secret", &traffic))
+	assert.Empty(t, traffic.Connections)
+	assert.Equal(t, http.StatusNotFound, call(t, http.MethodPost, srv.URL+"/admin/kick?connID=7", "secret", nil))
+	assert.Equal(t, http.StatusNotFound, call(t, http.MethodGet, srv.URL+"/admin/connections?server=other", "secret", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, call(t, http.MethodGet, srv.URL+"/admin/kick?connID=7", "secret", nil))
+
+	var sent map[string]int
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/broadcast?msgID=1", "secret", &sent))
+	assert.Equal(t, map[string]int{"sent": 0, "failed": 0}, sent)
+	var draining map[string]int
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/drain?tenant=acme&timeout=1s", "secret", &draining))
+	assert.Equal(t, map[string]int{"draining": 0}, draining)
+	assert.Equal(t, http.StatusBadRequest, call(t, http.MethodPost, srv.URL+"/admin/drain", "secret", nil))
+
+	defer zlog.SetLogLevel(zlog.GetLogLevel())
+	defer zlog.ResetModuleLevel(zlog.ModuleDecoder)
+	var levels zadmin.LogLevels
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/log?level=warn", "secret", &levels))
+	assert.Equal(t, "warn", levels.Level)
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/log?level=error&module=decoder", "secret", &levels))
+	assert.Equal(t, "error", levels.Modules[zlog.ModuleDecoder])
+	assert.Equal(t, http.StatusBadRequest, call(t, http.MethodPost, srv.URL+"/admin/log?level=loud", "secret", nil))
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/log/conn?connID=7&duration=1m", "secret", &levels))
+	assert.Contains(t, levels.DebugConns, uint64(7))
+	zlog.DisableConnDebug(7)
+
+	var size map[string]uint32
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/workers?size=4", "secret", &size))
+	assert.Equal(t, uint32(4), size["workerPoolSize"])
+
+	var faults ziface.Faults
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/faults?dropRatio=0.1&sendDelay=200ms&sendDelayRatio=0.5", "secret", &faults))
+	assert.Equal(t, ziface.Faults{DropRatio: 0.1, SendDelay: 200 * time.Millisecond, SendDelayRatio: 0.5}, faults)
+	assert.Equal(t, faults, s.GetFaults())
+	assert.Equal(t, http.StatusBadRequest, call(t, http.MethodPost, srv.URL+"/admin/faults?closeRatio=3", "secret", nil))
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/faults", "secret", &faults))
+	assert.False(t, s.GetFaults().Enabled())
+	var closed map[string]int
+	assert.Equal(t, http.StatusOK, call(t, http.MethodPost, srv.URL+"/admin/faults/close?ratio=0.5", "secret", &closed))
+	assert.Equal(t, map[string]int{"closed": 0, "connections": 0}, closed)
+	assert.Equal(t, http.StatusBadRequest, call(t, http.MethodPost, srv.URL+"/admin/faults/close", "secret", nil))
+	var conditions map[uint64]ziface.NetCondition
+	assert.Equal(t, http.StatusOK, call(t, http.MethodGet, srv.URL+"/admin/faults/network", "secret", &conditions))
+	assert.Empty(t, conditions)
+	assert.Equal(t, http.StatusNotFound, call(t, http.MethodPost, srv.URL+"/admin/faults/network?connID=1&latency=100ms", "secret", nil))
+	assert.Equal(t, http.StatusBadRequest, call(t, http.MethodPost, srv.URL+"/admin/faults/network?connID=x", "secret", nil))
+}
+
+func call(t *testing.T, method, url, token string, v interface{}) int {
+	req, _ := http.NewRequest(method, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode == http.StatusOK {
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestHandlerWithToken(t *testing.T) {
+	zadmin.SetToken("global")
+	defer zadmin.SetToken("")
+	srv := httptest.NewServer(zadmin.HandlerWithToken("own"))
+	defer srv.Close()
+
+	// The handler requires its own token, not the one of SetToken (处理器要求自己的令牌，而不是SetToken设置的令牌)
+	assert.Equal(t, http.StatusUnauthorized, call(t, http.MethodGet, srv.URL+"/debug/runtime", "global", nil))
+	assert.Equal(t, http.StatusOK, call(t, http.MethodGet, srv.URL+"/debug/runtime", "own", nil))
+
+	open := httptest.NewServer(zadmin.HandlerWithToken(""))
+	defer open.Close()
+	assert.Equal(t, http.StatusForbidden, call(t, http.MethodGet, open.URL+"/admin/connections", "global", nil))
+}
@@ -0,0 +1,523 @@
+package zadmin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// token authenticates the admin requests (管理请求的认证令牌)
+var token atomic.Value // string
+
+// SetToken sets the token the admin requests to Handler and ListenAndServe must carry as
+// "Authorization: Bearer <token>", the /admin/ API is refused until a token is set, the debug endpoints are open
+// without one. HandlerWithToken and ListenAndServeWithToken take their own token instead.
+// (设置Handler与ListenAndServe的管理请求需要以"Authorization: Bearer <token>"携带的令牌，设置令牌之前拒绝/admin/接口，
+// 没有令牌时调试端点是开放的。HandlerWithToken与ListenAndServeWithToken使用自己的令牌)
+func SetToken(t string) {
+	token.Store(t)
+}
+
+func getToken() string {
+	t, _ := token.Load().(string)
+	return t
+}
+
+// authorize wraps next with the check of the token returned by token (为next加上token返回的令牌的检查)
+func authorize(next http.Handler, token func() string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := token()
+		if want == "" || isProbe(r.URL.Path) {
+			if strings.HasPrefix(r.URL.Path, "/admin/") {
+				writeError(w, http.StatusForbidden, errors.New("the admin API is disabled until a token is set"))
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("invalid admin token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// The /admin/ API, the server is selected by the "server" parameter and may be omitted when there is only one
+// (/admin/接口，服务由"server"参数指定，只有一个服务时可以省略):
+//
+//	GET  /admin/connections                         the connections and their properties (连接及其属性)
+//	GET  /admin/traffic                             the traffic of the connections and tenants (连接与租户的流量)
+//	POST /admin/kick?connID=7                       closes a connection (关闭连接)
+//	POST /admin/broadcast?msgID=1                   sends the body to every connection (向所有连接发送请求体)
+//	POST /admin/drain?tenant=acme[&group=g&tag=k&value=v&msgID=1&timeout=30s]
+//	                                                drains the selected connections, the body is the grace message of msgID,
+//	                                                see IServer.DrainConns (排空选中的连接，请求体为msgID的通知消息)
+//	GET  /admin/log                                 the log levels (日志级别)
+//	POST /admin/log?level=warn[&module=decoder]     sets the global or a module log level, level=reset resets the module
+//	                                                (设置全局或子系统日志级别，level=reset重置子系统)
+//	POST /admin/log/conn?connID=7&duration=5m       logs everything of a connection for a while (在一段时间内输出连接的所有日志)
+//	POST /admin/workers?size=32                     resizes the worker pool (调整工作池大小)
+//	GET  /admin/faults                              the faults injected, see ziface.Faults (注入的故障)
+//	POST /admin/faults?dropRatio=0.1&sendDelay=200ms&sendDelayRatio=0.5&corruptRatio=0.01&closeRatio=0.001
+//	                                                replaces the faults injected, without parameters it stops injecting
+//	                                                (替换注入的故障，不带参数时停止注入)
+//	POST /admin/faults/close?ratio=0.2              closes that ratio of the connections at random (随机关闭该比例的连接)
+//	GET  /admin/faults/network                      the network conditions simulated by connID, see ziface.NetCondition
+//	                                                (按connID列出模拟的网络状况)
+//	POST /admin/faults/network?connID=7&latency=150ms&jitter=50ms&bandwidth=48000
+//	                                                simulates the network of a connection, without the other parameters
+//	                                                it stops simulating (模拟连接的网络，不带其他参数时停止模拟)
+func registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/connections", method(http.MethodGet, listConnections))
+	mux.HandleFunc("/admin/traffic", method(http.MethodGet, traffic))
+	mux.HandleFunc("/admin/kick", method(http.MethodPost, kick))
+	mux.HandleFunc("/admin/broadcast", method(http.MethodPost, broadcast))
+	mux.HandleFunc("/admin/drain", method(http.MethodPost, drain))
+	mux.HandleFunc("/admin/log", logLevels)
+	mux.HandleFunc("/admin/log/conn", method(http.MethodPost, connDebug))
+	mux.HandleFunc("/admin/workers", method(http.MethodPost, resizeWorkers))
+	mux.HandleFunc("/admin/faults", faults)
+	mux.HandleFunc("/admin/faults/close", method(http.MethodPost, closeConns))
+	mux.HandleFunc("/admin/faults/network", netConditions)
+}
+
+type apiFunc func(r *http.Request) (interface{}, error)
+
+// apiError is an error with the HTTP status to respond (带有响应HTTP状态码的错误)
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e *apiError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusNotFound, err: fmt.Errorf(format, args...)}
+}
+
+func method(m string, f apiFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != m {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s only", m))
+			return
+		}
+		serve(w, r, f)
+	}
+}
+
+func serve(w http.ResponseWriter, r *http.Request, f apiFunc) {
+	result, err := f(r)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var ae *apiError
+		if errors.As(err, &ae) {
+			status = ae.status
+		}
+		writeError(w, status, err)
+		return
+	}
+	if r.Method != http.MethodGet {
+		zlog.Ins().InfoF("zadmin: %s %s from %s", r.Method, r.URL.RequestURI(), r.RemoteAddr)
+	}
+	writeJSON(w, result)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, map[string]string{"error": err.Error()})
+}
+
+// findServer returns the server named by the "server" parameter (返回"server"参数指定的服务)
+func findServer(r *http.Request) (ziface.IServer, error) {
+	name := r.URL.Query().Get("server")
+	list := Servers()
+	if name == "" {
+		if len(list) == 1 {
+			return list[0], nil
+		}
+		return nil, badRequest("%d servers are started, choose one with the server parameter", len(list))
+	}
+	for _, s := range list {
+		if s.ServerName() == name {
+			return s, nil
+		}
+	}
+	return nil, notFound("server %q not found", name)
+}
+
+func findConn(r *http.Request) (ziface.IConnection, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	connID, err := strconv.ParseUint(r.URL.Query().Get("connID"), 10, 64)
+	if err != nil {
+		return nil, badRequest("invalid connID: %v", err)
+	}
+	conn, err := s.GetConnMgr().Get(connID)
+	if err != nil {
+		return nil, notFound("connection %d not found", connID)
+	}
+	return conn, nil
+}
+
+// ConnInfo describes a connection (描述一个连接)
+type ConnInfo struct {
+	ConnID     uint64                 `json:"connID"`
+	Name       string                 `json:"name"`
+	RemoteAddr string                 `json:"remoteAddr"`
+	LocalAddr  string                 `json:"localAddr"`
+	WorkerID   uint32                 `json:"workerID"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+func listConnections(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	conns := make([]ConnInfo, 0, s.GetConnMgr().Len())
+	_ = s.GetConnMgr().Range(func(connID uint64, conn ziface.IConnection, _ interface{}) error {
+		info := ConnInfo{
+			ConnID:     connID,
+			Name:       conn.GetName(),
+			RemoteAddr: conn.RemoteAddrString(),
+			LocalAddr:  conn.LocalAddrString(),
+			WorkerID:   conn.GetWorkerID(),
+		}
+		if lister, ok := conn.(ziface.IPropertyLister); ok {
+			info.Properties = printable(lister.GetProperties())
+		}
+		conns = append(conns, info)
+		return nil
+	}, nil)
+	return conns, nil
+}
+
+// printable keeps the property values which can be encoded as JSON and describes the others
+// (保留可以编码为JSON的属性值，其余的使用描述字符串)
+func printable(properties map[string]interface{}) map[string]interface{} {
+	for key, value := range properties {
+		switch value.(type) {
+		case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		case fmt.Stringer:
+			properties[key] = value.(fmt.Stringer).String()
+		default:
+			properties[key] = fmt.Sprintf("%T", value)
+		}
+	}
+	return properties
+}
+
+func traffic(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTrafficSnapshot(), nil
+}
+
+func kick(r *http.Request) (interface{}, error) {
+	conn, err := findConn(r)
+	if err != nil {
+		return nil, err
+	}
+	conn.Stop()
+	return map[string]uint64{"kicked": conn.GetConnID()}, nil
+}
+
+// maxBroadcastSize limits the body of /admin/broadcast (限制/admin/broadcast的请求体大小)
+const maxBroadcastSize = 1 << 20
+
+func broadcast(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	msgID, err := strconv.ParseUint(r.URL.Query().Get("msgID"), 10, 32)
+	if err != nil {
+		return nil, badRequest("invalid msgID: %v", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBroadcastSize+1))
+	if err != nil {
+		return nil, badRequest("read body: %v", err)
+	}
+	if len(data) > maxBroadcastSize {
+		return nil, badRequest("body larger than %d bytes", maxBroadcastSize)
+	}
+	var sent, failed int
+	_ = s.GetConnMgr().Range(func(_ uint64, conn ziface.IConnection, _ interface{}) error {
+		if conn.SendMsg(uint32(msgID), data) != nil {
+			failed++
+		} else {
+			sent++
+		}
+		return nil
+	}, nil)
+	return map[string]int{"sent": sent, "failed": failed}, nil
+}
+
+func drain(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	selector := ziface.ConnSelector{
+		Tenant:   query.Get("tenant"),
+		Group:    query.Get("group"),
+		Tag:      query.Get("tag"),
+		TagValue: query.Get("value"),
+	}
+	if selector.Tenant == "" && selector.Group == "" && selector.Tag == "" {
+		return nil, badRequest("select the connections with tenant, group or tag")
+	}
+	var option ziface.DrainOption
+	if v := query.Get("timeout"); v != "" {
+		if option.Timeout, err = time.ParseDuration(v); err != nil {
+			return nil, badRequest("invalid timeout: %v", err)
+		}
+	}
+	if v := query.Get("msgID"); v != "" {
+		msgID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, badRequest("invalid msgID: %v", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxBroadcastSize+1))
+		if err != nil {
+			return nil, badRequest("read body: %v", err)
+		}
+		if len(data) > maxBroadcastSize {
+			return nil, badRequest("body larger than %d bytes", maxBroadcastSize)
+		}
+		option.GraceMsgID, option.GraceData = uint32(msgID), data
+	}
+	drained, _ := s.DrainConns(selector, option)
+	return map[string]int{"draining": drained}, nil
+}
+
+// LogLevels describes the log levels (描述日志级别)
+type LogLevels struct {
+	Level      string               `json:"level"`
+	Modules    map[string]string    `json:"modules"`
+	DebugConns map[uint64]time.Time `json:"debugConns"` // The connections logging everything, until when (输出所有日志的连接及其截止时间)
+}
+
+func logLevels(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		serve(w, r, readLogLevels)
+	case http.MethodPost:
+		serve(w, r, setLogLevel)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("GET or POST only"))
+	}
+}
+
+func readLogLevels(*http.Request) (interface{}, error) {
+	levels := LogLevels{
+		Level:      zlog.LevelName(zlog.GetLogLevel()),
+		Modules:    make(map[string]string),
+		DebugConns: zlog.DebugConns(),
+	}
+	for module, level := range zlog.ModuleLevels() {
+		levels.Modules[module] = zlog.LevelName(level)
+	}
+	return levels, nil
+}
+
+func setLogLevel(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	module := query.Get("module")
+	if module != "" && query.Get("level") == "reset" {
+		zlog.ResetModuleLevel(module)
+		return readLogLevels(r)
+	}
+	level, err := zlog.ParseLevel(query.Get("level"))
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	if module != "" {
+		zlog.SetModuleLevel(module, level)
+	} else {
+		zlog.SetLogLevel(level)
+	}
+	return readLogLevels(r)
+}
+
+func connDebug(r *http.Request) (interface{}, error) {
+	connID, err := strconv.ParseUint(r.URL.Query().Get("connID"), 10, 64)
+	if err != nil {
+		return nil, badRequest("invalid connID: %v", err)
+	}
+	d, err := time.ParseDuration(r.URL.Query().Get("duration"))
+	if err != nil {
+		return nil, badRequest("invalid duration: %v", err)
+	}
+	if d <= 0 {
+		zlog.DisableConnDebug(connID)
+	} else {
+		zlog.EnableConnDebug(connID, d)
+	}
+	return readLogLevels(r)
+}
+
+func resizeWorkers(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.ParseUint(r.URL.Query().Get("size"), 10, 32)
+	if err != nil {
+		return nil, badRequest("invalid size: %v", err)
+	}
+	mh := s.GetMsgHandler()
+	if err := mh.ResizeWorkerPool(uint32(size)); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	return map[string]uint32{"workerPoolSize": mh.GetWorkerPoolSize()}, nil
+}
+
+func faults(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		serve(w, r, readFaults)
+	case http.MethodPost:
+		serve(w, r, setFaults)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("GET or POST only"))
+	}
+}
+
+func readFaults(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetFaults(), nil
+}
+
+func setFaults(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	var faults ziface.Faults
+	for _, p := range []struct {
+		name  string
+		ratio *float64
+	}{{"sendDelayRatio", &faults.SendDelayRatio}, {"dropRatio", &faults.DropRatio},
+		{"corruptRatio", &faults.CorruptRatio}, {"closeRatio", &faults.CloseRatio}} {
+		if v := query.Get(p.name); v != "" {
+			if *p.ratio, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, badRequest("invalid %s: %v", p.name, err)
+			}
+		}
+	}
+	if v := query.Get("sendDelay"); v != "" {
+		if faults.SendDelay, err = time.ParseDuration(v); err != nil {
+			return nil, badRequest("invalid sendDelay: %v", err)
+		}
+	}
+	if err := s.SetFaults(faults); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	return s.GetFaults(), nil
+}
+
+// closeConns closes a random share of the connections, as a network partition or a crashing proxy would
+// (随机关闭一部分连接，如同网络分区或代理崩溃)
+func closeConns(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	ratio, err := strconv.ParseFloat(r.URL.Query().Get("ratio"), 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return nil, badRequest("ratio must be between 0 and 1")
+	}
+	var conns []ziface.IConnection
+	_ = s.GetConnMgr().Range(func(_ uint64, conn ziface.IConnection, _ interface{}) error {
+		conns = append(conns, conn)
+		return nil
+	}, nil)
+	rand.Shuffle(len(conns), func(i, j int) { conns[i], conns[j] = conns[j], conns[i] })
+	closed := conns[:int(math.Round(ratio*float64(len(conns))))]
+	for _, conn := range closed {
+		conn.Stop()
+	}
+	return map[string]int{"closed": len(closed), "connections": len(conns)}, nil
+}
+
+func netConditions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		serve(w, r, readNetConditions)
+	case http.MethodPost:
+		serve(w, r, setNetCondition)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("GET or POST only"))
+	}
+}
+
+func readNetConditions(r *http.Request) (interface{}, error) {
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetNetConditions(), nil
+}
+
+func setNetCondition(r *http.Request) (interface{}, error) {
+	conn, err := findConn(r)
+	if err != nil {
+		return nil, err
+	}
+	s, err := findServer(r)
+	if err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	var condition ziface.NetCondition
+	for _, p := range []struct {
+		name string
+		d    *time.Duration
+	}{{"latency", &condition.Latency}, {"jitter", &condition.Jitter}} {
+		if v := query.Get(p.name); v != "" {
+			if *p.d, err = time.ParseDuration(v); err != nil {
+				return nil, badRequest("invalid %s: %v", p.name, err)
+			}
+		}
+	}
+	if v := query.Get("bandwidth"); v != "" {
+		if condition.Bandwidth, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, badRequest("invalid bandwidth: %v", err)
+		}
+	}
+	if err := s.SetNetCondition(conn.GetConnID(), condition); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	return s.GetNetConditions(), nil
+}
@@ -0,0 +1,50 @@
+package zadmin
+
+import (
+	"net/http"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// The probe endpoints are open without the token, so that Kubernetes can probe them
+// (探针端点无需令牌，以便Kubernetes探测)
+const (
+	livenessPath  = "/healthz"
+	readinessPath = "/readyz"
+)
+
+func isProbe(path string) bool {
+	return path == livenessPath || path == readinessPath
+}
+
+// probe responds 200 when ok holds for the servers selected by the "server" parameter, all of them by default,
+// and 503 otherwise, readiness needs at least a server
+// (当"server"参数选择的服务（默认为全部）都满足ok时响应200，否则响应503，就绪需要至少一个服务)
+func probe(ok func(ziface.HealthStatus) bool, needServer bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("server")
+		statuses := make([]ziface.HealthStatus, 0)
+		healthy := true
+		for _, s := range Servers() {
+			if name != "" && s.ServerName() != name {
+				continue
+			}
+			status := s.GetHealth()
+			statuses = append(statuses, status)
+			healthy = healthy && ok(status)
+		}
+		if len(statuses) == 0 && (needServer || name != "") {
+			healthy = false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		writeJSON(w, statuses)
+	}
+}
+
+func registerProbes(mux *http.ServeMux) {
+	mux.HandleFunc(livenessPath, probe(func(status ziface.HealthStatus) bool { return status.Live }, false))
+	mux.HandleFunc(readinessPath, probe(func(status ziface.HealthStatus) bool { return status.Ready }, true))
+}
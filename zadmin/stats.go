@@ -0,0 +1,85 @@
+package zadmin
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// RuntimeStats is a snapshot of the Go runtime (Go运行时的快照)
+type RuntimeStats struct {
+	GoVersion  string `json:"goVersion"`
+	NumCPU     int    `json:"numCPU"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	Goroutines int    `json:"goroutines"`
+
+	HeapAlloc   uint64 `json:"heapAlloc"`   // Bytes of the allocated heap objects (已分配的堆对象字节数)
+	HeapInuse   uint64 `json:"heapInuse"`   // Bytes of the in-use heap spans (使用中的堆span字节数)
+	HeapObjects uint64 `json:"heapObjects"` // Number of allocated heap objects (已分配的堆对象数)
+	Sys         uint64 `json:"sys"`         // Bytes obtained from the OS (从操作系统获得的字节数)
+
+	NumGC      int64           `json:"numGC"`
+	LastGC     time.Time       `json:"lastGC"`
+	PauseTotal time.Duration   `json:"pauseTotal"`
+	Pauses     []time.Duration `json:"pauses"` // The most recent GC pauses, newest first (最近的GC暂停，最新的在前)
+}
+
+// recentPauses is how many GC pauses RuntimeStats reports (RuntimeStats报告的GC暂停数)
+const recentPauses = 16
+
+// ReadRuntimeStats reads the stats of the Go runtime, it stops the world briefly like runtime.ReadMemStats
+// (读取Go运行时统计，与runtime.ReadMemStats一样会短暂地停止所有协程)
+func ReadRuntimeStats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	gc := debug.GCStats{Pause: make([]time.Duration, recentPauses)}
+	debug.ReadGCStats(&gc)
+	return RuntimeStats{
+		GoVersion:   runtime.Version(),
+		NumCPU:      runtime.NumCPU(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   mem.HeapAlloc,
+		HeapInuse:   mem.HeapInuse,
+		HeapObjects: mem.HeapObjects,
+		Sys:         mem.Sys,
+		NumGC:       gc.NumGC,
+		LastGC:      gc.LastGC,
+		PauseTotal:  gc.PauseTotal,
+		Pauses:      gc.Pause,
+	}
+}
+
+// ServerStats is a snapshot of the internals of a server (服务内部状态的快照)
+type ServerStats struct {
+	Name           string               `json:"name"`
+	Connections    int                  `json:"connections"`
+	WorkerPoolSize uint32               `json:"workerPoolSize"`
+	QueueDepths    []int                `json:"queueDepths"`
+	Timeouts       uint64               `json:"timeouts"` // Requests whose route timed out (路由超时的请求数)
+	Dropped        uint64               `json:"dropped"`  // Requests dropped by the queue full policy (队列满策略丢弃的请求数)
+	Spilled        uint64               `json:"spilled"`  // Requests spilled by the queue full policy (队列满策略溢出的请求数)
+	Workers        []ziface.WorkerStats `json:"workers"`
+}
+
+// ReadServerStats reads the stats of the servers shown in the admin endpoints (读取管理端点中展示的服务的统计)
+func ReadServerStats() []ServerStats {
+	list := Servers()
+	stats := make([]ServerStats, 0, len(list))
+	for _, s := range list {
+		mh := s.GetMsgHandler()
+		stats = append(stats, ServerStats{
+			Name:           s.ServerName(),
+			Connections:    s.GetConnMgr().Len(),
+			WorkerPoolSize: mh.GetWorkerPoolSize(),
+			QueueDepths:    mh.GetQueueDepths(),
+			Timeouts:       mh.GetTimeoutCount(),
+			Dropped:        mh.GetDropCount(),
+			Spilled:        mh.GetSpillCount(),
+			Workers:        mh.GetWorkerStats(),
+		})
+	}
+	return stats
+}
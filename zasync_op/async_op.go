@@ -23,6 +23,7 @@ import (
 	a.调用 Process 选择一个异步worker进行异步IO操作逻辑；
 	b.在异步IO逻辑中设置需要共享的变量，及异步返回结果：asyncResult.SetReturnedObj
 	c.注册设置异步回调，即回到原本的业务线程里继续进行后续的操作：asyncResult.OnComplete
+	d.或者调用 Async 得到 Promise，用 Then/Catch/Finally 串联后续操作，WhenAll/WhenAny 组合多个 Promise，WithTimeout 设置超时
 */
 
 /*
@@ -39,6 +40,7 @@ import (
 	a. Call Process to select an asynchronous worker for asynchronous IO operation logic;
 	b. Set the variables that need to be shared in the asynchronous IO logic and the asynchronous return result: asyncResult.SetReturnedObj
 	c. Register and set the asynchronous callback, that is, return to the original business thread to continue subsequent operations: asyncResult.OnComplete
+	d. Or call Async to get a Promise, chain the subsequent operations with Then/Catch/Finally, combine promises with WhenAll/WhenAny and set deadlines with WithTimeout
 */
 
 // Asynchronous worker group (异步worker组)
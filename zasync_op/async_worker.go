@@ -14,12 +14,12 @@ type AsyncWorker struct {
 
 func (aw *AsyncWorker) process(asyncOp func()) {
 	if asyncOp == nil {
-		zlog.Error("Async operation is empty.")
+		zlog.Ins().ErrorF("Async operation is empty.")
 		return
 	}
 
 	if aw.taskQ == nil {
-		zlog.Error("Task queue has not been initialized.")
+		zlog.Ins().ErrorF("Task queue has not been initialized.")
 		return
 	}
 
@@ -37,7 +37,7 @@ func (aw *AsyncWorker) process(asyncOp func()) {
 
 func (aw *AsyncWorker) loopExecTask() {
 	if aw.taskQ == nil {
-		zlog.Error("The task queue has not been initialized.")
+		zlog.Ins().ErrorF("The task queue has not been initialized.")
 		return
 	}
 
@@ -0,0 +1,254 @@
+/*
+	Package zasync_op
+	@File：promise.go
+*/
+
+package zasync_op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+)
+
+var (
+	// ErrPromiseTimeout rejects a promise not settled before the timeout of WithTimeout
+	// (在WithTimeout的超时时间前未完成的Promise以此失败)
+	ErrPromiseTimeout = errors.New("zasync_op: promise timed out")
+	// ErrPromisePanic wraps the panics of the async operations and the callbacks (包装异步操作和回调中的panic)
+	ErrPromisePanic = errors.New("zasync_op: promise panic")
+	// ErrNoPromises rejects WhenAny called without any promise (WhenAny未传入任何Promise时以此失败)
+	ErrNoPromises = errors.New("zasync_op: no promises")
+)
+
+type promiseCallback struct {
+	f func()
+	// direct callbacks run on the settling goroutine instead of the business thread (direct回调在完成Promise的协程中执行，而不是业务线程)
+	direct bool
+}
+
+// Promise is the eventual value or error of an asynchronous operation.
+// The callbacks of Then, Catch and Finally run back on the business thread of the connection of the promise, as
+// the completion callback of AsyncOpResult does, or on the goroutine settling the promise when it has no
+// connection. A panic in a callback rejects the promise it returned with ErrPromisePanic
+// (异步操作最终的值或错误。与AsyncOpResult的完成回调相同，Then、Catch和Finally的回调回到Promise所属连接的业务线程执行，
+// 没有连接时在完成Promise的协程中执行。回调中的panic会使其返回的Promise以ErrPromisePanic失败)
+type Promise struct {
+	conn ziface.IConnection
+	done chan struct{}
+
+	mu        sync.Mutex
+	settled   bool
+	value     interface{}
+	err       error
+	callbacks []promiseCallback
+}
+
+// NewPromise creates a pending promise whose callbacks run on the business thread of conn, conn may be nil
+// (新建等待中的Promise，其回调在conn的业务线程中执行，conn可以为nil)
+func NewPromise(conn ziface.IConnection) *Promise {
+	return &Promise{conn: conn, done: make(chan struct{})}
+}
+
+// Async runs op on the async worker of opId, as Process does, and returns the promise of its result
+// (与Process相同，在opId对应的异步worker中执行op，并返回其结果的Promise)
+func Async(conn ziface.IConnection, opId int, op func() (interface{}, error)) *Promise {
+	p := NewPromise(conn)
+	Process(opId, func() {
+		defer p.recover()
+		p.settle(op())
+	})
+	return p
+}
+
+// Resolve settles the promise with the value v, it returns false when the promise was already settled
+// (以值v完成Promise，Promise已完成时返回false)
+func (p *Promise) Resolve(v interface{}) bool {
+	return p.settle(v, nil)
+}
+
+// Reject settles the promise with the error err, it returns false when the promise was already settled
+// (以错误err完成Promise，Promise已完成时返回false)
+func (p *Promise) Reject(err error) bool {
+	return p.settle(nil, err)
+}
+
+// Then calls f with the value of the promise once resolved, the returned promise settles with the result of f,
+// or with the error of the promise when rejected, f is then not called
+// (Promise成功后以其值调用f，返回的Promise以f的结果完成；Promise失败时不调用f，返回的Promise以同样的错误失败)
+func (p *Promise) Then(f func(v interface{}) (interface{}, error)) *Promise {
+	return p.chain(func(v interface{}, err error) (interface{}, error) {
+		if err != nil {
+			return nil, err
+		}
+		return f(v)
+	})
+}
+
+// Catch calls f with the error of the promise once rejected, the returned promise settles with the result of f,
+// or with the value of the promise when resolved, f is then not called
+// (Promise失败后以其错误调用f，返回的Promise以f的结果完成；Promise成功时不调用f，返回的Promise以同样的值成功)
+func (p *Promise) Catch(f func(err error) (interface{}, error)) *Promise {
+	return p.chain(func(v interface{}, err error) (interface{}, error) {
+		if err == nil {
+			return v, nil
+		}
+		return f(err)
+	})
+}
+
+// Finally calls f once the promise is settled, the returned promise settles as the promise did
+// (Promise完成后调用f，返回的Promise与原Promise的结果相同)
+func (p *Promise) Finally(f func()) *Promise {
+	return p.chain(func(v interface{}, err error) (interface{}, error) {
+		f()
+		return v, err
+	})
+}
+
+// WithTimeout rejects the promise with ErrPromiseTimeout if it is not settled within d, and returns it.
+// The asynchronous operation itself is not interrupted, its result is then ignored
+// (Promise在d内未完成时以ErrPromiseTimeout失败，返回该Promise。异步操作本身不会被中断，其结果会被忽略)
+func (p *Promise) WithTimeout(d time.Duration) *Promise {
+	t := time.AfterFunc(d, func() {
+		p.Reject(ErrPromiseTimeout)
+	})
+	p.onSettled(promiseCallback{f: func() { t.Stop() }, direct: true})
+	return p
+}
+
+// WithContext rejects the promise with the error of ctx if ctx is done first, and returns it
+// (ctx先结束时Promise以ctx的错误失败，返回该Promise)
+func (p *Promise) WithContext(ctx context.Context) *Promise {
+	if ctx.Done() == nil {
+		return p
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.Reject(ctx.Err())
+		case <-p.done:
+		}
+	}()
+	return p
+}
+
+// Done is closed once the promise is settled (Promise完成时关闭)
+func (p *Promise) Done() <-chan struct{} {
+	return p.done
+}
+
+// Await waits for the promise to settle, or for ctx to be done. It must not be called on the business thread
+// the promise calls back on, which would never get to run the callbacks
+// (等待Promise完成或ctx结束。不能在Promise回调所在的业务线程中调用，否则该线程无法再执行回调)
+func (p *Promise) Await(ctx context.Context) (interface{}, error) {
+	select {
+	case <-p.done:
+		return p.value, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WhenAll resolves with the values of the promises, in order, once all of them are resolved, or rejects with
+// the first error (所有Promise都成功后按顺序以它们的值成功，或者以第一个错误失败)
+func WhenAll(conn ziface.IConnection, promises ...*Promise) *Promise {
+	all := NewPromise(conn)
+	values := make([]interface{}, len(promises))
+	if len(promises) == 0 {
+		all.Resolve(values)
+		return all
+	}
+	remaining := int32(len(promises))
+	for i, p := range promises {
+		i, p := i, p
+		p.onSettled(promiseCallback{f: func() {
+			if p.err != nil {
+				all.Reject(p.err)
+				return
+			}
+			values[i] = p.value
+			if atomic.AddInt32(&remaining, -1) == 0 {
+				all.Resolve(values)
+			}
+		}, direct: true})
+	}
+	return all
+}
+
+// WhenAny settles as the first of the promises to settle (以第一个完成的Promise的结果完成)
+func WhenAny(conn ziface.IConnection, promises ...*Promise) *Promise {
+	first := NewPromise(conn)
+	if len(promises) == 0 {
+		first.Reject(ErrNoPromises)
+		return first
+	}
+	for _, p := range promises {
+		p := p
+		p.onSettled(promiseCallback{f: func() {
+			first.settle(p.value, p.err)
+		}, direct: true})
+	}
+	return first
+}
+
+func (p *Promise) chain(f func(v interface{}, err error) (interface{}, error)) *Promise {
+	next := NewPromise(p.conn)
+	p.onSettled(promiseCallback{f: func() {
+		defer next.recover()
+		next.settle(f(p.value, p.err))
+	}})
+	return next
+}
+
+// recover rejects the promise with the panic of its operation or callback (以操作或回调中的panic使Promise失败)
+func (p *Promise) recover() {
+	if err := recover(); err != nil {
+		p.Reject(fmt.Errorf("%w: %v", ErrPromisePanic, err))
+	}
+}
+
+func (p *Promise) settle(v interface{}, err error) bool {
+	p.mu.Lock()
+	if p.settled {
+		p.mu.Unlock()
+		return false
+	}
+	p.settled, p.value, p.err = true, v, err
+	callbacks := p.callbacks
+	p.callbacks = nil
+	close(p.done)
+	p.mu.Unlock()
+
+	for _, cb := range callbacks {
+		p.dispatch(cb)
+	}
+	return true
+}
+
+func (p *Promise) onSettled(cb promiseCallback) {
+	p.mu.Lock()
+	if !p.settled {
+		p.callbacks = append(p.callbacks, cb)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	p.dispatch(cb)
+}
+
+// dispatch runs cb on the business thread of the connection of the promise, as AsyncOpResult does
+// (与AsyncOpResult相同，在Promise所属连接的业务线程中执行cb)
+func (p *Promise) dispatch(cb promiseCallback) {
+	if cb.direct || p.conn == nil {
+		cb.f()
+		return
+	}
+	p.conn.GetMsgHandler().SendMsgToTaskQueue(znet.NewFuncRequest(p.conn, cb.f))
+}
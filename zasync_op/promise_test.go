@@ -0,0 +1,101 @@
+package zasync_op
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromiseChain(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	var finally bool
+	v, err := Async(nil, 1, func() (interface{}, error) {
+		return 20, nil
+	}).Then(func(v interface{}) (interface{}, error) {
+		return v.(int) + 1, nil
+	}).Then(func(v interface{}) (interface{}, error) {
+		return nil, boom
+	}).Then(func(v interface{}) (interface{}, error) {
+		t.Error("Then is called after an error")
+		return nil, nil
+	}).Catch(func(err error) (interface{}, error) {
+		assert.ErrorIs(t, err, boom)
+		return 42, nil
+	}).Finally(func() {
+		finally = true
+	}).Await(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 42, v)
+	assert.True(t, finally)
+
+	_, err = Async(nil, 2, func() (interface{}, error) {
+		panic("oops")
+	}).Await(ctx)
+	assert.ErrorIs(t, err, ErrPromisePanic)
+
+	p := NewPromise(nil)
+	assert.True(t, p.Resolve(1))
+	assert.False(t, p.Reject(boom))
+	v, err = p.Then(func(v interface{}) (interface{}, error) { panic("again") }).Await(ctx)
+	assert.Nil(t, v)
+	assert.ErrorIs(t, err, ErrPromisePanic)
+}
+
+func TestPromiseDeadline(t *testing.T) {
+	ctx := context.Background()
+
+	slow := NewPromise(nil).WithTimeout(50 * time.Millisecond)
+	_, err := slow.Await(ctx)
+	assert.ErrorIs(t, err, ErrPromiseTimeout)
+	assert.False(t, slow.Resolve(1))
+
+	fast := NewPromise(nil).WithTimeout(time.Second)
+	fast.Resolve(1)
+	v, err := fast.Await(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	cctx, cancel := context.WithCancel(ctx)
+	canceled := NewPromise(nil).WithContext(cctx)
+	cancel()
+	_, err = canceled.Await(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPromiseCombinators(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	a, b := NewPromise(nil), NewPromise(nil)
+	all := WhenAll(nil, a, b)
+	b.Resolve("b")
+	a.Resolve("a")
+	v, err := all.Await(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, v)
+
+	c := NewPromise(nil)
+	failed := WhenAll(nil, c, Async(nil, 3, func() (interface{}, error) { return nil, boom }))
+	_, err = failed.Await(ctx)
+	assert.ErrorIs(t, err, boom)
+
+	v, err = WhenAll(nil).Await(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, v)
+
+	d := NewPromise(nil)
+	first := WhenAny(nil, c, d)
+	d.Resolve("d")
+	c.Reject(boom)
+	v, err = first.Await(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "d", v)
+
+	_, err = WhenAny(nil).Await(ctx)
+	assert.ErrorIs(t, err, ErrNoPromises)
+}
@@ -0,0 +1,310 @@
+// Package zbench is a load generator speaking the zinx datapack: it opens many client connections to a server, sends
+// them a weighted mix of messages at a paced rate and reports the throughput, the error rate and the latency
+// percentiles, see examples/zinx_bench for the command
+// (使用zinx数据包格式的压测客户端：向服务建立大量客户端连接，按权重混合发送消息并控制速率，报告吞吐量、错误率与延迟分位数，
+// 命令见examples/zinx_bench)
+package zbench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+var ErrNoMessages = errors.New("zbench: no message in the mix")
+
+// Message is one kind of message of the mix (混合消息中的一种消息)
+type Message struct {
+	// MsgID is the MsgID sent (发送的MsgID)
+	MsgID uint32
+	// Weight is the share of the messages of this kind, 1 if not set (该种消息所占的权重，未设置时为1)
+	Weight int
+	// MinSize and MaxSize bound the random size of the payloads, MaxSize defaults to MinSize
+	// (负载随机大小的上下限，MaxSize默认为MinSize)
+	MinSize, MaxSize int
+	// ReplyMsgID is the MsgID the server replies with, the latency is measured until it arrives, 0 sends without
+	// waiting for a reply (服务回复的MsgID，延迟统计到收到回复为止，为0时发送后不等待回复)
+	ReplyMsgID uint32
+}
+
+// Config describes a run (描述一次压测)
+type Config struct {
+	// Addr is the TCP address of the server (服务的TCP地址)
+	Addr string
+	// Dial opens the connections instead of dialing Addr, e.g. to go through TLS or in-memory connections
+	// (替代拨号Addr来建立连接，例如经由TLS或内存连接)
+	Dial func() (net.Conn, error)
+	// Packet is the datapack of the server, the default Zinx datapack if nil (服务的数据包格式，为nil时使用默认的Zinx数据包)
+	Packet ziface.IDataPack
+	// Conns is the number of concurrent connections, 1 if not set (并发连接数，未设置时为1)
+	Conns int
+	// Duration is the length of the run after the ramp-up, 0 runs until the context is done
+	// (爬坡之后压测的时长，为0时运行到context结束)
+	Duration time.Duration
+	// RampUp spreads the start of the connections evenly over its length (将连接的启动均匀分布在该时长内)
+	RampUp time.Duration
+	// Rate is the messages per second sent by each connection, 0 sends the next message as soon as the previous one
+	// is answered (每个连接每秒发送的消息数，为0时在上一条消息得到回复后立即发送下一条)
+	Rate float64
+	// Timeout bounds the wait for a reply, 5s if not set, a connection timing out is closed and dialed again
+	// (等待回复的最长时间，未设置时为5秒，超时的连接被关闭并重新拨号)
+	Timeout time.Duration
+	// Mix is the messages sent, picked at random by Weight (发送的消息，按Weight随机选择)
+	Mix []Message
+}
+
+// Run runs the load described by config and returns its report, it only fails on an invalid config, the dial and
+// send errors are counted in the report (执行config描述的压测并返回报告，只在配置无效时失败，拨号与发送错误计入报告)
+func Run(ctx context.Context, config Config) (*Report, error) {
+	if len(config.Mix) == 0 {
+		return nil, ErrNoMessages
+	}
+	b := &bench{config: config, report: newReport()}
+	if b.config.Packet == nil {
+		b.config.Packet = zpack.Factory().NewPack(ziface.ZinxDataPack)
+	}
+	if b.config.Conns < 1 {
+		b.config.Conns = 1
+	}
+	if b.config.Timeout <= 0 {
+		b.config.Timeout = 5 * time.Second
+	}
+	if b.config.Dial == nil {
+		b.config.Dial = func() (net.Conn, error) { return net.Dial("tcp", b.config.Addr) }
+	}
+	for _, m := range config.Mix {
+		if m.MaxSize < m.MinSize {
+			m.MaxSize = m.MinSize
+		}
+		if m.MinSize < 0 {
+			return nil, fmt.Errorf("zbench: negative size of msgID = %d", m.MsgID)
+		}
+		if m.Weight <= 0 {
+			m.Weight = 1
+		}
+		b.mix = append(b.mix, m)
+		b.totalWeight += m.Weight
+	}
+
+	if b.config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.config.RampUp+b.config.Duration)
+		defer cancel()
+	}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < b.config.Conns; i++ {
+		delay := b.config.RampUp * time.Duration(i) / time.Duration(b.config.Conns)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case <-time.After(delay):
+				b.worker(ctx, rand.New(rand.NewSource(start.UnixNano()+int64(i))))
+			case <-ctx.Done():
+			}
+		}(i)
+	}
+	wg.Wait()
+	b.report.finish(time.Since(start))
+	return b.report, nil
+}
+
+// bench is a run in progress (进行中的压测)
+type bench struct {
+	config      Config
+	mix         []Message
+	totalWeight int
+	report      *Report
+}
+
+// pick returns a message of the mix at random by weight (按权重随机返回混合消息中的一种)
+func (b *bench) pick(r *rand.Rand) *Message {
+	n := r.Intn(b.totalWeight)
+	for i := range b.mix {
+		if n < b.mix[i].Weight {
+			return &b.mix[i]
+		}
+		n -= b.mix[i].Weight
+	}
+	return &b.mix[len(b.mix)-1]
+}
+
+// worker drives one connection until ctx is done, dialing again after an error
+// (驱动一个连接直到ctx结束，出错后重新拨号)
+func (b *bench) worker(ctx context.Context, r *rand.Rand) {
+	var interval time.Duration
+	if b.config.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / b.config.Rate)
+	}
+	next := time.Now()
+	for ctx.Err() == nil {
+		conn, err := b.config.Dial()
+		if err != nil {
+			b.report.dialed(err)
+			// Back off before dialing again (重新拨号之前退避)
+			select {
+			case <-time.After(100 * time.Millisecond):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		b.report.dialed(nil)
+		// Unblock the reads when the run ends (压测结束时解除读阻塞)
+		stop := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+			case <-stop:
+			}
+		}()
+		for ctx.Err() == nil {
+			if interval > 0 {
+				if wait := time.Until(next); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-ctx.Done():
+						continue
+					}
+				}
+				next = next.Add(interval)
+				// A connection far behind does not burst to catch up (严重落后的连接不会突发发送来追赶)
+				if now := time.Now(); next.Before(now.Add(-interval)) {
+					next = now
+				}
+			}
+			if err := b.roundTrip(ctx, conn, b.pick(r), r); err != nil {
+				break
+			}
+		}
+		close(stop)
+		conn.Close()
+	}
+}
+
+// roundTrip sends a message of kind m on conn and waits for its reply, it returns an error if conn is no longer usable
+// (在conn上发送一条m类型的消息并等待回复，conn不再可用时返回错误)
+func (b *bench) roundTrip(ctx context.Context, conn net.Conn, m *Message, r *rand.Rand) error {
+	size := m.MinSize
+	if m.MaxSize > m.MinSize {
+		size += r.Intn(m.MaxSize - m.MinSize + 1)
+	}
+	data := make([]byte, size)
+	r.Read(data)
+	buf, err := b.config.Packet.Pack(zpack.NewMsgPackage(m.MsgID, data))
+	if err != nil {
+		b.report.record(m.MsgID, 0, false)
+		return nil
+	}
+
+	start := time.Now()
+	if _, err := conn.Write(buf); err != nil {
+		b.fail(ctx, m.MsgID)
+		return err
+	}
+	if m.ReplyMsgID == 0 {
+		b.report.record(m.MsgID, 0, true)
+		return nil
+	}
+	_ = conn.SetReadDeadline(start.Add(b.config.Timeout))
+	// Skip the other messages of the server, e.g. its heartbeats (跳过服务的其他消息，例如心跳)
+	for {
+		msgID, err := b.read(conn)
+		if err != nil {
+			b.fail(ctx, m.MsgID)
+			return err
+		}
+		if msgID == m.ReplyMsgID {
+			b.report.record(m.MsgID, time.Since(start), true)
+			return nil
+		}
+	}
+}
+
+// fail records a failed message unless the run has ended, which is not an error of the server
+// (记录一条失败的消息，压测已结束时除外，那不属于服务的错误)
+func (b *bench) fail(ctx context.Context, msgID uint32) {
+	if ctx.Err() == nil {
+		b.report.record(msgID, 0, false)
+	}
+}
+
+// read reads the next message of conn and returns its MsgID (读取conn的下一条消息并返回其MsgID)
+func (b *bench) read(conn net.Conn) (uint32, error) {
+	head := make([]byte, b.config.Packet.GetHeadLen())
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return 0, err
+	}
+	msg, err := b.config.Packet.Unpack(head)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.CopyN(io.Discard, conn, int64(msg.GetDataLen())); err != nil {
+		return 0, err
+	}
+	return msg.GetMsgID(), nil
+}
+
+// ParseMix parses a mix of comma separated messages msgID[:weight[:size[:replyMsgID]]], the size is a number of bytes
+// or a min-max range, e.g. "1:3:16-64:2,3:1:8" (解析逗号分隔的混合消息msgID[:weight[:size[:replyMsgID]]]，size为字节数或
+// min-max范围，例如"1:3:16-64:2,3:1:8")
+func ParseMix(s string) ([]Message, error) {
+	var mix []Message
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		fields := strings.Split(item, ":")
+		if len(fields) > 4 {
+			return nil, fmt.Errorf("zbench: invalid message %q", item)
+		}
+		var m Message
+		var err error
+		if m.MsgID, err = parseUint32(fields[0]); err != nil {
+			return nil, fmt.Errorf("zbench: invalid msgID of %q", item)
+		}
+		if len(fields) > 1 {
+			if m.Weight, err = strconv.Atoi(fields[1]); err != nil || m.Weight < 0 {
+				return nil, fmt.Errorf("zbench: invalid weight of %q", item)
+			}
+		}
+		if len(fields) > 2 {
+			lo, hi, found := strings.Cut(fields[2], "-")
+			if m.MinSize, err = strconv.Atoi(lo); err != nil || m.MinSize < 0 {
+				return nil, fmt.Errorf("zbench: invalid size of %q", item)
+			}
+			m.MaxSize = m.MinSize
+			if found {
+				if m.MaxSize, err = strconv.Atoi(hi); err != nil || m.MaxSize < m.MinSize {
+					return nil, fmt.Errorf("zbench: invalid size of %q", item)
+				}
+			}
+		}
+		if len(fields) > 3 {
+			if m.ReplyMsgID, err = parseUint32(fields[3]); err != nil {
+				return nil, fmt.Errorf("zbench: invalid reply msgID of %q", item)
+			}
+		}
+		mix = append(mix, m)
+	}
+	if len(mix) == 0 {
+		return nil, ErrNoMessages
+	}
+	return mix, nil
+}
+
+func parseUint32(s string) (uint32, error) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	return uint32(n), err
+}
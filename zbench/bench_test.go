@@ -0,0 +1,105 @@
+package zbench
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/ztest"
+	"github.com/stretchr/testify/assert"
+)
+
+type echoRouter struct {
+	znet.BaseRouter
+}
+
+func (r *echoRouter) Handle(request ziface.IRequest) {
+	_ = request.GetConnection().SendMsg(request.GetMsgID()+1, request.GetData())
+}
+
+func TestRun(t *testing.T) {
+	h := ztest.NewHarness()
+	defer h.Close()
+	h.Server.AddRouter(1, &echoRouter{})
+	h.Server.AddRouter(3, &echoRouter{})
+	h.Start()
+
+	report, err := Run(context.Background(), Config{
+		Dial:     func() (net.Conn, error) { return h.Dial().Conn(), nil },
+		Conns:    4,
+		RampUp:   40 * time.Millisecond,
+		Duration: 200 * time.Millisecond,
+		Mix: []Message{
+			{MsgID: 1, Weight: 3, MinSize: 16, MaxSize: 64, ReplyMsgID: 2},
+			{MsgID: 3, MinSize: 8},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), report.Dials)
+	assert.Equal(t, uint64(0), report.Errors)
+	assert.True(t, report.Requests > 0)
+	assert.True(t, report.Latency.Count > 0)
+	assert.True(t, report.Latency.P50 <= report.Latency.P99)
+	assert.Len(t, report.Messages, 2)
+	assert.Equal(t, report.Latency.Count, report.Messages[0].Latency.Count)
+	assert.Equal(t, 0, report.Messages[1].Latency.Count)
+}
+
+func TestRunTimeoutAndRate(t *testing.T) {
+	h := ztest.NewHarness()
+	defer h.Close()
+	h.Server.AddRouter(1, &echoRouter{})
+	h.Start()
+
+	// The reply never comes, every request times out and the connection is dialed again
+	// (回复永远不会到达，每个请求都超时并重新拨号)
+	report, err := Run(context.Background(), Config{
+		Dial:     func() (net.Conn, error) { return h.Dial().Conn(), nil },
+		Duration: 200 * time.Millisecond,
+		Rate:     50,
+		Timeout:  30 * time.Millisecond,
+		Mix:      []Message{{MsgID: 1, ReplyMsgID: 100}},
+	})
+	assert.Nil(t, err)
+	assert.True(t, report.Errors > 1)
+	assert.Equal(t, report.Errors, report.Requests)
+	assert.Equal(t, report.Dials, report.Errors+1)
+	assert.Equal(t, float64(1), report.ErrorRate())
+
+	_, err = Run(context.Background(), Config{})
+	assert.Equal(t, ErrNoMessages, err)
+}
+
+func TestLatencyStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	stats := newLatencyStats(latencies)
+	assert.Equal(t, 100, stats.Count)
+	assert.Equal(t, time.Millisecond, stats.Min)
+	assert.Equal(t, 100*time.Millisecond, stats.Max)
+	assert.Equal(t, 50*time.Millisecond, stats.P50)
+	assert.Equal(t, 90*time.Millisecond, stats.P90)
+	assert.Equal(t, 99*time.Millisecond, stats.P99)
+	assert.Equal(t, 100*time.Millisecond, stats.P999)
+	assert.Equal(t, "no reply", newLatencyStats(nil).String())
+}
+
+func TestParseMix(t *testing.T) {
+	mix, err := ParseMix("1:3:16-64:2, 3:1:8,5")
+	assert.Nil(t, err)
+	assert.Equal(t, []Message{
+		{MsgID: 1, Weight: 3, MinSize: 16, MaxSize: 64, ReplyMsgID: 2},
+		{MsgID: 3, Weight: 1, MinSize: 8, MaxSize: 8},
+		{MsgID: 5},
+	}, mix)
+
+	for _, s := range []string{"", "x", "1:-1", "1:1:64-16", "1:1:8:2:3"} {
+		_, err := ParseMix(s)
+		assert.NotNil(t, err, s)
+	}
+}
@@ -0,0 +1,156 @@
+package zbench
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyStats summarizes the latencies of the replied messages (汇总得到回复的消息的延迟)
+type LatencyStats struct {
+	Count               int
+	Min, Mean, Max      time.Duration
+	P50, P90, P99, P999 time.Duration
+}
+
+// newLatencyStats summarizes latencies, it sorts them (汇总latencies，会对其排序)
+func newLatencyStats(latencies []time.Duration) LatencyStats {
+	stats := LatencyStats{Count: len(latencies)}
+	if len(latencies) == 0 {
+		return stats
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	stats.Min, stats.Max, stats.Mean = latencies[0], latencies[len(latencies)-1], sum/time.Duration(len(latencies))
+	stats.P50 = percentile(latencies, 0.5)
+	stats.P90 = percentile(latencies, 0.9)
+	stats.P99 = percentile(latencies, 0.99)
+	stats.P999 = percentile(latencies, 0.999)
+	return stats
+}
+
+// percentile returns the p quantile of sorted by the nearest rank (按最近秩返回sorted的p分位数)
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func (s LatencyStats) String() string {
+	if s.Count == 0 {
+		return "no reply"
+	}
+	return fmt.Sprintf("min %v mean %v p50 %v p90 %v p99 %v p99.9 %v max %v",
+		s.Min, s.Mean, s.P50, s.P90, s.P99, s.P999, s.Max)
+}
+
+// MsgReport is the report of the messages of one MsgID (一个MsgID的消息的报告)
+type MsgReport struct {
+	MsgID    uint32
+	Requests uint64
+	Errors   uint64
+	Latency  LatencyStats
+
+	latencies []time.Duration
+}
+
+// Report is the result of a run, every message sent counts as a request, failing if it cannot be written or its
+// reply does not arrive in time (压测的结果，每条发送的消息计为一个请求，无法写入或未及时收到回复时计为失败)
+type Report struct {
+	// Duration is the length of the run, the ramp-up included (压测的时长，包括爬坡)
+	Duration time.Duration
+	// Dials and DialErrors count the connections opened and failed (成功与失败的建立连接次数)
+	Dials, DialErrors uint64
+	Requests, Errors  uint64
+	Latency           LatencyStats
+	// Messages are the reports by MsgID, in the order of the MsgIDs (按MsgID分类的报告，按MsgID排序)
+	Messages []*MsgReport
+
+	mu        sync.Mutex
+	byMsgID   map[uint32]*MsgReport
+	latencies []time.Duration
+}
+
+func newReport() *Report {
+	return &Report{byMsgID: make(map[uint32]*MsgReport)}
+}
+
+// dialed counts a connection opened, or failed with err (统计一次建立连接，err不为nil表示失败)
+func (r *Report) dialed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.DialErrors++
+		return
+	}
+	r.Dials++
+}
+
+// record counts a request of msgID, the latency of a replied one is kept (统计msgID的一个请求，记录得到回复的请求的延迟)
+func (r *Report) record(msgID uint32, latency time.Duration, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := r.byMsgID[msgID]
+	if m == nil {
+		m = &MsgReport{MsgID: msgID}
+		r.byMsgID[msgID] = m
+	}
+	r.Requests++
+	m.Requests++
+	switch {
+	case !ok:
+		r.Errors++
+		m.Errors++
+	case latency > 0:
+		r.latencies = append(r.latencies, latency)
+		m.latencies = append(m.latencies, latency)
+	}
+}
+
+// finish computes the statistics once the run is over (压测结束后计算统计数据)
+func (r *Report) finish(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Duration = d
+	r.Latency = newLatencyStats(r.latencies)
+	for _, m := range r.byMsgID {
+		m.Latency = newLatencyStats(m.latencies)
+		r.Messages = append(r.Messages, m)
+	}
+	sort.Slice(r.Messages, func(i, j int) bool { return r.Messages[i].MsgID < r.Messages[j].MsgID })
+}
+
+// ErrorRate returns the share of the requests failed (返回失败请求所占的比例)
+func (r *Report) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// Throughput returns the requests succeeded per second (返回每秒成功的请求数)
+func (r *Report) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Requests-r.Errors) / r.Duration.Seconds()
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "duration %v, %d connections (%d failed), %d requests, %.1f/s, errors %.2f%%\n",
+		r.Duration, r.Dials, r.DialErrors, r.Requests, r.Throughput(), 100*r.ErrorRate())
+	fmt.Fprintf(&b, "latency %v\n", r.Latency)
+	for _, m := range r.Messages {
+		fmt.Fprintf(&b, "  msgID %d: %d requests, %d errors, latency %v\n", m.MsgID, m.Requests, m.Errors, m.Latency)
+	}
+	return b.String()
+}
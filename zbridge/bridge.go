@@ -0,0 +1,282 @@
+// Package zbridge bridges a server with an external message queue: selected inbound messages are published to
+// the queue, e.g. for the analytics, and the commands consumed from the queue are sent to the connections or the
+// groups, both with at-least-once delivery. Kafka, through the Confluent REST Proxy, is the only broker shipped,
+// there is no RabbitMQ or NATS JetStream queue in the package, a broker without a Queue of its own plugs in by
+// implementing Queue.
+//
+//	queue, _ := zbridge.NewKafkaQueue("http://127.0.0.1:8082", "zinx")
+//	b := zbridge.New(s, queue, zbridge.WithInbound("game.chat", 100, 101), zbridge.WithOutbound("game.commands"))
+//	s.AddInterceptor(b)
+//	_ = b.Start()
+//	defer b.Stop()
+//
+// (将服务与外部消息队列桥接：选定的收到消息被发布到队列，例如用于数据分析，从队列消费的命令被发送给连接或分组，两个方向均为至少一次投递。
+// 本包仅提供通过Confluent REST Proxy访问的Kafka，不包含RabbitMQ或NATS JetStream的队列，没有对应Queue的消息中间件实现Queue即可接入)
+package zbridge
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// retryDelay is how long the publisher waits before publishing again a message the queue refused
+// (队列拒绝消息后发布者再次发布前的等待时长)
+var retryDelay = 500 * time.Millisecond
+
+// Stats are the counters of a Bridge (Bridge的计数)
+type Stats struct {
+	Published   uint64 // The inbound messages published (已发布的收到消息)
+	Dropped     uint64 // The inbound messages dropped, buffer full or bridge stopped (被丢弃的收到消息，缓冲区已满或桥接已停止)
+	Delivered   uint64 // The commands sent (已发送的命令)
+	Redelivered uint64 // The commands to be delivered again, the connection was busy (将被再次投递的命令，连接繁忙)
+	Discarded   uint64 // The commands which cannot be delivered, e.g. undecodable or to a closed connection (无法投递的命令，例如无法解码或发往已关闭的连接)
+	DeadLetters uint64 // The commands given up after the redeliveries, see WithRedelivery (重新投递次数用尽后放弃的命令，见WithRedelivery)
+}
+
+type record struct {
+	topic   string
+	key     string
+	payload []byte
+}
+
+// Bridge is the bridge of a server, it is an interceptor to add to the server
+// (服务的桥接，是需要添加到服务的拦截器)
+type Bridge struct {
+	stats  Stats // First for the 64-bit atomic operations (放在首位以满足64位原子操作的对齐要求)
+	server ziface.IServer
+	queue  Queue
+	opts   *options
+	buffer chan *record
+
+	mu      sync.Mutex
+	started bool
+	stop    chan struct{}
+	cancels []func()
+	done    chan struct{}
+}
+
+// New creates the bridge of s with queue (使用queue创建s的桥接)
+func New(s ziface.IServer, queue Queue, opts ...Option) *Bridge {
+	o := newOptions(opts)
+	return &Bridge{
+		server: s,
+		queue:  queue,
+		opts:   o,
+		buffer: make(chan *record, o.buffer),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start starts publishing and consuming (开始发布与消费)
+func (b *Bridge) Start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.started {
+		return nil
+	}
+	for _, topic := range b.opts.outbound {
+		topic := topic
+		cancel, err := b.queue.Consume(topic, b.handler(topic))
+		if err != nil {
+			for _, cancel := range b.cancels {
+				cancel()
+			}
+			b.cancels = nil
+			return err
+		}
+		b.cancels = append(b.cancels, cancel)
+	}
+	b.started = true
+	b.done = make(chan struct{})
+	go b.publish(b.done)
+	return nil
+}
+
+// Stop stops consuming, and publishes the buffered inbound messages once more before returning, the ones still
+// refused are dropped (停止消费，并在返回前再尝试发布一次缓冲的收到消息，仍被拒绝的消息被丢弃)
+func (b *Bridge) Stop() {
+	b.mu.Lock()
+	if !b.started || isClosed(b.stop) {
+		b.mu.Unlock()
+		return
+	}
+	close(b.stop)
+	cancels := b.cancels
+	b.cancels = nil
+	b.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	<-b.done
+}
+
+// Stats returns the counters of the bridge (返回桥接的计数)
+func (b *Bridge) Stats() Stats {
+	return Stats{
+		Published:   atomic.LoadUint64(&b.stats.Published),
+		Dropped:     atomic.LoadUint64(&b.stats.Dropped),
+		Delivered:   atomic.LoadUint64(&b.stats.Delivered),
+		Redelivered: atomic.LoadUint64(&b.stats.Redelivered),
+		Discarded:   atomic.LoadUint64(&b.stats.Discarded),
+		DeadLetters: atomic.LoadUint64(&b.stats.DeadLetters),
+	}
+}
+
+// Intercept buffers the selected inbound messages for the queue and passes every message on
+// (为队列缓冲选定的收到消息，并将所有消息继续传递)
+func (b *Bridge) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetConnection() == nil {
+		return chain.Proceed(chain.Request())
+	}
+	if topic, ok := b.opts.inbound[request.GetMsgID()]; ok {
+		b.enqueue(topic, request.GetConnection(), request.GetMsgID(), request.GetData())
+	}
+	return chain.Proceed(chain.Request())
+}
+
+func (b *Bridge) enqueue(topic string, conn ziface.IConnection, msgID uint32, data []byte) {
+	key, payload, err := b.opts.encode(conn, msgID, data)
+	if err != nil {
+		zlog.Ins().ErrorF("zbridge: encode msgID = %d of connID = %d err: %v", msgID, conn.GetConnID(), err)
+		atomic.AddUint64(&b.stats.Dropped, 1)
+		return
+	}
+	// The encoder may have kept data, which is reused by the request pool (编码结果可能引用了会被请求池复用的data)
+	rec := &record{topic: topic, key: key, payload: append([]byte(nil), payload...)}
+	if b.opts.backpressure == Drop {
+		select {
+		case b.buffer <- rec:
+		default:
+			atomic.AddUint64(&b.stats.Dropped, 1)
+		}
+		return
+	}
+	select {
+	case b.buffer <- rec:
+	case <-b.stop:
+		atomic.AddUint64(&b.stats.Dropped, 1)
+	}
+}
+
+// publish publishes the buffered messages in order, retrying each until the queue accepts it or the bridge stops
+// (按顺序发布缓冲的消息，每条消息重试直到队列接受或桥接停止)
+func (b *Bridge) publish(done chan struct{}) {
+	defer close(done)
+	for {
+		var rec *record
+		select {
+		case rec = <-b.buffer:
+		case <-b.stop:
+			b.drain()
+			return
+		}
+		for {
+			err := b.queue.Publish(rec.topic, rec.key, rec.payload)
+			if err == nil {
+				atomic.AddUint64(&b.stats.Published, 1)
+				break
+			}
+			zlog.Ins().ErrorF("zbridge: publish to %s err: %v", rec.topic, err)
+			if errors.Is(err, ErrQueueClosed) || !sleep(b.stop, retryDelay) {
+				atomic.AddUint64(&b.stats.Dropped, 1)
+				break
+			}
+		}
+	}
+}
+
+// drain publishes the buffered messages once each (将缓冲的消息各发布一次)
+func (b *Bridge) drain() {
+	for {
+		select {
+		case rec := <-b.buffer:
+			if err := b.queue.Publish(rec.topic, rec.key, rec.payload); err != nil {
+				zlog.Ins().ErrorF("zbridge: publish to %s on stop err: %v", rec.topic, err)
+				atomic.AddUint64(&b.stats.Dropped, 1)
+				continue
+			}
+			atomic.AddUint64(&b.stats.Published, 1)
+		default:
+			return
+		}
+	}
+}
+
+// handler returns the handler of the commands of topic, a command failing more than the redeliveries in a row is
+// handed to the dead letter hook instead of being delivered again, so that it cannot hold the topic forever
+// (返回topic的命令处理函数，连续失败超过重新投递次数的命令交给死信回调而不再被重新投递，以免其永远阻塞topic)
+func (b *Bridge) handler(topic string) func(payload []byte) error {
+	// The queues call the handler of a topic one message at a time, a failed message first
+	// (队列逐条调用topic的处理函数，失败的消息优先)
+	var last []byte
+	var failures int
+	return func(payload []byte) error {
+		err := b.deliver(topic, payload)
+		if err == nil {
+			last, failures = nil, 0
+			return nil
+		}
+		if failures == 0 || !bytes.Equal(payload, last) {
+			last, failures = append(last[:0], payload...), 0
+		}
+		failures++
+		if b.opts.redeliveries < 0 || failures <= b.opts.redeliveries {
+			atomic.AddUint64(&b.stats.Redelivered, 1)
+			return err
+		}
+		last, failures = nil, 0
+		atomic.AddUint64(&b.stats.DeadLetters, 1)
+		zlog.Ins().ErrorF("zbridge: command of %s given up after %d redeliveries: %v", topic, b.opts.redeliveries, err)
+		if b.opts.deadLetter != nil {
+			b.opts.deadLetter(topic, payload, err)
+		}
+		return nil
+	}
+}
+
+// deliver sends a consumed command, the error asks the queue to deliver it again, which only helps while the
+// connection is busy (发送消费的命令，返回错误会使队列再次投递，仅在连接繁忙时有意义)
+func (b *Bridge) deliver(topic string, payload []byte) error {
+	command, err := b.opts.decode(payload)
+	if err != nil {
+		zlog.Ins().ErrorF("zbridge: decode command of %s err: %v", topic, err)
+		atomic.AddUint64(&b.stats.Discarded, 1)
+		return nil
+	}
+
+	switch {
+	case command.ConnID != 0:
+		conn, err := b.server.GetConnMgr().Get(command.ConnID)
+		if err != nil {
+			zlog.Ins().DebugF("zbridge: command of %s to connID = %d: %v", topic, command.ConnID, err)
+			atomic.AddUint64(&b.stats.Discarded, 1)
+			return nil
+		}
+		if err := conn.SendBuffMsg(command.MsgID, command.Data); err != nil {
+			if conn.IsAlive() {
+				return err
+			}
+			atomic.AddUint64(&b.stats.Discarded, 1)
+			return nil
+		}
+	case command.Group != "":
+		b.server.GetPubSub().Publish(command.Group, command.MsgID, command.Data)
+	default:
+		_ = b.server.GetConnMgr().Range2(func(_ string, conn ziface.IConnection, _ interface{}) error {
+			if err := conn.SendBuffMsg(command.MsgID, command.Data); err != nil {
+				zlog.Ins().DebugF("zbridge: broadcast msgID = %d to connID = %d err: %v", command.MsgID, conn.GetConnID(), err)
+			}
+			return nil
+		}, nil)
+	}
+	atomic.AddUint64(&b.stats.Delivered, 1)
+	return nil
+}
@@ -0,0 +1,225 @@
+package zbridge
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type testConn struct {
+	ziface.IConnection
+	id uint64
+
+	mu    sync.Mutex
+	busy  int
+	sent  []uint32
+	alive bool
+}
+
+func (c *testConn) GetConnID() uint64 { return c.id }
+
+func (c *testConn) IsAlive() bool { return c.alive }
+
+func (c *testConn) SendBuffMsg(msgID uint32, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.busy > 0 {
+		c.busy--
+		return errors.New("send buffer full")
+	}
+	c.sent = append(c.sent, msgID)
+	return nil
+}
+
+func (c *testConn) received() []uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]uint32(nil), c.sent...)
+}
+
+type testConnMgr struct {
+	ziface.IConnManager
+	conns map[uint64]ziface.IConnection
+}
+
+func (m *testConnMgr) Get(connID uint64) (ziface.IConnection, error) {
+	if conn, ok := m.conns[connID]; ok {
+		return conn, nil
+	}
+	return nil, errors.New("connection not found")
+}
+
+func (m *testConnMgr) Range2(f func(string, ziface.IConnection, interface{}) error, args interface{}) error {
+	for _, conn := range m.conns {
+		_ = f("", conn, args)
+	}
+	return nil
+}
+
+type testPubSub struct {
+	ziface.IPubSub
+	mu     sync.Mutex
+	groups []string
+}
+
+func (p *testPubSub) Publish(topic string, msgID uint32, data []byte) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.groups = append(p.groups, topic)
+	return 1
+}
+
+type testServer struct {
+	ziface.IServer
+	connMgr *testConnMgr
+	pubSub  *testPubSub
+}
+
+func (s *testServer) GetConnMgr() ziface.IConnManager { return s.connMgr }
+
+func (s *testServer) GetPubSub() ziface.IPubSub { return s.pubSub }
+
+// testChain is the end of the interceptor chain (拦截器链的末端)
+type testChain struct {
+	ziface.IChain
+	request ziface.IRequest
+	passed  int
+}
+
+func (c *testChain) Request() ziface.IcReq { return c.request }
+
+func (c *testChain) Proceed(ziface.IcReq) ziface.IcResp {
+	c.passed++
+	return nil
+}
+
+func inbound(b *Bridge, conn ziface.IConnection, msgID uint32, data string) *testChain {
+	chain := &testChain{request: znet.NewRequest(conn, zpack.NewMsgPackage(msgID, []byte(data)))}
+	b.Intercept(chain)
+	return chain
+}
+
+func TestBridgeInbound(t *testing.T) {
+	queue := NewMemoryQueue()
+	defer queue.Close()
+	conn := &testConn{id: 7, alive: true}
+	b := New(&testServer{}, queue, WithInbound("chat", 100, 101))
+	assert.Nil(t, b.Start())
+
+	assert.Equal(t, 1, inbound(b, conn, 100, "hi").passed)
+	assert.Equal(t, 1, inbound(b, conn, 102, "skipped").passed)
+	inbound(b, conn, 101, "bye")
+	b.Stop()
+	assert.Equal(t, uint64(2), b.Stats().Published)
+
+	var records []Record
+	cancel, _ := queue.Consume("chat", func(data []byte) error {
+		var record Record
+		assert.Nil(t, json.Unmarshal(data, &record))
+		records = append(records, record)
+		return nil
+	})
+	assert.Eventually(t, func() bool { return queue.Len("chat") == 0 }, time.Second, 10*time.Millisecond)
+	cancel()
+	assert.Equal(t, []Record{{ConnID: 7, MsgID: 100, Data: []byte("hi")}, {ConnID: 7, MsgID: 101, Data: []byte("bye")}}, records)
+}
+
+// A failing queue is retried, the full buffer drops with Drop (失败的队列会被重试，使用Drop时缓冲区满则丢弃)
+func TestBridgeBackpressure(t *testing.T) {
+	retryDelay = 10 * time.Millisecond
+	queue := &flakyQueue{MemoryQueue: NewMemoryQueue(), failures: 3}
+	defer queue.Close()
+	conn := &testConn{id: 1, alive: true}
+	b := New(&testServer{}, queue, WithInbound("events", 1), WithBuffer(1, Drop))
+
+	// Not started yet, the second one does not fit (尚未启动，第二条消息放不下)
+	inbound(b, conn, 1, "a")
+	inbound(b, conn, 1, "b")
+	assert.Equal(t, uint64(1), b.Stats().Dropped)
+
+	assert.Nil(t, b.Start())
+	assert.Eventually(t, func() bool { return b.Stats().Published == 1 }, time.Second, 10*time.Millisecond)
+	b.Stop()
+	assert.Equal(t, 1, queue.Len("events"))
+}
+
+type flakyQueue struct {
+	*MemoryQueue
+	mu       sync.Mutex
+	failures int
+}
+
+func (q *flakyQueue) Publish(topic, key string, data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.failures > 0 {
+		q.failures--
+		return errors.New("broker unavailable")
+	}
+	return q.MemoryQueue.Publish(topic, key, data)
+}
+
+func TestBridgeOutbound(t *testing.T) {
+	redeliveryDelay = 10 * time.Millisecond
+	queue := NewMemoryQueue()
+	defer queue.Close()
+	busy, closed := &testConn{id: 1, alive: true, busy: 2}, &testConn{id: 2, busy: 1}
+	s := &testServer{
+		connMgr: &testConnMgr{conns: map[uint64]ziface.IConnection{1: busy, 2: closed}},
+		pubSub:  &testPubSub{},
+	}
+	b := New(s, queue, WithOutbound("commands"))
+	assert.Nil(t, b.Start())
+	defer b.Stop()
+
+	publish := func(command Command) {
+		data, _ := json.Marshal(&command)
+		assert.Nil(t, queue.Publish("commands", "", data))
+	}
+	publish(Command{ConnID: 1, MsgID: 10})     // Redelivered twice (被再次投递两次)
+	publish(Command{ConnID: 2, MsgID: 11})     // Closed (已关闭)
+	publish(Command{ConnID: 3, MsgID: 12})     // Unknown (不存在)
+	publish(Command{Group: "room", MsgID: 13}) // To the group (发给分组)
+	publish(Command{MsgID: 14})                // To everyone (发给所有连接)
+	assert.Nil(t, queue.Publish("commands", "", []byte("not json")))
+
+	assert.Eventually(t, func() bool {
+		stats := b.Stats()
+		return stats.Delivered == 3 && stats.Discarded == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(2), b.Stats().Redelivered)
+	assert.Equal(t, []uint32{10, 14}, busy.received())
+	assert.Equal(t, []string{"room"}, s.pubSub.groups)
+}
+
+// A command failing more than the redeliveries goes to the dead letters, the next ones are delivered
+// (失败超过重新投递次数的命令进入死信，后续命令照常投递)
+func TestBridgeDeadLetter(t *testing.T) {
+	redeliveryDelay = 10 * time.Millisecond
+	queue := NewMemoryQueue()
+	defer queue.Close()
+	busy := &testConn{id: 1, alive: true, busy: 5}
+	s := &testServer{connMgr: &testConnMgr{conns: map[uint64]ziface.IConnection{1: busy}}}
+	b := New(s, queue, WithOutbound("commands"), WithRedelivery(2, DeadLetterTo(queue, "commands.dead")))
+	assert.Nil(t, b.Start())
+	defer b.Stop()
+
+	for _, msgID := range []uint32{10, 11} {
+		data, _ := json.Marshal(&Command{ConnID: 1, MsgID: msgID})
+		assert.Nil(t, queue.Publish("commands", "", data))
+	}
+	assert.Eventually(t, func() bool { return b.Stats().Delivered == 1 }, time.Second, 10*time.Millisecond)
+	stats := b.Stats()
+	assert.Equal(t, uint64(1), stats.DeadLetters)
+	// 10 failed 3 times, 11 twice (10失败3次，11失败2次)
+	assert.Equal(t, uint64(4), stats.Redelivered)
+	assert.Equal(t, []uint32{11}, busy.received())
+	assert.Equal(t, 1, queue.Len("commands.dead"))
+}
@@ -0,0 +1,221 @@
+package zbridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	kafkaBinary = "application/vnd.kafka.binary.v2+json"
+	kafkaJSON   = "application/vnd.kafka.v2+json"
+)
+
+// KafkaQueue is a Queue on Kafka through the Confluent REST Proxy v2, the consumers of a topic form the consumer
+// group and commit the offset of a message once handled (通过Confluent REST Proxy v2访问Kafka的Queue，topic的消费者组成
+// 消费者组，并在消息处理完成后提交其偏移量)
+type KafkaQueue struct {
+	addr   string
+	group  string
+	client *http.Client
+	seq    uint64
+
+	mu      sync.Mutex
+	cancels map[uint64]func()
+	closed  bool
+}
+
+// NewKafkaQueue creates the queue of the REST Proxy at rawURL, e.g. "http://127.0.0.1:8082", group is the consumer
+// group of the consumers (创建位于rawURL的REST Proxy的队列，例如"http://127.0.0.1:8082"，group为消费者所属的消费者组)
+func NewKafkaQueue(rawURL, group string) (*KafkaQueue, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("zbridge: unsupported kafka rest proxy url %q", rawURL)
+	}
+	return &KafkaQueue{
+		addr:    strings.TrimSuffix(rawURL, "/"),
+		group:   group,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		cancels: make(map[uint64]func()),
+	}, nil
+}
+
+func (q *KafkaQueue) do(method, url, contentType string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", contentType)
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("zbridge: %s %s: %s %s", method, url, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type kafkaRecord struct {
+	Key       []byte `json:"key,omitempty"`
+	Value     []byte `json:"value"`
+	Topic     string `json:"topic,omitempty"`
+	Partition int    `json:"partition,omitempty"`
+	Offset    int64  `json:"offset,omitempty"`
+}
+
+func (q *KafkaQueue) Publish(topic, key string, data []byte) error {
+	record := kafkaRecord{Value: data}
+	if key != "" {
+		record.Key = []byte(key)
+	}
+	var resp struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	body := map[string]interface{}{"records": []kafkaRecord{record}}
+	if err := q.do(http.MethodPost, q.addr+"/topics/"+url.PathEscape(topic), kafkaBinary, body, &resp); err != nil {
+		return err
+	}
+	for _, offset := range resp.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("zbridge: kafka publish to %s: %d %s", topic, *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
+
+func (q *KafkaQueue) Consume(topic string, handler func(data []byte) error) (func(), error) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil, ErrQueueClosed
+	}
+	q.mu.Unlock()
+
+	id := atomic.AddUint64(&q.seq, 1)
+	var instance struct {
+		BaseURI string `json:"base_uri"`
+	}
+	body := map[string]string{
+		"name":               q.group + "-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(id, 10),
+		"format":             "binary",
+		"auto.offset.reset":  "earliest",
+		"auto.commit.enable": "false",
+	}
+	if err := q.do(http.MethodPost, q.addr+"/consumers/"+url.PathEscape(q.group), kafkaJSON, body, &instance); err != nil {
+		return nil, err
+	}
+	subscription := map[string][]string{"topics": {topic}}
+	if err := q.do(http.MethodPost, instance.BaseURI+"/subscription", kafkaJSON, subscription, nil); err != nil {
+		_ = q.do(http.MethodDelete, instance.BaseURI, kafkaJSON, nil, nil)
+		return nil, err
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go q.consume(instance.BaseURI, handler, stop, done)
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+			_ = q.do(http.MethodDelete, instance.BaseURI, kafkaJSON, nil, nil)
+			q.mu.Lock()
+			delete(q.cancels, id)
+			q.mu.Unlock()
+		})
+	}
+	q.mu.Lock()
+	q.cancels[id] = cancel
+	q.mu.Unlock()
+	return cancel, nil
+}
+
+// consume polls the records, handles them in order and commits their offsets, a failing record is handled again
+// until it succeeds to keep the order (拉取记录，按顺序处理并提交偏移量，失败的记录会被重复处理直到成功，以保持顺序)
+func (q *KafkaQueue) consume(base string, handler func(data []byte) error, stop, done chan struct{}) {
+	defer close(done)
+	for !isClosed(stop) {
+		var records []kafkaRecord
+		if err := q.do(http.MethodGet, base+"/records", kafkaBinary, nil, &records); err != nil {
+			zlog.Ins().ErrorF("zbridge: kafka poll err: %v", err)
+			if !sleep(stop, time.Second) {
+				return
+			}
+			continue
+		}
+		// The proxy waits for the records up to its request timeout, the pause guards the proxies answering at once
+		// (代理在其请求超时时间内等待记录，暂停用于防止立即应答的代理造成空转)
+		if len(records) == 0 && !sleep(stop, 50*time.Millisecond) {
+			return
+		}
+		for _, record := range records {
+			for handler(record.Value) != nil {
+				if !sleep(stop, redeliveryDelay) {
+					return
+				}
+			}
+			offset := map[string]interface{}{"topic": record.Topic, "partition": record.Partition, "offset": record.Offset}
+			body := map[string]interface{}{"offsets": []interface{}{offset}}
+			if err := q.do(http.MethodPost, base+"/offsets", kafkaJSON, body, nil); err != nil {
+				zlog.Ins().ErrorF("zbridge: kafka commit %s[%d]@%d err: %v", record.Topic, record.Partition, record.Offset, err)
+			}
+		}
+	}
+}
+
+func (q *KafkaQueue) Close() error {
+	q.mu.Lock()
+	q.closed = true
+	cancels := make([]func(), 0, len(q.cancels))
+	for _, cancel := range q.cancels {
+		cancels = append(cancels, cancel)
+	}
+	q.mu.Unlock()
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return nil
+}
+
+// sleep waits for d and reports false if stop was closed meanwhile (等待d，期间stop被关闭时返回false)
+func sleep(stop chan struct{}, d time.Duration) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
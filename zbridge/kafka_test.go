@@ -0,0 +1,122 @@
+package zbridge
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKafka is a REST Proxy with one partition per topic, the records are delivered again from the committed
+// offset to every new consumer instance (每个topic只有一个分区的REST Proxy，新的消费者实例从已提交的偏移量开始重新获取记录)
+func fakeKafka(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	topics := make(map[string][]kafkaRecord)
+	committed := make(map[string]int64)
+	polled := make(map[string]int64) // instance -> next offset
+	subscribed := make(map[string]string)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case parts[0] == "topics" && r.Method == http.MethodPost:
+			assert.Equal(t, kafkaBinary, r.Header.Get("Content-Type"))
+			var body struct {
+				Records []kafkaRecord `json:"records"`
+			}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			for _, record := range body.Records {
+				record.Topic, record.Offset = parts[1], int64(len(topics[parts[1]]))
+				topics[parts[1]] = append(topics[parts[1]], record)
+			}
+			_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":0}]}`))
+		case parts[0] == "consumers" && len(parts) == 2:
+			var body map[string]string
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "false", body["auto.commit.enable"])
+			name := body["name"]
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"instance_id": name,
+				"base_uri":    server.URL + "/consumers/" + parts[1] + "/instances/" + name,
+			})
+		case len(parts) == 4 && r.Method == http.MethodDelete:
+			delete(polled, parts[3])
+		case len(parts) == 5 && parts[4] == "subscription":
+			var body map[string][]string
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			subscribed[parts[3]] = body["topics"][0]
+			polled[parts[3]] = committed[body["topics"][0]]
+		case len(parts) == 5 && parts[4] == "records":
+			topic := subscribed[parts[3]]
+			records := topics[topic][polled[parts[3]]:]
+			polled[parts[3]] += int64(len(records))
+			_ = json.NewEncoder(w).Encode(records)
+		case len(parts) == 5 && parts[4] == "offsets":
+			var body struct {
+				Offsets []kafkaRecord `json:"offsets"`
+			}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			for _, offset := range body.Offsets {
+				committed[offset.Topic] = offset.Offset + 1
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+func TestKafkaQueue(t *testing.T) {
+	redeliveryDelay = 10 * time.Millisecond
+	server := fakeKafka(t)
+	defer server.Close()
+	q, err := NewKafkaQueue(server.URL, "zinx")
+	assert.Nil(t, err)
+	defer q.Close()
+
+	for _, data := range []string{"a", "b", "c"} {
+		assert.Nil(t, q.Publish("events", "conn-1", []byte(data)))
+	}
+
+	// b fails once, then the consumer stops after b (b失败一次，消费者在处理b之后停止)
+	var mu sync.Mutex
+	var got []string
+	failed := false
+	cancel, err := q.Consume("events", func(data []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if string(data) == "b" && !failed {
+			failed = true
+			return errors.New("busy")
+		}
+		got = append(got, string(data))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 3
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	assert.Equal(t, []string{"a", "b", "c"}, got)
+
+	// A new consumer starts after the committed offsets (新的消费者从已提交的偏移量之后开始)
+	assert.Nil(t, q.Publish("events", "", []byte("d")))
+	next := make(chan string, 4)
+	cancel, err = q.Consume("events", func(data []byte) error {
+		next <- string(data)
+		return nil
+	})
+	assert.Nil(t, err)
+	defer cancel()
+	assert.Equal(t, "d", <-next)
+}
@@ -0,0 +1,155 @@
+package zbridge
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// Backpressure is what the bridge does with an inbound message while its buffer is full
+// (缓冲区已满时桥接对收到的消息的处理方式)
+type Backpressure int
+
+const (
+	// Block blocks the reader of the connection until the buffer has room, the client is slowed down
+	// (阻塞连接的读取直到缓冲区有空间，客户端会被减速)
+	Block Backpressure = iota
+	// Drop drops the message and counts it in Stats.Dropped (丢弃消息并计入Stats.Dropped)
+	Drop
+)
+
+// Record is the default encoding of an inbound message published to the queue, in JSON
+// (发布到队列的收到消息的默认编码，JSON格式)
+type Record struct {
+	ConnID uint64 `json:"conn_id"`
+	MsgID  uint32 `json:"msg_id"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+// Command is a message consumed from the queue, sent to ConnID, to the subscribers of Group (see ziface.IPubSub),
+// or to every connection when both are empty, its default encoding is JSON
+// (从队列消费的消息，发送给ConnID、Group的订阅者(见ziface.IPubSub)，两者都为空时发送给所有连接，默认编码为JSON)
+type Command struct {
+	ConnID uint64 `json:"conn_id,omitempty"`
+	Group  string `json:"group,omitempty"`
+	MsgID  uint32 `json:"msg_id"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+// EncodeFunc encodes an inbound message for the queue, key keeps the order of the messages sharing it
+// (为队列编码收到的消息，key相同的消息保持顺序)
+type EncodeFunc func(conn ziface.IConnection, msgID uint32, data []byte) (key string, payload []byte, err error)
+
+// DecodeFunc decodes a message consumed from the queue (解码从队列消费的消息)
+type DecodeFunc func(payload []byte) (*Command, error)
+
+// DeadLetterFunc is called with a command of topic given up after the redeliveries and the error of its last
+// delivery (以重新投递次数用尽后放弃的topic命令及其最后一次投递的错误调用)
+type DeadLetterFunc func(topic string, payload []byte, err error)
+
+type options struct {
+	inbound      map[uint32]string
+	outbound     []string
+	buffer       int
+	backpressure Backpressure
+	encode       EncodeFunc
+	decode       DecodeFunc
+	redeliveries int
+	deadLetter   DeadLetterFunc
+}
+
+// Option configures a Bridge (Bridge的配置项)
+type Option func(o *options)
+
+// WithInbound publishes the inbound messages of msgIDs to topic, they are still routed as usual
+// (将msgIDs的收到消息发布到topic，这些消息仍照常路由)
+func WithInbound(topic string, msgIDs ...uint32) Option {
+	return func(o *options) {
+		for _, msgID := range msgIDs {
+			o.inbound[msgID] = topic
+		}
+	}
+}
+
+// WithOutbound consumes topics and sends their commands to the connections (消费topics并将其中的命令发送给连接)
+func WithOutbound(topics ...string) Option {
+	return func(o *options) {
+		o.outbound = append(o.outbound, topics...)
+	}
+}
+
+// WithBuffer sets how many inbound messages wait for being published, 1024 by default, and what happens once
+// they are that many, Block by default (设置等待发布的收到消息数量上限，默认1024，以及达到上限时的处理方式，默认Block)
+func WithBuffer(size int, backpressure Backpressure) Option {
+	return func(o *options) {
+		o.buffer = size
+		o.backpressure = backpressure
+	}
+}
+
+// WithEncoder sets how the inbound messages are encoded, a Record in JSON keyed by the ConnID by default
+// (设置收到消息的编码方式，默认为以ConnID为key的JSON格式Record)
+func WithEncoder(encode EncodeFunc) Option {
+	return func(o *options) {
+		o.encode = encode
+	}
+}
+
+// WithDecoder sets how the consumed messages are decoded, a Command in JSON by default
+// (设置消费消息的解码方式，默认为JSON格式的Command)
+func WithDecoder(decode DecodeFunc) Option {
+	return func(o *options) {
+		o.decode = decode
+	}
+}
+
+// WithRedelivery sets how many times in a row a command is delivered again while the connection is busy, 10 by
+// default, a negative max redelivers it until it succeeds. Then the command is counted in Stats.DeadLetters and
+// given to deadLetter if not nil, e.g. DeadLetterTo.
+// (设置连接繁忙时命令连续被重新投递的次数，默认10，max为负数时重新投递直到成功。之后命令计入Stats.DeadLetters，
+// deadLetter不为nil时交给deadLetter，例如DeadLetterTo)
+func WithRedelivery(max int, deadLetter DeadLetterFunc) Option {
+	return func(o *options) {
+		o.redeliveries = max
+		o.deadLetter = deadLetter
+	}
+}
+
+// DeadLetterTo returns a DeadLetterFunc which publishes the commands given up to topic of queue
+// (返回一个将放弃的命令发布到queue的topic的DeadLetterFunc)
+func DeadLetterTo(queue Queue, topic string) DeadLetterFunc {
+	return func(from string, payload []byte, err error) {
+		if err := queue.Publish(topic, "", payload); err != nil {
+			zlog.Ins().ErrorF("zbridge: publish dead letter of %s to %s err: %v", from, topic, err)
+		}
+	}
+}
+
+func encodeRecord(conn ziface.IConnection, msgID uint32, data []byte) (string, []byte, error) {
+	payload, err := json.Marshal(&Record{ConnID: conn.GetConnID(), MsgID: msgID, Data: data})
+	return strconv.FormatUint(conn.GetConnID(), 10), payload, err
+}
+
+func decodeCommand(payload []byte) (*Command, error) {
+	command := &Command{}
+	return command, json.Unmarshal(payload, command)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		inbound:      make(map[uint32]string),
+		buffer:       1024,
+		redeliveries: 10,
+		encode:       encodeRecord,
+		decode:       decodeCommand,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.buffer <= 0 {
+		panic("zbridge: buffer size must be positive")
+	}
+	return o
+}
@@ -0,0 +1,140 @@
+package zbridge
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// ErrQueueClosed is returned by the queues once closed (队列关闭后返回)
+var ErrQueueClosed = errors.New("zbridge: queue closed")
+
+// redeliveryDelay is how long the queues wait before delivering again a message whose handler failed
+// (处理函数失败后队列再次投递消息前的等待时长)
+var redeliveryDelay = 200 * time.Millisecond
+
+// Queue is an external message queue with at-least-once delivery. NewKafkaQueue talks to Kafka through the
+// Confluent REST Proxy and MemoryQueue stays within the process, no RabbitMQ or NATS JetStream client is shipped,
+// these and the other brokers plug in by implementing it
+// (至少一次投递的外部消息队列。NewKafkaQueue通过Confluent REST Proxy访问Kafka，MemoryQueue位于进程内，本包不提供RabbitMQ
+// 或NATS JetStream的客户端，它们及其他消息中间件实现该接口即可接入)
+type Queue interface {
+	// Publish returns once the broker accepted data, key keeps the order of the messages sharing it
+	// (在消息中间件接受data后返回，key相同的消息保持顺序)
+	Publish(topic, key string, data []byte) error
+	// Consume calls handler with the messages of topic one at a time until cancel is called, a message whose
+	// handler returns an error is delivered again before the next ones, the handler bounds the redeliveries by
+	// returning nil, see WithRedelivery
+	// (逐条以topic的消息调用handler直到调用cancel，处理函数返回错误的消息会在后续消息之前被再次投递，
+	// 处理函数通过返回nil限制重新投递次数，见WithRedelivery)
+	Consume(topic string, handler func(data []byte) error) (cancel func(), err error)
+	// Close releases the connections of the queue (释放队列的连接)
+	Close() error
+}
+
+// MemoryQueue is a Queue within the process, for the tests and the development
+// (进程内的Queue，用于测试与开发)
+type MemoryQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	topics  map[string][][]byte
+	closed  bool
+	pending sync.WaitGroup
+}
+
+// NewMemoryQueue creates a MemoryQueue (创建MemoryQueue)
+func NewMemoryQueue() *MemoryQueue {
+	q := &MemoryQueue{topics: make(map[string][][]byte)}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *MemoryQueue) Publish(topic, key string, data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	q.topics[topic] = append(q.topics[topic], append([]byte(nil), data...))
+	q.cond.Broadcast()
+	return nil
+}
+
+// Consume shares the messages of topic among its consumers (在topic的消费者之间分配消息)
+func (q *MemoryQueue) Consume(topic string, handler func(data []byte) error) (func(), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+	stop := make(chan struct{})
+	q.pending.Add(1)
+	go q.consume(topic, handler, stop)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			close(stop)
+			q.cond.Broadcast()
+			q.mu.Unlock()
+		})
+	}, nil
+}
+
+func (q *MemoryQueue) consume(topic string, handler func(data []byte) error, stop chan struct{}) {
+	defer q.pending.Done()
+	for {
+		q.mu.Lock()
+		for len(q.topics[topic]) == 0 && !q.closed && !isClosed(stop) {
+			q.cond.Wait()
+		}
+		if q.closed || isClosed(stop) {
+			q.mu.Unlock()
+			return
+		}
+		data := q.topics[topic][0]
+		q.topics[topic] = q.topics[topic][1:]
+		q.mu.Unlock()
+
+		for handler(data) != nil {
+			zlog.Ins().DebugF("zbridge: message of topic %s is delivered again", topic)
+			select {
+			case <-stop:
+				// Back to the queue for the other consumers (放回队列留给其他消费者)
+				q.mu.Lock()
+				q.topics[topic] = append([][]byte{data}, q.topics[topic]...)
+				q.cond.Broadcast()
+				q.mu.Unlock()
+				return
+			case <-time.After(redeliveryDelay):
+			}
+		}
+	}
+}
+
+// Len returns the number of the messages of topic waiting for a consumer (返回topic中等待消费者的消息数量)
+func (q *MemoryQueue) Len(topic string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.topics[topic])
+}
+
+func (q *MemoryQueue) Close() error {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	q.pending.Wait()
+	return nil
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,308 @@
+// Package zcapture records the decoded messages of a server into a capture file, and replays capture files
+// through a server or any handler, for debugging and for regression testing protocol changes.
+//
+// A capture file starts with the magic "ZCAP" and a uint16 version, followed by the records, every field is little endian:
+//
+//	|  time  | direction | connID | msgID | dataLen | data |
+//	| int64  |   uint8   | uint64 | uint32| uint32  | ...  |
+//
+// (zcapture将服务解码后的消息记录到抓包文件中，并可以将抓包文件通过服务或任意处理函数回放，用于调试和协议修改的回归测试。
+// 抓包文件以魔数"ZCAP"和uint16版本号开头，之后是记录，所有字段都是小端序)
+package zcapture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// Direction of a captured message (被捕获消息的方向)
+type Direction uint8
+
+const (
+	Inbound  Direction = 1 // Received from the peer (从对端收到)
+	Outbound Direction = 2 // Sent to the peer (发送给对端)
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Inbound:
+		return "inbound"
+	case Outbound:
+		return "outbound"
+	}
+	return fmt.Sprintf("Direction(%d)", uint8(d))
+}
+
+const (
+	magic   = "ZCAP"
+	version = 1
+
+	headerLen = 4 + 2
+	recordLen = 8 + 1 + 8 + 4 + 4
+
+	// maxDataLen guards the reader against corrupted files (防止读取损坏的文件)
+	maxDataLen = 64 * 1024 * 1024
+)
+
+// ErrBadCapture is returned when a file is not a capture file (文件不是抓包文件时返回)
+var ErrBadCapture = errors.New("zcapture: not a capture file")
+
+// Record is a captured message (一条被捕获的消息)
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	ConnID    uint64
+	MsgID     uint32
+	Data      []byte
+}
+
+// Filter selects the messages to capture or to replay, the zero Filter selects every message
+// (选择要捕获或回放的消息，零值选择所有消息)
+type Filter struct {
+	Direction Direction // Inbound or Outbound only, 0 for both (只选择Inbound或Outbound，0表示两者)
+	MsgIDs    []uint32  // Only these msgIDs, empty for all (只选择这些msgID，为空表示全部)
+	ConnIDs   []uint64  // Only these connections, empty for all (只选择这些连接，为空表示全部)
+}
+
+// Match reports whether the filter selects the message (返回过滤器是否选择该消息)
+func (f *Filter) Match(direction Direction, connID uint64, msgID uint32) bool {
+	if f.Direction != 0 && f.Direction != direction {
+		return false
+	}
+	if len(f.MsgIDs) > 0 && !containsUint32(f.MsgIDs, msgID) {
+		return false
+	}
+	if len(f.ConnIDs) > 0 && !containsUint64(f.ConnIDs, connID) {
+		return false
+	}
+	return true
+}
+
+func containsUint32(list []uint32, v uint32) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUint64(list []uint64, v uint64) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// flushInterval is how often the recorder writes its buffer out (记录器写出缓冲的间隔)
+const flushInterval = time.Second
+
+// Recorder writes the messages selected by its filter to a capture file, it implements ziface.IRecorder
+// and is installed with IServer.SetRecorder, or by zconf.Config.CaptureFile
+// (Recorder将过滤器选择的消息写入抓包文件，实现了ziface.IRecorder，通过IServer.SetRecorder安装，或由zconf.Config.CaptureFile开启)
+type Recorder struct {
+	mu     sync.Mutex
+	filter Filter
+	w      *bufio.Writer
+	closer io.Closer
+	err    error
+	buf    [recordLen]byte
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRecorder returns a Recorder writing to w, w is closed by Close when it is an io.Closer
+// (返回写入w的Recorder，w实现io.Closer时由Close关闭)
+func NewRecorder(w io.Writer, filter Filter) (*Recorder, error) {
+	r := &Recorder{
+		filter: filter,
+		w:      bufio.NewWriter(w),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if c, ok := w.(io.Closer); ok {
+		r.closer = c
+	}
+	var header [headerLen]byte
+	copy(header[:], magic)
+	binary.LittleEndian.PutUint16(header[4:], version)
+	if _, err := r.w.Write(header[:]); err != nil {
+		return nil, err
+	}
+	go r.flushLoop()
+	return r, nil
+}
+
+// Create creates the capture file path and returns a Recorder writing to it (创建抓包文件path并返回写入它的Recorder)
+func Create(path string, filter Filter) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRecorder(f, filter)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record captures a message when the filter selects it (过滤器选择该消息时捕获它)
+func (r *Recorder) Record(inbound bool, connID uint64, msgID uint32, data []byte) {
+	direction := Outbound
+	if inbound {
+		direction = Inbound
+	}
+	if !r.filter.Match(direction, connID, msgID) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	b := r.buf[:]
+	binary.LittleEndian.PutUint64(b[0:], uint64(time.Now().UnixNano()))
+	b[8] = byte(direction)
+	binary.LittleEndian.PutUint64(b[9:], connID)
+	binary.LittleEndian.PutUint32(b[17:], msgID)
+	binary.LittleEndian.PutUint32(b[21:], uint32(len(data)))
+	if _, err := r.w.Write(b); err != nil {
+		r.fail(err)
+		return
+	}
+	if _, err := r.w.Write(data); err != nil {
+		r.fail(err)
+	}
+}
+
+// fail stops the recording after the first write error (第一次写入错误后停止记录)
+func (r *Recorder) fail(err error) {
+	r.err = err
+	zlog.Ins().ErrorF("zcapture: recording stopped, write err: %v", err)
+}
+
+// Flush writes the buffered records out (写出缓冲的记录)
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.w.Flush(); err != nil {
+		r.fail(err)
+		return err
+	}
+	return nil
+}
+
+func (r *Recorder) flushLoop() {
+	defer close(r.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = r.Flush()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close flushes the records and closes the file (写出记录并关闭文件)
+func (r *Recorder) Close() error {
+	close(r.stop)
+	<-r.done
+	err := r.Flush()
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Reader reads the records of a capture file (读取抓包文件的记录)
+type Reader struct {
+	r      *bufio.Reader
+	closer io.Closer
+	buf    [recordLen]byte
+}
+
+// NewReader returns a Reader reading the capture r (返回读取抓包r的Reader)
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+	var header [headerLen]byte
+	if _, err := io.ReadFull(reader.r, header[:]); err != nil {
+		return nil, ErrBadCapture
+	}
+	if string(header[:4]) != magic {
+		return nil, ErrBadCapture
+	}
+	if v := binary.LittleEndian.Uint16(header[4:]); v != version {
+		return nil, fmt.Errorf("zcapture: unsupported version %d", v)
+	}
+	return reader, nil
+}
+
+// Open opens the capture file path (打开抓包文件path)
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the capture (返回下一条记录，抓包结束时返回io.EOF)
+func (r *Reader) Next() (*Record, error) {
+	b := r.buf[:]
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("zcapture: truncated record: %w", err)
+		}
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint32(b[21:])
+	if n > maxDataLen {
+		return nil, fmt.Errorf("zcapture: record of %d bytes is too large", n)
+	}
+	record := &Record{
+		Time:      time.Unix(0, int64(binary.LittleEndian.Uint64(b[0:]))),
+		Direction: Direction(b[8]),
+		ConnID:    binary.LittleEndian.Uint64(b[9:]),
+		MsgID:     binary.LittleEndian.Uint32(b[17:]),
+		Data:      make([]byte, n),
+	}
+	if _, err := io.ReadFull(r.r, record.Data); err != nil {
+		return nil, fmt.Errorf("zcapture: truncated record: %w", err)
+	}
+	return record, nil
+}
+
+// Close closes the file opened by Open (关闭Open打开的文件)
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
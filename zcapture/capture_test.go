@@ -0,0 +1,83 @@
+package zcapture
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func record(t *testing.T, filter Filter) *bytes.Buffer {
+	var buf bytes.Buffer
+	r, err := NewRecorder(&buf, filter)
+	assert.Nil(t, err)
+	r.Record(true, 1, 100, []byte("ping"))
+	r.Record(false, 1, 101, []byte("pong"))
+	r.Record(true, 2, 1, []byte("hello"))
+	r.Record(true, 2, 1, nil)
+	assert.Nil(t, r.Close())
+	return &buf
+}
+
+func TestRecordAndRead(t *testing.T) {
+	r, err := NewReader(record(t, Filter{}))
+	assert.Nil(t, err)
+	var got []*Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		got = append(got, rec)
+	}
+	assert.Len(t, got, 4)
+	assert.Equal(t, Inbound, got[0].Direction)
+	assert.Equal(t, uint32(100), got[0].MsgID)
+	assert.Equal(t, []byte("ping"), got[0].Data)
+	assert.Equal(t, Outbound, got[1].Direction)
+	assert.Equal(t, uint64(2), got[2].ConnID)
+	assert.Empty(t, got[3].Data)
+
+	r, _ = NewReader(record(t, Filter{Direction: Inbound, ConnIDs: []uint64{2}}))
+	var n int
+	assert.Nil(t, Replay(r, ReplayOptions{}, func(rec *Record) error {
+		assert.Equal(t, uint64(2), rec.ConnID)
+		n++
+		return nil
+	}))
+	assert.Equal(t, 2, n)
+
+	_, err = NewReader(bytes.NewReader([]byte("PCAP\x01\x00")))
+	assert.Equal(t, ErrBadCapture, err)
+	truncated := record(t, Filter{}).Bytes()
+	r, _ = NewReader(bytes.NewReader(truncated[:len(truncated)-20]))
+	assert.NotNil(t, Replay(r, ReplayOptions{}, func(*Record) error { return nil }))
+}
+
+func TestReplayTo(t *testing.T) {
+	r, _ := NewReader(record(t, Filter{}))
+	client, server := net.Pipe()
+	packet := zpack.NewDataPack()
+	go func() {
+		defer client.Close()
+		assert.Nil(t, ReplayTo(client, packet, r, ReplayOptions{Filter: Filter{MsgIDs: []uint32{100, 101}}}))
+	}()
+
+	head := make([]byte, packet.GetHeadLen())
+	_, err := io.ReadFull(server, head)
+	assert.Nil(t, err)
+	msg, err := packet.Unpack(head)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(100), msg.GetMsgID())
+	data := make([]byte, msg.GetDataLen())
+	_, _ = io.ReadFull(server, data)
+	assert.Equal(t, []byte("ping"), data)
+
+	// the outbound 101 is not replayed (发出的101不会被回放)
+	_, err = server.Read(head)
+	assert.Equal(t, io.EOF, err)
+}
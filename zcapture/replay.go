@@ -0,0 +1,81 @@
+package zcapture
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+// ReplayOptions controls a replay (控制回放)
+type ReplayOptions struct {
+	// Filter selects the records replayed, replaying through a server only sends the inbound ones
+	// (选择回放的记录，通过服务回放时只发送收到的消息)
+	Filter Filter
+	// Speed keeps the captured timing divided by Speed, 1 is real time and 0 replays as fast as possible
+	// (按Speed倍速保持捕获时的时序，1为实时，0为尽可能快)
+	Speed float64
+}
+
+// Replay calls handle with every record of r selected by the options, in order, it stops at the first error of handle.
+// Tests use it to feed a capture to a decoder or a router directly
+// (按顺序对r中被选项选择的每条记录调用handle，handle返回错误时停止，测试可以用它将抓包直接交给解码器或路由)
+func Replay(r *Reader, opts ReplayOptions, handle func(*Record) error) error {
+	var first, start time.Time
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !opts.Filter.Match(record.Direction, record.ConnID, record.MsgID) {
+			continue
+		}
+		if opts.Speed > 0 {
+			if first.IsZero() {
+				first, start = record.Time, time.Now()
+			}
+			due := start.Add(time.Duration(float64(record.Time.Sub(first)) / opts.Speed))
+			if wait := time.Until(due); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		if err := handle(record); err != nil {
+			return err
+		}
+	}
+}
+
+// ReplayTo packs the inbound records of r with packet and writes them to conn, so that a server decodes and handles
+// them again, the messages of every captured connection go through conn
+// (使用packet将r中收到的记录封包并写入conn，由服务重新解码和处理，所有被捕获连接的消息都经由conn发送)
+func ReplayTo(conn net.Conn, packet ziface.IDataPack, r *Reader, opts ReplayOptions) error {
+	opts.Filter.Direction = Inbound
+	return Replay(r, opts, func(record *Record) error {
+		buf, err := packet.Pack(zpack.NewMsgPackage(record.MsgID, record.Data))
+		if err != nil {
+			return err
+		}
+		_, err = conn.Write(buf)
+		return err
+	})
+}
+
+// ReplayFile replays the capture file path to the TCP server at addr (将抓包文件path回放到位于addr的TCP服务)
+func ReplayFile(path, addr string, packet ziface.IDataPack, opts ReplayOptions) error {
+	r, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return ReplayTo(conn, packet, r, opts)
+}
@@ -0,0 +1,162 @@
+package zcluster
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrBusClosed is returned by the buses once closed (总线关闭后返回)
+var ErrBusClosed = errors.New("zcluster: bus closed")
+
+// Bus carries the messages between the nodes, NewRedisBus and NewNATSBus connect to Redis pub/sub and NATS,
+// other brokers plug in by implementing it (在节点之间传递消息，NewRedisBus和NewNATSBus分别连接Redis pub/sub与NATS，实现该接口即可接入其他消息中间件)
+type Bus interface {
+	// Publish sends data to the subscribers of subject on every node (将data发送给所有节点上subject的订阅者)
+	Publish(subject string, data []byte) error
+	// Subscribe calls handler with the data published to subject until cancel is called, the calls of a
+	// subject are not concurrent (以发布到subject的数据调用handler直到调用cancel，同一subject的调用不会并发)
+	Subscribe(subject string, handler func(data []byte)) (cancel func(), err error)
+	// Close releases the connections of the bus (释放总线的连接)
+	Close() error
+}
+
+// subscriptions keeps the handlers of the subjects for the buses (为总线保存各subject的处理函数)
+type subscriptions struct {
+	sync.RWMutex
+	handlers map[string]map[uint64]func(data []byte)
+	nextID   uint64
+}
+
+// add returns the ID of the handler and whether it is the first of subject
+// (返回处理函数的ID以及它是否为subject的第一个处理函数)
+func (s *subscriptions) add(subject string, handler func(data []byte)) (uint64, bool) {
+	s.Lock()
+	defer s.Unlock()
+	if s.handlers == nil {
+		s.handlers = make(map[string]map[uint64]func(data []byte))
+	}
+	s.nextID++
+	handlers, ok := s.handlers[subject]
+	if !ok {
+		handlers = make(map[uint64]func(data []byte))
+		s.handlers[subject] = handlers
+	}
+	handlers[s.nextID] = handler
+	return s.nextID, !ok
+}
+
+// remove returns whether the handler was the last of subject (返回该处理函数是否为subject的最后一个处理函数)
+func (s *subscriptions) remove(subject string, id uint64) bool {
+	s.Lock()
+	defer s.Unlock()
+	handlers, ok := s.handlers[subject]
+	if !ok {
+		return false
+	}
+	if _, ok := handlers[id]; !ok {
+		return false
+	}
+	delete(handlers, id)
+	if len(handlers) == 0 {
+		delete(s.handlers, subject)
+		return true
+	}
+	return false
+}
+
+func (s *subscriptions) subjects() []string {
+	s.RLock()
+	defer s.RUnlock()
+	subjects := make([]string, 0, len(s.handlers))
+	for subject := range s.handlers {
+		subjects = append(subjects, subject)
+	}
+	return subjects
+}
+
+func (s *subscriptions) dispatch(subject string, data []byte) {
+	s.RLock()
+	handlers := make([]func(data []byte), 0, len(s.handlers[subject]))
+	for _, handler := range s.handlers[subject] {
+		handlers = append(handlers, handler)
+	}
+	s.RUnlock()
+	for _, handler := range handlers {
+		handler(data)
+	}
+}
+
+// MemoryBus is a Bus within the process, for tests and for the nodes sharing a process, the messages are
+// delivered one at a time on a goroutine of the bus
+// (进程内的总线，用于测试以及同一进程中的多个节点，消息在总线的协程中逐条投递)
+type MemoryBus struct {
+	subs subscriptions
+
+	mu      sync.Mutex
+	queue   []memoryMessage
+	wake    chan struct{}
+	closed  bool
+	started bool
+}
+
+type memoryMessage struct {
+	subject string
+	data    []byte
+}
+
+// NewMemoryBus creates a MemoryBus (创建MemoryBus)
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{wake: make(chan struct{}, 1)}
+}
+
+func (b *MemoryBus) Publish(subject string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	b.queue = append(b.queue, memoryMessage{subject: subject, data: data})
+	if !b.started {
+		b.started = true
+		go b.deliver()
+	}
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (b *MemoryBus) deliver() {
+	for range b.wake {
+		for {
+			b.mu.Lock()
+			if len(b.queue) == 0 {
+				b.mu.Unlock()
+				break
+			}
+			msg := b.queue[0]
+			b.queue = b.queue[1:]
+			b.mu.Unlock()
+			b.subs.dispatch(msg.subject, msg.data)
+		}
+	}
+}
+
+func (b *MemoryBus) Subscribe(subject string, handler func(data []byte)) (func(), error) {
+	id, _ := b.subs.add(subject, handler)
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.subs.remove(subject, id) })
+	}, nil
+}
+
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.wake)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package zcluster
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBroker fans the published messages out to its subscribed connections (将发布的消息扇出给已订阅的连接)
+type fakeBroker struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]map[string]string // subject -> sid (subject到sid)
+}
+
+func newFakeBroker(t *testing.T, serve func(b *fakeBroker, conn net.Conn)) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	b := &fakeBroker{ln: ln, conns: make(map[net.Conn]map[string]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns[conn] = make(map[string]string)
+			b.mu.Unlock()
+			go serve(b, conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) subscribers(subject string) map[net.Conn]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := make(map[net.Conn]string)
+	for conn, subjects := range b.conns {
+		if sid, ok := subjects[subject]; ok {
+			subs[conn] = sid
+		}
+	}
+	return subs
+}
+
+func (b *fakeBroker) subscribe(conn net.Conn, subject, sid string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.conns[conn][subject] = sid
+}
+
+func (b *fakeBroker) unsubscribe(conn net.Conn, match func(subject, sid string) bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for subject, sid := range b.conns[conn] {
+		if match(subject, sid) {
+			delete(b.conns[conn], subject)
+		}
+	}
+}
+
+// drop closes every connection, as a restarting broker would (关闭所有连接，模拟中间件重启)
+func (b *fakeBroker) drop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for conn := range b.conns {
+		_ = conn.Close()
+		delete(b.conns, conn)
+	}
+}
+
+func (b *fakeBroker) close() {
+	_ = b.ln.Close()
+	b.drop()
+}
+
+func serveRedis(b *fakeBroker, conn net.Conn) {
+	c := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	for {
+		reply, err := c.read()
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, item := range reply.([]interface{}) {
+			args = append(args, string(item.([]byte)))
+		}
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			conn.Write([]byte("+OK\r\n"))
+		case "SUBSCRIBE":
+			for _, subject := range args[1:] {
+				b.subscribe(conn, subject, "")
+				_ = c.send("subscribe", subject)
+			}
+		case "UNSUBSCRIBE":
+			b.unsubscribe(conn, func(subject, _ string) bool { return subject == args[1] })
+		case "PUBLISH":
+			subs := b.subscribers(args[1])
+			for sub := range subs {
+				w := &respConn{conn: sub, w: bufio.NewWriter(sub)}
+				_ = w.send("message", args[1], args[2])
+			}
+			conn.Write([]byte(":" + strconv.Itoa(len(subs)) + "\r\n"))
+		}
+	}
+}
+
+func serveNATS(b *fakeBroker, conn net.Conn) {
+	conn.Write([]byte("INFO {}\r\n"))
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "PING":
+			conn.Write([]byte("PONG\r\n"))
+		case "SUB":
+			b.subscribe(conn, fields[1], fields[2])
+		case "UNSUB":
+			b.unsubscribe(conn, func(_, sid string) bool { return sid == fields[1] })
+		case "PUB":
+			n, _ := strconv.Atoi(fields[2])
+			data := make([]byte, n+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			for sub, sid := range b.subscribers(fields[1]) {
+				sub.Write([]byte("MSG " + fields[1] + " " + sid + " " + fields[2] + "\r\n" + string(data)))
+			}
+		}
+	}
+}
+
+func testBus(t *testing.T, bus Bus, broker *fakeBroker) {
+	received := make(chan string, 10)
+	cancel, err := bus.Subscribe("zinx.test", func(data []byte) { received <- string(data) })
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return len(broker.subscribers("zinx.test")) == 1 }, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, bus.Publish("zinx.test", []byte("hello")))
+	select {
+	case data := <-received:
+		assert.Equal(t, "hello", data)
+	case <-time.After(time.Second):
+		t.Fatal("the message was not received")
+	}
+
+	// The bus reconnects and subscribes again (总线重新连接并重新订阅)
+	broker.drop()
+	assert.Eventually(t, func() bool { return len(broker.subscribers("zinx.test")) == 1 }, 3*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return bus.Publish("zinx.test", []byte("again")) == nil }, time.Second, 10*time.Millisecond)
+	select {
+	case data := <-received:
+		assert.Equal(t, "again", data)
+	case <-time.After(time.Second):
+		t.Fatal("the message was not received after reconnecting")
+	}
+
+	cancel()
+	assert.Eventually(t, func() bool { return len(broker.subscribers("zinx.test")) == 0 }, time.Second, 10*time.Millisecond)
+	assert.Nil(t, bus.Close())
+	assert.ErrorIs(t, bus.Publish("zinx.test", nil), ErrBusClosed)
+}
+
+func TestRedisBus(t *testing.T) {
+	broker := newFakeBroker(t, serveRedis)
+	defer broker.close()
+	bus, err := NewRedisBus("redis://:secret@" + broker.ln.Addr().String())
+	assert.Nil(t, err)
+	testBus(t, bus, broker)
+}
+
+func TestNATSBus(t *testing.T) {
+	broker := newFakeBroker(t, serveNATS)
+	defer broker.close()
+	bus, err := NewNATSBus("nats://token@" + broker.ln.Addr().String())
+	assert.Nil(t, err)
+	testBus(t, bus, broker)
+}
@@ -0,0 +1,555 @@
+// Package zcluster lets several zinx instances act as one: each node shares the presence of its users (which
+// node holds which user) over a pluggable Bus, and messages are routed to a user on any node or broadcast to the
+// clients of every node.
+//
+//	bus, _ := zcluster.NewNATSBus("nats://127.0.0.1:4222")
+//	c := zcluster.New("node-1", bus, s)
+//	_ = c.Start()
+//	s.SetOnConnStart(func(conn ziface.IConnection) { _ = c.Bind(userID(conn), conn) })
+//	_ = c.SendTo("user-42", 100, data)
+//	_ = c.Broadcast(200, notice)
+//	reply, err := c.Call("node-2", kickMsgID, []byte("user-42"))
+//
+// The nodes know each other from the heartbeats on the bus, or from a Registry (Consul, etcd, the Kubernetes
+// endpoints) with WithRegistry (节点通过总线上的心跳相互发现，或通过WithRegistry使用注册中心(Consul、etcd、Kubernetes endpoints))
+//
+// Place tells which node a user should connect to by consistent hashing with bounded load, and WithOnRebalance
+// hands the users over with their session when the nodes change
+// (Place通过带负载上限的一致性哈希给出用户应连接的节点，WithOnRebalance在节点变化时连同会话移交用户)
+//
+// (zcluster使多个zinx实例协同工作：各节点通过可插拔的总线共享其用户的在线信息(哪个节点持有哪个用户)，
+// 消息可以路由给任意节点上的用户，或广播给所有节点的客户端)
+package zcluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+var (
+	ErrUserNotFound = errors.New("zcluster: user not found")
+	ErrNotStarted   = errors.New("zcluster: cluster not started")
+)
+
+// The types of the messages between the nodes (节点间消息的类型)
+const (
+	typeJoin      = "join"      // A node started and asks for the presence (节点启动并请求在线信息)
+	typePresence  = "presence"  // All the users of a node (节点的所有用户)
+	typeOnline    = "online"    // A user was bound (用户已绑定)
+	typeOffline   = "offline"   // A user was unbound (用户已解绑)
+	typeHeartbeat = "heartbeat" // A node is alive (节点存活)
+	typeLeave     = "leave"     // A node stopped (节点已停止)
+	typeSend      = "send"      // A message to a user of a node (发给节点上某个用户的消息)
+	typeBroadcast = "broadcast" // A message to every connection (发给所有连接的消息)
+	typePublish   = "publish"   // A message to the subscribers of a topic (发给主题订阅者的消息)
+	typeSession   = "session"   // A session handed over to a node (移交给节点的会话)
+	typeCall      = "call"      // A call of a handler of a node (对节点处理函数的调用)
+	typeReply     = "reply"     // The reply to a call (调用的应答)
+)
+
+type envelope struct {
+	Type  string   `json:"type"`
+	Node  string   `json:"node"`
+	User  string   `json:"user,omitempty"`
+	Users []string `json:"users,omitempty"`
+	Topic string   `json:"topic,omitempty"`
+	MsgID uint32   `json:"msg_id,omitempty"`
+	Data  []byte   `json:"data,omitempty"`
+	Seq   uint64   `json:"seq,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// Cluster is the node of a server in the cluster (服务在集群中的节点)
+type Cluster struct {
+	node   string
+	bus    Bus
+	server ziface.IServer
+	opts   *options
+
+	mu       sync.RWMutex
+	started  bool
+	local    map[string]ziface.IConnection // The users bound on this node (本节点绑定的用户)
+	remote   map[string]string             // The node of the users bound on other nodes (其他节点绑定的用户所在的节点)
+	nodes    map[string]time.Time          // When the other nodes were heard from last (最近一次收到其他节点消息的时间)
+	members  map[string]Member             // The other healthy members of the registry (注册中心中其他健康的成员)
+	ring     *Ring                         // The ring of the nodes, nil once they changed (节点的哈希环，节点变化后为nil)
+	sessions map[string]pendingSession     // The sessions handed over by other nodes (其他节点移交的会话)
+	cancels  []func()
+	stop     chan struct{}
+	calls    calls
+}
+
+// New creates the node nodeID of server, the node IDs are unique within the cluster
+// (创建server的节点nodeID，节点ID在集群内唯一)
+func New(nodeID string, bus Bus, server ziface.IServer, opts ...Option) *Cluster {
+	return &Cluster{
+		node:     nodeID,
+		bus:      bus,
+		server:   server,
+		opts:     newOptions(opts),
+		local:    make(map[string]ziface.IConnection),
+		remote:   make(map[string]string),
+		nodes:    make(map[string]time.Time),
+		members:  make(map[string]Member),
+		sessions: make(map[string]pendingSession),
+	}
+}
+
+func (c *Cluster) broadcastSubject() string {
+	return c.opts.prefix + ".broadcast"
+}
+
+func (c *Cluster) nodeSubject(node string) string {
+	return c.opts.prefix + ".node." + node
+}
+
+// Start joins the cluster (加入集群)
+func (c *Cluster) Start() error {
+	c.mu.Lock()
+	if c.started {
+		c.mu.Unlock()
+		return nil
+	}
+	if c.opts.registry != nil {
+		if err := c.opts.registry.Register(c.self()); err != nil {
+			c.mu.Unlock()
+			return err
+		}
+	}
+	for _, subject := range []string{c.broadcastSubject(), c.nodeSubject(c.node)} {
+		cancel, err := c.bus.Subscribe(subject, c.receive)
+		if err != nil {
+			c.cancelAll()
+			c.mu.Unlock()
+			if c.opts.registry != nil {
+				_ = c.opts.registry.Deregister(c.node)
+			}
+			return err
+		}
+		c.cancels = append(c.cancels, cancel)
+	}
+	c.started = true
+	c.stop = make(chan struct{})
+	go c.heartbeat(c.stop)
+	c.publish(c.broadcastSubject(), &envelope{Type: typeJoin})
+	c.mu.Unlock()
+
+	if c.opts.registry != nil {
+		c.discover()
+	}
+	return nil
+}
+
+func (c *Cluster) self() Member {
+	m := c.opts.self
+	m.ID = c.node
+	return m
+}
+
+// Stop leaves the cluster, the users bound on this node are no longer reachable from the other nodes
+// (离开集群，其他节点不再能访问本节点绑定的用户)
+func (c *Cluster) Stop() {
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return
+	}
+	c.started = false
+	close(c.stop)
+	c.publish(c.broadcastSubject(), &envelope{Type: typeLeave})
+	c.cancelAll()
+	c.remote = make(map[string]string)
+	c.nodes = make(map[string]time.Time)
+	c.members = make(map[string]Member)
+	c.sessions = make(map[string]pendingSession)
+	c.ring = nil
+	c.mu.Unlock()
+
+	if c.opts.registry != nil {
+		if err := c.opts.registry.Deregister(c.node); err != nil {
+			zlog.Ins().ErrorF("zcluster: node %s deregister err: %v", c.node, err)
+		}
+	}
+}
+
+func (c *Cluster) cancelAll() {
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+	c.cancels = nil
+}
+
+// Bind makes conn the connection of userID in the cluster until it closes or Unbind is called, the session
+// handed over for userID by another node is imported into conn first
+// (将conn设为userID在集群中的连接，直到连接关闭或调用Unbind，其他节点移交给userID的会话会先导入conn)
+func (c *Cluster) Bind(userID string, conn ziface.IConnection) error {
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return ErrNotStarted
+	}
+	session := c.takeSession(userID)
+	c.local[userID] = conn
+	delete(c.remote, userID)
+	c.mu.Unlock()
+
+	if stored := c.loadSession(userID); stored != nil && session == nil {
+		session = stored
+	}
+	if session != nil {
+		session.Import(conn)
+	}
+	c.setPresence(userID)
+
+	c.publish(c.broadcastSubject(), &envelope{Type: typeOnline, User: userID})
+
+	conn.AddCloseCallback(c, userID, func() {
+		c.unbind(userID, conn)
+	})
+	return nil
+}
+
+// Unbind removes the binding of userID on this node (移除userID在本节点的绑定)
+func (c *Cluster) Unbind(userID string) {
+	c.mu.RLock()
+	conn, ok := c.local[userID]
+	c.mu.RUnlock()
+	if ok && c.unbind(userID, conn) {
+		conn.RemoveCloseCallback(c, userID)
+	}
+}
+
+// unbind removes userID if it is still bound to conn, it is also the close callback so it must not touch the
+// callbacks (userID仍绑定到conn时移除绑定，同时也是关闭回调，因此不能操作回调列表)
+func (c *Cluster) unbind(userID string, conn ziface.IConnection) bool {
+	c.mu.Lock()
+	if c.local[userID] != conn {
+		c.mu.Unlock()
+		return false
+	}
+	delete(c.local, userID)
+	started := c.started
+	c.mu.Unlock()
+
+	if started {
+		c.publish(c.broadcastSubject(), &envelope{Type: typeOffline, User: userID})
+	}
+	c.saveSession(userID, conn)
+	return true
+}
+
+// Locate returns the node holding userID, asking the session store for the users not heard about on the bus
+// (返回持有userID的节点，总线上未得知的用户会向会话存储查询)
+func (c *Cluster) Locate(userID string) (string, bool) {
+	c.mu.RLock()
+	_, local := c.local[userID]
+	node, remote := c.remote[userID]
+	c.mu.RUnlock()
+	switch {
+	case local:
+		return c.node, true
+	case remote:
+		return node, true
+	}
+	return c.storedPresence(userID)
+}
+
+// Nodes returns the IDs of the nodes of the cluster, this node included, sorted
+// (返回集群中节点的ID，包括本节点，已排序)
+func (c *Cluster) Nodes() []string {
+	members := c.Members()
+	nodes := make([]string, 0, len(members))
+	for _, m := range members {
+		nodes = append(nodes, m.ID)
+	}
+	return nodes
+}
+
+// Members returns the nodes of the cluster, this node included, sorted by ID, only the IDs are known without a
+// registry (返回集群中的节点，包括本节点，按ID排序，没有注册中心时只有ID)
+func (c *Cluster) Members() []Member {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	members := []Member{c.self()}
+	if c.opts.registry != nil {
+		for _, m := range c.members {
+			members = append(members, m)
+		}
+	} else {
+		for node := range c.nodes {
+			members = append(members, Member{ID: node})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
+
+// SendTo sends the message to userID on whichever node holds it (将消息发送给userID，无论其位于哪个节点)
+func (c *Cluster) SendTo(userID string, msgID uint32, data []byte) error {
+	c.mu.RLock()
+	conn, local := c.local[userID]
+	node, remote := c.remote[userID]
+	c.mu.RUnlock()
+	if !local && !remote {
+		node, remote = c.storedPresence(userID)
+	}
+	switch {
+	case local:
+		return conn.SendMsg(msgID, data)
+	case remote && node != c.node:
+		return c.publish(c.nodeSubject(node), &envelope{Type: typeSend, User: userID, MsgID: msgID, Data: data})
+	}
+	return fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+}
+
+// Broadcast sends the message to every connection of every node (将消息发送给所有节点的所有连接)
+func (c *Cluster) Broadcast(msgID uint32, data []byte) error {
+	c.sendAll(msgID, data)
+	return c.publish(c.broadcastSubject(), &envelope{Type: typeBroadcast, MsgID: msgID, Data: data})
+}
+
+// Publish publishes the message to the subscribers of topic on every node, see ziface.IPubSub
+// (将消息发布给所有节点上topic的订阅者，见ziface.IPubSub)
+func (c *Cluster) Publish(topic string, msgID uint32, data []byte) error {
+	c.server.GetPubSub().Publish(topic, msgID, data)
+	return c.publish(c.broadcastSubject(), &envelope{Type: typePublish, Topic: topic, MsgID: msgID, Data: data})
+}
+
+func (c *Cluster) sendAll(msgID uint32, data []byte) {
+	_ = c.server.GetConnMgr().Range2(func(_ string, conn ziface.IConnection, _ interface{}) error {
+		if err := conn.SendBuffMsg(msgID, data); err != nil {
+			zlog.Ins().DebugF("zcluster: broadcast msgID = %d to connID = %d err: %v", msgID, conn.GetConnID(), err)
+		}
+		return nil
+	}, nil)
+}
+
+func (c *Cluster) publish(subject string, env *envelope) error {
+	env.Node = c.node
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	if err := c.bus.Publish(subject, data); err != nil {
+		zlog.Ins().ErrorF("zcluster: node %s publish %s err: %v", c.node, env.Type, err)
+		return err
+	}
+	return nil
+}
+
+// heartbeat announces the node and forgets the nodes gone silent (宣告本节点并移除不再有消息的节点)
+func (c *Cluster) heartbeat(stop chan struct{}) {
+	ticker := time.NewTicker(c.opts.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		c.publish(c.broadcastSubject(), &envelope{Type: typeHeartbeat})
+
+		c.mu.Lock()
+		c.expireSessions()
+		c.mu.Unlock()
+		c.refreshPresence()
+		if c.opts.registry != nil {
+			c.discover()
+			continue
+		}
+		var left []Member
+		c.mu.Lock()
+		deadline := time.Now().Add(-3 * c.opts.heartbeat)
+		for node, heard := range c.nodes {
+			if heard.Before(deadline) {
+				zlog.Ins().ErrorF("zcluster: node %s is silent, its users are dropped", node)
+				c.forget(node)
+				left = append(left, Member{ID: node})
+			}
+		}
+		c.mu.Unlock()
+		c.notify(nil, left)
+	}
+}
+
+// discover syncs the members with the registry, the members are kept while the registry is unreachable
+// (将成员与注册中心同步，注册中心不可达时保留现有成员)
+func (c *Cluster) discover() {
+	members, err := c.opts.registry.Members()
+	if err != nil {
+		zlog.Ins().ErrorF("zcluster: node %s discover err: %v", c.node, err)
+		return
+	}
+
+	var joined, left []Member
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return
+	}
+	healthy := make(map[string]Member, len(members))
+	for _, m := range members {
+		if m.ID == c.node {
+			continue
+		}
+		healthy[m.ID] = m
+		if _, ok := c.members[m.ID]; !ok {
+			joined = append(joined, m)
+		}
+	}
+	for node, m := range c.members {
+		if _, ok := healthy[node]; !ok {
+			zlog.Ins().ErrorF("zcluster: node %s is no longer healthy, its users are dropped", node)
+			c.forget(node)
+			left = append(left, m)
+		}
+	}
+	c.members = healthy
+	c.mu.Unlock()
+	c.notify(joined, left)
+}
+
+// notify calls the join and leave callbacks and rebalances the users, c.mu is not held
+// (调用加入与离开回调并重新平衡用户，调用时不持有c.mu)
+func (c *Cluster) notify(joined, left []Member) {
+	if len(joined) == 0 && len(left) == 0 {
+		return
+	}
+	if c.opts.onJoin != nil {
+		for _, m := range joined {
+			c.opts.onJoin(m)
+		}
+	}
+	if c.opts.onLeave != nil {
+		for _, m := range left {
+			c.opts.onLeave(m)
+		}
+	}
+	c.mu.Lock()
+	c.ring = nil
+	c.mu.Unlock()
+	c.rebalance()
+}
+
+// forget removes node and its users, c.mu is held (移除节点及其用户，调用时持有c.mu)
+func (c *Cluster) forget(node string) {
+	delete(c.nodes, node)
+	for user, n := range c.remote {
+		if n == node {
+			delete(c.remote, user)
+		}
+	}
+}
+
+func (c *Cluster) receive(data []byte) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		zlog.Ins().ErrorF("zcluster: node %s decode message err: %v", c.node, err)
+		return
+	}
+	if env.Node == c.node {
+		return
+	}
+
+	switch env.Type {
+	case typeSend:
+		c.mu.RLock()
+		conn, ok := c.local[env.User]
+		c.mu.RUnlock()
+		if !ok {
+			zlog.Ins().DebugF("zcluster: user %s is no longer on node %s", env.User, c.node)
+			return
+		}
+		if err := conn.SendMsg(env.MsgID, env.Data); err != nil {
+			zlog.Ins().DebugF("zcluster: send msgID = %d to user %s err: %v", env.MsgID, env.User, err)
+		}
+		return
+	case typeBroadcast:
+		c.sendAll(env.MsgID, env.Data)
+		return
+	case typePublish:
+		c.server.GetPubSub().Publish(env.Topic, env.MsgID, env.Data)
+		return
+	case typeCall:
+		go c.serveCall(&env)
+		return
+	case typeReply:
+		c.receiveReply(&env)
+		return
+	case typeSession:
+		session := &Session{}
+		if err := json.Unmarshal(env.Data, session); err != nil {
+			zlog.Ins().ErrorF("zcluster: node %s decode session of user %s err: %v", c.node, env.User, err)
+			return
+		}
+		c.mu.Lock()
+		c.sessions[env.User] = pendingSession{session: session, received: time.Now()}
+		c.mu.Unlock()
+		return
+	}
+
+	var moved ziface.IConnection
+	var presence []string
+	var joined, left []Member
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return
+	}
+	// Without a registry the nodes join and leave as heard from on the bus (没有注册中心时，节点按总线上的消息加入和离开)
+	_, known := c.nodes[env.Node]
+	if env.Type == typeLeave {
+		if known && c.opts.registry == nil {
+			left = append(left, Member{ID: env.Node})
+		}
+	} else {
+		if !known && c.opts.registry == nil {
+			joined = append(joined, Member{ID: env.Node})
+		}
+		c.nodes[env.Node] = time.Now()
+	}
+	switch env.Type {
+	case typeJoin:
+		presence = make([]string, 0, len(c.local))
+		for user := range c.local {
+			presence = append(presence, user)
+		}
+	case typePresence:
+		for _, user := range env.Users {
+			if _, ok := c.local[user]; !ok {
+				c.remote[user] = env.Node
+			}
+		}
+	case typeOnline:
+		c.remote[env.User] = env.Node
+		if conn, ok := c.local[env.User]; ok {
+			delete(c.local, env.User)
+			moved = conn
+		}
+	case typeOffline:
+		if c.remote[env.User] == env.Node {
+			delete(c.remote, env.User)
+		}
+	case typeLeave:
+		c.forget(env.Node)
+	}
+	c.mu.Unlock()
+	c.notify(joined, left)
+
+	if presence != nil {
+		c.publish(c.nodeSubject(env.Node), &envelope{Type: typePresence, Users: presence})
+	}
+	if moved != nil {
+		moved.RemoveCloseCallback(c, env.User)
+		if c.opts.onMoved != nil {
+			c.opts.onMoved(env.User, moved)
+		}
+	}
+}
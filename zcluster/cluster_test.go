@@ -0,0 +1,245 @@
+package zcluster
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+type testConn struct {
+	ziface.IConnection
+	id uint64
+
+	mu      sync.Mutex
+	sent    []uint32
+	onClose func()
+}
+
+func (c *testConn) GetConnID() uint64 { return c.id }
+
+func (c *testConn) SendMsg(msgID uint32, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msgID)
+	return nil
+}
+
+func (c *testConn) SendBuffMsg(msgID uint32, data []byte) error {
+	return c.SendMsg(msgID, data)
+}
+
+func (c *testConn) AddCloseCallback(handler, key interface{}, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onClose = f
+}
+
+func (c *testConn) RemoveCloseCallback(handler, key interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onClose = nil
+}
+
+func (c *testConn) received() []uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]uint32(nil), c.sent...)
+}
+
+type testConnMgr struct {
+	ziface.IConnManager
+	conns []ziface.IConnection
+}
+
+func (m *testConnMgr) Range2(f func(string, ziface.IConnection, interface{}) error, args interface{}) error {
+	for _, conn := range m.conns {
+		_ = f("", conn, args)
+	}
+	return nil
+}
+
+type testPubSub struct {
+	ziface.IPubSub
+	mu     sync.Mutex
+	topics []string
+}
+
+func (p *testPubSub) Publish(topic string, msgID uint32, data []byte) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.topics = append(p.topics, topic)
+	return 0
+}
+
+type testServer struct {
+	ziface.IServer
+	connMgr *testConnMgr
+	pubSub  *testPubSub
+}
+
+func (s *testServer) GetConnMgr() ziface.IConnManager { return s.connMgr }
+
+func (s *testServer) GetPubSub() ziface.IPubSub { return s.pubSub }
+
+func newTestNode(id string, bus Bus, conns ...ziface.IConnection) (*Cluster, *testServer) {
+	s := &testServer{connMgr: &testConnMgr{conns: conns}, pubSub: &testPubSub{}}
+	return New(id, bus, s, WithHeartbeat(50*time.Millisecond)), s
+}
+
+func TestCluster(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+
+	alice, bob := &testConn{id: 1}, &testConn{id: 2}
+	var moved []string
+	a, _ := newTestNode("a", bus, alice)
+	a.opts.onMoved = func(userID string, conn ziface.IConnection) { moved = append(moved, userID) }
+	assert.ErrorIs(t, a.Bind("alice", alice), ErrNotStarted)
+	assert.Nil(t, a.Start())
+	assert.Nil(t, a.Bind("alice", alice))
+
+	// b learns about alice from the presence of a when joining (b加入时从a的在线信息得知alice)
+	b, bServer := newTestNode("b", bus, bob)
+	assert.Nil(t, b.Start())
+	assert.Eventually(t, func() bool {
+		node, ok := b.Locate("alice")
+		return ok && node == "a"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, b.Nodes())
+
+	assert.Nil(t, b.SendTo("alice", 100, []byte("hi")))
+	assert.Eventually(t, func() bool { return len(alice.received()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, b.SendTo("carol", 100, nil), ErrUserNotFound)
+
+	assert.Nil(t, a.Broadcast(200, []byte("notice")))
+	assert.Eventually(t, func() bool { return len(bob.received()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []uint32{100, 200}, alice.received())
+
+	assert.Nil(t, a.Publish("news", 300, nil))
+	assert.Eventually(t, func() bool {
+		bServer.pubSub.mu.Lock()
+		defer bServer.pubSub.mu.Unlock()
+		return len(bServer.pubSub.topics) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// The newest binding wins (以最新的绑定为准)
+	assert.Nil(t, b.Bind("alice", bob))
+	assert.Eventually(t, func() bool {
+		node, _ := a.Locate("alice")
+		return node == "b"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"alice"}, moved)
+
+	// Closing the connection unbinds the user (关闭连接会解绑用户)
+	bob.onClose()
+	assert.Eventually(t, func() bool {
+		_, ok := a.Locate("alice")
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, b.Bind("bob", bob))
+	assert.Eventually(t, func() bool { _, ok := a.Locate("bob"); return ok }, time.Second, 10*time.Millisecond)
+	b.Stop()
+	assert.Eventually(t, func() bool {
+		_, ok := a.Locate("bob")
+		return !ok && len(a.Nodes()) == 1
+	}, time.Second, 10*time.Millisecond)
+	a.Stop()
+}
+
+// A node gone silent is forgotten with its users (不再有消息的节点连同其用户被移除)
+func TestClusterSilentNode(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	a, _ := newTestNode("a", bus)
+	assert.Nil(t, a.Start())
+	defer a.Stop()
+
+	a.receive([]byte(`{"type":"online","node":"ghost","user":"casper"}`))
+	node, _ := a.Locate("casper")
+	assert.Equal(t, "ghost", node)
+	assert.Eventually(t, func() bool {
+		_, ok := a.Locate("casper")
+		return !ok
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+// With a registry the healthy members are the nodes (使用注册中心时健康的成员即为集群节点)
+func TestClusterRegistry(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	registry := NewMemoryRegistry()
+
+	var mu sync.Mutex
+	var events []string
+	hooks := []Option{
+		WithHeartbeat(50 * time.Millisecond),
+		WithOnNodeJoin(func(m Member) { mu.Lock(); events = append(events, "+"+m.ID+"@"+m.Addr); mu.Unlock() }),
+		WithOnNodeLeave(func(m Member) { mu.Lock(); events = append(events, "-"+m.ID); mu.Unlock() }),
+	}
+	s := &testServer{connMgr: &testConnMgr{}, pubSub: &testPubSub{}}
+	a := New("a", bus, s, append(hooks, WithRegistry(registry, "10.0.0.1:8999", nil))...)
+	b := New("b", bus, s, WithHeartbeat(50*time.Millisecond), WithRegistry(registry, "10.0.0.2:8999", nil))
+	assert.Nil(t, a.Start())
+	defer a.Stop()
+	assert.Nil(t, b.Start())
+	assert.Nil(t, b.Bind("bob", &testConn{id: 2}))
+
+	assert.Eventually(t, func() bool {
+		_, ok := a.Locate("bob")
+		return ok && len(a.Nodes()) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []Member{{ID: "a", Addr: "10.0.0.1:8999"}, {ID: "b", Addr: "10.0.0.2:8999"}}, a.Members())
+
+	// b is no longer healthy although it still sends heartbeats (b仍在发送心跳但已不再健康)
+	assert.Nil(t, registry.Deregister("b"))
+	assert.Eventually(t, func() bool {
+		_, ok := a.Locate("bob")
+		return !ok && len(a.Nodes()) == 1
+	}, time.Second, 10*time.Millisecond)
+	b.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"+b@10.0.0.2:8999", "-b"}, events)
+}
+
+func TestClusterCall(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	a, _ := newTestNode("a", bus)
+	b, _ := newTestNode("b", bus)
+	b.opts.callTimeout = 100 * time.Millisecond
+	a.Handle(1, func(from string, data []byte) ([]byte, error) {
+		return append([]byte(from+":"), data...), nil
+	})
+	a.Handle(2, func(from string, data []byte) ([]byte, error) { return nil, errors.New("kick failed") })
+	a.Handle(3, func(from string, data []byte) ([]byte, error) { time.Sleep(time.Second); return nil, nil })
+	assert.Nil(t, a.Start())
+	defer a.Stop()
+	assert.Nil(t, b.Start())
+	defer b.Stop()
+	assert.Eventually(t, func() bool { return len(b.Nodes()) == 2 }, time.Second, 10*time.Millisecond)
+
+	reply, err := b.Call("a", 1, []byte("presence?"))
+	assert.Nil(t, err)
+	assert.Equal(t, "b:presence?", string(reply))
+	reply, err = a.Call("a", 1, []byte("self"))
+	assert.Nil(t, err)
+	assert.Equal(t, "a:self", string(reply))
+
+	_, err = b.Call("a", 2, nil)
+	var remote *RemoteError
+	assert.ErrorAs(t, err, &remote)
+	assert.Equal(t, "kick failed", remote.Message)
+	_, err = b.Call("a", 4, nil)
+	assert.ErrorIs(t, err, ErrNoHandler)
+	_, err = b.Call("a", 3, nil)
+	assert.ErrorIs(t, err, ErrCallTimeout)
+	_, err = b.Call("c", 1, nil)
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+}
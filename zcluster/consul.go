@@ -0,0 +1,127 @@
+package zcluster
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// ConsulRegistry is a Registry on the Consul agent HTTP API, the members are the instances of a Consul service
+// whose TTL check passes (基于Consul agent HTTP API的Registry，成员为TTL检查通过的Consul服务实例)
+type ConsulRegistry struct {
+	addr    string
+	service string
+	header  http.Header
+	opts    *registryOptions
+
+	mu    sync.Mutex
+	stops map[string]chan struct{}
+}
+
+// NewConsulRegistry creates the registry of service on the Consul agent at rawURL, e.g. "http://127.0.0.1:8500",
+// a token is given as the user name: "http://token@127.0.0.1:8500"
+// (创建位于rawURL的Consul agent上service的注册中心，例如"http://127.0.0.1:8500"，token以用户名的形式给出)
+func NewConsulRegistry(rawURL, service string, opts ...RegistryOption) (*ConsulRegistry, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("zcluster: unsupported consul url %q", rawURL)
+	}
+	header := make(http.Header)
+	if u.User != nil {
+		header.Set("X-Consul-Token", u.User.Username())
+	}
+	return &ConsulRegistry{
+		addr:    u.Scheme + "://" + u.Host,
+		service: service,
+		header:  header,
+		opts:    newRegistryOptions(opts),
+		stops:   make(map[string]chan struct{}),
+	}, nil
+}
+
+type consulCheck struct {
+	CheckID                        string `json:"CheckID"`
+	TTL                            string `json:"TTL"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter"`
+}
+
+type consulService struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name,omitempty"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Check   *consulCheck      `json:"Check,omitempty"`
+}
+
+func (r *ConsulRegistry) checkID(id string) string {
+	return "zinx:" + r.service + ":" + id
+}
+
+func (r *ConsulRegistry) Register(m Member) error {
+	service := consulService{ID: m.ID, Name: r.service, Address: m.Addr, Meta: m.Meta}
+	if host, port, err := net.SplitHostPort(m.Addr); err == nil {
+		service.Address = host
+		service.Port, _ = strconv.Atoi(port)
+	}
+	checkID := r.checkID(m.ID)
+	service.Check = &consulCheck{
+		CheckID:                        checkID,
+		TTL:                            r.opts.ttl.String(),
+		DeregisterCriticalServiceAfter: (10 * r.opts.ttl).String(),
+	}
+	if err := doJSON(r.opts.client, http.MethodPut, r.addr+"/v1/agent/service/register", r.header, &service, nil); err != nil {
+		return err
+	}
+	pass := func() error {
+		return doJSON(r.opts.client, http.MethodPut, r.addr+"/v1/agent/check/pass/"+url.PathEscape(checkID), r.header, nil, nil)
+	}
+	if err := pass(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stop, ok := r.stops[m.ID]; ok {
+		close(stop)
+	}
+	stop := make(chan struct{})
+	r.stops[m.ID] = stop
+	go keepAlive(r.opts.ttl/3, stop, "consul", pass)
+	return nil
+}
+
+func (r *ConsulRegistry) Deregister(id string) error {
+	r.mu.Lock()
+	if stop, ok := r.stops[id]; ok {
+		close(stop)
+		delete(r.stops, id)
+	}
+	r.mu.Unlock()
+	return doJSON(r.opts.client, http.MethodPut, r.addr+"/v1/agent/service/deregister/"+url.PathEscape(id), r.header, nil, nil)
+}
+
+func (r *ConsulRegistry) Members() ([]Member, error) {
+	var entries []struct {
+		Service consulService `json:"Service"`
+	}
+	path := r.addr + "/v1/health/service/" + url.PathEscape(r.service) + "?passing=true"
+	if err := doJSON(r.opts.client, http.MethodGet, path, r.header, nil, &entries); err != nil {
+		return nil, err
+	}
+	members := make([]Member, 0, len(entries))
+	for _, entry := range entries {
+		m := Member{ID: entry.Service.ID, Addr: entry.Service.Address, Meta: entry.Service.Meta}
+		if entry.Service.Port > 0 {
+			m.Addr = net.JoinHostPort(m.Addr, strconv.Itoa(entry.Service.Port))
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
@@ -0,0 +1,154 @@
+package zcluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+
+// KubernetesRegistry is a Registry on the endpoints of a Kubernetes service, the pods are registered by the
+// selector of the service and are healthy while their readiness probe passes, so Register and Deregister do nothing,
+// the member IDs are the pod names so the node IDs of the clusters are the pod names, e.g. os.Getenv("HOSTNAME")
+// (基于Kubernetes服务endpoints的Registry，Pod由服务的选择器注册，就绪探针通过时为健康状态，因此Register与Deregister
+// 不做任何事，成员ID为Pod名称，因此集群的节点ID应为Pod名称，例如os.Getenv("HOSTNAME"))
+type KubernetesRegistry struct {
+	api       string
+	namespace string
+	service   string
+	port      string
+	token     func() (string, error)
+	opts      *registryOptions
+}
+
+// NewKubernetesRegistry creates the registry of service in namespace from within a pod, with the service account
+// of the pod, namespace defaults to the namespace of the pod, port names the port of the endpoints in the member
+// addresses, the first one if empty
+// (在Pod内使用Pod的服务账号创建namespace中service的注册中心，namespace默认为Pod所在的命名空间，port为成员地址中使用的
+// endpoints端口名称，为空时使用第一个端口)
+func NewKubernetesRegistry(namespace, service, port string, opts ...RegistryOption) (*KubernetesRegistry, error) {
+	host, hostPort := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || hostPort == "" {
+		return nil, errors.New("zcluster: not running in a kubernetes pod")
+	}
+	if namespace == "" {
+		data, err := os.ReadFile(serviceAccountDir + "namespace")
+		if err != nil {
+			return nil, err
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("zcluster: invalid kubernetes ca certificate")
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	r := newKubernetesRegistry("https://"+net.JoinHostPort(host, hostPort), namespace, service, port,
+		append([]RegistryOption{WithRegistryHTTPClient(client)}, opts...))
+	// The projected tokens are rotated, they are read again for each request (投射的令牌会轮换，每次请求重新读取)
+	r.token = func() (string, error) {
+		data, err := os.ReadFile(serviceAccountDir + "token")
+		return strings.TrimSpace(string(data)), err
+	}
+	return r, nil
+}
+
+// NewKubernetesRegistryAt creates the registry of service in namespace on the API server at apiURL with token,
+// e.g. from outside the cluster (使用token创建位于apiURL的API服务器上namespace中service的注册中心，例如在集群外使用)
+func NewKubernetesRegistryAt(apiURL, token, namespace, service, port string, opts ...RegistryOption) (*KubernetesRegistry, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("zcluster: unsupported kubernetes url %q", apiURL)
+	}
+	r := newKubernetesRegistry(u.Scheme+"://"+u.Host, namespace, service, port, opts)
+	r.token = func() (string, error) { return token, nil }
+	return r, nil
+}
+
+func newKubernetesRegistry(api, namespace, service, port string, opts []RegistryOption) *KubernetesRegistry {
+	return &KubernetesRegistry{
+		api:       api,
+		namespace: namespace,
+		service:   service,
+		port:      port,
+		opts:      newRegistryOptions(opts),
+	}
+}
+
+func (r *KubernetesRegistry) Register(m Member) error {
+	return nil
+}
+
+func (r *KubernetesRegistry) Deregister(id string) error {
+	return nil
+}
+
+type kubernetesEndpoints struct {
+	Subsets []struct {
+		Addresses []struct {
+			IP        string `json:"ip"`
+			TargetRef *struct {
+				Name string `json:"name"`
+			} `json:"targetRef"`
+		} `json:"addresses"`
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"subsets"`
+}
+
+// Members returns the ready addresses of the endpoints, the not ready ones are left out
+// (返回endpoints中就绪的地址，未就绪的地址被排除)
+func (r *KubernetesRegistry) Members() ([]Member, error) {
+	token, err := r.token()
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	var endpoints kubernetesEndpoints
+	path := r.api + "/api/v1/namespaces/" + url.PathEscape(r.namespace) + "/endpoints/" + url.PathEscape(r.service)
+	if err := doJSON(r.opts.client, http.MethodGet, path, header, nil, &endpoints); err != nil {
+		return nil, err
+	}
+
+	var members []Member
+	for _, subset := range endpoints.Subsets {
+		port := 0
+		for _, p := range subset.Ports {
+			if r.port == "" || p.Name == r.port {
+				port = p.Port
+				break
+			}
+		}
+		for _, address := range subset.Addresses {
+			m := Member{ID: address.IP, Addr: address.IP}
+			if address.TargetRef != nil && address.TargetRef.Name != "" {
+				m.ID = address.TargetRef.Name
+			}
+			if port > 0 {
+				m.Addr = net.JoinHostPort(address.IP, strconv.Itoa(port))
+			}
+			members = append(members, m)
+		}
+	}
+	return members, nil
+}
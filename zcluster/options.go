@@ -0,0 +1,143 @@
+package zcluster
+
+import (
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+type options struct {
+	prefix    string
+	heartbeat time.Duration
+	onMoved   func(userID string, conn ziface.IConnection)
+	registry  Registry
+	self      Member
+	onJoin    func(m Member)
+	onLeave   func(m Member)
+
+	replicas    int
+	loadFactor  float64
+	onRebalance func(userID string, conn ziface.IConnection, to Member)
+	callTimeout time.Duration
+
+	store    ziface.ISessionStore
+	storeTTL time.Duration
+}
+
+// Option configures a Cluster (Cluster的配置项)
+type Option func(o *options)
+
+// WithSubjectPrefix sets the prefix of the subjects of the cluster on the bus, "zinx.cluster" by default,
+// the clusters sharing a bus use different prefixes (设置集群在总线上的subject前缀，默认"zinx.cluster"，共用总线的多个集群使用不同的前缀)
+func WithSubjectPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithHeartbeat sets how often the nodes announce themselves, 5 seconds by default, a node silent for three
+// heartbeats is considered gone with its users (设置节点宣告自身的间隔，默认5秒，三个心跳周期没有消息的节点连同其用户被视为已离开)
+func WithHeartbeat(interval time.Duration) Option {
+	return func(o *options) {
+		o.heartbeat = interval
+	}
+}
+
+// WithOnUserMoved sets the callback of the users bound on this node which were bound on another node since,
+// e.g. to close the previous connection, the newest binding wins
+// (设置本节点已绑定的用户又在其他节点绑定时的回调，例如关闭之前的连接，以最新的绑定为准)
+func WithOnUserMoved(onMoved func(userID string, conn ziface.IConnection)) Option {
+	return func(o *options) {
+		o.onMoved = onMoved
+	}
+}
+
+// WithRegistry registers the node in registry with its address addr and meta, and makes the healthy members of
+// registry the nodes of the cluster instead of the nodes heard from on the bus, the registry is polled every
+// heartbeat (将节点以地址addr和meta注册到registry，并以registry中健康的成员而非总线上收到消息的节点作为集群的节点，
+// 每个心跳周期轮询一次registry)
+func WithRegistry(registry Registry, addr string, meta map[string]string) Option {
+	return func(o *options) {
+		o.registry = registry
+		o.self = Member{Addr: addr, Meta: meta}
+	}
+}
+
+// WithOnNodeJoin sets the callback of the nodes joining the cluster (设置节点加入集群时的回调)
+func WithOnNodeJoin(onJoin func(m Member)) Option {
+	return func(o *options) {
+		o.onJoin = onJoin
+	}
+}
+
+// WithOnNodeLeave sets the callback of the nodes leaving the cluster, stopped or no longer healthy, their users
+// are no longer reachable (设置节点离开集群时的回调，节点已停止或不再健康，其用户不再可达)
+func WithOnNodeLeave(onLeave func(m Member)) Option {
+	return func(o *options) {
+		o.onLeave = onLeave
+	}
+}
+
+// WithPlacement sets the number of virtual nodes of each node on the consistent hash ring of Place, 100 by default,
+// and the load factor bounding the users of a node to loadFactor times the average, 1.25 by default, 0 disables
+// the bound (设置Place使用的一致性哈希环上每个节点的虚拟节点数，默认100，以及将节点用户数限制为平均值loadFactor倍的负载因子，
+// 默认1.25，0表示不限制)
+func WithPlacement(replicas int, loadFactor float64) Option {
+	return func(o *options) {
+		o.replicas = replicas
+		o.loadFactor = loadFactor
+	}
+}
+
+// WithOnRebalance sets the callback of the local users whose node on the ring changed with the nodes, their
+// session is exported and handed over to the node to, where Bind imports it, the callback typically tells the
+// client to reconnect to to.Addr and closes conn, without it the users are not rebalanced
+// (设置哈希环上所属节点随节点变化而改变的本地用户的回调，其会话被导出并移交给节点to，在to上由Bind导入，回调通常通知客户端
+// 重连到to.Addr并关闭conn，不设置时不重新平衡用户)
+func WithOnRebalance(onRebalance func(userID string, conn ziface.IConnection, to Member)) Option {
+	return func(o *options) {
+		o.onRebalance = onRebalance
+	}
+}
+
+// WithCallTimeout sets how long Call waits for the reply, 5 seconds by default (设置Call等待应答的时长，默认5秒)
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.callTimeout = timeout
+	}
+}
+
+// WithSessionStore keeps the sessions and the presence of the users in store, e.g. a RedisStore, so they survive
+// the restarts of the nodes: the session of a user is saved for ttl when its connection closes and imported by Bind
+// on whichever node it connects to next, and Locate finds the users the node did not hear about on the bus
+// (将用户的会话与在线信息保存在store中，例如RedisStore，使其在节点重启后仍然存在：用户的会话在其连接关闭时保存ttl时长，
+// 并在其下次连接的节点上由Bind导入，Locate可以找到本节点未在总线上得知的用户)
+func WithSessionStore(store ziface.ISessionStore, ttl time.Duration) Option {
+	return func(o *options) {
+		o.store = store
+		o.storeTTL = ttl
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		prefix:      "zinx.cluster",
+		heartbeat:   5 * time.Second,
+		replicas:    100,
+		loadFactor:  1.25,
+		callTimeout: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.heartbeat <= 0 {
+		panic("zcluster: heartbeat must be positive")
+	}
+	if o.replicas <= 0 || (o.loadFactor != 0 && o.loadFactor < 1) {
+		panic("zcluster: invalid placement")
+	}
+	if o.callTimeout <= 0 {
+		panic("zcluster: call timeout must be positive")
+	}
+	return o
+}
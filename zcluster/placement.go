@@ -0,0 +1,266 @@
+package zcluster
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// pendingSessionTTL is how long a node keeps a session handed over by another node for its user to connect
+// (节点保存其他节点移交的会话以等待其用户连接的时长)
+const pendingSessionTTL = time.Minute
+
+// Session is the state of a user handed over to another node, the properties of its connection encoded in JSON
+// (移交给其他节点的用户状态，即其连接以JSON编码的属性)
+type Session struct {
+	UserID     string                     `json:"user_id"`
+	Properties map[string]json.RawMessage `json:"properties,omitempty"`
+}
+
+// ExportSession exports the properties of conn which can be encoded in JSON, conn must implement
+// ziface.IPropertyLister as the connections of znet do (导出conn中可以JSON编码的属性，conn须像znet的连接一样实现ziface.IPropertyLister)
+func ExportSession(userID string, conn ziface.IConnection) *Session {
+	s := &Session{UserID: userID, Properties: make(map[string]json.RawMessage)}
+	lister, ok := conn.(ziface.IPropertyLister)
+	if !ok {
+		return s
+	}
+	for key, value := range lister.GetProperties() {
+		data, err := json.Marshal(value)
+		if err != nil {
+			zlog.Ins().DebugF("zcluster: property %s of user %s is not exported: %v", key, userID, err)
+			continue
+		}
+		s.Properties[key] = data
+	}
+	return s
+}
+
+// Import sets the properties of the session on conn, decoded as by json.Unmarshal into an interface{}, e.g. the
+// numbers become float64 (将会话的属性设置到conn上，按json.Unmarshal解码到interface{}的方式解码，例如数字变为float64)
+func (s *Session) Import(conn ziface.IConnection) {
+	for key, data := range s.Properties {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			continue
+		}
+		conn.SetProperty(key, value)
+	}
+}
+
+type pendingSession struct {
+	session  *Session
+	received time.Time
+}
+
+// Place returns the node userID should connect to: the node holding it if bound, otherwise its node on the
+// consistent hash ring of the nodes among those within the bounded load, see WithPlacement
+// (返回userID应连接的节点：已绑定时为持有它的节点，否则为节点一致性哈希环上负载未超限的节点中它所属的节点，见WithPlacement)
+func (c *Cluster) Place(userID string) Member {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.local[userID]; ok {
+		return c.self()
+	}
+	if node, ok := c.remote[userID]; ok {
+		return c.member(node)
+	}
+	return c.member(c.ringLocked().GetBounded(userID, c.loadsLocked(), c.opts.loadFactor))
+}
+
+// member returns the member node, c.mu is held (返回节点node的成员信息，调用时持有c.mu)
+func (c *Cluster) member(node string) Member {
+	if node == c.node {
+		return c.self()
+	}
+	if m, ok := c.members[node]; ok {
+		return m
+	}
+	return Member{ID: node}
+}
+
+// ringLocked returns the ring of the current nodes, c.mu is held (返回当前节点的哈希环，调用时持有c.mu)
+func (c *Cluster) ringLocked() *Ring {
+	if c.ring != nil {
+		return c.ring
+	}
+	nodes := []string{c.node}
+	if c.opts.registry != nil {
+		for node := range c.members {
+			nodes = append(nodes, node)
+		}
+	} else {
+		for node := range c.nodes {
+			nodes = append(nodes, node)
+		}
+	}
+	c.ring = NewRing(c.opts.replicas, nodes...)
+	return c.ring
+}
+
+// loadsLocked returns the number of users of the nodes, c.mu is held (返回各节点的用户数量，调用时持有c.mu)
+func (c *Cluster) loadsLocked() map[string]int {
+	loads := map[string]int{c.node: len(c.local)}
+	for _, node := range c.remote {
+		loads[node]++
+	}
+	return loads
+}
+
+type handover struct {
+	userID string
+	conn   ziface.IConnection
+	to     Member
+}
+
+// rebalance hands the local users whose node on the ring changed over to it, within its bounded load, so only
+// the users of the joining and leaving nodes move (将哈希环上所属节点已变化的本地用户在负载上限内移交给新节点，因此只有加入和离开节点的用户会移动)
+func (c *Cluster) rebalance() {
+	if c.opts.onRebalance == nil {
+		return
+	}
+	var moves []handover
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return
+	}
+	ring := c.ringLocked()
+	loads := c.loadsLocked()
+	capacity := BoundedCapacity(loads, len(ring.nodes), c.opts.loadFactor)
+	for userID, conn := range c.local {
+		owner := ring.Get(userID)
+		if owner == c.node {
+			continue
+		}
+		if c.opts.loadFactor >= 1 && loads[owner] >= capacity {
+			continue
+		}
+		loads[owner]++
+		loads[c.node]--
+		moves = append(moves, handover{userID: userID, conn: conn, to: c.member(owner)})
+	}
+	c.mu.Unlock()
+
+	for _, move := range moves {
+		data, err := json.Marshal(ExportSession(move.userID, move.conn))
+		if err == nil && c.opts.store != nil {
+			err = c.opts.store.Set(move.userID, data, c.opts.storeTTL)
+		}
+		if err == nil {
+			err = c.publish(c.nodeSubject(move.to.ID), &envelope{Type: typeSession, User: move.userID, Data: data})
+		}
+		if err != nil {
+			zlog.Ins().ErrorF("zcluster: hand user %s over to node %s err: %v", move.userID, move.to.ID, err)
+			continue
+		}
+		c.opts.onRebalance(move.userID, move.conn, move.to)
+	}
+}
+
+// takeSession removes and returns the session handed over for userID, nil if none, c.mu is held
+// (移除并返回移交给userID的会话，没有时返回nil，调用时持有c.mu)
+func (c *Cluster) takeSession(userID string) *Session {
+	pending, ok := c.sessions[userID]
+	if !ok {
+		return nil
+	}
+	delete(c.sessions, userID)
+	if time.Since(pending.received) >= pendingSessionTTL {
+		return nil
+	}
+	return pending.session
+}
+
+// expireSessions drops the sessions whose user did not connect in time, c.mu is held
+// (丢弃用户未及时连接的会话，调用时持有c.mu)
+func (c *Cluster) expireSessions() {
+	for userID, pending := range c.sessions {
+		if time.Since(pending.received) >= pendingSessionTTL {
+			delete(c.sessions, userID)
+		}
+	}
+}
+
+// presenceTTL is how long the presence of a user stays in the session store without being refreshed
+// (用户的在线信息在会话存储中未刷新时保留的时长)
+func (c *Cluster) presenceTTL() time.Duration {
+	return 3 * c.opts.heartbeat
+}
+
+// loadSession returns the session of userID in the session store, if any (返回会话存储中userID的会话，如有)
+func (c *Cluster) loadSession(userID string) *Session {
+	if c.opts.store == nil {
+		return nil
+	}
+	data, err := c.opts.store.Get(userID)
+	if err != nil {
+		zlog.Ins().ErrorF("zcluster: load session of user %s err: %v", userID, err)
+		return nil
+	}
+	if data == nil {
+		return nil
+	}
+	session := &Session{}
+	if err := json.Unmarshal(data, session); err != nil {
+		zlog.Ins().ErrorF("zcluster: decode session of user %s err: %v", userID, err)
+		return nil
+	}
+	return session
+}
+
+// saveSession saves the session of userID whose conn closed and removes its presence
+// (保存连接已关闭的userID的会话并移除其在线信息)
+func (c *Cluster) saveSession(userID string, conn ziface.IConnection) {
+	if c.opts.store == nil {
+		return
+	}
+	data, err := json.Marshal(ExportSession(userID, conn))
+	if err == nil {
+		err = c.opts.store.Set(userID, data, c.opts.storeTTL)
+	}
+	if err != nil {
+		zlog.Ins().ErrorF("zcluster: save session of user %s err: %v", userID, err)
+	}
+	if err := c.opts.store.RemovePresence(userID, c.node); err != nil {
+		zlog.Ins().ErrorF("zcluster: remove presence of user %s err: %v", userID, err)
+	}
+}
+
+func (c *Cluster) setPresence(userID string) {
+	if c.opts.store == nil {
+		return
+	}
+	if err := c.opts.store.SetPresence(userID, c.node, c.presenceTTL()); err != nil {
+		zlog.Ins().ErrorF("zcluster: set presence of user %s err: %v", userID, err)
+	}
+}
+
+// refreshPresence keeps the presence of the local users alive in the session store (在会话存储中保持本地用户的在线信息)
+func (c *Cluster) refreshPresence() {
+	if c.opts.store == nil {
+		return
+	}
+	c.mu.RLock()
+	users := make([]string, 0, len(c.local))
+	for userID := range c.local {
+		users = append(users, userID)
+	}
+	c.mu.RUnlock()
+	for _, userID := range users {
+		c.setPresence(userID)
+	}
+}
+
+func (c *Cluster) storedPresence(userID string) (string, bool) {
+	if c.opts.store == nil {
+		return "", false
+	}
+	node, err := c.opts.store.Presence(userID)
+	if err != nil {
+		zlog.Ins().ErrorF("zcluster: presence of user %s err: %v", userID, err)
+	}
+	return node, node != ""
+}
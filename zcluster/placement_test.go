@@ -0,0 +1,111 @@
+package zcluster
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	assert.Equal(t, "", NewRing(10).Get("alice"))
+
+	three := NewRing(100, "c", "a", "b")
+	four := NewRing(100, "a", "b", "c", "d")
+	assert.Equal(t, []string{"a", "b", "c"}, three.Nodes())
+
+	// Adding a node only moves keys to it (新增节点只会把键移动到该节点)
+	counts := make(map[string]int)
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		before, after := three.Get(key), four.Get(key)
+		assert.Equal(t, before, three.Get(key))
+		if before != after {
+			assert.Equal(t, "d", after)
+			moved++
+		}
+		counts[before]++
+	}
+	assert.InDelta(t, 750, moved, 250)
+	for _, node := range three.Nodes() {
+		assert.InDelta(t, 1000, counts[node], 300)
+	}
+
+	// The bounded load skips the full nodes (负载上限跳过已满的节点)
+	loads := map[string]int{"a": 10, "b": 0, "c": 0}
+	assert.Equal(t, 4, BoundedCapacity(loads, 3, 1))
+	for i := 0; i < 50; i++ {
+		assert.NotEqual(t, "a", three.GetBounded(fmt.Sprintf("user-%d", i), loads, 1.25))
+	}
+}
+
+type sessionConn struct {
+	testConn
+	properties map[string]interface{}
+}
+
+func (c *sessionConn) GetProperties() map[string]interface{} { return c.properties }
+
+func (c *sessionConn) SetProperty(key string, value interface{}) { c.properties[key] = value }
+
+func TestClusterRebalance(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+
+	var mu sync.Mutex
+	handed := make(map[string]string)
+	s := &testServer{connMgr: &testConnMgr{}, pubSub: &testPubSub{}}
+	a := New("a", bus, s, WithHeartbeat(50*time.Millisecond), WithOnRebalance(func(userID string, conn ziface.IConnection, to Member) {
+		mu.Lock()
+		defer mu.Unlock()
+		handed[userID] = to.ID
+	}))
+	assert.Nil(t, a.Start())
+	defer a.Stop()
+	for i := 0; i < 100; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		assert.Equal(t, "a", a.Place(userID).ID)
+		conn := &sessionConn{testConn: testConn{id: uint64(i)}, properties: map[string]interface{}{"level": i}}
+		assert.Nil(t, a.Bind(userID, conn))
+	}
+
+	b := New("b", bus, s, WithHeartbeat(50*time.Millisecond))
+	assert.Nil(t, b.Start())
+	defer b.Stop()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handed) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	// Only the users of b on the ring are handed over to it (只有哈希环上属于b的用户被移交给b)
+	ring := NewRing(100, "a", "b")
+	mu.Lock()
+	for userID, to := range handed {
+		assert.Equal(t, "b", to)
+		assert.Equal(t, "b", ring.Get(userID))
+	}
+	var userID string
+	for userID = range handed {
+		break
+	}
+	mu.Unlock()
+
+	// b imports the session when the user binds there (用户在b上绑定时b导入其会话)
+	conn := &sessionConn{properties: make(map[string]interface{})}
+	assert.Eventually(t, func() bool {
+		b.mu.RLock()
+		defer b.mu.RUnlock()
+		_, ok := b.sessions[userID]
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	assert.Nil(t, b.Bind(userID, conn))
+	var level int
+	_, _ = fmt.Sscanf(userID, "user-%d", &level)
+	assert.Equal(t, float64(level), conn.properties["level"])
+	assert.Eventually(t, func() bool { return a.Place(userID).ID == "b" }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,198 @@
+package zcluster
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	dialTimeout     = 5 * time.Second
+	minReconnectGap = 100 * time.Millisecond
+	maxReconnectGap = 5 * time.Second
+)
+
+// RedisBus is a Bus over Redis pub/sub, the subjects are the channels, it reconnects and subscribes again when
+// the connection is lost, the messages published meanwhile are missed
+// (基于Redis pub/sub的总线，subject即频道，连接断开时重新连接并重新订阅，期间发布的消息会丢失)
+type RedisBus struct {
+	addr, password string
+	subs           subscriptions
+
+	pubMu sync.Mutex
+	pub   *respConn
+
+	// subMu guards the subscriber connection and its writes (保护订阅连接及其写入)
+	subMu  sync.Mutex
+	sub    *respConn
+	closed bool
+	done   chan struct{}
+}
+
+// NewRedisBus connects to the Redis of rawURL, e.g. "redis://:password@127.0.0.1:6379"
+// (连接rawURL指定的Redis，例如"redis://:password@127.0.0.1:6379")
+func NewRedisBus(rawURL string) (*RedisBus, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	b := &RedisBus{addr: u.Host, done: make(chan struct{})}
+	if u.User != nil {
+		b.password, _ = u.User.Password()
+	}
+	sub, err := b.subscribe()
+	if err != nil {
+		return nil, err
+	}
+	go b.run(sub)
+	return b, nil
+}
+
+func (b *RedisBus) Publish(subject string, data []byte) error {
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+	var err error
+	// A connection broken since the last publish is dialed again once (上次发布后断开的连接会重新连接一次)
+	for i := 0; i < 2; i++ {
+		if b.pub == nil {
+			if b.isClosed() {
+				return ErrBusClosed
+			}
+			if b.pub, err = dialResp(b.addr, b.password, dialTimeout); err != nil {
+				return err
+			}
+		}
+		if _, err = b.pub.do("PUBLISH", subject, string(data)); err == nil {
+			return nil
+		}
+		b.pub.close()
+		b.pub = nil
+	}
+	return err
+}
+
+func (b *RedisBus) Subscribe(subject string, handler func(data []byte)) (func(), error) {
+	id, first := b.subs.add(subject, handler)
+	if first {
+		b.command("SUBSCRIBE", subject)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if b.subs.remove(subject, id) {
+				b.command("UNSUBSCRIBE", subject)
+			}
+		})
+	}, nil
+}
+
+// command sends a command on the subscriber connection, a lost connection subscribes again when reconnected
+// (在订阅连接上发送命令，连接断开时重新连接后会重新订阅)
+func (b *RedisBus) command(args ...string) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	if b.sub != nil {
+		if err := b.sub.send(args...); err != nil {
+			b.sub.close()
+		}
+	}
+}
+
+func (b *RedisBus) Close() error {
+	b.subMu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+		if b.sub != nil {
+			b.sub.close()
+		}
+	}
+	b.subMu.Unlock()
+
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+	if b.pub != nil {
+		b.pub.close()
+		b.pub = nil
+	}
+	return nil
+}
+
+func (b *RedisBus) isClosed() bool {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	return b.closed
+}
+
+// subscribe dials the subscriber connection and subscribes to every subject (建立订阅连接并订阅所有subject)
+func (b *RedisBus) subscribe() (*respConn, error) {
+	conn, err := dialResp(b.addr, b.password, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	if b.closed {
+		conn.close()
+		return nil, ErrBusClosed
+	}
+	if subjects := b.subs.subjects(); len(subjects) > 0 {
+		if err := conn.send(append([]string{"SUBSCRIBE"}, subjects...)...); err != nil {
+			conn.close()
+			return nil, err
+		}
+	}
+	b.sub = conn
+	return conn, nil
+}
+
+func (b *RedisBus) run(conn *respConn) {
+	gap := minReconnectGap
+	for {
+		if conn != nil {
+			gap = minReconnectGap
+			b.read(conn)
+		}
+		if b.isClosed() {
+			return
+		}
+		select {
+		case <-b.done:
+			return
+		case <-time.After(gap):
+		}
+		if gap *= 2; gap > maxReconnectGap {
+			gap = maxReconnectGap
+		}
+		var err error
+		if conn, err = b.subscribe(); err != nil {
+			zlog.Ins().ErrorF("zcluster: reconnect redis %s err: %v", b.addr, err)
+		}
+	}
+}
+
+// read dispatches the messages of conn until it fails (分发conn上的消息直到连接出错)
+func (b *RedisBus) read(conn *respConn) {
+	defer conn.close()
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			if !b.isClosed() {
+				zlog.Ins().ErrorF("zcluster: redis %s subscription err: %v", b.addr, err)
+			}
+			return
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 3 {
+			continue
+		}
+		kind, _ := items[0].([]byte)
+		subject, _ := items[1].([]byte)
+		data, _ := items[2].([]byte)
+		if string(kind) == "message" {
+			b.subs.dispatch(string(subject), data)
+		}
+	}
+}
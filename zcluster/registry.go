@@ -0,0 +1,162 @@
+package zcluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// Member is a node registered in a Registry (注册在Registry中的节点)
+type Member struct {
+	ID   string            `json:"id"`             // The node ID of the Cluster (Cluster的节点ID)
+	Addr string            `json:"addr,omitempty"` // The address the node serves on (节点的服务地址)
+	Meta map[string]string `json:"meta,omitempty"` // Free form attributes, e.g. the zone (自定义属性，例如所在区域)
+}
+
+// Registry registers the nodes and discovers the healthy ones, NewConsulRegistry, NewEtcdRegistry and
+// NewKubernetesRegistry use Consul, etcd and the Kubernetes endpoints, other registries plug in by implementing it
+// (注册节点并发现健康的节点，NewConsulRegistry、NewEtcdRegistry与NewKubernetesRegistry分别使用Consul、etcd和Kubernetes
+// endpoints，实现该接口即可接入其他注册中心)
+type Registry interface {
+	// Register registers m and keeps it healthy until Deregister is called (注册m并保持其健康，直到调用Deregister)
+	Register(m Member) error
+	// Deregister removes the member id (移除成员id)
+	Deregister(id string) error
+	// Members returns the healthy members (返回健康的成员)
+	Members() ([]Member, error)
+}
+
+type registryOptions struct {
+	ttl    time.Duration
+	client *http.Client
+}
+
+// RegistryOption configures the registries (注册中心的配置项)
+type RegistryOption func(o *registryOptions)
+
+// WithRegistryTTL sets how long a member stays healthy without renewing its registration, 15 seconds by default,
+// the registration is renewed every third of it (设置成员未续约时保持健康的时长，默认15秒，每三分之一周期续约一次)
+func WithRegistryTTL(ttl time.Duration) RegistryOption {
+	return func(o *registryOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithRegistryHTTPClient sets the HTTP client talking to the registry, e.g. for TLS
+// (设置访问注册中心的HTTP客户端，例如用于TLS)
+func WithRegistryHTTPClient(client *http.Client) RegistryOption {
+	return func(o *registryOptions) {
+		o.client = client
+	}
+}
+
+func newRegistryOptions(opts []RegistryOption) *registryOptions {
+	o := &registryOptions{ttl: 15 * time.Second}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.ttl < 3*time.Millisecond {
+		panic("zcluster: registry ttl is too short")
+	}
+	if o.client == nil {
+		o.client = &http.Client{Timeout: o.ttl / 3}
+	}
+	return o
+}
+
+// doJSON sends body encoded in JSON and decodes the response into out, out may be nil
+// (以JSON编码发送body并将响应解码到out，out可以为nil)
+func doJSON(client *http.Client, method, url string, header http.Header, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("zcluster: %s %s: %s %s", method, url, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// keepAlive calls renew every interval until stop is closed (每隔interval调用renew，直到stop被关闭)
+func keepAlive(interval time.Duration, stop chan struct{}, name string, renew func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if err := renew(); err != nil {
+			zlog.Ins().ErrorF("zcluster: %s registry renew err: %v", name, err)
+		}
+	}
+}
+
+// MemoryRegistry is a Registry within the process, for the tests and the nodes sharing a process
+// (进程内的Registry，用于测试以及同一进程中的多个节点)
+type MemoryRegistry struct {
+	mu      sync.RWMutex
+	members map[string]Member
+}
+
+// NewMemoryRegistry creates a MemoryRegistry (创建MemoryRegistry)
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{members: make(map[string]Member)}
+}
+
+func (r *MemoryRegistry) Register(m Member) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.members[m.ID] = m
+	return nil
+}
+
+func (r *MemoryRegistry) Deregister(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.members, id)
+	return nil
+}
+
+func (r *MemoryRegistry) Members() ([]Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	members := make([]Member, 0, len(r.members))
+	for _, m := range r.members {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members, nil
+}
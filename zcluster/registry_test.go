@@ -0,0 +1,175 @@
+package zcluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConsul keeps the services whose TTL check passed (保存TTL检查已通过的服务)
+func fakeConsul(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	services := make(map[string]consulService)
+	passing := make(map[string]bool)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+		switch {
+		case r.URL.Path == "/v1/agent/service/register":
+			var service consulService
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&service))
+			services[service.ID] = service
+		case strings.HasPrefix(r.URL.Path, "/v1/agent/check/pass/"):
+			passing[strings.TrimPrefix(r.URL.Path, "/v1/agent/check/pass/zinx:game:")] = true
+		case strings.HasPrefix(r.URL.Path, "/v1/agent/service/deregister/"):
+			delete(services, strings.TrimPrefix(r.URL.Path, "/v1/agent/service/deregister/"))
+		case r.URL.Path == "/v1/health/service/game":
+			assert.Equal(t, "true", r.URL.Query().Get("passing"))
+			var entries []map[string]consulService
+			for id, service := range services {
+				if passing[id] {
+					entries = append(entries, map[string]consulService{"Service": service})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(entries)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// fakeEtcd keeps the keys of the live leases (保存存活租约的键)
+func fakeEtcd(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	kvs := make(map[string][]byte)
+	leases := make(map[string]string) // lease -> key
+	var nextLease int
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var body map[string]json.RawMessage
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		lease := strings.Trim(string(body["ID"]), `"`)
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			_, _ = w.Write([]byte(`{"token":"tok"}`))
+			return
+		case "/v3/lease/grant":
+			nextLease++
+			_, _ = w.Write([]byte(`{"ID":"` + string(rune('0'+nextLease)) + `","TTL":"1"}`))
+			return
+		case "/v3/lease/keepalive":
+			if _, ok := leases[lease]; ok {
+				_, _ = w.Write([]byte(`{"result":{"ID":"` + lease + `","TTL":"1"}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"result":{"ID":"` + lease + `"}}`))
+			}
+			return
+		}
+		assert.Equal(t, "tok", r.Header.Get("Authorization"))
+		var key, value []byte
+		_ = json.Unmarshal(body["key"], &key)
+		_ = json.Unmarshal(body["value"], &value)
+		switch r.URL.Path {
+		case "/v3/kv/put":
+			kvs[string(key)] = value
+			leases[strings.Trim(string(body["lease"]), `"`)] = string(key)
+		case "/v3/lease/revoke":
+			delete(kvs, leases[lease])
+			delete(leases, lease)
+		case "/v3/kv/range":
+			var resp struct {
+				Kvs []map[string][]byte `json:"kvs"`
+			}
+			for k, v := range kvs {
+				if strings.HasPrefix(k, string(key)) {
+					resp.Kvs = append(resp.Kvs, map[string][]byte{"key": []byte(k), "value": v})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(&resp)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestConsulRegistry(t *testing.T) {
+	server := fakeConsul(t)
+	defer server.Close()
+	r, err := NewConsulRegistry("http://secret@"+strings.TrimPrefix(server.URL, "http://"), "game",
+		WithRegistryTTL(30*time.Millisecond))
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.Register(Member{ID: "a", Addr: "10.0.0.1:8999", Meta: map[string]string{"zone": "east"}}))
+	assert.Nil(t, r.Register(Member{ID: "b", Addr: "10.0.0.2:8999"}))
+	members, err := r.Members()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []Member{
+		{ID: "a", Addr: "10.0.0.1:8999", Meta: map[string]string{"zone": "east"}},
+		{ID: "b", Addr: "10.0.0.2:8999"},
+	}, members)
+
+	time.Sleep(30 * time.Millisecond) // The checks keep passing (检查持续通过)
+	assert.Nil(t, r.Deregister("b"))
+	members, err = r.Members()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, memberIDs(members))
+	assert.Nil(t, r.Deregister("a"))
+}
+
+func TestEtcdRegistry(t *testing.T) {
+	server := fakeEtcd(t)
+	defer server.Close()
+	r, err := NewEtcdRegistry("http://root:pw@"+strings.TrimPrefix(server.URL, "http://"), "/zinx/game",
+		WithRegistryTTL(30*time.Millisecond))
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.Register(Member{ID: "a", Addr: "10.0.0.1:8999"}))
+	assert.Nil(t, r.Register(Member{ID: "b", Addr: "10.0.0.2:8999"}))
+	members, err := r.Members()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, memberIDs(members))
+
+	assert.Nil(t, r.Deregister("b"))
+	members, err = r.Members()
+	assert.Nil(t, err)
+	assert.Equal(t, []Member{{ID: "a", Addr: "10.0.0.1:8999"}}, members)
+	assert.Nil(t, r.Deregister("a"))
+}
+
+func TestKubernetesRegistry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		assert.Equal(t, "/api/v1/namespaces/prod/endpoints/game", r.URL.Path)
+		_, _ = w.Write([]byte(`{"subsets":[{
+			"addresses":[{"ip":"10.0.0.1","targetRef":{"kind":"Pod","name":"game-0"}},{"ip":"10.0.0.2"}],
+			"notReadyAddresses":[{"ip":"10.0.0.3","targetRef":{"kind":"Pod","name":"game-2"}}],
+			"ports":[{"name":"metrics","port":9100},{"name":"tcp","port":8999}]}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, err := NewKubernetesRegistry("", "game", "")
+	assert.NotNil(t, err)
+	r, err := NewKubernetesRegistryAt(server.URL, "tok", "prod", "game", "tcp")
+	assert.Nil(t, err)
+	assert.Nil(t, r.Register(Member{ID: "game-0"}))
+	members, err := r.Members()
+	assert.Nil(t, err)
+	assert.Equal(t, []Member{{ID: "game-0", Addr: "10.0.0.1:8999"}, {ID: "10.0.0.2", Addr: "10.0.0.2:8999"}}, members)
+}
+
+func memberIDs(members []Member) []string {
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
@@ -0,0 +1,94 @@
+package zcluster
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Ring is a consistent hash ring of nodes, adding or removing a node only moves the keys of that node
+// (节点的一致性哈希环，增删节点只会移动该节点上的键)
+type Ring struct {
+	nodes  []string
+	hashes []uint64
+	owners map[uint64]string
+}
+
+// NewRing creates the ring of nodes with replicas virtual nodes each (创建nodes的哈希环，每个节点有replicas个虚拟节点)
+func NewRing(replicas int, nodes ...string) *Ring {
+	if replicas <= 0 {
+		panic("zcluster: ring replicas must be positive")
+	}
+	r := &Ring{
+		nodes:  append([]string(nil), nodes...),
+		hashes: make([]uint64, 0, replicas*len(nodes)),
+		owners: make(map[uint64]string, replicas*len(nodes)),
+	}
+	sort.Strings(r.nodes)
+	for _, node := range r.nodes {
+		for i := 0; i < replicas; i++ {
+			h := hashKey(node + "#" + strconv.Itoa(i))
+			if _, ok := r.owners[h]; ok {
+				continue
+			}
+			r.owners[h] = node
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	// fnv spreads the close keys poorly over the high bits, mix them (fnv对相近键的高位分布较差，对其进行混合)
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+// Nodes returns the nodes of the ring, sorted (返回环上的节点，已排序)
+func (r *Ring) Nodes() []string {
+	return append([]string(nil), r.nodes...)
+}
+
+// Get returns the node owning key, "" if the ring is empty (返回拥有key的节点，环为空时返回"")
+func (r *Ring) Get(key string) string {
+	return r.GetBounded(key, nil, 0)
+}
+
+// GetBounded returns the node owning key among the nodes whose load stays within loadFactor times the average
+// once key is added, loads holds the number of keys of the nodes, a loadFactor below 1 disables the bound
+// (在加入key后负载不超过平均值loadFactor倍的节点中返回拥有key的节点，loads为各节点的键数量，loadFactor小于1时不限制负载)
+func (r *Ring) GetBounded(key string, loads map[string]int, loadFactor float64) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	capacity := math.MaxInt32
+	if loadFactor >= 1 {
+		capacity = BoundedCapacity(loads, len(r.nodes), loadFactor)
+	}
+	h := hashKey(key)
+	start := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	for i := 0; i < len(r.hashes); i++ {
+		node := r.owners[r.hashes[(start+i)%len(r.hashes)]]
+		if loads[node] < capacity {
+			return node
+		}
+	}
+	return r.owners[r.hashes[start%len(r.hashes)]]
+}
+
+// BoundedCapacity returns how many keys a node holds at most once a key is added to loads spread over nodes
+// (返回向分布在nodes个节点上的loads再加入一个键后，单个节点最多持有的键数量)
+func BoundedCapacity(loads map[string]int, nodes int, loadFactor float64) int {
+	total := 1
+	for _, load := range loads {
+		total += load
+	}
+	return int(math.Ceil(loadFactor * float64(total) / float64(nodes)))
+}
@@ -0,0 +1,153 @@
+package zcluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+var (
+	ErrNodeNotFound = errors.New("zcluster: node not found")
+	ErrNoHandler    = errors.New("zcluster: no handler for the call")
+	ErrCallTimeout  = errors.New("zcluster: call timed out")
+)
+
+// CallHandler handles the calls of msgID from the node from and returns the reply
+// (处理来自节点from的msgID调用并返回应答)
+type CallHandler func(from string, data []byte) ([]byte, error)
+
+// RemoteError is the error returned by the handler of a call on another node (其他节点上调用处理函数返回的错误)
+type RemoteError struct {
+	Node    string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("zcluster: node %s: %s", e.Node, e.Message)
+}
+
+// calls keeps the handlers and the calls waiting for their reply (保存处理函数与等待应答的调用)
+type calls struct {
+	mu       sync.RWMutex
+	handlers map[uint32]CallHandler
+	pending  map[uint64]chan *envelope
+	seq      uint64
+}
+
+// Handle makes handler handle the calls of msgID from the other nodes, e.g. "kick user" or "query presence", the
+// handlers run concurrently (使handler处理其他节点对msgID的调用，例如"踢出用户"或"查询在线状态"，处理函数并发执行)
+func (c *Cluster) Handle(msgID uint32, handler CallHandler) {
+	c.calls.mu.Lock()
+	defer c.calls.mu.Unlock()
+	if c.calls.handlers == nil {
+		c.calls.handlers = make(map[uint32]CallHandler)
+	}
+	c.calls.handlers[msgID] = handler
+}
+
+func (c *Cluster) handler(msgID uint32) CallHandler {
+	c.calls.mu.RLock()
+	defer c.calls.mu.RUnlock()
+	return c.calls.handlers[msgID]
+}
+
+// Call invokes the handler of msgID on the node nodeID and returns its reply, it times out after the call timeout,
+// see WithCallTimeout (调用节点nodeID上msgID的处理函数并返回其应答，超过调用超时时间后超时，见WithCallTimeout)
+func (c *Cluster) Call(nodeID string, msgID uint32, data []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.callTimeout)
+	defer cancel()
+	return c.CallContext(ctx, nodeID, msgID, data)
+}
+
+// CallContext is Call until ctx is done (在ctx结束前执行Call)
+func (c *Cluster) CallContext(ctx context.Context, nodeID string, msgID uint32, data []byte) ([]byte, error) {
+	if nodeID == c.node {
+		handler := c.handler(msgID)
+		if handler == nil {
+			return nil, ErrNoHandler
+		}
+		return handler(c.node, data)
+	}
+	found := false
+	for _, node := range c.Nodes() {
+		found = found || node == nodeID
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+	}
+
+	seq := atomic.AddUint64(&c.calls.seq, 1)
+	reply := make(chan *envelope, 1)
+	c.calls.mu.Lock()
+	if c.calls.pending == nil {
+		c.calls.pending = make(map[uint64]chan *envelope)
+	}
+	c.calls.pending[seq] = reply
+	c.calls.mu.Unlock()
+	defer func() {
+		c.calls.mu.Lock()
+		delete(c.calls.pending, seq)
+		c.calls.mu.Unlock()
+	}()
+
+	if err := c.publish(c.nodeSubject(nodeID), &envelope{Type: typeCall, MsgID: msgID, Seq: seq, Data: data}); err != nil {
+		return nil, err
+	}
+	select {
+	case env := <-reply:
+		switch {
+		case env.Error == "":
+			return env.Data, nil
+		case env.Error == ErrNoHandler.Error():
+			return nil, ErrNoHandler
+		}
+		return nil, &RemoteError{Node: env.Node, Message: env.Error}
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrCallTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// serveCall runs the handler of the call and replies to the caller (执行调用的处理函数并应答调用方)
+func (c *Cluster) serveCall(env *envelope) {
+	reply := &envelope{Type: typeReply, Seq: env.Seq}
+	handler := c.handler(env.MsgID)
+	if handler == nil {
+		reply.Error = ErrNoHandler.Error()
+	} else {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					zlog.Ins().ErrorF("zcluster: call msgID = %d from node %s panic: %v", env.MsgID, env.Node, err)
+					reply.Error = fmt.Sprintf("panic: %v", err)
+				}
+			}()
+			data, err := handler(env.Node, env.Data)
+			if err != nil {
+				reply.Error = err.Error()
+				return
+			}
+			reply.Data = data
+		}()
+	}
+	c.publish(c.nodeSubject(env.Node), reply)
+}
+
+// receiveReply hands the reply over to the waiting call, if it did not give up yet (将应答交给仍在等待的调用)
+func (c *Cluster) receiveReply(env *envelope) {
+	c.calls.mu.RLock()
+	reply, ok := c.calls.pending[env.Seq]
+	c.calls.mu.RUnlock()
+	if ok {
+		select {
+		case reply <- env:
+		default:
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package zcluster
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a ziface.ISessionStore within the process, for the tests and the single node deployments, its
+// entries do not survive the restarts (进程内的ziface.ISessionStore，用于测试与单节点部署，其条目在重启后不再存在)
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]storeEntry
+	presence map[string]storeEntry
+}
+
+type storeEntry struct {
+	value    []byte
+	deadline time.Time
+}
+
+func (e storeEntry) expired(now time.Time) bool {
+	return !e.deadline.IsZero() && !now.Before(e.deadline)
+}
+
+func deadlineOf(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// NewMemoryStore creates a MemoryStore (创建MemoryStore)
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]storeEntry), presence: make(map[string]storeEntry)}
+}
+
+// lookup returns the live entry of key in entries, s.mu is held (返回entries中key的有效条目，调用时持有s.mu)
+func (s *MemoryStore) lookup(entries map[string]storeEntry, key string) (storeEntry, bool) {
+	e, ok := entries[key]
+	if ok && e.expired(time.Now()) {
+		delete(entries, key)
+		return storeEntry{}, false
+	}
+	return e, ok
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, _ := s.lookup(s.sessions, key)
+	return append([]byte(nil), e.value...), nil
+}
+
+func (s *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[key] = storeEntry{value: append([]byte(nil), value...), deadline: deadlineOf(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Expire(key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.lookup(s.sessions, key)
+	if ok {
+		e.deadline = deadlineOf(ttl)
+		s.sessions[key] = e
+	}
+	return ok, nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, key)
+	return nil
+}
+
+func (s *MemoryStore) SetPresence(userID, nodeID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.presence[userID] = storeEntry{value: []byte(nodeID), deadline: deadlineOf(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Presence(userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, _ := s.lookup(s.presence, userID)
+	return string(e.value), nil
+}
+
+func (s *MemoryStore) RemovePresence(userID, nodeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.lookup(s.presence, userID); ok && string(e.value) == nodeID {
+		delete(s.presence, userID)
+	}
+	return nil
+}
+
+// removePresenceScript deletes the presence only if it still names the node (仅当在线信息仍指向该节点时删除)
+const removePresenceScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// RedisStore is a ziface.ISessionStore on Redis, the sessions are stored under "<prefix>session:<key>" and the
+// presence under "<prefix>presence:<userID>", it is a ziface.INonceStore too (基于Redis的ziface.ISessionStore，
+// 会话保存在"<prefix>session:<key>"，在线信息保存在"<prefix>presence:<userID>"，同时也是ziface.INonceStore)
+type RedisStore struct {
+	addr, password string
+	db             string
+	prefix         string
+
+	mu   sync.Mutex
+	conn *respConn
+}
+
+// NewRedisStore connects to the Redis of rawURL, e.g. "redis://:password@127.0.0.1:6379/2" for the database 2,
+// the keys start with prefix, e.g. "zinx:" (连接rawURL指定的Redis，例如使用2号数据库的"redis://:password@127.0.0.1:6379/2"，
+// 键以prefix开头，例如"zinx:")
+func NewRedisStore(rawURL, prefix string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	s := &RedisStore{addr: u.Host, db: strings.Trim(u.Path, "/"), prefix: prefix}
+	if u.User != nil {
+		s.password, _ = u.User.Password()
+	}
+	if s.db != "" {
+		if _, err := strconv.Atoi(s.db); err != nil {
+			return nil, errors.New("zcluster: invalid redis database " + s.db)
+		}
+	}
+	if _, err := s.do("PING"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// do runs a command, a connection broken since the last command is dialed again once
+// (执行命令，上次命令后断开的连接会重新连接一次)
+func (s *RedisStore) do(args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if s.conn, err = dialResp(s.addr, s.password, dialTimeout); err != nil {
+				s.conn = nil
+				return nil, err
+			}
+			if s.db != "" {
+				if _, err = s.conn.do("SELECT", s.db); err != nil {
+					s.conn.close()
+					s.conn = nil
+					return nil, err
+				}
+			}
+		}
+		var reply interface{}
+		_ = s.conn.conn.SetDeadline(time.Now().Add(dialTimeout))
+		if reply, err = s.conn.do(args...); err == nil || strings.HasPrefix(err.Error(), "zcluster: redis: ") {
+			return reply, err
+		}
+		s.conn.close()
+		s.conn = nil
+	}
+	return nil, err
+}
+
+// withTTL appends the expiry of ttl to the SET command args (为SET命令参数追加ttl对应的过期时间)
+func withTTL(args []string, ttl time.Duration) []string {
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttlMillis(ttl), 10))
+	}
+	return args
+}
+
+func ttlMillis(ttl time.Duration) int64 {
+	if ms := ttl.Milliseconds(); ms > 0 {
+		return ms
+	}
+	return 1
+}
+
+func (s *RedisStore) Get(key string) ([]byte, error) {
+	reply, err := s.do("GET", s.prefix+"session:"+key)
+	value, _ := reply.([]byte)
+	return value, err
+}
+
+func (s *RedisStore) Set(key string, value []byte, ttl time.Duration) error {
+	_, err := s.do(withTTL([]string{"SET", s.prefix + "session:" + key, string(value)}, ttl)...)
+	return err
+}
+
+func (s *RedisStore) Expire(key string, ttl time.Duration) (bool, error) {
+	var reply interface{}
+	var err error
+	if ttl > 0 {
+		reply, err = s.do("PEXPIRE", s.prefix+"session:"+key, strconv.FormatInt(ttlMillis(ttl), 10))
+	} else {
+		// PERSIST answers 0 for the keys without expiry too, EXISTS tells them apart (对没有过期时间的键PERSIST也返回0，用EXISTS区分)
+		if _, err = s.do("PERSIST", s.prefix+"session:"+key); err == nil {
+			reply, err = s.do("EXISTS", s.prefix+"session:"+key)
+		}
+	}
+	n, _ := reply.(int64)
+	return n == 1, err
+}
+
+func (s *RedisStore) Delete(key string) error {
+	_, err := s.do("DEL", s.prefix+"session:"+key)
+	return err
+}
+
+func (s *RedisStore) SetPresence(userID, nodeID string, ttl time.Duration) error {
+	_, err := s.do(withTTL([]string{"SET", s.prefix + "presence:" + userID, nodeID}, ttl)...)
+	return err
+}
+
+func (s *RedisStore) Presence(userID string) (string, error) {
+	reply, err := s.do("GET", s.prefix+"presence:"+userID)
+	node, _ := reply.([]byte)
+	return string(node), err
+}
+
+func (s *RedisStore) RemovePresence(userID, nodeID string) error {
+	_, err := s.do("EVAL", removePresenceScript, "1", s.prefix+"presence:"+userID, nodeID)
+	return err
+}
+
+// Add implements ziface.INonceStore, the nonces are stored under "<prefix>nonce:<nonce>", see znet.WithNonceStore
+// (实现ziface.INonceStore，nonce保存在"<prefix>nonce:<nonce>"，见znet.WithNonceStore)
+func (s *RedisStore) Add(nonce string, ttl time.Duration) (bool, error) {
+	reply, err := s.do(append(withTTL([]string{"SET", s.prefix + "nonce:" + nonce, "1"}, ttl), "NX")...)
+	return reply != nil, err
+}
+
+// Close closes the connection (关闭连接)
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.close()
+		s.conn = nil
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package zcluster
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveRedisKV is a Redis keeping the keys with their expiry (保存键及其过期时间的Redis)
+func serveRedisKV() func(b *fakeBroker, conn net.Conn) {
+	var mu sync.Mutex
+	values := make(map[string]string)
+	deadlines := make(map[string]time.Time)
+	live := func(key string) (string, bool) {
+		if d, ok := deadlines[key]; ok && !time.Now().Before(d) {
+			delete(values, key)
+			delete(deadlines, key)
+		}
+		value, ok := values[key]
+		return value, ok
+	}
+	bulk := func(value string, ok bool) string {
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+	}
+	integer := func(ok bool) string {
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	}
+	return func(b *fakeBroker, conn net.Conn) {
+		c := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+		for {
+			reply, err := c.read()
+			if err != nil {
+				return
+			}
+			var args []string
+			for _, item := range reply.([]interface{}) {
+				args = append(args, string(item.([]byte)))
+			}
+			mu.Lock()
+			var out string
+			switch strings.ToUpper(args[0]) {
+			case "PING":
+				out = "+PONG\r\n"
+			case "AUTH", "SELECT":
+				out = "+OK\r\n"
+			case "GET":
+				out = bulk(live(args[1]))
+			case "SET":
+				if _, ok := live(args[1]); ok && strings.ToUpper(args[len(args)-1]) == "NX" {
+					out = "$-1\r\n"
+					break
+				}
+				values[args[1]] = args[2]
+				delete(deadlines, args[1])
+				if len(args) >= 5 {
+					ms, _ := strconv.Atoi(args[4])
+					deadlines[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+				}
+				out = "+OK\r\n"
+			case "PEXPIRE":
+				_, ok := live(args[1])
+				if ok {
+					ms, _ := strconv.Atoi(args[2])
+					deadlines[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+				}
+				out = integer(ok)
+			case "PERSIST":
+				_, ok := deadlines[args[1]]
+				delete(deadlines, args[1])
+				out = integer(ok)
+			case "EXISTS":
+				_, ok := live(args[1])
+				out = integer(ok)
+			case "DEL":
+				_, ok := live(args[1])
+				delete(values, args[1])
+				out = integer(ok)
+			case "EVAL":
+				value, ok := live(args[3])
+				if ok && value == args[4] {
+					delete(values, args[3])
+				}
+				out = integer(ok && value == args[4])
+			default:
+				out = "-ERR unknown command\r\n"
+			}
+			mu.Unlock()
+			_, _ = conn.Write([]byte(out))
+		}
+	}
+}
+
+func testSessionStore(t *testing.T, store ziface.ISessionStore) {
+	data, err := store.Get("alice")
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+
+	assert.Nil(t, store.Set("alice", []byte("level=3"), 0))
+	assert.Nil(t, store.Set("bob", []byte("level=5"), 30*time.Millisecond))
+	data, _ = store.Get("alice")
+	assert.Equal(t, "level=3", string(data))
+	ok, err := store.Expire("alice", 30*time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = store.Expire("bob", 0)
+	assert.True(t, ok)
+	ok, _ = store.Expire("carol", time.Second)
+	assert.False(t, ok)
+	time.Sleep(50 * time.Millisecond)
+	data, _ = store.Get("alice")
+	assert.Nil(t, data)
+	data, _ = store.Get("bob")
+	assert.Equal(t, "level=5", string(data))
+	assert.Nil(t, store.Delete("bob"))
+	data, _ = store.Get("bob")
+	assert.Nil(t, data)
+
+	assert.Nil(t, store.SetPresence("alice", "a", time.Second))
+	node, err := store.Presence("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", node)
+	// A stale node does not remove the presence of the newer one (过期节点不会移除较新节点的在线信息)
+	assert.Nil(t, store.RemovePresence("alice", "b"))
+	node, _ = store.Presence("alice")
+	assert.Equal(t, "a", node)
+	assert.Nil(t, store.RemovePresence("alice", "a"))
+	node, _ = store.Presence("alice")
+	assert.Equal(t, "", node)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testSessionStore(t, NewMemoryStore())
+}
+
+func TestRedisStore(t *testing.T) {
+	broker := newFakeBroker(t, serveRedisKV())
+	defer broker.close()
+	store, err := NewRedisStore("redis://:secret@"+broker.ln.Addr().String()+"/2", "zinx:")
+	assert.Nil(t, err)
+	defer store.Close()
+	testSessionStore(t, store)
+
+	fresh, err := store.Add("a1", 30*time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, fresh)
+	fresh, _ = store.Add("a1", 30*time.Millisecond)
+	assert.False(t, fresh)
+	time.Sleep(50 * time.Millisecond)
+	fresh, _ = store.Add("a1", 30*time.Millisecond)
+	assert.True(t, fresh)
+
+	// The store dials again after a broken connection (连接断开后存储会重新连接)
+	broker.drop()
+	assert.Nil(t, store.Set("alice", []byte("back"), 0))
+	data, _ := store.Get("alice")
+	assert.Equal(t, "back", string(data))
+}
+
+// The session of a user survives the restart of its node (用户的会话在其节点重启后仍然存在)
+func TestClusterSessionStore(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	store := NewMemoryStore()
+	s := &testServer{connMgr: &testConnMgr{}, pubSub: &testPubSub{}}
+	newNode := func(id string) *Cluster {
+		return New(id, bus, s, WithHeartbeat(50*time.Millisecond), WithSessionStore(store, time.Minute))
+	}
+
+	a := newNode("a")
+	assert.Nil(t, a.Start())
+	alice := &sessionConn{properties: map[string]interface{}{"room": "lobby"}}
+	assert.Nil(t, a.Bind("alice", alice))
+	node, _ := store.Presence("alice")
+	assert.Equal(t, "a", node)
+
+	// b finds alice without hearing about her on the bus (b未在总线上得知alice也能找到她)
+	b := newNode("b")
+	b.opts.prefix = "other"
+	assert.Nil(t, b.Start())
+	defer b.Stop()
+	node, ok := b.Locate("alice")
+	assert.True(t, ok)
+	assert.Equal(t, "a", node)
+
+	alice.onClose()
+	a.Stop()
+	_, ok = b.Locate("alice")
+	assert.False(t, ok)
+
+	restarted := newNode("a")
+	assert.Nil(t, restarted.Start())
+	defer restarted.Stop()
+	again := &sessionConn{properties: make(map[string]interface{})}
+	assert.Nil(t, restarted.Bind("alice", again))
+	assert.Equal(t, "lobby", again.properties["room"])
+}
@@ -0,0 +1,51 @@
+// Package zcodec provides the payload codecs used by typed handlers
+// (提供类型化处理函数使用的消息体编解码器)
+package zcodec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aceld/zinx/ziface"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// JSON encodes payloads with encoding/json (使用encoding/json编解码)
+	JSON ziface.ICodec = jsonCodec{}
+	// Proto encodes payloads with protobuf, values must implement proto.Message
+	// (使用protobuf编解码，值必须实现proto.Message)
+	Proto ziface.ICodec = protoCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("zcodec: %T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("zcodec: %T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
@@ -0,0 +1,112 @@
+// Package zcompress provides the compressors negotiated by the compression handshake
+// (提供压缩握手协商使用的压缩算法)
+package zcompress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+var (
+	// Gzip compresses with compress/gzip (使用compress/gzip压缩)
+	Gzip ziface.ICompressor = gzipCompressor{}
+	// Deflate compresses with compress/flate, it has a smaller header than gzip (使用compress/flate压缩，头部比gzip更小)
+	Deflate ziface.ICompressor = deflateCompressor{}
+
+	// MaxDecompressedSize bounds the size of decompressed data, protecting from decompression bombs
+	// (解压后数据的最大长度，防止压缩炸弹)
+	MaxDecompressedSize int64 = 64 * 1024 * 1024
+
+	ErrTooLarge = errors.New("zcompress: decompressed data exceeds MaxDecompressedSize")
+
+	compressors     = map[string]ziface.ICompressor{}
+	compressorsLock sync.RWMutex
+)
+
+func init() {
+	Register(Gzip)
+	Register(Deflate)
+}
+
+// Register adds a compressor which can be negotiated by its name, e.g. a snappy or zstd binding
+// (注册一个可按名称协商的压缩算法，例如snappy或zstd的实现)
+func Register(c ziface.ICompressor) {
+	compressorsLock.Lock()
+	defer compressorsLock.Unlock()
+	compressors[c.Name()] = c
+}
+
+// Get returns the compressor registered with name (返回以name注册的压缩算法)
+func Get(name string) (ziface.ICompressor, bool) {
+	compressorsLock.RLock()
+	defer compressorsLock.RUnlock()
+	c, ok := compressors[name]
+	return c, ok
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readAll(r)
+}
+
+type deflateCompressor struct{}
+
+func (deflateCompressor) Name() string { return "deflate" }
+
+func (deflateCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (deflateCompressor) Decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return readAll(r)
+}
+
+func readAll(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxDecompressedSize {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,31 @@
+package zcompress
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressors(t *testing.T) {
+	data := bytes.Repeat([]byte("zinx "), 1000)
+	for _, name := range []string{"gzip", "deflate"} {
+		c, ok := Get(name)
+		assert.True(t, ok)
+
+		compressed, err := c.Compress(data)
+		assert.Nil(t, err)
+		assert.Less(t, len(compressed), len(data))
+
+		got, err := c.Decompress(compressed)
+		assert.Nil(t, err)
+		assert.Equal(t, data, got)
+	}
+
+	old := MaxDecompressedSize
+	MaxDecompressedSize = 100
+	defer func() { MaxDecompressedSize = old }()
+	compressed, _ := Gzip.Compress(data)
+	_, err := Gzip.Decompress(compressed)
+	assert.Equal(t, ErrTooLarge, err)
+}
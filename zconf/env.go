@@ -41,6 +41,9 @@ func init() {
 	env.configFilePath = configFilePath
 }
 
+// GetConfigFilePath returns the config file path, when it does not exist a zinx.yaml, zinx.yml or zinx.toml
+// next to it is used instead
+// (返回配置文件路径，该文件不存在时改用同目录下的zinx.yaml、zinx.yml或zinx.toml)
 func GetConfigFilePath() string {
-	return env.configFilePath
+	return resolveConfigFile(env.configFilePath)
 }
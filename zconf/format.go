@@ -0,0 +1,127 @@
+package zconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config file formats, detected by the extension of the config file (配置文件格式，由配置文件扩展名识别)
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// configExts are tried in this order when the default zinx.json does not exist (默认的zinx.json不存在时按该顺序尝试的扩展名)
+var configExts = []string{".yaml", ".yml", ".toml"}
+
+// FormatOf returns the format of a config file by its extension, JSON for unknown extensions
+// (根据扩展名返回配置文件的格式，未知扩展名视为JSON)
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// resolveConfigFile returns path, or the first sibling of it named zinx.yaml, zinx.yml or zinx.toml when path does not exist
+// (返回path，path不存在时返回同目录下第一个存在的zinx.yaml、zinx.yml或zinx.toml)
+func resolveConfigFile(path string) string {
+	if exists, _ := PathExists(path); exists {
+		return path
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range configExts {
+		if exists, _ := PathExists(base + ext); exists {
+			return base + ext
+		}
+	}
+	return path
+}
+
+// decodeConfig decodes data in format, see Format*, into g.
+// YAML and TOML keys match the field names case-insensitively, either as is (TCPPort) or in snake case (tcp_port).
+// Sections such as tls, metrics or limits only group keys, their keys are applied like top level keys.
+// (按format(见Format*)将data解码到g。YAML和TOML的键不区分大小写地匹配字段名，可以是原名(TCPPort)或下划线形式(tcp_port)。
+// tls、metrics、limits等分节只用于对键分组，其中的键与顶层键同样生效)
+func decodeConfig(format string, data []byte, g *Config) error {
+	var tree map[string]interface{}
+	switch format {
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return err
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return err
+		}
+	default:
+		return json.Unmarshal(data, g)
+	}
+
+	flat := make(map[string]interface{})
+	if err := flattenConfig(tree, configKeys(), flat); err != nil {
+		return err
+	}
+	// The JSON round trip converts the values into the field types the same way as zinx.json
+	// (经JSON转换，字段类型的转换方式与zinx.json相同)
+	data, err := json.Marshal(flat)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, g)
+}
+
+// configKeys maps the lower case field names and snake case keys to the field names (将小写字段名和下划线键映射到字段名)
+func configKeys() map[string]string {
+	keys := make(map[string]string)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		keys[strings.ToLower(name)] = name
+		keys[strings.ToLower(KeyName(name))] = name
+	}
+	return keys
+}
+
+func flattenConfig(tree map[string]interface{}, keys map[string]string, flat map[string]interface{}) error {
+	for key, value := range tree {
+		if field, ok := keys[strings.ToLower(key)]; ok {
+			if _, dup := flat[field]; dup {
+				return fmt.Errorf("config key %s is set more than once", field)
+			}
+			if servers, ok := value.(map[string]interface{}); ok && field == "Servers" {
+				// The keys of the server instances are matched the same way (服务实例的键按同样方式匹配)
+				instances := make(map[string]interface{}, len(servers))
+				for name, section := range servers {
+					instance := make(map[string]interface{})
+					if tree, ok := section.(map[string]interface{}); ok {
+						if err := flattenConfig(tree, keys, instance); err != nil {
+							return fmt.Errorf("server instance %s: %w", name, err)
+						}
+					}
+					instances[name] = instance
+				}
+				value = instances
+			}
+			flat[field] = value
+			continue
+		}
+		// Unknown keys are ignored as in zinx.json (与zinx.json一样忽略未知的键)
+		if section, ok := value.(map[string]interface{}); ok {
+			if err := flattenConfig(section, keys, flat); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
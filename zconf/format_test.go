@@ -0,0 +1,94 @@
+package zconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeConfigFormats(t *testing.T) {
+	yamlData := `
+name: yaml-server
+tcp_port: 9100
+WorkerPoolSize: 8
+tls:
+  cert_file: server.crt
+  PrivateKeyFile: server.key
+limits:
+  max_conn: 500
+header_fields: [flags, crc32]
+`
+	tomlData := `
+# zinx.toml
+name = "toml-server"
+tcp_port = 9_100
+WorkerPoolSize = 8
+header_fields = [
+  "flags", # optional fields
+  "crc32",
+]
+
+[tls]
+cert_file = 'server.crt'
+PrivateKeyFile = "server.key"
+
+[limits]
+max_conn = 500
+`
+	for path, data := range map[string]string{"zinx.yaml": yamlData, "zinx.toml": tomlData} {
+		g := &Config{}
+		assert.Nil(t, decodeConfig(FormatOf(path), []byte(data), g), path)
+		assert.Equal(t, 9100, g.TCPPort, path)
+		assert.Equal(t, uint32(8), g.WorkerPoolSize, path)
+		assert.Equal(t, "server.crt", g.CertFile, path)
+		assert.Equal(t, "server.key", g.PrivateKeyFile, path)
+		assert.Equal(t, 500, g.MaxConn, path)
+		assert.Equal(t, []string{"flags", "crc32"}, g.HeaderFields, path)
+	}
+
+	assert.NotNil(t, decodeConfig(FormatYAML, []byte("tcp_port: 1\nTCPPort: 2\n"), &Config{}))
+	assert.NotNil(t, decodeConfig(FormatTOML, []byte("tcp_port = \n"), &Config{}))
+	assert.NotNil(t, decodeConfig(FormatTOML, []byte("[[servers]]\n"), &Config{}))
+}
+
+func TestDecodeConfigTOML(t *testing.T) {
+	// Multi-line strings, server instances as tables and dotted keys (多行字符串、作为表的服务实例和点分键)
+	data := `
+name = """
+gateway"""
+tls.cert_file = "server.crt"
+
+[servers.edge]
+tcp_port = 9200
+`
+	g := &Config{}
+	assert.Nil(t, decodeConfig(FormatTOML, []byte(data), g))
+	assert.Equal(t, "gateway", g.Name)
+	assert.Equal(t, "server.crt", g.CertFile)
+	assert.JSONEq(t, `{"TCPPort":9200}`, string(g.Servers["edge"]))
+
+	// Syntax errors report their line (语法错误报告其所在行)
+	err := decodeConfig(FormatTOML, []byte("name = \"x\"\ntcp_port = 1 2\n"), &Config{})
+	assert.ErrorContains(t, err, "line 2")
+	err = decodeConfig(FormatTOML, []byte("name = \"x\"\nname = \"y\"\n"), &Config{})
+	assert.ErrorContains(t, err, "line 2")
+
+	// A value of the wrong type is rejected like in zinx.json (类型错误的值与zinx.json一样被拒绝)
+	assert.NotNil(t, decodeConfig(FormatTOML, []byte("tcp_port = \"x\"\n"), &Config{}))
+}
+
+func TestResolveConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "zinx.json")
+	assert.Equal(t, jsonPath, resolveConfigFile(jsonPath))
+
+	yamlPath := filepath.Join(dir, "zinx.yaml")
+	assert.Nil(t, os.WriteFile(yamlPath, []byte("name: x\n"), 0644))
+	assert.Equal(t, yamlPath, resolveConfigFile(jsonPath))
+	assert.Equal(t, FormatYAML, FormatOf(yamlPath))
+
+	assert.Nil(t, os.WriteFile(jsonPath, []byte("{}"), 0644))
+	assert.Equal(t, jsonPath, resolveConfigFile(jsonPath))
+}
@@ -0,0 +1,166 @@
+package zconf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// EnvPrefix prefixes the environment variables overriding config keys, e.g. ZINX_TCP_PORT overrides TCPPort
+// (覆盖配置项的环境变量前缀，例如ZINX_TCP_PORT覆盖TCPPort)
+const EnvPrefix = "ZINX_"
+
+// Config sources, lowest to highest precedence (配置来源，优先级由低到高):
+//
+//	defaults < zinx.json < ZINX_* environment variables < command-line flags bound by BindFlags
+//
+// The overrides are applied again on every Reload and HotReload. UserConfToGlobal, used by
+// NewUserConfServer, is applied by code afterwards and overrides them all.
+// (每次Reload和HotReload都会重新应用覆盖值。NewUserConfServer使用的UserConfToGlobal之后由代码调用，覆盖所有来源)
+
+var (
+	// Flags set on the command line by name of config field (命令行设置的flag，按配置字段名保存)
+	flagOverrides     = make(map[string]string)
+	flagOverridesLock sync.Mutex
+)
+
+// KeyName converts a config field name into upper snake case, e.g. TCPPort -> TCP_PORT, IOReadBuffSize -> IO_READ_BUFF_SIZE
+// (将配置字段名转换为大写下划线形式)
+func KeyName(field string) string {
+	runes := []rune(field)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// flagName is the command-line flag of a config field, e.g. TCPPort -> zinx-tcp-port (配置字段对应的命令行flag)
+func flagName(field string) string {
+	return "zinx-" + strings.ReplaceAll(strings.ToLower(KeyName(field)), "_", "-")
+}
+
+// BindFlags registers a flag for every config field on fs, e.g. -zinx-tcp-port=9000, -zinx-header-fields=flags,crc32.
+// Call ApplyFlags once fs is parsed, only the flags given on the command line override the config.
+// (在fs上为每个配置字段注册flag，fs解析后调用ApplyFlags，只有命令行中给出的flag会覆盖配置)
+func BindFlags(fs *flag.FlagSet) {
+	val := reflect.ValueOf(GlobalObject).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if field.PkgPath != "" || !isSupportedKind(field.Type) {
+			continue
+		}
+		fs.String(flagName(field.Name), "", fmt.Sprintf("overrides %s of zinx.json", field.Name))
+	}
+}
+
+// ApplyFlags applies the flags of fs given on the command line to GlobalObject, it returns the first invalid value
+// (将命令行中给出的fs的flag应用到GlobalObject，返回第一个无效的值)
+func ApplyFlags(fs *flag.FlagSet) error {
+	fields := make(map[string]string)
+	val := reflect.ValueOf(GlobalObject).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		fields[flagName(val.Type().Field(i).Name)] = val.Type().Field(i).Name
+	}
+
+	flagOverridesLock.Lock()
+	defer flagOverridesLock.Unlock()
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		field, ok := fields[f.Name]
+		if !ok || err != nil {
+			return
+		}
+		if err = setField(GlobalObject, field, f.Value.String()); err == nil {
+			flagOverrides[field] = f.Value.String()
+		}
+	})
+	return err
+}
+
+// applyOverrides applies the environment variables and then the flags to g
+// (将环境变量和flag依次应用到g)
+func applyOverrides(g *Config) error {
+	val := reflect.ValueOf(g).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		if field.PkgPath != "" || !isSupportedKind(field.Type) {
+			continue
+		}
+		if value, ok := os.LookupEnv(EnvPrefix + KeyName(field.Name)); ok {
+			if err := setField(g, field.Name, value); err != nil {
+				return fmt.Errorf("env %s%s: %w", EnvPrefix, KeyName(field.Name), err)
+			}
+		}
+	}
+
+	flagOverridesLock.Lock()
+	defer flagOverridesLock.Unlock()
+	for field, value := range flagOverrides {
+		if err := setField(g, field, value); err != nil {
+			return fmt.Errorf("flag -%s: %w", flagName(field), err)
+		}
+	}
+	return nil
+}
+
+func isSupportedKind(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// setField parses value into the field of g, slices of strings are comma separated
+// (将value解析到g的字段中，字符串切片以逗号分隔)
+func setField(g *Config, name, value string) error {
+	field := reflect.ValueOf(g).Elem().FieldByName(name)
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Slice:
+		var items []string
+		if value != "" {
+			items = strings.Split(value, ",")
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config field %s", name)
+	}
+	return nil
+}
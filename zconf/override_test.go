@@ -0,0 +1,46 @@
+package zconf
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyName(t *testing.T) {
+	assert.Equal(t, "TCP_PORT", KeyName("TCPPort"))
+	assert.Equal(t, "IO_READ_BUFF_SIZE", KeyName("IOReadBuffSize"))
+	assert.Equal(t, "MAX_CONN", KeyName("MaxConn"))
+	assert.Equal(t, "zinx-tcp-port", flagName("TCPPort"))
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	saved, savedPath := *GlobalObject, env.configFilePath
+	defer func() {
+		*GlobalObject, env.configFilePath = saved, savedPath
+		flagOverrides = make(map[string]string)
+	}()
+
+	env.configFilePath = filepath.Join(t.TempDir(), "zinx.json")
+	assert.Nil(t, os.WriteFile(env.configFilePath, []byte(`{"TCPPort":7000,"MaxConn":10,"Name":"file"}`), 0644))
+	t.Setenv("ZINX_TCP_PORT", "8000")
+	t.Setenv("ZINX_MAX_CONN", "20")
+	t.Setenv("ZINX_HEADER_FIELDS", "flags,crc32")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	BindFlags(fs)
+	assert.Nil(t, fs.Parse([]string{"-zinx-tcp-port=9000"}))
+	assert.Nil(t, ApplyFlags(fs))
+
+	GlobalObject.Reload()
+	assert.Equal(t, 9000, GlobalObject.TCPPort)
+	assert.Equal(t, 20, GlobalObject.MaxConn)
+	assert.Equal(t, "file", GlobalObject.Name)
+	assert.Equal(t, []string{"flags", "crc32"}, GlobalObject.HeaderFields)
+
+	t.Setenv("ZINX_MAX_CONN", "many")
+	_, err := HotReload()
+	assert.NotNil(t, err)
+}
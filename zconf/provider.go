@@ -0,0 +1,111 @@
+package zconf
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// ConfigProvider is a remote source of the config, e.g. a key of etcd or Consul, or a Nacos data ID,
+// so a fleet of servers shares one centralized config
+// (配置的远程来源，例如etcd或Consul的一个键，或Nacos的一个dataId，使一组服务共享集中管理的配置)
+type ConfigProvider interface {
+	// Name identifies the provider in logs (在日志中标识该提供者)
+	Name() string
+	// Format of the config data, see Format* (配置数据的格式，见Format*)
+	Format() string
+	// Load returns the current config data (返回当前的配置数据)
+	Load(ctx context.Context) ([]byte, error)
+	// Watch calls onChange with the new data every time the config changes, until ctx is done
+	// (每次配置变化时以新数据调用onChange，直到ctx结束)
+	Watch(ctx context.Context, onChange func(data []byte)) error
+}
+
+// ErrConfigNotFound is returned by the providers when the key does not exist (键不存在时提供者返回的错误)
+var ErrConfigNotFound = errors.New("zinx: remote config not found")
+
+// defaultWatchInterval is how often the providers check for changes (提供者检查变化的默认间隔)
+const defaultWatchInterval = 5 * time.Second
+
+// LoadFromProvider loads the whole config from p into GlobalObject like Reload does with the config file,
+// call it before creating the servers. Environment variables and flags still take precedence.
+// (像Reload读取配置文件一样从p加载完整配置到GlobalObject，需在创建服务前调用。环境变量和flag仍然优先)
+func LoadFromProvider(ctx context.Context, p ConfigProvider) error {
+	data, err := p.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	loaded := *GlobalObject
+	if err = decodeConfig(p.Format(), data, &loaded); err != nil {
+		return err
+	}
+	if err = applyOverrides(&loaded); err != nil {
+		return err
+	}
+	if err = loaded.Validate(); err != nil {
+		return err
+	}
+	*GlobalObject = loaded
+	resetLive()
+	GlobalObject.InitLogConfig()
+	zlog.Ins().InfoF("config loaded from %s", p.Name())
+	return nil
+}
+
+// WatchProvider hot reloads the changes of p until ctx is done, following the rules of HotReload:
+// only the fields safe at runtime are applied and the listeners of OnConfigChanged are notified.
+// (在ctx结束前热加载p的变化，规则与HotReload相同：只应用运行时可安全修改的字段，并通知OnConfigChanged的监听者)
+func WatchProvider(ctx context.Context, p ConfigProvider) {
+	go func() {
+		err := p.Watch(ctx, func(data []byte) {
+			reloadLock.Lock()
+			defer reloadLock.Unlock()
+			if _, err := hotReload(p.Format(), data); err != nil {
+				zlog.Ins().ErrorF("reload config from %s err: %v", p.Name(), err)
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			zlog.Ins().ErrorF("watch config of %s err: %v", p.Name(), err)
+		}
+	}()
+}
+
+// pollWatch calls load every interval and onChange when the data differs from the previous one,
+// it is the watch of the providers without a push API
+// (每隔interval调用load，数据与上次不同时调用onChange，用于没有推送接口的提供者)
+func pollWatch(ctx context.Context, interval time.Duration, load func(ctx context.Context) ([]byte, error), onChange func(data []byte)) error {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	last, err := load(ctx)
+	if err != nil && !errors.Is(err, ErrConfigNotFound) {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		data, err := load(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				zlog.Ins().ErrorF("poll remote config err: %v", err)
+			}
+			continue
+		}
+		if string(data) != string(last) {
+			last = data
+			onChange(data)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package zconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// ConsulProvider reads the config from a key of the Consul KV store, Watch uses blocking queries
+// (从Consul KV存储的一个键读取配置，Watch使用阻塞查询)
+type ConsulProvider struct {
+	Address    string        // e.g. http://127.0.0.1:8500
+	Key        string        // e.g. zinx/gateway/config
+	Token      string        // ACL token, optional.(ACL令牌，可选)
+	ConfigType string        // Format of the value, see Format*, JSON by default.(值的格式，见Format*，默认JSON)
+	Wait       time.Duration // The longest wait of one blocking query.(一次阻塞查询的最长等待时间)
+	Client     *http.Client
+}
+
+// NewConsulProvider creates a ConsulProvider of key on the Consul agent at address (创建读取address上Consul中key的ConsulProvider)
+func NewConsulProvider(address, key string) *ConsulProvider {
+	return &ConsulProvider{Address: strings.TrimRight(address, "/"), Key: strings.TrimLeft(key, "/"), ConfigType: FormatJSON,
+		Wait: time.Minute, Client: http.DefaultClient}
+}
+
+func (p *ConsulProvider) Name() string   { return "consul " + p.Address + "/" + p.Key }
+func (p *ConsulProvider) Format() string { return p.ConfigType }
+
+func (p *ConsulProvider) Load(ctx context.Context) ([]byte, error) {
+	data, _, err := p.get(ctx, 0)
+	return data, err
+}
+
+// get reads the key, blocking until its index passes index when index is not 0 (读取键，index不为0时阻塞到键的索引超过index)
+func (p *ConsulProvider) get(ctx context.Context, index uint64) ([]byte, uint64, error) {
+	query := url.Values{"raw": {""}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%ds", int(p.Wait.Seconds())))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Address+"/v1/kv/"+p.Key+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.Token != "" {
+		req.Header.Set("X-Consul-Token", p.Token)
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, newIndex, ErrConfigNotFound
+	default:
+		return nil, newIndex, fmt.Errorf("consul get %s: %s", p.Key, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return data, newIndex, err
+}
+
+func (p *ConsulProvider) Watch(ctx context.Context, onChange func(data []byte)) error {
+	last, index, err := p.get(ctx, 0)
+	if err != nil && err != ErrConfigNotFound {
+		return err
+	}
+	for ctx.Err() == nil {
+		data, newIndex, err := p.get(ctx, index)
+		if err != nil && err != ErrConfigNotFound {
+			if ctx.Err() == nil {
+				zlog.Ins().ErrorF("watch %s err: %v", p.Name(), err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		// The index may go backwards when the KV store is reset (KV存储被重置时索引可能回退)
+		if newIndex < index {
+			newIndex = 0
+		}
+		index = newIndex
+		if err == nil && string(data) != string(last) {
+			last = data
+			onChange(data)
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,65 @@
+package zconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EtcdProvider reads the config from a key of etcd v3 through its HTTP/JSON gateway
+// (通过etcd v3的HTTP/JSON网关从一个键读取配置)
+type EtcdProvider struct {
+	Endpoint   string        // e.g. http://127.0.0.1:2379
+	Key        string        // e.g. /zinx/gateway/config
+	ConfigType string        // Format of the value, see Format*, JSON by default.(值的格式，见Format*，默认JSON)
+	Interval   time.Duration // How often Watch checks the key.(Watch检查键的间隔)
+	Client     *http.Client
+}
+
+// NewEtcdProvider creates an EtcdProvider of key on the etcd at endpoint (创建读取endpoint上etcd中key的EtcdProvider)
+func NewEtcdProvider(endpoint, key string) *EtcdProvider {
+	return &EtcdProvider{Endpoint: strings.TrimRight(endpoint, "/"), Key: key, ConfigType: FormatJSON,
+		Interval: defaultWatchInterval, Client: http.DefaultClient}
+}
+
+func (p *EtcdProvider) Name() string   { return "etcd " + p.Endpoint + p.Key }
+func (p *EtcdProvider) Format() string { return p.ConfigType }
+
+func (p *EtcdProvider) Load(ctx context.Context) ([]byte, error) {
+	body, _ := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(p.Key))})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("etcd range %s: %s", p.Key, resp.Status)
+	}
+
+	var result struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Kvs) == 0 {
+		return nil, ErrConfigNotFound
+	}
+	return base64.StdEncoding.DecodeString(result.Kvs[0].Value)
+}
+
+func (p *EtcdProvider) Watch(ctx context.Context, onChange func(data []byte)) error {
+	return pollWatch(ctx, p.Interval, p.Load, onChange)
+}
@@ -0,0 +1,63 @@
+package zconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NacosProvider reads the config of a data ID from the Nacos config service through its open API
+// (通过Nacos配置服务的开放API读取一个dataId的配置)
+type NacosProvider struct {
+	Address    string        // e.g. http://127.0.0.1:8848
+	DataID     string        // e.g. zinx-gateway.yaml
+	Group      string        // DEFAULT_GROUP by default.(默认DEFAULT_GROUP)
+	Namespace  string        // The tenant, empty for the public namespace.(租户，为空时使用public命名空间)
+	ConfigType string        // Format of the content, by the extension of DataID by default.(内容的格式，默认根据DataID的扩展名判断)
+	Interval   time.Duration // How often Watch checks the config.(Watch检查配置的间隔)
+	Client     *http.Client
+}
+
+// NewNacosProvider creates a NacosProvider of dataID in group on the Nacos server at address
+// (创建读取address上Nacos中group下dataID的NacosProvider)
+func NewNacosProvider(address, dataID, group string) *NacosProvider {
+	if group == "" {
+		group = "DEFAULT_GROUP"
+	}
+	return &NacosProvider{Address: strings.TrimRight(address, "/"), DataID: dataID, Group: group,
+		ConfigType: FormatOf(dataID), Interval: defaultWatchInterval, Client: http.DefaultClient}
+}
+
+func (p *NacosProvider) Name() string   { return "nacos " + p.Address + " " + p.Group + "/" + p.DataID }
+func (p *NacosProvider) Format() string { return p.ConfigType }
+
+func (p *NacosProvider) Load(ctx context.Context) ([]byte, error) {
+	query := url.Values{"dataId": {p.DataID}, "group": {p.Group}}
+	if p.Namespace != "" {
+		query.Set("tenant", p.Namespace)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Address+"/nacos/v1/cs/configs?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, ErrConfigNotFound
+	}
+	return nil, fmt.Errorf("nacos get %s: %s", p.DataID, resp.Status)
+}
+
+func (p *NacosProvider) Watch(ctx context.Context, onChange func(data []byte)) error {
+	return pollWatch(ctx, p.Interval, p.Load, onChange)
+}
@@ -0,0 +1,100 @@
+package zconf
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// remoteValue is the config served by the fake remote stores (模拟远程存储提供的配置)
+type remoteValue struct {
+	data  string
+	index int
+	sync.Mutex
+}
+
+func (v *remoteValue) set(data string) {
+	v.Lock()
+	defer v.Unlock()
+	v.data, v.index = data, v.index+1
+}
+
+func (v *remoteValue) get() (string, int) {
+	v.Lock()
+	defer v.Unlock()
+	return v.data, v.index
+}
+
+func TestConfigProviders(t *testing.T) {
+	value := &remoteValue{}
+	value.set(`{"MaxConn":77}`)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/kv/range", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := value.get()
+		fmt.Fprintf(w, `{"kvs":[{"value":%q}]}`, base64.StdEncoding.EncodeToString([]byte(data)))
+	})
+	mux.HandleFunc("/v1/kv/zinx/config", func(w http.ResponseWriter, r *http.Request) {
+		data, index := value.get()
+		w.Header().Set("X-Consul-Index", fmt.Sprint(index))
+		_, _ = w.Write([]byte(data))
+	})
+	mux.HandleFunc("/nacos/v1/cs/configs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("dataId") != "zinx.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, _ := value.get()
+		_, _ = w.Write([]byte(data))
+	})
+	remote := httptest.NewServer(mux)
+	defer remote.Close()
+
+	ctx := context.Background()
+	for _, p := range []ConfigProvider{
+		NewEtcdProvider(remote.URL, "/zinx/config"),
+		NewConsulProvider(remote.URL, "zinx/config"),
+		NewNacosProvider(remote.URL, "zinx.json", ""),
+	} {
+		data, err := p.Load(ctx)
+		assert.Nil(t, err, p.Name())
+		assert.Equal(t, `{"MaxConn":77}`, string(data), p.Name())
+	}
+	_, err := NewNacosProvider(remote.URL, "missing.json", "").Load(ctx)
+	assert.Equal(t, ErrConfigNotFound, err)
+
+	saved := *GlobalObject
+	defer func() {
+		*GlobalObject = saved
+		resetLive()
+		changeListeners = nil
+	}()
+
+	assert.Nil(t, LoadFromProvider(ctx, NewConsulProvider(remote.URL, "zinx/config")))
+	assert.Equal(t, 77, GlobalObject.MaxConn)
+
+	changed := make(chan []ConfigChange, 1)
+	OnConfigChanged(func(changes []ConfigChange) { changed <- changes })
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p := NewNacosProvider(remote.URL, "zinx.json", "")
+	p.Interval = 10 * time.Millisecond
+	WatchProvider(watchCtx, p)
+
+	time.Sleep(30 * time.Millisecond)
+	value.set(`{"MaxConn":88}`)
+	select {
+	case changes := <-changed:
+		assert.Equal(t, []ConfigChange{{Field: "MaxConn", Old: 77, New: 88, Applied: true}}, changes)
+	case <-time.After(time.Second):
+		t.Fatal("the change was not reloaded")
+	}
+	assert.Equal(t, 88, GlobalObject.Live().MaxConn)
+}
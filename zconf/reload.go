@@ -0,0 +1,224 @@
+package zconf
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// ConfigChange is one field which differs between the running config and the reloaded file
+// (运行中配置与重新加载的文件之间不同的一个字段)
+type ConfigChange struct {
+	Field string
+	Old   interface{}
+	New   interface{}
+
+	// Applied is false for the fields which need a restart, Live keeps the old value
+	// (需要重启才能生效的字段为false，Live保留旧值)
+	Applied bool
+}
+
+// live is the config published by the latest hot reload, GlobalObject itself is never written at runtime so that
+// it is read without locks (最近一次热加载发布的配置，运行时不会修改GlobalObject本身，因此读取时无需加锁)
+var live atomic.Value
+
+// Live returns the config in effect: the snapshot published by the latest hot reload for GlobalObject, the config
+// itself otherwise. The snapshots are immutable, the components read the hot fields through Live.
+// (返回生效中的配置：GlobalObject返回最近一次热加载发布的快照，其它配置返回其自身。快照不可修改，各组件通过Live读取可热加载的字段)
+func (g *Config) Live() *Config {
+	if g == GlobalObject {
+		if c, _ := live.Load().(*Config); c != nil {
+			return c
+		}
+	}
+	return g
+}
+
+// resetLive drops the snapshot once GlobalObject is loaded again (GlobalObject重新加载后丢弃快照)
+func resetLive() {
+	live.Store((*Config)(nil))
+}
+
+// hotFields are the fields which are read at runtime through Live and safe to change without a restart
+// (运行时通过Live读取、无需重启即可安全修改的字段)
+var hotFields = map[string]struct{}{
+	"LogIsolationLevel":  {},
+	"LogCons":            {},
+	"LogJSON":            {},
+	"HeartbeatMax":       {},
+	"MaxConn":            {},
+	"WorkerPoolSize":     {},
+	"MaxWorkerTaskLen":   {},
+	"QueueFullPolicy":    {},
+	"QueueFullTimeout":   {},
+	"CompressThreshold":  {},
+	"MaxFragmentBuffers": {},
+	"MaxFragmentedSize":  {},
+	"RateLimitMessages":  {},
+	"RateLimitBytes":     {},
+	"RateLimitBurst":     {},
+	"RateLimitAction":    {},
+	"RateLimitWarnings":  {},
+}
+
+var (
+	changeListeners []func(changes []ConfigChange)
+	reloadLock      sync.Mutex
+)
+
+// OnConfigChanged registers f to be called with the changes of every reload which changes something,
+// e.g. znet resizes the worker pool of running servers on a WorkerPoolSize change
+// (注册f，每次重新加载有变化时以变化列表调用f，例如znet在WorkerPoolSize变化时调整运行中服务的worker池大小)
+func OnConfigChanged(f func(changes []ConfigChange)) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	changeListeners = append(changeListeners, f)
+}
+
+// HotReload reads the config file again and publishes the fields which are safe at runtime as the Live config of
+// GlobalObject, the listeners of OnConfigChanged are called with all the changes, applied or not
+// (重新读取配置文件，将运行时可安全修改的字段发布为GlobalObject的Live配置，并以所有变化(无论是否应用)调用OnConfigChanged的监听者)
+func HotReload() ([]ConfigChange, error) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	confFilePath := GetConfigFilePath()
+	data, err := os.ReadFile(confFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return hotReload(FormatOf(confFilePath), data)
+}
+
+// hotReload applies data in format like HotReload, reloadLock must be held
+// (像HotReload一样应用format格式的data，调用时必须持有reloadLock)
+func hotReload(format string, data []byte) ([]ConfigChange, error) {
+	current := GlobalObject.Live()
+	loaded := cloneConfig(current)
+	if err := decodeConfig(format, data, &loaded); err != nil {
+		return nil, err
+	}
+	if err := applyOverrides(&loaded); err != nil {
+		return nil, err
+	}
+	// The fields the worker mode derives at startup are not the ones of the file (工作模式在启动时推导的字段与文件不同)
+	if current.WorkerMode == WorkerModeBind && loaded.WorkerMode == WorkerModeBind {
+		loaded.WorkerPoolSize = current.WorkerPoolSize
+	}
+	// An invalid config is rejected as a whole (无效的配置整体被拒绝)
+	if err := loaded.Validate(); err != nil {
+		return nil, err
+	}
+
+	changes := diffConfig(current, &loaded)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	next := cloneConfig(current)
+	nextVal := reflect.ValueOf(&next).Elem()
+	for i := range changes {
+		if !isHotField(current, changes[i].Field) {
+			zlog.Ins().InfoF("config %s changed from %v to %v, restart to apply it", changes[i].Field, changes[i].Old, changes[i].New)
+			continue
+		}
+		nextVal.FieldByName(changes[i].Field).Set(reflect.ValueOf(changes[i].New))
+		changes[i].Applied = true
+		zlog.Ins().InfoF("config %s changed from %v to %v", changes[i].Field, changes[i].Old, changes[i].New)
+	}
+	live.Store(&next)
+	zlog.SetLogLevel(next.LogIsolationLevel)
+	zlog.SetJSON(next.LogJSON)
+	if next.LogFile != "" {
+		zlog.SetCons(next.LogCons)
+	}
+
+	for _, f := range changeListeners {
+		f(changes)
+	}
+	return changes, nil
+}
+
+// isHotField tells whether field can change at runtime under the worker mode of config: only the hash modes resize
+// their pool, and the workers of the bind modes are allocated by MaxConn
+// (判断在config的工作模式下field能否在运行时修改：只有哈希模式可以调整worker池大小，绑定模式的worker按MaxConn分配)
+func isHotField(config *Config, field string) bool {
+	if _, ok := hotFields[field]; !ok {
+		return false
+	}
+	switch field {
+	case "WorkerPoolSize":
+		return config.WorkerMode == "" || config.WorkerMode == WorkerModeHash
+	case "MaxConn":
+		return config.WorkerMode != WorkerModeBind && config.WorkerMode != WorkerModeDynamicBind
+	}
+	return true
+}
+
+// diffConfig returns the exported fields which differ between old and new (返回old与new之间不同的导出字段)
+func diffConfig(old, new *Config) []ConfigChange {
+	var changes []ConfigChange
+	oldVal, newVal := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := 0; i < oldVal.NumField(); i++ {
+		field := oldVal.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		o, n := oldVal.Field(i).Interface(), newVal.Field(i).Interface()
+		if !reflect.DeepEqual(o, n) {
+			changes = append(changes, ConfigChange{Field: field.Name, Old: o, New: n})
+		}
+	}
+	return changes
+}
+
+// WatchConfig calls HotReload when the config file is modified, checked every interval, or when the
+// process receives SIGHUP. The returned function stops watching.
+// (配置文件被修改(每隔interval检查一次)或进程收到SIGHUP时调用HotReload，返回的函数用于停止监听)
+func WatchConfig(interval time.Duration) (stop func()) {
+	modTime := func() time.Time {
+		if info, err := os.Stat(GetConfigFilePath()); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+	reload := func() {
+		if _, err := HotReload(); err != nil {
+			zlog.Ins().ErrorF("reload config %s err: %v", GetConfigFilePath(), err)
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := modTime()
+		for {
+			select {
+			case <-hup:
+				reload()
+			case <-ticker.C:
+				if t := modTime(); !t.Equal(last) {
+					last = t
+					reload()
+				}
+			case <-done:
+				signal.Stop(hup)
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
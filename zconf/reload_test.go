@@ -0,0 +1,97 @@
+package zconf
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotReload(t *testing.T) {
+	saved, savedPath := *GlobalObject, env.configFilePath
+	defer func() {
+		*GlobalObject, env.configFilePath = saved, savedPath
+		resetLive()
+		changeListeners = nil
+	}()
+
+	env.configFilePath = filepath.Join(t.TempDir(), "zinx.json")
+	assert.Nil(t, os.WriteFile(env.configFilePath, []byte(`{"Name":"renamed","MaxConn":42}`), 0644))
+
+	var notified []ConfigChange
+	OnConfigChanged(func(changes []ConfigChange) { notified = changes })
+
+	changes, err := HotReload()
+	assert.Nil(t, err)
+	assert.Equal(t, changes, notified)
+	assert.ElementsMatch(t, []ConfigChange{
+		{Field: "Name", Old: saved.Name, New: "renamed", Applied: false},
+		{Field: "MaxConn", Old: saved.MaxConn, New: 42, Applied: true},
+	}, changes)
+	// The change is published by Live, GlobalObject itself is not written (变化由Live发布，GlobalObject本身不会被修改)
+	assert.Equal(t, 42, GlobalObject.Live().MaxConn)
+	assert.Equal(t, saved.MaxConn, GlobalObject.MaxConn)
+	assert.Equal(t, saved.Name, GlobalObject.Live().Name)
+	other := DefaultConfig()
+	assert.Same(t, other, other.Live())
+
+	// The fields which need a restart are reported until then (需要重启的字段在重启前会一直被报告)
+	changes, err = HotReload()
+	assert.Nil(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "Name", changes[0].Field)
+}
+
+func TestHotReloadWorkerMode(t *testing.T) {
+	saved, savedPath := *GlobalObject, env.configFilePath
+	defer func() {
+		*GlobalObject, env.configFilePath = saved, savedPath
+		resetLive()
+	}()
+
+	// In the Bind mode the pool is sized by MaxConn at startup (Bind模式下worker池在启动时按MaxConn设置大小)
+	GlobalObject.WorkerMode = WorkerModeBind
+	GlobalObject.MaxConn = 100
+	GlobalObject.WorkerPoolSize = 100
+	env.configFilePath = filepath.Join(t.TempDir(), "zinx.json")
+	data := `{"WorkerMode":"Bind","MaxConn":100,"WorkerPoolSize":10,"HeartbeatMax":` + strconv.Itoa(saved.HeartbeatMax+1) + `}`
+	assert.Nil(t, os.WriteFile(env.configFilePath, []byte(data), 0644))
+
+	changes, err := HotReload()
+	assert.Nil(t, err)
+	assert.Equal(t, []ConfigChange{{Field: "HeartbeatMax", Old: saved.HeartbeatMax, New: saved.HeartbeatMax + 1, Applied: true}}, changes)
+
+	// MaxConn sizes the workers too, it needs a restart (MaxConn同时决定worker数量，需要重启)
+	assert.Nil(t, os.WriteFile(env.configFilePath, []byte(`{"WorkerMode":"Bind","MaxConn":200,"WorkerPoolSize":10}`), 0644))
+	changes, err = HotReload()
+	assert.Nil(t, err)
+	assert.Equal(t, []ConfigChange{{Field: "MaxConn", Old: 100, New: 200, Applied: false}}, changes)
+	assert.Equal(t, 100, GlobalObject.Live().MaxConn)
+}
+
+func TestHotReloadConcurrentReads(t *testing.T) {
+	saved, savedPath := *GlobalObject, env.configFilePath
+	defer func() {
+		*GlobalObject, env.configFilePath = saved, savedPath
+		resetLive()
+	}()
+	env.configFilePath = filepath.Join(t.TempDir(), "zinx.json")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_ = GlobalObject.Live().MaxConn
+			_ = GlobalObject.MaxConn
+		}
+	}()
+	for i := 1; i <= 10; i++ {
+		assert.Nil(t, os.WriteFile(env.configFilePath, []byte(`{"MaxConn":`+strconv.Itoa(i)+`}`), 0644))
+		_, err := HotReload()
+		assert.Nil(t, err)
+	}
+	<-done
+	assert.Equal(t, 10, GlobalObject.Live().MaxConn)
+}
@@ -0,0 +1,65 @@
+package zconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// cloneConfig copies g, the slices and maps of the copy do not share memory with g
+// (复制g，副本的切片和map不与g共享内存)
+func cloneConfig(g *Config) Config {
+	clone := *g
+	val := reflect.ValueOf(&clone).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		if !field.CanSet() || field.IsZero() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Slice:
+			s := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(s, field)
+			field.Set(s)
+		case reflect.Map:
+			m := reflect.MakeMapWithSize(field.Type(), field.Len())
+			iter := field.MapRange()
+			for iter.Next() {
+				m.SetMapIndex(iter.Key(), iter.Value())
+			}
+			field.Set(m)
+		}
+	}
+	return clone
+}
+
+// Scope returns the config of the server instance name: a copy of g overridden by Servers[name],
+// with Name defaulting to name. It returns an error if Servers has no such instance.
+// (返回服务实例name的配置：g的副本被Servers[name]覆盖，Name默认为name。Servers中没有该实例时返回错误)
+func (g *Config) Scope(name string) (*Config, error) {
+	section, ok := g.Servers[name]
+	if !ok {
+		return nil, fmt.Errorf("zinx: no server instance %q in Servers", name)
+	}
+	scoped := cloneConfig(g)
+	scoped.Servers = nil
+	scoped.Name = name
+	if err := json.Unmarshal(section, &scoped); err != nil {
+		return nil, fmt.Errorf("zinx: server instance %q: %w", name, err)
+	}
+	if scoped.Servers != nil {
+		return nil, fmt.Errorf("zinx: server instance %q must not nest Servers", name)
+	}
+	return &scoped, nil
+}
+
+// ScopeNames returns the names of the server instances in Servers, sorted (返回Servers中服务实例的名称，已排序)
+func (g *Config) ScopeNames() []string {
+	names := make([]string, 0, len(g.Servers))
+	for name := range g.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,67 @@
+package zconf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope(t *testing.T) {
+	g := DefaultConfig()
+	g.HeaderFields = []string{"flags"}
+	g.Servers = map[string]json.RawMessage{
+		"game":  json.RawMessage(`{"TCPPort": 9100, "MaxPacketSize": 8192, "HeartbeatMax": 30}`),
+		"admin": json.RawMessage(`{"Name": "console", "Mode": "websocket", "WsPort": 9200, "DataPack": "zinx_pack_json"}`),
+	}
+	assert.Equal(t, []string{"admin", "game"}, g.ScopeNames())
+
+	game, err := g.Scope("game")
+	assert.Nil(t, err)
+	assert.Equal(t, "game", game.Name)
+	assert.Equal(t, 9100, game.TCPPort)
+	assert.Equal(t, uint32(8192), game.MaxPacketSize)
+	assert.Equal(t, 30, game.HeartbeatMax)
+	assert.Equal(t, g.WorkerPoolSize, game.WorkerPoolSize)
+	assert.Nil(t, game.Servers)
+	game.HeaderFields[0] = "crc32"
+	assert.Equal(t, "flags", g.HeaderFields[0])
+
+	admin, err := g.Scope("admin")
+	assert.Nil(t, err)
+	assert.Equal(t, "console", admin.Name)
+	assert.Equal(t, ziface.ZinxDataPackJSON, admin.DataPack)
+
+	_, err = g.Scope("missing")
+	assert.NotNil(t, err)
+	assert.Nil(t, g.Validate())
+
+	// Both instances listen on TCP 9100 (两个实例都监听TCP 9100)
+	g.Servers["admin"] = json.RawMessage(`{"Mode": "tcp", "TCPPort": 9100, "Decoder": "xml"}`)
+	errs, _ := g.Validate().(ConfigErrors)
+	assert.Equal(t, ConfigErrors{
+		{Field: "Servers.admin.Decoder", Problem: `unknown decoder "xml"`},
+		{Field: "Servers.game", Problem: "tcp port 9100 is also used by admin"},
+	}, errs)
+
+	yamlData := "servers:\n  game:\n    tcp_port: 9300\n    max_packet_size: 512\n"
+	loaded := &Config{}
+	assert.Nil(t, decodeConfig(FormatYAML, []byte(yamlData), loaded))
+	game, err = loaded.Scope("game")
+	assert.Nil(t, err)
+	assert.Equal(t, 9300, game.TCPPort)
+	assert.Equal(t, uint32(512), game.MaxPacketSize)
+}
+
+func TestHasOwnLog(t *testing.T) {
+	assert.False(t, GlobalObject.HasOwnLog())
+	g := DefaultConfig()
+	g.LogDir, g.LogFile = GlobalObject.LogDir, GlobalObject.LogFile
+	g.LogIsolationLevel, g.LogJSON = GlobalObject.LogIsolationLevel, GlobalObject.LogJSON
+	assert.False(t, g.HasOwnLog())
+
+	g.LogJSON = !g.LogJSON
+	assert.True(t, g.HasOwnLog())
+	assert.NotNil(t, g.NewLogger())
+}
@@ -7,6 +7,7 @@ import "github.com/aceld/zinx/zlog"
 // because other parameters are called from this structure parameter.
 // (注意如果使用UserConf应该调用方法同步至 GlobalConfObject 因为其他参数是调用的此结构体参数)
 func UserConfToGlobal(config *Config) {
+	defer resetLive()
 
 	// Server
 	if config.Name != "" {
@@ -39,12 +40,28 @@ func UserConfToGlobal(config *Config) {
 		GlobalObject.WorkerMode = config.WorkerMode
 	}
 
+	if config.DataPack != "" {
+		GlobalObject.DataPack = config.DataPack
+	}
+	if config.Decoder != "" {
+		GlobalObject.Decoder = config.Decoder
+	}
+	if config.Servers != nil {
+		GlobalObject.Servers = config.Servers
+	}
+
 	if config.MaxMsgChanLen != 0 {
 		GlobalObject.MaxMsgChanLen = config.MaxMsgChanLen
 	}
 	if config.IOReadBuffSize != 0 {
 		GlobalObject.IOReadBuffSize = config.IOReadBuffSize
 	}
+	if config.IOReadBuffMin != 0 {
+		GlobalObject.IOReadBuffMin = config.IOReadBuffMin
+	}
+	if config.IOReadBuffMax != 0 {
+		GlobalObject.IOReadBuffMax = config.IOReadBuffMax
+	}
 
 	// logger
 	// By default, it is False. If the config is not initialized, the default configuration will be used.
@@ -64,6 +81,10 @@ func UserConfToGlobal(config *Config) {
 		GlobalObject.LogFile = config.LogFile
 		zlog.SetLogFile(GlobalObject.LogDir, GlobalObject.LogFile)
 	}
+	if config.SecurityLogFile != "" {
+		GlobalObject.SecurityLogFile = config.SecurityLogFile
+		zlog.SetSecuritySink(zlog.NewJSONSecuritySink(GlobalObject.securityLogWriter()))
+	}
 
 	// Keepalive
 	if config.HeartbeatMax != 0 {
@@ -77,10 +98,65 @@ func UserConfToGlobal(config *Config) {
 	if config.PrivateKeyFile != "" {
 		GlobalObject.PrivateKeyFile = config.PrivateKeyFile
 	}
+	if config.ClientCAFile != "" {
+		GlobalObject.ClientCAFile = config.ClientCAFile
+	}
+	if config.CertReloadInterval != 0 {
+		GlobalObject.CertReloadInterval = config.CertReloadInterval
+	}
+	if config.OCSPStapling {
+		GlobalObject.OCSPStapling = true
+	}
+
+	if config.ReplayWindow != 0 {
+		GlobalObject.ReplayWindow = config.ReplayWindow
+	}
+
+	// Listener
+	if config.ListenBacklog != 0 {
+		GlobalObject.ListenBacklog = config.ListenBacklog
+	}
+	if config.TCPDeferAccept != 0 {
+		GlobalObject.TCPDeferAccept = config.TCPDeferAccept
+	}
+	if config.AbuseThreshold != 0 {
+		GlobalObject.AbuseThreshold = config.AbuseThreshold
+	}
+	if config.AbuseWindow != 0 {
+		GlobalObject.AbuseWindow = config.AbuseWindow
+	}
+	if config.AbusePause != 0 {
+		GlobalObject.AbusePause = config.AbusePause
+	}
+
+	// Rate limits
+	if config.RateLimitMessages != 0 {
+		GlobalObject.RateLimitMessages = config.RateLimitMessages
+	}
+	if config.RateLimitBytes != 0 {
+		GlobalObject.RateLimitBytes = config.RateLimitBytes
+	}
+	if config.RateLimitBurst != 0 {
+		GlobalObject.RateLimitBurst = config.RateLimitBurst
+	}
+	if config.RateLimitAction != "" {
+		GlobalObject.RateLimitAction = config.RateLimitAction
+	}
+	if config.RateLimitWarnings != 0 {
+		GlobalObject.RateLimitWarnings = config.RateLimitWarnings
+	}
+
+	// Auth
+	if config.AuthTimeout != 0 {
+		GlobalObject.AuthTimeout = config.AuthTimeout
+	}
 
 	if config.Mode != "" {
 		GlobalObject.Mode = config.Mode
 	}
+	if config.ProxyUpstream != "" {
+		GlobalObject.ProxyUpstream = config.ProxyUpstream
+	}
 	if config.WsPort != 0 {
 		GlobalObject.WsPort = config.WsPort
 	}
@@ -137,4 +213,102 @@ func UserConfToGlobal(config *Config) {
 		GlobalObject.KcpFecParityShards = config.KcpFecParityShards
 	}
 
+	// Metrics
+	if config.MetricsAddr != "" {
+		GlobalObject.MetricsAddr = config.MetricsAddr
+	}
+	if config.StatsInterval != 0 {
+		GlobalObject.StatsInterval = config.StatsInterval
+	}
+
+	// Watchdog
+	if config.SlowHandlerThreshold != 0 {
+		GlobalObject.SlowHandlerThreshold = config.SlowHandlerThreshold
+	}
+	if config.LargeMessageSize != 0 {
+		GlobalObject.LargeMessageSize = config.LargeMessageSize
+	}
+
+	// Traffic
+	if config.TrafficInterval != 0 {
+		GlobalObject.TrafficInterval = config.TrafficInterval
+	}
+
+	// Timer
+	if config.TimerTick != 0 {
+		GlobalObject.TimerTick = config.TimerTick
+	}
+	if config.TimerScales != 0 {
+		GlobalObject.TimerScales = config.TimerScales
+	}
+	if config.TimerLevels != 0 {
+		GlobalObject.TimerLevels = config.TimerLevels
+	}
+
+	// Health
+	if config.HealthQueueThreshold != 0 {
+		GlobalObject.HealthQueueThreshold = config.HealthQueueThreshold
+	}
+	if config.HealthDecodeErrorRate != 0 {
+		GlobalObject.HealthDecodeErrorRate = config.HealthDecodeErrorRate
+	}
+	if config.ShutdownDelay != 0 {
+		GlobalObject.ShutdownDelay = config.ShutdownDelay
+	}
+	if config.StartupReport != "" {
+		GlobalObject.StartupReport = config.StartupReport
+	}
+
+	// Capture
+	if config.CaptureFile != "" {
+		GlobalObject.CaptureFile = config.CaptureFile
+	}
+	if config.CaptureDirection != "" {
+		GlobalObject.CaptureDirection = config.CaptureDirection
+	}
+	if len(config.CaptureMsgIDs) > 0 {
+		GlobalObject.CaptureMsgIDs = config.CaptureMsgIDs
+	}
+
+	// Admin
+	if config.AdminAddr != "" {
+		GlobalObject.AdminAddr = config.AdminAddr
+	}
+	if config.AdminToken != "" {
+		GlobalObject.AdminToken = config.AdminToken
+	}
+
+	// Fault injection
+	if config.FaultSendDelayRatio != 0 {
+		GlobalObject.FaultSendDelayRatio = config.FaultSendDelayRatio
+	}
+	if config.FaultSendDelay != 0 {
+		GlobalObject.FaultSendDelay = config.FaultSendDelay
+	}
+	if config.FaultDropRatio != 0 {
+		GlobalObject.FaultDropRatio = config.FaultDropRatio
+	}
+	if config.FaultCorruptRatio != 0 {
+		GlobalObject.FaultCorruptRatio = config.FaultCorruptRatio
+	}
+	if config.FaultCloseRatio != 0 {
+		GlobalObject.FaultCloseRatio = config.FaultCloseRatio
+	}
+
+	// Tracing
+	if config.TraceExporter != "" {
+		GlobalObject.TraceExporter = config.TraceExporter
+	}
+	if config.TraceEndpoint != "" {
+		GlobalObject.TraceEndpoint = config.TraceEndpoint
+	}
+	if config.TraceSampleRatio != 0 {
+		GlobalObject.TraceSampleRatio = config.TraceSampleRatio
+	}
+
+	// Plugins
+	if len(config.Plugins) > 0 {
+		GlobalObject.Plugins = config.Plugins
+	}
+
 }
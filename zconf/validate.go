@@ -0,0 +1,343 @@
+package zconf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zutils"
+)
+
+// ConfigProblem is one invalid setting found by Validate (Validate发现的一个无效配置)
+type ConfigProblem struct {
+	Field   string
+	Problem string
+}
+
+// ConfigErrors lists all the problems of a config, it is the error returned by Validate
+// (列出配置的所有问题，是Validate返回的错误)
+type ConfigErrors []ConfigProblem
+
+func (e ConfigErrors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "zinx: %d config problem(s):", len(e))
+	for _, p := range e {
+		fmt.Fprintf(&b, "\n  - %s: %s", p.Field, p.Problem)
+	}
+	return b.String()
+}
+
+// Add records a problem of field (记录field的一个问题)
+func (e *ConfigErrors) Add(field, format string, args ...interface{}) {
+	*e = append(*e, ConfigProblem{Field: field, Problem: fmt.Sprintf(format, args...)})
+}
+
+// Err returns e as an error, nil if there is no problem (将e作为error返回，没有问题时返回nil)
+func (e ConfigErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate checks the settings which would make the server misbehave at runtime, it returns ConfigErrors
+// listing every problem, or nil
+// (检查会导致服务运行时行为异常的配置，返回列出所有问题的ConfigErrors，没有问题时返回nil)
+func (g *Config) Validate() error {
+	var errs ConfigErrors
+	g.validate(&errs)
+	return errs.Err()
+}
+
+func (g *Config) validate(errs *ConfigErrors) {
+	g.validateServer(errs)
+	g.validateLog(errs)
+	g.validateTrace(errs)
+
+	// Every server instance is checked with its scoped config, and they must not listen on the same port
+	// (每个服务实例使用其作用域配置检查，且不能监听相同的端口)
+	type address struct {
+		network string
+		port    int
+	}
+	listeners := make(map[address]string)
+	for _, name := range g.ScopeNames() {
+		scoped, err := g.Scope(name)
+		if err != nil {
+			errs.Add("Servers."+name, "%v", err)
+			continue
+		}
+		var scopedErrs ConfigErrors
+		scoped.validateServer(&scopedErrs)
+		for _, p := range scopedErrs {
+			errs.Add("Servers."+name+"."+p.Field, "%s", p.Problem)
+		}
+
+		var addrs []address
+		switch scoped.Mode {
+		case ServerModeTcp, ServerModeProxy:
+			addrs = []address{{"tcp", scoped.TCPPort}}
+		case ServerModeWebsocket:
+			addrs = []address{{"tcp", scoped.WsPort}}
+		case ServerModeKcp:
+			addrs = []address{{"udp", scoped.KcpPort}}
+		case "":
+			addrs = []address{{"tcp", scoped.TCPPort}, {"tcp", scoped.WsPort}}
+		}
+		for _, addr := range addrs {
+			if addr.port == 0 {
+				continue
+			}
+			if other, ok := listeners[addr]; ok {
+				errs.Add("Servers."+name, "%s port %d is also used by %s", addr.network, addr.port, other)
+				continue
+			}
+			listeners[addr] = name
+		}
+	}
+}
+
+// validateLog checks the log settings, they are process-wide (检查日志配置，日志配置是进程级的)
+func (g *Config) validateLog(errs *ConfigErrors) {
+	if g.LogSaveDays < 0 {
+		errs.Add("LogSaveDays", "must not be negative, got %d", g.LogSaveDays)
+	}
+	if g.LogFileSize < 0 {
+		errs.Add("LogFileSize", "must not be negative, got %d", g.LogFileSize)
+	}
+	if g.LogMaxBackups < 0 {
+		errs.Add("LogMaxBackups", "must not be negative, got %d", g.LogMaxBackups)
+	}
+	if g.LogRotateHours < 0 {
+		errs.Add("LogRotateHours", "must not be negative, got %d", g.LogRotateHours)
+	}
+	switch g.LogCompress {
+	case "", zutils.CompressZip, zutils.CompressGzip, zutils.CompressNone:
+	default:
+		errs.Add("LogCompress", "must be %q, %q or %q, got %q", zutils.CompressZip, zutils.CompressGzip, zutils.CompressNone, g.LogCompress)
+	}
+}
+
+// validateTrace checks the tracing settings, tracing is process-wide (检查链路追踪配置，追踪是进程级的)
+func (g *Config) validateTrace(errs *ConfigErrors) {
+	switch g.TraceExporter {
+	case "", "log":
+	case "otlp":
+		if g.TraceEndpoint == "" {
+			errs.Add("TraceEndpoint", "must be set for the otlp exporter")
+		}
+	default:
+		errs.Add("TraceExporter", "must be \"log\" or \"otlp\", got %q", g.TraceExporter)
+	}
+	if g.TraceSampleRatio < 0 || g.TraceSampleRatio > 1 {
+		errs.Add("TraceSampleRatio", "must be between 0 and 1, got %v", g.TraceSampleRatio)
+	}
+}
+
+// validateServer checks the settings of one server (检查单个服务的配置)
+func (g *Config) validateServer(errs *ConfigErrors) {
+	switch g.DataPack {
+	case "", ziface.ZinxDataPack, ziface.ZinxDataPackOld, ziface.ZinxDataPackTrace, ziface.ZinxDataPackProtobuf,
+		ziface.ZinxDataPackJSON, ziface.ZinxDataPackJSONLine:
+	default:
+		errs.Add("DataPack", "unknown datapack %q", g.DataPack)
+	}
+	switch g.Decoder {
+	case "", DecoderTLV, DecoderLTV, DecoderHTLVCRC, DecoderTrace, DecoderJSON, DecoderJSONLine, DecoderProtobuf, DecoderNone:
+	default:
+		errs.Add("Decoder", "unknown decoder %q", g.Decoder)
+	}
+
+	switch g.Mode {
+	case "", ServerModeTcp, ServerModeWebsocket, ServerModeKcp:
+	case ServerModeProxy:
+		if g.ProxyUpstream == "" {
+			errs.Add("ProxyUpstream", "is required by the %q mode", ServerModeProxy)
+		}
+	default:
+		errs.Add("Mode", "unknown mode %q, expected %q, %q, %q, %q or empty",
+			g.Mode, ServerModeTcp, ServerModeWebsocket, ServerModeKcp, ServerModeProxy)
+	}
+	for _, p := range []struct {
+		field string
+		port  int
+	}{{"TCPPort", g.TCPPort}, {"WsPort", g.WsPort}, {"KcpPort", g.KcpPort}} {
+		if p.port < 0 || p.port > 65535 {
+			errs.Add(p.field, "port %d is out of range 0-65535", p.port)
+		}
+	}
+	if g.Mode == "" && g.TCPPort != 0 && g.TCPPort == g.WsPort {
+		errs.Add("WsPort", "TCP and websocket both listen on port %d", g.TCPPort)
+	}
+
+	if g.MaxConn <= 0 {
+		errs.Add("MaxConn", "must be positive, got %d", g.MaxConn)
+	}
+	if g.IOReadBuffSize == 0 {
+		errs.Add("IOReadBuffSize", "must be positive")
+	}
+	if g.IOReadBuffMin != 0 || g.IOReadBuffMax != 0 {
+		if g.IOReadBuffMin == 0 || g.IOReadBuffMax < g.IOReadBuffMin {
+			errs.Add("IOReadBuffMax", "must be set along with IOReadBuffMin and not below it, got %d and %d",
+				g.IOReadBuffMax, g.IOReadBuffMin)
+		} else if g.IOReadBuffSize < g.IOReadBuffMin || g.IOReadBuffSize > g.IOReadBuffMax {
+			errs.Add("IOReadBuffSize", "%d must be within IOReadBuffMin %d and IOReadBuffMax %d",
+				g.IOReadBuffSize, g.IOReadBuffMin, g.IOReadBuffMax)
+		}
+	}
+	if g.HeartbeatMax < 0 {
+		errs.Add("HeartbeatMax", "must not be negative, got %d", g.HeartbeatMax)
+	}
+
+	switch g.WorkerMode {
+	case "", WorkerModeHash, WorkerModeBind, WorkerModeDynamicBind, WorkerModeSteal:
+	default:
+		errs.Add("WorkerMode", "unknown worker mode %q", g.WorkerMode)
+	}
+	if g.WorkerPoolSize > 0 && g.MaxWorkerTaskLen == 0 {
+		errs.Add("MaxWorkerTaskLen", "must be positive when WorkerPoolSize is %d", g.WorkerPoolSize)
+	}
+	if g.WorkerMode == WorkerModeDynamicBind && int(g.WorkerPoolSize) > g.MaxConn {
+		errs.Add("WorkerPoolSize", "%d exceeds MaxConn %d in %s mode", g.WorkerPoolSize, g.MaxConn, WorkerModeDynamicBind)
+	}
+	if g.Shards < 0 {
+		errs.Add("Shards", "must not be negative, got %d", g.Shards)
+	}
+	hashMode := g.WorkerMode != WorkerModeBind && g.WorkerMode != WorkerModeDynamicBind && g.WorkerMode != WorkerModeSteal
+	if g.Shards > 1 && hashMode && g.WorkerPoolSize%uint32(g.Shards) != 0 {
+		errs.Add("WorkerPoolSize", "%d must be a multiple of Shards %d", g.WorkerPoolSize, g.Shards)
+	}
+
+	switch g.QueueFullPolicy {
+	case "", QueueFullBlock, QueueFullBlockTimeout, QueueFullDrop, QueueFullSpill, QueueFullShed:
+	default:
+		errs.Add("QueueFullPolicy", "unknown policy %q", g.QueueFullPolicy)
+	}
+	if g.QueueFullPolicy == QueueFullBlockTimeout && g.QueueFullTimeout <= 0 {
+		errs.Add("QueueFullTimeout", "must be positive with %s, got %d", QueueFullBlockTimeout, g.QueueFullTimeout)
+	}
+
+	if g.FragmentMode && g.MaxPacketSize == 0 {
+		errs.Add("MaxPacketSize", "must be set in FragmentMode, it is the size of the fragments")
+	}
+	if g.CompressThreshold < 0 {
+		errs.Add("CompressThreshold", "must not be negative, got %d", g.CompressThreshold)
+	}
+
+	if (g.CertFile == "") != (g.PrivateKeyFile == "") {
+		errs.Add("CertFile", "CertFile and PrivateKeyFile must be set together")
+	}
+	if g.ClientCAFile != "" && g.CertFile == "" {
+		errs.Add("ClientCAFile", "requires CertFile and PrivateKeyFile")
+	}
+	for _, f := range []struct{ field, file string }{{"CertFile", g.CertFile}, {"PrivateKeyFile", g.PrivateKeyFile}, {"ClientCAFile", g.ClientCAFile}} {
+		if f.file == "" {
+			continue
+		}
+		if _, err := os.Stat(f.file); err != nil {
+			errs.Add(f.field, "%v", err)
+		}
+	}
+	if g.CertReloadInterval < 0 {
+		errs.Add("CertReloadInterval", "must not be negative, got %d", g.CertReloadInterval)
+	}
+	if g.SlowHandlerThreshold < 0 {
+		errs.Add("SlowHandlerThreshold", "must not be negative, got %d", g.SlowHandlerThreshold)
+	}
+	if g.LargeMessageSize < 0 {
+		errs.Add("LargeMessageSize", "must not be negative, got %d", g.LargeMessageSize)
+	}
+	if g.StatsInterval < 0 {
+		errs.Add("StatsInterval", "must not be negative, got %d", g.StatsInterval)
+	}
+	if g.ReplayWindow < 0 {
+		errs.Add("ReplayWindow", "must not be negative, got %d", g.ReplayWindow)
+	} else if g.ReplayWindow > 0 && !(hasString(g.HeaderFields, "timestamp") && hasString(g.HeaderFields, "nonce")) {
+		errs.Add("ReplayWindow", "needs the timestamp and nonce HeaderFields")
+	}
+	for _, f := range []struct {
+		field string
+		value int
+	}{{"ListenBacklog", g.ListenBacklog}, {"TCPDeferAccept", g.TCPDeferAccept}, {"AbuseThreshold", g.AbuseThreshold}} {
+		if f.value < 0 {
+			errs.Add(f.field, "must not be negative, got %d", f.value)
+		}
+	}
+	if g.AbuseThreshold > 0 && (g.AbuseWindow <= 0 || g.AbusePause <= 0) {
+		errs.Add("AbuseThreshold", "needs a positive AbuseWindow and AbusePause")
+	}
+	if g.RateLimitMessages < 0 {
+		errs.Add("RateLimitMessages", "must not be negative, got %v", g.RateLimitMessages)
+	}
+	if g.RateLimitBytes < 0 {
+		errs.Add("RateLimitBytes", "must not be negative, got %d", g.RateLimitBytes)
+	}
+	if g.RateLimitBurst < 0 {
+		errs.Add("RateLimitBurst", "must not be negative, got %d", g.RateLimitBurst)
+	}
+	if g.RateLimitWarnings < 0 {
+		errs.Add("RateLimitWarnings", "must not be negative, got %d", g.RateLimitWarnings)
+	}
+	switch g.RateLimitAction {
+	case "", RateLimitDrop, RateLimitDelay, RateLimitClose:
+	default:
+		errs.Add("RateLimitAction", "must be %q, %q, %q or empty, got %q", RateLimitDrop, RateLimitDelay, RateLimitClose, g.RateLimitAction)
+	}
+	if g.AuthTimeout < 0 {
+		errs.Add("AuthTimeout", "must not be negative, got %d", g.AuthTimeout)
+	}
+	if g.TrafficInterval < 0 {
+		errs.Add("TrafficInterval", "must not be negative, got %d", g.TrafficInterval)
+	}
+	if g.TimerTick < 0 {
+		errs.Add("TimerTick", "must not be negative, got %d", g.TimerTick)
+	}
+	if g.TimerScales < 0 {
+		errs.Add("TimerScales", "must not be negative, got %d", g.TimerScales)
+	}
+	if g.TimerLevels < 0 || g.TimerLevels > 5 {
+		errs.Add("TimerLevels", "must be between 0 and 5, got %d", g.TimerLevels)
+	}
+	if g.HealthQueueThreshold < 0 {
+		errs.Add("HealthQueueThreshold", "must not be negative, got %d", g.HealthQueueThreshold)
+	}
+	if g.HealthDecodeErrorRate < 0 {
+		errs.Add("HealthDecodeErrorRate", "must not be negative, got %v", g.HealthDecodeErrorRate)
+	}
+	if g.ShutdownDelay < 0 {
+		errs.Add("ShutdownDelay", "must not be negative, got %d", g.ShutdownDelay)
+	}
+	switch g.StartupReport {
+	case "", StartupReportText, StartupReportJSON, StartupReportOff:
+	default:
+		errs.Add("StartupReport", "must be %q, %q, %q or empty, got %q",
+			StartupReportText, StartupReportJSON, StartupReportOff, g.StartupReport)
+	}
+	switch g.CaptureDirection {
+	case "", CaptureInbound, CaptureOutbound:
+	default:
+		errs.Add("CaptureDirection", "must be %q, %q or empty, got %q", CaptureInbound, CaptureOutbound, g.CaptureDirection)
+	}
+	for _, r := range []struct {
+		field string
+		ratio float64
+	}{{"FaultSendDelayRatio", g.FaultSendDelayRatio}, {"FaultDropRatio", g.FaultDropRatio},
+		{"FaultCorruptRatio", g.FaultCorruptRatio}, {"FaultCloseRatio", g.FaultCloseRatio}} {
+		if r.ratio < 0 || r.ratio > 1 {
+			errs.Add(r.field, "must be between 0 and 1, got %v", r.ratio)
+		}
+	}
+	if g.FaultSendDelay < 0 {
+		errs.Add("FaultSendDelay", "must not be negative, got %d", g.FaultSendDelay)
+	}
+}
+
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
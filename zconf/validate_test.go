@@ -0,0 +1,76 @@
+package zconf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, DefaultConfig().Validate())
+
+	g := DefaultConfig()
+	g.Mode = "udp"
+	g.WsPort = -1
+	g.WorkerPoolSize = 6
+	g.Shards = 4
+	g.QueueFullPolicy = QueueFullBlockTimeout
+	g.QueueFullTimeout = 0
+	g.CertFile = "missing.crt"
+	g.LogCompress = "bz2"
+
+	err := g.Validate()
+	errs, ok := err.(ConfigErrors)
+	assert.True(t, ok)
+	var fields []string
+	for _, p := range errs {
+		fields = append(fields, p.Field)
+	}
+	assert.Equal(t, []string{"Mode", "WsPort", "WorkerPoolSize", "QueueFullTimeout", "CertFile", "CertFile", "LogCompress"}, fields)
+	assert.Contains(t, err.Error(), "7 config problem(s)")
+}
+
+func TestValidateTrace(t *testing.T) {
+	g := DefaultConfig()
+	g.TraceExporter = "otlp"
+	g.TraceSampleRatio = 2
+	err := g.Validate()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "TraceEndpoint")
+	assert.Contains(t, err.Error(), "TraceSampleRatio")
+
+	g.TraceExporter = "jaeger"
+	g.TraceSampleRatio = 0.1
+	assert.Contains(t, g.Validate().Error(), "TraceExporter")
+}
+
+func TestValidateReplayWindow(t *testing.T) {
+	g := DefaultConfig()
+	g.ReplayWindow = 30000
+	g.HeaderFields = []string{"timestamp"}
+	assert.Contains(t, g.Validate().Error(), "ReplayWindow")
+
+	g.HeaderFields = append(g.HeaderFields, "nonce")
+	assert.Nil(t, g.Validate())
+}
+
+func TestValidateProxy(t *testing.T) {
+	g := DefaultConfig()
+	g.Mode = ServerModeProxy
+	assert.Contains(t, g.Validate().Error(), "ProxyUpstream")
+
+	g.ProxyUpstream = "127.0.0.1:8999"
+	assert.Nil(t, g.Validate())
+}
+
+func TestValidateReadBuff(t *testing.T) {
+	g := DefaultConfig()
+	g.IOReadBuffMin = 64
+	assert.Contains(t, g.Validate().Error(), "IOReadBuffMax")
+
+	g.IOReadBuffMax = 512
+	assert.Contains(t, g.Validate().Error(), "IOReadBuffSize")
+
+	g.IOReadBuffMax = 64 * 1024
+	assert.Nil(t, g.Validate())
+}
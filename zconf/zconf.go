@@ -9,17 +9,35 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zutils"
 )
 
 const (
 	ServerModeTcp       = "tcp"
 	ServerModeWebsocket = "websocket"
 	ServerModeKcp       = "kcp"
+	// ServerModeProxy relays the TCP connections to ProxyUpstream, decoding and logging the frames both ways
+	// (将TCP连接转发到ProxyUpstream，并解码、记录双向的帧)
+	ServerModeProxy = "proxy"
+)
+
+// Decoders selected by Config.Decoder (Config.Decoder可选的解码器)
+const (
+	DecoderTLV      = "tlv"      // zdecoder.NewTLVDecoder, or the schema decoder when HeaderFields are set
+	DecoderLTV      = "ltv"      // zdecoder.NewLTV_Little_Decoder
+	DecoderHTLVCRC  = "htlvcrc"  // zdecoder.NewHTLVCRCDecoder
+	DecoderTrace    = "trace"    // zdecoder.NewTraceTLVDecoder
+	DecoderJSON     = "json"     // zdecoder.NewJSONDecoder
+	DecoderJSONLine = "jsonline" // zdecoder.NewJSONLineDecoder
+	DecoderProtobuf = "protobuf" // zdecoder.NewProtobufDecoder
+	DecoderNone     = "none"     // No decoder, the datapack reads the frames.(不使用解码器，由数据包读取帧)
 )
 
 const (
@@ -33,6 +51,43 @@ const (
 	//跟WorkerModeHash的区别是，如果业务层回调有阻塞操作的话，也不影响其他连接的业务层处理。
 	//跟WorkerModeBind的区别是，不需要像Bind模式那样一开始就创建很多worker,而是根据连接数动态创建worker，这样可以避免闲置worker数量过多导致的资源浪费。
 	WorkerModeDynamicBind = "DynamicBind" // Dynamic binding of a worker to each connection when there is no worker in worker pool.(临时动态创建一个worker绑定到每个连接)
+
+	// WorkerModeSteal queues the messages of each connection separately, a connection starts on the worker chosen
+	// by its ConnID but idle workers steal whole connection queues from busy ones, the messages of a connection
+	// are still handled one by one in arrival order.
+	// (每个连接的消息单独排队，连接默认由ConnID对应的worker处理，空闲的worker会从繁忙的worker窃取整个连接的队列，
+	// 同一连接的消息仍按到达顺序逐个处理)
+	WorkerModeSteal = "Steal"
+)
+
+// What happens to a request when the task queue of its worker is full (worker任务队列已满时如何处理请求)
+const (
+	QueueFullBlock        = "Block"        // By default, wait until the worker takes a task.(默认一直等待worker取走任务)
+	QueueFullBlockTimeout = "BlockTimeout" // Wait at most QueueFullTimeout, then drop.(最多等待QueueFullTimeout，之后丢弃)
+	QueueFullDrop         = "Drop"         // Drop at once.(立即丢弃)
+	QueueFullSpill        = "Spill"        // Run on a spillover goroutine, out of order.(在溢出协程中执行，不保证顺序)
+	QueueFullShed         = "Shed"         // Drop routes of priority 0 or lower, wait for the others.(丢弃优先级不大于0的路由，其他路由等待)
+)
+
+// What happens to an inbound message over the rate limits of its connection (入站消息超过其连接的速率限制时如何处理)
+const (
+	RateLimitDrop  = "Drop"  // By default, drop it.(默认丢弃)
+	RateLimitDelay = "Delay" // Stop reading the connection until it is within the limits.(暂停读取连接直到回到限制之内)
+	RateLimitClose = "Close" // Drop it, close the connection after RateLimitWarnings of them.(丢弃，超过RateLimitWarnings次后关闭连接)
+)
+
+// The messages recorded into CaptureFile, empty records both (记录到CaptureFile的消息，为空时两者都记录)
+const (
+	CaptureInbound  = "inbound"
+	CaptureOutbound = "outbound"
+)
+
+// How the startup report of a server is printed on Start, see znet.Server.StartupReport
+// (服务启动时如何打印启动报告，见znet.Server.StartupReport)
+const (
+	StartupReportText = "text" // By default, one line per item.(默认每项一行)
+	StartupReportJSON = "json" // One JSON object, for the deployments asserting on it.(一个JSON对象，便于部署时校验)
+	StartupReportOff  = "off"  // Nothing is printed.(不打印)
 )
 
 /*
@@ -74,12 +129,75 @@ type Config struct {
 	WorkerPoolSize   uint32 // The number of worker pools in the business logic.(业务工作Worker池的数量)
 	MaxWorkerTaskLen uint32 // The maximum number of tasks that a worker pool can handle.(业务工作Worker对应负责的任务队列最大任务存储数量)
 	WorkerMode       string // The way to assign workers to connections.(为链接分配worker的方式)
+	QueueFullPolicy  string // What happens to a request when the task queue of its worker is full, see QueueFull*.(worker任务队列已满时的处理策略)
+	QueueFullTimeout int    // The maximum wait of QueueFullBlockTimeout in milliseconds.(QueueFullBlockTimeout的最长等待时间，单位毫秒)
 	MaxMsgChanLen    uint32 // The maximum length of the send buffer message queue.(SendBuffMsg发送消息的缓冲最大长度)
 	IOReadBuffSize   uint32 // The maximum size of the read buffer for each IO operation.(每次IO最大的读取长度)
 
+	// Bounds of the adaptive read buffer of the TCP and KCP connections, the buffer starts at IOReadBuffMin, doubles
+	// when a read fills it and halves after two reads fitting in half of it, and the read loop waits for the peer on
+	// a buffer of IOReadBuffMin, so that idle connections keep a small buffer while bulk transfers get a large one.
+	// 0 keeps the buffer at IOReadBuffSize.
+	// (TCP和KCP连接自适应读缓冲的上下限，缓冲初始为IOReadBuffMin，读满时加倍，连续两次读取不超过一半时减半，
+	// 读循环使用IOReadBuffMin大小的缓冲等待对端，使空闲连接保持较小的缓冲而大量传输获得较大的缓冲。为0时缓冲固定为IOReadBuffSize)
+	IOReadBuffMin uint32
+	IOReadBuffMax uint32
+
+	// Partition the TCP connections across this many engine shards, each with its own listener (SO_REUSEPORT
+	// where supported), accept loop, ConnID sequence, workers and timer wheel. 0 or 1 disables sharding.
+	// WorkerPoolSize must be a multiple of Shards, a connection of shard i only uses the workers w where w % Shards == i.
+	// (将TCP连接划分到多个引擎分片，每个分片拥有独立的监听(支持时使用SO_REUSEPORT)、Accept循环、ConnID序列、worker和时间轮，
+	// 0或1表示不分片。WorkerPoolSize必须是Shards的整数倍，分片i的连接只使用满足w % Shards == i的worker)
+	Shards int
+
+	// Optional fields appended to the header of the default TLV datapack, any of "version", "flags", "crc32",
+	// "timestamp", "nonce", "trace_id" and "signature", see znet.WithMessageSigning. They are always laid out in
+	// that order, both peers must configure the same fields.
+	// (默认TLV数据包头部追加的可选字段，可选"version"、"flags"、"crc32"、"timestamp"、"nonce"、"trace_id"、
+	// "signature"(见znet.WithMessageSigning)，始终按该顺序排列，通信双方必须配置相同的字段)
+	HeaderFields []string
+	// Milliseconds a message may be older or newer than the clock of the server, a message out of the window or
+	// with the nonce of a message within the window is rejected as a replay, 0 disables it. It needs the
+	// "timestamp" and "nonce" HeaderFields, see znet.WithNonceStore.
+	// (消息时间与服务端时钟允许相差的毫秒数，超出窗口或nonce与窗口内消息重复的消息作为重放被拒绝，0表示不开启。
+	// 需要HeaderFields包含"timestamp"和"nonce"，见znet.WithNonceStore)
+	ReplayWindow int
+	// The protocol version written into the "version" header field, frames carrying another version are rejected. 0 accepts any version.
+	// (写入"version"头部字段的协议版本号，携带其他版本号的帧会被拒绝，为0时接受任意版本)
+	HeaderVersion uint8
+
+	// Split messages larger than MaxPacketSize into fragments and reassemble them on the receiving side, both peers must enable it.
+	// (将超过MaxPacketSize的消息拆分为分片发送并在接收端重组，通信双方必须同时开启)
+	FragmentMode       bool
+	MaxFragmentBuffers int    // The maximum number of messages being reassembled at the same time on one connection.(每个连接同时重组的最大消息数)
+	MaxFragmentedSize  uint32 // The maximum size of a reassembled message, 0 is DefaultFragmentsPerMessage times MaxPacketSize.(重组后消息的最大长度，0表示MaxPacketSize的DefaultFragmentsPerMessage倍)
+
+	// Compression algorithms offered in the compression handshake, in order of preference, e.g. ["gzip", "deflate"].
+	// Empty disables compression. It needs a datapack carrying flags, e.g. HeaderFields with "flags".
+	// (压缩握手中提供的压缩算法，按优先级排列，例如["gzip", "deflate"]。为空时不压缩。
+	// 需要可以携带标志位的数据包，例如HeaderFields包含"flags")
+	Compression       []string
+	CompressThreshold int // Only data of at least this size is compressed.(只压缩不小于该长度的数据)
+
 	//The server mode, which can be "tcp" or "websocket". If it is empty, both modes are enabled.
 	//"tcp":tcp监听, "websocket":websocket 监听 为空时同时开启
 	Mode string
+	// The address the "proxy" mode relays the connections to, e.g. "10.0.0.5:8999", the frames are decoded with
+	// DataPack and recorded into CaptureFile if set.
+	// ("proxy"模式转发连接的目标地址，例如"10.0.0.5:8999"，帧使用DataPack解码，设置了CaptureFile时记录到其中)
+	ProxyUpstream string
+
+	// The datapack of the server, one of the ziface.ZinxDataPack* kinds, the default TLV datapack if empty.
+	// (服务使用的数据包，取值为ziface.ZinxDataPack*之一，为空时使用默认的TLV数据包)
+	DataPack string
+	// The decoder of the server, see Decoder*, empty picks the decoder matching DataPack.
+	// (服务使用的解码器，见Decoder*，为空时选择与DataPack匹配的解码器)
+	Decoder string
+
+	// Settings of named server instances, each overriding the fields above for the server created by
+	// znet.NewScopedServer(name), e.g. {"game": {"TCPPort": 9000, "MaxPacketSize": 8192}, "admin": {"Mode": "websocket"}}.
+	// (命名服务实例的配置，分别覆盖上面的字段，用于znet.NewScopedServer(name)创建的服务)
+	Servers map[string]json.RawMessage
 
 	// A boolean value that indicates whether the new or old version of the router is used. The default value is false.
 	// 路由模式 false为旧版本路由，true为启用新版本的路由 默认使用旧版本
@@ -99,11 +217,21 @@ type Config struct {
 	LogSaveDays int   // 日志最大保留天数
 	LogFileSize int64 // 日志单个日志最大容量 默认 64MB,单位：字节，记得一定要换算成MB（1024 * 1024）
 	LogCons     bool  // 日志标准输出  默认 false
+	LogJSON     bool  // Write JSON lines for log indexers such as ELK.(输出JSON格式的日志，便于ELK等索引)
+
+	LogMaxBackups  int    // The number of rotated log files to keep, 0 keeps all.(最多保留的旧日志文件数，0表示全部保留)
+	LogCompress    string // Compression of the rotated log files: "zip" (default), "gzip" or "none".(旧日志的压缩方式)
+	LogRotateHours int    // Rotate every N hours instead of daily, 0 rotates daily.(每N小时切割一次，0表示按天切割)
 
 	// The level of log isolation. The values can be 0 (all open), 1 (debug off), 2 (debug/info off), 3 (debug/info/warn off), and so on.
 	// 日志隔离级别  -- 0：全开 1：关debug 2：关debug/info 3：关debug/info/warn ...
 	LogIsolationLevel int
 
+	// The file in LogDir the security events (auth failures, rate limit violations, kicked connections and so on)
+	// are written to as JSON lines, rotated like LogFile, see zlog.Security. Empty disables them.
+	// (安全事件(认证失败、超过速率限制、被踢出的连接等)以JSON行写入的LogDir中的文件，与LogFile一样切割，见zlog.Security。为空时不记录)
+	SecurityLogFile string
+
 	/*
 		Keepalive
 	*/
@@ -116,6 +244,172 @@ type Config struct {
 	*/
 	CertFile       string // The name of the certificate file. If it is empty, TLS encryption is not enabled.(证书文件名称 默认"")
 	PrivateKeyFile string // The name of the private key file. If it is empty, TLS encryption is not enabled.(私钥文件名称 默认"" --如果没有设置证书和私钥文件，则不启用TLS加密)
+	// The PEM file of the CAs the client certificates are verified with, the clients must present a certificate when
+	// it is set, see znet.TLSAuthenticator.(校验客户端证书所用CA的PEM文件，设置后客户端必须出示证书，见znet.TLSAuthenticator)
+	ClientCAFile string
+	// Seconds between the checks of CertFile and PrivateKeyFile for changes, the renewed certificates are served
+	// without a restart, 0 checks on every handshake, defaults to 30.
+	// (检查CertFile与PrivateKeyFile是否变化的间隔秒数，更新的证书无需重启即可生效，0表示每次握手都检查，默认为30)
+	CertReloadInterval int
+	// Staple the OCSP response of the certificate to the handshakes, fetched from the responder of the certificate.
+	// (将证书的OCSP响应装订到握手中，从证书的OCSP服务获取)
+	OCSPStapling bool
+
+	/*
+		Listener
+	*/
+	// The length of the accept queue of the TCP listener on Linux, 0 uses net.core.somaxconn.
+	// (Linux下TCP监听accept队列的长度，0表示使用net.core.somaxconn)
+	ListenBacklog int
+	// Seconds the kernel holds a connection until it sends data before it is accepted, with TCP_DEFER_ACCEPT on Linux,
+	// so that the idle handshakes of a SYN flood never reach the server, 0 disables it.
+	// (Linux下通过TCP_DEFER_ACCEPT在连接发送数据之前由内核保留的秒数，使SYN洪水中的空闲握手不会到达服务，0表示关闭)
+	TCPDeferAccept int
+	// The decode errors and auth failures from one IP within AbuseWindow after which the new connections of the IP
+	// are refused for AbusePause, doubled each time the IP is paused again, 0 disables it.
+	// (同一IP在AbuseWindow内的解码错误与认证失败次数达到该值后，在AbusePause内拒绝该IP的新连接，IP再次被暂停时时长加倍，0表示关闭)
+	AbuseThreshold int
+	// The window of AbuseThreshold in seconds, defaults to 10.(AbuseThreshold的统计窗口秒数，默认为10)
+	AbuseWindow int
+	// Seconds the new connections of an abusive IP are first refused for, defaults to 30.(首次拒绝滥用IP新连接的秒数，默认为30)
+	AbusePause int
+
+	/*
+		Rate limits
+	*/
+	// The inbound messages per second allowed to a connection, 0 is unlimited, see znet.SetRouterRateLimit for the
+	// limits of the routes.(每个连接每秒允许的入站消息数，0表示不限制，路由的限制见znet.SetRouterRateLimit)
+	RateLimitMessages float64
+	// The inbound bytes per second allowed to a connection, 0 is unlimited.(每个连接每秒允许的入站字节数，0表示不限制)
+	RateLimitBytes int
+	// The messages a connection may send at once above the rate, defaults to one second of RateLimitMessages.
+	// (连接可以超出速率一次性发送的消息数，默认为RateLimitMessages一秒的量)
+	RateLimitBurst int
+	// What happens to the messages over the limits, see RateLimit*, defaults to RateLimitDrop.
+	// (超过限制的消息如何处理，见RateLimit*，默认为RateLimitDrop)
+	RateLimitAction string
+	// The messages over the limits tolerated with RateLimitClose before the connection is closed, defaults to 10.
+	// (使用RateLimitClose时关闭连接之前容忍的超限消息数，默认为10)
+	RateLimitWarnings int
+
+	/*
+		Auth
+	*/
+	// Milliseconds a connection may stay unauthenticated with znet.WithAuth before it is closed, defaults to 10000.
+	// (使用znet.WithAuth时连接未认证状态可保持的毫秒数，超时后连接被关闭，默认为10000)
+	AuthTimeout int
+
+	/*
+		Metrics
+	*/
+	// The address of the HTTP listener exposing the Prometheus metrics on /metrics, e.g. ":9100", empty disables it.
+	// (以Prometheus格式在/metrics上暴露指标的HTTP监听地址，如":9100"，为空时不开启)
+	MetricsAddr string
+	// Seconds between the calls of the stats hooks, see znet.WithStatsHook, defaults to 10.
+	// (统计钩子的调用间隔秒数，见znet.WithStatsHook，默认为10)
+	StatsInterval int
+
+	/*
+		Watchdog
+	*/
+	// Handlers still running after this many milliseconds are logged with their stack, 0 disables it.
+	// (运行超过该毫秒数的处理函数会连同其调用栈一起记录，0表示关闭)
+	SlowHandlerThreshold int
+	// Inbound and outbound messages whose data is larger than this many bytes are logged, 0 disables it.
+	// (数据超过该字节数的收发消息会被记录，0表示关闭)
+	LargeMessageSize int
+
+	/*
+		Traffic
+	*/
+	// Seconds between the traffic snapshots of IServer.GetTrafficSnapshot, defaults to 10, 0 takes them on demand.
+	// (IServer.GetTrafficSnapshot的流量快照间隔秒数，默认为10，0表示按需获取)
+	TrafficInterval int
+
+	/*
+		Timer
+	*/
+	// Milliseconds between the ticks of the finest timing wheel of the connections, e.g. 1 for game loops,
+	// 0 keeps the second, minute and hour wheels.
+	// (连接所用最细一层时间轮的刻度毫秒数，例如游戏循环为1，0表示使用秒、分钟、小时三层时间轮)
+	TimerTick int
+	// The scales of each wheel with TimerTick, defaults to 60.(设置TimerTick时每层时间轮的刻度数，默认为60)
+	TimerScales int
+	// The levels of wheels with TimerTick, defaults to 3.(设置TimerTick时时间轮的层数，默认为3)
+	TimerLevels int
+
+	/*
+		Health
+	*/
+	// The server is not ready while a worker has more requests waiting than this, 0 disables the check.
+	// (worker等待的请求数超过该值时服务未就绪，0表示不检查)
+	HealthQueueThreshold int
+	// The server is not ready while more frames per second than this fail to decode, 0 disables the check.
+	// (每秒解码失败的帧数超过该值时服务未就绪，0表示不检查)
+	HealthDecodeErrorRate float64
+	// Seconds Serve keeps the server not ready but running after SIGINT or SIGTERM, so that the load balancers
+	// stop routing to it first, 0 returns at once.
+	// (Serve在收到SIGINT或SIGTERM后保持服务运行但未就绪的秒数，以便负载均衡先停止转发，0表示立即返回)
+	ShutdownDelay int
+	// How the startup report is printed on Start, see StartupReport*, empty is StartupReportText.
+	// (启动时如何打印启动报告，见StartupReport*，为空时为StartupReportText)
+	StartupReport string
+
+	/*
+		Capture
+	*/
+	// The file the decoded messages are recorded into, see zcapture, empty disables it.(记录解码后消息的文件，见zcapture，为空时不开启)
+	CaptureFile string
+	// The messages recorded: "inbound", "outbound", or empty for both.(记录的消息："inbound"、"outbound"，为空表示两者)
+	CaptureDirection string
+	// Only record these msgIDs, empty records all.(只记录这些msgID，为空表示全部)
+	CaptureMsgIDs []uint32
+
+	/*
+		Admin
+	*/
+	// The address of the HTTP listener exposing net/http/pprof, the runtime stats and the framework internals,
+	// e.g. "127.0.0.1:6060", empty disables it. It should only be reachable by the operators.
+	// (暴露net/http/pprof、运行时统计和框架内部状态的HTTP监听地址，如"127.0.0.1:6060"，为空时不开启，应只对运维人员可达)
+	AdminAddr string
+	// The token the admin requests carry as "Authorization: Bearer <token>", the /admin/ API to list and kick
+	// connections, broadcast, change the log levels and resize the worker pool is disabled without it.
+	// (管理请求以"Authorization: Bearer <token>"携带的令牌，没有令牌时禁用列出和踢出连接、广播、修改日志级别、调整工作池的/admin/接口)
+	AdminToken string
+
+	/*
+		Fault injection
+	*/
+	// Faults injected into the connections to test the reconnect and the reliability logic of the clients, never in
+	// production, see ziface.Faults, they can also be changed by the /admin/faults API. The ratios are from 0 to 1.
+	// (向连接注入的故障，用于测试客户端的重连与可靠性逻辑，不可用于生产环境，见ziface.Faults，也可以通过/admin/faults接口修改，比例取值0到1)
+	// The ratio of the sends delayed by up to FaultSendDelay milliseconds.(被延迟最多FaultSendDelay毫秒的发送所占比例)
+	FaultSendDelayRatio float64
+	FaultSendDelay      int
+	// The ratio of the inbound frames dropped before they are decoded.(在解码之前被丢弃的入站帧所占比例)
+	FaultDropRatio float64
+	// The ratio of the inbound frames with a byte flipped before they are decoded.(在解码之前被翻转一个字节的入站帧所占比例)
+	FaultCorruptRatio float64
+	// The ratio of the inbound frames closing their connection.(导致连接被关闭的入站帧所占比例)
+	FaultCloseRatio float64
+
+	/*
+		Tracing
+	*/
+	// The exporter of the spans recorded by ztrace: "log" writes them to the log, "otlp" posts them to TraceEndpoint,
+	// empty disables tracing.(ztrace记录的span的导出器："log"写入日志，"otlp"发送到TraceEndpoint，为空时不开启追踪)
+	TraceExporter string
+	// The OTLP/HTTP receiver of the "otlp" exporter, e.g. "http://localhost:4318".("otlp"导出器的OTLP/HTTP接收端)
+	TraceEndpoint string
+	// The ratio of the traces recorded, from 0 to 1, defaults to 1.(被记录的链路比例，取值0到1，默认为1)
+	TraceSampleRatio float64
+
+	/*
+		Plugins
+	*/
+	// The plugins enabled by their registered names, in order, see znet.RegisterPlugin, e.g. ["metrics", "auth"].
+	// (按注册名称开启的插件，按顺序初始化，见znet.RegisterPlugin，例如["metrics", "auth"])
+	Plugins []string
 }
 
 // GlobalObject Define a global object.(定义一个全局的对象)
@@ -139,12 +433,18 @@ func PathExists(path string) (bool, error) {
 // and updates the fields of the "Config" structure accordingly.
 // If the configuration file does not exist, it prints an error message to the log and returns.
 func (g *Config) Reload() {
+	if g == GlobalObject {
+		defer resetLive()
+	}
 	confFilePath := GetConfigFilePath()
 	if confFileExists, _ := PathExists(confFilePath); confFileExists != true {
 
 		// The configuration file may not exist,
 		// in which case the default parameters should be used to initialize the logging module configuration.
 		// (配置文件不存在也需要用默认参数初始化日志模块配置)
+		if err := applyOverrides(g); err != nil {
+			panic(err)
+		}
 		g.InitLogConfig()
 
 		zlog.Ins().ErrorF("Config File %s is not exist!! \n You can set configFile by setting the environment variable %s, like export %s = xxx/xxx/zinx.conf ", confFilePath, EnvConfigFilePathKey, EnvConfigFilePathKey)
@@ -156,11 +456,17 @@ func (g *Config) Reload() {
 		panic(err)
 	}
 
-	err = json.Unmarshal(data, g)
+	// JSON, YAML or TOML by the extension of the file (根据文件扩展名按JSON、YAML或TOML解析)
+	err = decodeConfig(FormatOf(confFilePath), data, g)
 	if err != nil {
 		panic(err)
 	}
 
+	// Environment variables and flags take precedence over the file (环境变量和flag优先于配置文件)
+	if err = applyOverrides(g); err != nil {
+		panic(err)
+	}
+
 	g.InitLogConfig()
 }
 
@@ -183,62 +489,160 @@ func (g *Config) HeartbeatMaxDuration() time.Duration {
 	return time.Duration(g.HeartbeatMax) * time.Second
 }
 
+func (g *Config) StatsIntervalDuration() time.Duration {
+	return time.Duration(g.StatsInterval) * time.Second
+}
+
+// DefaultFragmentsPerMessage bounds the reassembled messages to this many MaxPacketSize unless MaxFragmentedSize is
+// set (未设置MaxFragmentedSize时，重组后的消息最多为MaxPacketSize的这一倍数)
+const DefaultFragmentsPerMessage = 256
+
+// FragmentedSizeLimit returns the maximum size of a reassembled message, 0 if MaxFragmentedSize and MaxPacketSize are
+// both unlimited (返回重组后消息的最大长度，MaxFragmentedSize与MaxPacketSize均不限制时返回0)
+func (g *Config) FragmentedSizeLimit() uint64 {
+	if g.MaxFragmentedSize > 0 {
+		return uint64(g.MaxFragmentedSize)
+	}
+	return DefaultFragmentsPerMessage * uint64(g.MaxPacketSize)
+}
+
+func (g *Config) QueueFullTimeoutDuration() time.Duration {
+	return time.Duration(g.QueueFullTimeout) * time.Millisecond
+}
+
+func (g *Config) ReplayWindowDuration() time.Duration {
+	return time.Duration(g.ReplayWindow) * time.Millisecond
+}
+
+func (g *Config) CertReloadIntervalDuration() time.Duration {
+	return time.Duration(g.CertReloadInterval) * time.Second
+}
+
+func (g *Config) TCPDeferAcceptDuration() time.Duration {
+	return time.Duration(g.TCPDeferAccept) * time.Second
+}
+
+func (g *Config) AbuseWindowDuration() time.Duration {
+	return time.Duration(g.AbuseWindow) * time.Second
+}
+
+func (g *Config) AbusePauseDuration() time.Duration {
+	return time.Duration(g.AbusePause) * time.Second
+}
+
+// Faults returns the faults injected by the config (返回配置注入的故障)
+func (g *Config) Faults() ziface.Faults {
+	return ziface.Faults{
+		SendDelayRatio: g.FaultSendDelayRatio,
+		SendDelay:      time.Duration(g.FaultSendDelay) * time.Millisecond,
+		DropRatio:      g.FaultDropRatio,
+		CorruptRatio:   g.FaultCorruptRatio,
+		CloseRatio:     g.FaultCloseRatio,
+	}
+}
+
+func (g *Config) AuthTimeoutDuration() time.Duration {
+	return time.Duration(g.AuthTimeout) * time.Millisecond
+}
+
 func (g *Config) InitLogConfig() {
+	g.configureLog(zlog.StdZinxLog)
+	if g.SecurityLogFile != "" {
+		zlog.SetSecuritySink(zlog.NewJSONSecuritySink(g.securityLogWriter()))
+	}
+}
+
+// NewLogger returns a logger of its own configured by the log settings of g, the logger of a server whose config
+// logs differently from the global one (返回按g的日志设置配置的独立日志，用于日志设置与全局配置不同的服务)
+func (g *Config) NewLogger() ziface.ILogger {
+	core := zlog.NewZinxLog("", zlog.BitDefault)
+	g.configureLog(core)
+	return zlog.New(core)
+}
+
+// HasOwnLog reports whether g logs differently from the global config (判断g的日志设置是否与全局配置不同)
+func (g *Config) HasOwnLog() bool {
+	return g != GlobalObject && (g.LogDir != GlobalObject.LogDir || g.LogFile != GlobalObject.LogFile ||
+		g.LogIsolationLevel != GlobalObject.LogIsolationLevel || g.LogJSON != GlobalObject.LogJSON)
+}
+
+func (g *Config) configureLog(core *zlog.ZinxLoggerCore) {
 	if g.LogFile != "" {
-		zlog.SetLogFile(g.LogDir, g.LogFile)
-		zlog.SetCons(g.LogCons)
+		core.SetLogFile(g.LogDir, g.LogFile)
+		core.SetCons(g.LogCons)
 	}
 	if g.LogSaveDays > 0 {
-		zlog.SetMaxAge(g.LogSaveDays)
+		core.SetMaxAge(g.LogSaveDays)
 	}
 	if g.LogFileSize > 0 {
-		zlog.SetMaxSize(g.LogFileSize)
+		core.SetMaxSize(g.LogFileSize)
 	}
+	core.SetMaxBackups(g.LogMaxBackups)
+	if g.LogCompress != "" {
+		core.SetCompress(g.LogCompress)
+	}
+	core.SetRotateInterval(time.Duration(g.LogRotateHours) * time.Hour)
 	if g.LogIsolationLevel > zlog.LogDebug {
-		zlog.SetLogLevel(g.LogIsolationLevel)
+		core.SetLogLevel(g.LogIsolationLevel)
 	}
+	core.SetJSON(g.LogJSON)
 }
 
-/*
-init, set default value
-*/
-func init() {
+// securityLogWriter opens SecurityLogFile with the rotation of the log file (以日志文件的切割设置打开SecurityLogFile)
+func (g *Config) securityLogWriter() *zutils.Writer {
+	w := zutils.New(filepath.Join(g.LogDir, g.SecurityLogFile))
+	if g.LogSaveDays > 0 {
+		w.SetMaxAge(g.LogSaveDays)
+	}
+	if g.LogFileSize > 0 {
+		w.SetMaxSize(g.LogFileSize)
+	}
+	w.SetMaxBackups(g.LogMaxBackups)
+	if g.LogCompress != "" {
+		w.SetCompress(g.LogCompress)
+	}
+	w.SetRotateInterval(time.Duration(g.LogRotateHours) * time.Hour)
+	return w
+}
+
+// DefaultConfig returns a new config with the default values, without reading any file
+// (返回一个使用默认值的新配置，不读取任何文件)
+func DefaultConfig() *Config {
 	pwd, err := os.Getwd()
 	if err != nil {
 		pwd = "."
 	}
 
-	// Note: Prevent errors like "flag provided but not defined: -test.paniconexit0" from occurring in go test.
-	// (防止 go test 出现"flag provided but not defined: -test.paniconexit0"等错误)
-	testing.Init()
-
-	// Initialize the GlobalObject variable and set some default values.
-	// (初始化GlobalObject变量，设置一些默认值)
-	GlobalObject = &Config{
-		Name:              "ZinxServerApp",
-		Version:           "V1.0",
-		TCPPort:           8999,
-		WsPort:            9000,
-		KcpPort:           9001,
-		Host:              "0.0.0.0",
-		MaxConn:           12000,
-		MaxPacketSize:     4096,
-		WorkerPoolSize:    10,
-		MaxWorkerTaskLen:  1024,
-		WorkerMode:        "",
-		MaxMsgChanLen:     1024,
-		LogDir:            pwd + "/log",
-		LogFile:           "", // if set "", print to Stderr(默认日志文件为空，打印到stderr)
-		LogIsolationLevel: 0,
-		HeartbeatMax:      10, // The default maximum interval for heartbeat detection is 10 seconds. (默认心跳检测最长间隔为10秒)
-		IOReadBuffSize:    1024,
-		CertFile:          "",
-		PrivateKeyFile:    "",
-		Mode:              ServerModeTcp,
-		RouterSlicesMode:  false,
-		RequestPoolMode:   false,
-		KcpACKNoDelay:     false,
-		KcpStreamMode:     true,
+	return &Config{
+		Name:               "ZinxServerApp",
+		Version:            "V1.0",
+		TCPPort:            8999,
+		WsPort:             9000,
+		KcpPort:            9001,
+		Host:               "0.0.0.0",
+		MaxConn:            12000,
+		MaxPacketSize:      4096,
+		WorkerPoolSize:     10,
+		MaxWorkerTaskLen:   1024,
+		WorkerMode:         "",
+		QueueFullPolicy:    QueueFullBlock,
+		QueueFullTimeout:   100,
+		MaxMsgChanLen:      1024,
+		MaxFragmentBuffers: 4,
+		CompressThreshold:  1024,
+		LogDir:             pwd + "/log",
+		LogFile:            "", // if set "", print to Stderr(默认日志文件为空，打印到stderr)
+		LogIsolationLevel:  0,
+		HeartbeatMax:       10, // The default maximum interval for heartbeat detection is 10 seconds. (默认心跳检测最长间隔为10秒)
+		IOReadBuffSize:     1024,
+		CertFile:           "",
+		PrivateKeyFile:     "",
+		CertReloadInterval: 30,
+		Mode:               ServerModeTcp,
+		RouterSlicesMode:   false,
+		RequestPoolMode:    false,
+		KcpACKNoDelay:      false,
+		KcpStreamMode:      true,
 		//Normal Mode: ikcp_nodelay(kcp, 0, 40, 0, 0);
 		//Turbo Mode： ikcp_nodelay(kcp, 1, 10, 2, 1);
 		KcpNoDelay:         1,
@@ -249,7 +653,27 @@ func init() {
 		KcpSendWindow:      32,
 		KcpFecDataShards:   0,
 		KcpFecParityShards: 0,
+		TraceSampleRatio:   1,
+		TrafficInterval:    10,
+		AuthTimeout:        10000,
+		RateLimitWarnings:  10,
+		AbuseWindow:        10,
+		AbusePause:         30,
+		StatsInterval:      10,
 	}
+}
+
+/*
+init, set default value
+*/
+func init() {
+	// Note: Prevent errors like "flag provided but not defined: -test.paniconexit0" from occurring in go test.
+	// (防止 go test 出现"flag provided but not defined: -test.paniconexit0"等错误)
+	testing.Init()
+
+	// Initialize the GlobalObject variable and set some default values.
+	// (初始化GlobalObject变量，设置一些默认值)
+	GlobalObject = DefaultConfig()
 
 	// Note: Load some user-configured parameters from the configuration file.
 	// (从配置文件中加载一些用户配置的参数)
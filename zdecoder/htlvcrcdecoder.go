@@ -48,6 +48,7 @@ import (
 
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
 )
 
 const HEADER_SIZE = 5
@@ -105,7 +106,10 @@ func (hcd *HtlvCrcDecoder) decode(data []byte) *HtlvCrcDecoder {
 
 	// CRC
 	if !CheckCRC(data[:datasize-2], htlvData.Crc) {
-		zlog.Ins().DebugF("crc check error %s %s\n", hex.EncodeToString(data), hex.EncodeToString(htlvData.Crc))
+		zmetrics.DecodeErrors.Inc()
+		if ctx, ok := decodeErrLog.Allow(decoderLogCtx); ok {
+			zlog.Ins().DebugFX(ctx, "crc check error %s %s\n", hex.EncodeToString(data), hex.EncodeToString(htlvData.Crc))
+		}
 		return nil
 	}
 
@@ -135,6 +139,11 @@ func (hcd *HtlvCrcDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
 
 	//4. HTLV+CRC Decode
 	htlvData := hcd.decode(data)
+	if htlvData == nil {
+		// The CRC check failed, the frame is dropped (CRC校验失败，丢弃该帧)
+		reportDecodeError(chain)
+		return nil
+	}
 
 	//5. Set the decoded data back to the IMessage, the Zinx Router needs MsgID for addressing
 	// (将解码后的数据重新设置到IMessage中, Zinx的Router需要MsgID来寻址)
@@ -0,0 +1,93 @@
+// JSONDecoder decodes the frames of zpack.DataPackJSON, {"id":1,"data":{...}} envelopes framed by
+// a newline or by a 4 bytes big endian length prefix.
+// (解码zpack.DataPackJSON的帧，即以换行或4字节大端长度前缀分帧的{"id":1,"data":{...}}信封)
+//
+// Newline framing (换行分帧):
+//
+//	{"id":1,"data":{"name":"zinx"}}\n
+//	{"id":0,"topic":"device/1001/telemetry","data":{"temp":21}}\n
+//
+// Length prefixed framing (长度前缀分帧):
+//
+//	+---------------+--------------------------+
+//	|    Length     |      JSON envelope       |
+//	| uint32(4byte) |         n byte           |
+//	+---------------+--------------------------+
+
+package zdecoder
+
+import (
+	"math"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+// topicMessage is a message the topic of an envelope can be set on (可以设置信封主题的消息)
+type topicMessage interface {
+	SetTopic(topic string)
+}
+
+type JSONDecoder struct {
+	Newline bool // newline delimited instead of length prefixed(使用换行而不是长度前缀分帧)
+}
+
+// NewJSONDecoder creates a decoder of length prefixed JSON envelopes (创建长度前缀分帧的JSON解码器)
+func NewJSONDecoder() ziface.IDecoder {
+	return &JSONDecoder{}
+}
+
+// NewJSONLineDecoder creates a decoder of newline delimited JSON envelopes (创建换行分帧的JSON解码器)
+func NewJSONLineDecoder() ziface.IDecoder {
+	return &JSONDecoder{Newline: true}
+}
+
+func (d *JSONDecoder) GetLengthField() *ziface.LengthField {
+	if d.Newline {
+		return &ziface.LengthField{
+			MaxFrameLength: math.MaxUint32,
+			Delimiter:      []byte("\n"),
+		}
+	}
+	return &ziface.LengthField{
+		MaxFrameLength:      math.MaxUint32 + 4,
+		LengthFieldOffset:   0,
+		LengthFieldLength:   4,
+		LengthAdjustment:    0,
+		InitialBytesToStrip: 4,
+	}
+}
+
+func (d *JSONDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	iMessage := chain.GetIMessage()
+	if iMessage == nil {
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	envelope, err := zpack.UnmarshalJSONEnvelope(iMessage.GetData())
+	if err != nil {
+		decodeErrors(chain).Inc()
+		if ctx, ok := decodeErrLog.Allow(chainLogCtx(chain)); ok {
+			zlog.Ins().ErrorFX(ctx, "json envelope decode error: %v", err)
+		}
+		reportDecodeError(chain)
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	// Set the decoded data back to the IMessage, the Zinx Router needs MsgID for addressing
+	// (将解码后的数据重新设置到IMessage中, Zinx的Router需要MsgID来寻址)
+	iMessage.SetMsgID(envelope.GetMsgID())
+	iMessage.SetData(envelope.GetData())
+	iMessage.SetDataLen(envelope.GetDataLen())
+	// The topic routes the request by topic, see znet.DefaultTopicExtractor (主题使请求按主题路由，见znet.DefaultTopicExtractor)
+	if topic := envelope.GetTopic(); topic != "" {
+		if tm, ok := iMessage.(topicMessage); ok {
+			tm.SetTopic(topic)
+		} else if request, ok := chain.Request().(ziface.IRequest); ok {
+			request.Set(ziface.TopicKey, topic)
+		}
+	}
+
+	return chain.ProceedWithIMessage(iMessage, envelope)
+}
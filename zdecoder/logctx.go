@@ -0,0 +1,46 @@
+package zdecoder
+
+import (
+	"context"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+// decoderLogCtx is the log context of the decoder lines without a connection (没有连接的解码日志的context)
+var decoderLogCtx = zlog.WithModule(context.Background(), zlog.ModuleDecoder)
+
+// decodeErrLog samples the decode error lines, a client can send malformed frames at will
+// (解码错误日志的采样器，客户端可以随意发送错误的帧)
+var decodeErrLog = zlog.PerSecond(10)
+
+// chainLogCtx returns the log context of the frame being decoded in chain, with the fields of its connection
+// (返回chain中正在解码的帧的日志context，附带其连接的字段)
+func chainLogCtx(chain ziface.IChain) context.Context {
+	if request, ok := chain.Request().(ziface.IRequest); ok {
+		return zlog.WithFields(decoderLogCtx, zlog.ConnFields(request.GetConnection())...)
+	}
+	return decoderLogCtx
+}
+
+// reportDecodeError reports a frame which could not be decoded to the message handler of its connection, e.g. so
+// that the sources sending malformed frames are paused (向连接的消息处理器报告无法解码的帧，例如以暂停发送错误帧的来源)
+// decodeErrors returns the decode error counter of the server owning the connection of chain
+// (返回chain所属连接的服务的解码错误计数器)
+func decodeErrors(chain ziface.IChain) *zmetrics.Counter {
+	if request, ok := chain.Request().(ziface.IRequest); ok && request.GetConnection() != nil {
+		return zmetrics.Of(request.GetConnection().GetMsgHandler()).DecodeErrors
+	}
+	return zmetrics.DecodeErrors
+}
+
+func reportDecodeError(chain ziface.IChain) {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetConnection() == nil {
+		return
+	}
+	if reporter, ok := request.GetConnection().GetMsgHandler().(ziface.IAbuseReporter); ok {
+		reporter.ReportAbuse(request.GetConnection(), ziface.AbuseDecodeError)
+	}
+}
@@ -0,0 +1,68 @@
+// ProtobufDecoder decodes the frames of zpack.DataPackProtobuf, a 4 bytes big endian length
+// followed by a protobuf Envelope (msg_id, flags, correlation_id, body).
+// (解码zpack.DataPackProtobuf的帧，即4字节大端长度加上protobuf信封(msg_id, flags, correlation_id, body))
+//
+// +---------------+--------------------------+
+// |    Length     |   Envelope (protobuf)    |
+// | uint32(4byte) |         n byte           |
+// +---------------+--------------------------+
+//
+//	lengthFieldOffset   = 0 (长度字段位于帧首)
+//	lengthFieldLength   = 4 (Length是4个byte)
+//	lengthAdjustment    = 0 (Length只表示Envelope长度)
+//	initialBytesToStrip = 4 (去掉长度前缀，只返回Envelope)
+
+package zdecoder
+
+import (
+	"math"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+type ProtobufDecoder struct{}
+
+func NewProtobufDecoder() ziface.IDecoder {
+	return &ProtobufDecoder{}
+}
+
+func (d *ProtobufDecoder) GetLengthField() *ziface.LengthField {
+	return &ziface.LengthField{
+		MaxFrameLength:      math.MaxUint32 + 4,
+		LengthFieldOffset:   0,
+		LengthFieldLength:   4,
+		LengthAdjustment:    0,
+		InitialBytesToStrip: 4,
+	}
+}
+
+func (d *ProtobufDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	iMessage := chain.GetIMessage()
+	if iMessage == nil {
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	envelope, err := zpack.UnmarshalEnvelope(iMessage.GetData())
+	if err != nil {
+		decodeErrors(chain).Inc()
+		if ctx, ok := decodeErrLog.Allow(chainLogCtx(chain)); ok {
+			zlog.Ins().ErrorFX(ctx, "protobuf envelope decode error: %v", err)
+		}
+		reportDecodeError(chain)
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	// Set the decoded data back to the IMessage, the Zinx Router needs MsgID for addressing
+	// (将解码后的数据重新设置到IMessage中, Zinx的Router需要MsgID来寻址)
+	iMessage.SetMsgID(envelope.GetMsgID())
+	iMessage.SetData(envelope.GetData())
+	iMessage.SetDataLen(envelope.GetDataLen())
+	if em, ok := iMessage.(ziface.IEnvelopeMessage); ok {
+		em.SetFlags(envelope.GetFlags())
+		em.SetCorrelationID(envelope.GetCorrelationID())
+	}
+
+	return chain.ProceedWithIMessage(iMessage, envelope)
+}
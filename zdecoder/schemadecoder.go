@@ -0,0 +1,102 @@
+// SchemaTLVDecoder decodes the frames of zpack.DataPackSchema, the default TLV extended with the
+// optional fields of a zpack.HeaderSchema between the length field and the value. Frames with an
+// unsupported version or a wrong CRC32 are dropped.
+// (解码zpack.DataPackSchema的帧，即在长度字段和内容之间扩展了zpack.HeaderSchema可选字段的默认TLV，
+// 版本号不支持或CRC32错误的帧会被丢弃)
+//
+// +---------------+---------------+-----------------+-----------+
+// |      Tag      |    Length     | optional fields |   Value   |
+// | uint32(4byte) | uint32(4byte) |  ExtLen() byte  |  n byte   |
+// +---------------+---------------+-----------------+-----------+
+//
+//	lengthFieldOffset   = 4        (Length的字节位索引下标是4)
+//	lengthFieldLength   = 4        (Length是4个byte)
+//	lengthAdjustment    = ExtLen() (Length只表示Value长度，Length与Value之间还有可选字段)
+//	initialBytesToStrip = 0        (返回完整的协议内容)
+
+package zdecoder
+
+import (
+	"math"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+type SchemaTLVDecoder struct {
+	Schema    *zpack.HeaderSchema
+	Tag       uint32 //T
+	Length    uint32 //L
+	Version   uint8
+	Flags     uint32
+	Checksum  uint32
+	Timestamp int64
+	Nonce     uint64
+	Signature []byte
+	Value     []byte //V
+}
+
+func NewSchemaTLVDecoder(schema *zpack.HeaderSchema) ziface.IDecoder {
+	return &SchemaTLVDecoder{Schema: schema}
+}
+
+func (d *SchemaTLVDecoder) GetLengthField() *ziface.LengthField {
+	return &ziface.LengthField{
+		MaxFrameLength:      math.MaxUint32 + uint64(d.Schema.HeadLen()),
+		LengthFieldOffset:   4,
+		LengthFieldLength:   4,
+		LengthAdjustment:    int(d.Schema.ExtLen()),
+		InitialBytesToStrip: 0,
+	}
+}
+
+func (d *SchemaTLVDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	iMessage := chain.GetIMessage()
+	if iMessage == nil {
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	data := iMessage.GetData()
+	if uint32(len(data)) < d.Schema.HeadLen() {
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	msg, err := d.Schema.Decode(data)
+	if err != nil {
+		// Drop the frame, it never reaches the routers (丢弃该帧，不会进入路由)
+		decodeErrors(chain).Inc()
+		if ctx, ok := decodeErrLog.Allow(chainLogCtx(chain)); ok {
+			zlog.Ins().ErrorFX(ctx, "schema tlv decode msg err: %v", err)
+		}
+		reportDecodeError(chain)
+		return nil
+	}
+
+	// Set the decoded data back to the IMessage, the Zinx Router needs MsgID for addressing
+	// (将解码后的数据重新设置到IMessage中, Zinx的Router需要MsgID来寻址)
+	iMessage.SetMsgID(msg.GetMsgID())
+	iMessage.SetData(msg.GetData())
+	iMessage.SetDataLen(msg.GetDataLen())
+	if hm, ok := iMessage.(ziface.IHeaderMessage); ok {
+		hm.SetVersion(msg.GetVersion())
+		hm.SetFlags(msg.GetFlags())
+		hm.SetChecksum(msg.GetChecksum())
+		hm.SetTimestamp(msg.GetTimestamp())
+		hm.SetNonce(msg.GetNonce())
+		hm.SetSignature(msg.GetSignature())
+	}
+
+	return chain.ProceedWithIMessage(iMessage, SchemaTLVDecoder{
+		Schema:    d.Schema,
+		Tag:       msg.GetMsgID(),
+		Length:    msg.GetDataLen(),
+		Version:   msg.GetVersion(),
+		Flags:     msg.GetFlags(),
+		Checksum:  msg.GetChecksum(),
+		Timestamp: msg.GetTimestamp(),
+		Nonce:     msg.GetNonce(),
+		Signature: msg.GetSignature(),
+		Value:     msg.GetData(),
+	})
+}
@@ -50,11 +50,11 @@
 package zdecoder
 
 import (
-	"bytes"
 	"encoding/binary"
 	"math"
 
 	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
 )
 
 const TLV_HEADER_SIZE = 8 //表示TLV空包长度
@@ -65,7 +65,13 @@ type TLVDecoder struct {
 	Value  []byte //V
 }
 
+// NewTLVDecoder returns the default TLV decoder, a SchemaTLVDecoder when optional header fields
+// are configured by zconf.Config.HeaderFields
+// (返回默认的TLV解码器，配置了zconf.Config.HeaderFields可选头部字段时返回SchemaTLVDecoder)
 func NewTLVDecoder() ziface.IDecoder {
+	if schema := zpack.HeaderSchemaFromConfig(); schema != nil {
+		return NewSchemaTLVDecoder(schema)
+	}
 	return &TLVDecoder{}
 }
 
@@ -94,20 +100,19 @@ func (tlv *TLVDecoder) GetLengthField() *ziface.LengthField {
 	}
 }
 
-func (tlv *TLVDecoder) decode(data []byte) *TLVDecoder {
+func (tlv *TLVDecoder) decode(data []byte) TLVDecoder {
 	tlvData := TLVDecoder{}
 	//Get T
 	tlvData.Tag = binary.BigEndian.Uint32(data[0:4])
 	//Get L
 	tlvData.Length = binary.BigEndian.Uint32(data[4:8])
-	//Determine the length of V. (确定V的长度)
-	tlvData.Value = make([]byte, tlvData.Length)
-
-	//Get V
-	binary.Read(bytes.NewBuffer(data[8:8+tlvData.Length]), binary.BigEndian, tlvData.Value)
+	//Get V, the frame is not reused so V shares its bytes instead of copying them
+	// (获取V，帧不会被复用，因此V直接共享其字节而不复制)
+	end := 8 + tlvData.Length
+	tlvData.Value = data[8:end:end]
 
 	//zlog.Ins().DebugF("TLV-DecodeData size:%d data:%+v\n", unsafe.Sizeof(data), tlvData)
-	return &tlvData
+	return tlvData
 }
 
 func (tlv *TLVDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
@@ -140,5 +145,5 @@ func (tlv *TLVDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
 
 	//6. Pass the decoded data to the next layer.
 	// (将解码后的数据进入下一层)
-	return chain.ProceedWithIMessage(iMessage, *tlvData)
+	return chain.ProceedWithIMessage(iMessage, tlvData)
 }
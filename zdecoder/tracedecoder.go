@@ -0,0 +1,86 @@
+// TraceTLVDecoder decodes the frames of zpack.DataPackTrace, a TLV with a 16 bytes trace ID
+// between the length field and the value.
+// (解码zpack.DataPackTrace的帧，即在长度字段和内容之间携带16字节链路追踪ID的TLV)
+//
+// +---------------+---------------+----------------+-----------+
+// |      Tag      |    Length     |    TraceID     |   Value   |
+// | uint32(4byte) | uint32(4byte) |    16 byte     |  n byte   |
+// +---------------+---------------+----------------+-----------+
+//
+//	lengthFieldOffset   = 4  (Length的字节位索引下标是4)
+//	lengthFieldLength   = 4  (Length是4个byte)
+//	lengthAdjustment    = 16 (Length只表示Value长度，Length与Value之间还有16字节的TraceID)
+//	initialBytesToStrip = 0  (返回完整的协议内容)
+
+package zdecoder
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zutils"
+)
+
+const TRACE_TLV_HEADER_SIZE = TLV_HEADER_SIZE + zutils.TraceIDLen
+
+type TraceTLVDecoder struct {
+	Tag     uint32 //T
+	Length  uint32 //L
+	TraceID string
+	Value   []byte //V
+}
+
+func NewTraceTLVDecoder() ziface.IDecoder {
+	return &TraceTLVDecoder{}
+}
+
+func (d *TraceTLVDecoder) GetLengthField() *ziface.LengthField {
+	return &ziface.LengthField{
+		MaxFrameLength:      math.MaxUint32 + TRACE_TLV_HEADER_SIZE,
+		LengthFieldOffset:   4,
+		LengthFieldLength:   4,
+		LengthAdjustment:    zutils.TraceIDLen,
+		InitialBytesToStrip: 0,
+	}
+}
+
+func (d *TraceTLVDecoder) decode(data []byte) *TraceTLVDecoder {
+	tlvData := TraceTLVDecoder{}
+	tlvData.Tag = binary.BigEndian.Uint32(data[0:4])
+	tlvData.Length = binary.BigEndian.Uint32(data[4:8])
+	if uint64(len(data)) < uint64(TRACE_TLV_HEADER_SIZE)+uint64(tlvData.Length) {
+		return nil
+	}
+	tlvData.TraceID = zutils.TraceIDFromBytes(data[8:TRACE_TLV_HEADER_SIZE])
+	tlvData.Value = data[TRACE_TLV_HEADER_SIZE : TRACE_TLV_HEADER_SIZE+tlvData.Length]
+	return &tlvData
+}
+
+func (d *TraceTLVDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	iMessage := chain.GetIMessage()
+	if iMessage == nil {
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	data := iMessage.GetData()
+	if len(data) < TRACE_TLV_HEADER_SIZE {
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	tlvData := d.decode(data)
+	if tlvData == nil {
+		return chain.ProceedWithIMessage(iMessage, nil)
+	}
+
+	// Set the decoded data back to the IMessage, the Zinx Router needs MsgID for addressing
+	// (将解码后的数据重新设置到IMessage中, Zinx的Router需要MsgID来寻址)
+	iMessage.SetMsgID(tlvData.Tag)
+	iMessage.SetData(tlvData.Value)
+	iMessage.SetDataLen(tlvData.Length)
+	if tm, ok := iMessage.(ziface.ITraceMessage); ok {
+		tm.SetTraceID(tlvData.TraceID)
+	}
+
+	return chain.ProceedWithIMessage(iMessage, *tlvData)
+}
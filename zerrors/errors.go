@@ -0,0 +1,96 @@
+// Package zerrors defines the failure modes of zinx, the send, decode and pack APIs return errors which callers tell
+// apart with errors.Is and errors.As instead of matching their text
+// (定义zinx的失败类型，发送、解码与封包接口返回的错误可以使用errors.Is与errors.As区分，而不需要匹配错误文本)
+package zerrors
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrConnClosed is returned when sending on a closed connection (在已关闭的连接上发送时返回)
+	ErrConnClosed = errors.New("zinx: connection closed")
+	// ErrSendQueueFull is returned when the send queue of a connection stays full (连接的发送队列持续已满时返回)
+	ErrSendQueueFull = errors.New("zinx: send queue full")
+	// ErrFrameTooLong is returned for a frame over the limit of the datapack or of the decoder
+	// (帧超过数据包或解码器的限制时返回)
+	ErrFrameTooLong = errors.New("zinx: frame too long")
+	// ErrDecode is returned for bytes which cannot be decoded (字节无法解码时返回)
+	ErrDecode = errors.New("zinx: decode error")
+	// ErrTimeout is returned when an operation gives up after waiting (操作等待超时放弃时返回)
+	ErrTimeout = errors.New("zinx: timeout")
+)
+
+// ConnError is a failure of the operation Op on the connection ConnID, Err tells which one
+// (连接ConnID上的操作Op失败，Err说明失败类型)
+type ConnError struct {
+	ConnID uint64
+	Op     string
+	Err    error
+}
+
+func (e *ConnError) Error() string {
+	return fmt.Sprintf("connID = %d %s: %v", e.ConnID, e.Op, e.Err)
+}
+
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+// FrameTooLongError is a frame of Length bytes over the limit Max, it is an ErrFrameTooLong
+// (长度为Length字节、超过限制Max的帧，属于ErrFrameTooLong)
+type FrameTooLongError struct {
+	Length uint64
+	Max    uint64
+}
+
+func (e *FrameTooLongError) Error() string {
+	return fmt.Sprintf("zinx: frame of %d bytes exceeds %d", e.Length, e.Max)
+}
+
+func (e *FrameTooLongError) Is(target error) bool {
+	return target == ErrFrameTooLong
+}
+
+// DecodeError is an ErrDecode caused by Err, its text is the one of Err (由Err导致的ErrDecode，其文本与Err相同)
+type DecodeError struct {
+	Err error
+}
+
+// NewDecodeError returns a DecodeError of text (返回文本为text的DecodeError)
+func NewDecodeError(text string) error {
+	return &DecodeError{Err: errors.New(text)}
+}
+
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+// SendQueueFullError is a send queue which stayed full for Wait, it is both an ErrSendQueueFull and an ErrTimeout
+// (发送队列在Wait时间内持续已满，既属于ErrSendQueueFull也属于ErrTimeout)
+type SendQueueFullError struct {
+	Wait time.Duration
+}
+
+func (e *SendQueueFullError) Error() string {
+	return fmt.Sprintf("zinx: send queue full for %v", e.Wait)
+}
+
+func (e *SendQueueFullError) Is(target error) bool {
+	return target == ErrSendQueueFull || target == ErrTimeout
+}
+
+// Timeout reports true like the timeouts of the net package (与net包的超时错误一样返回true)
+func (e *SendQueueFullError) Timeout() bool {
+	return true
+}
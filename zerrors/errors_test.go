@@ -0,0 +1,33 @@
+package zerrors
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	var err error = &ConnError{ConnID: 3, Op: "send msg", Err: ErrConnClosed}
+	assert.True(t, errors.Is(err, ErrConnClosed))
+	assert.Equal(t, "connID = 3 send msg: zinx: connection closed", err.Error())
+	var connErr *ConnError
+	assert.True(t, errors.As(err, &connErr))
+	assert.Equal(t, uint64(3), connErr.ConnID)
+
+	err = &ConnError{ConnID: 3, Op: "send buff msg", Err: &SendQueueFullError{Wait: 5 * time.Millisecond}}
+	assert.True(t, errors.Is(err, ErrSendQueueFull))
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.False(t, errors.Is(err, ErrConnClosed))
+
+	err = &FrameTooLongError{Length: 32, Max: 16}
+	assert.True(t, errors.Is(err, ErrFrameTooLong))
+	assert.False(t, errors.Is(err, ErrDecode))
+
+	cause := errors.New("head too short")
+	err = &DecodeError{Err: cause}
+	assert.True(t, errors.Is(err, ErrDecode))
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "head too short", err.Error())
+}
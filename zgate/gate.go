@@ -0,0 +1,147 @@
+package zgate
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+)
+
+// MetadataRemoteAddr is the metadata holding the remote address of the client (保存客户端远程地址的元数据)
+const MetadataRemoteAddr = "zinx.gate.remote_addr"
+
+var ErrNoBackend = errors.New("zgate: no backend connected")
+
+// route is the backend link a client is routed to (客户端被路由到的后端链路)
+type route struct {
+	link ziface.IConnection
+}
+
+// Gate forwards the messages the server has no router for to the backends, and routes their replies back
+// (将服务没有路由的消息转发给后端，并将后端的回复路由回客户端)
+type Gate struct {
+	server ziface.IServer
+	opts   *options
+
+	mu       sync.RWMutex
+	backends []ziface.IClient
+	routes   map[uint64]*route
+}
+
+// NewGate creates the Gate of s and sets it as the not found router of s, the messages with a router on s are
+// still handled by the gate itself, e.g. the login or the heartbeat
+// (创建s的网关并将其设置为s的未匹配路由，s上有路由的消息仍由网关自身处理，例如登录或心跳)
+func NewGate(s ziface.IServer, opts ...Option) *Gate {
+	g := &Gate{server: s, opts: newOptions(opts), routes: make(map[uint64]*route)}
+	znet.SetNotFoundHandler(s, g.forward)
+	return g
+}
+
+// AddBackend adds the client connected to a worker, it is called before the client starts
+// (添加连接到工作进程的客户端，在客户端启动之前调用)
+func (g *Gate) AddBackend(client ziface.IClient) {
+	client.AddRouter(g.opts.msgID, &backendRouter{gate: g})
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.backends = append(g.backends, client)
+}
+
+// forward sends the request to the worker of its client (将请求发送给其客户端所在的工作进程)
+func (g *Gate) forward(request ziface.IRequest) {
+	conn := request.GetConnection()
+	link, err := g.link(conn)
+	if err != nil {
+		zlog.Ins().ErrorF("zgate: forward msgID = %d of connID = %d err: %v", request.GetMsgID(), conn.GetConnID(), err)
+		conn.Stop()
+		return
+	}
+	if err := link.SendMsg(g.opts.msgID, encodeEnvelope(kindForward, conn.GetConnID(), request.GetMsgID(), request.GetData())); err != nil {
+		zlog.Ins().ErrorF("zgate: forward msgID = %d of connID = %d err: %v", request.GetMsgID(), conn.GetConnID(), err)
+	}
+}
+
+// link returns the backend link of conn, routing it on its first message. A client whose backend link was lost
+// is closed, its session on the worker is gone
+// (返回conn的后端链路，在第一条消息时为其选择路由。后端链路断开的客户端会被关闭，因为其在工作进程上的会话已不存在)
+func (g *Gate) link(conn ziface.IConnection) (ziface.IConnection, error) {
+	connID := conn.GetConnID()
+	g.mu.RLock()
+	r, ok := g.routes[connID]
+	g.mu.RUnlock()
+	if ok {
+		if !r.link.IsAlive() {
+			return nil, ErrNoBackend
+		}
+		return r.link, nil
+	}
+
+	g.mu.Lock()
+	var links []ziface.IConnection
+	for _, backend := range g.backends {
+		if link := backend.Conn(); link != nil && link.IsAlive() {
+			links = append(links, link)
+		}
+	}
+	if len(links) == 0 {
+		g.mu.Unlock()
+		return nil, ErrNoBackend
+	}
+	r = &route{link: links[g.opts.balance(conn, len(links))]}
+	g.routes[connID] = r
+	g.mu.Unlock()
+
+	metadata := map[string]string{MetadataRemoteAddr: conn.RemoteAddrString()}
+	if g.opts.metadata != nil {
+		for key, value := range g.opts.metadata(conn) {
+			metadata[key] = value
+		}
+	}
+	if err := r.link.SendMsg(g.opts.msgID, encodeEnvelope(kindOpen, connID, 0, encodeMetadata(metadata))); err != nil {
+		return nil, err
+	}
+	conn.AddCloseCallback(g, nil, func() {
+		g.closed(connID)
+	})
+	return r.link, nil
+}
+
+// closed tells the worker of a closed client, it is the close callback so it must not touch the callbacks
+// (通知工作进程客户端已关闭，它是关闭回调，因此不能操作回调列表)
+func (g *Gate) closed(connID uint64) {
+	g.mu.Lock()
+	r, ok := g.routes[connID]
+	delete(g.routes, connID)
+	g.mu.Unlock()
+	if ok && r.link.IsAlive() {
+		_ = r.link.SendMsg(g.opts.msgID, encodeEnvelope(kindClose, connID, 0, nil))
+	}
+}
+
+// backendRouter receives the envelopes of a worker (接收工作进程的信封)
+type backendRouter struct {
+	znet.BaseRouter
+	gate *Gate
+}
+
+func (r *backendRouter) Handle(request ziface.IRequest) {
+	env, err := decodeEnvelope(request.GetData())
+	if err != nil {
+		zlog.Ins().ErrorF("zgate: decode envelope err: %v", err)
+		return
+	}
+	conn, err := r.gate.server.GetConnMgr().Get(env.connID)
+	if err != nil {
+		zlog.Ins().DebugF("zgate: connID = %d is gone: %v", env.connID, err)
+		return
+	}
+	switch env.kind {
+	case kindReply:
+		if err := conn.SendMsg(env.msgID, env.data); err != nil {
+			zlog.Ins().DebugF("zgate: reply msgID = %d to connID = %d err: %v", env.msgID, env.connID, err)
+		}
+	case kindClose:
+		conn.Stop()
+	}
+}
@@ -0,0 +1,278 @@
+package zgate
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/ztimer"
+	"github.com/gorilla/websocket"
+)
+
+var ErrRawSend = errors.New("zgate: raw data can't be sent to a client behind a gate")
+
+var (
+	timersOnce sync.Once
+	timers     *ztimer.TimerScheduler
+)
+
+func timerScheduler() *ztimer.TimerScheduler {
+	timersOnce.Do(func() {
+		timers = ztimer.NewAutoExecTimerScheduler()
+	})
+	return timers
+}
+
+// gateAddr is the remote address of a client as reported by its gate (网关报告的客户端远程地址)
+type gateAddr string
+
+func (a gateAddr) Network() string { return "gate" }
+
+func (a gateAddr) String() string { return string(a) }
+
+type closeCallback struct {
+	handler, key interface{}
+	f            func()
+}
+
+// gateConn is a client behind a gate as seen by the worker (工作进程所见的网关后的客户端)
+type gateConn struct {
+	worker *Worker
+	link   ziface.IConnection
+	connID uint64
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	closed     bool
+	properties map[string]interface{}
+	callbacks  []closeCallback
+	tenant     string
+
+	bytesIn, bytesOut, msgsIn, msgsOut uint64
+}
+
+func newGateConn(w *Worker, link ziface.IConnection, connID uint64, metadata map[string]string) *gateConn {
+	c := &gateConn{worker: w, link: link, connID: connID, properties: make(map[string]interface{})}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	for key, value := range metadata {
+		c.properties[key] = value
+	}
+	return c
+}
+
+func (c *gateConn) received(n int) {
+	atomic.AddUint64(&c.bytesIn, uint64(n))
+	atomic.AddUint64(&c.msgsIn, 1)
+}
+
+// close closes the session, the gate is told to close the client when notify is set
+// (关闭会话，notify为true时通知网关关闭客户端)
+func (c *gateConn) close(notify bool) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	callbacks := c.callbacks
+	c.callbacks = nil
+	c.mu.Unlock()
+
+	c.cancel()
+	c.worker.remove(c)
+	if notify && c.link.IsAlive() {
+		_ = c.link.SendMsg(c.worker.opts.msgID, encodeEnvelope(kindClose, c.connID, 0, nil))
+	}
+	if onStop := c.worker.server.GetOnConnStop(); onStop != nil {
+		onStop(c)
+	}
+	for _, cb := range callbacks {
+		cb.f()
+	}
+}
+
+func (c *gateConn) Start() {}
+
+// Stop closes the client on the gate (在网关上关闭客户端)
+func (c *gateConn) Stop() {
+	c.close(true)
+}
+
+func (c *gateConn) Context() context.Context { return c.ctx }
+
+func (c *gateConn) GetName() string { return "gate-" + strconv.FormatUint(c.connID, 10) }
+
+func (c *gateConn) GetConnection() net.Conn { return nil }
+
+func (c *gateConn) GetWsConn() *websocket.Conn { return nil }
+
+func (c *gateConn) GetTCPConnection() net.Conn { return nil }
+
+func (c *gateConn) GetConnID() uint64 { return c.connID }
+
+func (c *gateConn) GetConnIdStr() string { return strconv.FormatUint(c.connID, 10) }
+
+func (c *gateConn) GetMsgHandler() ziface.IMsgHandle { return c.worker.server.GetMsgHandler() }
+
+// GetWorkerID spreads the clients over the workers by ConnID, keeping the messages of a client in order
+// (按ConnID将客户端分配到各worker，同一客户端的消息保持有序)
+func (c *gateConn) GetWorkerID() uint32 {
+	if size := c.GetMsgHandler().GetWorkerPoolSize(); size > 0 {
+		return uint32(c.connID % uint64(size))
+	}
+	return 0
+}
+
+func (c *gateConn) RemoteAddr() net.Addr { return gateAddr(c.RemoteAddrString()) }
+
+func (c *gateConn) LocalAddr() net.Addr { return c.link.LocalAddr() }
+
+func (c *gateConn) LocalAddrString() string { return c.link.LocalAddrString() }
+
+func (c *gateConn) RemoteAddrString() string {
+	addr, _ := c.GetProperty(MetadataRemoteAddr)
+	s, _ := addr.(string)
+	return s
+}
+
+func (c *gateConn) Send(data []byte) error { return ErrRawSend }
+
+func (c *gateConn) SendToQueue(data []byte) error { return ErrRawSend }
+
+func (c *gateConn) SendMsg(msgID uint32, data []byte) error {
+	if !c.IsAlive() {
+		return &zerrors.ConnError{ConnID: c.connID, Op: "send msg", Err: zerrors.ErrConnClosed}
+	}
+	atomic.AddUint64(&c.bytesOut, uint64(len(data)))
+	atomic.AddUint64(&c.msgsOut, 1)
+	return c.link.SendMsg(c.worker.opts.msgID, encodeEnvelope(kindReply, c.connID, msgID, data))
+}
+
+func (c *gateConn) SendBuffMsg(msgID uint32, data []byte) error {
+	if !c.IsAlive() {
+		return &zerrors.ConnError{ConnID: c.connID, Op: "send buff msg", Err: zerrors.ErrConnClosed}
+	}
+	atomic.AddUint64(&c.bytesOut, uint64(len(data)))
+	atomic.AddUint64(&c.msgsOut, 1)
+	return c.link.SendBuffMsg(c.worker.opts.msgID, encodeEnvelope(kindReply, c.connID, msgID, data))
+}
+
+func (c *gateConn) SendMessage(msg ziface.IMessage) error {
+	return c.SendMsg(msg.GetMsgID(), msg.GetData())
+}
+
+func (c *gateConn) SendMsgAfter(delay time.Duration, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	return c.sendMsgAfter(delay, msgID, data), nil
+}
+
+func (c *gateConn) SendMsgAt(at time.Time, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	return c.sendMsgAfter(time.Until(at), msgID, data), nil
+}
+
+func (c *gateConn) sendMsgAfter(delay time.Duration, msgID uint32, data []byte) *ztimer.TimerHandle {
+	return timerScheduler().AfterFuncContext(c.ctx, delay, func() {
+		if err := c.SendMsg(msgID, data); err != nil {
+			zlog.Ins().DebugF("zgate: scheduled send msgID = %d err: %v", msgID, err)
+		}
+	})
+}
+
+func (c *gateConn) AfterFunc(d time.Duration, f func()) ziface.IConnTimer {
+	return timerScheduler().AfterFuncContext(c.ctx, d, f)
+}
+
+func (c *gateConn) Ticker(d time.Duration, f func()) ziface.IConnTimer {
+	return timerScheduler().TickFuncContext(c.ctx, d, f)
+}
+
+func (c *gateConn) SetProperty(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.properties[key] = value
+}
+
+func (c *gateConn) GetProperty(key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if value, ok := c.properties[key]; ok {
+		return value, nil
+	}
+	return nil, errors.New("no property found")
+}
+
+func (c *gateConn) RemoveProperty(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.properties, key)
+}
+
+func (c *gateConn) GetProperties() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	properties := make(map[string]interface{}, len(c.properties))
+	for key, value := range c.properties {
+		properties[key] = value
+	}
+	return properties
+}
+
+func (c *gateConn) IsAlive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.closed && c.link.IsAlive()
+}
+
+func (c *gateConn) SetTenant(tenant string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tenant = tenant
+}
+
+func (c *gateConn) GetTenant() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tenant
+}
+
+func (c *gateConn) GetTraffic() ziface.TrafficStats {
+	return ziface.TrafficStats{
+		BytesIn:  atomic.LoadUint64(&c.bytesIn),
+		BytesOut: atomic.LoadUint64(&c.bytesOut),
+		MsgsIn:   atomic.LoadUint64(&c.msgsIn),
+		MsgsOut:  atomic.LoadUint64(&c.msgsOut),
+	}
+}
+
+// SetHeartBeat does nothing, the gate checks the heartbeats of its clients (不做任何事，由网关检测其客户端的心跳)
+func (c *gateConn) SetHeartBeat(checker ziface.IHeartbeatChecker) {}
+
+func (c *gateConn) AddCloseCallback(handler, key interface{}, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed && f != nil {
+		c.callbacks = append(c.callbacks, closeCallback{handler: handler, key: key, f: f})
+	}
+}
+
+func (c *gateConn) RemoveCloseCallback(handler, key interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, cb := range c.callbacks {
+		if cb.handler == handler && cb.key == key {
+			c.callbacks = append(c.callbacks[:i:i], c.callbacks[i+1:]...)
+			return
+		}
+	}
+}
+
+// InvokeCloseCallbacks does nothing, the callbacks are invoked when the session closes
+// (不做任何事，回调在会话关闭时调用)
+func (c *gateConn) InvokeCloseCallbacks() {}
@@ -0,0 +1,210 @@
+package zgate
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type sentMsg struct {
+	msgID uint32
+	data  string
+}
+
+// testConn is a client of the gate, or an end of a link when handle is set
+// (网关的客户端，设置handle时为链路的一端)
+type testConn struct {
+	ziface.IConnection
+	id     uint64
+	handle func(request ziface.IRequest)
+	peer   *testConn
+
+	mu        sync.Mutex
+	sent      []sentMsg
+	stopped   bool
+	callbacks []func()
+}
+
+func (c *testConn) GetConnID() uint64 { return c.id }
+
+func (c *testConn) RemoteAddrString() string { return "1.2.3.4:5678" }
+
+func (c *testConn) IsAlive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.stopped
+}
+
+func (c *testConn) SendMsg(msgID uint32, data []byte) error {
+	if c.handle != nil {
+		c.handle(znet.NewRequest(c.peer, zpack.NewMsgPackage(msgID, data)))
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, sentMsg{msgID: msgID, data: string(data)})
+	return nil
+}
+
+func (c *testConn) SendBuffMsg(msgID uint32, data []byte) error {
+	return c.SendMsg(msgID, data)
+}
+
+func (c *testConn) AddCloseCallback(handler, key interface{}, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callbacks = append(c.callbacks, f)
+}
+
+func (c *testConn) Stop() {
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.stopped = true
+	callbacks := c.callbacks
+	c.mu.Unlock()
+	for _, f := range callbacks {
+		f()
+	}
+}
+
+type testConnMgr struct {
+	ziface.IConnManager
+	conns map[uint64]ziface.IConnection
+}
+
+func (m *testConnMgr) Get(connID uint64) (ziface.IConnection, error) {
+	if conn, ok := m.conns[connID]; ok {
+		return conn, nil
+	}
+	return nil, errors.New("connection not found")
+}
+
+// testMsgHandle runs the requests at once (立即处理请求)
+type testMsgHandle struct {
+	ziface.IMsgHandle
+	handlers map[uint32]ziface.RouterHandler
+}
+
+func (h *testMsgHandle) GetWorkerPoolSize() uint32 { return 4 }
+
+func (h *testMsgHandle) SendMsgToTaskQueue(request ziface.IRequest) {
+	h.handlers[request.GetMsgID()](request)
+}
+
+type testServer struct {
+	ziface.IServer
+	connMgr  *testConnMgr
+	handle   *testMsgHandle
+	notFound ziface.IRouter
+	started  []ziface.IConnection
+	stopped  []ziface.IConnection
+}
+
+func newTestServer() *testServer {
+	return &testServer{
+		connMgr: &testConnMgr{conns: make(map[uint64]ziface.IConnection)},
+		handle:  &testMsgHandle{handlers: make(map[uint32]ziface.RouterHandler)},
+	}
+}
+
+func (s *testServer) AddRouter(msgID uint32, router ziface.IRouter) {
+	s.handle.handlers[msgID] = router.Handle
+}
+
+func (s *testServer) SetNotFoundRouter(router ziface.IRouter) { s.notFound = router }
+
+func (s *testServer) GetConnMgr() ziface.IConnManager { return s.connMgr }
+
+func (s *testServer) GetMsgHandler() ziface.IMsgHandle { return s.handle }
+
+func (s *testServer) GetOnConnStart() func(ziface.IConnection) {
+	return func(conn ziface.IConnection) { s.started = append(s.started, conn) }
+}
+
+func (s *testServer) GetOnConnStop() func(ziface.IConnection) {
+	return func(conn ziface.IConnection) { s.stopped = append(s.stopped, conn) }
+}
+
+type testClient struct {
+	ziface.IClient
+	link   *testConn
+	router ziface.IRouter
+}
+
+func (c *testClient) AddRouter(msgID uint32, router ziface.IRouter) { c.router = router }
+
+func (c *testClient) Conn() ziface.IConnection { return c.link }
+
+func TestGate(t *testing.T) {
+	gateServer, workerServer := newTestServer(), newTestServer()
+	gate := NewGate(gateServer, WithMetadata(func(conn ziface.IConnection) map[string]string {
+		return map[string]string{"user": "alice"}
+	}))
+	worker := NewWorker(workerServer)
+
+	// The link between the gate and the worker (网关与工作进程之间的链路)
+	gateSide, workerSide := &testConn{id: 1}, &testConn{id: 1}
+	backend := &testClient{link: gateSide}
+	gate.AddBackend(backend)
+	gateSide.peer, gateSide.handle = workerSide, workerServer.handle.handlers[DefaultMsgID]
+	workerSide.peer, workerSide.handle = gateSide, backend.router.Handle
+
+	// The worker echoes with the user from the metadata (工作进程回显元数据中的用户)
+	workerServer.handle.handlers[100] = func(request ziface.IRequest) {
+		conn := request.GetConnection()
+		user, _ := conn.GetProperty("user")
+		_ = conn.SendMsg(101, []byte(user.(string)+":"+string(request.GetData())+"@"+conn.RemoteAddrString()))
+	}
+	workerServer.handle.handlers[200] = func(request ziface.IRequest) {
+		request.GetConnection().Stop()
+	}
+
+	client := &testConn{id: 42}
+	gateServer.connMgr.conns[42] = client
+	gateServer.notFound.Handle(znet.NewRequest(client, zpack.NewMsgPackage(100, []byte("hi"))))
+	assert.Equal(t, []sentMsg{{msgID: 101, data: "alice:hi@1.2.3.4:5678"}}, client.sent)
+	assert.Len(t, workerServer.started, 1)
+	assert.Equal(t, uint64(42), workerServer.started[0].GetConnID())
+	assert.Equal(t, 1, worker.Len())
+
+	// The worker closes the client on the gate (工作进程在网关上关闭客户端)
+	gateServer.notFound.Handle(znet.NewRequest(client, zpack.NewMsgPackage(200, nil)))
+	assert.True(t, client.stopped)
+	assert.Len(t, workerServer.stopped, 1)
+	assert.Equal(t, 0, worker.Len())
+
+	// A client closing on the gate closes its session on the worker (客户端在网关上关闭会关闭其在工作进程上的会话)
+	other := &testConn{id: 43}
+	gateServer.connMgr.conns[43] = other
+	gateServer.notFound.Handle(znet.NewRequest(other, zpack.NewMsgPackage(100, []byte("hey"))))
+	assert.Equal(t, 1, worker.Len())
+	other.Stop()
+	assert.Equal(t, 0, worker.Len())
+	assert.Len(t, workerServer.stopped, 2)
+
+	// Without a connected backend the client is closed (没有已连接的后端时关闭客户端)
+	gateSide.Stop()
+	late := &testConn{id: 44}
+	gateServer.notFound.Handle(znet.NewRequest(late, zpack.NewMsgPackage(100, nil)))
+	assert.True(t, late.stopped)
+}
+
+func TestMetadata(t *testing.T) {
+	metadata := map[string]string{"a": "1", "user": "", "": "x"}
+	decoded, err := decodeMetadata(encodeMetadata(metadata))
+	assert.Nil(t, err)
+	assert.Equal(t, metadata, decoded)
+
+	_, err = decodeMetadata([]byte{1, 0, 'a'})
+	assert.NotNil(t, err)
+	_, err = decodeEnvelope([]byte{kindOpen})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,57 @@
+package zgate
+
+import "github.com/aceld/zinx/ziface"
+
+// MetadataFunc returns the metadata of a client sent to its worker, they are the properties of the connection
+// on the worker (返回发送给工作进程的客户端元数据，它们是工作进程上该连接的属性)
+type MetadataFunc func(conn ziface.IConnection) map[string]string
+
+// BalanceFunc picks the backend of a client among n connected backends, the client then stays on it
+// (在n个已连接的后端中为客户端选择一个，之后客户端固定在该后端上)
+type BalanceFunc func(conn ziface.IConnection, n int) int
+
+type options struct {
+	msgID    uint32
+	metadata MetadataFunc
+	balance  BalanceFunc
+}
+
+// Option configures a Gate or a Worker (Gate或Worker的配置项)
+type Option func(o *options)
+
+// WithMsgID sets the MsgID carrying the envelopes, DefaultMsgID by default, the gates and the workers use the
+// same (设置承载信封的MsgID，默认DefaultMsgID，网关与工作进程需使用相同的值)
+func WithMsgID(msgID uint32) Option {
+	return func(o *options) {
+		o.msgID = msgID
+	}
+}
+
+// WithMetadata adds metadata of the clients to the remote address sent by default, e.g. the tenant or the
+// user authenticated by the gate (在默认发送的远程地址之外添加客户端元数据，例如租户或网关认证的用户)
+func WithMetadata(metadata MetadataFunc) Option {
+	return func(o *options) {
+		o.metadata = metadata
+	}
+}
+
+// WithBalance sets how the clients are spread over the backends, by ConnID by default
+// (设置客户端在后端之间的分配方式，默认按ConnID分配)
+func WithBalance(balance BalanceFunc) Option {
+	return func(o *options) {
+		o.balance = balance
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		msgID: DefaultMsgID,
+		balance: func(conn ziface.IConnection, n int) int {
+			return int(conn.GetConnID() % uint64(n))
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,112 @@
+// Package zgate splits a deployment into gates terminating the client connections and workers running the
+// business handlers: a gate forwards the messages it has no router for to a worker, wrapped in an envelope
+// carrying the ConnID of the client, and routes the replies of the worker back to that client.
+//
+// On the gate (网关):
+//
+//	g := zgate.NewGate(s)
+//	g.AddBackend(znet.NewClient("10.0.0.2", 9000))
+//
+// On the worker (工作进程):
+//
+//	zgate.NewWorker(s)
+//	s.AddRouter(100, &LoginRouter{}) // request.GetConnection() is the client behind the gate (request.GetConnection()即网关后的客户端)
+//
+// (zgate将部署拆分为终结客户端连接的网关和运行业务处理函数的工作进程：网关将自身没有路由的消息封装在携带客户端ConnID的信封中
+// 转发给工作进程，并将工作进程的回复路由回对应的客户端)
+package zgate
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// DefaultMsgID is the zinx MsgID carrying the envelopes between the gates and the workers
+// (网关与工作进程之间承载信封的zinx MsgID)
+const DefaultMsgID uint32 = 0xFFFF0002
+
+// The kinds of envelopes (信封的类型)
+const (
+	kindOpen    byte = iota + 1 // A client is routed to the worker, with its metadata (客户端被路由到工作进程，携带其元数据)
+	kindForward                 // A message of the client (客户端的消息)
+	kindReply                   // A message to the client (发给客户端的消息)
+	kindClose                   // The client or its session is closed (客户端或其会话已关闭)
+)
+
+// headerLen is kind(1 byte) + connID(8 bytes) + msgID(4 bytes) (信封头长度)
+const headerLen = 13
+
+var errShortEnvelope = errors.New("zgate: envelope too short")
+
+type envelope struct {
+	kind   byte
+	connID uint64
+	msgID  uint32
+	// data is the payload of forward and reply, the encoded metadata of open
+	// (forward和reply的消息体，open的元数据编码)
+	data []byte
+}
+
+func encodeEnvelope(kind byte, connID uint64, msgID uint32, data []byte) []byte {
+	frame := make([]byte, headerLen+len(data))
+	frame[0] = kind
+	binary.LittleEndian.PutUint64(frame[1:], connID)
+	binary.LittleEndian.PutUint32(frame[9:], msgID)
+	copy(frame[headerLen:], data)
+	return frame
+}
+
+func decodeEnvelope(frame []byte) (envelope, error) {
+	if len(frame) < headerLen {
+		return envelope{}, errShortEnvelope
+	}
+	return envelope{
+		kind:   frame[0],
+		connID: binary.LittleEndian.Uint64(frame[1:]),
+		msgID:  binary.LittleEndian.Uint32(frame[9:]),
+		data:   frame[headerLen:],
+	}, nil
+}
+
+// encodeMetadata encodes the pairs as len(key)(2 bytes) + key + len(value)(2 bytes) + value, sorted by key
+// (按key排序，将每对编码为key长度(2字节) + key + value长度(2字节) + value)
+func encodeMetadata(metadata map[string]string) []byte {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var data []byte
+	for _, key := range keys {
+		for _, s := range []string{key, metadata[key]} {
+			data = append(data, byte(len(s)), byte(len(s)>>8))
+			data = append(data, s...)
+		}
+	}
+	return data
+}
+
+func decodeMetadata(data []byte) (map[string]string, error) {
+	metadata := make(map[string]string)
+	var pair [2]string
+	i := 0
+	for ; len(data) > 0; i++ {
+		if len(data) < 2 {
+			return nil, errShortEnvelope
+		}
+		n := int(binary.LittleEndian.Uint16(data))
+		if len(data) < 2+n {
+			return nil, errShortEnvelope
+		}
+		pair[i%2] = string(data[2 : 2+n])
+		data = data[2+n:]
+		if i%2 == 1 {
+			metadata[pair[0]] = pair[1]
+		}
+	}
+	if i%2 == 1 {
+		return nil, errShortEnvelope
+	}
+	return metadata, nil
+}
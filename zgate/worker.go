@@ -0,0 +1,128 @@
+package zgate
+
+import (
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+)
+
+// Worker runs the messages forwarded by the gates through the routers of its server, as if the clients were
+// connected to it: request.GetConnection() is the client behind the gate, replying to it goes back through
+// the gate, and the OnConnStart and OnConnStop hooks of the server are called for the clients
+// (通过其服务的路由处理网关转发的消息，如同客户端直接连接到它：request.GetConnection()即网关后的客户端，向其回复会经网关返回，
+// 服务的OnConnStart和OnConnStop钩子也会为这些客户端调用)
+type Worker struct {
+	server ziface.IServer
+	opts   *options
+
+	mu    sync.Mutex
+	conns map[ziface.IConnection]map[uint64]*gateConn // The clients of each gate link (每条网关链路上的客户端)
+}
+
+// NewWorker creates the Worker of s and registers its envelope router on s (创建s的Worker并在s上注册信封路由)
+func NewWorker(s ziface.IServer, opts ...Option) *Worker {
+	w := &Worker{server: s, opts: newOptions(opts), conns: make(map[ziface.IConnection]map[uint64]*gateConn)}
+	znet.AddRouterHandler(s, w.opts.msgID, w.handle)
+	return w
+}
+
+// Len returns the number of clients served through the gates (返回通过网关服务的客户端数量)
+func (w *Worker) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := 0
+	for _, conns := range w.conns {
+		n += len(conns)
+	}
+	return n
+}
+
+func (w *Worker) handle(request ziface.IRequest) {
+	link := request.GetConnection()
+	env, err := decodeEnvelope(request.GetData())
+	if err != nil {
+		zlog.Ins().ErrorF("zgate: decode envelope err: %v", err)
+		return
+	}
+
+	switch env.kind {
+	case kindOpen:
+		metadata, err := decodeMetadata(env.data)
+		if err != nil {
+			zlog.Ins().ErrorF("zgate: decode metadata of connID = %d err: %v", env.connID, err)
+			return
+		}
+		w.open(link, env.connID, metadata)
+	case kindForward:
+		conn := w.get(link, env.connID)
+		if conn == nil {
+			// Opened on a previous link of the gate (在网关之前的链路上打开)
+			conn = w.open(link, env.connID, nil)
+		}
+		conn.received(len(env.data))
+		// The data is copied, the request of the link is recycled once the handler returns
+		// (复制数据，链路的请求在处理函数返回后会被回收)
+		msg := zpack.NewMsgPackage(env.msgID, append([]byte(nil), env.data...))
+		w.server.GetMsgHandler().SendMsgToTaskQueue(znet.NewRequest(conn, msg))
+	case kindClose:
+		if conn := w.get(link, env.connID); conn != nil {
+			conn.close(false)
+		}
+	}
+}
+
+func (w *Worker) get(link ziface.IConnection, connID uint64) *gateConn {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conns[link][connID]
+}
+
+func (w *Worker) open(link ziface.IConnection, connID uint64, metadata map[string]string) *gateConn {
+	conn := newGateConn(w, link, connID, metadata)
+	w.mu.Lock()
+	conns, ok := w.conns[link]
+	if !ok {
+		conns = make(map[uint64]*gateConn)
+		w.conns[link] = conns
+	}
+	prev := conns[connID]
+	conns[connID] = conn
+	w.mu.Unlock()
+
+	if prev != nil {
+		prev.close(false)
+	}
+	if !ok {
+		link.AddCloseCallback(w, nil, func() {
+			w.linkClosed(link)
+		})
+	}
+	if onStart := w.server.GetOnConnStart(); onStart != nil {
+		onStart(conn)
+	}
+	return conn
+}
+
+// remove forgets conn unless it was replaced (移除conn，除非它已被替换)
+func (w *Worker) remove(conn *gateConn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if conns := w.conns[conn.link]; conns[conn.connID] == conn {
+		delete(conns, conn.connID)
+	}
+}
+
+// linkClosed closes the clients of a lost gate link, it is the close callback so it must not touch the callbacks
+// (关闭断开的网关链路上的客户端，它是关闭回调，因此不能操作回调列表)
+func (w *Worker) linkClosed(link ziface.IConnection) {
+	w.mu.Lock()
+	conns := w.conns[link]
+	delete(w.conns, link)
+	w.mu.Unlock()
+	for _, conn := range conns {
+		conn.close(false)
+	}
+}
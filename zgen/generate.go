@@ -0,0 +1,156 @@
+package zgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+// initialisms are the parts of the field names written in capitals in Go (字段名中在Go里全大写书写的部分)
+var initialisms = map[string]bool{"id": true, "ip": true, "url": true, "uid": true, "uuid": true, "json": true, "http": true}
+
+// goName returns the exported Go name of a field, user_id becomes UserID (返回字段导出的Go名称，user_id变为UserID)
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		switch {
+		case part == "":
+		case initialisms[strings.ToLower(part)]:
+			b.WriteString(strings.ToUpper(part))
+		default:
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+var funcs = template.FuncMap{
+	"goName": goName,
+	"quote":  func(s string) string { return fmt.Sprintf("%q", s) },
+}
+
+var fileTemplate = template.Must(template.New("file").Funcs(funcs).Parse(`// Code generated by zinx-gen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/aceld/zinx/zcodec"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+{{- range .Imports}}
+	{{.Alias}} {{quote .Path}}
+{{- end}}
+)
+
+// The MsgIDs of the protocol (协议的MsgID)
+const (
+{{- range .Messages}}
+	MsgID{{.Name}} uint32 = {{.MsgID}}
+{{- end}}
+)
+
+// Codec encodes the payloads of the protocol (协议消息体的编解码器)
+var Codec = zcodec.{{if eq .Codec "proto"}}Proto{{else}}JSON{{end}}
+{{range .Messages}}
+// {{.Name}} is the payload of MsgID{{.Name}}
+{{- if .Type}}
+type {{.Name}} = {{.Type}}
+{{- else}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{goName .Name}} {{.Type}} ` + "`json:\"{{.Name}}\"`" + `
+{{- end}}
+}
+{{- end}}
+{{end}}
+// Handler handles the routed messages on the server (在服务端处理被路由的消息)
+type Handler interface {
+{{- range .Routes}}
+{{- if .Reply}}
+	// {{.Request}} handles MsgID{{.Request}}, the returned {{.Reply}} is replied as MsgID{{.Reply}}
+	{{.Request}}(ctx context.Context, conn ziface.IConnection, req *{{.Request}}) (*{{.Reply}}, error)
+{{- else}}
+	// {{.Request}} handles MsgID{{.Request}}
+	{{.Request}}(ctx context.Context, conn ziface.IConnection, req *{{.Request}}) error
+{{- end}}
+{{- end}}
+}
+
+// RegisterHandler registers the routers of h on s (在s上注册h的路由)
+func RegisterHandler(s ziface.IServer, h Handler) {
+{{- range .Routes}}
+{{- if .Reply}}
+	znet.Handle(s, MsgID{{.Request}}, h.{{.Request}}, znet.WithCodec(Codec), znet.WithReplyMsgID(MsgID{{.Reply}}))
+{{- else}}
+	znet.Handle(s, MsgID{{.Request}}, func(ctx context.Context, conn ziface.IConnection, req *{{.Request}}) (struct{}, error) {
+		return struct{}{}, h.{{.Request}}(ctx, conn, req)
+	}, znet.WithCodec(Codec), znet.WithNoReply())
+{{- end}}
+{{- end}}
+}
+
+// ClientHandler handles the messages sent by the server, the replies and the pushes, on the client
+// (在客户端处理服务发送的消息，包括回复与推送)
+type ClientHandler interface {
+{{- range .ClientMessages}}
+	// {{.Name}} handles MsgID{{.Name}}
+	{{.Name}}(ctx context.Context, conn ziface.IConnection, msg *{{.Name}}) error
+{{- end}}
+}
+
+// RegisterClientHandler registers the routers of h on c (在c上注册h的路由)
+func RegisterClientHandler(c ziface.IClient, h ClientHandler) {
+{{- range .ClientMessages}}
+	c.AddRouter(MsgID{{.Name}}, znet.TypedRouter(func(ctx context.Context, conn ziface.IConnection, msg *{{.Name}}) (struct{}, error) {
+		return struct{}{}, h.{{.Name}}(ctx, conn, msg)
+	}, znet.WithCodec(Codec), znet.WithNoReply()))
+{{- end}}
+}
+{{range .Messages}}
+// Send{{.Name}} sends msg as MsgID{{.Name}} on conn (在conn上以MsgID{{.Name}}发送msg)
+func Send{{.Name}}(conn ziface.IConnection, msg *{{.Name}}) error {
+	return send(conn, MsgID{{.Name}}, msg)
+}
+{{end}}
+func send(conn ziface.IConnection, msgID uint32, msg interface{}) error {
+	data, err := Codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.SendMsg(msgID, data)
+}
+`))
+
+// Generate returns the Go source of f, source is the name of the definition file written in the header
+// (返回f的Go源码，source为写入文件头的定义文件名)
+func Generate(f *File, source string) ([]byte, error) {
+	data := struct {
+		*File
+		Source         string
+		ClientMessages []*Message
+	}{File: f, Source: source}
+	// The client handles what the server does not (客户端处理服务端不处理的消息)
+	routed := make(map[string]bool)
+	for _, r := range f.Routes {
+		routed[r.Request] = true
+	}
+	for _, m := range f.Messages {
+		if !routed[m.Name] {
+			data.ClientMessages = append(data.ClientMessages, m)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("zgen: invalid generated code, check the field types: %w", err)
+	}
+	return src, nil
+}
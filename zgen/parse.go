@@ -0,0 +1,240 @@
+// Package zgen generates the protocol scaffolding of a zinx service from a message definition file: the MsgID
+// constants, the payload types, a typed handler interface with its router registration, and the client call
+// wrappers, so that the server and the client teams share one definition instead of copies
+// (根据消息定义文件生成zinx服务的协议脚手架：MsgID常量、消息体类型、类型化处理接口及其路由注册、客户端调用封装，
+// 使服务端与客户端团队共用同一份定义而不是各自复制)
+//
+// The definition file is line based, # and // start comments:
+//
+//	package game
+//	codec json
+//
+//	message Login = 1 {
+//		token string
+//		device string
+//	}
+//	message LoginReply = 2 {
+//		user_id uint64
+//		roles []string
+//	}
+//	message Move = 3 {
+//		x float32
+//		y float32
+//	}
+//
+//	route Login -> LoginReply
+//	route Move
+//
+// With "codec proto" the messages are aliases of the types generated by protoc:
+//
+//	import pb "example.com/game/pb"
+//	message Login = 1 pb.LoginReq
+package zgen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Codec names (编解码器名称)
+const (
+	CodecJSON  = "json"
+	CodecProto = "proto"
+)
+
+// Field is a field of a message defined in the file (文件中定义的消息的字段)
+type Field struct {
+	// Name is the name in the file, the key of the JSON encoding (文件中的名称，即JSON编码的键)
+	Name string
+	// Type is the Go type (Go类型)
+	Type string
+}
+
+// Message is a message of the protocol (协议中的一种消息)
+type Message struct {
+	Name  string
+	MsgID uint32
+	// Type is the Go type of an imported payload, e.g. pb.LoginReq, empty for the messages defined by their fields
+	// (导入的消息体的Go类型，例如pb.LoginReq，通过字段定义的消息为空)
+	Type   string
+	Fields []Field
+}
+
+// Route is a message handled by the server, with the message it replies if any (由服务处理的消息，以及其回复的消息，如有)
+type Route struct {
+	Request string
+	Reply   string
+}
+
+// Import is a package imported by the generated file (生成文件导入的包)
+type Import struct {
+	Alias string
+	Path  string
+}
+
+// File is a parsed definition file (解析后的定义文件)
+type File struct {
+	Package  string
+	Codec    string
+	Imports  []Import
+	Messages []*Message
+	Routes   []Route
+}
+
+// Message returns the message named name, nil if there is none (返回名为name的消息，不存在时返回nil)
+func (f *File) Message(name string) *Message {
+	for _, m := range f.Messages {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+var (
+	messageLine = regexp.MustCompile(`^message\s+(\w+)\s*=\s*(\d+)\s*([\w.*]*)\s*(\{\s*\}|\{)?$`)
+	routeLine   = regexp.MustCompile(`^route\s+(\w+)(?:\s*->\s*(\w+))?$`)
+	importLine  = regexp.MustCompile(`^import\s+(?:(\w+)\s+)?"([^"]+)"$`)
+	fieldLine   = regexp.MustCompile(`^(\w+)\s+(\S+)$`)
+)
+
+// Parse parses a definition file, see the package documentation (解析定义文件，见包文档)
+func Parse(src []byte) (*File, error) {
+	f := &File{Codec: CodecJSON}
+	var open *Message
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("zgen: line %d: %s", n, fmt.Sprintf(format, args...))
+		}
+
+		if open != nil {
+			if line == "}" {
+				open = nil
+				continue
+			}
+			match := fieldLine.FindStringSubmatch(line)
+			if match == nil {
+				return nil, errorf("invalid field %q", line)
+			}
+			open.Fields = append(open.Fields, Field{Name: match[1], Type: match[2]})
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch keyword {
+		case "package":
+			if !token.IsIdentifier(rest) {
+				return nil, errorf("invalid package %q", rest)
+			}
+			f.Package = rest
+		case "codec":
+			if rest != CodecJSON && rest != CodecProto {
+				return nil, errorf("unknown codec %q, %s or %s", rest, CodecJSON, CodecProto)
+			}
+			f.Codec = rest
+		case "import":
+			match := importLine.FindStringSubmatch(line)
+			if match == nil {
+				return nil, errorf("invalid import %q", line)
+			}
+			f.Imports = append(f.Imports, Import{Alias: match[1], Path: match[2]})
+		case "message":
+			match := messageLine.FindStringSubmatch(line)
+			if match == nil {
+				return nil, errorf("invalid message %q", line)
+			}
+			id, err := strconv.ParseUint(match[2], 10, 32)
+			if err != nil {
+				return nil, errorf("invalid msgID %q", match[2])
+			}
+			m := &Message{Name: match[1], MsgID: uint32(id), Type: match[3]}
+			if m.Type != "" && match[4] != "" {
+				return nil, errorf("message %s has both a type and fields", m.Name)
+			}
+			if match[4] == "{" {
+				open = m
+			}
+			f.Messages = append(f.Messages, m)
+		case "route":
+			match := routeLine.FindStringSubmatch(line)
+			if match == nil {
+				return nil, errorf("invalid route %q", line)
+			}
+			f.Routes = append(f.Routes, Route{Request: match[1], Reply: match[2]})
+		default:
+			return nil, errorf("unknown keyword %q", keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return nil, fmt.Errorf("zgen: message %s is not closed", open.Name)
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// validate checks the references and the uniqueness of the names and the MsgIDs
+// (检查引用以及名称与MsgID的唯一性)
+func (f *File) validate() error {
+	if f.Package == "" {
+		return fmt.Errorf("zgen: no package")
+	}
+	if len(f.Messages) == 0 {
+		return fmt.Errorf("zgen: no message")
+	}
+	names, ids := make(map[string]bool), make(map[uint32]string)
+	for _, m := range f.Messages {
+		if names[m.Name] {
+			return fmt.Errorf("zgen: message %s is defined twice", m.Name)
+		}
+		names[m.Name] = true
+		if other, ok := ids[m.MsgID]; ok {
+			return fmt.Errorf("zgen: messages %s and %s have the same msgID %d", other, m.Name, m.MsgID)
+		}
+		ids[m.MsgID] = m.Name
+		if f.Codec == CodecProto && m.Type == "" {
+			return fmt.Errorf("zgen: message %s of the proto codec needs a protoc generated type", m.Name)
+		}
+		for _, field := range m.Fields {
+			if !token.IsIdentifier(field.Name) {
+				return fmt.Errorf("zgen: invalid field %s of message %s", field.Name, m.Name)
+			}
+		}
+	}
+	routed := make(map[string]bool)
+	for _, r := range f.Routes {
+		if f.Message(r.Request) == nil {
+			return fmt.Errorf("zgen: route of the undefined message %s", r.Request)
+		}
+		if r.Reply != "" && f.Message(r.Reply) == nil {
+			return fmt.Errorf("zgen: route %s replies the undefined message %s", r.Request, r.Reply)
+		}
+		if routed[r.Request] {
+			return fmt.Errorf("zgen: message %s is routed twice", r.Request)
+		}
+		routed[r.Request] = true
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package zgen
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/examples/zinx_gen/game"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/ztest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(`
+package game // the package
+import pb "example.com/game/pb"
+
+message Login = 1 pb.LoginReq
+message LoginReply = 2 pb.LoginResp
+message Ping = 3 {}
+route Login -> LoginReply
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, "game", f.Package)
+	assert.Equal(t, CodecJSON, f.Codec)
+	assert.Equal(t, []Import{{Alias: "pb", Path: "example.com/game/pb"}}, f.Imports)
+	assert.Len(t, f.Messages, 3)
+	assert.Equal(t, &Message{Name: "LoginReply", MsgID: 2, Type: "pb.LoginResp"}, f.Message("LoginReply"))
+	assert.Equal(t, []Route{{Request: "Login", Reply: "LoginReply"}}, f.Routes)
+	assert.Nil(t, f.Message("Logout"))
+}
+
+func TestParseErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		"codec json":                        "no package",
+		"package p":                         "no message",
+		"package p\nmessage A = 1 {\nx int": "not closed",
+		"package p\nmessage A = 1\nmessage B = 1":         "same msgID",
+		"package p\nmessage A = 1\nmessage A = 2":         "defined twice",
+		"package p\nmessage A = 1\nroute B":               "undefined message B",
+		"package p\nmessage A = 1\nroute A -> C":          "undefined message C",
+		"package p\ncodec proto\nmessage A = 1 {}":        "protoc generated type",
+		"package p\ncodec xml":                            "line 2: unknown codec",
+		"package p\nmessage A = x":                        "invalid message",
+		"package p\nmessage A = 1 {\nx int y\n}":          "invalid field",
+		"package p\nmessage A = 1 pb.A {\n}":              "both a type and fields",
+		"package p\nrpc A":                                "unknown keyword",
+		"package p\nmessage A = 1\nroute A\nroute A -> A": "routed twice",
+	} {
+		_, err := Parse([]byte(src))
+		if assert.NotNil(t, err, src) {
+			assert.Contains(t, err.Error(), want, src)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "UserID", goName("user_id"))
+	assert.Equal(t, "AvatarURL", goName("avatar_url"))
+	assert.Equal(t, "X", goName("x"))
+	assert.Equal(t, "HP", goName("HP"))
+}
+
+// TestGenerateExample checks that the generated example is up to date (检查生成的示例是最新的)
+func TestGenerateExample(t *testing.T) {
+	src, err := os.ReadFile("../examples/zinx_gen/game/game.zinx")
+	assert.Nil(t, err)
+	f, err := Parse(src)
+	assert.Nil(t, err)
+	code, err := Generate(f, "game.zinx")
+	assert.Nil(t, err)
+	committed, err := os.ReadFile("../examples/zinx_gen/game/game.zinx.go")
+	assert.Nil(t, err)
+	assert.Equal(t, string(committed), string(code), "run go run ./examples/zinx_gen -in examples/zinx_gen/game/game.zinx")
+}
+
+func TestGenerateProto(t *testing.T) {
+	f, err := Parse([]byte("package game\ncodec proto\nimport pb \"example.com/game/pb\"\nmessage Login = 1 pb.LoginReq\nroute Login"))
+	assert.Nil(t, err)
+	code, err := Generate(f, "game.zinx")
+	assert.Nil(t, err)
+	for _, want := range []string{`pb "example.com/game/pb"`, "var Codec = zcodec.Proto", "type Login = pb.LoginReq"} {
+		assert.True(t, strings.Contains(string(code), want), want)
+	}
+
+	f, err = Parse([]byte("package game\nmessage Login = 1 {\nx map[\n}"))
+	assert.Nil(t, err)
+	_, err = Generate(f, "game.zinx")
+	assert.NotNil(t, err)
+}
+
+type gameHandler struct {
+	moves chan *game.Move
+}
+
+func (h *gameHandler) Login(ctx context.Context, conn ziface.IConnection, req *game.Login) (*game.LoginReply, error) {
+	return &game.LoginReply{UserID: 7, Roles: []string{req.Device}}, nil
+}
+
+func (h *gameHandler) Move(ctx context.Context, conn ziface.IConnection, req *game.Move) error {
+	h.moves <- req
+	return nil
+}
+
+// TestGeneratedServer serves the generated example (运行生成的示例)
+func TestGeneratedServer(t *testing.T) {
+	h := ztest.NewHarness()
+	defer h.Close()
+	handler := &gameHandler{moves: make(chan *game.Move, 1)}
+	game.RegisterHandler(h.Server, handler)
+	h.Start()
+	client := h.Dial()
+
+	assert.Nil(t, client.SendMsg(game.MsgIDLogin, []byte(`{"token":"t","device":"ios"}`)))
+	msg, err := client.Recv(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, game.MsgIDLoginReply, msg.GetMsgID())
+	var reply game.LoginReply
+	assert.Nil(t, json.Unmarshal(msg.GetData(), &reply))
+	assert.Equal(t, game.LoginReply{UserID: 7, Roles: []string{"ios"}}, reply)
+
+	assert.Nil(t, client.SendMsg(game.MsgIDMove, []byte(`{"x":1,"y":2}`)))
+	assert.Equal(t, &game.Move{X: 1, Y: 2}, <-handler.moves)
+
+	conn := ztest.NewMockConnection(1)
+	assert.Nil(t, game.SendMoved(conn, &game.Moved{UserID: 7, Position: game.Move{X: 1}}))
+	assert.Equal(t, game.MsgIDMoved, conn.LastSent().GetMsgID())
+	assert.Equal(t, `{"user_id":7,"position":{"x":1,"y":0}}`, string(conn.LastSent().GetData()))
+}
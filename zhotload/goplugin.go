@@ -0,0 +1,33 @@
+//go:build (linux || darwin || freebsd) && cgo
+// +build linux darwin freebsd
+// +build cgo
+
+package zhotload
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+func init() {
+	RegisterLoader(".so", loadGoPlugin)
+}
+
+// loadGoPlugin opens the Go plugin at path and calls its ZinxRouters (打开path处的Go插件并调用其ZinxRouters)
+func loadGoPlugin(path string) (map[uint32]ziface.IRouter, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := p.Lookup(Symbol)
+	if err != nil {
+		return nil, err
+	}
+	routers, ok := symbol.(func() map[uint32]ziface.IRouter)
+	if !ok {
+		return nil, fmt.Errorf("%s is a %T, not a func() map[uint32]ziface.IRouter", Symbol, symbol)
+	}
+	return routers(), nil
+}
@@ -0,0 +1,19 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+// +build !linux,!darwin,!freebsd !cgo
+
+package zhotload
+
+import (
+	"errors"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+func init() {
+	RegisterLoader(".so", loadGoPlugin)
+}
+
+// loadGoPlugin fails where the plugin package is not supported (在不支持plugin包的平台上返回错误)
+func loadGoPlugin(path string) (map[uint32]ziface.IRouter, error) {
+	return nil, errors.New("Go plugins need cgo on linux, darwin or freebsd")
+}
@@ -0,0 +1,102 @@
+// Package zhotload loads routers from modules at runtime and swaps them into a running server with
+// IServer.ReplaceRouterDrain, so that a patch of the business logic ships without restarting the gateway holding the
+// connections: the requests in flight finish on the old routers while the new ones go to the loaded routers.
+//
+// Modules are loaded by the loader registered for their file extension. ".so" Go plugins are supported out of the
+// box where the plugin package is (linux, darwin and freebsd with cgo), a plugin exports
+//
+//	func ZinxRouters() map[uint32]ziface.IRouter
+//
+// and must be built with the same Go version and zinx version as the server. A Go plugin cannot be unloaded nor
+// loaded twice from the same path, so every patch has its own file, e.g. game_v2.so. Other formats such as WASM
+// modules are loaded by registering a loader binding a runtime, e.g. RegisterLoader(".wasm", wazeroLoader).
+//
+// (zhotload在运行时从模块加载路由，并通过IServer.ReplaceRouterDrain替换到运行中的服务，业务逻辑的补丁无需重启持有连接的网关即可发布：
+// 正在处理的请求在旧路由上完成，新请求交给加载的路由。
+// 模块由其文件扩展名注册的加载器加载。在支持plugin包的平台(开启cgo的linux、darwin和freebsd)上直接支持".so"的Go插件，
+// 插件导出上面的ZinxRouters函数，且必须使用与服务相同的Go版本和zinx版本编译。Go插件无法卸载，也不能从同一路径加载两次，
+// 因此每个补丁使用自己的文件，例如game_v2.so。WASM等其它格式的模块通过注册绑定运行时的加载器加载，例如RegisterLoader(".wasm", wazeroLoader))
+package zhotload
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// Symbol is the function a Go plugin exports its routers with (Go插件导出路由的函数名)
+const Symbol = "ZinxRouters"
+
+// Loader loads the routers of the module at path, by MsgID (加载path处模块的路由，按MsgID索引)
+type Loader func(path string) (map[uint32]ziface.IRouter, error)
+
+var (
+	ErrNoLoader = errors.New("zhotload: no loader registered for the extension")
+	ErrNoRoutes = errors.New("zhotload: the module has no routers")
+	// ErrRouterSlicesMode is returned by Load for a server in RouterSlicesMode, which has no IRouter to replace
+	// (服务处于RouterSlicesMode时Load返回的错误，该模式下没有可替换的IRouter)
+	ErrRouterSlicesMode = errors.New("zhotload: the server is in RouterSlicesMode")
+
+	loaders     = map[string]Loader{}
+	loadersLock sync.RWMutex
+)
+
+// RegisterLoader makes the modules with the extension ext, e.g. ".wasm", loadable by loader
+// (使扩展名为ext的模块(例如".wasm")可以由loader加载)
+func RegisterLoader(ext string, loader Loader) {
+	loadersLock.Lock()
+	defer loadersLock.Unlock()
+	loaders[ext] = loader
+}
+
+// configured is implemented by the servers of znet (znet的服务实现该接口)
+type configured interface {
+	GetConfig() *zconf.Config
+}
+
+func loaderOf(path string) (Loader, bool) {
+	loadersLock.RLock()
+	defer loadersLock.RUnlock()
+	loader, ok := loaders[filepath.Ext(path)]
+	return loader, ok
+}
+
+// Load loads the routers of the module at path and replaces the routers of their MsgIDs on server, onDrained is
+// called with each replaced router once its requests in flight are done, it may be nil. It returns the MsgIDs
+// loaded, the server is left unchanged on an error, e.g. ErrRouterSlicesMode.
+// (加载path处模块的路由并替换服务上对应MsgID的路由，被替换的路由上正在处理的请求完成后以其调用onDrained，onDrained可以为nil。
+// 返回加载的MsgID，出错时服务保持不变，例如ErrRouterSlicesMode)
+func Load(server ziface.IServer, path string, onDrained func(msgID uint32, old ziface.IRouter)) ([]uint32, error) {
+	// ReplaceRouterDrain panics in RouterSlicesMode (RouterSlicesMode下ReplaceRouterDrain会panic)
+	if s, ok := server.(configured); ok && s.GetConfig().RouterSlicesMode {
+		return nil, fmt.Errorf("%w: %s", ErrRouterSlicesMode, path)
+	}
+	loader, ok := loaderOf(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoLoader, path)
+	}
+	routers, err := loader(path)
+	if err != nil {
+		return nil, fmt.Errorf("zhotload: load %s: %w", path, err)
+	}
+	if len(routers) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoRoutes, path)
+	}
+
+	msgIDs := make([]uint32, 0, len(routers))
+	for msgID := range routers {
+		msgIDs = append(msgIDs, msgID)
+	}
+	sort.Slice(msgIDs, func(i, j int) bool { return msgIDs[i] < msgIDs[j] })
+	for _, msgID := range msgIDs {
+		server.ReplaceRouterDrain(msgID, routers[msgID], onDrained)
+	}
+	zlog.Ins().InfoF("[HOTLOAD] loaded %s, msgIDs %v", path, msgIDs)
+	return msgIDs, nil
+}
@@ -0,0 +1,63 @@
+package zhotload
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+type replaceServer struct {
+	ziface.IServer
+	routers map[uint32]ziface.IRouter
+}
+
+func (s *replaceServer) ReplaceRouterDrain(msgID uint32, router ziface.IRouter, onDrained func(uint32, ziface.IRouter)) {
+	s.routers[msgID] = router
+}
+
+func TestLoad(t *testing.T) {
+	patch := &znet.BaseRouter{}
+	RegisterLoader(".patch", func(path string) (map[uint32]ziface.IRouter, error) {
+		switch path {
+		case "game_v2.patch":
+			return map[uint32]ziface.IRouter{3: patch, 1: patch}, nil
+		case "empty.patch":
+			return nil, nil
+		}
+		return nil, errors.New("corrupt")
+	})
+
+	s := &replaceServer{routers: map[uint32]ziface.IRouter{}}
+	msgIDs, err := Load(s, "game_v2.patch", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 3}, msgIDs)
+	assert.Equal(t, patch, s.routers[3])
+
+	_, err = Load(s, "empty.patch", nil)
+	assert.ErrorIs(t, err, ErrNoRoutes)
+	_, err = Load(s, "game_v3.patch", nil)
+	assert.EqualError(t, err, "zhotload: load game_v3.patch: corrupt")
+	_, err = Load(s, "game.wasm", nil)
+	assert.ErrorIs(t, err, ErrNoLoader)
+	// A missing Go plugin fails to open (不存在的Go插件打开失败)
+	_, err = Load(s, "missing.so", nil)
+	assert.NotNil(t, err)
+	assert.Len(t, s.routers, 2)
+}
+
+func TestLoadRouterSlicesMode(t *testing.T) {
+	RegisterLoader(".slices", func(path string) (map[uint32]ziface.IRouter, error) {
+		return map[uint32]ziface.IRouter{1: &znet.BaseRouter{}}, nil
+	})
+	s := znet.NewServerWithOptions(znet.WithConfig(func(c *zconf.Config) { c.RouterSlicesMode = true }))
+
+	// The server is left unchanged instead of panicking (服务保持不变而不是panic)
+	assert.NotPanics(t, func() {
+		_, err := Load(s, "game.slices", nil)
+		assert.ErrorIs(t, err, ErrRouterSlicesMode)
+	})
+}
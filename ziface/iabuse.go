@@ -0,0 +1,17 @@
+// @Title iabuse.go
+// @Description Provides interfaces for reporting the misbehaviour of the connections
+package ziface
+
+// Kinds of the misbehaviour reported to an IAbuseReporter (向IAbuseReporter报告的不当行为类型)
+const (
+	AbuseDecodeError  = "decode_error"  // A frame could not be decoded (帧无法解码)
+	AbuseAuthFailure  = "auth_failure"  // The authentication failed or timed out (认证失败或超时)
+	AbuseBadSignature = "bad_signature" // A message was unsigned or its signature invalid (消息未签名或签名无效)
+)
+
+// IAbuseReporter is implemented by the message handlers tracking the sources of abusive traffic, the decoders
+// report their errors to the message handler of the connection through it
+// (由跟踪滥用流量来源的消息处理器实现，解码器通过它向连接的消息处理器报告错误)
+type IAbuseReporter interface {
+	ReportAbuse(conn IConnection, kind string)
+}
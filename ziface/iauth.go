@@ -0,0 +1,46 @@
+// @Title iauth.go
+// @Description Provides interfaces for authenticating the connections
+package ziface
+
+// Principal is the authenticated identity of a connection (连接经过认证的身份)
+type Principal struct {
+	// The identity, e.g. the user ID or the common name of the client certificate
+	// (身份标识，例如用户ID或客户端证书的通用名称)
+	ID string
+	// The roles and the permissions granted to the identity (授予该身份的角色与权限)
+	Roles       []string
+	Permissions []string
+	// Other claims of the credentials, e.g. the tenant (凭证中的其他声明，例如租户)
+	Attributes map[string]string
+}
+
+// HasRole reports whether the principal has the role (判断是否拥有该角色)
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && contains(p.Roles, role)
+}
+
+// HasPermission reports whether the principal has the permission (判断是否拥有该权限)
+func (p *Principal) HasPermission(permission string) bool {
+	return p != nil && contains(p.Permissions, permission)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// IAuthenticator validates the credentials carried by the auth messages of a connection, it is called in the reader
+// goroutine of the connection, one message at a time
+// (校验连接的认证消息中携带的凭证，在连接的读协程中逐条调用)
+type IAuthenticator interface {
+	// Authenticate handles the auth message msgID, reply is sent back with the same msgID unless it is nil.
+	// A principal authenticates the connection, an error fails the authentication and closes the connection,
+	// neither continues a multi-step handshake such as a challenge-response.
+	// (处理认证消息msgID，reply不为nil时以相同的msgID回复。返回principal表示连接认证成功，返回错误表示认证失败并关闭连接，
+	// 两者均为nil时继续多步握手，例如挑战-应答)
+	Authenticate(conn IConnection, msgID uint32, data []byte) (principal *Principal, reply []byte, err error)
+}
@@ -5,6 +5,7 @@
 package ziface
 
 import (
+	"context"
 	"net/url"
 	"time"
 )
@@ -12,6 +13,9 @@ import (
 type IClient interface {
 	Restart()
 	Start()
+	// StartContext starts the client, the dial and the connection are canceled once ctx is done
+	// (启动客户端，ctx结束时取消拨号与连接)
+	StartContext(ctx context.Context)
 	Stop()
 	AddRouter(msgID uint32, router IRouter)
 	Conn() IConnection
@@ -59,6 +63,11 @@ type IClient interface {
 	// AddInterceptor Add an interceptor for this Client 添加拦截器
 	AddInterceptor(IInterceptor)
 
+	// AddSendInterceptor Add hooks called with every outbound message before it is packed
+	// (添加在每条发出消息封包前调用的钩子)
+	AddSendInterceptor(interceptors ...SendInterceptor)
+	GetSendInterceptors() []SendInterceptor
+
 	// Get the error channel for this Client 获取客户端错误管道
 	GetErrChan() chan error
 
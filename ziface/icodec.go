@@ -0,0 +1,11 @@
+// @Title icodec.go
+// @Description Provides the interface for encoding and decoding message payloads
+package ziface
+
+// ICodec marshals and unmarshals the payload of messages, e.g. JSON or protobuf
+// (消息体的编解码器，例如JSON或protobuf)
+type ICodec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
@@ -0,0 +1,22 @@
+// @Title icompressor.go
+// @Description Wire compression negotiated between client and server
+package ziface
+
+// ICompressor compresses the data of messages on the wire (压缩线路上的消息数据)
+type ICompressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// IFlagDataPack is implemented by datapacks whose header may carry the flags of a message,
+// compressed messages are marked by MessageFlagCompressed so compression is only negotiated on them.
+// (头部可以携带消息标志位的数据包，压缩的消息通过MessageFlagCompressed标记，因此只有这类数据包会协商压缩)
+type IFlagDataPack interface {
+	HasFlags() bool
+}
+
+// CompressionHandshakeMsgID is the MsgID reserved for the compression handshake, the client offers
+// "offer:gzip,deflate" and the server answers "accept:gzip", or "accept:" if none is supported.
+// (为压缩握手保留的MsgID，客户端发送"offer:gzip,deflate"，服务端回复"accept:gzip"，都不支持时回复"accept:")
+const CompressionHandshakeMsgID uint32 = 0xFFFFFFF1
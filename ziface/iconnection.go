@@ -6,6 +6,7 @@ package ziface
 import (
 	"context"
 	"net"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -48,13 +49,42 @@ type IConnection interface {
 	// 直接将Message数据发送给远程的TCP客户端(有缓冲)
 	SendBuffMsg(msgID uint32, data []byte) error
 
+	// Pack and send a Message directly, datapacks can carry extra header fields of msg such as the trace ID
+	// (直接封包并发送Message，数据包可以携带msg的额外头部字段，例如链路追踪ID)
+	SendMessage(msg IMessage) error
+
+	// Send Message data after delay or at a point in time, backed by the ztimer time wheel (about 100ms precision),
+	// pending sends are canceled when the connection closes
+	// (在延迟delay之后或在指定时间发送Message数据，基于ztimer时间轮(精度约100ms)，连接关闭时未发送的消息会被取消)
+	SendMsgAfter(delay time.Duration, msgID uint32, data []byte) (IScheduledSend, error)
+	SendMsgAt(at time.Time, msgID uint32, data []byte) (IScheduledSend, error)
+
+	// Call f after d, or every d until canceled, on the ztimer time wheel, the timers are canceled when the
+	// connection closes. The callbacks run on the timer goroutines, and a ticker waits for f to return before the
+	// next d (在d之后调用f，或每隔d调用f直到取消，基于ztimer时间轮，连接关闭时定时器自动取消。回调在定时器协程中运行，Ticker在f返回后才开始下一个d)
+	AfterFunc(d time.Duration, f func()) IConnTimer
+	Ticker(d time.Duration, f func()) IConnTimer
+
 	SetProperty(key string, value interface{})   // Set connection property
 	GetProperty(key string) (interface{}, error) // Get connection property
 	RemoveProperty(key string)                   // Remove connection property
 	IsAlive() bool                               // Check if the current connection is alive(判断当前连接是否存活)
-	SetHeartBeat(checker IHeartbeatChecker)      // Set the heartbeat detector (设置心跳检测器)
+
+	// Account the traffic of the connection to tenant from now on, e.g. once it has logged in
+	// (从现在起将连接的流量计入tenant，例如在登录之后)
+	SetTenant(tenant string)
+	GetTenant() string
+	GetTraffic() TrafficStats // The traffic of the connection since it started (连接开始以来的流量)
+
+	SetHeartBeat(checker IHeartbeatChecker) // Set the heartbeat detector (设置心跳检测器)
 
 	AddCloseCallback(handler, key interface{}, callback func()) // Add a close callback function (添加关闭回调函数)
 	RemoveCloseCallback(handler, key interface{})               // Remove a close callback function (删除关闭回调函数)
 	InvokeCloseCallbacks()                                      // Trigger the close callback function (触发关闭回调函数，独立协程完成)
 }
+
+// IPropertyLister is implemented by the connections which can list their properties, such as the ones of znet
+// (可以列出自身属性的连接实现该接口，例如znet的连接)
+type IPropertyLister interface {
+	GetProperties() map[string]interface{}
+}
@@ -0,0 +1,18 @@
+// @Title iconntimer.go
+// @Description Handle of the timers of a connection
+package ziface
+
+import "time"
+
+// IConnTimer is the handle of a timer of a connection set by AfterFunc or Ticker, it is canceled when the
+// connection closes (连接上由AfterFunc或Ticker设置的定时器的句柄，连接关闭时自动取消)
+type IConnTimer interface {
+	// Cancel stops the timer, no callback starts once it returns, it returns false if the timer was not pending
+	// (停止定时器，返回后不会再有回调开始执行，定时器不在等待中时返回false)
+	Cancel() bool
+	// Reset schedules the timer again after d, a ticker then ticks every d, it returns whether the timer was pending
+	// (在d之后重新调度定时器，Ticker之后每隔d触发，返回定时器是否在等待中)
+	Reset(d time.Duration) bool
+	// Pending reports whether the callback is still to be called (返回回调是否仍在等待调用)
+	Pending() bool
+}
@@ -12,10 +12,27 @@ type IDataPack interface {
 	Unpack([]byte) (IMessage, error)   // Unpackage message(拆包方法)
 }
 
+// IBufferReleaser is implemented by datapacks whose Pack takes its buffer from a pool,
+// the send path hands the buffer back with ReleaseBuffer once it has been written.
+// Callers packing by themselves may keep the buffer and never release it.
+// (Pack的缓冲取自缓冲池的数据包实现该接口，发送流程写出缓冲后通过ReleaseBuffer归还，
+// 自行调用Pack的使用者可以持有该缓冲而不归还)
+type IBufferReleaser interface {
+	ReleaseBuffer(buf []byte)
+}
+
 const (
 	// Zinx standard packing and unpacking method (Zinx 标准封包和拆包方式)
 	ZinxDataPack    string = "zinx_pack_tlv_big_endian"
 	ZinxDataPackOld string = "zinx_pack_ltv_little_endian"
+	// TLV with a 16 bytes trace ID after the length field (在长度字段之后携带16字节链路追踪ID的TLV)
+	ZinxDataPackTrace string = "zinx_pack_tlv_trace"
+	// Length prefixed protobuf envelope (长度前缀加protobuf信封)
+	ZinxDataPackProtobuf string = "zinx_pack_protobuf"
+	// {"id":...,"data":...} JSON envelopes, length prefixed or newline delimited
+	// (JSON信封，长度前缀分帧或换行分帧)
+	ZinxDataPackJSON     string = "zinx_pack_json"
+	ZinxDataPackJSONLine string = "zinx_pack_json_line"
 
 	//...(+)
 	//// Custom packing method can be added here(自定义封包方式在此添加)
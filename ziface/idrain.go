@@ -0,0 +1,29 @@
+// @Title idrain.go
+// @Description Provides the types of the connection draining, for the maintenance windows
+package ziface
+
+import "time"
+
+// ConnSelector selects the connections matching all of its fields set, the zero ConnSelector selects none
+// (选择匹配其所有已设置字段的连接，零值ConnSelector不选择任何连接)
+type ConnSelector struct {
+	Tenant string `json:"tenant,omitempty"`
+	// The connections subscribed to the topic Group, see IPubSub (订阅了主题Group的连接，见IPubSub)
+	Group string `json:"group,omitempty"`
+	// The connections with the property Tag, equal to TagValue if it is set (具有属性Tag的连接，TagValue不为空时属性值需与其相等)
+	Tag      string `json:"tag,omitempty"`
+	TagValue string `json:"tagValue,omitempty"`
+	// Match selects the connections by code (通过代码选择连接)
+	Match func(conn IConnection) bool `json:"-"`
+}
+
+// DrainOption is how the selected connections are drained (如何排空选中的连接)
+type DrainOption struct {
+	// The grace message sent to each connection first, e.g. telling the client to reconnect elsewhere, none if
+	// GraceData is nil (首先发送给每个连接的通知消息，例如告知客户端重连到别处，GraceData为nil时不发送)
+	GraceMsgID uint32
+	GraceData  []byte
+	// The connections are closed after Timeout even with requests in flight, 0 waits for the requests
+	// (超过Timeout后即使仍有正在处理的请求也关闭连接，0表示等待请求完成)
+	Timeout time.Duration
+}
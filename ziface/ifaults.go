@@ -0,0 +1,69 @@
+// @Title ifaults.go
+// @Description Provides the faults injected into the connections for resilience testing, see zadmin /admin/faults
+package ziface
+
+import (
+	"fmt"
+	"time"
+)
+
+// Faults are injected into the connections of a server to test the reconnect and the reliability logic of the clients
+// against realistic failures, never in production. The ratios are from 0 to 1, the zero value injects nothing
+// (注入服务连接的故障，用于针对真实的失败测试客户端的重连与可靠性逻辑，不可用于生产环境。比例取值0到1，零值不注入任何故障)
+type Faults struct {
+	// The ratio of the sends delayed, by a random time up to SendDelay (被延迟的发送所占比例，延迟为不超过SendDelay的随机时间)
+	SendDelayRatio float64       `json:"sendDelayRatio"`
+	SendDelay      time.Duration `json:"sendDelay"`
+	// The ratio of the inbound frames dropped before they are decoded (在解码之前被丢弃的入站帧所占比例)
+	DropRatio float64 `json:"dropRatio"`
+	// The ratio of the inbound frames with a random byte flipped before they are decoded (在解码之前被随机翻转一个字节的入站帧所占比例)
+	CorruptRatio float64 `json:"corruptRatio"`
+	// The ratio of the inbound frames closing their connection instead of being handled (导致连接被关闭而不被处理的入站帧所占比例)
+	CloseRatio float64 `json:"closeRatio"`
+}
+
+// Enabled reports whether any fault is injected (是否注入了任何故障)
+func (f Faults) Enabled() bool {
+	return (f.SendDelayRatio > 0 && f.SendDelay > 0) || f.DropRatio > 0 || f.CorruptRatio > 0 || f.CloseRatio > 0
+}
+
+// Validate checks the ratios and the delay (检查比例与延迟)
+func (f Faults) Validate() error {
+	for _, r := range []struct {
+		name  string
+		ratio float64
+	}{{"SendDelayRatio", f.SendDelayRatio}, {"DropRatio", f.DropRatio}, {"CorruptRatio", f.CorruptRatio}, {"CloseRatio", f.CloseRatio}} {
+		if r.ratio < 0 || r.ratio > 1 {
+			return fmt.Errorf("%s must be between 0 and 1, got %v", r.name, r.ratio)
+		}
+	}
+	if f.SendDelay < 0 {
+		return fmt.Errorf("SendDelay must not be negative, got %v", f.SendDelay)
+	}
+	return nil
+}
+
+// NetCondition simulates the network of one connection, e.g. a player on 3G, without shaping the network outside the
+// server, the zero value simulates nothing (模拟一个连接的网络状况，例如使用3G的玩家，无需在服务之外进行网络整形，零值不模拟)
+type NetCondition struct {
+	// Latency is added to every message in each direction, a round trip takes twice as long
+	// (每个方向上的每条消息都增加该延迟，一次往返增加两倍)
+	Latency time.Duration `json:"latency"`
+	// Jitter adds a random time up to it to the latency (在延迟之上增加不超过Jitter的随机时间)
+	Jitter time.Duration `json:"jitter"`
+	// Bandwidth throttles each direction to the bytes per second, 0 is unlimited (将每个方向限制为每秒的字节数，0为不限制)
+	Bandwidth int64 `json:"bandwidth"`
+}
+
+// Enabled reports whether the condition simulates anything (是否模拟了任何网络状况)
+func (c NetCondition) Enabled() bool {
+	return c.Latency > 0 || c.Jitter > 0 || c.Bandwidth > 0
+}
+
+// Validate checks that nothing is negative (检查各项均不为负)
+func (c NetCondition) Validate() error {
+	if c.Latency < 0 || c.Jitter < 0 || c.Bandwidth < 0 {
+		return fmt.Errorf("the latency, the jitter and the bandwidth must not be negative, got %+v", c)
+	}
+	return nil
+}
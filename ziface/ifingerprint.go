@@ -0,0 +1,28 @@
+// @Title ifingerprint.go
+// @Description Provides interfaces for classifying the connections when they are accepted
+package ziface
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+// Fingerprint is what is known of a TCP connection before its read loop starts (TCP连接在读循环开始之前的已知信息)
+type Fingerprint struct {
+	ConnID     uint64
+	RemoteAddr net.Addr
+	LocalAddr  net.Addr
+	// The first bytes sent by the client, shorter than asked if the client paused within the timeout, they are
+	// still read by the connection afterwards (客户端最先发送的字节，客户端在超时内停顿时少于请求的长度，之后连接仍会读到它们)
+	Peek []byte
+	// The ClientHello of the TLS handshake, nil without TLS (TLS握手的ClientHello，不使用TLS时为nil)
+	ClientHello *tls.ClientHelloInfo
+	// The accepted socket, e.g. for its options through SyscallConn, it must not be read or closed
+	// (接受的套接字，例如通过SyscallConn读取其选项，不得读取或关闭)
+	Socket net.Conn
+}
+
+// Fingerprinter classifies a new connection, e.g. as a bot or by client version, an error rejects it and the
+// connection is closed before it is added to the connection manager
+// (对新连接进行分类，例如识别机器人或客户端版本，返回错误时拒绝该连接，在加入连接管理器之前将其关闭)
+type Fingerprinter func(fp *Fingerprint) (class string, err error)
@@ -0,0 +1,20 @@
+// @Title ihealth.go
+// @Description Provides the health status of the servers, see zadmin /healthz and /readyz
+package ziface
+
+// HealthCheck is the result of one readiness check (一项就绪检查的结果)
+type HealthCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HealthStatus is the health of a server: it is live once started until stopped, and ready while it accepts
+// connections and every check passes (服务的健康状态：启动后到停止前为存活，接受连接且所有检查通过时为就绪)
+type HealthStatus struct {
+	Server string        `json:"server"`
+	State  string        `json:"state"` // "starting", "accepting", "draining" or "stopped"
+	Live   bool          `json:"live"`
+	Ready  bool          `json:"ready"`
+	Checks []HealthCheck `json:"checks"`
+}
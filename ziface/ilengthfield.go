@@ -6,6 +6,13 @@ type IFrameDecoder interface {
 	Decode(buff []byte) [][]byte
 }
 
+// IFrameErrDecoder is implemented by the frame decoders reporting their failures, a zerrors.FrameTooLongError after
+// discarding a frame or a zerrors.DecodeError once the stream cannot be decoded, the connections close on the latter
+// (由报告失败的帧解码器实现，丢弃帧后返回zerrors.FrameTooLongError，数据流无法解码时返回zerrors.DecodeError，连接在后者时关闭)
+type IFrameErrDecoder interface {
+	DecodeFrames(buff []byte) ([][]byte, error)
+}
+
 // ILengthField Basic attributes possessed by ILengthField
 // (具备的基础属性)
 type LengthField struct {
@@ -24,4 +31,9 @@ type LengthField struct {
 	LengthFieldLength   int              //The length of the length field in bytes(长度域字段的字节数)
 	LengthAdjustment    int              //The length adjustment(长度调整)
 	InitialBytesToStrip int              //The number of bytes to strip from the decoded frame(需要跳过的字节数)
+
+	// Delimiter splits frames by a delimiter such as "\n" instead of a length field, the delimiter is stripped,
+	// all length field settings except MaxFrameLength are ignored when it is set
+	// (按分隔符(例如"\n")而不是长度字段分帧，分隔符会被去掉，设置后除MaxFrameLength外的长度字段配置都被忽略)
+	Delimiter []byte
 }
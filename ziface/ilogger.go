@@ -13,3 +13,19 @@ type ILogger interface {
 	ErrorFX(ctx context.Context, format string, v ...interface{})
 	DebugFX(ctx context.Context, format string, v ...interface{})
 }
+
+// LogField is a key/value pair attached to a log line (日志行附带的键值对)
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// ILeveledLogger is the minimal leveled logger with fields, logging backends such as zap, logrus or slog
+// are adapted to it and installed with zlog.SetLeveledLogger
+// (带字段的最小分级日志接口，zap、logrus、slog等日志库适配为该接口后通过zlog.SetLeveledLogger安装)
+type ILeveledLogger interface {
+	Debug(msg string, fields ...LogField)
+	Info(msg string, fields ...LogField)
+	Warn(msg string, fields ...LogField)
+	Error(msg string, fields ...LogField)
+}
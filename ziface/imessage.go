@@ -14,3 +14,68 @@ type IMessage interface {
 	SetData([]byte)    // Sets the content of the message(设计消息内容)
 	SetDataLen(uint32) // Sets the length of the message data segment(设置消息数据段长度)
 }
+
+// IPooledMessage is implemented by messages taken from an object pool, Release hands them back
+// once the request has been handled. The message must not be used after Release.
+// (取自对象池的消息，请求处理完成后通过Release归还，Release之后不能再使用该消息)
+type IPooledMessage interface {
+	IMessage
+	Release()
+}
+
+// ITraceMessage is implemented by messages carrying a trace ID in their header,
+// datapacks supporting tracing read and write it end-to-end
+// (头部携带链路追踪ID的消息，支持链路追踪的数据包会端到端地读写该ID)
+type ITraceMessage interface {
+	IMessage
+	GetTraceID() string
+	SetTraceID(traceID string)
+}
+
+// IEnvelopeMessage is implemented by messages carrying flags and a correlation ID in their envelope,
+// e.g. the protobuf envelope datapack
+// (信封中携带标志位和关联ID的消息，例如protobuf信封数据包)
+type IEnvelopeMessage interface {
+	IMessage
+	GetFlags() uint32
+	SetFlags(flags uint32)
+	GetCorrelationID() uint64
+	SetCorrelationID(id uint64)
+}
+
+// Bits of the flags header field (flags头部字段的标志位)
+const (
+	MessageFlagCompressed uint32 = 1 << 0 // The data is compressed (数据已压缩)
+	MessageFlagEncrypted  uint32 = 1 << 1 // The data is encrypted (数据已加密)
+)
+
+// IHeaderMessage is implemented by messages exposing the optional fields of the configurable header schema
+// of the default datapack, see zconf.Config.HeaderFields
+// (暴露默认数据包可配置头部中可选字段的消息，见zconf.Config.HeaderFields)
+type IHeaderMessage interface {
+	IMessage
+	GetVersion() uint8
+	SetVersion(version uint8)
+	GetFlags() uint32
+	SetFlags(flags uint32)
+	GetChecksum() uint32 // CRC32 (IEEE) of the data (数据的CRC32校验值)
+	SetChecksum(checksum uint32)
+	GetTimestamp() int64 // Unix milliseconds when the message was packed (封包时的Unix毫秒时间戳)
+	SetTimestamp(ms int64)
+	GetNonce() uint64 // Unique per message, for the replay protection (每条消息唯一，用于防重放)
+	SetNonce(nonce uint64)
+	GetSignature() []byte // Verified by the signer of WithMessageSigning (由WithMessageSigning的签名器校验)
+	SetSignature(signature []byte)
+}
+
+// FragmentMsgID is the MsgID reserved for the fragments of messages larger than MaxPacketSize
+// when zconf.Config.FragmentMode is on
+// (开启zconf.Config.FragmentMode时，为超过MaxPacketSize的消息分片保留的MsgID)
+const FragmentMsgID uint32 = 0xFFFFFFF0
+
+// MsgIDs reserved for the reliable delivery layer zreliable, a reliable message is acknowledged by its sequence number
+// (为可靠投递层zreliable保留的MsgID，可靠消息通过序号确认)
+const (
+	ReliableMsgID    uint32 = 0xFFFFFFF2
+	ReliableAckMsgID uint32 = 0xFFFFFFF3
+)
@@ -3,16 +3,107 @@
 // @Author Aceld - Thu Mar 11 10:32:29 CST 2019
 package ziface
 
+import (
+	"context"
+	"time"
+)
+
 // IMsgHandle Abstract layer of message management(消息管理抽象层)
 type IMsgHandle interface {
 	// Add specific handling logic for messages, msgID supports int and string types
 	// (为消息添加具体的处理逻辑, msgID，支持整型，字符串)
 	AddRouter(msgID uint32, router IRouter)
 	AddRouterSlices(msgId uint32, handler ...RouterHandler) IRouterSlices
+
+	// Remove or replace a router, safe to call while the server is running
+	// (删除或替换路由，服务运行期间可以安全调用)
+	RemoveRouter(msgID uint32)
+	ReplaceRouter(msgID uint32, router IRouter)
+	RemoveRouterSlices(msgId uint32)
+	ReplaceRouterSlices(msgId uint32, handler ...RouterHandler) IRouterSlices
+
+	// Replace a router letting the requests in flight finish on the old one, onDrained is called once they are done
+	// (替换路由，正在处理的请求仍由旧路由处理完，全部完成后调用onDrained)
+	ReplaceRouterDrain(msgID uint32, router IRouter, onDrained func(msgID uint32, old IRouter))
+
+	// Add a router for all MsgIDs in [start, end], exact MsgID routers take precedence
+	// (为MsgID区间[start, end]添加路由，精确MsgID路由优先)
+	AddRouterRange(start, end uint32, router IRouter)
+	AddRouterSlicesRange(start, end uint32, handler ...RouterHandler) IRouterSlices
+
+	// Set the router used when no router matches the MsgID
+	// (设置没有任何路由匹配MsgID时使用的默认路由)
+	SetNotFoundRouter(router IRouter)
+	SetNotFoundRouterSlices(handler ...RouterHandler)
+
+	// Add a router for a string topic or a topic pattern such as "device/+/telemetry", "rpc/*",
+	// requests carrying a topic are routed by topic instead of MsgID
+	// (为字符串主题或主题模式添加路由，携带主题的请求按主题而不是MsgID路由)
+	AddTopicRouter(topic string, router IRouter)
+	AddTopicRouterSlices(topic string, handler ...RouterHandler) IRouterSlices
+	SetTopicExtractor(extractor TopicExtractor)
+
+	// Limit the duration of the handler of msgID, on expiry the request context is canceled,
+	// onTimeout is called and the worker moves on, 0 removes the limit
+	// (限制msgID处理函数的执行时长，超时后取消请求Context并调用onTimeout，worker继续处理下一个请求，0表示取消限制)
+	SetRouterTimeout(msgID uint32, timeout time.Duration, onTimeout ...TimeoutHandler)
+	// Number of requests which timed out (处理超时的请求数量)
+	GetTimeoutCount() uint64
+
+	// Opt msgID out of per-connection ordering, its messages are dispatched round-robin to all workers
+	// (设置msgID不要求按连接顺序处理，其消息轮询分发给所有worker)
+	SetRouterUnordered(msgID uint32, unordered bool)
+
+	// Create a dedicated worker pool and bind routes to it, so expensive routes cannot starve the shared pool
+	// (创建专属worker池并将路由绑定到该池，避免耗时路由拖垮共享worker池)
+	AddWorkerPool(name string, size uint32, queueLen uint32)
+	SetRouterPool(msgID uint32, name string)
+
+	// Route priorities, requests of routes with a priority above 0 are handled ahead of the queued ones.
+	// Overflow handling of full task queues, see zconf.QueueFullPolicy: the hook called on dropped requests and the counters
+	// (路由优先级，优先级大于0的路由的请求先于排队的请求处理。
+	// 任务队列已满时的溢出处理，见zconf.QueueFullPolicy：丢弃请求时的回调以及计数)
+	SetRouterPriority(msgID uint32, priority int)
+	SetOnQueueFull(handler QueueFullHandler)
+
+	// Limit the inbound messages of a route per connection, on top of the RateLimit* limits of the connection
+	// (按连接限制路由的入站消息，在连接的RateLimit*限制之外)
+	SetRouterRateLimit(msgID uint32, limit RateLimit)
+	SetOnRateLimited(handler RateLimitHandler)
+	GetDropCount() uint64
+	GetSpillCount() uint64
+	GetQueueDepths() []int
+
+	// Snapshot of the utilization, queue length and latency histograms of every running worker
+	// (所有运行中worker的利用率、队列长度和延迟直方图快照)
+	GetWorkerStats() []WorkerStats
+
+	// Validate payloads before the handler runs, rejected requests are counted per route
+	// (在处理函数执行前校验消息体，按路由统计校验失败的请求数量)
+	AddValidator(msgID uint32, validators ...Validator)
+	SetValidationFailedHandler(handler ValidationFailedHandler)
+	GetValidationFailures(msgID uint32) uint64
+	// Handle the requests denied by RequirePermissions or RequireRoles, e.g. to reply a forbidden error
+	// (处理被RequirePermissions或RequireRoles拒绝的请求，例如回复禁止访问的错误)
+	SetForbiddenHandler(handler ValidationFailedHandler)
+	GetForbiddenCount() uint64
+
+	// Set the hook called after a handler panic is recovered, e.g. PanicReply to reply an error
+	// (设置处理函数panic被恢复后的回调，例如使用PanicReply回复错误信息)
+	SetOnHandlerPanic(handler PanicHandler)
+
 	Group(start, end uint32, Handlers ...RouterHandler) IGroupRouterSlices
+	RouterGroup(Handlers ...RouterHandler) IGroupRouterSlices
 	Use(Handlers ...RouterHandler) IRouterSlices
 
-	StartWorkerPool()                    //  Start the worker pool
+	StartWorkerPool() //  Start the worker pool
+	// StartWorkerPoolContext starts the worker pool, the workers quit once ctx is done
+	// (启动worker池，ctx结束后worker退出)
+	StartWorkerPoolContext(ctx context.Context)
+	// Change the number of workers at runtime, removed workers drain their queue before quitting,
+	// 0 switches to one goroutine per request (运行时调整worker数量，被移除的worker处理完队列后退出，0表示每个请求一个协程)
+	ResizeWorkerPool(size uint32) error
+	GetWorkerPoolSize() uint32
 	SendMsgToTaskQueue(request IRequest) // Pass the message to the TaskQueue for processing by the worker(将消息交给TaskQueue,由worker进行处理)
 
 	Execute(request IRequest) // Execute interceptor methods on the responsibility chain(执行责任链上的拦截器方法)
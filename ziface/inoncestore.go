@@ -0,0 +1,13 @@
+// @Title inoncestore.go
+// @Description Provides interfaces for remembering the nonces of the messages against replays
+package ziface
+
+import "time"
+
+// INonceStore remembers the nonces of the recent messages so that a replayed message is rejected, the nodes
+// behind a load balancer share one to reject the replays on another node
+// (记住最近消息的nonce以拒绝重放的消息，负载均衡后的节点共享同一个存储以拒绝在其他节点上的重放)
+type INonceStore interface {
+	// Add records nonce for ttl, it reports false if nonce is still recorded (记录nonce，有效期为ttl，nonce仍被记录时返回false)
+	Add(nonce string, ttl time.Duration) (bool, error)
+}
@@ -0,0 +1,28 @@
+// @Title iplugin.go
+// @Description Features packaged with their lifecycle, enabled by config
+package ziface
+
+// IPlugin is a feature packaged with its lifecycle, e.g. metrics, auth or rate limiting, enabled by the Plugins of the
+// config or by znet.WithPlugin instead of being wired in main()
+// (带有生命周期的功能包，例如指标、认证或限流，通过配置中的Plugins或znet.WithPlugin开启，而不是在main()中手动组装)
+type IPlugin interface {
+	// Name is the name the plugin is registered and reported with (插件注册与报告时使用的名称)
+	Name() string
+	// Init is called once on the first Start before the workers start, an error fails the Start
+	// (首次Start时在worker启动前调用一次，返回错误时Start失败)
+	Init(server IServer) error
+	// OnStart is called on every Start before the listeners start (每次Start时在监听开始前调用)
+	OnStart(server IServer)
+	// OnStop is called on Stop, in the reverse order of the plugins (Stop时按插件的逆序调用)
+	OnStop(server IServer)
+}
+
+// IRouterPlugin is a plugin registering routers after Init (在Init之后注册路由的插件)
+type IRouterPlugin interface {
+	Routers() map[uint32]IRouter
+}
+
+// IInterceptorPlugin is a plugin adding interceptors after Init, in order (在Init之后按顺序添加拦截器的插件)
+type IInterceptorPlugin interface {
+	Interceptors() []IInterceptor
+}
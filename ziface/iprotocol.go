@@ -0,0 +1,21 @@
+// @Title iprotocol.go
+// @Description Datapack versions selected per connection
+package ziface
+
+// Protocol is one datapack version a server accepts on the same port, the protocol of a connection
+// is picked from the first bytes the peer sends, e.g. v1 firmware and v2 clients side by side.
+// (服务器在同一端口上接受的一种数据包版本，根据对端最先发送的字节为连接选择协议，例如v1固件与v2客户端共存)
+type Protocol struct {
+	Name string
+
+	// Hello is an explicit greeting starting the stream, it is consumed when it matches
+	// (显式的问候字节，出现在数据流开头，匹配后会被去掉)
+	Hello []byte
+
+	// Match sniffs the first bytes when there is no Hello, e.g. a magic number or a version byte
+	// (没有Hello时根据最先读到的字节判断，例如魔数或版本号字节)
+	Match func(head []byte) bool
+
+	Packet  IDataPack // nil keeps the datapack of the server (为nil时使用服务器的数据包)
+	Decoder IDecoder  // nil keeps the decoder of the server (为nil时使用服务器的解码器)
+}
@@ -0,0 +1,35 @@
+// @Title ipubsub.go
+// @Description Provides interfaces for publishing messages to the connections subscribed to topics
+package ziface
+
+// IPubSub manages the topic subscriptions of the connections of a server and fans published messages out to them,
+// the topics are matched as the topic routers do, "+" matches one level and a trailing "*" or "#" all remaining
+// levels (管理服务连接的主题订阅，并将发布的消息扇出给订阅者，主题的匹配方式与主题路由相同，"+"匹配单个层级，末尾的"*"或"#"匹配剩余所有层级)
+type IPubSub interface {
+	// Subscribe subscribes conn to a topic or a topic pattern, the subscriptions are removed when conn closes
+	// (为conn订阅主题或主题模式，连接关闭时订阅会被移除)
+	Subscribe(conn IConnection, topic string) error
+	// Unsubscribe removes a subscription of conn (移除conn的一个订阅)
+	Unsubscribe(conn IConnection, topic string)
+	// UnsubscribeAll removes all the subscriptions of conn (移除conn的所有订阅)
+	UnsubscribeAll(conn IConnection)
+	// Subscriptions returns the topics conn is subscribed to, sorted (返回conn订阅的主题，已排序)
+	Subscriptions(conn IConnection) []string
+
+	// Publish sends the message to every connection subscribed to a topic matching topic, once even when several
+	// of its subscriptions match, it returns the number of connections the message was queued to
+	// (将消息发送给订阅了匹配topic的主题的每个连接，连接有多个订阅匹配时也只发送一次，返回消息成功进入发送队列的连接数)
+	Publish(topic string, msgID uint32, data []byte) int
+
+	// Topics returns the statistics of the subscribed topics, sorted by topic (返回被订阅主题的统计，按主题排序)
+	Topics() []TopicStats
+}
+
+// TopicStats are the statistics of a subscribed topic or topic pattern (被订阅的主题或主题模式的统计)
+type TopicStats struct {
+	Topic       string `json:"topic"`
+	Subscribers int    `json:"subscribers"`
+	Published   uint64 `json:"published"` // Messages published to the topic (发布到该主题的消息数)
+	Delivered   uint64 `json:"delivered"` // Messages queued to the subscribers (进入订阅者发送队列的消息数)
+	Dropped     uint64 `json:"dropped"`   // Messages the subscribers failed to queue (订阅者未能进入发送队列的消息数)
+}
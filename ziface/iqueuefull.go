@@ -0,0 +1,8 @@
+// @Title iqueuefull.go
+// @Description Provides the declarations of the worker queue overflow handling
+package ziface
+
+// QueueFullHandler is called when a request is dropped because the task queue of its worker is full,
+// it runs on the reader goroutine of the connection, the request is recycled afterwards.
+// (请求因worker任务队列已满被丢弃时调用，在连接的读协程中执行，之后请求会被回收)
+type QueueFullHandler func(request IRequest)
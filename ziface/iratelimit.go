@@ -0,0 +1,16 @@
+// @Title iratelimit.go
+// @Description Provides the declarations of the inbound rate limits
+package ziface
+
+// RateLimit limits the inbound messages of a connection, the zero values are unlimited
+// (限制连接的入站消息，零值表示不限制)
+type RateLimit struct {
+	Messages float64 // Messages per second (每秒消息数)
+	Bytes    float64 // Bytes per second, the burst is one second of it (每秒字节数，突发量为一秒的量)
+	Burst    int     // Messages at once above the rate, defaults to one second of Messages (可超出速率一次性发送的消息数，默认为一秒的量)
+}
+
+// RateLimitHandler is called with every message over the rate limits and the number of them on its connection so
+// far, it runs on the reader goroutine of the connection
+// (每条超过速率限制的消息都会调用，violations为其连接至今超限的消息数，在连接的读协程中执行)
+type RateLimitHandler func(request IRequest, violations int)
@@ -0,0 +1,10 @@
+// @Title irecorder.go
+// @Description Provides the interface of the traffic recorders, see zcapture
+package ziface
+
+// IRecorder taps the decoded inbound messages and the outbound messages before they are packed,
+// zcapture.Recorder writes them to a capture file
+// (IRecorder截取解码后收到的消息以及封包前发出的消息，zcapture.Recorder将它们写入抓包文件)
+type IRecorder interface {
+	Record(inbound bool, connID uint64, msgID uint32, data []byte)
+}
@@ -0,0 +1,10 @@
+// @Title irecovery.go
+// @Description Provides the declarations of handler panic recovery
+package ziface
+
+// PanicHandler is called after a handler panic is recovered, err is the value passed to panic
+// and stack is the stack trace of the panicking goroutine. It may reply an error to the client,
+// the worker goroutine and the connection keep running.
+// (处理函数panic被恢复后调用，err为panic的值，stack为发生panic的协程堆栈。
+// 可以在其中向客户端回复错误信息，worker协程和连接都会继续运行)
+type PanicHandler func(request IRequest, err interface{}, stack []byte)
@@ -0,0 +1,21 @@
+// @Title ireply.go
+// @Description Provides the interface of deferred replies
+package ziface
+
+import "context"
+
+// IReply is a one-shot token returned by IRequest.Defer, it can be completed later from any goroutine
+// after the handler has returned and released the worker.
+// (IRequest.Defer返回的一次性回复凭证，处理函数返回并释放worker后，可以在任意协程中完成回复)
+type IReply interface {
+	// Reply sends the reply to the client, it returns an error if called more than once
+	// (向客户端发送回复，重复调用返回错误)
+	Reply(msgID uint32, data []byte) error
+	// GetConnection returns the connection of the deferred request (获取请求所属的连接)
+	GetConnection() IConnection
+	// GetMsgID returns the MsgID of the deferred request (获取请求的MsgID)
+	GetMsgID() uint32
+	// Context is canceled when the connection is closed, the pending work can stop then
+	// (连接关闭时被取消，此时可以停止尚未完成的工作)
+	Context() context.Context
+}
@@ -3,6 +3,11 @@
 // @Author Aceld - Thu Mar 11 10:32:29 CST 2019
 package ziface
 
+import (
+	"context"
+	"time"
+)
+
 type HandleStep int
 
 // IFuncRequest function message interface (函数消息接口)
@@ -55,6 +60,44 @@ type IRequest interface {
 	Set(key string, value interface{})
 	//Get 从 Request 中获取一个上下文信息
 	Get(key string) (value interface{}, exists bool)
+	// Request-scoped values let middleware (auth, tracing) pass the principal, span or tenant to the handler,
+	// they are cleared when the request is recycled and never leak to other messages of the connection
+	// (请求级上下文用于中间件(鉴权、链路追踪)向处理函数传递用户、span或租户信息，
+	// 请求回收时会被清空，不会像连接属性一样泄漏到该连接的其他消息)
+	MustGet(key string) interface{}
+	GetString(key string) string
+	GetInt(key string) int
+	GetBool(key string) bool
+	Delete(key string)
+
+	// GetContext returns the context of the request, it is canceled when the route times out
+	// (获取请求的Context，路由超时时会被取消)
+	GetContext() context.Context
+	// SetContext sets the context of the request (设置请求的Context)
+	SetContext(ctx context.Context)
+
+	// Defer marks the request as deferred and returns a reply token, the handler can return at once
+	// and reply later from another goroutine, e.g. after a DB query or a downstream RPC completes,
+	// without holding the worker. Calling Defer again returns the same token.
+	// (将请求标记为延迟回复并返回回复凭证，处理函数可以立即返回，
+	// 在其他协程中(例如数据库查询或下游RPC完成后)再进行回复，不占用worker。重复调用返回同一个凭证)
+	Defer() IReply
+	// IsDeferred reports whether Defer has been called (是否已调用Defer)
+	IsDeferred() bool
+
+	// Stream returns a writer which sends the response as start/chunk/end frames sharing one stream ID,
+	// the client reassembles them with znet.StreamAssembler
+	// (返回一个流式写入器，将响应拆分为共享同一流ID的start/chunk/end帧发送，客户端通过znet.StreamAssembler重组)
+	Stream() IStreamWriter
+
+	// GetCreateTime returns when the request was read from the connection, used to drop stale queued requests
+	// (获取请求从连接读取的时间，用于丢弃在队列中等待过久的请求)
+	GetCreateTime() time.Time
+
+	// GetTraceID returns the trace ID carried by the datapack, a message without one is given one when the
+	// request is dispatched
+	// (获取数据包携带的链路追踪ID，消息中没有时在请求分发时为其生成一个)
+	GetTraceID() string
 }
 
 type BaseRequest struct{}
@@ -76,3 +119,16 @@ func (br *BaseRequest) Copy() IRequest                   { return nil }
 func (br *BaseRequest) Set(key string, value interface{}) {}
 
 func (br *BaseRequest) Get(key string) (value interface{}, exists bool) { return nil, false }
+func (br *BaseRequest) MustGet(key string) interface{}                  { return nil }
+func (br *BaseRequest) GetString(key string) string                     { return "" }
+func (br *BaseRequest) GetInt(key string) int                           { return 0 }
+func (br *BaseRequest) GetBool(key string) bool                         { return false }
+func (br *BaseRequest) Delete(key string)                               {}
+
+func (br *BaseRequest) GetContext() context.Context    { return context.Background() }
+func (br *BaseRequest) SetContext(ctx context.Context) {}
+func (br *BaseRequest) Defer() IReply                  { return nil }
+func (br *BaseRequest) IsDeferred() bool               { return false }
+func (br *BaseRequest) Stream() IStreamWriter          { return nil }
+func (br *BaseRequest) GetCreateTime() time.Time       { return time.Time{} }
+func (br *BaseRequest) GetTraceID() string             { return "" }
@@ -28,6 +28,21 @@ type IRouterSlices interface {
 	// Add a route (添加业务处理器集合)
 	AddHandler(msgId uint32, handlers ...RouterHandler)
 
+	// Remove or replace a route at runtime (运行时删除或替换路由)
+	RemoveHandler(msgId uint32)
+	ReplaceHandler(msgId uint32, handlers ...RouterHandler)
+
+	// Add a route for a topic or a topic pattern such as "device/+/telemetry"
+	// (为主题或主题模式添加路由)
+	AddTopicHandler(topic string, handlers ...RouterHandler)
+	GetTopicHandlers(topic string) ([]RouterHandler, bool)
+
+	// Add a route for all MsgIDs in [start, end] (为MsgID区间添加业务处理器集合)
+	AddHandlerRange(start, end uint32, handlers ...RouterHandler)
+
+	// Set the handlers of messages that no router matches (设置未匹配任何路由的消息的处理器集合)
+	NotFound(handlers ...RouterHandler)
+
 	// Router group management （路由分组管理，并且会返回一个组管理器）
 	Group(start, end uint32, Handlers ...RouterHandler) IGroupRouterSlices
 
@@ -41,4 +56,11 @@ type IGroupRouterSlices interface {
 
 	// Add group routing components (添加业务处理器集合)
 	AddHandler(MsgId uint32, Handlers ...RouterHandler)
+
+	// Add an IRouter behind the group components (在分组组件之后添加IRouter路由)
+	AddRouter(MsgId uint32, router IRouter)
+
+	// Create a sub group inheriting the components of the current group
+	// (创建一个继承当前分组组件的子分组)
+	Group(Handlers ...RouterHandler) IGroupRouterSlices
 }
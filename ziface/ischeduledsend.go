@@ -0,0 +1,11 @@
+// @Title ischeduledsend.go
+// @Description Handle of a delayed or scheduled send
+package ziface
+
+// IScheduledSend is the handle of a message sent later by SendMsgAfter or SendMsgAt
+// (SendMsgAfter或SendMsgAt稍后发送的消息的句柄)
+type IScheduledSend interface {
+	// Cancel stops the send, it returns false if the message has already been sent or canceled
+	// (取消发送，消息已经发送或已经取消时返回false)
+	Cancel() bool
+}
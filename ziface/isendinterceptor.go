@@ -0,0 +1,10 @@
+// @Title isendinterceptor.go
+// @Description Hooks on outbound messages
+package ziface
+
+// SendInterceptor is called with every outbound message before it is packed, it returns the message
+// to send, msg itself or a replacement, or an error to veto the send, the error is returned to the sender.
+// Framework messages (MsgIDs from 0xFFFFFFF0, e.g. fragments) are not intercepted.
+// (每条发出的消息在封包前都会经过SendInterceptor，返回要发送的消息(msg本身或替换后的消息)，
+// 或者返回错误否决本次发送，该错误会返回给发送方。框架消息(MsgID不小于0xFFFFFFF0，例如分片)不会被拦截)
+type SendInterceptor func(conn IConnection, msg IMessage) (IMessage, error)
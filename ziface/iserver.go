@@ -4,6 +4,7 @@
 package ziface
 
 import (
+	"context"
 	"net/http"
 	"time"
 )
@@ -14,6 +15,13 @@ type IServer interface {
 	Stop()  // Stop the server method (停止服务器方法)
 	Serve() // Start the business service method(开启业务服务方法)
 
+	// StartContext starts the server, which stops once ctx is done (启动服务，ctx结束时服务停止)
+	StartContext(ctx context.Context)
+	// ServeContext runs the server until ctx is done or SIGINT or SIGTERM (运行服务直到ctx结束或收到SIGINT或SIGTERM)
+	ServeContext(ctx context.Context)
+	// Context returns the context of the server, canceled when it stops (返回服务的context，服务停止时取消)
+	Context() context.Context
+
 	// Routing feature: register a routing business method for the current service for client link processing use
 	//(路由功能：给当前服务注册一个路由业务方法，供客户端链接处理使用)
 	AddRouter(msgID uint32, router IRouter)
@@ -21,9 +29,85 @@ type IServer interface {
 	// New version of routing (新版路由方式)
 	AddRouterSlices(msgID uint32, router ...RouterHandler) IRouterSlices
 
+	// Remove or replace a router while the server is running, e.g. hot-swapping plugins
+	// (服务运行期间删除或替换路由，例如热更新插件)
+	RemoveRouter(msgID uint32)
+	ReplaceRouter(msgID uint32, router IRouter)
+	RemoveRouterSlices(msgID uint32)
+	ReplaceRouterSlices(msgID uint32, router ...RouterHandler) IRouterSlices
+
+	// Replace a router once its requests in flight are done on it, onDrained is called then, e.g. to unload the old
+	// version of a plugin (替换路由，正在处理的请求在旧路由上完成后调用onDrained，例如卸载旧版本的插件)
+	ReplaceRouterDrain(msgID uint32, router IRouter, onDrained func(msgID uint32, old IRouter))
+
+	// Register a router for all MsgIDs in [start, end]
+	// (为MsgID区间[start, end]注册路由)
+	AddRouterRange(start, end uint32, router IRouter)
+	AddRouterSlicesRange(start, end uint32, router ...RouterHandler) IRouterSlices
+
+	// Set the router for messages that no router matches
+	// (设置未匹配任何路由的消息的默认路由)
+	SetNotFoundRouter(router IRouter)
+	SetNotFoundRouterSlices(router ...RouterHandler)
+
+	// Register a router for a string topic or a topic pattern, e.g. "device/+/telemetry", "rpc/*"
+	// (为字符串主题或主题模式注册路由)
+	AddTopicRouter(topic string, router IRouter)
+	AddTopicRouterSlices(topic string, router ...RouterHandler) IRouterSlices
+	// Set how the topic of a request is extracted (设置提取请求主题的方法)
+	SetTopicExtractor(extractor TopicExtractor)
+
+	// Limit the duration of the handler of msgID, e.g.
+	// s.SetRouterTimeout(1, 3*time.Second, znet.TimeoutReply(500, []byte("timeout")))
+	// (限制msgID处理函数的执行时长)
+	SetRouterTimeout(msgID uint32, timeout time.Duration, onTimeout ...TimeoutHandler)
+
+	// Messages of one connection are handled in arrival order by the same worker, different connections
+	// run in parallel. SetRouterUnordered lets a stateless route use all workers instead.
+	// (同一连接的消息由同一个worker按到达顺序处理，不同连接并行处理。SetRouterUnordered让无状态路由使用所有worker)
+	SetRouterUnordered(msgID uint32, unordered bool)
+
+	// Bulkheads: run routes on a dedicated worker pool with its own size and queue, e.g.
+	// s.AddWorkerPool("report", 2, 64); s.SetRouterPool(100, "report")
+	// (舱壁隔离：让路由在拥有独立大小和队列的专属worker池中执行)
+	AddWorkerPool(name string, size uint32, queueLen uint32)
+	SetRouterPool(msgID uint32, name string)
+
+	// Overflow handling of full task queues under zconf.QueueFullPolicy, e.g.
+	// s.SetRouterPriority(1, 10); s.SetOnQueueFull(znet.QueueFullReply(503, []byte("busy")))
+	// (任务队列已满时的溢出处理)
+	SetRouterPriority(msgID uint32, priority int)
+	SetOnQueueFull(handler QueueFullHandler)
+
+	// Limit the inbound messages of a route per connection before they reach the workers, e.g.
+	// s.SetRouterRateLimit(1, ziface.RateLimit{Messages: 5}); s.SetOnRateLimited(znet.RateLimitReply(429, []byte("slow down")))
+	// (在入站消息到达worker之前按连接限制路由的消息)
+	SetRouterRateLimit(msgID uint32, limit RateLimit)
+	SetOnRateLimited(handler RateLimitHandler)
+
+	// Validate payloads before the handler runs, e.g.
+	// s.AddValidator(1, znet.ValidateJSON(LoginReq{})); s.SetValidationFailedHandler(znet.ValidationReply(400))
+	// (在处理函数执行前校验消息体)
+	AddValidator(msgID uint32, validators ...Validator)
+	SetValidationFailedHandler(handler ValidationFailedHandler)
+
+	// Authorize the routes by the principal of the connection, e.g.
+	// s.AddValidator(1, znet.RequirePermissions("chat:send")); s.SetForbiddenHandler(znet.ForbiddenReply(403))
+	// (根据连接的身份对路由授权)
+	SetForbiddenHandler(handler ValidationFailedHandler)
+
+	// Set the hook called after a handler panic is recovered, e.g.
+	// s.SetOnHandlerPanic(znet.PanicReply(500, []byte("internal error")))
+	// (设置处理函数panic被恢复后的回调)
+	SetOnHandlerPanic(handler PanicHandler)
+
 	// Route group management (路由组管理)
 	Group(start, end uint32, Handlers ...RouterHandler) IGroupRouterSlices
 
+	// Route group covering all msgIDs, groups can be nested with IGroupRouterSlices.Group
+	// (覆盖全部msgID的路由组，可以通过IGroupRouterSlices.Group嵌套分组)
+	RouterGroup(Handlers ...RouterHandler) IGroupRouterSlices
+
 	// Common component management (公共组件管理)
 	Use(Handlers ...RouterHandler) IRouterSlices
 
@@ -73,6 +157,51 @@ type IServer interface {
 	SetDecoder(IDecoder)
 	AddInterceptor(IInterceptor)
 
+	// Register a datapack version selected per connection from its first bytes, connections matching
+	// no protocol keep the datapack and decoder of the server
+	// (注册一个根据连接最先发送的字节选择的数据包版本，未匹配任何协议的连接使用服务器的数据包和解码器)
+	AddProtocol(protocol Protocol)
+	GetProtocols() []Protocol
+
+	// Add hooks called with every outbound message before it is packed, e.g. to audit or redact it
+	// (添加在每条发出消息封包前调用的钩子，例如审计或脱敏)
+	AddSendInterceptor(interceptors ...SendInterceptor)
+	GetSendInterceptors() []SendInterceptor
+
+	// Tap the decoded inbound and the outbound messages into recorder, such as a zcapture.Recorder, nil stops it
+	// (将解码后收到的消息和发出的消息交给recorder，例如zcapture.Recorder，nil停止截取)
+	SetRecorder(recorder IRecorder)
+	// Tap every decoded inbound message before it is routed into a channel, without blocking, e.g. to mirror the traffic
+	// (在路由之前以非阻塞方式将每条解码后收到的消息交给通道，例如镜像流量)
+	AddMessageTap(buffer int) IMessageTap
+
+	// Drain the selected connections, e.g. for the maintenance of a tenant: send them a grace message, refuse their
+	// new requests and close them once their requests in flight are done, done is closed once they are all closed
+	// (排空选中的连接，例如维护某个租户时：向其发送通知消息，拒绝其新请求，在正在处理的请求完成后关闭连接，全部关闭后关闭done)
+	DrainConns(selector ConnSelector, option DrainOption) (drained int, done <-chan struct{})
+
+	// Get the traffic of the connections and tenants, as of the latest periodic snapshot, see zconf.Config.TrafficInterval
+	// (获取连接与租户的流量，为最近一次定期快照，见zconf.Config.TrafficInterval)
+	GetTrafficSnapshot() TrafficSnapshot
+
+	// Get the liveness and readiness of the server, see zconf.Config.HealthQueueThreshold
+	// (获取服务的存活与就绪状态，见zconf.Config.HealthQueueThreshold)
+	GetHealth() HealthStatus
+
+	// Inject faults into the connections for resilience testing, the zero Faults stops it, see zconf.Config.FaultDropRatio
+	// (向连接注入故障用于韧性测试，零值Faults停止注入，见zconf.Config.FaultDropRatio)
+	SetFaults(faults Faults) error
+	GetFaults() Faults
+	// Simulate the network condition of the connection connID, the zero NetCondition stops it
+	// (模拟连接connID的网络状况，零值NetCondition停止模拟)
+	SetNetCondition(connID uint64, condition NetCondition) error
+	// The network conditions simulated by connID (按connID列出模拟的网络状况)
+	GetNetConditions() map[uint64]NetCondition
+
+	// Get the topic subscriptions of the connections, to publish messages to the subscribers
+	// (获取连接的主题订阅，用于向订阅者发布消息)
+	GetPubSub() IPubSub
+
 	// Add WebSocket authentication method
 	// (添加websocket认证方法)
 	SetWebsocketAuth(func(r *http.Request) error)
@@ -0,0 +1,28 @@
+// @Title isessionstore.go
+// @Description Provides interfaces for keeping the sessions and the presence of the users outside the nodes
+package ziface
+
+import "time"
+
+// ISessionStore keeps the session blobs and the presence of the users outside the nodes, so they survive the
+// restarts of the nodes, a ttl of 0 keeps an entry until it is deleted
+// (在节点之外保存用户的会话数据与在线信息，使其在节点重启后仍然存在，ttl为0时条目一直保留直到被删除)
+type ISessionStore interface {
+	// Get returns the session of key, nil if there is none (返回key的会话，不存在时返回nil)
+	Get(key string) ([]byte, error)
+	// Set stores the session of key for ttl (保存key的会话，有效期为ttl)
+	Set(key string, value []byte, ttl time.Duration) error
+	// Expire sets the ttl of the session of key, it reports false if there is none
+	// (设置key的会话的有效期，不存在时返回false)
+	Expire(key string, ttl time.Duration) (bool, error)
+	// Delete removes the session of key (删除key的会话)
+	Delete(key string) error
+
+	// SetPresence records that userID is on the node nodeID for ttl (记录userID位于节点nodeID，有效期为ttl)
+	SetPresence(userID, nodeID string, ttl time.Duration) error
+	// Presence returns the node of userID, "" if it is offline (返回userID所在的节点，离线时返回"")
+	Presence(userID string) (string, error)
+	// RemovePresence removes the presence of userID if it is still on the node nodeID
+	// (userID仍位于节点nodeID时移除其在线信息)
+	RemovePresence(userID, nodeID string) error
+}
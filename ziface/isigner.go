@@ -0,0 +1,18 @@
+// @Title isigner.go
+// @Description Provides interfaces for signing the messages and verifying their signatures
+package ziface
+
+// IMessageSigner signs the messages with a key and verifies their signatures, see the signature HeaderField
+// (使用密钥对消息签名并校验其签名，见signature头部字段)
+type IMessageSigner interface {
+	// Sign returns the signature of payload, at most 64 bytes (返回payload的签名，最多64字节)
+	Sign(payload []byte) ([]byte, error)
+	// Verify reports whether signature is a valid signature of payload, signature is zero padded to 64 bytes
+	// (判断signature是否为payload的有效签名，signature以0补齐到64字节)
+	Verify(payload, signature []byte) bool
+}
+
+// SignerLookup returns the signer holding the key of conn, e.g. by the tenant of its principal, it is called for
+// every message so it should be cheap, an error or a nil signer rejects the message
+// (返回持有conn密钥的签名器，例如根据其身份的租户查找，每条消息都会调用，应保持廉价，返回错误或nil签名器时拒绝该消息)
+type SignerLookup func(conn IConnection) (IMessageSigner, error)
@@ -0,0 +1,21 @@
+// @Title istream.go
+// @Description Provides the interfaces of chunked response streaming
+package ziface
+
+import "io"
+
+// Stream frame flags, carried in the first byte of every frame payload
+// (流式帧标志位，位于每一帧消息体的第一个字节)
+const (
+	StreamFlagStart byte = 1 << iota // first frame of a stream(流的第一帧)
+	StreamFlagChunk                  // frame carrying data(携带数据的帧)
+	StreamFlagEnd                    // last frame of a stream(流的最后一帧)
+)
+
+// IStreamWriter writes a large response as a sequence of framed messages sharing one stream ID:
+// start, chunks, end. Every Write sends one chunk, Close sends the end frame.
+// (将大的响应以共享同一个流ID的一组帧发送：start、chunk、end。每次Write发送一个chunk，Close发送end帧)
+type IStreamWriter interface {
+	io.WriteCloser
+	StreamID() uint32
+}
@@ -0,0 +1,26 @@
+// @Title itap.go
+// @Description Provides the interface of the raw message taps
+package ziface
+
+import "time"
+
+// TapMessage is a copy of a decoded inbound message handed to the taps (交给截取通道的解码后收到的消息的副本)
+type TapMessage struct {
+	Time   time.Time
+	ConnID uint64
+	MsgID  uint32
+	Data   []byte
+}
+
+// IMessageTap receives every decoded inbound message before it is routed, for the wire-level analytics or for
+// mirroring the traffic to a staging cluster. It never blocks the connections: the messages arriving while C is full
+// are dropped and counted.
+// (在路由之前接收每条解码后收到的消息，用于线路级分析或将流量镜像到预发集群。它不会阻塞连接：C已满时到达的消息被丢弃并计数)
+type IMessageTap interface {
+	// C receives the messages, it is closed by Close (接收消息的通道，Close时关闭)
+	C() <-chan TapMessage
+	// Dropped is the number of messages dropped as C was full (因C已满而丢弃的消息数)
+	Dropped() uint64
+	// Close removes the tap from the server (从服务中移除该截取)
+	Close()
+}
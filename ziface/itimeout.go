@@ -0,0 +1,8 @@
+// @Title itimeout.go
+// @Description Provides the declarations of per-route handler timeouts
+package ziface
+
+// TimeoutHandler is called when a handler exceeds the timeout of its route,
+// it runs on the worker, the request context has already been canceled.
+// (路由处理超时后被调用，在worker中执行，此时请求的Context已经被取消)
+type TimeoutHandler func(request IRequest)
@@ -0,0 +1,20 @@
+// @Title itopic.go
+// @Description Provides interfaces for routing messages by string topics
+package ziface
+
+// TopicKey is the request attribute key a decoder or interceptor can set the topic on,
+// e.g. request.Set(ziface.TopicKey, "device/1001/telemetry")
+// (解码器或拦截器可以通过该Key将主题设置到请求上)
+const TopicKey = "zinx.topic"
+
+// ITopicMessage is implemented by messages which carry a string topic,
+// a datapack can unpack such messages so that they are routed by topic instead of MsgID
+// (携带字符串主题的消息，数据包解包出此类消息后将按主题而不是MsgID路由)
+type ITopicMessage interface {
+	IMessage
+	GetTopic() string
+}
+
+// TopicExtractor extracts the topic of a request, ok is false if the request has no topic
+// (从请求中提取主题，请求没有主题时ok为false)
+type TopicExtractor func(request IRequest) (topic string, ok bool)
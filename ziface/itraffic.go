@@ -0,0 +1,50 @@
+// @Title itraffic.go
+// @Description Provides the snapshot types of the traffic accounting
+package ziface
+
+import "time"
+
+// TrafficStats counts the traffic of a connection or a tenant, bytes are counted on the wire and messages
+// once decoded (统计连接或租户的流量，字节数按线路上的统计，消息数按解码后的统计)
+type TrafficStats struct {
+	BytesIn  uint64 `json:"bytesIn"`
+	BytesOut uint64 `json:"bytesOut"`
+	MsgsIn   uint64 `json:"msgsIn"`
+	MsgsOut  uint64 `json:"msgsOut"`
+}
+
+// Sub returns the traffic from prev to s (返回从prev到s之间的流量)
+func (s TrafficStats) Sub(prev TrafficStats) TrafficStats {
+	return TrafficStats{
+		BytesIn:  s.BytesIn - prev.BytesIn,
+		BytesOut: s.BytesOut - prev.BytesOut,
+		MsgsIn:   s.MsgsIn - prev.MsgsIn,
+		MsgsOut:  s.MsgsOut - prev.MsgsOut,
+	}
+}
+
+// ConnTraffic is the traffic of a connection (连接的流量)
+type ConnTraffic struct {
+	ConnID uint64       `json:"connID"`
+	Tenant string       `json:"tenant,omitempty"`
+	Total  TrafficStats `json:"total"` // Since the connection started (连接开始以来)
+	Delta  TrafficStats `json:"delta"` // Since the previous snapshot (自上一次快照以来)
+}
+
+// TenantTraffic is the traffic of the connections of a tenant, closed connections included
+// (租户所有连接的流量，包括已关闭的连接)
+type TenantTraffic struct {
+	Tenant      string       `json:"tenant"`
+	Connections int          `json:"connections"` // Current connections of the tenant (租户当前的连接数)
+	Total       TrafficStats `json:"total"`
+	Delta       TrafficStats `json:"delta"`
+}
+
+// TrafficSnapshot is the traffic of the connections and tenants of a server at Time
+// (服务的连接与租户在Time时刻的流量)
+type TrafficSnapshot struct {
+	Time        time.Time       `json:"time"`
+	Interval    time.Duration   `json:"interval"` // Since the previous snapshot, 0 when there is none (自上一次快照以来的时长，没有时为0)
+	Connections []ConnTraffic   `json:"connections"`
+	Tenants     []TenantTraffic `json:"tenants"`
+}
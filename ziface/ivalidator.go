@@ -0,0 +1,11 @@
+// @Title ivalidator.go
+// @Description Provides the declarations of per-route payload validation
+package ziface
+
+// Validator checks the payload of a request before the handler runs, a non-nil error rejects the request
+// (在处理函数执行前校验请求的消息体，返回非nil的error表示拒绝该请求)
+type Validator func(request IRequest) error
+
+// ValidationFailedHandler is called when a validator rejects a request, e.g. to reply a standardized error
+// (请求校验失败时调用，例如回复统一格式的错误信息)
+type ValidationFailedHandler func(request IRequest, err error)
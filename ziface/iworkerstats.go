@@ -0,0 +1,26 @@
+// @Title iworkerstats.go
+// @Description Provides the snapshot types of the worker pool instrumentation
+package ziface
+
+import "time"
+
+// HistogramSnapshot is a snapshot of a latency histogram (延迟直方图的快照)
+type HistogramSnapshot struct {
+	Bounds []time.Duration // Upper bounds of the buckets, the last bucket is unbounded (各桶的上界，最后一个桶没有上界)
+	Counts []uint64        // Observations per bucket, len(Bounds)+1 (每个桶的观测次数)
+	Count  uint64          // Number of observations (观测总次数)
+	Sum    time.Duration   // Sum of the observations (观测值总和)
+}
+
+// WorkerStats is a snapshot of the counters of one worker (单个worker的统计快照)
+type WorkerStats struct {
+	Pool      string            // "" for the shared pool, the name given to AddWorkerPool otherwise (共享池为""，否则为AddWorkerPool的名称)
+	WorkerID  int               // ID of the worker inside its pool (worker在池中的ID)
+	QueueLen  int               // Requests waiting for the worker (等待该worker处理的请求数)
+	Handled   uint64            // Requests handled (已处理的请求数)
+	Busy      time.Duration     // Time spent handling requests (处理请求花费的时间)
+	Uptime    time.Duration     // Time since the worker started (worker启动以来的时间)
+	BusyRatio float64           // Busy / Uptime (繁忙比例)
+	WaitTime  HistogramSnapshot // Time from reading a request to the worker taking it (请求从读取到被worker取出的时间)
+	ExecTime  HistogramSnapshot // Time spent handling a request (处理单个请求的时间)
+}
@@ -11,6 +11,9 @@ type Chain struct {
 	req          ziface.IcReq
 	position     int
 	interceptors []ziface.IInterceptor
+	// next is the chain of the following position when allocated along with this one, see NewChains
+	// (与本chain一起分配时为下一位置的chain，见NewChains)
+	next *Chain
 }
 
 func NewChain(list []ziface.IInterceptor, pos int, req ziface.IcReq) ziface.IChain {
@@ -21,13 +24,33 @@ func NewChain(list []ziface.IInterceptor, pos int, req ziface.IcReq) ziface.ICha
 	}
 }
 
+// NewChains is NewChain at position 0 allocating the chains of all the positions at once, one allocation per
+// request instead of one per interceptor (相当于位置0的NewChain，一次分配所有位置的chain，每个请求只分配一次而不是每个拦截器一次)
+func NewChains(list []ziface.IInterceptor, req ziface.IcReq) ziface.IChain {
+	chains := make([]Chain, len(list)+1)
+	for i := range chains {
+		chains[i].position = i
+		chains[i].interceptors = list
+		if i < len(list) {
+			chains[i].next = &chains[i+1]
+		}
+	}
+	chains[0].req = req
+	return &chains[0]
+}
+
 func (c *Chain) Request() ziface.IcReq {
 	return c.req
 }
 
 func (c *Chain) Proceed(request ziface.IcReq) ziface.IcResp {
 	if c.position < len(c.interceptors) {
-		chain := NewChain(c.interceptors, c.position+1, request)
+		chain := c.next
+		if chain != nil {
+			chain.req = request
+		} else {
+			chain = &Chain{req: request, position: c.position + 1, interceptors: c.interceptors}
+		}
 		interceptor := c.interceptors[c.position]
 		response := interceptor.Intercept(chain)
 		return response
@@ -0,0 +1,91 @@
+package zinterceptor
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+// frameErrLog samples the discarded frame lines of the decoder module, a client can send oversized frames at will
+// (解码模块丢弃帧日志的采样器，客户端可以随意发送超长的帧)
+var (
+	frameErrLog = zlog.PerSecond(10)
+	frameLogCtx = zlog.WithModule(context.Background(), zlog.ModuleDecoder)
+)
+
+// DelimiterFrameDecoder splits the received bytes into frames by a delimiter, e.g. newline delimited JSON,
+// the delimiter is stripped. Frames longer than maxFrameLength are discarded up to the next delimiter.
+// (按分隔符将收到的字节流拆分为帧，例如按换行分隔的JSON，分隔符会被去掉。超过maxFrameLength的帧会被丢弃直到下一个分隔符)
+type DelimiterFrameDecoder struct {
+	delimiter      []byte
+	maxFrameLength uint64
+	discarding     bool
+	in             []byte
+	lock           sync.Mutex
+}
+
+func NewDelimiterFrameDecoder(delimiter []byte, maxFrameLength uint64) ziface.IFrameDecoder {
+	return &DelimiterFrameDecoder{
+		delimiter:      append([]byte(nil), delimiter...),
+		maxFrameLength: maxFrameLength,
+	}
+}
+
+func (d *DelimiterFrameDecoder) Decode(buff []byte) [][]byte {
+	frames, _ := d.DecodeFrames(buff)
+	return frames
+}
+
+// DecodeFrames decodes the frames of buff like Decode, it also returns a zerrors.FrameTooLongError after discarding a
+// frame over maxFrameLength (像Decode一样解码buff中的帧，丢弃超过maxFrameLength的帧后还会返回zerrors.FrameTooLongError)
+func (d *DelimiterFrameDecoder) DecodeFrames(buff []byte) ([][]byte, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.in = append(d.in, buff...)
+	resp := make([][]byte, 0)
+
+	var tooLong error
+	for {
+		idx := bytes.Index(d.in, d.delimiter)
+		if idx < 0 {
+			if d.maxFrameLength > 0 && uint64(len(d.in)) > d.maxFrameLength {
+				// Too long frame, discard until the next delimiter (帧过长，丢弃直到下一个分隔符)
+				tooLong = d.discard(len(d.in))
+				d.discarding = true
+				d.in = d.in[:0]
+			}
+			return resp, tooLong
+		}
+
+		frame := d.in[:idx]
+		d.in = d.in[idx+len(d.delimiter):]
+
+		if d.discarding {
+			d.discarding = false
+			continue
+		}
+		if len(frame) == 0 {
+			continue
+		}
+		if d.maxFrameLength > 0 && uint64(len(frame)) > d.maxFrameLength {
+			tooLong = d.discard(len(frame))
+			continue
+		}
+		resp = append(resp, append([]byte(nil), frame...))
+	}
+}
+
+// discard counts and logs a too long frame of length bytes (统计并记录长度为length字节的过长帧)
+func (d *DelimiterFrameDecoder) discard(length int) error {
+	zmetrics.DecodeErrors.Inc()
+	if ctx, ok := frameErrLog.Allow(frameLogCtx); ok {
+		zlog.Ins().ErrorFX(ctx, "delimiter frame exceeds %d bytes - discarded", d.maxFrameLength)
+	}
+	return &zerrors.FrameTooLongError{Length: uint64(length), Max: d.maxFrameLength}
+}
@@ -9,11 +9,15 @@ package zinterceptor
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"sync"
 
+	"github.com/aceld/zinx/zerrors"
 	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
 )
 
 // FrameDecoder
@@ -316,9 +320,14 @@ type FrameDecoder struct {
 	bytesToDiscard         int64 // Records how many bytes still need to be discarded (记录还剩余多少字节需要丢弃)
 	in                     []byte
 	lock                   sync.Mutex
+	// err is the failure of the latest decode, see DecodeFrames (最近一次解码的失败，见DecodeFrames)
+	err error
 }
 
 func NewFrameDecoder(lf ziface.LengthField) ziface.IFrameDecoder {
+	if len(lf.Delimiter) > 0 {
+		return NewDelimiterFrameDecoder(lf.Delimiter, lf.MaxFrameLength)
+	}
 
 	frameDecoder := new(FrameDecoder)
 
@@ -352,14 +361,12 @@ func NewFrameDecoderByParams(maxFrameLength uint64, lengthFieldOffset, lengthFie
 }
 
 func (d *FrameDecoder) fail(frameLength int64) {
-	//丢弃完成或未完成都抛异常
-	//if frameLength > 0 {
-	//	msg := fmt.Sprintf("Adjusted frame length exceeds %d : %d - discarded", this.MaxFrameLength, frameLength)
-	//	panic(msg)
-	//} else {
-	//	msg := fmt.Sprintf("Adjusted frame length exceeds %d - discarded", this.MaxFrameLength)
-	//	panic(msg)
-	//}
+	// The frame is discarded, DecodeFrames reports it (帧被丢弃，由DecodeFrames报告)
+	zmetrics.DecodeErrors.Inc()
+	if ctx, ok := frameErrLog.Allow(frameLogCtx); ok {
+		zlog.Ins().ErrorFX(ctx, "adjusted frame length exceeds %d : %d - discarded", d.MaxFrameLength, frameLength)
+	}
+	d.err = &zerrors.FrameTooLongError{Length: uint64(frameLength), Max: d.MaxFrameLength}
 }
 
 func (d *FrameDecoder) discardingTooLongFrameFunc(buffer *bytes.Buffer) {
@@ -387,22 +394,17 @@ func (d *FrameDecoder) getUnadjustedFrameLength(buf *bytes.Buffer, offset int, l
 	// Value of the length field (长度字段的值)
 	var frameLength int64
 
+	// Read straight from the bytes, binary.Read would allocate for every frame (直接从字节读取，binary.Read每帧都会分配内存)
 	arr := buf.Bytes()
 	arr = arr[offset : offset+length]
 
-	buffer := bytes.NewBuffer(arr)
-
 	switch length {
 	case 1:
 		//byte
-		var value uint8
-		binary.Read(buffer, order, &value)
-		frameLength = int64(value)
+		frameLength = int64(arr[0])
 	case 2:
 		//short
-		var value uint16
-		binary.Read(buffer, order, &value)
-		frameLength = int64(value)
+		frameLength = int64(order.Uint16(arr))
 	case 3:
 		// int occupies 32 bits, here take out the last 24 bits and return as int type
 		// (int占32位，这里取出后24位，返回int类型)
@@ -415,12 +417,10 @@ func (d *FrameDecoder) getUnadjustedFrameLength(buf *bytes.Buffer, offset int, l
 		}
 	case 4:
 		//int
-		var value uint32
-		binary.Read(buffer, order, &value)
-		frameLength = int64(value)
+		frameLength = int64(order.Uint32(arr))
 	case 8:
 		//long
-		binary.Read(buffer, order, &frameLength)
+		frameLength = int64(order.Uint64(arr))
 	default:
 		panic(fmt.Sprintf("unsupported LengthFieldLength: %d (expected: 1, 2, 3, 4, or 8)", d.LengthFieldLength))
 	}
@@ -429,7 +429,7 @@ func (d *FrameDecoder) getUnadjustedFrameLength(buf *bytes.Buffer, offset int, l
 
 func (d *FrameDecoder) failOnNegativeLengthField(in *bytes.Buffer, frameLength int64, lengthFieldEndOffset int) {
 	in.Next(lengthFieldEndOffset)
-	panic(fmt.Sprintf("negative pre-adjustment length field: %d", frameLength))
+	d.err = zerrors.NewDecodeError(fmt.Sprintf("negative pre-adjustment length field: %d", frameLength))
 }
 
 func (d *FrameDecoder) failIfNecessary(firstDetectionOfTooLongFrame bool) {
@@ -491,11 +491,14 @@ func (d *FrameDecoder) exceededFrameLength(in *bytes.Buffer, frameLength int64)
 
 func (d *FrameDecoder) failOnFrameLengthLessThanInitialBytesToStrip(in *bytes.Buffer, frameLength int64, initialBytesToStrip int) {
 	in.Next(int(frameLength))
-	panic(fmt.Sprintf("Adjusted frame length (%d) is less  than InitialBytesToStrip: %d", frameLength, initialBytesToStrip))
+	d.err = zerrors.NewDecodeError(fmt.Sprintf("Adjusted frame length (%d) is less  than InitialBytesToStrip: %d", frameLength, initialBytesToStrip))
 }
 
-func (d *FrameDecoder) decode(buf []byte) []byte {
+// decode returns the next frame of buf, nil if there is none yet, and the number of bytes of buf consumed
+// (返回buf中的下一帧，尚无完整帧时返回nil，以及buf中被消费的字节数)
+func (d *FrameDecoder) decode(buf []byte) ([]byte, int) {
 	in := bytes.NewBuffer(buf)
+	consumed := func() int { return len(buf) - in.Len() }
 
 	// Determine if it is in discard mode (判断是否为丢弃模式)
 	if d.discardingTooLongFrame {
@@ -507,7 +510,7 @@ func (d *FrameDecoder) decode(buf []byte) []byte {
 	if in.Len() < d.LengthFieldEndOffset {
 		// Indicates that the length field packets are incomplete, half package
 		// (说明长度字段的包都还不完整，半包)
-		return nil
+		return nil, consumed()
 	}
 
 	// --> If execution reaches here, it means that the value of the length field can be parsed <--
@@ -527,6 +530,7 @@ func (d *FrameDecoder) decode(buf []byte) []byte {
 		// It will skip the number of bytes of this data packet and throw an exception
 		// (内部会跳过这个数据包的字节数，并抛异常)
 		d.failOnNegativeLengthField(in, frameLength, d.LengthFieldEndOffset)
+		return nil, consumed()
 	}
 
 	// Apply the formula: Number of bytes after the length field = value of the length field + lengthAdjustment (应用公式:长度字段后的字节数=长度字段的值+长度调整值)
@@ -541,7 +545,7 @@ func (d *FrameDecoder) decode(buf []byte) []byte {
 		// It has exceeded the maximum length of a single data frame, and the exceeded part is processed
 		// (已经超过单次数据帧最大长度，对超过的部分进行处理)
 		d.exceededFrameLength(in, frameLength)
-		return nil
+		return nil, consumed()
 	}
 
 	// --> If execution reaches here, it means normal mode <--
@@ -552,7 +556,7 @@ func (d *FrameDecoder) decode(buf []byte) []byte {
 	// Determine if the number of readable bytes in the buffer is less than the size of the data packet (判断缓冲区可读字节数是否小于数据包的字节数)
 	if in.Len() < frameLengthInt {
 		// Half package, will parse again later (半包，等会再来解析)
-		return nil
+		return nil, consumed()
 	}
 
 	// --> If execution reaches here, it means that the buffer already contains the entire data packet <--
@@ -562,6 +566,7 @@ func (d *FrameDecoder) decode(buf []byte) []byte {
 	if d.InitialBytesToStrip > frameLengthInt {
 		// Will throw an exception if the length of the data packet is less than the number of bytes to be skipped (如果数据包长度小于跳过的字节数，将抛出异常)
 		d.failOnFrameLengthLessThanInitialBytesToStrip(in, frameLength, d.InitialBytesToStrip)
+		return nil, consumed()
 	}
 
 	// Skip the initialBytesToStrip bytes (跳过initialBytesToStrip个字节)
@@ -575,29 +580,68 @@ func (d *FrameDecoder) decode(buf []byte) []byte {
 	buff := make([]byte, actualFrameLength)
 	_, _ = in.Read(buff)
 
-	return buff
+	return buff, consumed()
 }
 
 func (d *FrameDecoder) Decode(buff []byte) [][]byte {
+	frames, _ := d.DecodeFrames(buff)
+	return frames
+}
+
+// DecodeFrames decodes the frames of buff like Decode, it also returns a zerrors.FrameTooLongError after discarding a
+// frame over MaxFrameLength, or a zerrors.DecodeError when the stream cannot be decoded any longer.
+// The returned slice and its frames belong to the caller, they are not reused by the next call.
+// (像Decode一样解码buff中的帧，丢弃超过MaxFrameLength的帧后还会返回zerrors.FrameTooLongError，
+// 数据流无法继续解码时返回zerrors.DecodeError。返回的切片及其中的帧归调用方所有，不会被下一次调用复用)
+func (d *FrameDecoder) DecodeFrames(buff []byte) ([][]byte, error) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	d.in = append(d.in, buff...)
-	resp := make([][]byte, 0)
+	in := append(d.in, buff...)
+	var off int
+	defer func() { d.in = compactBuffer(in, off) }()
+
+	var resp [][]byte
 
+	var tooLong error
 	for {
-		arr := d.decode(d.in)
-
-		if arr != nil {
-			// Indicates that a complete packet has been parsed
-			// (证明已经解析出一个完整包)
-			resp = append(resp, arr)
-			_size := len(arr) + d.InitialBytesToStrip
-			if _size > 0 {
-				d.in = d.in[_size:]
+		arr, consumed := d.decode(in[off:])
+		off += consumed
+		if err := d.err; err != nil {
+			d.err = nil
+			if !errors.Is(err, zerrors.ErrFrameTooLong) {
+				return resp, err
 			}
-		} else {
-			return resp
+			// The frames after the discarded one are still decoded (被丢弃的帧之后的帧仍会被解码)
+			tooLong = err
+		}
+		if arr == nil {
+			// Bytes were discarded, the next frame may follow them (有字节被丢弃，其后可能还有下一帧)
+			if consumed > 0 && off < len(in) {
+				continue
+			}
+			return resp, tooLong
+		}
+		// Indicates that a complete packet has been parsed
+		// (证明已经解析出一个完整包)
+		resp = append(resp, arr)
+	}
+}
+
+// retainedBufferSize is the capacity the buffer of undecoded bytes keeps at least (未解码字节缓冲至少保留的容量)
+const retainedBufferSize = 4096
+
+// compactBuffer moves the undecoded bytes of in from off to the front so that the buffer is reused, a buffer grown by
+// a burst far beyond them is reallocated so that the connection does not pin its peak memory
+// (将in中从off开始的未解码字节移到开头以便复用缓冲区，突发数据使缓冲远大于这些字节时重新分配，连接不会一直占用其峰值内存)
+func compactBuffer(in []byte, off int) []byte {
+	rest := in[off:]
+	if cap(in) > retainedBufferSize && len(rest)*4 < cap(in) {
+		size := retainedBufferSize
+		if len(rest) > size {
+			size = len(rest)
 		}
+		return append(make([]byte, 0, size), rest...)
 	}
+	return in[:copy(in, rest)]
 }
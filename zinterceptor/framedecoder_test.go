@@ -0,0 +1,36 @@
+package zinterceptor
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameDecoderBuffers(t *testing.T) {
+	frame := func(size int) []byte {
+		buf := make([]byte, 4, 4+size)
+		binary.BigEndian.PutUint32(buf, uint32(size))
+		return append(buf, make([]byte, size)...)
+	}
+	d := NewFrameDecoder(ziface.LengthField{MaxFrameLength: 1 << 20, LengthFieldLength: 4}).(*FrameDecoder)
+
+	// The frames returned belong to the caller (返回的帧归调用方所有)
+	first, err := d.DecodeFrames(frame(1))
+	assert.Nil(t, err)
+	second, err := d.DecodeFrames(append(frame(2), frame(3)...))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{frame(1)}, first)
+	assert.Equal(t, [][]byte{frame(2), frame(3)}, second)
+
+	// A burst does not pin its memory once decoded (突发数据解码后不再占用其内存)
+	burst := frame(512 * 1024)
+	frames, _ := d.DecodeFrames(burst[:len(burst)-1])
+	assert.Empty(t, frames)
+	assert.True(t, cap(d.in) >= len(burst)-1)
+	frames, _ = d.DecodeFrames(append(burst[len(burst)-1:], frame(8)[:2]...))
+	assert.Len(t, frames, 1)
+	assert.Len(t, d.in, 2)
+	assert.Equal(t, retainedBufferSize, cap(d.in))
+}
@@ -0,0 +1,137 @@
+package zlog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// leveledLogger lets an ziface.ILeveledLogger serve as the ziface.ILogger behind Ins(),
+// the fields carried by the ctx of the context variants are passed on, and lines below the level of their
+// module are dropped, the backend's own level decides for the others
+// (使ziface.ILeveledLogger作为Ins()背后的ziface.ILogger，context版本中ctx携带的字段会一并传递，
+// 低于所属子系统级别的日志被丢弃，其余由日志库自身的级别决定)
+type leveledLogger struct {
+	l ziface.ILeveledLogger
+}
+
+func (log *leveledLogger) InfoF(format string, v ...interface{}) {
+	log.l.Info(fmt.Sprintf(format, v...))
+}
+
+func (log *leveledLogger) ErrorF(format string, v ...interface{}) {
+	log.l.Error(fmt.Sprintf(format, v...))
+}
+
+func (log *leveledLogger) DebugF(format string, v ...interface{}) {
+	log.l.Debug(fmt.Sprintf(format, v...))
+}
+
+func (log *leveledLogger) InfoFX(ctx context.Context, format string, v ...interface{}) {
+	fields := FieldsFromContext(ctx)
+	if level, ok := levelOf(fields); ok && level > LogInfo {
+		return
+	}
+	log.l.Info(fmt.Sprintf(format, v...), fields...)
+}
+
+func (log *leveledLogger) ErrorFX(ctx context.Context, format string, v ...interface{}) {
+	fields := FieldsFromContext(ctx)
+	if level, ok := levelOf(fields); ok && level > LogError {
+		return
+	}
+	log.l.Error(fmt.Sprintf(format, v...), fields...)
+}
+
+func (log *leveledLogger) DebugFX(ctx context.Context, format string, v ...interface{}) {
+	fields := FieldsFromContext(ctx)
+	if level, ok := levelOf(fields); ok && level > LogDebug {
+		return
+	}
+	log.l.Debug(fmt.Sprintf(format, v...), fields...)
+}
+
+// SetLeveledLogger routes every framework log line to l, see NewZapLogger, NewLogrusLogger and NewSlogLogger
+// (将框架的所有日志输出到l，参见NewZapLogger、NewLogrusLogger和NewSlogLogger)
+func SetLeveledLogger(l ziface.ILeveledLogger) {
+	if ll, ok := l.(ziface.ILogger); ok {
+		SetLogger(ll)
+		return
+	}
+	SetLogger(&leveledLogger{l: l})
+}
+
+// SugaredLogger is the method set of *zap.SugaredLogger used by NewZapLogger
+// (NewZapLogger所使用的*zap.SugaredLogger方法集)
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+type zapLogger struct {
+	l SugaredLogger
+}
+
+// NewZapLogger adapts a zap logger, pass zapLogger.Sugar(), zinx does not depend on zap itself
+// (适配zap日志，传入zapLogger.Sugar()即可，zinx本身不依赖zap)
+func NewZapLogger(l SugaredLogger) ziface.ILeveledLogger {
+	return &zapLogger{l: l}
+}
+
+func (z *zapLogger) Debug(msg string, fields ...Field) { z.l.Debugw(msg, keysAndValues(fields)...) }
+
+func (z *zapLogger) Info(msg string, fields ...Field) { z.l.Infow(msg, keysAndValues(fields)...) }
+
+func (z *zapLogger) Warn(msg string, fields ...Field) { z.l.Warnw(msg, keysAndValues(fields)...) }
+
+func (z *zapLogger) Error(msg string, fields ...Field) { z.l.Errorw(msg, keysAndValues(fields)...) }
+
+func keysAndValues(fields []Field) []interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+	kv := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		kv = append(kv, f.Key, f.Value)
+	}
+	return kv
+}
+
+// LogrusEntry is the method set of *logrus.Entry used by NewLogrusLogger
+// (NewLogrusLogger所使用的*logrus.Entry方法集)
+type LogrusEntry[E any] interface {
+	WithField(key string, value interface{}) E
+	Debug(args ...interface{})
+	Info(args ...interface{})
+	Warn(args ...interface{})
+	Error(args ...interface{})
+}
+
+type logrusLogger[E LogrusEntry[E]] struct {
+	entry E
+}
+
+// NewLogrusLogger adapts a logrus logger, pass logrus.NewEntry(logger), zinx does not depend on logrus itself
+// (适配logrus日志，传入logrus.NewEntry(logger)即可，zinx本身不依赖logrus)
+func NewLogrusLogger[E LogrusEntry[E]](entry E) ziface.ILeveledLogger {
+	return &logrusLogger[E]{entry: entry}
+}
+
+func (l *logrusLogger[E]) with(fields []Field) E {
+	e := l.entry
+	for _, f := range fields {
+		e = e.WithField(f.Key, f.Value)
+	}
+	return e
+}
+
+func (l *logrusLogger[E]) Debug(msg string, fields ...Field) { l.with(fields).Debug(msg) }
+
+func (l *logrusLogger[E]) Info(msg string, fields ...Field) { l.with(fields).Info(msg) }
+
+func (l *logrusLogger[E]) Warn(msg string, fields ...Field) { l.with(fields).Warn(msg) }
+
+func (l *logrusLogger[E]) Error(msg string, fields ...Field) { l.with(fields).Error(msg) }
@@ -0,0 +1,35 @@
+//go:build go1.21
+
+package zlog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger adapts a log/slog logger (适配log/slog日志)
+func NewSlogLogger(l *slog.Logger) ziface.ILeveledLogger {
+	return &slogLogger{l: l}
+}
+
+func (s *slogLogger) log(level slog.Level, msg string, fields []Field) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	s.l.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+func (s *slogLogger) Debug(msg string, fields ...Field) { s.log(slog.LevelDebug, msg, fields) }
+
+func (s *slogLogger) Info(msg string, fields ...Field) { s.log(slog.LevelInfo, msg, fields) }
+
+func (s *slogLogger) Warn(msg string, fields ...Field) { s.log(slog.LevelWarn, msg, fields) }
+
+func (s *slogLogger) Error(msg string, fields ...Field) { s.log(slog.LevelError, msg, fields) }
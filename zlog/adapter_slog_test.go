@@ -0,0 +1,26 @@
+//go:build go1.21
+
+package zlog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/aceld/zinx/zlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := zlog.NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	l.Warn("queue full", zlog.F(zlog.FieldConnID, 1))
+	assert.Equal(t, "level=WARN msg=\"queue full\" connID=1\n", buf.String())
+}
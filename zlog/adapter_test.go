@@ -0,0 +1,62 @@
+package zlog_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aceld/zinx/zlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type sugared struct {
+	lines []string
+}
+
+func (s *sugared) add(level, msg string, kv []interface{}) {
+	s.lines = append(s.lines, fmt.Sprint(level, " ", msg, " ", kv))
+}
+
+func (s *sugared) Debugw(msg string, kv ...interface{}) { s.add("debug", msg, kv) }
+func (s *sugared) Infow(msg string, kv ...interface{})  { s.add("info", msg, kv) }
+func (s *sugared) Warnw(msg string, kv ...interface{})  { s.add("warn", msg, kv) }
+func (s *sugared) Errorw(msg string, kv ...interface{}) { s.add("error", msg, kv) }
+
+type entry struct {
+	fields map[string]interface{}
+	lines  *[]string
+}
+
+func (e *entry) WithField(key string, value interface{}) *entry {
+	fields := map[string]interface{}{key: value}
+	for k, v := range e.fields {
+		fields[k] = v
+	}
+	return &entry{fields: fields, lines: e.lines}
+}
+
+func (e *entry) add(level string, args []interface{}) {
+	*e.lines = append(*e.lines, fmt.Sprint(level, " ", fmt.Sprint(args...), " ", e.fields))
+}
+
+func (e *entry) Debug(args ...interface{}) { e.add("debug", args) }
+func (e *entry) Info(args ...interface{})  { e.add("info", args) }
+func (e *entry) Warn(args ...interface{})  { e.add("warn", args) }
+func (e *entry) Error(args ...interface{}) { e.add("error", args) }
+
+func TestLeveledLogger(t *testing.T) {
+	defer zlog.SetLogger(zlog.Ins())
+
+	s := &sugared{}
+	zlog.SetLeveledLogger(zlog.NewZapLogger(s))
+	zlog.Ins().InfoF("start %d", 1)
+	zlog.Ins().ErrorFX(zlog.WithFields(context.Background(), zlog.F(zlog.FieldConnID, 2)), "closed")
+	assert.Equal(t, []string{"info start 1 []", "error closed [connID 2]"}, s.lines)
+
+	var lines []string
+	l := zlog.NewLogrusLogger(&entry{lines: &lines})
+	zlog.SetLeveledLogger(l)
+	zlog.Ins().DebugFX(zlog.WithFields(context.Background(), zlog.F(zlog.FieldMsgID, 3)), "recv")
+	l.Warn("slow", zlog.F("cost", "2s"))
+	assert.Equal(t, []string{"debug recv map[msgID:3]", "warn slow map[cost:2s]"}, lines)
+}
@@ -9,33 +9,65 @@ import (
 
 var zLogInstance ziface.ILogger = new(zinxDefaultLog)
 
-type zinxDefaultLog struct{}
+// zinxDefaultLog writes to its core, StdZinxLog if it has none (写入自身的core，没有时写入StdZinxLog)
+type zinxDefaultLog struct {
+	core *ZinxLoggerCore
+}
+
+// New returns a logger writing to core instead of StdZinxLog, e.g. for a server logging into its own file at its
+// own level (返回写入core而不是StdZinxLog的日志，例如用于以自身级别写入自身文件的服务)
+func New(core *ZinxLoggerCore) ziface.ILogger {
+	return &zinxDefaultLog{core: core}
+}
+
+func (log *zinxDefaultLog) std() *ZinxLoggerCore {
+	if log.core != nil {
+		return log.core
+	}
+	return StdZinxLog
+}
 
 func (log *zinxDefaultLog) InfoF(format string, v ...interface{}) {
-	StdZinxLog.Infof(format, v...)
+	log.std().Infof(format, v...)
 }
 
 func (log *zinxDefaultLog) ErrorF(format string, v ...interface{}) {
-	StdZinxLog.Errorf(format, v...)
+	log.std().Errorf(format, v...)
 }
 
 func (log *zinxDefaultLog) DebugF(format string, v ...interface{}) {
-	StdZinxLog.Debugf(format, v...)
+	log.std().Debugf(format, v...)
 }
 
+// The context variants append the fields carried by ctx, see WithFields (context版本会追加ctx携带的字段，见WithFields)
 func (log *zinxDefaultLog) InfoFX(ctx context.Context, format string, v ...interface{}) {
-	fmt.Println(ctx)
-	StdZinxLog.Infof(format, v...)
+	log.std().Infow(fmt.Sprintf(format, v...), FieldsFromContext(ctx)...)
 }
 
 func (log *zinxDefaultLog) ErrorFX(ctx context.Context, format string, v ...interface{}) {
-	fmt.Println(ctx)
-	StdZinxLog.Errorf(format, v...)
+	log.std().Errorw(fmt.Sprintf(format, v...), FieldsFromContext(ctx)...)
 }
 
 func (log *zinxDefaultLog) DebugFX(ctx context.Context, format string, v ...interface{}) {
-	fmt.Println(ctx)
-	StdZinxLog.Debugf(format, v...)
+	log.std().Debugw(fmt.Sprintf(format, v...), FieldsFromContext(ctx)...)
+}
+
+// Debug, Info, Warn and Error make the default logger an ziface.ILeveledLogger
+// (Debug、Info、Warn和Error使默认日志实现ziface.ILeveledLogger)
+func (log *zinxDefaultLog) Debug(msg string, fields ...Field) {
+	log.std().Debugw(msg, fields...)
+}
+
+func (log *zinxDefaultLog) Info(msg string, fields ...Field) {
+	log.std().Infow(msg, fields...)
+}
+
+func (log *zinxDefaultLog) Warn(msg string, fields ...Field) {
+	log.std().Warnw(msg, fields...)
+}
+
+func (log *zinxDefaultLog) Error(msg string, fields ...Field) {
+	log.std().Errorw(msg, fields...)
 }
 
 func SetLogger(newlog ziface.ILogger) {
@@ -0,0 +1,115 @@
+package zlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// Standard fields attached by the framework to the log lines of connections and requests
+// (框架为连接和请求的日志自动附加的标准字段)
+const (
+	FieldConnID     = "connID"
+	FieldRemoteAddr = "remoteAddr"
+	FieldMsgID      = "msgID"
+	FieldTraceID    = "traceID"
+)
+
+// Field is a key/value pair of a structured log line (结构化日志中的一个键值对)
+type Field = ziface.LogField
+
+// F creates a Field (创建一个Field)
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// ConnFields returns the standard fields of conn (返回conn的标准字段)
+func ConnFields(conn ziface.IConnection) []Field {
+	if conn == nil {
+		return nil
+	}
+	return []Field{F(FieldConnID, conn.GetConnID()), F(FieldRemoteAddr, conn.RemoteAddrString())}
+}
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx carrying fields in addition to those already carried,
+// the context variants of the log methods (InfoFX and so on) append them to the line
+// (返回附加了fields的ctx副本，日志方法的context版本(InfoFX等)会将它们追加到日志中)
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := FieldsFromContext(ctx)
+	all := make([]Field, 0, len(parent)+len(fields))
+	all = append(append(all, parent...), fields...)
+	return context.WithValue(ctx, fieldsKey{}, all)
+}
+
+// FieldsFromContext returns the fields carried by ctx (返回ctx携带的字段)
+func FieldsFromContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+	return fields
+}
+
+// writeTextFields appends the fields as " key=value" (以" key=value"形式追加字段)
+func writeTextFields(buf *bytes.Buffer, fields []Field) {
+	for _, f := range fields {
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		switch v := f.Value.(type) {
+		case string:
+			buf.WriteString(v)
+		case error:
+			buf.WriteString(v.Error())
+		default:
+			fmt.Fprint(buf, v)
+		}
+	}
+}
+
+// writeJSON writes one JSON log line, the fields follow the standard keys time, level, caller, prefix and msg
+// (写入一行JSON日志，字段位于time、level、caller、prefix和msg这些标准键之后)
+func writeJSON(buf *bytes.Buffer, t time.Time, level int, caller string, prefix, msg string, fields []Field) {
+	buf.WriteString(`{"time":`)
+	buf.WriteString(strconv.Quote(t.Format(time.RFC3339Nano)))
+	buf.WriteString(`,"level":`)
+	buf.WriteString(strconv.Quote(levelNames[level]))
+	if caller != "" {
+		buf.WriteString(`,"caller":`)
+		writeJSONValue(buf, caller)
+	}
+	if prefix != "" {
+		buf.WriteString(`,"prefix":`)
+		writeJSONValue(buf, prefix)
+	}
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(buf, msg)
+	for _, f := range fields {
+		buf.WriteByte(',')
+		writeJSONValue(buf, f.Key)
+		buf.WriteByte(':')
+		writeJSONValue(buf, f.Value)
+	}
+	buf.WriteString("}\n")
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(data)
+}
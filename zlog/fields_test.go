@@ -0,0 +1,37 @@
+package zlog_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aceld/zinx/zlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogFields(t *testing.T) {
+	log := zlog.NewZinxLog("", zlog.BitDefault)
+	var line string
+	log.SetLogHook(func(b []byte) { line = string(b) })
+
+	log.Infow("conn started", zlog.F(zlog.FieldConnID, uint64(7)), zlog.F(zlog.FieldRemoteAddr, "127.0.0.1:9000"))
+	assert.True(t, strings.HasSuffix(line, "conn started connID=7 remoteAddr=127.0.0.1:9000\n"), line)
+
+	log.SetJSON(true)
+	log.Errorw("not found", zlog.F(zlog.FieldMsgID, uint32(3)), zlog.F("err", assert.AnError))
+	var obj map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(line), &obj))
+	assert.Equal(t, "error", obj["level"])
+	assert.Equal(t, "not found", obj["msg"])
+	assert.Equal(t, float64(3), obj[zlog.FieldMsgID])
+	assert.Equal(t, assert.AnError.Error(), obj["err"])
+	assert.Contains(t, obj["caller"], "fields_test.go")
+}
+
+func TestWithFields(t *testing.T) {
+	ctx := zlog.WithFields(context.Background(), zlog.F(zlog.FieldConnID, 1))
+	ctx = zlog.WithFields(ctx, zlog.F(zlog.FieldTraceID, "abc"))
+	assert.Equal(t, []zlog.Field{zlog.F(zlog.FieldConnID, 1), zlog.F(zlog.FieldTraceID, "abc")}, zlog.FieldsFromContext(ctx))
+	assert.Nil(t, zlog.FieldsFromContext(context.Background()))
+}
@@ -0,0 +1,181 @@
+package zlog
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// Subsystems of the framework whose log level can be set apart, see SetModuleLevel
+// (可单独设置日志级别的框架子系统，见SetModuleLevel)
+const (
+	ModuleDecoder    = "decoder"
+	ModuleHeartbeat  = "heartbeat"
+	ModuleDispatcher = "dispatcher"
+	ModuleAudit      = "audit"
+)
+
+// FieldModule is the field naming the subsystem of a log line (标明日志所属子系统的字段)
+const FieldModule = "module"
+
+// levelOverrides holds the module levels and the connections with debug logging enabled,
+// active counts both so that lines without overrides skip the lookup
+// (保存子系统级别以及开启了调试日志的连接，active为两者的总数，没有设置时跳过查找)
+var levelOverrides = struct {
+	sync.RWMutex
+	modules map[string]int
+	conns   map[uint64]time.Time
+	active  int32
+}{
+	modules: make(map[string]int),
+	conns:   make(map[uint64]time.Time),
+}
+
+// WithModule returns a copy of ctx whose log lines belong to module (返回日志属于module的ctx副本)
+func WithModule(ctx context.Context, module string) context.Context {
+	return WithFields(ctx, F(FieldModule, module))
+}
+
+// SetModuleLevel sets the isolation level of the log lines of module, such as ModuleDecoder,
+// it takes precedence over SetLogLevel for them, e.g. SetModuleLevel(ModuleHeartbeat, LogError)
+// (设置module日志的隔离级别，优先于SetLogLevel，如SetModuleLevel(ModuleHeartbeat, LogError))
+func SetModuleLevel(module string, level int) {
+	levelOverrides.Lock()
+	defer levelOverrides.Unlock()
+	if _, ok := levelOverrides.modules[module]; !ok {
+		atomic.AddInt32(&levelOverrides.active, 1)
+	}
+	levelOverrides.modules[module] = level
+}
+
+// ResetModuleLevel makes module follow the global level again (使module重新遵循全局级别)
+func ResetModuleLevel(module string) {
+	levelOverrides.Lock()
+	defer levelOverrides.Unlock()
+	if _, ok := levelOverrides.modules[module]; ok {
+		delete(levelOverrides.modules, module)
+		atomic.AddInt32(&levelOverrides.active, -1)
+	}
+}
+
+// ModuleLevels returns the modules with their own level (返回设置了单独级别的子系统)
+func ModuleLevels() map[string]int {
+	levelOverrides.RLock()
+	defer levelOverrides.RUnlock()
+	levels := make(map[string]int, len(levelOverrides.modules))
+	for module, level := range levelOverrides.modules {
+		levels[module] = level
+	}
+	return levels
+}
+
+// EnableConnDebug logs every line of the connection connID, debug included, for the duration d,
+// whatever the global and module levels are
+// (在d时间内输出连接connID的所有日志(包括debug)，不受全局和子系统级别限制)
+func EnableConnDebug(connID uint64, d time.Duration) {
+	levelOverrides.Lock()
+	defer levelOverrides.Unlock()
+	if _, ok := levelOverrides.conns[connID]; !ok {
+		atomic.AddInt32(&levelOverrides.active, 1)
+	}
+	levelOverrides.conns[connID] = time.Now().Add(d)
+}
+
+// DisableConnDebug stops the debug logging of the connection connID (停止连接connID的调试日志)
+func DisableConnDebug(connID uint64) {
+	levelOverrides.Lock()
+	defer levelOverrides.Unlock()
+	if _, ok := levelOverrides.conns[connID]; ok {
+		delete(levelOverrides.conns, connID)
+		atomic.AddInt32(&levelOverrides.active, -1)
+	}
+}
+
+// DebugConns returns the connections with debug logging enabled and when it ends
+// (返回开启了调试日志的连接及其结束时间)
+func DebugConns() map[uint64]time.Time {
+	levelOverrides.RLock()
+	defer levelOverrides.RUnlock()
+	conns := make(map[uint64]time.Time, len(levelOverrides.conns))
+	for connID, until := range levelOverrides.conns {
+		if time.Now().Before(until) {
+			conns[connID] = until
+		}
+	}
+	return conns
+}
+
+// levelOf returns the isolation level that applies to a line with fields: 0 when its connection has debug logging
+// enabled, else the level of its module, ok is false when neither applies and the global level is used
+// (返回带fields的日志适用的隔离级别：连接开启调试日志时为0，否则为其子系统的级别，都不适用时ok为false，使用全局级别)
+func levelOf(fields []Field) (level int, ok bool) {
+	if len(fields) == 0 || atomic.LoadInt32(&levelOverrides.active) == 0 {
+		return 0, false
+	}
+	var module string
+	var connID uint64
+	var hasConn bool
+	for _, f := range fields {
+		switch f.Key {
+		case FieldModule:
+			module, _ = f.Value.(string)
+		case FieldConnID:
+			connID, hasConn = f.Value.(uint64)
+		}
+	}
+
+	levelOverrides.RLock()
+	if hasConn {
+		if until, found := levelOverrides.conns[connID]; found {
+			if time.Now().Before(until) {
+				levelOverrides.RUnlock()
+				return LogDebug, true
+			}
+			levelOverrides.RUnlock()
+			DisableConnDebug(connID)
+			levelOverrides.RLock()
+		}
+	}
+	level, ok = levelOverrides.modules[module]
+	levelOverrides.RUnlock()
+	return level, ok
+}
+
+// DebugEnabled reports whether logger may write debug lines, it is false only for a default logger whose core is
+// above LogDebug while no module level or connection debug is set, so that hot paths can skip formatting them
+// (报告logger是否可能写出调试日志，仅当默认日志的core高于LogDebug且未设置子系统级别或连接调试时为false，
+// 使热路径可以跳过格式化)
+func DebugEnabled(logger ziface.ILogger) bool {
+	log, ok := logger.(*zinxDefaultLog)
+	if !ok {
+		return true
+	}
+	return !log.std().verifyLogIsolation(LogDebug) || atomic.LoadInt32(&levelOverrides.active) != 0
+}
+
+// LevelName returns the name of level, e.g. "debug" for LogDebug (返回level的名称，如LogDebug为"debug")
+func LevelName(level int) string {
+	if level >= 0 && level < len(levelNames) {
+		return levelNames[level]
+	}
+	return strconv.Itoa(level)
+}
+
+// ParseLevel parses a level given by its name, such as "warn", or its number (解析以名称(如"warn")或数字给出的级别)
+func ParseLevel(name string) (int, error) {
+	for level, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return level, nil
+		}
+	}
+	if level, err := strconv.Atoi(name); err == nil && level >= LogDebug && level <= LogFatal {
+		return level, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
@@ -0,0 +1,63 @@
+package zlog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleAndConnLevels(t *testing.T) {
+	log := zlog.NewZinxLog("", zlog.BitDefault)
+	log.SetLogLevel(zlog.LogError)
+	var lines int
+	log.SetLogHook(func([]byte) { lines++ })
+
+	decoder := zlog.F(zlog.FieldModule, zlog.ModuleDecoder)
+	heartbeat := zlog.F(zlog.FieldModule, zlog.ModuleHeartbeat)
+	conn := zlog.F(zlog.FieldConnID, uint64(5))
+
+	log.Debugw("frame", decoder)
+	assert.Equal(t, 0, lines)
+
+	zlog.SetModuleLevel(zlog.ModuleDecoder, zlog.LogDebug)
+	zlog.SetModuleLevel(zlog.ModuleHeartbeat, zlog.LogPanic)
+	defer zlog.ResetModuleLevel(zlog.ModuleDecoder)
+	defer zlog.ResetModuleLevel(zlog.ModuleHeartbeat)
+	assert.Equal(t, map[string]int{zlog.ModuleDecoder: zlog.LogDebug, zlog.ModuleHeartbeat: zlog.LogPanic}, zlog.ModuleLevels())
+
+	log.Debugw("frame", decoder)
+	log.Errorw("timeout", heartbeat, conn)
+	log.Errorw("other")
+	assert.Equal(t, 2, lines)
+
+	zlog.EnableConnDebug(5, time.Minute)
+	assert.Contains(t, zlog.DebugConns(), uint64(5))
+	log.Debugw("ping", heartbeat, conn)
+	log.Debugw("ping", heartbeat, zlog.F(zlog.FieldConnID, uint64(6)))
+	assert.Equal(t, 3, lines)
+
+	zlog.DisableConnDebug(5)
+	log.Debugw("ping", heartbeat, conn)
+	assert.Equal(t, 3, lines)
+
+	zlog.EnableConnDebug(5, -time.Second)
+	log.Debugw("ping", heartbeat, conn)
+	assert.Equal(t, 3, lines)
+	assert.Empty(t, zlog.DebugConns())
+}
+
+func TestDebugEnabled(t *testing.T) {
+	core := zlog.NewZinxLog("", zlog.BitDefault)
+	logger := zlog.New(core)
+	assert.True(t, zlog.DebugEnabled(logger))
+
+	core.SetLogLevel(zlog.LogInfo)
+	assert.False(t, zlog.DebugEnabled(logger))
+
+	zlog.EnableConnDebug(7, time.Minute)
+	assert.True(t, zlog.DebugEnabled(logger))
+	zlog.DisableConnDebug(7)
+	assert.False(t, zlog.DebugEnabled(logger))
+}
@@ -21,6 +21,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -64,6 +66,9 @@ var levels = []string{
 	"[FATAL]",
 }
 
+// Level names of the JSON output (JSON输出中的日志级别名称)
+var levelNames = []string{"debug", "info", "warn", "error", "panic", "fatal"}
+
 type ZinxLoggerCore struct {
 	// to ensure thread-safe when multiple goroutines read and write files to prevent mixed-up content, achieving concurrency safety
 	// (确保多协程读写文件，防止文件内容混乱，做到协程安全)
@@ -90,6 +95,9 @@ type ZinxLoggerCore struct {
 	fw *zutils.Writer
 
 	onLogHook func([]byte)
+
+	// Write every line as one JSON object instead of the text header (每行输出一个JSON对象，而不是文本头部)
+	json bool
 }
 
 /*
@@ -195,6 +203,16 @@ func (log *ZinxLoggerCore) formatHeader(t time.Time, file string, line int, leve
 
 // OutPut outputs log file, the original method
 func (log *ZinxLoggerCore) OutPut(level int, s string) error {
+	return log.output(log.calldDepth+1, level, s, nil)
+}
+
+// OutPutFields outputs a log line with structured fields, as " key=value" after the text or as JSON keys
+// (输出带结构化字段的日志，文本格式下以" key=value"追加，JSON格式下作为JSON的键)
+func (log *ZinxLoggerCore) OutPutFields(level int, s string, fields []Field) error {
+	return log.output(log.calldDepth+1, level, s, fields)
+}
+
+func (log *ZinxLoggerCore) output(depth int, level int, s string, fields []Field) error {
 	now := time.Now() // get current time
 	var file string   // file name of the current caller of the log interface
 	var line int      // line number of the executed code
@@ -205,7 +223,7 @@ func (log *ZinxLoggerCore) OutPut(level int, s string) error {
 		log.mu.Unlock()
 		var ok bool
 		// get the file name and line number of the current caller
-		_, file, line, ok = runtime.Caller(log.calldDepth)
+		_, file, line, ok = runtime.Caller(depth)
 		if !ok {
 			file = "unknown-file"
 			line = 0
@@ -215,12 +233,22 @@ func (log *ZinxLoggerCore) OutPut(level int, s string) error {
 
 	// reset buffer
 	log.buf.Reset()
-	// write log header
-	log.formatHeader(now, file, line, level)
-	// write log content
-	log.buf.WriteString(s)
-	// add line break
-	if len(s) > 0 && s[len(s)-1] != '\n' {
+	if log.json {
+		caller := ""
+		if file != "" {
+			if log.flag&BitShortFile != 0 {
+				file = filepath.Base(file)
+			}
+			caller = file + ":" + strconv.Itoa(line)
+		}
+		writeJSON(&log.buf, now, level, caller, log.prefix, strings.TrimSuffix(s, "\n"), fields)
+	} else {
+		// write log header
+		log.formatHeader(now, file, line, level)
+		// write log content
+		log.buf.WriteString(strings.TrimSuffix(s, "\n"))
+		writeTextFields(&log.buf, fields)
+		// add line break
 		log.buf.WriteByte('\n')
 	}
 
@@ -243,6 +271,15 @@ func (log *ZinxLoggerCore) verifyLogIsolation(logLevel int) bool {
 	return log.isolationLevel > logLevel
 }
 
+// filtered applies the level of the module or connection of the fields before the isolation level, see SetModuleLevel
+// (先按fields所属子系统或连接的级别过滤，再按隔离级别过滤，见SetModuleLevel)
+func (log *ZinxLoggerCore) filtered(logLevel int, fields []Field) bool {
+	if level, ok := levelOf(fields); ok {
+		return level > logLevel
+	}
+	return log.verifyLogIsolation(logLevel)
+}
+
 func (log *ZinxLoggerCore) Debugf(format string, v ...interface{}) {
 	if log.verifyLogIsolation(LogDebug) {
 		return
@@ -343,6 +380,44 @@ func (log *ZinxLoggerCore) Stack(v ...interface{}) {
 	_ = log.OutPut(LogError, s)
 }
 
+// Debugw, Infow, Warnw and Errorw log msg with structured fields
+// (Debugw、Infow、Warnw和Errorw输出带结构化字段的msg)
+func (log *ZinxLoggerCore) Debugw(msg string, fields ...Field) {
+	if log.filtered(LogDebug, fields) {
+		return
+	}
+	_ = log.output(log.calldDepth, LogDebug, msg, fields)
+}
+
+func (log *ZinxLoggerCore) Infow(msg string, fields ...Field) {
+	if log.filtered(LogInfo, fields) {
+		return
+	}
+	_ = log.output(log.calldDepth, LogInfo, msg, fields)
+}
+
+func (log *ZinxLoggerCore) Warnw(msg string, fields ...Field) {
+	if log.filtered(LogWarn, fields) {
+		return
+	}
+	_ = log.output(log.calldDepth, LogWarn, msg, fields)
+}
+
+func (log *ZinxLoggerCore) Errorw(msg string, fields ...Field) {
+	if log.filtered(LogError, fields) {
+		return
+	}
+	_ = log.output(log.calldDepth, LogError, msg, fields)
+}
+
+// SetJSON switches to JSON output, one object per line with the keys time, level, caller, prefix, msg and the fields
+// (切换为JSON输出，每行一个对象，包含time、level、caller、prefix、msg及各字段)
+func (log *ZinxLoggerCore) SetJSON(on bool) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.json = on
+}
+
 // Flags gets the current log bitmap flags
 // (获取当前日志bitmap标记)
 func (log *ZinxLoggerCore) Flags() int {
@@ -404,6 +479,36 @@ func (log *ZinxLoggerCore) SetMaxSize(ms int64) {
 	log.fw.SetMaxSize(ms)
 }
 
+// SetMaxBackups 最多保留的旧日志文件数，0表示不限制
+func (log *ZinxLoggerCore) SetMaxBackups(n int) {
+	if log.fw == nil {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.fw.SetMaxBackups(n)
+}
+
+// SetCompress 旧日志的压缩方式 zutils.CompressZip、zutils.CompressGzip或zutils.CompressNone
+func (log *ZinxLoggerCore) SetCompress(c string) {
+	if log.fw == nil {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.fw.SetCompress(c)
+}
+
+// SetRotateInterval 按时间切割的间隔，0表示按天切割
+func (log *ZinxLoggerCore) SetRotateInterval(d time.Duration) {
+	if log.fw == nil {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.fw.SetRotateInterval(d)
+}
+
 // SetCons 同时输出控制台
 func (log *ZinxLoggerCore) SetCons(b bool) {
 	if log.fw == nil {
@@ -426,6 +531,11 @@ func (log *ZinxLoggerCore) SetLogLevel(logLevel int) {
 	log.isolationLevel = logLevel
 }
 
+// GetLogLevel returns the isolation level set by SetLogLevel (返回SetLogLevel设置的隔离级别)
+func (log *ZinxLoggerCore) GetLogLevel() int {
+	return log.isolationLevel
+}
+
 // Convert an integer to a fixed-length string, where the width of the string should be greater than 0
 // Ensure that the buffer has sufficient capacity
 // (将一个整形转换成一个固定长度的字符串，字符串宽度应该是大于0的
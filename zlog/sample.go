@@ -0,0 +1,92 @@
+package zlog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FieldSuppressed counts the lines a Sampler dropped since the previous one it let through
+// (Sampler自上一条放行的日志以来丢弃的日志数)
+const FieldSuppressed = "suppressed"
+
+// Sampler limits how often a log line is written, it guards the error lines of hot paths such as decode errors
+// and send failures so that one abusive client cannot flood the log, a Sampler is usually a package variable
+// shared by one log site:
+//
+//	var sendErrLog = zlog.PerSecond(10)
+//
+//	if ctx, ok := sendErrLog.Allow(ctx); ok {
+//		zlog.Ins().ErrorFX(ctx, "send err: %v", err)
+//	}
+//
+// (Sampler限制日志的输出频率，用于解码错误、发送失败等热点路径上的错误日志，避免单个恶意客户端刷爆日志，
+// 通常作为包级变量由一处日志共享)
+type Sampler struct {
+	mu sync.Mutex
+
+	// Let the first of every n lines through (每n条放行第一条)
+	every uint64
+	// Let at most perSecond lines through in each second (每秒最多放行perSecond条)
+	perSecond int
+
+	seen       uint64
+	window     int64
+	inWindow   int
+	suppressed uint64
+}
+
+// EveryN returns a Sampler letting the first of every n lines through (返回每n条放行第一条的Sampler)
+func EveryN(n uint64) *Sampler {
+	if n == 0 {
+		n = 1
+	}
+	return &Sampler{every: n}
+}
+
+// PerSecond returns a Sampler letting at most n lines through in each second (返回每秒最多放行n条的Sampler)
+func PerSecond(n int) *Sampler {
+	if n < 1 {
+		n = 1
+	}
+	return &Sampler{perSecond: n}
+}
+
+// Allow reports whether the line should be written, when it should and lines were dropped before it,
+// the returned ctx carries their number as the FieldSuppressed field
+// (返回该日志是否应当输出，输出且之前有丢弃时，返回的ctx以FieldSuppressed字段携带丢弃数)
+func (s *Sampler) Allow(ctx context.Context) (context.Context, bool) {
+	s.mu.Lock()
+	ok := s.allow(time.Now())
+	suppressed := s.suppressed
+	if ok {
+		s.suppressed = 0
+	} else {
+		s.suppressed++
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return ctx, false
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if suppressed > 0 {
+		ctx = WithFields(ctx, F(FieldSuppressed, suppressed))
+	}
+	return ctx, true
+}
+
+func (s *Sampler) allow(now time.Time) bool {
+	if s.every > 0 {
+		s.seen++
+		return (s.seen-1)%s.every == 0
+	}
+	if sec := now.Unix(); sec != s.window {
+		s.window = sec
+		s.inWindow = 0
+	}
+	s.inWindow++
+	return s.inWindow <= s.perSecond
+}
@@ -0,0 +1,33 @@
+package zlog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aceld/zinx/zlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	every := zlog.EveryN(3)
+	var allowed []int
+	var ctx context.Context
+	for i := 0; i < 7; i++ {
+		if c, ok := every.Allow(context.Background()); ok {
+			allowed = append(allowed, i)
+			ctx = c
+		}
+	}
+	assert.Equal(t, []int{0, 3, 6}, allowed)
+	assert.Equal(t, []zlog.Field{zlog.F(zlog.FieldSuppressed, uint64(2))}, zlog.FieldsFromContext(ctx))
+
+	perSecond := zlog.PerSecond(2)
+	count := 0
+	for i := 0; i < 100; i++ {
+		if _, ok := perSecond.Allow(context.Background()); ok {
+			count++
+		}
+	}
+	// The loop may cross a second boundary (循环可能跨过一秒的边界)
+	assert.True(t, count == 2 || count == 4, count)
+}
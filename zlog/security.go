@@ -0,0 +1,119 @@
+package zlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Types of the security events (安全事件的类型)
+const (
+	SecurityAuthFailed  = "auth_failed"     // A connection failed to authenticate (连接认证失败)
+	SecurityAuthTimeout = "auth_timeout"    // A connection did not authenticate in time (连接未及时认证)
+	SecurityForbidden   = "forbidden"       // A principal was denied a route (身份被拒绝访问路由)
+	SecurityRateLimited = "rate_limited"    // A message was over the rate limits (消息超过速率限制)
+	SecurityReplay      = "replay"          // A message was rejected as a replay (消息作为重放被拒绝)
+	SecurityKicked      = "kicked"          // The server closed a connection for its behaviour (服务因连接的行为关闭了连接)
+	SecurityIPDenied    = "ip_denied"       // A connection from a denied address was refused (拒绝了来自被禁止地址的连接)
+	SecurityRejected    = "rejected"        // A connection was rejected when accepted (连接在接受时被拒绝)
+	SecurityOversized   = "oversized_frame" // A message exceeded the size limits (消息超过大小限制)
+	SecurityTampered    = "tampered"        // A message was unsigned or its signature invalid (消息未签名或签名无效)
+)
+
+// SecurityEvent is a structured security event, its fields are those of the log context it was raised with, such as
+// connID and remoteAddr (结构化的安全事件，其字段来自产生事件的日志context，例如connID与remoteAddr)
+type SecurityEvent struct {
+	Time   time.Time
+	Type   string
+	Msg    string
+	Fields []Field
+}
+
+// SecuritySink consumes the security events, apart from the general log (在普通日志之外消费安全事件)
+type SecuritySink interface {
+	Emit(event SecurityEvent)
+}
+
+// SecuritySinkFunc adapts a function to a SecuritySink (将函数适配为SecuritySink)
+type SecuritySinkFunc func(event SecurityEvent)
+
+func (f SecuritySinkFunc) Emit(event SecurityEvent) {
+	f(event)
+}
+
+var securitySink atomic.Value // securitySinkHolder
+
+type securitySinkHolder struct {
+	sink SecuritySink
+}
+
+// SetSecuritySink sends the security events to sink, nil drops them, which is the default
+// (将安全事件发送给sink，为nil时丢弃，默认丢弃)
+func SetSecuritySink(sink SecuritySink) {
+	if old, ok := securitySink.Load().(securitySinkHolder); ok {
+		if closer, ok := old.sink.(io.Closer); ok && old.sink != sink {
+			_ = closer.Close()
+		}
+	}
+	securitySink.Store(securitySinkHolder{sink: sink})
+}
+
+// Security raises a security event of type with the fields of ctx, events are never sampled and the isolation levels
+// do not apply to them (以ctx的字段产生type类型的安全事件，事件不会被采样，也不受隔离级别影响)
+func Security(ctx context.Context, typ string, msg string, fields ...Field) {
+	holder, _ := securitySink.Load().(securitySinkHolder)
+	if holder.sink == nil {
+		return
+	}
+	all := FieldsFromContext(ctx)
+	if len(fields) > 0 {
+		all = append(append(make([]Field, 0, len(all)+len(fields)), all...), fields...)
+	}
+	holder.sink.Emit(SecurityEvent{Time: time.Now(), Type: typ, Msg: msg, Fields: all})
+}
+
+// jsonSecuritySink writes one JSON object per event (每个事件写入一个JSON对象)
+type jsonSecuritySink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// NewJSONSecuritySink writes the events to w as JSON lines, {"time":...,"event":...,"msg":...} followed by the
+// fields, w is closed when the sink is replaced if it is an io.Closer
+// (将事件以JSON行写入w，格式为{"time":...,"event":...,"msg":...}之后跟随各字段，替换sink时若w为io.Closer则将其关闭)
+func NewJSONSecuritySink(w io.Writer) SecuritySink {
+	return &jsonSecuritySink{w: w}
+}
+
+func (s *jsonSecuritySink) Emit(event SecurityEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf.Reset()
+	s.buf.WriteString(`{"time":`)
+	s.buf.WriteString(strconv.Quote(event.Time.Format(time.RFC3339Nano)))
+	s.buf.WriteString(`,"event":`)
+	writeJSONValue(&s.buf, event.Type)
+	s.buf.WriteString(`,"msg":`)
+	writeJSONValue(&s.buf, event.Msg)
+	for _, f := range event.Fields {
+		s.buf.WriteByte(',')
+		writeJSONValue(&s.buf, f.Key)
+		s.buf.WriteByte(':')
+		writeJSONValue(&s.buf, f.Value)
+	}
+	s.buf.WriteString("}\n")
+	_, _ = s.w.Write(s.buf.Bytes())
+}
+
+func (s *jsonSecuritySink) Close() error {
+	if closer, ok := s.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
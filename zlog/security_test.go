@@ -0,0 +1,58 @@
+package zlog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aceld/zinx/zlog"
+	"github.com/stretchr/testify/assert"
+)
+
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestSecurity(t *testing.T) {
+	defer zlog.SetSecuritySink(nil)
+	ctx := zlog.WithFields(context.Background(), zlog.F(zlog.FieldConnID, uint64(7)))
+
+	// Dropped without a sink (没有sink时丢弃)
+	zlog.Security(ctx, zlog.SecurityAuthFailed, "bad token")
+
+	var events []zlog.SecurityEvent
+	zlog.SetSecuritySink(zlog.SecuritySinkFunc(func(event zlog.SecurityEvent) { events = append(events, event) }))
+	zlog.Security(ctx, zlog.SecurityRateLimited, "drop", zlog.F("violations", 2))
+	assert.Len(t, events, 1)
+	assert.Equal(t, zlog.SecurityRateLimited, events[0].Type)
+	assert.Equal(t, []zlog.Field{zlog.F(zlog.FieldConnID, uint64(7)), zlog.F("violations", 2)}, events[0].Fields)
+	assert.False(t, events[0].Time.IsZero())
+}
+
+func TestJSONSecuritySink(t *testing.T) {
+	defer zlog.SetSecuritySink(nil)
+	w := &closeBuffer{}
+	zlog.SetSecuritySink(zlog.NewJSONSecuritySink(w))
+
+	ctx := zlog.WithFields(context.Background(), zlog.F(zlog.FieldConnID, uint64(7)), zlog.F(zlog.FieldRemoteAddr, "127.0.0.1:9000"))
+	zlog.Security(ctx, zlog.SecurityKicked, "rate_limited", zlog.F("violations", 11))
+	var obj map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Bytes(), &obj))
+	assert.Equal(t, zlog.SecurityKicked, obj["event"])
+	assert.Equal(t, "rate_limited", obj["msg"])
+	assert.Equal(t, float64(7), obj[zlog.FieldConnID])
+	assert.Equal(t, "127.0.0.1:9000", obj[zlog.FieldRemoteAddr])
+	assert.Equal(t, float64(11), obj["violations"])
+	assert.NotEmpty(t, obj["time"])
+
+	// The writer is closed with the sink (sink被替换时关闭其writer)
+	zlog.SetSecuritySink(nil)
+	assert.True(t, w.closed)
+}
@@ -21,6 +21,8 @@ package zlog
    zlog.Ins().InfoF()等方法
 */
 
+import "time"
+
 // StdZinxLog creates a global log
 var StdZinxLog = NewZinxLog("", BitDefault)
 
@@ -59,6 +61,21 @@ func SetMaxSize(ms int64) {
 	StdZinxLog.SetMaxSize(ms)
 }
 
+// SetMaxBackups 最多保留的旧日志文件数，0表示不限制
+func SetMaxBackups(n int) {
+	StdZinxLog.SetMaxBackups(n)
+}
+
+// SetCompress 旧日志的压缩方式
+func SetCompress(c string) {
+	StdZinxLog.SetCompress(c)
+}
+
+// SetRotateInterval 按时间切割的间隔，0表示按天切割
+func SetRotateInterval(d time.Duration) {
+	StdZinxLog.SetRotateInterval(d)
+}
+
 // SetCons 同时输出控制台
 func SetCons(b bool) {
 	StdZinxLog.SetCons(b)
@@ -69,6 +86,32 @@ func SetLogLevel(logLevel int) {
 	StdZinxLog.SetLogLevel(logLevel)
 }
 
+// GetLogLevel returns the log level of StdZinxLog
+func GetLogLevel() int {
+	return StdZinxLog.GetLogLevel()
+}
+
+// SetJSON switches StdZinxLog to JSON output
+func SetJSON(on bool) {
+	StdZinxLog.SetJSON(on)
+}
+
+func Debugw(msg string, fields ...Field) {
+	StdZinxLog.Debugw(msg, fields...)
+}
+
+func Infow(msg string, fields ...Field) {
+	StdZinxLog.Infow(msg, fields...)
+}
+
+func Warnw(msg string, fields ...Field) {
+	StdZinxLog.Warnw(msg, fields...)
+}
+
+func Errorw(msg string, fields ...Field) {
+	StdZinxLog.Errorw(msg, fields...)
+}
+
 func Debugf(format string, v ...interface{}) {
 	StdZinxLog.Debugf(format, v...)
 }
@@ -0,0 +1,362 @@
+// Package zlua implements routers with Lua scripts run by gopher-lua, the script is reloaded when its file changes
+// so that the event logic can be iterated on without restarting the server. A script defines a global handle
+// function called with the request of every message:
+//
+//	function handle(req)
+//		local name = req.get("name") or "guest"
+//		req.set("seen", true)
+//		local ok, err = req.send(req.msg_id() + 1, "hello " .. name .. ": " .. req.data())
+//	end
+//
+// The scripts only reach the safe API of req: msg_id(), conn_id(), data(), get(key), set(key, value), send(msgID,
+// data) and log(text). Only the base, string, table and math libraries are opened, without the functions loading
+// code, and the property keys starting with "zinx." are reserved to the framework. A script runs with the context
+// of the request bounded by the timeout of the Router, see WithTimeout, so an endless loop cannot hold a worker.
+//
+// The Lua states are pooled and reused by the requests of every connection. The globals a request assigns are
+// reset once it returns, but the tables they reach, e.g. a table of the script, are shared by the requests run on
+// the same state: keep the state of a connection with req.set and req.get.
+//
+// (zlua使用gopher-lua运行的Lua脚本实现路由，脚本文件修改后会重新加载，无需重启服务即可迭代事件逻辑。
+// 脚本定义全局的handle函数，每条消息以其请求调用。脚本只能访问req的安全接口，只开启base、string、table和math库且去掉了加载代码的函数，
+// 以"zinx."开头的属性保留给框架使用。脚本使用受Router超时(见WithTimeout)限制的请求context运行，死循环无法占住worker。
+// Lua状态被池化并由所有连接的请求复用。请求赋值的全局变量在其返回后被重置，但通过它们访问到的表(例如脚本中的表)由同一状态上运行的请求共享：
+// 连接的状态请使用req.set与req.get保存)
+package zlua
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// HandleFunc is the global function of a script called for every request (脚本中每个请求调用的全局函数)
+const HandleFunc = "handle"
+
+// reservedPrefix is the prefix of the connection properties the scripts cannot touch (脚本不能访问的连接属性前缀)
+const reservedPrefix = "zinx."
+
+// DefaultTimeout is how long a request may run the script unless set by WithTimeout
+// (未通过WithTimeout设置时请求运行脚本的最长时间)
+const DefaultTimeout = time.Second
+
+// Option configures a Router (配置Router)
+type Option func(r *Router)
+
+// WithReloadInterval checks the script for changes at most once per interval, 1s by default, 0 never reloads it
+// (每个间隔内最多检查一次脚本是否修改，默认1秒，0表示不重新加载)
+func WithReloadInterval(interval time.Duration) Option {
+	return func(r *Router) {
+		r.interval = interval
+	}
+}
+
+// WithTimeout interrupts the script once a request ran it for timeout, DefaultTimeout if not positive
+// (请求运行脚本timeout之后将其中断，不为正数时使用DefaultTimeout)
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Router) {
+		r.timeout = timeout
+	}
+}
+
+// Router is a router running the handle function of a Lua script (运行Lua脚本中handle函数的路由)
+type Router struct {
+	znet.BaseRouter
+	path     string
+	interval time.Duration
+	timeout  time.Duration
+
+	lock    sync.Mutex
+	script  *script
+	modTime time.Time
+	checked time.Time
+}
+
+// script is one version of the script, its states are pooled as a state runs one request at a time
+// (脚本的一个版本，一个状态同时只能运行一个请求，因此将状态池化)
+type script struct {
+	proto  *lua.FunctionProto
+	states sync.Pool
+}
+
+// state is a Lua state with the req table bound to the request it runs (绑定了req表的Lua状态，req表指向其正在运行的请求)
+type state struct {
+	L       *lua.LState
+	req     *lua.LTable
+	request ziface.IRequest
+	globals map[lua.LValue]lua.LValue // The globals once the script ran (脚本运行后的全局变量)
+}
+
+// NewRouter loads the script at path, it fails if the script does not compile
+// (加载path处的脚本，脚本无法编译时返回错误)
+func NewRouter(path string, opts ...Option) (*Router, error) {
+	r := &Router{path: path, interval: time.Second}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.timeout <= 0 {
+		r.timeout = DefaultTimeout
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := compile(path)
+	if err != nil {
+		return nil, err
+	}
+	r.script, r.modTime, r.checked = s, info.ModTime(), time.Now()
+	return r, nil
+}
+
+func compile(path string) (*script, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	chunk, err := parse.Parse(file, path)
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &script{proto: proto}
+	// A state which fails to run the script is a nil state, the first one is checked here
+	// (运行脚本失败的状态为nil，第一个状态在此检查)
+	s.states.New = func() interface{} {
+		st, _ := s.newState()
+		return st
+	}
+	st, err := s.newState()
+	if err != nil {
+		return nil, err
+	}
+	s.states.Put(st)
+	return s, nil
+}
+
+// current returns the script, reloading it once its file changed, a script failing to compile is logged and the
+// previous version kept (返回脚本，文件修改后重新加载，编译失败时记录日志并保留之前的版本)
+func (r *Router) current(request ziface.IRequest) *script {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.interval <= 0 || time.Since(r.checked) < r.interval {
+		return r.script
+	}
+	r.checked = time.Now()
+	info, err := os.Stat(r.path)
+	if err != nil || info.ModTime().Equal(r.modTime) {
+		return r.script
+	}
+	r.modTime = info.ModTime()
+	s, err := compile(r.path)
+	if err != nil {
+		znet.LoggerOf(request.GetConnection()).ErrorF("[LUA] reload %s err: %v", r.path, err)
+		return r.script
+	}
+	znet.LoggerOf(request.GetConnection()).InfoF("[LUA] reloaded %s", r.path)
+	r.script = s
+	return s
+}
+
+// Handle runs the handle function of the script with the request (以请求运行脚本的handle函数)
+func (r *Router) Handle(request ziface.IRequest) {
+	s := r.current(request)
+	st, _ := s.states.Get().(*state)
+	if st == nil {
+		return
+	}
+	st.request = request
+	ctx, cancel := context.WithTimeout(request.GetContext(), r.timeout)
+	st.L.SetContext(ctx)
+
+	err := st.L.CallByParam(lua.P{Fn: st.L.GetGlobal(HandleFunc), Protect: true}, st.req)
+
+	st.L.RemoveContext()
+	cancel()
+	st.request = nil
+	if err != nil {
+		znet.LoggerOf(request.GetConnection()).ErrorF("[LUA] %s msgID = %d err: %v", r.path, request.GetMsgID(), err)
+		// The stack of an interrupted state may be left in any shape (被中断的状态的栈可能处于任意状态)
+		st.L.Close()
+		return
+	}
+	st.reset()
+	s.states.Put(st)
+}
+
+// reset restores the globals of the state for the next request (为下一个请求恢复状态的全局变量)
+func (st *state) reset() {
+	globals := st.L.G.Global
+	var added []lua.LValue
+	globals.ForEach(func(key, _ lua.LValue) {
+		if _, ok := st.globals[key]; !ok {
+			added = append(added, key)
+		}
+	})
+	for _, key := range added {
+		globals.RawSet(key, lua.LNil)
+	}
+	for key, value := range st.globals {
+		if globals.RawGet(key) != value {
+			globals.RawSet(key, value)
+		}
+	}
+}
+
+// newState creates a sandboxed state running the script, it fails if the script fails or defines no handle function
+// (创建运行脚本的沙箱状态，脚本运行失败或未定义handle函数时返回错误)
+func (s *script) newState() (*state, error) {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	st := &state{L: L}
+	st.req = L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"msg_id":  st.msgID,
+		"conn_id": st.connID,
+		"data":    st.data,
+		"get":     st.get,
+		"set":     st.set,
+		"send":    st.send,
+		"log":     st.log,
+	})
+
+	L.Push(L.NewFunctionFromProto(s.proto))
+	if err := L.PCall(0, lua.MultRet, nil); err != nil {
+		L.Close()
+		return nil, err
+	}
+	if L.GetGlobal(HandleFunc).Type() != lua.LTFunction {
+		L.Close()
+		return nil, fmt.Errorf("zlua: %s does not define a %s function", s.proto.SourceName, HandleFunc)
+	}
+	st.globals = make(map[lua.LValue]lua.LValue)
+	L.G.Global.ForEach(func(key, value lua.LValue) {
+		st.globals[key] = value
+	})
+	return st, nil
+}
+
+func (st *state) msgID(L *lua.LState) int {
+	L.Push(lua.LNumber(st.request.GetMsgID()))
+	return 1
+}
+
+func (st *state) connID(L *lua.LState) int {
+	L.Push(lua.LNumber(st.request.GetConnection().GetConnID()))
+	return 1
+}
+
+func (st *state) data(L *lua.LState) int {
+	L.Push(lua.LString(st.request.GetData()))
+	return 1
+}
+
+func (st *state) get(L *lua.LState) int {
+	key := L.CheckString(1)
+	if strings.HasPrefix(key, reservedPrefix) {
+		L.ArgError(1, "reserved property "+key)
+	}
+	value, err := st.request.GetConnection().GetProperty(key)
+	if err != nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(toLua(value))
+	return 1
+}
+
+func (st *state) set(L *lua.LState) int {
+	key := L.CheckString(1)
+	if strings.HasPrefix(key, reservedPrefix) {
+		L.ArgError(1, "reserved property "+key)
+	}
+	switch value := L.Get(2).(type) {
+	case lua.LString:
+		st.request.GetConnection().SetProperty(key, string(value))
+	case lua.LNumber:
+		st.request.GetConnection().SetProperty(key, float64(value))
+	case lua.LBool:
+		st.request.GetConnection().SetProperty(key, bool(value))
+	case *lua.LNilType:
+		st.request.GetConnection().RemoveProperty(key)
+	default:
+		L.ArgError(2, "string, number, boolean or nil expected, got "+value.Type().String())
+	}
+	return 0
+}
+
+// send returns true, or false and the error (返回true，或false与错误)
+func (st *state) send(L *lua.LState) int {
+	msgID := L.CheckInt64(1)
+	data := L.CheckString(2)
+	if err := st.request.GetConnection().SendMsg(uint32(msgID), []byte(data)); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+func (st *state) log(L *lua.LState) int {
+	znet.LoggerOf(st.request.GetConnection()).InfoF("[LUA] %s", L.CheckString(1))
+	return 0
+}
+
+// toLua converts the property values a script can read, other values are nil (转换脚本可以读取的属性值，其它值为nil)
+func toLua(value interface{}) lua.LValue {
+	switch v := value.(type) {
+	case string:
+		return lua.LString(v)
+	case []byte:
+		return lua.LString(v)
+	case bool:
+		return lua.LBool(v)
+	case float64:
+		return lua.LNumber(v)
+	case float32:
+		return lua.LNumber(v)
+	case int:
+		return lua.LNumber(v)
+	case int32:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case uint32:
+		return lua.LNumber(v)
+	case uint64:
+		return lua.LNumber(v)
+	}
+	return lua.LNil
+}
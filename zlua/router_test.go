@@ -0,0 +1,115 @@
+package zlua
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ztest"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeScript(t *testing.T, path, source string, modTime time.Time) {
+	assert.Nil(t, os.WriteFile(path, []byte(source), 0o644))
+	assert.Nil(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestRouter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greet.lua")
+	now := time.Now()
+	writeScript(t, path, `
+function handle(req)
+	local name = req.get("name") or "guest"
+	req.set("seen", true)
+	req.send(req.msg_id() + 1, "hello " .. name .. ": " .. req.data())
+end`, now.Add(-time.Minute))
+
+	r, err := NewRouter(path, WithReloadInterval(time.Nanosecond))
+	assert.Nil(t, err)
+
+	conn := ztest.NewMockConnection(1)
+	conn.SetProperty("name", "zinx")
+	r.Handle(ztest.NewRequest(1).Conn(conn).String("hi").Build())
+	assert.Equal(t, uint32(2), conn.LastSent().GetMsgID())
+	assert.Equal(t, "hello zinx: hi", string(conn.LastSent().GetData()))
+	seen, _ := conn.GetProperty("seen")
+	assert.Equal(t, true, seen)
+
+	// The changed script is used by the next request, a broken one keeps the previous version
+	// (下一个请求使用修改后的脚本，损坏的脚本保留之前的版本)
+	writeScript(t, path, `function handle(req) req.send(9, "v2") end`, now)
+	time.Sleep(time.Millisecond)
+	r.Handle(ztest.NewRequest(1).Conn(conn).Build())
+	assert.Equal(t, "v2", string(conn.LastSent().GetData()))
+
+	writeScript(t, path, `function handle(req`, now.Add(time.Minute))
+	time.Sleep(time.Millisecond)
+	r.Handle(ztest.NewRequest(1).Conn(conn).Build())
+	assert.Equal(t, "v2", string(conn.LastSent().GetData()))
+}
+
+func TestRouterSandbox(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "noop.lua")
+	writeScript(t, path, `x = 1`, time.Now())
+	_, err := NewRouter(path)
+	assert.ErrorContains(t, err, "does not define a handle function")
+
+	writeScript(t, path, `
+function handle(req)
+	if dofile ~= nil or require ~= nil or os ~= nil or io ~= nil then
+		req.send(1, "escaped")
+	end
+	req.set("zinx.protocol", "forged")
+end`, time.Now())
+	r, err := NewRouter(path)
+	assert.Nil(t, err)
+	conn := ztest.NewMockConnection(1)
+	r.Handle(ztest.NewRequest(1).Conn(conn).Build())
+	assert.Empty(t, conn.Sent())
+	_, err = conn.GetProperty("zinx.protocol")
+	assert.NotNil(t, err)
+
+	// An endless script is interrupted by the context of the request (死循环的脚本被请求的context中断)
+	writeScript(t, path, `function handle(req) while true do end end`, time.Now().Add(time.Minute))
+	r, err = NewRouter(path)
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	r.Handle(ztest.NewRequest(1).Conn(conn).Context(ctx).Build())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRouterTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.lua")
+	writeScript(t, path, `function handle(req) while true do end end`, time.Now())
+	r, err := NewRouter(path, WithTimeout(20*time.Millisecond))
+	assert.Nil(t, err)
+
+	// Interrupted without a deadline of the request (请求没有截止时间时也会被中断)
+	start := time.Now()
+	r.Handle(ztest.NewRequest(1).Conn(ztest.NewMockConnection(1)).Build())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRouterGlobals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "globals.lua")
+	writeScript(t, path, `
+function handle(req)
+	if last ~= nil then
+		req.send(2, last)
+	end
+	last = req.data()
+end`, time.Now())
+	r, err := NewRouter(path)
+	assert.Nil(t, err)
+
+	// A request does not see the globals of the previous one (请求看不到前一个请求的全局变量)
+	conn := ztest.NewMockConnection(1)
+	r.Handle(ztest.NewRequest(1).Conn(conn).String("secret").Build())
+	r.Handle(ztest.NewRequest(1).Conn(conn).String("next").Build())
+	assert.Empty(t, conn.Sent())
+}
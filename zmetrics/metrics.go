@@ -0,0 +1,405 @@
+// Package zmetrics records the metrics of the framework and exposes them in the Prometheus text format on /metrics,
+// metrics are only recorded once Enable is called or zconf MetricsAddr is set.
+// (zmetrics记录框架的指标，并以Prometheus文本格式在/metrics上暴露，调用Enable或设置zconf MetricsAddr后才会记录指标)
+package zmetrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var enabled int32
+
+// Enable starts recording metrics (开始记录指标)
+func Enable() {
+	atomic.StoreInt32(&enabled, 1)
+}
+
+// Enabled reports whether metrics are recorded (返回是否记录指标)
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// Counter is a value that only goes up (只增不减的计数器)
+type Counter struct {
+	v uint64
+}
+
+// Inc adds 1 to the counter (计数器加1)
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds n to the counter (计数器加n)
+func (c *Counter) Add(n uint64) {
+	if Enabled() {
+		atomic.AddUint64(&c.v, n)
+	}
+}
+
+// Value returns the current value (返回当前值)
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.v)
+}
+
+// Gauge is a value that goes up and down (可增可减的指标)
+type Gauge struct {
+	bits uint64
+}
+
+// Set sets the gauge to v (设置为v)
+func (g *Gauge) Set(v float64) {
+	if Enabled() {
+		atomic.StoreUint64(&g.bits, math.Float64bits(v))
+	}
+}
+
+// Add adds delta to the gauge, delta may be negative (加上delta，delta可以为负数)
+func (g *Gauge) Add(delta float64) {
+	if !Enabled() {
+		return
+	}
+	for {
+		old := atomic.LoadUint64(&g.bits)
+		if atomic.CompareAndSwapUint64(&g.bits, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Value returns the current value (返回当前值)
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of the handler duration histograms
+// (处理耗时直方图各桶的上界，单位秒)
+var DefaultBuckets = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5}
+
+// Histogram counts observations in buckets of upper bounds (按上界分桶统计观测值的直方图)
+type Histogram struct {
+	bounds  []float64
+	counts  []uint64 // len(bounds)+1, the last one is +Inf
+	count   uint64
+	sumBits uint64
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe records v (记录观测值v)
+func (h *Histogram) Observe(v float64) {
+	if !Enabled() {
+		return
+	}
+	i := sort.SearchFloat64s(h.bounds, v)
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	for {
+		old := atomic.LoadUint64(&h.sumBits)
+		if atomic.CompareAndSwapUint64(&h.sumBits, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Count returns the number of observations (返回观测次数)
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+// metric is one metric family of the registry (注册表中的一个指标族)
+type metric struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	bounds []float64
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+// series is the value of one combination of label values (一组标签值对应的指标值)
+type series struct {
+	values    []string
+	counter   *Counter
+	gauge     *Gauge
+	histogram *Histogram
+}
+
+func (m *metric) with(values []string) *series {
+	if len(values) != len(m.labels) {
+		panic("zmetrics: " + m.name + " expects labels " + strings.Join(m.labels, ", "))
+	}
+	key := strings.Join(values, "\xff")
+	m.mu.RLock()
+	s, ok := m.series[key]
+	m.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok = m.series[key]; ok {
+		return s
+	}
+	s = &series{values: append([]string(nil), values...)}
+	switch m.typ {
+	case "counter":
+		s.counter = &Counter{}
+	case "gauge":
+		s.gauge = &Gauge{}
+	case "histogram":
+		s.histogram = newHistogram(m.bounds)
+	}
+	m.series[key] = s
+	return s
+}
+
+func (m *metric) delete(values []string) {
+	m.mu.Lock()
+	delete(m.series, strings.Join(values, "\xff"))
+	m.mu.Unlock()
+}
+
+// CounterVec is a counter partitioned by labels (按标签划分的计数器)
+type CounterVec struct{ m *metric }
+
+// With returns the counter of the label values, in the order of the label names
+// (返回标签值对应的计数器，标签值按标签名的顺序给出)
+func (v CounterVec) With(values ...string) *Counter { return v.m.with(values).counter }
+
+// GaugeVec is a gauge partitioned by labels (按标签划分的指标)
+type GaugeVec struct{ m *metric }
+
+// With returns the gauge of the label values (返回标签值对应的指标)
+func (v GaugeVec) With(values ...string) *Gauge { return v.m.with(values).gauge }
+
+// Delete removes the gauge of the label values (删除标签值对应的指标)
+func (v GaugeVec) Delete(values ...string) { v.m.delete(values) }
+
+// HistogramVec is a histogram partitioned by labels (按标签划分的直方图)
+type HistogramVec struct{ m *metric }
+
+// With returns the histogram of the label values (返回标签值对应的直方图)
+func (v HistogramVec) With(values ...string) *Histogram { return v.m.with(values).histogram }
+
+// Registry holds metric families and writes them in the Prometheus text format
+// (保存指标族并以Prometheus文本格式输出)
+type Registry struct {
+	mu        sync.Mutex
+	metrics   []*metric
+	names     map[string]struct{}
+	collects  map[int]func()
+	collectID int
+}
+
+// NewRegistry creates an empty Registry (创建一个空的Registry)
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]struct{}), collects: make(map[int]func())}
+}
+
+// Default is the registry of the framework metrics and of the New* functions (框架指标以及New*函数使用的注册表)
+var Default = NewRegistry()
+
+func (r *Registry) register(name, help, typ string, labels []string, bounds []float64) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.names[name]; dup {
+		panic("zmetrics: metric " + name + " is already registered")
+	}
+	r.names[name] = struct{}{}
+	m := &metric{name: name, help: help, typ: typ, labels: labels, bounds: bounds, series: make(map[string]*series)}
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// NewCounter registers a counter without labels (注册一个无标签的计数器)
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.register(name, help, "counter", nil, nil).with(nil).counter
+}
+
+// NewCounterVec registers a counter with labels (注册一个带标签的计数器)
+func (r *Registry) NewCounterVec(name, help string, labels ...string) CounterVec {
+	return CounterVec{r.register(name, help, "counter", labels, nil)}
+}
+
+// NewGauge registers a gauge without labels (注册一个无标签的指标)
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.register(name, help, "gauge", nil, nil).with(nil).gauge
+}
+
+// NewGaugeVec registers a gauge with labels (注册一个带标签的指标)
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) GaugeVec {
+	return GaugeVec{r.register(name, help, "gauge", labels, nil)}
+}
+
+// NewHistogramVec registers a histogram with labels and the upper bounds of its buckets in increasing order
+// (注册一个带标签的直方图，bounds为按升序排列的各桶上界)
+func (r *Registry) NewHistogramVec(name, help string, bounds []float64, labels ...string) HistogramVec {
+	return HistogramVec{r.register(name, help, "histogram", labels, bounds)}
+}
+
+// OnCollect registers f to be called before the metrics are written, to update gauges from snapshots,
+// the returned function unregisters it
+// (注册在输出指标前调用的f，用于根据快照更新指标，返回的函数用于取消注册)
+func (r *Registry) OnCollect(f func()) (remove func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectID++
+	id := r.collectID
+	r.collects[id] = f
+	return func() {
+		r.mu.Lock()
+		delete(r.collects, id)
+		r.mu.Unlock()
+	}
+}
+
+// collect calls the OnCollect functions and returns the metric families (调用OnCollect函数并返回指标族)
+func (r *Registry) collect() []*metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]int, 0, len(r.collects))
+	for id := range r.collects {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		r.collects[id]()
+	}
+	return append([]*metric(nil), r.metrics...)
+}
+
+// WriteTo writes the metrics in the Prometheus text format (以Prometheus文本格式输出指标)
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	metrics := r.collect()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// sorted returns the series ordered by their label values (返回按标签值排序的序列)
+func (m *metric) sorted() []*series {
+	m.mu.RLock()
+	all := make([]*series, 0, len(m.series))
+	for _, s := range m.series {
+		all = append(all, s)
+	}
+	m.mu.RUnlock()
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].values, "\xff") < strings.Join(all[j].values, "\xff")
+	})
+	return all
+}
+
+func (m *metric) write(w *bufio.Writer) {
+	all := m.sorted()
+	if len(all) == 0 {
+		return
+	}
+
+	w.WriteString("# HELP " + m.name + " " + strings.ReplaceAll(m.help, "\n", `\n`) + "\n")
+	w.WriteString("# TYPE " + m.name + " " + m.typ + "\n")
+	for _, s := range all {
+		switch m.typ {
+		case "counter":
+			writeSample(w, m.name, m.labels, s.values, "", "", float64(s.counter.Value()))
+		case "gauge":
+			writeSample(w, m.name, m.labels, s.values, "", "", s.gauge.Value())
+		case "histogram":
+			h := s.histogram
+			var cumulative uint64
+			for i, bound := range h.bounds {
+				cumulative += atomic.LoadUint64(&h.counts[i])
+				writeSample(w, m.name+"_bucket", m.labels, s.values, "le", formatFloat(bound), float64(cumulative))
+			}
+			cumulative += atomic.LoadUint64(&h.counts[len(h.bounds)])
+			writeSample(w, m.name+"_bucket", m.labels, s.values, "le", "+Inf", float64(cumulative))
+			writeSample(w, m.name+"_sum", m.labels, s.values, "", "", math.Float64frombits(atomic.LoadUint64(&h.sumBits)))
+			writeSample(w, m.name+"_count", m.labels, s.values, "", "", float64(h.Count()))
+		}
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, label, values[i])
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLabel(w *bufio.Writer, label, value string) {
+	w.WriteString(label)
+	w.WriteString(`="`)
+	labelEscaper.WriteString(w, value)
+	w.WriteByte('"')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Handler serves the metrics of the registry (提供注册表指标的http.Handler)
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// Handler serves the metrics of Default, to mount /metrics on an existing mux
+// (提供Default指标的http.Handler，用于挂载到已有的mux上)
+func Handler() http.Handler {
+	return Default.Handler()
+}
@@ -0,0 +1,75 @@
+package zmetrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	Enable()
+	r := NewRegistry()
+	conns := r.NewGauge("conns", "Current connections.")
+	closed := r.NewCounterVec("closed_total", "Closed connections.", "reason")
+	latency := r.NewHistogramVec("latency_seconds", "Handler latency.", []float64{0.1, 1}, "msg_id")
+	queue := r.NewGaugeVec("queue", "Queue depth.", "worker")
+	r.OnCollect(func() { queue.With("0").Set(3) })()
+	remove := r.OnCollect(func() { queue.With("1").Set(5) })
+	defer remove()
+
+	conns.Add(2)
+	conns.Add(-1)
+	closed.With("read_error").Inc()
+	closed.With(`a"b`).Add(2)
+	latency.With("1").Observe(0.05)
+	latency.With("1").Observe(0.5)
+	latency.With("1").Observe(2)
+
+	var buf bytes.Buffer
+	_, err := r.WriteTo(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, `# HELP conns Current connections.
+# TYPE conns gauge
+conns 1
+# HELP closed_total Closed connections.
+# TYPE closed_total counter
+closed_total{reason="a\"b"} 2
+closed_total{reason="read_error"} 1
+# HELP latency_seconds Handler latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{msg_id="1",le="0.1"} 1
+latency_seconds_bucket{msg_id="1",le="1"} 2
+latency_seconds_bucket{msg_id="1",le="+Inf"} 3
+latency_seconds_sum{msg_id="1"} 2.55
+latency_seconds_count{msg_id="1"} 3
+# HELP queue Queue depth.
+# TYPE queue gauge
+queue{worker="1"} 5
+`, buf.String())
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Header().Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, rec.Body.String(), "conns 1")
+
+	assert.Panics(t, func() { r.NewGauge("conns", "") })
+	assert.Panics(t, func() { closed.With("a", "b") })
+}
+
+func TestNewMetrics(t *testing.T) {
+	Enable()
+	r := NewRegistry()
+	m := NewMetrics(r)
+	before := Zinx.MessagesSent.Value()
+	m.MessagesSent.Inc()
+	m.SlowHandler(7)
+
+	// The metrics count into their own registry only (指标只计入自己的注册表)
+	assert.Equal(t, uint64(1), m.MessagesSent.Value())
+	assert.Equal(t, before, Zinx.MessagesSent.Value())
+	snapshot := r.Snapshot()
+	assert.NotNil(t, snapshot.Family("zinx_slow_handlers_total"))
+	assert.Panics(t, func() { NewMetrics(r) })
+}
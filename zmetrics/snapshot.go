@@ -0,0 +1,148 @@
+package zmetrics
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// Snapshot holds the values of the metrics at Time, the same numbers as /metrics, for the applications pushing
+// them into a telemetry system of their own instead of being scraped
+// (Time时刻的指标值，与/metrics的数值相同，用于将指标推送到自有监控系统而不是被采集的应用)
+type Snapshot struct {
+	Time     time.Time
+	Families []Family
+}
+
+// Family is a metric family with its series (指标族及其序列)
+type Family struct {
+	Name    string
+	Help    string
+	Type    string // "counter", "gauge" or "histogram"
+	Samples []Sample
+}
+
+// Sample is the value of a series, Value is the sum of the observations for the histograms
+// (一个序列的值，直方图的Value为观测值总和)
+type Sample struct {
+	Labels  map[string]string
+	Value   float64
+	Count   uint64   // Observations of the histograms (直方图的观测次数)
+	Buckets []Bucket // Cumulative buckets of the histograms (直方图的累积桶)
+}
+
+// Bucket is a cumulative histogram bucket (直方图的累积桶)
+type Bucket struct {
+	UpperBound float64 // +Inf for the last bucket (最后一个桶为+Inf)
+	Count      uint64
+}
+
+// Family returns the family name, or nil (返回名为name的指标族，不存在时返回nil)
+func (s *Snapshot) Family(name string) *Family {
+	for i := range s.Families {
+		if s.Families[i].Name == name {
+			return &s.Families[i]
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the current values of the metrics, the OnCollect functions are called first, families
+// without series are left out (返回指标的当前值，会先调用OnCollect函数，不含序列的指标族被省略)
+func (r *Registry) Snapshot() Snapshot {
+	snapshot := Snapshot{Time: time.Now()}
+	for _, m := range r.collect() {
+		all := m.sorted()
+		if len(all) == 0 {
+			continue
+		}
+		family := Family{Name: m.name, Help: m.help, Type: m.typ, Samples: make([]Sample, 0, len(all))}
+		for _, s := range all {
+			sample := Sample{}
+			if len(m.labels) > 0 {
+				sample.Labels = make(map[string]string, len(m.labels))
+				for i, label := range m.labels {
+					sample.Labels[label] = s.values[i]
+				}
+			}
+			switch m.typ {
+			case "counter":
+				sample.Value = float64(s.counter.Value())
+			case "gauge":
+				sample.Value = s.gauge.Value()
+			case "histogram":
+				h := s.histogram
+				var cumulative uint64
+				sample.Buckets = make([]Bucket, 0, len(h.bounds)+1)
+				for i, bound := range h.bounds {
+					cumulative += atomic.LoadUint64(&h.counts[i])
+					sample.Buckets = append(sample.Buckets, Bucket{UpperBound: bound, Count: cumulative})
+				}
+				cumulative += atomic.LoadUint64(&h.counts[len(h.bounds)])
+				sample.Buckets = append(sample.Buckets, Bucket{UpperBound: math.Inf(1), Count: cumulative})
+				sample.Value = math.Float64frombits(atomic.LoadUint64(&h.sumBits))
+				sample.Count = h.Count()
+			}
+			family.Samples = append(family.Samples, sample)
+		}
+		snapshot.Families = append(snapshot.Families, family)
+	}
+	return snapshot
+}
+
+// StatsHook receives the snapshots of the metrics periodically, see AddStatsHook (定期接收指标快照，见AddStatsHook)
+type StatsHook interface {
+	OnStatsTick(snapshot Snapshot)
+}
+
+// StatsHookFunc is a function used as a StatsHook (作为StatsHook使用的函数)
+type StatsHookFunc func(snapshot Snapshot)
+
+func (f StatsHookFunc) OnStatsTick(snapshot Snapshot) {
+	f(snapshot)
+}
+
+// AddStatsHook calls hook with a snapshot of the registry every interval until remove is called, it enables
+// the metrics. The hook runs in its own goroutine, one tick is skipped while it is still running
+// (每隔interval以注册表的快照调用hook，直到调用remove，会开启指标。hook在独立的协程中运行，仍在运行时跳过本次调用)
+func (r *Registry) AddStatsHook(hook StatsHook, interval time.Duration) (remove func()) {
+	if interval <= 0 {
+		panic("zmetrics: the interval of a stats hook must be positive")
+	}
+	Enable()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.tick(hook)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// tick calls hook, a panicking hook is logged and called again at the next tick
+// (调用hook，hook发生panic时记录日志并在下次照常调用)
+func (r *Registry) tick(hook StatsHook) {
+	defer func() {
+		if err := recover(); err != nil {
+			zlog.Ins().ErrorF("zmetrics: stats hook panic: %v", err)
+		}
+	}()
+	hook.OnStatsTick(r.Snapshot())
+}
+
+// AddStatsHook calls hook with a snapshot of Default every interval until remove is called
+// (每隔interval以Default的快照调用hook，直到调用remove)
+func AddStatsHook(hook StatsHook, interval time.Duration) (remove func()) {
+	return Default.AddStatsHook(hook, interval)
+}
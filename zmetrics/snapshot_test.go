@@ -0,0 +1,61 @@
+package zmetrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	Enable()
+	r := NewRegistry()
+	conns := r.NewGauge("conns", "Current connections.")
+	closed := r.NewCounterVec("closed_total", "Closed connections.", "reason")
+	latency := r.NewHistogramVec("latency_seconds", "Handler latency.", []float64{0.1, 1}, "msg_id")
+	r.NewGaugeVec("queue", "Queue depth.", "worker")
+
+	conns.Set(2)
+	closed.With("read_error").Add(3)
+	latency.With("1").Observe(0.05)
+	latency.With("1").Observe(2)
+
+	snapshot := r.Snapshot()
+	assert.Len(t, snapshot.Families, 3)
+	assert.Nil(t, snapshot.Family("queue"))
+	assert.Equal(t, []Sample{{Value: 2}}, snapshot.Family("conns").Samples)
+	assert.Equal(t, []Sample{{Labels: map[string]string{"reason": "read_error"}, Value: 3}}, snapshot.Family("closed_total").Samples)
+	assert.Equal(t, []Sample{{
+		Labels:  map[string]string{"msg_id": "1"},
+		Value:   2.05,
+		Count:   2,
+		Buckets: []Bucket{{0.1, 1}, {1, 1}, {math.Inf(1), 2}},
+	}}, snapshot.Family("latency_seconds").Samples)
+}
+
+func TestAddStatsHook(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("ticks_total", "Ticks.").Inc()
+
+	snapshots := make(chan Snapshot, 10)
+	calls := 0
+	remove := r.AddStatsHook(StatsHookFunc(func(snapshot Snapshot) {
+		calls++
+		if calls == 1 {
+			panic("the next tick still happens")
+		}
+		snapshots <- snapshot
+	}), 10*time.Millisecond)
+	defer remove()
+
+	select {
+	case snapshot := <-snapshots:
+		assert.Equal(t, float64(1), snapshot.Family("ticks_total").Samples[0].Value)
+	case <-time.After(time.Second):
+		t.Fatal("the stats hook was not called")
+	}
+	remove()
+	remove()
+	assert.Panics(t, func() { r.AddStatsHook(StatsHookFunc(func(Snapshot) {}), 0) })
+}
@@ -0,0 +1,238 @@
+package zmetrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// Reasons of zinx_connections_closed_total (zinx_connections_closed_total的关闭原因)
+const (
+	CloseReasonClient      = "client_closed"     // The peer closed the connection (对端关闭连接)
+	CloseReasonReadError   = "read_error"        // Reading the connection failed (读取连接失败)
+	CloseReasonHeartbeat   = "heartbeat_timeout" // The heartbeat checker found the connection not alive (心跳检测发现连接不再存活)
+	CloseReasonStopped     = "stopped"           // Stopped by the application or the server (被应用或服务停止)
+	CloseReasonRateLimited = "rate_limited"      // Over the inbound rate limits too often (过于频繁地超过入站速率限制)
+)
+
+// Reasons of zinx_connections_rejected_total (zinx_connections_rejected_total的拒绝原因)
+const (
+	RejectReasonFingerprint = "fingerprint" // Rejected by the fingerprinter (被指纹识别拒绝)
+	RejectReasonAbuse       = "abuse"       // From an IP paused for abuse (来自因滥用被暂停的IP)
+)
+
+// Metrics are the metrics of the servers and the clients of the framework in a registry, every server and client
+// counts into its own, zinx.Zinx unless set with WithMetrics
+// (注册表中框架服务与客户端的指标，每个服务与客户端计入各自的Metrics，除非通过WithMetrics设置，否则为Zinx)
+type Metrics struct {
+	// The registry the metrics are registered in (指标所在的注册表)
+	Registry *Registry
+
+	Connections         *Gauge
+	ConnectionsAccepted *Counter
+	ConnectionsClosed   CounterVec
+	ConnectionsRejected CounterVec
+
+	MessagesReceived *Counter
+	MessagesSent     *Counter
+	BytesReceived    *Counter
+	BytesSent        *Counter
+
+	HandlerDuration  HistogramVec
+	WorkerQueueDepth GaugeVec
+
+	DecodeErrors      *Counter
+	RateLimited       CounterVec
+	ReplayRejected    CounterVec
+	SignatureRejected CounterVec
+	HeartbeatTimeouts *Counter
+	RequestsDropped   CounterVec
+
+	SlowHandlers  CounterVec
+	LargeMessages CounterVec
+
+	TenantBytesReceived    CounterVec
+	TenantBytesSent        CounterVec
+	TenantMessagesReceived CounterVec
+	TenantMessagesSent     CounterVec
+
+	PubSubPublished CounterVec
+	PubSubDelivered CounterVec
+	PubSubDropped   CounterVec
+}
+
+// NewMetrics registers the metrics of the framework in r, it panics when they are already registered in r
+// (在r中注册框架的指标，r中已注册时panic)
+func NewMetrics(r *Registry) *Metrics {
+	return &Metrics{
+		Registry: r,
+
+		Connections:         r.NewGauge("zinx_connections", "Current number of connections."),
+		ConnectionsAccepted: r.NewCounter("zinx_connections_accepted_total", "Connections accepted."),
+		ConnectionsClosed:   r.NewCounterVec("zinx_connections_closed_total", "Connections closed, by reason.", "reason"),
+		ConnectionsRejected: r.NewCounterVec("zinx_connections_rejected_total", "Connections rejected when accepted, by reason.", "reason"),
+
+		MessagesReceived: r.NewCounter("zinx_messages_received_total", "Messages received."),
+		MessagesSent:     r.NewCounter("zinx_messages_sent_total", "Messages sent."),
+		BytesReceived:    r.NewCounter("zinx_received_bytes_total", "Bytes received."),
+		BytesSent:        r.NewCounter("zinx_sent_bytes_total", "Bytes sent."),
+
+		HandlerDuration: r.NewHistogramVec("zinx_handler_duration_seconds",
+			"Time taken by the handlers, by msgID.", DefaultBuckets, "msg_id"),
+		WorkerQueueDepth: r.NewGaugeVec("zinx_worker_queue_depth",
+			"Requests waiting for a worker.", "server", "pool", "worker"),
+
+		DecodeErrors:      r.NewCounter("zinx_decode_errors_total", "Frames dropped because they could not be decoded."),
+		RateLimited:       r.NewCounterVec("zinx_rate_limited_total", "Inbound messages over the rate limits, by action.", "action"),
+		ReplayRejected:    r.NewCounterVec("zinx_replay_rejected_total", "Messages rejected as replays, by reason.", "reason"),
+		SignatureRejected: r.NewCounterVec("zinx_signature_rejected_total", "Messages rejected by their signature, by reason.", "reason"),
+		HeartbeatTimeouts: r.NewCounter("zinx_heartbeat_timeouts_total", "Connections found not alive by the heartbeat checker."),
+		RequestsDropped:   r.NewCounterVec("zinx_requests_dropped_total", "Requests dropped before their handlers, by reason.", "reason"),
+
+		SlowHandlers: r.NewCounterVec("zinx_slow_handlers_total",
+			"Handlers still running after SlowHandlerThreshold, by msgID.", "msg_id"),
+		LargeMessages: r.NewCounterVec("zinx_large_messages_total",
+			"Messages larger than LargeMessageSize, by direction.", "direction"),
+
+		TenantBytesReceived:    r.NewCounterVec("zinx_tenant_received_bytes_total", "Bytes received, by tenant.", "tenant"),
+		TenantBytesSent:        r.NewCounterVec("zinx_tenant_sent_bytes_total", "Bytes sent, by tenant.", "tenant"),
+		TenantMessagesReceived: r.NewCounterVec("zinx_tenant_messages_received_total", "Messages received, by tenant.", "tenant"),
+		TenantMessagesSent:     r.NewCounterVec("zinx_tenant_messages_sent_total", "Messages sent, by tenant.", "tenant"),
+
+		PubSubPublished: r.NewCounterVec("zinx_pubsub_published_total", "Messages published, by subscribed topic.", "topic"),
+		PubSubDelivered: r.NewCounterVec("zinx_pubsub_delivered_total", "Messages queued to the subscribers, by subscribed topic.", "topic"),
+		PubSubDropped:   r.NewCounterVec("zinx_pubsub_dropped_total", "Messages the subscribers failed to queue, by subscribed topic.", "topic"),
+	}
+}
+
+// Zinx are the metrics of the framework in Default, shared by the servers and the clients without their own, the
+// frame decoders of zinterceptor count into it too (Default中的框架指标，由没有自己指标的服务与客户端共用，zinterceptor的帧解码器也计入其中)
+var Zinx = NewMetrics(Default)
+
+// Owner is implemented by the message handlers of the servers and the clients, see Of
+// (由服务与客户端的消息处理器实现，见Of)
+type Owner interface {
+	Metrics() *Metrics
+}
+
+// Of returns the metrics of owner, e.g. the message handler of a connection, Zinx if it is not an Owner
+// (返回owner的指标，例如连接的消息处理器，不是Owner时返回Zinx)
+func Of(owner interface{}) *Metrics {
+	if o, ok := owner.(Owner); ok {
+		if m := o.Metrics(); m != nil {
+			return m
+		}
+	}
+	return Zinx
+}
+
+// The metrics of Zinx, kept for the code reading them directly (Zinx的各项指标，保留给直接读取它们的代码)
+var (
+	Connections         = Zinx.Connections
+	ConnectionsAccepted = Zinx.ConnectionsAccepted
+	ConnectionsClosed   = Zinx.ConnectionsClosed
+	ConnectionsRejected = Zinx.ConnectionsRejected
+
+	MessagesReceived = Zinx.MessagesReceived
+	MessagesSent     = Zinx.MessagesSent
+	BytesReceived    = Zinx.BytesReceived
+	BytesSent        = Zinx.BytesSent
+
+	HandlerDuration  = Zinx.HandlerDuration
+	WorkerQueueDepth = Zinx.WorkerQueueDepth
+
+	DecodeErrors      = Zinx.DecodeErrors
+	RateLimited       = Zinx.RateLimited
+	ReplayRejected    = Zinx.ReplayRejected
+	SignatureRejected = Zinx.SignatureRejected
+	HeartbeatTimeouts = Zinx.HeartbeatTimeouts
+	RequestsDropped   = Zinx.RequestsDropped
+
+	SlowHandlers  = Zinx.SlowHandlers
+	LargeMessages = Zinx.LargeMessages
+
+	TenantBytesReceived    = Zinx.TenantBytesReceived
+	TenantBytesSent        = Zinx.TenantBytesSent
+	TenantMessagesReceived = Zinx.TenantMessagesReceived
+	TenantMessagesSent     = Zinx.TenantMessagesSent
+
+	PubSubPublished = Zinx.PubSubPublished
+	PubSubDelivered = Zinx.PubSubDelivered
+	PubSubDropped   = Zinx.PubSubDropped
+)
+
+// The metrics of the timing wheels of ztimer (ztimer时间轮的指标)
+var (
+	Timers                = Default.NewGauge("zinx_timers", "Timers waiting in the timing wheels.")
+	TimerLateness         = Default.NewHistogramVec("zinx_timer_lateness_seconds", "Time from the deadline of the timers to their trigger.", DefaultBuckets)
+	TimerCallbackDuration = Default.NewHistogramVec("zinx_timer_callback_duration_seconds", "Time taken by the timer callbacks.", DefaultBuckets)
+)
+
+// ObserveHandler records the time taken by the handler of msgID since start in Zinx
+// (在Zinx中记录msgID的处理函数自start起的耗时)
+func ObserveHandler(msgID uint32, start time.Time) {
+	Zinx.ObserveHandler(msgID, start)
+}
+
+// ObserveHandler records the time taken by the handler of msgID since start (记录msgID的处理函数自start起的耗时)
+func (m *Metrics) ObserveHandler(msgID uint32, start time.Time) {
+	if Enabled() {
+		m.HandlerDuration.With(formatMsgID(msgID)).Observe(time.Since(start).Seconds())
+	}
+}
+
+// SlowHandler counts a handler of msgID still running after the threshold in Zinx
+// (在Zinx中记录msgID的处理函数超过阈值仍在运行)
+func SlowHandler(msgID uint32) {
+	Zinx.SlowHandler(msgID)
+}
+
+// SlowHandler counts a handler of msgID still running after the threshold (记录msgID的处理函数超过阈值仍在运行)
+func (m *Metrics) SlowHandler(msgID uint32) {
+	if Enabled() {
+		m.SlowHandlers.With(formatMsgID(msgID)).Inc()
+	}
+}
+
+// msgIDLabels caches the label values of the msgIDs (缓存msgID的标签值)
+var msgIDLabels sync.Map
+
+func formatMsgID(msgID uint32) string {
+	if label, ok := msgIDLabels.Load(msgID); ok {
+		return label.(string)
+	}
+	label := strconv.FormatUint(uint64(msgID), 10)
+	msgIDLabels.Store(msgID, label)
+	return label
+}
+
+var listeners sync.Map
+
+// ListenAndServe enables the metrics and serves Default on addr under /metrics, in the background,
+// it does nothing when addr is already served, so that servers configured with the same address share it
+// (开启指标并在后台于addr的/metrics上提供Default，addr已经在提供时不做任何事，以便配置相同地址的多个服务共用)
+func ListenAndServe(addr string) {
+	Default.ListenAndServe(addr)
+}
+
+// ListenAndServe enables the metrics and serves the registry on addr under /metrics like the ListenAndServe function
+// (开启指标并像ListenAndServe函数一样在addr的/metrics上提供该注册表)
+func (r *Registry) ListenAndServe(addr string) {
+	Enable()
+	if _, loaded := listeners.LoadOrStore(addr, struct{}{}); loaded {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+	go func() {
+		zlog.Ins().InfoF("metrics are served on %s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zlog.Ins().ErrorF("serve metrics on %s err: %v", addr, err)
+			listeners.Delete(addr)
+		}
+	}()
+}
@@ -0,0 +1,239 @@
+// Package zmirror mirrors a sample of the inbound messages of a server to a shadow zinx server, e.g. one running a
+// new version of the handlers, so that it is validated against the production traffic. The mirroring is
+// fire-and-forget: the messages are taken from a tap of the server (see ziface.IMessageTap), so the connections are
+// never slowed down, and the replies of the shadow are discarded.
+//
+//	m := zmirror.New(s, "10.0.0.2:8999", zmirror.WithPercent(5))
+//	m.Start()
+//	defer m.Stop()
+//
+// Every mirrored connection gets its own connection to the shadow, so that the sessions are mirrored as they are.
+// The messages are packed with the datapack of the server, the transport features such as the compression or the
+// signatures are not mirrored.
+//
+// (将服务收到的部分消息镜像到影子zinx服务，例如运行新版本处理函数的服务，从而用生产流量验证它。镜像是发后即忘的：
+// 消息取自服务的截取(见ziface.IMessageTap)，不会拖慢连接，影子服务的回复被丢弃。每个被镜像的连接都有自己到影子服务的连接，
+// 使会话被原样镜像。消息使用服务的数据包封包，压缩、签名等传输特性不会被镜像)
+package zmirror
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+// retryDelay is how long the new connections are not mirrored after the shadow could not be dialed
+// (无法拨号影子服务后，新连接在这段时间内不被镜像)
+var retryDelay = time.Second
+
+// Stats are the counters of a Mirror (Mirror的计数)
+type Stats struct {
+	Mirrored uint64 // The messages written to the shadow (写入影子服务的消息)
+	Dropped  uint64 // The messages sampled but not mirrored, buffers full or shadow down (被采样但未镜像的消息，缓冲已满或影子服务不可用)
+	Conns    int64  // The connections open to the shadow (到影子服务的连接数)
+}
+
+// upstream is the connection to the shadow of one mirrored connection (一个被镜像连接到影子服务的连接)
+type upstream struct {
+	msgs chan ziface.TapMessage
+	last time.Time
+	dead int32
+}
+
+// Mirror mirrors the messages of a server to a shadow (将服务的消息镜像到影子服务)
+type Mirror struct {
+	stats  Stats // First for the 64-bit atomic operations (放在首位以满足64位原子操作的对齐要求)
+	server ziface.IServer
+	addr   string
+	opts   *options
+	packet ziface.IDataPack
+
+	mu      sync.Mutex
+	tap     ziface.IMessageTap
+	done    chan struct{}
+	retryAt int64
+	wg      sync.WaitGroup
+}
+
+// New creates the mirror of s to the shadow server at addr (创建s到addr处影子服务的镜像)
+func New(s ziface.IServer, addr string, opts ...Option) *Mirror {
+	return &Mirror{
+		server: s,
+		addr:   addr,
+		opts:   newOptions(opts),
+		packet: s.GetPacket(),
+	}
+}
+
+// Start starts mirroring (开始镜像)
+func (m *Mirror) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tap != nil {
+		return
+	}
+	m.tap = m.server.AddMessageTap(m.opts.buffer)
+	m.done = make(chan struct{})
+	go m.dispatch(m.tap, m.done)
+}
+
+// Stop stops mirroring and closes the connections to the shadow, the messages not written yet are dropped
+// (停止镜像并关闭到影子服务的连接，尚未写入的消息被丢弃)
+func (m *Mirror) Stop() {
+	m.mu.Lock()
+	tap, done := m.tap, m.done
+	m.tap = nil
+	m.mu.Unlock()
+	if tap == nil {
+		return
+	}
+	tap.Close()
+	<-done
+	atomic.AddUint64(&m.stats.Dropped, tap.Dropped())
+}
+
+// Stats returns the counters of the mirror (返回镜像的计数)
+func (m *Mirror) Stats() Stats {
+	stats := Stats{
+		Mirrored: atomic.LoadUint64(&m.stats.Mirrored),
+		Dropped:  atomic.LoadUint64(&m.stats.Dropped),
+		Conns:    atomic.LoadInt64(&m.stats.Conns),
+	}
+	m.mu.Lock()
+	if m.tap != nil {
+		stats.Dropped += m.tap.Dropped()
+	}
+	m.mu.Unlock()
+	return stats
+}
+
+// sampled tells whether the messages of connID are mirrored, the ConnIDs are scattered by a multiplicative hash as
+// they are usually sequential (判断connID的消息是否被镜像，ConnID通常是连续的，因此先用乘法哈希打散)
+func (m *Mirror) sampled(connID uint64) bool {
+	if m.opts.percent >= 100 {
+		return true
+	}
+	h := (connID * 0x9E3779B97F4A7C15) >> 11
+	return float64(h)/(1<<53)*100 < m.opts.percent
+}
+
+// dispatch hands the sampled messages to the upstreams of their connections, it alone owns the upstreams
+// (将被采样的消息交给其连接的上游，只有它访问上游列表)
+func (m *Mirror) dispatch(tap ziface.IMessageTap, done chan struct{}) {
+	defer close(done)
+	upstreams := make(map[uint64]*upstream)
+	sweep := time.NewTicker(m.opts.idleTimeout / 2)
+	defer sweep.Stop()
+	defer func() {
+		for connID, up := range upstreams {
+			m.closeUpstream(upstreams, connID, up)
+		}
+		m.wg.Wait()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-tap.C():
+			if !ok {
+				return
+			}
+			if (len(m.opts.msgIDs) > 0 && !m.opts.msgIDs[msg.MsgID]) || !m.sampled(msg.ConnID) {
+				continue
+			}
+			up := m.upstreamOf(upstreams, msg.ConnID)
+			if up == nil {
+				atomic.AddUint64(&m.stats.Dropped, 1)
+				continue
+			}
+			up.last = time.Now()
+			select {
+			case up.msgs <- msg:
+			default:
+				atomic.AddUint64(&m.stats.Dropped, 1)
+			}
+		case now := <-sweep.C:
+			for connID, up := range upstreams {
+				if now.Sub(up.last) >= m.opts.idleTimeout {
+					m.closeUpstream(upstreams, connID, up)
+				}
+			}
+		}
+	}
+}
+
+// upstreamOf returns the upstream of connID, replacing a dead one, nil while the shadow is down or the upstreams
+// are MaxConns (返回connID的上游，替换已断开的上游，影子服务不可用或上游数已达上限时返回nil)
+func (m *Mirror) upstreamOf(upstreams map[uint64]*upstream, connID uint64) *upstream {
+	up := upstreams[connID]
+	if up != nil && atomic.LoadInt32(&up.dead) == 0 {
+		return up
+	}
+	if up != nil {
+		m.closeUpstream(upstreams, connID, up)
+	}
+	if len(upstreams) >= m.opts.maxConns || time.Now().UnixNano() < atomic.LoadInt64(&m.retryAt) {
+		return nil
+	}
+
+	up = &upstream{msgs: make(chan ziface.TapMessage, 64)}
+	upstreams[connID] = up
+	m.wg.Add(1)
+	go m.write(up)
+	return up
+}
+
+func (m *Mirror) closeUpstream(upstreams map[uint64]*upstream, connID uint64, up *upstream) {
+	delete(upstreams, connID)
+	close(up.msgs)
+}
+
+// write dials the shadow and writes the messages of up to it until up is closed or a write fails
+// (拨号影子服务并向其写入up的消息，直到up被关闭或写入失败)
+func (m *Mirror) write(up *upstream) {
+	defer m.wg.Done()
+	defer atomic.StoreInt32(&up.dead, 1)
+
+	conn, err := net.DialTimeout("tcp", m.addr, m.opts.dialTimeout)
+	if err != nil {
+		zlog.Ins().ErrorF("zmirror: dial %s err: %v", m.addr, err)
+		atomic.StoreInt64(&m.retryAt, time.Now().Add(retryDelay).UnixNano())
+		m.drop(up)
+		return
+	}
+	atomic.AddInt64(&m.stats.Conns, 1)
+	defer atomic.AddInt64(&m.stats.Conns, -1)
+	defer conn.Close()
+	// The replies of the shadow are discarded (丢弃影子服务的回复)
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	for msg := range up.msgs {
+		frame, err := m.packet.Pack(zpack.NewMsgPackage(msg.MsgID, msg.Data))
+		if err == nil {
+			_ = conn.SetWriteDeadline(time.Now().Add(m.opts.writeTimeout))
+			_, err = conn.Write(frame)
+		}
+		if err != nil {
+			zlog.Ins().DebugF("zmirror: write msgID = %d of connID = %d to %s err: %v", msg.MsgID, msg.ConnID, m.addr, err)
+			atomic.AddUint64(&m.stats.Dropped, 1)
+			m.drop(up)
+			return
+		}
+		atomic.AddUint64(&m.stats.Mirrored, 1)
+	}
+}
+
+// drop counts the messages of a dead upstream as dropped until it is closed (在已断开的上游被关闭前，将其消息计为丢弃)
+func (m *Mirror) drop(up *upstream) {
+	atomic.StoreInt32(&up.dead, 1)
+	for range up.msgs {
+		atomic.AddUint64(&m.stats.Dropped, 1)
+	}
+}
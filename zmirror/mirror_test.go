@@ -0,0 +1,115 @@
+package zmirror
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTap struct {
+	c    chan ziface.TapMessage
+	once sync.Once
+}
+
+func (t *testTap) C() <-chan ziface.TapMessage { return t.c }
+func (t *testTap) Dropped() uint64             { return 0 }
+func (t *testTap) Close()                      { t.once.Do(func() { close(t.c) }) }
+
+type testServer struct {
+	ziface.IServer
+	tap *testTap
+}
+
+func (s *testServer) AddMessageTap(buffer int) ziface.IMessageTap { return s.tap }
+func (s *testServer) GetPacket() ziface.IDataPack                 { return zpack.NewDataPack() }
+
+// shadow accepts the mirrored connections and reports the messages received as "connection:data"
+// (接受镜像连接，并以"连接序号:数据"报告收到的消息)
+func shadow(t *testing.T) (net.Listener, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	received := make(chan string, 16)
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(i int, conn net.Conn) {
+				packet := zpack.NewDataPack()
+				head := make([]byte, packet.GetHeadLen())
+				for {
+					if _, err := io.ReadFull(conn, head); err != nil {
+						return
+					}
+					msg, _ := packet.Unpack(head)
+					data := make([]byte, msg.GetDataLen())
+					if _, err := io.ReadFull(conn, data); err != nil {
+						return
+					}
+					received <- string(rune('a'+i)) + ":" + string(data)
+				}
+			}(i, conn)
+		}
+	}()
+	return listener, received
+}
+
+func TestMirror(t *testing.T) {
+	listener, received := shadow(t)
+	defer listener.Close()
+
+	s := &testServer{tap: &testTap{c: make(chan ziface.TapMessage, 8)}}
+	m := New(s, listener.Addr().String(), WithMsgIDs(1))
+	m.Start()
+	s.tap.c <- ziface.TapMessage{ConnID: 1, MsgID: 1, Data: []byte("login")}
+	s.tap.c <- ziface.TapMessage{ConnID: 1, MsgID: 2, Data: []byte("skipped")}
+	s.tap.c <- ziface.TapMessage{ConnID: 1, MsgID: 1, Data: []byte("move")}
+
+	// The messages of a connection are mirrored in order on its own connection (连接的消息在其自己的连接上按顺序镜像)
+	for _, want := range []string{"a:login", "a:move"} {
+		select {
+		case got := <-received:
+			assert.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatal("message is not mirrored")
+		}
+	}
+	m.Stop()
+	assert.Equal(t, Stats{Mirrored: 2}, m.Stats())
+}
+
+func TestMirrorSampling(t *testing.T) {
+	m := New(&testServer{}, "", WithPercent(10))
+	sampled := 0
+	for connID := uint64(1); connID <= 10000; connID++ {
+		if m.sampled(connID) {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 1000, sampled, 100)
+
+	assert.Panics(t, func() { New(&testServer{}, "", WithPercent(120)) })
+}
+
+func TestMirrorShadowDown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := listener.Addr().String()
+	assert.Nil(t, listener.Close())
+
+	s := &testServer{tap: &testTap{c: make(chan ziface.TapMessage, 8)}}
+	m := New(s, addr)
+	m.Start()
+	s.tap.c <- ziface.TapMessage{ConnID: 1, MsgID: 1, Data: []byte("ping")}
+	s.tap.c <- ziface.TapMessage{ConnID: 2, MsgID: 1, Data: []byte("ping")}
+	m.Stop()
+	assert.Equal(t, uint64(0), m.Stats().Mirrored)
+	assert.Equal(t, uint64(2), m.Stats().Dropped)
+}
@@ -0,0 +1,89 @@
+package zmirror
+
+import "time"
+
+type options struct {
+	percent      float64
+	msgIDs       map[uint32]bool
+	buffer       int
+	maxConns     int
+	idleTimeout  time.Duration
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+// Option configures a Mirror (Mirror的配置项)
+type Option func(o *options)
+
+// WithPercent mirrors the connections whose ConnID samples under percent, from 0 to 100, 100 by default. The
+// connections are sampled rather than the messages so that the shadow sees whole sessions, e.g. the login first.
+// (镜像ConnID采样值低于percent的连接，取值0到100，默认100。按连接而不是按消息采样，使影子服务看到完整的会话，例如先登录)
+func WithPercent(percent float64) Option {
+	return func(o *options) {
+		o.percent = percent
+	}
+}
+
+// WithMsgIDs only mirrors the messages of msgIDs, all of them by default (只镜像msgIDs的消息，默认全部镜像)
+func WithMsgIDs(msgIDs ...uint32) Option {
+	return func(o *options) {
+		for _, msgID := range msgIDs {
+			o.msgIDs[msgID] = true
+		}
+	}
+}
+
+// WithBuffer sets how many messages wait for being mirrored, 1024 by default, the ones over it are dropped
+// (设置等待镜像的消息数量上限，默认1024，超出的消息被丢弃)
+func WithBuffer(size int) Option {
+	return func(o *options) {
+		o.buffer = size
+	}
+}
+
+// WithMaxConns bounds the connections to the shadow, one per mirrored connection, 1024 by default, the messages of
+// the connections over it are dropped (限制到影子服务的连接数，每个被镜像的连接一个，默认1024，超出的连接的消息被丢弃)
+func WithMaxConns(maxConns int) Option {
+	return func(o *options) {
+		o.maxConns = maxConns
+	}
+}
+
+// WithIdleTimeout closes the connection to the shadow of a connection mirroring nothing for timeout, 1 minute by
+// default (被镜像的连接在timeout内没有消息时关闭其到影子服务的连接，默认1分钟)
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = timeout
+	}
+}
+
+// WithTimeouts bounds the dial of the shadow and the writes to it, 1s both by default
+// (限制拨号影子服务与向其写入的时间，默认均为1秒)
+func WithTimeouts(dial, write time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = dial
+		o.writeTimeout = write
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		percent:      100,
+		msgIDs:       make(map[uint32]bool),
+		buffer:       1024,
+		maxConns:     1024,
+		idleTimeout:  time.Minute,
+		dialTimeout:  time.Second,
+		writeTimeout: time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.percent < 0 || o.percent > 100 {
+		panic("zmirror: percent must be from 0 to 100")
+	}
+	if o.buffer <= 0 || o.maxConns <= 0 || o.idleTimeout <= 0 {
+		panic("zmirror: buffer size, max conns and idle timeout must be positive")
+	}
+	return o
+}
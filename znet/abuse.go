@@ -0,0 +1,154 @@
+package znet
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+// maxPauseShift caps the doubling of the pause of a source at 64 times AbusePause (将来源暂停时长的加倍上限设为AbusePause的64倍)
+const maxPauseShift = 6
+
+// abuseSource is the state of one IP (单个IP的状态)
+type abuseSource struct {
+	count       int
+	windowStart time.Time
+	pausedUntil time.Time
+	pauses      uint
+}
+
+// sourceGuard counts the decode errors and auth failures of the IPs and refuses the new connections of an IP over
+// AbuseThreshold for a pause doubling each time, the connections of the other IPs keep being accepted during an attack
+// (统计各IP的解码错误与认证失败，对超过AbuseThreshold的IP在逐次加倍的暂停期内拒绝其新连接，攻击期间其他IP的连接仍被正常接受)
+type sourceGuard struct {
+	threshold int
+	window    time.Duration
+	pause     time.Duration
+
+	mu        sync.Mutex
+	sources   map[string]*abuseSource
+	lastSweep time.Time
+}
+
+// newSourceGuard returns the guard of config, nil if AbuseThreshold is not set (返回config的来源守卫，未设置AbuseThreshold时返回nil)
+func newSourceGuard(config *zconf.Config) *sourceGuard {
+	if config.AbuseThreshold <= 0 {
+		return nil
+	}
+	return &sourceGuard{
+		threshold: config.AbuseThreshold,
+		window:    config.AbuseWindowDuration(),
+		pause:     config.AbusePauseDuration(),
+		sources:   make(map[string]*abuseSource),
+		lastSweep: time.Now(),
+	}
+}
+
+// sourceIP returns the IP of addr, addr itself if it has no port (返回addr的IP，没有端口时返回addr本身)
+func sourceIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// report counts one abuse of ip and pauses it over the threshold, it returns the pause started, 0 if none
+// (记录ip的一次滥用，超过阈值时暂停该ip，返回开始的暂停时长，未暂停时返回0)
+func (g *sourceGuard) report(ip string, now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+	src, ok := g.sources[ip]
+	if !ok {
+		src = &abuseSource{windowStart: now}
+		g.sources[ip] = src
+	}
+	if now.Sub(src.windowStart) > g.window {
+		src.count, src.windowStart = 0, now
+	}
+	src.count++
+	if src.count < g.threshold || now.Before(src.pausedUntil) {
+		return 0
+	}
+
+	shift := src.pauses
+	if shift > maxPauseShift {
+		shift = maxPauseShift
+	}
+	pause := g.pause << shift
+	src.pauses++
+	src.pausedUntil = now.Add(pause)
+	src.count, src.windowStart = 0, now
+	return pause
+}
+
+// allow reports whether the new connections of ip are accepted (判断是否接受ip的新连接)
+func (g *sourceGuard) allow(ip string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	src, ok := g.sources[ip]
+	return !ok || !now.Before(src.pausedUntil)
+}
+
+// sweep forgets the IPs quiet for a while, at most once per window, the pause doubling of an IP is forgotten once
+// it has been quiet for as long as its last pause (每个窗口最多一次清理沉寂的IP，IP沉寂时间达到其上次暂停时长后忘记其暂停加倍)
+func (g *sourceGuard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < g.window {
+		return
+	}
+	g.lastSweep = now
+	for ip, src := range g.sources {
+		if now.Sub(src.windowStart) > g.window && now.Sub(src.pausedUntil) > g.pause<<minUint(src.pauses, maxPauseShift) {
+			delete(g.sources, ip)
+		}
+	}
+}
+
+func minUint(a, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ReportAbuse counts the misbehaviour of the IP of conn, see AbuseThreshold (统计conn所在IP的不当行为，见AbuseThreshold)
+func (mh *MsgHandle) ReportAbuse(conn ziface.IConnection, kind string) {
+	if mh == nil || mh.sources == nil || conn == nil {
+		return
+	}
+	ip := sourceIP(conn.RemoteAddrString())
+	if pause := mh.sources.report(ip, time.Now()); pause > 0 {
+		ctx := zlog.WithFields(context.Background(), zlog.F("ip", ip), zlog.F("kind", kind))
+		mh.log().InfoFX(ctx, "pausing the new connections of %s for %v after too many %s", ip, pause, kind)
+		zlog.Security(ctx, zlog.SecurityIPDenied, "paused for "+pause.String())
+	}
+}
+
+// reportAbuse reports the misbehaviour of conn to its message handler (向conn的消息处理器报告其不当行为)
+func reportAbuse(conn ziface.IConnection, kind string) {
+	if conn == nil {
+		return
+	}
+	if reporter, ok := conn.GetMsgHandler().(ziface.IAbuseReporter); ok {
+		reporter.ReportAbuse(conn, kind)
+	}
+}
+
+// refused reports whether the new conn comes from a paused IP, it is closed then
+// (判断新连接是否来自被暂停的IP，是则将其关闭)
+func (g *sourceGuard) refused(conn net.Conn, m *zmetrics.Metrics) bool {
+	if g == nil || g.allow(sourceIP(conn.RemoteAddr().String()), time.Now()) {
+		return false
+	}
+	m.ConnectionsRejected.With(zmetrics.RejectReasonAbuse).Inc()
+	_ = conn.Close()
+	return true
+}
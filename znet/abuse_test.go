@@ -0,0 +1,75 @@
+package znet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceGuard(t *testing.T) {
+	config := *zconf.GlobalObject
+	assert.Nil(t, newSourceGuard(&config))
+	config.AbuseThreshold, config.AbuseWindow, config.AbusePause = 3, 10, 30
+	g := newSourceGuard(&config)
+
+	now := time.Now()
+	assert.Equal(t, time.Duration(0), g.report("10.0.0.1", now))
+	assert.Equal(t, time.Duration(0), g.report("10.0.0.1", now))
+	// The count restarts after the window (窗口之后重新计数)
+	assert.Equal(t, time.Duration(0), g.report("10.0.0.1", now.Add(11*time.Second)))
+	now = now.Add(11 * time.Second)
+	g.report("10.0.0.1", now)
+	assert.Equal(t, 30*time.Second, g.report("10.0.0.1", now))
+	assert.False(t, g.allow("10.0.0.1", now))
+	assert.True(t, g.allow("10.0.0.2", now))
+	assert.True(t, g.allow("10.0.0.1", now.Add(30*time.Second)))
+
+	// Paused again, for twice as long (再次暂停，时长加倍)
+	now = now.Add(30 * time.Second)
+	for i := 0; i < 2; i++ {
+		g.report("10.0.0.1", now)
+	}
+	assert.Equal(t, 60*time.Second, g.report("10.0.0.1", now))
+
+	assert.Equal(t, "10.0.0.1", sourceIP("10.0.0.1:9000"))
+	assert.Equal(t, "::1", sourceIP("[::1]:9000"))
+}
+
+type abuseConn struct {
+	*authConn
+	mh *MsgHandle
+}
+
+func (c *abuseConn) GetMsgHandler() ziface.IMsgHandle { return c.mh }
+
+func (c *abuseConn) RemoteAddrString() string { return "pipe" }
+
+func TestReportAbuse(t *testing.T) {
+	config := *zconf.GlobalObject
+	config.AbuseThreshold = 2
+	mh := newMsgHandle()
+	mh.sources = newSourceGuard(&config)
+	conn := &abuseConn{authConn: newAuthConn(), mh: mh}
+
+	// Auth failures count against the IP of the connection (认证失败计入连接所在的IP)
+	ai := newAuthInterceptor(AuthenticatorFunc(func(conn ziface.IConnection, msgID uint32, data []byte) (*ziface.Principal, []byte, error) {
+		return nil, nil, ErrAuthFailed
+	}), []uint32{1})
+	for i := 0; i < 2; i++ {
+		ai.authenticate(conn, NewRequest(conn, zpack.NewMsgPackage(1, nil)))
+	}
+
+	// net.Pipe connections come from "pipe" (net.Pipe连接的地址为"pipe")
+	server, client := net.Pipe()
+	assert.True(t, mh.sources.refused(server, zmetrics.Zinx))
+	_, err := client.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+	var nilGuard *sourceGuard
+	assert.False(t, nilGuard.refused(server, zmetrics.Zinx))
+}
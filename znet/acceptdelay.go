@@ -1,6 +1,7 @@
 package znet
 
 import (
+	"sync"
 	"time"
 )
 
@@ -8,14 +9,11 @@ const (
 	maxDelay = 1 * time.Second
 )
 
-var AcceptDelay *acceptDelay
-
-func init() {
-	AcceptDelay = &acceptDelay{duration: 0}
-}
-
+// acceptDelay backs off an accept loop after errors, every loop has its own, it is locked as the websocket handlers of
+// a listener share theirs (接受循环出错后的退避，每个循环使用自己的退避，同一监听的websocket处理函数共享退避，因此需要加锁)
 type acceptDelay struct {
 	duration time.Duration
+	lock     sync.Mutex
 }
 
 func (d *acceptDelay) Delay() {
@@ -24,10 +22,21 @@ func (d *acceptDelay) Delay() {
 }
 
 func (d *acceptDelay) Reset() {
+	d.lock.Lock()
 	d.duration = 0
+	d.lock.Unlock()
+}
+
+// get returns the current delay (返回当前的延迟)
+func (d *acceptDelay) get() time.Duration {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.duration
 }
 
 func (d *acceptDelay) Up() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 	if d.duration == 0 {
 		d.duration = 5 * time.Millisecond
 		return
@@ -39,7 +48,7 @@ func (d *acceptDelay) Up() {
 }
 
 func (d *acceptDelay) do() {
-	if d.duration > 0 {
-		time.Sleep(d.duration)
+	if duration := d.get(); duration > 0 {
+		time.Sleep(duration)
 	}
 }
@@ -14,21 +14,21 @@ func setup() {
 }
 
 func teardown() {
-	AcceptDelay.Reset()
 	fmt.Println("Test End")
 }
 
 func TestDelay(t *testing.T) {
-	assert.Equal(t, time.Duration(0), AcceptDelay.duration)
-	AcceptDelay.Up()
-	assert.Equal(t, 5*time.Millisecond, AcceptDelay.duration)
-	AcceptDelay.Reset()
-	assert.Equal(t, time.Duration(0), AcceptDelay.duration)
+	delay := &acceptDelay{}
+	assert.Equal(t, time.Duration(0), delay.duration)
+	delay.Up()
+	assert.Equal(t, 5*time.Millisecond, delay.duration)
+	delay.Reset()
+	assert.Equal(t, time.Duration(0), delay.duration)
 
 	for i := 0; i < 600; i++ {
-		AcceptDelay.Up()
+		delay.Up()
 	}
-	assert.Equal(t, 1*time.Second, AcceptDelay.duration)
+	assert.Equal(t, 1*time.Second, delay.duration)
 }
 
 func TestMain(m *testing.M) {
@@ -0,0 +1,240 @@
+package znet
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	principalKey = "zinx.principal"
+	challengeKey = "zinx.auth_challenge"
+)
+
+const (
+	challengeHello    = "hello:"
+	challengeIssued   = "challenge:"
+	challengeProof    = "proof:"
+	challengeNonceLen = 32
+)
+
+var (
+	// ErrAuthFailed is returned by the authenticators for invalid credentials (凭证无效时认证器返回的错误)
+	ErrAuthFailed = errors.New("zinx: authentication failed")
+	// ErrNoClientCert is returned by TLSAuthenticator when the client presented no verified certificate
+	// (客户端未出示经过校验的证书时TLSAuthenticator返回的错误)
+	ErrNoClientCert = errors.New("zinx: no verified client certificate")
+)
+
+// GetPrincipal returns the principal of an authenticated connection, nil before the authentication
+// (返回已认证连接的身份，认证之前返回nil)
+func GetPrincipal(conn ziface.IConnection) *ziface.Principal {
+	if conn == nil {
+		return nil
+	}
+	value, err := conn.GetProperty(principalKey)
+	if err != nil {
+		return nil
+	}
+	principal, _ := value.(*ziface.Principal)
+	return principal
+}
+
+// AuthenticatorFunc adapts a function to ziface.IAuthenticator (将函数适配为ziface.IAuthenticator)
+type AuthenticatorFunc func(conn ziface.IConnection, msgID uint32, data []byte) (*ziface.Principal, []byte, error)
+
+func (f AuthenticatorFunc) Authenticate(conn ziface.IConnection, msgID uint32, data []byte) (*ziface.Principal, []byte, error) {
+	return f(conn, msgID, data)
+}
+
+// TokenAuthenticator authenticates a connection with the token carried as the data of one auth message, e.g. a JWT
+// checked by verify (以一条认证消息的数据作为令牌认证连接，例如由verify校验的JWT)
+func TokenAuthenticator(verify func(token string) (*ziface.Principal, error)) ziface.IAuthenticator {
+	return AuthenticatorFunc(func(conn ziface.IConnection, msgID uint32, data []byte) (*ziface.Principal, []byte, error) {
+		principal, err := verify(string(data))
+		if err == nil && principal == nil {
+			err = ErrAuthFailed
+		}
+		return principal, nil, err
+	})
+}
+
+// ChallengeAuthenticator authenticates a connection by proving the knowledge of a secret shared with the server
+// without sending it: the client sends "hello:<id>", the server answers "challenge:<nonce>" and the client sends
+// "proof:<HMAC-SHA256 of the nonce with the secret>", see ChallengeResponse. lookup returns the secret and the
+// principal of the id.
+// (通过证明知道与服务端共享的密钥而不发送密钥来认证连接：客户端发送"hello:<id>"，服务端回复"challenge:<nonce>"，客户端发送
+// "proof:<以密钥计算的nonce的HMAC-SHA256>"，见ChallengeResponse。lookup返回id的密钥与身份)
+func ChallengeAuthenticator(lookup func(id string) (secret []byte, principal *ziface.Principal, err error)) ziface.IAuthenticator {
+	return AuthenticatorFunc(func(conn ziface.IConnection, msgID uint32, data []byte) (*ziface.Principal, []byte, error) {
+		payload := string(data)
+		switch {
+		case strings.HasPrefix(payload, challengeHello):
+			nonce := make([]byte, challengeNonceLen)
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, nil, err
+			}
+			conn.SetProperty(challengeKey, strings.TrimPrefix(payload, challengeHello)+"\x00"+hex.EncodeToString(nonce))
+			return nil, []byte(challengeIssued + hex.EncodeToString(nonce)), nil
+		case strings.HasPrefix(payload, challengeProof):
+			value, err := conn.GetProperty(challengeKey)
+			if err != nil {
+				return nil, nil, ErrAuthFailed
+			}
+			// A challenge is answered once (每个挑战只能应答一次)
+			conn.RemoveProperty(challengeKey)
+			id, nonce, _ := strings.Cut(value.(string), "\x00")
+			secret, principal, err := lookup(id)
+			if err != nil {
+				return nil, nil, err
+			}
+			proof, err := hex.DecodeString(strings.TrimPrefix(payload, challengeProof))
+			if err != nil || secret == nil || principal == nil || !hmac.Equal(proof, challengeMAC(secret, nonce)) {
+				return nil, nil, ErrAuthFailed
+			}
+			return principal, nil, nil
+		}
+		return nil, nil, ErrAuthFailed
+	})
+}
+
+func challengeMAC(secret []byte, nonce string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(nonce))
+	return mac.Sum(nil)
+}
+
+// ChallengeHello returns the first message of a client to ChallengeAuthenticator (返回客户端发给ChallengeAuthenticator的第一条消息)
+func ChallengeHello(id string) []byte {
+	return []byte(challengeHello + id)
+}
+
+// ChallengeResponse returns the answer of a client with secret to the challenge sent by ChallengeAuthenticator
+// (返回持有secret的客户端对ChallengeAuthenticator所发挑战的应答)
+func ChallengeResponse(secret, challenge []byte) ([]byte, error) {
+	nonce := string(challenge)
+	if !strings.HasPrefix(nonce, challengeIssued) {
+		return nil, errors.New("zinx: not a challenge")
+	}
+	return []byte(challengeProof + hex.EncodeToString(challengeMAC(secret, strings.TrimPrefix(nonce, challengeIssued)))), nil
+}
+
+// TLSAuthenticator authenticates a TLS connection with the client certificate verified against ClientCAFile, the
+// client sends any auth message once connected. principal maps the certificate to the principal, nil uses its
+// common name as the ID.
+// (以根据ClientCAFile校验过的客户端证书认证TLS连接，客户端连接后发送任意认证消息即可。principal将证书映射为身份，
+// 为nil时以证书的通用名称作为ID)
+func TLSAuthenticator(principal func(cert *x509.Certificate) (*ziface.Principal, error)) ziface.IAuthenticator {
+	if principal == nil {
+		principal = func(cert *x509.Certificate) (*ziface.Principal, error) {
+			return &ziface.Principal{ID: cert.Subject.CommonName}, nil
+		}
+	}
+	return AuthenticatorFunc(func(conn ziface.IConnection, msgID uint32, data []byte) (*ziface.Principal, []byte, error) {
+		tlsConn, ok := conn.GetConnection().(tlsStater)
+		if !ok {
+			return nil, nil, ErrNoClientCert
+		}
+		chains := tlsConn.ConnectionState().VerifiedChains
+		if len(chains) == 0 || len(chains[0]) == 0 {
+			return nil, nil, ErrNoClientCert
+		}
+		p, err := principal(chains[0][0])
+		if err == nil && p == nil {
+			err = ErrAuthFailed
+		}
+		return p, nil, err
+	})
+}
+
+// authInterceptor routes only the auth messages of the connections not authenticated yet, it runs right after the
+// decompression and the reassembly of the fragments
+// (对尚未认证的连接只放行认证消息，紧跟在解压与分片重组之后执行)
+type authInterceptor struct {
+	authenticator ziface.IAuthenticator
+	msgIDs        map[uint32]struct{}
+}
+
+func newAuthInterceptor(authenticator ziface.IAuthenticator, msgIDs []uint32) *authInterceptor {
+	if authenticator == nil {
+		panic("zinx: WithAuth needs an authenticator")
+	}
+	if len(msgIDs) == 0 {
+		panic("zinx: WithAuth needs at least one auth msgID")
+	}
+	ai := &authInterceptor{authenticator: authenticator, msgIDs: make(map[uint32]struct{}, len(msgIDs))}
+	for _, msgID := range msgIDs {
+		ai.msgIDs[msgID] = struct{}{}
+	}
+	return ai
+}
+
+func (ai *authInterceptor) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetConnection() == nil {
+		return chain.Proceed(chain.Request())
+	}
+	conn := request.GetConnection()
+
+	if _, ok := ai.msgIDs[request.GetMsgID()]; ok {
+		ai.authenticate(conn, request)
+		PutRequest(request)
+		return nil
+	}
+	if GetPrincipal(conn) == nil {
+		LoggerOf(conn).DebugFX(requestLogCtx(request), "drop msgID = %d of the unauthenticated connID = %d", request.GetMsgID(), conn.GetConnID())
+		PutRequest(request)
+		return nil
+	}
+	return chain.Proceed(chain.Request())
+}
+
+func (ai *authInterceptor) authenticate(conn ziface.IConnection, request ziface.IRequest) {
+	principal, reply, err := ai.authenticator.Authenticate(conn, request.GetMsgID(), request.GetData())
+	// The reply is sent before the connection is closed on a failure (认证失败时先发送回复再关闭连接)
+	if reply != nil {
+		if sendErr := conn.SendMsg(request.GetMsgID(), reply); sendErr != nil {
+			LoggerOf(conn).ErrorFX(requestLogCtx(request), "connID = %d answer auth msgID = %d err: %v", conn.GetConnID(), request.GetMsgID(), sendErr)
+		}
+	}
+	switch {
+	case err != nil:
+		if ctx, ok := authErrLog.Allow(requestLogCtx(request)); ok {
+			LoggerOf(conn).ErrorFX(ctx, "connID = %d authentication failed: %v", conn.GetConnID(), err)
+		}
+		zlog.Security(requestLogCtx(request), zlog.SecurityAuthFailed, err.Error())
+		reportAbuse(conn, ziface.AbuseAuthFailure)
+		conn.Stop()
+	case principal != nil:
+		conn.SetProperty(principalKey, principal)
+		LoggerOf(conn).InfoFX(connLogCtx(conn), "connID = %d authenticated as %s", conn.GetConnID(), principal.ID)
+	}
+}
+
+// expire closes conn if it is still unauthenticated after timeout (在timeout之后连接仍未认证时将其关闭)
+func (ai *authInterceptor) expire(conn ziface.IConnection, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	// The timer is bound to a context canceled on close, the connection context does not exist before Start
+	// (定时器绑定在关闭时取消的context上，连接的context在Start之前还不存在)
+	ctx, cancel := context.WithCancel(context.Background())
+	conn.AddCloseCallback(ai, conn.GetConnID(), cancel)
+	connAfterFunc(ctx, conn, timeout, func() {
+		if GetPrincipal(conn) == nil {
+			LoggerOf(conn).InfoFX(connLogCtx(conn), "connID = %d not authenticated within %v, closing", conn.GetConnID(), timeout)
+			zlog.Security(connLogCtx(conn), zlog.SecurityAuthTimeout, "not authenticated within "+timeout.String())
+			reportAbuse(conn, ziface.AbuseAuthFailure)
+			conn.Stop()
+		}
+	})
+}
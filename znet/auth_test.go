@@ -0,0 +1,131 @@
+package znet
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type authConn struct {
+	propConn
+	mu      sync.Mutex
+	stopped bool
+	onClose func()
+}
+
+func (c *authConn) GetConnection() net.Conn { return nil }
+
+func (c *authConn) RemoveProperty(key string) { delete(c.props, key) }
+
+func (c *authConn) AddCloseCallback(handler, key interface{}, callback func()) { c.onClose = callback }
+
+func (c *authConn) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+}
+
+func (c *authConn) isStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+func newAuthConn() *authConn {
+	return &authConn{propConn: propConn{props: map[string]interface{}{}}}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	ai := newAuthInterceptor(TokenAuthenticator(func(token string) (*ziface.Principal, error) {
+		if token != "secret" {
+			return nil, errors.New("bad token")
+		}
+		return &ziface.Principal{ID: "alice", Roles: []string{"admin"}}, nil
+	}), []uint32{1})
+	capture := &captureInterceptor{}
+	execute := func(conn ziface.IConnection, msgID uint32, data string) {
+		request := NewRequest(conn, zpack.NewMsgPackage(msgID, []byte(data)))
+		zinterceptor.NewChain([]ziface.IInterceptor{ai, capture}, 0, request).Proceed(request)
+	}
+
+	conn := newAuthConn()
+	// Only the auth messages pass before the authentication (认证之前只有认证消息可以通过)
+	execute(conn, 2, "early")
+	assert.Empty(t, capture.requests)
+	assert.Nil(t, GetPrincipal(conn))
+
+	execute(conn, 1, "secret")
+	assert.Empty(t, capture.requests)
+	assert.Equal(t, "alice", GetPrincipal(conn).ID)
+	assert.True(t, GetPrincipal(conn).HasRole("admin"))
+	execute(conn, 2, "late")
+	assert.Len(t, capture.requests, 1)
+	assert.False(t, conn.isStopped())
+
+	// A failure closes the connection (认证失败会关闭连接)
+	other := newAuthConn()
+	execute(other, 1, "guess")
+	assert.True(t, other.isStopped())
+	assert.Nil(t, GetPrincipal(other))
+
+	assert.Panics(t, func() { newAuthInterceptor(ai.authenticator, nil) })
+}
+
+func TestChallengeAuthenticator(t *testing.T) {
+	secret := []byte("shared")
+	auth := ChallengeAuthenticator(func(id string) ([]byte, *ziface.Principal, error) {
+		if id != "device-7" {
+			return nil, nil, ErrAuthFailed
+		}
+		return secret, &ziface.Principal{ID: id}, nil
+	})
+
+	conn := newAuthConn()
+	principal, challenge, err := auth.Authenticate(conn, 1, ChallengeHello("device-7"))
+	assert.Nil(t, err)
+	assert.Nil(t, principal)
+	proof, err := ChallengeResponse(secret, challenge)
+	assert.Nil(t, err)
+	principal, _, err = auth.Authenticate(conn, 1, proof)
+	assert.Nil(t, err)
+	assert.Equal(t, "device-7", principal.ID)
+
+	// A challenge is answered once (每个挑战只能应答一次)
+	_, _, err = auth.Authenticate(conn, 1, proof)
+	assert.Equal(t, ErrAuthFailed, err)
+
+	_, challenge, _ = auth.Authenticate(conn, 1, ChallengeHello("device-7"))
+	proof, _ = ChallengeResponse([]byte("wrong"), challenge)
+	_, _, err = auth.Authenticate(conn, 1, proof)
+	assert.Equal(t, ErrAuthFailed, err)
+
+	_, _, err = TLSAuthenticator(nil).Authenticate(conn, 1, nil)
+	assert.Equal(t, ErrNoClientCert, err)
+}
+
+func TestAuthTimeout(t *testing.T) {
+	ai := newAuthInterceptor(TokenAuthenticator(func(token string) (*ziface.Principal, error) {
+		return &ziface.Principal{ID: token}, nil
+	}), []uint32{1})
+
+	idle := newAuthConn()
+	ai.expire(idle, 20*time.Millisecond)
+	authenticated := newAuthConn()
+	ai.expire(authenticated, 20*time.Millisecond)
+	ai.authenticate(authenticated, NewRequest(authenticated, zpack.NewMsgPackage(1, []byte("bob"))))
+	closed := newAuthConn()
+	ai.expire(closed, 20*time.Millisecond)
+	closed.onClose()
+
+	assert.Eventually(t, idle.isStopped, time.Second, 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, authenticated.isStopped())
+	assert.False(t, closed.isStopped())
+}
@@ -0,0 +1,127 @@
+package znet
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// ForbiddenError is returned by RequirePermissions and RequireRoles when the principal of the connection lacks the
+// access to a route, Principal is empty for an unauthenticated connection
+// (连接的身份无权访问路由时RequirePermissions与RequireRoles返回的错误，未认证的连接Principal为空)
+type ForbiddenError struct {
+	MsgID     uint32
+	Principal string
+	// What is missing, e.g. "permission chat:send" or "role admin" (缺少的内容，例如"permission chat:send"或"role admin")
+	Required string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("msgID = %d forbidden: requires %s", e.MsgID, e.Required)
+}
+
+// RequirePermissions returns a Validator which requires the principal of the connection to have all the permissions,
+// see GetPrincipal, e.g. s.AddValidator(msgID, znet.RequirePermissions("chat:send"))
+// (返回一个校验器，要求连接的身份拥有全部权限，见GetPrincipal，例如s.AddValidator(msgID, znet.RequirePermissions("chat:send")))
+func RequirePermissions(permissions ...string) ziface.Validator {
+	return func(request ziface.IRequest) error {
+		principal := GetPrincipal(request.GetConnection())
+		for _, permission := range permissions {
+			if !principal.HasPermission(permission) {
+				return forbiddenError(request, principal, "permission "+permission)
+			}
+		}
+		return nil
+	}
+}
+
+// RequireRoles returns a Validator which requires the principal of the connection to have one of the roles
+// (返回一个校验器，要求连接的身份拥有其中一个角色)
+func RequireRoles(roles ...string) ziface.Validator {
+	return func(request ziface.IRequest) error {
+		principal := GetPrincipal(request.GetConnection())
+		for _, role := range roles {
+			if principal.HasRole(role) {
+				return nil
+			}
+		}
+		return forbiddenError(request, principal, "role "+strings.Join(roles, "|"))
+	}
+}
+
+func forbiddenError(request ziface.IRequest, principal *ziface.Principal, required string) *ForbiddenError {
+	err := &ForbiddenError{MsgID: request.GetMsgID(), Required: required}
+	if principal != nil {
+		err.Principal = principal.ID
+	}
+	return err
+}
+
+// RouterRequirePermissions is the RouterSlicesMode middleware of RequirePermissions, e.g. for a route group
+// (RequirePermissions在RouterSlicesMode下的中间件，例如用于路由分组)
+func RouterRequirePermissions(permissions ...string) ziface.RouterHandler {
+	return routerAuthorize(RequirePermissions(permissions...))
+}
+
+// RouterRequireRoles is the RouterSlicesMode middleware of RequireRoles (RequireRoles在RouterSlicesMode下的中间件)
+func RouterRequireRoles(roles ...string) ziface.RouterHandler {
+	return routerAuthorize(RequireRoles(roles...))
+}
+
+func routerAuthorize(validator ziface.Validator) ziface.RouterHandler {
+	return func(request ziface.IRequest) {
+		err := validator(request)
+		if err == nil {
+			return
+		}
+		var mh *MsgHandle
+		if conn := request.GetConnection(); conn != nil {
+			mh, _ = conn.GetMsgHandler().(*MsgHandle)
+		}
+		mh.forbid(request, err.(*ForbiddenError))
+		request.Abort()
+	}
+}
+
+// ForbiddenReply returns a forbidden handler which replies msgID with the error text
+// (返回一个以msgID回复错误信息的禁止访问处理函数)
+func ForbiddenReply(msgID uint32) ziface.ValidationFailedHandler {
+	return ValidationReply(msgID)
+}
+
+// SetForbiddenHandler sets what happens when a request is denied, denials are only audited by default
+// (设置请求被拒绝访问后的处理，默认只记录审计日志)
+func (mh *MsgHandle) SetForbiddenHandler(handler ziface.ValidationFailedHandler) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.onForbidden = handler
+}
+
+// GetForbiddenCount returns the number of denied requests (获取被拒绝访问的请求数量)
+func (mh *MsgHandle) GetForbiddenCount() uint64 {
+	return atomic.LoadUint64(&mh.forbiddenCount)
+}
+
+// forbid audits a denied request and calls the forbidden handler, mh may be nil for the connections of a client
+// (记录被拒绝请求的审计日志并调用禁止访问处理函数，客户端的连接mh可能为nil)
+func (mh *MsgHandle) forbid(request ziface.IRequest, err *ForbiddenError) {
+	ctx := zlog.WithModule(requestLogCtx(request), zlog.ModuleAudit)
+	ctx = zlog.WithFields(ctx, zlog.F("principal", err.Principal), zlog.F("required", err.Required))
+	mh.log().InfoFX(ctx, "%v", err)
+	zlog.Security(ctx, zlog.SecurityForbidden, err.Error())
+	if mh == nil {
+		return
+	}
+
+	atomic.AddUint64(&mh.forbiddenCount, 1)
+	mh.apisLock.RLock()
+	onForbidden := mh.onForbidden
+	mh.apisLock.RUnlock()
+	if onForbidden != nil {
+		onForbidden(request, err)
+	}
+}
@@ -0,0 +1,85 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+// principalConn is a connection authenticated as principal, served by mh (以principal认证、由mh处理的连接)
+type principalConn struct {
+	recordConn
+	mh        ziface.IMsgHandle
+	principal *ziface.Principal
+}
+
+func (c *principalConn) GetMsgHandler() ziface.IMsgHandle { return c.mh }
+
+func (c *principalConn) GetProperty(key string) (interface{}, error) {
+	if key == principalKey && c.principal != nil {
+		return c.principal, nil
+	}
+	return nil, nil
+}
+
+func TestRequirePermissions(t *testing.T) {
+	mh := newMsgHandle()
+	router := &blockRouter{handled: make(chan uint32, 4)}
+	mh.AddRouter(1, router)
+	mh.AddValidator(1, RequirePermissions("chat:send", "chat:read"))
+	mh.AddRouter(2, router)
+	mh.AddValidator(2, RequireRoles("admin", "moderator"))
+	mh.SetForbiddenHandler(ForbiddenReply(403))
+
+	send := func(conn *principalConn, msgID uint32) {
+		mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(msgID, nil)), 0)
+	}
+	guest := &principalConn{mh: mh}
+	reader := &principalConn{mh: mh, principal: &ziface.Principal{ID: "bob", Permissions: []string{"chat:read"}}}
+	mod := &principalConn{mh: mh, principal: &ziface.Principal{ID: "carol", Roles: []string{"moderator"},
+		Permissions: []string{"chat:read", "chat:send"}}}
+
+	send(guest, 1)
+	send(reader, 1)
+	send(reader, 2)
+	send(mod, 1)
+	send(mod, 2)
+
+	assert.Len(t, router.handled, 2)
+	assert.Equal(t, uint64(3), mh.GetForbiddenCount())
+	// Denials are not invalid payloads (拒绝访问不算作消息体无效)
+	assert.Equal(t, uint64(0), mh.GetValidationFailures(1))
+	assert.Equal(t, []sentMsg{{msgID: 403, data: []byte("msgID = 1 forbidden: requires permission chat:send")}}, guest.sent)
+	assert.Equal(t, "msgID = 2 forbidden: requires role admin|moderator", string(reader.sent[1].data))
+	assert.Empty(t, mod.sent)
+}
+
+func TestRouterRequireRoles(t *testing.T) {
+	config := *zconf.GlobalObject
+	config.RouterSlicesMode = true
+	mh := newMsgHandleWithConfig(&config)
+	mh.SetForbiddenHandler(ForbiddenReply(403))
+
+	var handled []uint32
+	handler := func(request ziface.IRequest) { handled = append(handled, request.GetMsgID()) }
+	admin := mh.Group(100, 199, RouterRequireRoles("admin"))
+	admin.AddHandler(100, handler)
+	admin.Group(RouterRequirePermissions("user:kick")).AddHandler(101, handler)
+
+	send := func(conn *principalConn, msgID uint32) {
+		mh.doMsgHandlerSlices(NewRequest(conn, zpack.NewMsgPackage(msgID, nil)), 0)
+	}
+	user := &principalConn{mh: mh, principal: &ziface.Principal{ID: "bob"}}
+	root := &principalConn{mh: mh, principal: &ziface.Principal{ID: "alice", Roles: []string{"admin"}}}
+	send(user, 100)
+	send(root, 100)
+	send(root, 101)
+
+	assert.Equal(t, []uint32{100}, handled)
+	assert.Equal(t, uint64(2), mh.GetForbiddenCount())
+	assert.Len(t, user.sent, 1)
+	assert.Equal(t, "msgID = 101 forbidden: requires permission user:kick", string(root.sent[0].data))
+}
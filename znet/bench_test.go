@@ -0,0 +1,110 @@
+package znet
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+// benchConn feeds a frame to the reader n times and counts the replies written
+// (向读协程提供n次帧并统计写出的回复)
+type benchConn struct {
+	frame   []byte
+	n       int
+	unread  []byte
+	replies sync.WaitGroup
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (c *benchConn) Read(p []byte) (int, error) {
+	if len(c.unread) == 0 {
+		if c.n == 0 {
+			<-c.closed
+			return 0, io.EOF
+		}
+		c.n--
+		c.unread = c.frame
+	}
+	// A frame larger than p is read in pieces (大于p的帧分多次读取)
+	n := copy(p, c.unread)
+	c.unread = c.unread[n:]
+	return n, nil
+}
+
+func (c *benchConn) Write(p []byte) (int, error) {
+	c.replies.Done()
+	return len(p), nil
+}
+
+func (c *benchConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *benchConn) LocalAddr() net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8999} }
+func (c *benchConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50000}
+}
+func (c *benchConn) SetDeadline(t time.Time) error      { return nil }
+func (c *benchConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *benchConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// benchmarkMessagePath reads, decodes, unpacks and dispatches b.N messages of size bytes to a router packing and
+// writing the echo (读取、解码、拆包并分发b.N条size字节的消息给回显的路由，路由封包并写出回复)
+func benchmarkMessagePath(b *testing.B, size int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Measured at the production level, debug lines are not built then (按生产环境的级别测量，此时不构造调试日志)
+	level := zlog.GetLogLevel()
+	zlog.SetLogLevel(zlog.LogInfo)
+	defer zlog.SetLogLevel(level)
+
+	config := zconf.DefaultConfig()
+	config.RequestPoolMode = true
+	config.WorkerPoolSize = 1
+	s := newServerWithConfig(config, "tcp", WithListener(listener)).(*Server)
+	s.AddRouter(1, &echoRouter{})
+	s.Start()
+	defer s.Stop()
+
+	frame, _ := zpack.NewDataPack().Pack(zpack.NewMsgPackage(1, make([]byte, size)))
+	conn := &benchConn{frame: frame, n: b.N, closed: make(chan struct{})}
+	conn.replies.Add(b.N)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame)))
+	b.ResetTimer()
+	go s.StartConn(newServerConn(s, conn, 1))
+	conn.replies.Wait()
+	b.StopTimer()
+	conn.Close()
+}
+
+func BenchmarkMessagePath64(b *testing.B) {
+	benchmarkMessagePath(b, 64)
+}
+
+func BenchmarkMessagePath1K(b *testing.B) {
+	benchmarkMessagePath(b, 1024)
+}
+
+func BenchmarkGetRequest(b *testing.B) {
+	config := zconf.DefaultConfig()
+	config.RequestPoolMode = true
+	conn := &Connection{connID: 3, connIdStr: "3", msgHandler: newMsgHandleWithConfig(config)}
+	data := make([]byte, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		request := GetRequest(conn, getMessage(conn, data))
+		PutRequest(request)
+	}
+}
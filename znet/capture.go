@@ -0,0 +1,79 @@
+package znet
+
+import (
+	"github.com/aceld/zinx/zcapture"
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
+
+// recorderBox lets the atomic.Value hold a nil recorder (使atomic.Value可以保存nil记录器)
+type recorderBox struct {
+	recorder ziface.IRecorder
+}
+
+func (mh *MsgHandle) setRecorder(recorder ziface.IRecorder) {
+	mh.recorder.Store(recorderBox{recorder})
+}
+
+// recorderOf returns the recorder of the server of conn, or nil (返回conn所属服务的记录器，没有时返回nil)
+func recorderOf(conn ziface.IConnection) ziface.IRecorder {
+	if conn == nil {
+		return nil
+	}
+	mh, ok := conn.GetMsgHandler().(*MsgHandle)
+	if !ok {
+		return nil
+	}
+	box, _ := mh.recorder.Load().(recorderBox)
+	return box.recorder
+}
+
+// SetRecorder taps the decoded inbound and the outbound messages into recorder, nil stops it
+// (将解码后收到的消息和发出的消息交给recorder，nil停止截取)
+func (s *Server) SetRecorder(recorder ziface.IRecorder) {
+	s.recorder = recorder
+	if mh, ok := s.msgHandler.(*MsgHandle); ok {
+		mh.setRecorder(recorder)
+	}
+}
+
+// startCapture records the messages into CaptureFile until the server stops (将消息记录到CaptureFile直到服务停止)
+func (s *Server) startCapture() {
+	filter := zcapture.Filter{MsgIDs: s.config.CaptureMsgIDs}
+	switch s.config.CaptureDirection {
+	case zconf.CaptureInbound:
+		filter.Direction = zcapture.Inbound
+	case zconf.CaptureOutbound:
+		filter.Direction = zcapture.Outbound
+	}
+	recorder, err := zcapture.Create(s.config.CaptureFile, filter)
+	if err != nil {
+		s.log().ErrorF("capture into %s err: %v", s.config.CaptureFile, err)
+		return
+	}
+	s.log().InfoF("capturing the messages of server %s into %s", s.Name, s.config.CaptureFile)
+	s.SetRecorder(recorder)
+	s.closeCapture = func() {
+		s.SetRecorder(nil)
+		if err := recorder.Close(); err != nil {
+			s.log().ErrorF("close capture %s err: %v", s.config.CaptureFile, err)
+		}
+	}
+}
+
+// observeMessage is called with every decoded inbound message and every outbound message before it is packed,
+// fragments are transport details and are skipped
+// (对每条解码后收到的消息和每条封包前发出的消息调用，分片属于传输细节，不做处理)
+func observeMessage(conn ziface.IConnection, direction string, msgID uint32, data []byte) {
+	if msgID == ziface.FragmentMsgID {
+		return
+	}
+	countMessage(conn, direction == directionInbound)
+	checkMessageSize(conn, direction, msgID, len(data))
+	if recorder := recorderOf(conn); recorder != nil {
+		recorder.Record(direction == directionInbound, conn.GetConnID(), msgID, data)
+	}
+	if direction == directionInbound {
+		tapMessage(conn, msgID, data)
+	}
+}
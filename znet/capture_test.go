@@ -0,0 +1,37 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+type memRecorder struct {
+	records []string
+}
+
+func (r *memRecorder) Record(inbound bool, connID uint64, msgID uint32, data []byte) {
+	direction := directionOutbound
+	if inbound {
+		direction = directionInbound
+	}
+	r.records = append(r.records, direction+":"+string(data))
+}
+
+func TestObserveMessage(t *testing.T) {
+	mh := newMsgHandleWithConfig(zconf.DefaultConfig())
+	conn := &Connection{connID: 3, connIdStr: "3", msgHandler: mh}
+	observeMessage(conn, directionInbound, 1, []byte("before"))
+
+	recorder := &memRecorder{}
+	mh.setRecorder(recorder)
+	observeMessage(conn, directionInbound, 1, []byte("ping"))
+	observeMessage(conn, directionOutbound, 2, []byte("pong"))
+	observeMessage(conn, directionOutbound, ziface.FragmentMsgID, []byte("fragment"))
+	mh.setRecorder(nil)
+	observeMessage(conn, directionInbound, 1, []byte("after"))
+
+	assert.Equal(t, []string{"inbound:ping", "outbound:pong"}, recorder.records)
+}
@@ -16,6 +16,9 @@ import (
 type chainBuilder struct {
 	body       []ziface.IInterceptor
 	head, tail ziface.IInterceptor
+	// all is head, body and tail in order, rebuilt on every change so that Execute does not allocate it
+	// (按顺序排列的head、body和tail，每次变更时重建，使Execute无需分配)
+	all []ziface.IInterceptor
 }
 
 // newChainBuilder creates a new instance of chainBuilder.
@@ -28,22 +31,28 @@ func newChainBuilder() *chainBuilder {
 // Head adds an interceptor to the head of the chain.
 func (ic *chainBuilder) Head(interceptor ziface.IInterceptor) {
 	ic.head = interceptor
+	ic.rebuild()
 }
 
 // Tail adds an interceptor to the tail of the chain.
 func (ic *chainBuilder) Tail(interceptor ziface.IInterceptor) {
 	ic.tail = interceptor
+	ic.rebuild()
 }
 
 // AddInterceptor adds an interceptor to the body of the chain.
 func (ic *chainBuilder) AddInterceptor(interceptor ziface.IInterceptor) {
 	ic.body = append(ic.body, interceptor)
+	ic.rebuild()
 }
 
-// Execute executes all the interceptors in the current chain in order.
-func (ic *chainBuilder) Execute(req ziface.IcReq) ziface.IcResp {
+// prepend adds an interceptor to the front of the body, right after the head.
+func (ic *chainBuilder) prepend(interceptor ziface.IInterceptor) {
+	ic.body = append([]ziface.IInterceptor{interceptor}, ic.body...)
+	ic.rebuild()
+}
 
-	// Put all the interceptors into the builder
+func (ic *chainBuilder) rebuild() {
 	var interceptors []ziface.IInterceptor
 	if ic.head != nil {
 		interceptors = append(interceptors, ic.head)
@@ -54,9 +63,13 @@ func (ic *chainBuilder) Execute(req ziface.IcReq) ziface.IcResp {
 	if ic.tail != nil {
 		interceptors = append(interceptors, ic.tail)
 	}
+	ic.all = interceptors
+}
 
+// Execute executes all the interceptors in the current chain in order.
+func (ic *chainBuilder) Execute(req ziface.IcReq) ziface.IcResp {
 	// Create a new interceptor chain and execute each interceptor
-	chain := zinterceptor.NewChain(interceptors, 0, req)
+	chain := zinterceptor.NewChains(ic.all, req)
 
 	// Execute the chain
 	return chain.Proceed(req)
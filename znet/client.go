@@ -1,15 +1,18 @@
 package znet
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
 	"net/url"
 	"time"
 
+	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/zdecoder"
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
 	"github.com/aceld/zinx/zpack"
 	"github.com/gorilla/websocket"
 )
@@ -32,13 +35,28 @@ type Client struct {
 	onConnStop func(conn ziface.IConnection)
 	// Data packet packer 数据报文封包方式
 	packet ziface.IDataPack
-	// Asynchronous channel for capturing connection close status 异步捕获链接关闭状态
-	exitChan chan struct{}
+	// The context of StartContext, the context of the running client derived from it is canceled by Stop
+	// (StartContext的context，由其派生的运行中客户端的context由Stop取消)
+	base   context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	// The config of the client, zconf.GlobalObject unless created by NewClientWithConfig
+	// (客户端的配置，除非通过NewClientWithConfig创建，否则为zconf.GlobalObject)
+	config *zconf.Config
+	// The metrics of the client, zmetrics.Zinx unless set by WithMetricsClient
+	// (客户端的指标，除非通过WithMetricsClient设置，否则为zmetrics.Zinx)
+	metrics *zmetrics.Metrics
+	// The timer wheels of the connection, stopped with the client (连接的时间轮，随客户端停止)
+	timers *shardTimers
 	// Message management module 消息管理模块
 	msgHandler ziface.IMsgHandle
 	// Disassembly and assembly decoder for resolving sticky and broken packages
 	//断粘包解码器
 	decoder ziface.IDecoder
+	// Hooks called with every outbound message before it is packed 每条发出消息封包前调用的钩子
+	sendInterceptors []ziface.SendInterceptor
+	// The message signing set by WithSigningClient (WithSigningClient设置的消息签名)
+	signing *messageSigning
 	// Heartbeat checker 心跳检测器
 	hc ziface.IHeartbeatChecker
 	// Use TLS 使用TLS
@@ -50,50 +68,49 @@ type Client struct {
 }
 
 func NewClient(ip string, port int, opts ...ClientOption) ziface.IClient {
-
-	c := &Client{
-		// Default name, can be modified using the WithNameClient Option
-		// (默认名称，可以使用WithNameClient的Option修改)
-		Name: "ZinxClientTcp",
-		Ip:   ip,
-		Port: port,
-
-		msgHandler: newCliMsgHandle(),
-		packet:     zpack.Factory().NewPack(ziface.ZinxDataPack), // Default to using Zinx's TLV packet format(默认使用zinx的TLV封包方式)
-		decoder:    zdecoder.NewTLVDecoder(),                     // Default to using Zinx's TLV decoder(默认使用zinx的TLV解码器)
-		version:    "tcp",
-		ErrChan:    make(chan error),
-	}
-
-	// Apply Option settings (应用Option设置)
-	for _, opt := range opts {
-		opt(c)
-	}
-
-	return c
+	return newClientWithConfig(zconf.GlobalObject, "tcp", ip, port, opts...)
 }
 
 func NewWsClient(ip string, port int, opts ...ClientOption) ziface.IClient {
+	return newClientWithConfig(zconf.GlobalObject, "websocket", ip, port, opts...)
+}
 
+// NewClientWithConfig creates a TCP client with its own config instead of zconf.GlobalObject, e.g. the client of
+// a server created by NewServerWithOptions (使用自己的配置而不是zconf.GlobalObject创建TCP客户端，例如NewServerWithOptions创建的服务的客户端)
+func NewClientWithConfig(config *zconf.Config, ip string, port int, opts ...ClientOption) ziface.IClient {
+	return newClientWithConfig(config, "tcp", ip, port, opts...)
+}
+
+func newClientWithConfig(config *zconf.Config, version, ip string, port int, opts ...ClientOption) ziface.IClient {
 	c := &Client{
 		// Default name, can be modified using the WithNameClient Option
 		// (默认名称，可以使用WithNameClient的Option修改)
-		Name: "ZinxClientWs",
+		Name: "ZinxClientTcp",
 		Ip:   ip,
 		Port: port,
 
-		msgHandler: newCliMsgHandle(),
+		config:     config,
+		metrics:    zmetrics.Zinx,
+		timers:     newShardTimers(config),
+		msgHandler: newCliMsgHandle(config),
 		packet:     zpack.Factory().NewPack(ziface.ZinxDataPack), // Default to using Zinx's TLV packet format(默认使用zinx的TLV封包方式)
 		decoder:    zdecoder.NewTLVDecoder(),                     // Default to using Zinx's TLV decoder(默认使用zinx的TLV解码器)
-		version:    "websocket",
-		dialer:     &websocket.Dialer{},
+		version:    version,
 		ErrChan:    make(chan error),
 	}
+	if version == "websocket" {
+		c.Name = "ZinxClientWs"
+		c.dialer = &websocket.Dialer{}
+	}
 
 	// Apply Option settings (应用Option设置)
 	for _, opt := range opts {
 		opt(c)
 	}
+	if mh, ok := c.msgHandler.(*MsgHandle); ok {
+		mh.metrics, mh.timers = c.metrics, c.timers
+	}
+	bindPacket(c.packet, config)
 
 	return c
 }
@@ -110,9 +127,13 @@ func NewTLSClient(ip string, port int, opts ...ClientOption) ziface.IClient {
 // Start starts the client, sends requests and establishes a connection.
 // (重新启动客户端，发送请求且建立连接)
 func (c *Client) Restart() {
-	c.exitChan = make(chan struct{})
+	base := c.base
+	if base == nil {
+		base = context.Background()
+	}
+	c.ctx, c.cancel = context.WithCancel(base)
 
-	go func() {
+	go func(ctx context.Context) {
 
 		addr := &net.TCPAddr{
 			IP:   net.ParseIP(c.Ip),
@@ -129,7 +150,7 @@ func (c *Client) Restart() {
 			}
 
 			// Create a raw socket and get net.Conn (创建原始Socket，得到net.Conn)
-			wsConn, _, err := c.dialer.Dial(wsAddr, nil)
+			wsConn, _, err := c.dialer.DialContext(ctx, wsAddr, nil)
 			if err != nil {
 				// connection failed
 				zlog.Ins().ErrorF("WsClient connect to server failed, err:%v", err)
@@ -150,14 +171,16 @@ func (c *Client) Restart() {
 					InsecureSkipVerify: true,
 				}
 
-				conn, err = tls.Dial("tcp", fmt.Sprintf("%v:%v", net.ParseIP(c.Ip), c.Port), config)
+				dialer := &tls.Dialer{Config: config}
+				conn, err = dialer.DialContext(ctx, "tcp", fmt.Sprintf("%v:%v", net.ParseIP(c.Ip), c.Port))
 				if err != nil {
 					zlog.Ins().ErrorF("tls client connect to server failed, err:%v", err)
 					c.ErrChan <- err
 					return
 				}
 			} else {
-				conn, err = net.DialTCP("tcp", nil, addr)
+				var dialer net.Dialer
+				conn, err = dialer.DialContext(ctx, "tcp", addr.String())
 				if err != nil {
 					// connection failed
 					zlog.Ins().ErrorF("client connect to server failed, err:%v", err)
@@ -178,23 +201,55 @@ func (c *Client) Restart() {
 		}
 
 		// Start connection
-		go c.conn.Start()
+		if starter, ok := c.conn.(contextStarter); ok {
+			go starter.StartContext(ctx)
+		} else {
+			go c.conn.Start()
+		}
 
-		select {
-		case <-c.exitChan:
-			zlog.Ins().InfoF("client exit.")
+		// Offer compression once connected (连接建立后发起压缩握手)
+		if len(c.config.Compression) > 0 && packetHasFlags(c.packet) {
+			if err := SendCompressionOffer(c.conn); err != nil {
+				zlog.Ins().ErrorF("client send compression offer err: %v", err)
+			}
 		}
-	}()
+
+		<-ctx.Done()
+		zlog.Ins().InfoF("client exit.")
+	}(c.ctx)
 }
 
 // Start starts the client, sends requests and establishes a connection.
 // (启动客户端，发送请求且建立链接)
 func (c *Client) Start() {
+	c.StartContext(context.Background())
+}
+
+// StartContext starts the client, the dial and the connection are canceled once ctx is done, Restart reuses ctx
+// (启动客户端，ctx结束时取消拨号与连接，Restart复用ctx)
+func (c *Client) StartContext(ctx context.Context) {
+	c.base = ctx
 
 	// Add the decoder to the interceptor list (将解码器添加到拦截器)
 	if c.decoder != nil {
 		c.msgHandler.AddInterceptor(c.decoder)
 	}
+	if c.signing != nil {
+		c.signing.schema = headerSchemaOf(c.config)
+		if c.signing.schema == nil || !c.signing.schema.Signature {
+			panic("zinx: WithSigningClient needs the " + zpack.HeaderFieldSignature + " HeaderField")
+		}
+		if mh, ok := c.msgHandler.(*MsgHandle); ok {
+			mh.signing = c.signing
+		}
+		c.msgHandler.AddInterceptor(c.signing)
+	}
+	if len(c.config.Compression) > 0 {
+		c.msgHandler.AddInterceptor(&compressionInterceptor{packet: c.packet})
+	}
+	if c.config.FragmentMode {
+		c.msgHandler.AddInterceptor(&fragmentInterceptor{})
+	}
 
 	c.Restart()
 }
@@ -239,8 +294,8 @@ func (c *Client) StartHeartBeatWithOption(interval time.Duration, option *ziface
 func (c *Client) Stop() {
 	zlog.Ins().InfoF("[STOP] Zinx Client LocalAddr: %s, RemoteAddr: %s\n", c.conn.LocalAddr(), c.conn.RemoteAddr())
 	c.conn.Stop()
-	c.exitChan <- struct{}{}
-	close(c.exitChan)
+	c.cancel()
+	c.timers.stop()
 	close(c.ErrChan)
 }
 
@@ -284,6 +339,14 @@ func (c *Client) AddInterceptor(interceptor ziface.IInterceptor) {
 	c.msgHandler.AddInterceptor(interceptor)
 }
 
+func (c *Client) AddSendInterceptor(interceptors ...ziface.SendInterceptor) {
+	c.sendInterceptors = append(c.sendInterceptors, interceptors...)
+}
+
+func (c *Client) GetSendInterceptors() []ziface.SendInterceptor {
+	return c.sendInterceptors
+}
+
 func (c *Client) SetDecoder(decoder ziface.IDecoder) {
 	c.decoder = decoder
 }
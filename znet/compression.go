@@ -0,0 +1,194 @@
+package znet
+
+import (
+	"strings"
+
+	"github.com/aceld/zinx/zcompress"
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+// compressionKey is the connection property holding the negotiated ziface.ICompressor
+// (保存协商出的ziface.ICompressor的连接属性)
+const compressionKey = "zinx.compression"
+
+const (
+	compressionOffer  = "offer:"
+	compressionAccept = "accept:"
+)
+
+// flagsMessage is implemented by messages carrying flags, see ziface.IHeaderMessage and ziface.IEnvelopeMessage
+type flagsMessage interface {
+	GetFlags() uint32
+	SetFlags(flags uint32)
+}
+
+func packetHasFlags(packet ziface.IDataPack) bool {
+	fp, ok := packet.(ziface.IFlagDataPack)
+	return ok && fp.HasFlags()
+}
+
+func connCompressor(conn ziface.IConnection) ziface.ICompressor {
+	if value, err := conn.GetProperty(compressionKey); err == nil {
+		c, _ := value.(ziface.ICompressor)
+		return c
+	}
+	return nil
+}
+
+// SendCompressionOffer starts the compression handshake with the algorithms of zconf.Config.Compression,
+// the client sends it once connected when its datapack carries flags.
+// (以zconf.Config.Compression中的算法发起压缩握手，客户端在连接建立且数据包可以携带标志位时发送)
+func SendCompressionOffer(conn ziface.IConnection) error {
+	offer := compressionOffer + strings.Join(configOf(conn).Compression, ",")
+	return conn.SendMsg(ziface.CompressionHandshakeMsgID, []byte(offer))
+}
+
+// chooseCompressor picks the first algorithm of zconf.Config.Compression offered by the peer
+// (按zconf.Config.Compression的顺序选择对端提供的第一个算法)
+func chooseCompressor(config *zconf.Config, offered []string) ziface.ICompressor {
+	for _, name := range config.Compression {
+		for _, o := range offered {
+			if o != name {
+				continue
+			}
+			if c, ok := zcompress.Get(name); ok {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// compressMessage compresses the data of msg with the compressor negotiated on conn when it is at least
+// CompressThreshold bytes long, the returned message is marked with ziface.MessageFlagCompressed.
+// msg itself is left untouched, it may be shared by several connections.
+// (数据不少于CompressThreshold字节时使用conn上协商的算法压缩msg的数据，返回的消息带有ziface.MessageFlagCompressed标记。
+// msg本身不会被修改，它可能被多个连接共享)
+func compressMessage(conn ziface.IConnection, msg ziface.IMessage) ziface.IMessage {
+	config := configOf(conn)
+	if len(config.Compression) == 0 || len(msg.GetData()) < config.Live().CompressThreshold ||
+		msg.GetMsgID() == ziface.CompressionHandshakeMsgID {
+		return msg
+	}
+	c := connCompressor(conn)
+	if c == nil {
+		return msg
+	}
+
+	data, err := c.Compress(msg.GetData())
+	if err != nil {
+		LoggerOf(conn).ErrorF("compress msgID = %d with %s err: %v", msg.GetMsgID(), c.Name(), err)
+		return msg
+	}
+	if len(data) >= len(msg.GetData()) {
+		// Not worth it (压缩无收益)
+		return msg
+	}
+
+	compressed := cloneMessage(msg, data)
+	compressed.SetFlags(compressed.GetFlags() | ziface.MessageFlagCompressed)
+	return compressed
+}
+
+// cloneMessage returns a message with the MsgID and the header fields of msg carrying data
+// (返回携带data且MsgID与头部字段同msg一致的消息)
+func cloneMessage(msg ziface.IMessage, data []byte) *zpack.Message {
+	clone := zpack.NewMsgPackage(msg.GetMsgID(), data)
+	if fm, ok := msg.(flagsMessage); ok {
+		clone.SetFlags(fm.GetFlags())
+	}
+	if tm, ok := msg.(ziface.ITraceMessage); ok {
+		clone.SetTraceID(tm.GetTraceID())
+	}
+	if em, ok := msg.(ziface.IEnvelopeMessage); ok {
+		clone.SetCorrelationID(em.GetCorrelationID())
+	}
+	if hm, ok := msg.(ziface.IHeaderMessage); ok {
+		clone.SetVersion(hm.GetVersion())
+		clone.SetTimestamp(hm.GetTimestamp())
+		clone.SetNonce(hm.GetNonce())
+	}
+	return clone
+}
+
+// compressionInterceptor answers the compression handshake and decompresses the messages marked
+// with ziface.MessageFlagCompressed, it runs right after the decoder.
+// (应答压缩握手并解压带有ziface.MessageFlagCompressed标记的消息，紧跟在解码器之后执行)
+type compressionInterceptor struct {
+	packet ziface.IDataPack
+}
+
+func (ci *compressionInterceptor) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetConnection() == nil {
+		return chain.Proceed(chain.Request())
+	}
+	conn := request.GetConnection()
+
+	if request.GetMsgID() == ziface.CompressionHandshakeMsgID {
+		ci.handshake(conn, string(request.GetData()))
+		PutRequest(request)
+		return nil
+	}
+
+	fm, ok := request.GetMessage().(flagsMessage)
+	if !ok || fm.GetFlags()&ziface.MessageFlagCompressed == 0 {
+		return chain.Proceed(chain.Request())
+	}
+
+	c := connCompressor(conn)
+	if c == nil {
+		metricsOf(conn).DecodeErrors.Inc()
+		if ctx, ok := decodeErrLog.Allow(requestLogCtx(request)); ok {
+			LoggerOf(conn).ErrorFX(ctx, "connID = %d received a compressed msgID = %d without negotiated compression", conn.GetConnID(), request.GetMsgID())
+		}
+		reportAbuse(conn, ziface.AbuseDecodeError)
+		PutRequest(request)
+		return nil
+	}
+	data, err := c.Decompress(request.GetData())
+	if err != nil {
+		metricsOf(conn).DecodeErrors.Inc()
+		if ctx, ok := decodeErrLog.Allow(requestLogCtx(request)); ok {
+			LoggerOf(conn).ErrorFX(ctx, "connID = %d decompress msgID = %d with %s err: %v", conn.GetConnID(), request.GetMsgID(), c.Name(), err)
+		}
+		reportAbuse(conn, ziface.AbuseDecodeError)
+		PutRequest(request)
+		return nil
+	}
+
+	msg := request.GetMessage()
+	msg.SetData(data)
+	msg.SetDataLen(uint32(len(data)))
+	fm.SetFlags(fm.GetFlags() &^ ziface.MessageFlagCompressed)
+	return chain.Proceed(request)
+}
+
+func (ci *compressionInterceptor) handshake(conn ziface.IConnection, payload string) {
+	switch {
+	case strings.HasPrefix(payload, compressionOffer):
+		var c ziface.ICompressor
+		if packetHasFlags(ci.packet) {
+			c = chooseCompressor(configOf(conn), strings.Split(strings.TrimPrefix(payload, compressionOffer), ","))
+		}
+		name := ""
+		if c != nil {
+			name = c.Name()
+		}
+		// The answer is sent before the compressor is set (先发送应答再设置压缩算法)
+		if err := conn.SendMsg(ziface.CompressionHandshakeMsgID, []byte(compressionAccept+name)); err != nil {
+			LoggerOf(conn).ErrorFX(connLogCtx(conn), "connID = %d answer compression handshake err: %v", conn.GetConnID(), err)
+			return
+		}
+		if c != nil {
+			conn.SetProperty(compressionKey, c)
+		}
+	case strings.HasPrefix(payload, compressionAccept):
+		if c, ok := zcompress.Get(strings.TrimPrefix(payload, compressionAccept)); ok {
+			conn.SetProperty(compressionKey, c)
+			LoggerOf(conn).InfoFX(connLogCtx(conn), "connID = %d negotiated compression %s", conn.GetConnID(), c.Name())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package znet
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type propConn struct {
+	recordConn
+	props map[string]interface{}
+}
+
+func (c *propConn) GetConnID() uint64 { return 1 }
+
+func (c *propConn) SetProperty(key string, value interface{}) { c.props[key] = value }
+
+func (c *propConn) GetProperty(key string) (interface{}, error) {
+	if value, ok := c.props[key]; ok {
+		return value, nil
+	}
+	return nil, errors.New("no property found")
+}
+
+type captureInterceptor struct {
+	requests []ziface.IRequest
+}
+
+func (ci *captureInterceptor) Intercept(chain ziface.IChain) ziface.IcResp {
+	ci.requests = append(ci.requests, chain.Request().(ziface.IRequest))
+	return nil
+}
+
+func TestCompressionHandshake(t *testing.T) {
+	old := zconf.GlobalObject.Compression
+	zconf.GlobalObject.Compression = []string{"deflate", "gzip"}
+	defer func() { zconf.GlobalObject.Compression = old }()
+
+	schema, _ := zpack.NewHeaderSchema([]string{zpack.HeaderFieldFlags}, 0)
+	ci := &compressionInterceptor{packet: zpack.NewDataPackSchema(schema)}
+	capture := &captureInterceptor{}
+	execute := func(conn ziface.IConnection, msg ziface.IMessage) {
+		zinterceptor.NewChain([]ziface.IInterceptor{ci, capture}, 0, NewRequest(conn, msg)).Proceed(NewRequest(conn, msg))
+	}
+
+	server := &propConn{props: map[string]interface{}{}}
+	execute(server, zpack.NewMsgPackage(ziface.CompressionHandshakeMsgID, []byte("offer:gzip,deflate")))
+	assert.Equal(t, []sentMsg{{msgID: ziface.CompressionHandshakeMsgID, data: []byte("accept:deflate")}}, server.sent)
+	assert.Equal(t, "deflate", connCompressor(server).Name())
+
+	client := &propConn{props: map[string]interface{}{}}
+	execute(client, zpack.NewMsgPackage(ziface.CompressionHandshakeMsgID, server.sent[0].data))
+	assert.Equal(t, "deflate", connCompressor(client).Name())
+	assert.Empty(t, capture.requests)
+
+	// Small payloads stay uncompressed
+	small := zpack.NewMsgPackage(1, []byte("small"))
+	assert.Equal(t, ziface.IMessage(small), compressMessage(client, small))
+
+	payload := bytes.Repeat([]byte("firmware"), 1000)
+	compressed := compressMessage(client, zpack.NewMsgPackage(1, payload)).(*zpack.Message)
+	assert.Equal(t, ziface.MessageFlagCompressed, compressed.GetFlags())
+	assert.Less(t, len(compressed.GetData()), len(payload))
+
+	execute(server, compressed)
+	assert.Len(t, capture.requests, 1)
+	assert.Equal(t, payload, capture.requests[0].GetData())
+	assert.Equal(t, uint32(0), capture.requests[0].GetMessage().(ziface.IHeaderMessage).GetFlags())
+}
+
+func TestCompressionHandshakeWithoutFlags(t *testing.T) {
+	old := zconf.GlobalObject.Compression
+	zconf.GlobalObject.Compression = []string{"gzip"}
+	defer func() { zconf.GlobalObject.Compression = old }()
+
+	ci := &compressionInterceptor{packet: zpack.NewDataPack()}
+	server := &propConn{props: map[string]interface{}{}}
+	request := NewRequest(server, zpack.NewMsgPackage(ziface.CompressionHandshakeMsgID, []byte("offer:gzip")))
+	zinterceptor.NewChain([]ziface.IInterceptor{ci}, 0, request).Proceed(request)
+
+	// The default datapack cannot mark compressed messages
+	assert.Equal(t, []byte("accept:"), server.sent[0].data)
+	assert.Nil(t, connCompressor(server))
+}
@@ -0,0 +1,169 @@
+package znet
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// drainPollInterval is how often the draining connections are checked for requests in flight
+// (检查正在排空的连接是否还有正在处理的请求的间隔)
+const drainPollInterval = 10 * time.Millisecond
+
+// connDrain is embedded in the connections, it counts their requests from the dispatch until their handler
+// returns, so that a draining connection is closed once they are done
+// (嵌入在连接中，统计从分发到处理函数返回之间的请求，正在排空的连接在这些请求完成后关闭)
+type connDrain struct {
+	inflight int64
+	draining int32
+}
+
+// beginWork counts a request, or refuses it while draining, counting first so that drain never misses it
+// (统计一个请求，排空时拒绝请求。先计数，排空时不会漏掉该请求)
+func (d *connDrain) beginWork() bool {
+	atomic.AddInt64(&d.inflight, 1)
+	if atomic.LoadInt32(&d.draining) == 1 {
+		d.endWork()
+		return false
+	}
+	return true
+}
+
+// endWork counts a request done, the requests not counted by beginWork, e.g. handled directly, are ignored
+// (统计完成的请求，未经beginWork统计的请求(例如被直接处理的请求)将被忽略)
+func (d *connDrain) endWork() {
+	for {
+		n := atomic.LoadInt64(&d.inflight)
+		if n <= 0 || atomic.CompareAndSwapInt64(&d.inflight, n, n-1) {
+			return
+		}
+	}
+}
+
+func (d *connDrain) startDrain() bool {
+	return atomic.CompareAndSwapInt32(&d.draining, 0, 1)
+}
+
+func (d *connDrain) idle() bool {
+	return atomic.LoadInt64(&d.inflight) == 0
+}
+
+type drainable interface {
+	beginWork() bool
+	endWork()
+	startDrain() bool
+	idle() bool
+}
+
+// beginWork counts a request of conn, false if conn is draining (统计conn的一个请求，conn正在排空时返回false)
+func beginWork(conn ziface.IConnection) bool {
+	if d, ok := conn.(drainable); ok {
+		return d.beginWork()
+	}
+	return true
+}
+
+// endWork counts a request of conn done (统计conn完成的一个请求)
+func endWork(conn ziface.IConnection) {
+	if d, ok := conn.(drainable); ok {
+		d.endWork()
+	}
+}
+
+// selects tells whether selector selects conn (判断selector是否选中conn)
+func (s *Server) selects(selector ziface.ConnSelector, conn ziface.IConnection) bool {
+	if selector.Tenant == "" && selector.Group == "" && selector.Tag == "" && selector.Match == nil {
+		return false
+	}
+	if selector.Tenant != "" && conn.GetTenant() != selector.Tenant {
+		return false
+	}
+	if selector.Group != "" && !s.subscribed(conn, selector.Group) {
+		return false
+	}
+	if selector.Tag != "" {
+		value, err := conn.GetProperty(selector.Tag)
+		if err != nil || (selector.TagValue != "" && fmt.Sprint(value) != selector.TagValue) {
+			return false
+		}
+	}
+	return selector.Match == nil || selector.Match(conn)
+}
+
+func (s *Server) subscribed(conn ziface.IConnection, group string) bool {
+	for _, topic := range s.pubSub.Subscriptions(conn) {
+		if topic == group {
+			return true
+		}
+	}
+	return false
+}
+
+// DrainConns drains the connections selected by selector, e.g. the ones of a tenant during its maintenance window:
+// each gets the grace message of option, then its new requests are refused, and it is closed once its requests in
+// flight are done. The requests past their router timeout are not waited for. It returns the number of connections
+// drained and a channel closed once they are all closed.
+// (排空selector选中的连接，例如某个租户维护窗口内的连接：每个连接先收到option中的通知消息，之后拒绝其新请求，
+// 在其正在处理的请求完成后关闭连接。不等待超过路由超时的请求。返回被排空的连接数以及在它们全部关闭后关闭的通道)
+func (s *Server) DrainConns(selector ziface.ConnSelector, option ziface.DrainOption) (int, <-chan struct{}) {
+	var conns []ziface.IConnection
+	_ = s.ConnMgr.Range(func(_ uint64, conn ziface.IConnection, _ interface{}) error {
+		if _, ok := conn.(drainable); ok && s.selects(selector, conn) {
+			conns = append(conns, conn)
+		}
+		return nil
+	}, nil)
+
+	draining := conns[:0]
+	for _, conn := range conns {
+		// The grace message goes first, a connection already draining is left to its drain
+		// (先发送通知消息，已在排空的连接由其排空流程处理)
+		if !conn.(drainable).startDrain() {
+			continue
+		}
+		if option.GraceData != nil {
+			if err := conn.SendMsg(option.GraceMsgID, option.GraceData); err != nil {
+				LoggerOf(conn).DebugF("drain connID = %d grace message err: %v", conn.GetConnID(), err)
+			}
+		}
+		draining = append(draining, conn)
+	}
+
+	done := make(chan struct{})
+	s.log().InfoF("[DRAIN] server %s drains %d connections", s.Name, len(draining))
+	go s.closeDrained(draining, option.Timeout, done)
+	return len(draining), done
+}
+
+// closeDrained closes each connection once it is idle or timeout is over (在连接空闲或超时后将其关闭)
+func (s *Server) closeDrained(conns []ziface.IConnection, timeout time.Duration, done chan struct{}) {
+	defer close(done)
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pending := conns[:0]
+		for _, conn := range conns {
+			if conn.(drainable).idle() || (!deadline.IsZero() && time.Now().After(deadline)) {
+				conn.Stop()
+				continue
+			}
+			pending = append(pending, conn)
+		}
+		if conns = pending; len(conns) == 0 {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
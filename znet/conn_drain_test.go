@@ -0,0 +1,145 @@
+package znet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+// graceConn records the messages sent to it instead of writing them
+type graceConn struct {
+	*Connection
+	sent []uint32
+}
+
+func (c *graceConn) SendMsg(msgID uint32, data []byte) error {
+	c.sent = append(c.sent, msgID)
+	return nil
+}
+
+func newDrainConn(connID uint64, shard string) *graceConn {
+	c := newTestConn(connID)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.SetProperty("shard", shard)
+	return &graceConn{Connection: c}
+}
+
+func TestDrainConns(t *testing.T) {
+	s := newServerWithConfig(&zconf.Config{Name: "drain"}, "tcp").(*Server)
+	busy, idle, other := newDrainConn(1, "3"), newDrainConn(2, "3"), newDrainConn(3, "4")
+	for _, conn := range []*graceConn{busy, idle, other} {
+		s.ConnMgr.Add(conn)
+	}
+
+	// The zero selector selects none
+	n, done := s.DrainConns(ziface.ConnSelector{}, ziface.DrainOption{})
+	assert.Equal(t, 0, n)
+	<-done
+
+	router := &blockingRouter{started: make(chan struct{}), release: make(chan struct{})}
+	s.msgHandler.AddRouter(1, router)
+	assert.True(t, beginWork(busy))
+	handled := make(chan struct{})
+	go func() {
+		s.msgHandler.(*MsgHandle).doMsgHandler(NewRequest(busy, zpack.NewMsgPackage(1, nil)), 0)
+		close(handled)
+	}()
+	<-router.started
+
+	n, done = s.DrainConns(ziface.ConnSelector{Tag: "shard", TagValue: "3"}, ziface.DrainOption{GraceMsgID: 9, GraceData: []byte("bye")})
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []uint32{9}, busy.sent)
+	assert.Equal(t, []uint32{9}, idle.sent)
+	assert.Empty(t, other.sent)
+
+	// New requests are refused while the one in flight keeps the connection open
+	assert.False(t, beginWork(busy))
+	assert.Eventually(t, func() bool { return idle.ctx.Err() != nil }, time.Second, time.Millisecond)
+	assert.NoError(t, busy.ctx.Err())
+
+	close(router.release)
+	<-handled
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain is not done")
+	}
+	assert.Error(t, busy.ctx.Err())
+	assert.NoError(t, other.ctx.Err())
+
+	// Draining again leaves the connections already drained alone
+	n, _ = s.DrainConns(ziface.ConnSelector{Match: func(ziface.IConnection) bool { return true }}, ziface.DrainOption{})
+	assert.Equal(t, 1, n)
+}
+
+func TestDrainConnsTimeout(t *testing.T) {
+	s := newServerWithConfig(&zconf.Config{Name: "drain"}, "tcp").(*Server)
+	conn := newDrainConn(1, "3")
+	s.ConnMgr.Add(conn)
+	assert.True(t, beginWork(conn))
+
+	n, done := s.DrainConns(ziface.ConnSelector{Tag: "shard"}, ziface.DrainOption{Timeout: 20 * time.Millisecond})
+	assert.Equal(t, 1, n)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain does not time out")
+	}
+	assert.Error(t, conn.ctx.Err())
+
+	// A request done after the close does not make the count negative
+	endWork(conn)
+	endWork(conn)
+	assert.True(t, conn.idle())
+}
+
+func TestDrainConnsRouteTimeout(t *testing.T) {
+	s := newServerWithConfig(&zconf.Config{Name: "drain"}, "tcp").(*Server)
+	conn := newDrainConn(1, "3")
+	s.ConnMgr.Add(conn)
+
+	router := &blockingRouter{started: make(chan struct{}), release: make(chan struct{})}
+	mh := s.msgHandler.(*MsgHandle)
+	mh.AddRouter(1, router)
+	mh.SetRouterTimeout(1, 10*time.Millisecond)
+	assert.True(t, beginWork(conn))
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(1, nil)), 0)
+	assert.Equal(t, uint64(1), mh.GetTimeoutCount())
+
+	// The handler still runs after the timeout, the connection is closed once it returns
+	_, done := s.DrainConns(ziface.ConnSelector{Tag: "shard"}, ziface.DrainOption{})
+	time.Sleep(3 * drainPollInterval)
+	assert.False(t, conn.idle())
+	assert.NoError(t, conn.ctx.Err())
+
+	close(router.release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain is not done")
+	}
+	assert.Error(t, conn.ctx.Err())
+}
+
+func TestInterceptRefusesDraining(t *testing.T) {
+	mh := newMsgHandleWithConfig(&zconf.Config{Name: "drain"})
+	router := &countRouter{handled: make(chan uint32, 1)}
+	mh.AddRouter(1, router)
+	capture := &captureInterceptor{}
+	conn := newDrainConn(1, "3")
+	assert.True(t, conn.startDrain())
+
+	// The refused request is recycled and goes no further down the chain
+	request := NewRequest(conn, zpack.NewMsgPackage(1, nil))
+	resp := zinterceptor.NewChain([]ziface.IInterceptor{mh, capture}, 0, request).Proceed(request)
+	assert.Nil(t, resp)
+	assert.Empty(t, capture.requests)
+	assert.Empty(t, router.handled)
+	assert.True(t, conn.idle())
+}
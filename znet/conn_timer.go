@@ -0,0 +1,37 @@
+package znet
+
+import (
+	"context"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// closedCtx is used for the timers of a connection not started or already closed, they are never scheduled
+// (用于未启动或已关闭连接的定时器，这些定时器不会被调度)
+var closedCtx = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// connTimerCtx returns the context binding the timers of a connection, ctx is nil before Start
+// (返回绑定连接定时器的context，Start之前ctx为nil)
+func connTimerCtx(ctx context.Context) context.Context {
+	if ctx == nil {
+		return closedCtx
+	}
+	return ctx
+}
+
+// connAfterFunc calls f after d unless ctx, the context of conn, is done first
+// (在d之后调用f，除非conn的context ctx先结束)
+func connAfterFunc(ctx context.Context, conn ziface.IConnection, d time.Duration, f func()) ziface.IConnTimer {
+	return shardScheduler(conn).AfterFuncContext(connTimerCtx(ctx), d, f)
+}
+
+// connTickerFunc calls f every d until canceled or ctx, the context of conn, is done
+// (每隔d调用f，直到被取消或conn的context ctx结束)
+func connTickerFunc(ctx context.Context, conn ziface.IConnection, d time.Duration, f func()) ziface.IConnTimer {
+	return shardScheduler(conn).TickFuncContext(connTimerCtx(ctx), d, f)
+}
@@ -0,0 +1,44 @@
+package znet
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnTimers(t *testing.T) {
+	c := &Connection{connID: 1}
+	// Before Start the timers are never scheduled (Start之前定时器不会被调度)
+	assert.False(t, c.AfterFunc(time.Millisecond, func() {}).Pending())
+	assert.False(t, c.Ticker(time.Millisecond, func() {}).Pending())
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	var fired, ticks int32
+	c.AfterFunc(50*time.Millisecond, func() { atomic.AddInt32(&fired, 1) })
+	idle := c.AfterFunc(time.Hour, func() {})
+	ticker := c.Ticker(50*time.Millisecond, func() { atomic.AddInt32(&ticks, 1) })
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&ticks) >= 3 }, 3*time.Second, 20*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fired))
+	assert.True(t, ticker.Pending())
+
+	assert.True(t, ticker.Cancel())
+	assert.False(t, ticker.Pending())
+	stopped := atomic.LoadInt32(&ticks)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, stopped, atomic.LoadInt32(&ticks))
+
+	ticker.Reset(50 * time.Millisecond)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&ticks) > stopped }, 3*time.Second, 20*time.Millisecond)
+
+	// Closing the connection cancels its timers (关闭连接会取消其定时器)
+	c.cancel()
+	assert.Eventually(t, func() bool { return !idle.Pending() && !ticker.Pending() }, time.Second, 10*time.Millisecond)
+	closed := atomic.LoadInt32(&ticks)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, closed, atomic.LoadInt32(&ticks))
+	assert.False(t, c.AfterFunc(time.Millisecond, func() {}).Pending())
+}
@@ -11,10 +11,12 @@ import (
 	"time"
 
 	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zerrors"
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zinterceptor"
 	"github.com/aceld/zinx/zlog"
 	"github.com/aceld/zinx/zpack"
+	"github.com/aceld/zinx/ztimer"
 
 	"github.com/gorilla/websocket"
 )
@@ -53,7 +55,7 @@ type Connection struct {
 
 	// Buffered channel used for message communication between the read and write goroutines
 	// (有缓冲管道，用于读、写两个goroutine之间的消息通信)
-	msgBuffChan chan []byte
+	msgBuffChan chan queuedData
 
 	// Go StartWriter Flag
 	// (开始初始化写协程标志)
@@ -83,14 +85,21 @@ type Connection struct {
 	// (数据报文封包方式)
 	packet ziface.IDataPack
 
-	// Last activity time
-	// (最后一次活动时间)
-	lastActivityTime time.Time
+	// Last activity time, a time.Time written by the reader and read by the heartbeat checker
+	// (最后一次活动时间，由读协程写入、心跳检测器读取)
+	lastActivityTime atomic.Value
 
 	// Framedecoder for solving fragmentation and packet sticking problems
 	// (断粘包解码器)
 	frameDecoder ziface.IFrameDecoder
 
+	// Protocols of the server to pick from with the first bytes read, nil once picked
+	// (根据最先读到的字节从中选择的服务器协议，选择后置为nil)
+	protocols []ziface.Protocol
+
+	// Hooks called with every outbound message before it is packed (每条发出消息封包前调用的钩子)
+	sendInterceptors []ziface.SendInterceptor
+
 	// Heartbeat checker
 	// (心跳检测器)
 	hc ziface.IHeartbeatChecker
@@ -112,6 +121,12 @@ type Connection struct {
 
 	// Close callback mutex
 	closeCallbackMutex sync.RWMutex
+
+	// Why the connection was closed, for the metrics (连接关闭的原因，用于指标统计)
+	closeReason
+	connTraffic
+	connDrain
+	connFragments
 }
 
 // newServerConn :for Server, method to create a Server-side connection with Server-specific properties
@@ -138,6 +153,8 @@ func newServerConn(server ziface.IServer, conn net.Conn, connID uint64) ziface.I
 
 	// Inherited properties from server (从server继承过来的属性)
 	c.packet = server.GetPacket()
+	c.protocols = server.GetProtocols()
+	c.sendInterceptors = server.GetSendInterceptors()
 	c.onConnStart = server.GetOnConnStart()
 	c.onConnStop = server.GetOnConnStop()
 	c.msgHandler = server.GetMsgHandler()
@@ -175,6 +192,7 @@ func newClientConn(client ziface.IClient, conn net.Conn) ziface.IConnection {
 
 	// Inherited properties from server (从client继承过来的属性)
 	c.packet = client.GetPacket()
+	c.sendInterceptors = client.GetSendInterceptors()
 	c.onConnStart = client.GetOnConnStart()
 	c.onConnStop = client.GetOnConnStop()
 	c.msgHandler = client.GetMsgHandler()
@@ -185,20 +203,25 @@ func newClientConn(client ziface.IClient, conn net.Conn) ziface.IConnection {
 // StartWriter is the goroutine that writes messages to the client
 // (写消息Goroutine， 用户将数据发送给客户端)
 func (c *Connection) StartWriter() {
-	zlog.Ins().InfoF("Writer Goroutine is running")
-	defer zlog.Ins().InfoF("%s [conn Writer exit!]", c.RemoteAddr().String())
+	LoggerOf(c).InfoFX(connLogCtx(c), "Writer Goroutine is running")
+	defer LoggerOf(c).InfoFX(connLogCtx(c), "%s [conn Writer exit!]", c.RemoteAddr().String())
 
 	for {
 		select {
-		case data, ok := <-c.msgBuffChan:
+		case queued, ok := <-c.msgBuffChan:
 			if ok {
-				if err := c.Send(data); err != nil {
-					zlog.Ins().ErrorF("Send Buff Data error:, %s Conn Writer exit", err)
+				err := c.Send(queued.data)
+				// Hand the pooled pack buffer back once it has been written (写出后归还池化的封包缓冲)
+				if queued.packed {
+					releasePacked(c.packet, queued.data)
+				}
+				if err != nil {
+					LoggerOf(c).ErrorFX(connLogCtx(c), "Send Buff Data error:, %s Conn Writer exit", err)
 					break
 				}
 
 			} else {
-				zlog.Ins().ErrorF("msgBuffChan is Closed")
+				LoggerOf(c).ErrorFX(connLogCtx(c), "msgBuffChan is Closed")
 				break
 			}
 		case <-c.ctx.Done():
@@ -210,18 +233,18 @@ func (c *Connection) StartWriter() {
 // StartReader is a goroutine that reads data from the client
 // (读消息Goroutine，用于从客户端中读取数据)
 func (c *Connection) StartReader() {
-	zlog.Ins().InfoF("[Reader Goroutine is running]")
-	defer zlog.Ins().InfoF("%s [conn Reader exit!]", c.RemoteAddr().String())
+	LoggerOf(c).InfoFX(connLogCtx(c), "[Reader Goroutine is running]")
+	defer LoggerOf(c).InfoFX(connLogCtx(c), "%s [conn Reader exit!]", c.RemoteAddr().String())
 	defer c.Stop()
 	defer func() {
 		if err := recover(); err != nil {
-			zlog.Ins().ErrorF("connID=%d, panic err=%v", c.GetConnID(), err)
+			LoggerOf(c).ErrorFX(connLogCtx(c), "connID=%d, panic err=%v", c.GetConnID(), err)
 		}
 	}()
 
 	//Reduce buffer allocation times to improve efficiency
 	// add by ray 2023-02-03
-	buffer := make([]byte, zconf.GlobalObject.IOReadBuffSize)
+	readBuf := newReadBuffer(configOf(c))
 
 	for {
 		select {
@@ -231,12 +254,18 @@ func (c *Connection) StartReader() {
 
 			// read data from the connection's IO into the memory buffer
 			// (从conn的IO中读取数据到内存缓冲buffer中)
+			buffer := readBuf.bytes()
 			n, err := c.conn.Read(buffer)
 			if err != nil {
-				zlog.Ins().ErrorF("read msg head [read datalen=%d], error = %s", n, err)
+				LoggerOf(c).ErrorFX(connLogCtx(c), "read msg head [read datalen=%d], error = %s", n, err)
+				c.setCloseReason(readCloseReason(err))
 				return
 			}
-			zlog.Ins().DebugF("read buffer %s \n", hex.EncodeToString(buffer[0:n]))
+			readBuf.record(n)
+			c.received(metricsOf(c), n)
+			if zlog.DebugEnabled(LoggerOf(c)) {
+				LoggerOf(c).DebugFX(connLogCtx(c), "read buffer %s \n", hex.EncodeToString(buffer[0:n]))
+			}
 
 			// If normal data is read from the peer, update the heartbeat detection Active state
 			// (正常读取到对端数据，更新心跳检测Active状态)
@@ -244,25 +273,31 @@ func (c *Connection) StartReader() {
 				c.updateActivity()
 			}
 
+			data := buffer[0:n]
+			// Pick the protocol of the connection from the first bytes read (根据最先读到的字节选择连接的协议)
+			if c.protocols != nil {
+				data = c.selectProtocol(data)
+			}
+
 			// Deal with the custom protocol fragmentation problem, added by uuxia 2023-03-21
 			// (处理自定义协议断粘包问题)
 			if c.frameDecoder != nil {
 				// Decode the 0-n bytes of data read
 				// (为读取到的0-n个字节的数据进行解码)
-				bufArrays := c.frameDecoder.Decode(buffer[0:n])
-				if bufArrays == nil {
-					continue
-				}
+				bufArrays, ok := decodeFrames(c, c.frameDecoder, data)
 				for _, bytes := range bufArrays {
-					// zlog.Ins().DebugF("read buffer %s \n", hex.EncodeToString(bytes))
-					msg := zpack.NewMessage(uint32(len(bytes)), bytes)
+					// LoggerOf(c).DebugFX(connLogCtx(c), "read buffer %s \n", hex.EncodeToString(bytes))
+					msg := getMessage(c, bytes)
 					// Get the current client's Request data
 					// (得到当前客户端请求的Request数据)
 					req := GetRequest(c, msg)
 					c.msgHandler.Execute(req)
 				}
+				if !ok {
+					return
+				}
 			} else {
-				msg := zpack.NewMessage(uint32(n), buffer[0:n])
+				msg := getMessage(c, data)
 				// Get the current client's Request data
 				// (得到当前客户端请求的Request数据)
 				req := GetRequest(c, msg)
@@ -275,12 +310,17 @@ func (c *Connection) StartReader() {
 // Start starts the connection and makes the current connection work.
 // (启动连接，让当前连接开始工作)
 func (c *Connection) Start() {
+	c.StartContext(context.Background())
+}
+
+// StartContext starts the connection, which stops once ctx is done (启动连接，ctx结束时连接停止)
+func (c *Connection) StartContext(ctx context.Context) {
 	defer func() {
 		if err := recover(); err != nil {
-			zlog.Ins().ErrorF("Connection Start() error: %v", err)
+			LoggerOf(c).ErrorFX(connLogCtx(c), "Connection Start() error: %v", err)
 		}
 	}()
-	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.ctx, c.cancel = context.WithCancel(ctx)
 
 	// Execute the hook method for processing business logic when creating a connection
 	// (按照用户传递进来的创建连接时需要处理的业务，执行钩子方法)
@@ -350,22 +390,31 @@ func (c *Connection) LocalAddr() net.Addr {
 
 func (c *Connection) Send(data []byte) error {
 	if c.isClosed() == true {
-		return errors.New("connection closed when send msg")
+		return connError(c, "send msg", zerrors.ErrConnClosed)
 	}
 
 	_, err := c.conn.Write(data)
 	if err != nil {
-		zlog.Ins().ErrorF("SendMsg err data = %+v, err = %+v", data, err)
+		if ctx, ok := sendErrLog.Allow(connLogCtx(c)); ok {
+			LoggerOf(c).ErrorFX(ctx, "SendMsg err data = %+v, err = %+v", data, err)
+		}
 		return err
 	}
+	c.sent(metricsOf(c), data)
 
 	return nil
 }
 
 func (c *Connection) SendToQueue(data []byte) error {
+	return c.sendToQueue(data, false)
+}
+
+// sendToQueue queues data to the writer, packed data comes from Pack and is handed back to the datapack once written
+// (将data放入写队列，packed的数据来自Pack，写出后归还数据包)
+func (c *Connection) sendToQueue(data []byte, packed bool) error {
 
 	if c.msgBuffChan == nil && c.setStartWriterFlag() {
-		c.msgBuffChan = make(chan []byte, zconf.GlobalObject.MaxMsgChanLen)
+		c.msgBuffChan = make(chan queuedData, configOf(c).MaxMsgChanLen)
 		// Start a Goroutine to write data back to the client
 		// This method only reads data from the MsgBuffChan without allocating memory or starting a Goroutine
 		// (开启用于写回客户端数据流程的Goroutine
@@ -373,15 +422,15 @@ func (c *Connection) SendToQueue(data []byte) error {
 		go c.StartWriter()
 	}
 
-	idleTimeout := time.NewTimer(5 * time.Millisecond)
+	idleTimeout := time.NewTimer(sendBuffWait)
 	defer idleTimeout.Stop()
 
 	if c.isClosed() == true {
-		return errors.New("Connection closed when send buff msg")
+		return connError(c, "send buff msg", zerrors.ErrConnClosed)
 	}
 
 	if data == nil {
-		zlog.Ins().ErrorF("Pack data is nil")
+		LoggerOf(c).ErrorFX(connLogCtx(c), "Pack data is nil")
 		return errors.New("Pack data is nil")
 	}
 
@@ -390,10 +439,10 @@ func (c *Connection) SendToQueue(data []byte) error {
 	case <-c.ctx.Done():
 		// Close all channels associated with the connection
 		close(c.msgBuffChan)
-		return errors.New("connection closed when send buff msg")
+		return connError(c, "send buff msg", zerrors.ErrConnClosed)
 	case <-idleTimeout.C:
-		return errors.New("send buff msg timeout")
-	case c.msgBuffChan <- data:
+		return connError(c, "send buff msg", &zerrors.SendQueueFullError{Wait: sendBuffWait})
+	case c.msgBuffChan <- queuedData{data: data, packed: packed}:
 		return nil
 	}
 }
@@ -401,33 +450,106 @@ func (c *Connection) SendToQueue(data []byte) error {
 // SendMsg directly sends Message data to the remote TCP client.
 // (直接将Message数据发送数据给远程的TCP客户端)
 func (c *Connection) SendMsg(msgID uint32, data []byte) error {
+	return c.SendMessage(zpack.NewMsgPackage(msgID, data))
+}
+
+// SendMessage packs and sends msg directly, datapacks can carry extra header fields of msg such as the trace ID
+// (直接封包并发送msg，数据包可以携带msg的额外头部字段，例如链路追踪ID)
+func (c *Connection) SendMessage(msg ziface.IMessage) error {
+	msg, err := interceptSend(c, c.sendInterceptors, msg)
+	if err != nil {
+		return err
+	}
+	observeMessage(c, directionOutbound, msg.GetMsgID(), msg.GetData())
+	// Messages larger than MaxPacketSize are sent in fragments (超过MaxPacketSize的消息分片发送)
+	if handled, err := sendFragments(c, msg, c.SendMessage); handled {
+		return err
+	}
+	// Compress the data with the negotiated algorithm and sign it (使用协商的算法压缩数据并签名)
+	msg, err = sealMessage(c, msg)
+	if err != nil {
+		return err
+	}
+	msgID := msg.GetMsgID()
+	span := traceSend(c, msg)
+	defer span.End()
 
 	if c.isClosed() == true {
-		return errors.New("connection closed when send msg")
+		return connError(c, "send msg", zerrors.ErrConnClosed)
 	}
 	// Pack data and send it
-	msg, err := c.packet.Pack(zpack.NewMsgPackage(msgID, data))
+	buf, err := c.packet.Pack(msg)
 	if err != nil {
-		zlog.Ins().ErrorF("Pack error msg ID = %d", msgID)
-		return errors.New("Pack error msg ")
+		if ctx, ok := sendErrLog.Allow(connLogCtx(c)); ok {
+			LoggerOf(c).ErrorFX(ctx, "Pack error msg ID = %d", msgID)
+		}
+		span.SetError(err)
+		return connError(c, "pack msg", err)
+	}
+	// A simulated network delivers the message later through the writer (模拟的网络稍后通过写协程投递消息)
+	if deferSend(c, buf) {
+		return nil
+	}
+	// Hand the pooled pack buffer back once it has been written (写出后归还池化的封包缓冲)
+	if releaser, ok := c.packet.(ziface.IBufferReleaser); ok {
+		defer releaser.ReleaseBuffer(buf)
 	}
 
-	err = c.Send(msg)
+	err = c.Send(buf)
 	if err != nil {
-		zlog.Ins().ErrorF("SendMsg err msg ID = %d, data = %+v, err = %+v", msgID, string(msg), err)
+		if ctx, ok := sendErrLog.Allow(connLogCtx(c)); ok {
+			LoggerOf(c).ErrorFX(ctx, "SendMsg err msg ID = %d, data = %+v, err = %+v", msgID, string(buf), err)
+		}
+		span.SetError(err)
 		return err
 	}
 
 	return nil
 }
 
+func (c *Connection) SendMsgAfter(delay time.Duration, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	return scheduleSend(c, time.Now().Add(delay), msgID, data)
+}
+
+func (c *Connection) SendMsgAt(at time.Time, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	return scheduleSend(c, at, msgID, data)
+}
+
+func (c *Connection) AfterFunc(d time.Duration, f func()) ziface.IConnTimer {
+	return connAfterFunc(c.ctx, c, d, f)
+}
+
+func (c *Connection) Ticker(d time.Duration, f func()) ziface.IConnTimer {
+	return connTickerFunc(c.ctx, c, d, f)
+}
+
 func (c *Connection) SendBuffMsg(msgID uint32, data []byte) error {
-	msg, err := c.packet.Pack(zpack.NewMsgPackage(msgID, data))
+	intercepted, err := interceptSend(c, c.sendInterceptors, zpack.NewMsgPackage(msgID, data))
+	if err != nil {
+		return err
+	}
+	msgID = intercepted.GetMsgID()
+	observeMessage(c, directionOutbound, msgID, intercepted.GetData())
+	if handled, err := sendFragments(c, intercepted, func(fragment ziface.IMessage) error {
+		return c.SendBuffMsg(fragment.GetMsgID(), fragment.GetData())
+	}); handled {
+		return err
+	}
+	sealed, err := sealMessage(c, intercepted)
+	if err != nil {
+		return err
+	}
+	msg, err := c.packet.Pack(sealed)
 	if err != nil {
-		zlog.Ins().ErrorF("Pack error msg ID = %d", msgID)
-		return errors.New("Pack error msg ")
+		if ctx, ok := sendErrLog.Allow(connLogCtx(c)); ok {
+			LoggerOf(c).ErrorFX(ctx, "Pack error msg ID = %d", msgID)
+		}
+		return connError(c, "pack msg", err)
+	}
+	if deferSend(c, msg) {
+		return nil
 	}
-	return c.SendToQueue(msg)
+	return c.sendToQueue(msg, true)
 
 }
 
@@ -452,6 +574,18 @@ func (c *Connection) GetProperty(key string) (interface{}, error) {
 	return nil, errors.New("no property found")
 }
 
+// GetProperties returns a copy of the properties of the connection (返回连接属性的副本)
+func (c *Connection) GetProperties() map[string]interface{} {
+	c.propertyLock.Lock()
+	defer c.propertyLock.Unlock()
+
+	properties := make(map[string]interface{}, len(c.property))
+	for key, value := range c.property {
+		properties[key] = value
+	}
+	return properties
+}
+
 func (c *Connection) RemoveProperty(key string) {
 	c.propertyLock.Lock()
 	defer c.propertyLock.Unlock()
@@ -459,6 +593,19 @@ func (c *Connection) RemoveProperty(key string) {
 	delete(c.property, key)
 }
 
+// SetTenant accounts the traffic of the connection to tenant (将连接的流量计入租户tenant)
+func (c *Connection) SetTenant(tenant string) {
+	setConnTenant(c.connManager, c, tenant)
+}
+
+func (c *Connection) GetTenant() string {
+	return c.tenantName()
+}
+
+func (c *Connection) GetTraffic() ziface.TrafficStats {
+	return c.stats()
+}
+
 func (c *Connection) Context() context.Context {
 	return c.ctx
 }
@@ -484,26 +631,26 @@ func (c *Connection) finalizer() {
 	go func() {
 		defer func() {
 			if err := recover(); err != nil {
-				zlog.Ins().ErrorF("Conn finalizer panic: %v", err)
+				LoggerOf(c).ErrorFX(connLogCtx(c), "Conn finalizer panic: %v", err)
 			}
 		}()
 
 		c.InvokeCloseCallbacks()
 	}()
 
-	zlog.Ins().InfoF("Conn Stop()...ConnID = %d", c.connID)
+	LoggerOf(c).InfoFX(connLogCtx(c), "Conn Stop()...ConnID = %d", c.connID)
 }
 
 func (c *Connection) callOnConnStart() {
 	if c.onConnStart != nil {
-		zlog.Ins().InfoF("ZINX CallOnConnStart....")
+		LoggerOf(c).InfoFX(connLogCtx(c), "ZINX CallOnConnStart....")
 		c.onConnStart(c)
 	}
 }
 
 func (c *Connection) callOnConnStop() {
 	if c.onConnStop != nil {
-		zlog.Ins().InfoF("ZINX CallOnConnStop....")
+		LoggerOf(c).InfoFX(connLogCtx(c), "ZINX CallOnConnStop....")
 		c.onConnStop(c)
 	}
 }
@@ -515,11 +662,12 @@ func (c *Connection) IsAlive() bool {
 	// Check the last activity time of the connection. If it's beyond the heartbeat interval,
 	// then the connection is considered dead.
 	// (检查连接最后一次活动时间，如果超过心跳间隔，则认为连接已经死亡)
-	return time.Now().Sub(c.lastActivityTime) < zconf.GlobalObject.HeartbeatMaxDuration()
+	last, _ := c.lastActivityTime.Load().(time.Time)
+	return ztimer.Now().Sub(last) < configOf(c).Live().HeartbeatMaxDuration()
 }
 
 func (c *Connection) updateActivity() {
-	c.lastActivityTime = time.Now()
+	c.lastActivityTime.Store(ztimer.Now())
 }
 
 func (c *Connection) SetHeartBeat(checker ziface.IHeartbeatChecker) {
@@ -573,3 +721,54 @@ func (s *Connection) InvokeCloseCallbacks() {
 	defer s.closeCallbackMutex.RUnlock()
 	s.closeCallback.Invoke()
 }
+
+// selectProtocol switches the connection to the protocol matching its first bytes, it returns
+// data without the Hello of the protocol.
+// (将连接切换为与最先读到的字节匹配的协议，返回去掉协议Hello之后的数据)
+func (c *Connection) selectProtocol(data []byte) []byte {
+	p, data := matchProtocol(c.protocols, data)
+	c.protocols = nil
+	if p == nil {
+		return data
+	}
+
+	if p.Packet != nil {
+		c.packet = p.Packet
+	}
+	if p.Decoder != nil {
+		c.frameDecoder = nil
+		if lengthField := p.Decoder.GetLengthField(); lengthField != nil {
+			c.frameDecoder = zinterceptor.NewFrameDecoder(*lengthField)
+		}
+	}
+	c.SetProperty(protocolKey, p)
+	LoggerOf(c).InfoFX(connLogCtx(c), "connID = %d selected protocol %s", c.GetConnID(), p.Name)
+	return data
+}
+
+// queuedData is the data queued to the writer, packed tells whether it is a pack buffer to hand back once written,
+// the data queued by SendToQueue belongs to the caller
+// (放入写队列的数据，packed表示其是否为写出后需要归还的封包缓冲，SendToQueue放入的数据属于调用方)
+type queuedData struct {
+	data   []byte
+	packed bool
+}
+
+// releasePacked hands buf back to packet if its Pack takes buffers from a pool (Pack使用缓冲池时将buf归还packet)
+func releasePacked(packet ziface.IDataPack, buf []byte) {
+	if releaser, ok := packet.(ziface.IBufferReleaser); ok {
+		releaser.ReleaseBuffer(buf)
+	}
+}
+
+// configOf returns the config of the server or the client owning conn, zconf.GlobalObject if it has none
+// (返回conn所属服务或客户端的配置，没有时返回zconf.GlobalObject)
+func configOf(conn ziface.IConnection) *zconf.Config {
+	if conn == nil {
+		return zconf.GlobalObject
+	}
+	if mh, ok := conn.GetMsgHandler().(*MsgHandle); ok && mh.config != nil {
+		return mh.config
+	}
+	return zconf.GlobalObject
+}
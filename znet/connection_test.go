@@ -0,0 +1,57 @@
+package znet
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+// releaseRecorder records the pack buffers handed back (记录归还的封包缓冲)
+type releaseRecorder struct {
+	ziface.IDataPack
+	mu       sync.Mutex
+	released int
+}
+
+func (r *releaseRecorder) ReleaseBuffer(buf []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.released++
+}
+
+func (r *releaseRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.released
+}
+
+func TestSendBuffMsgReleasesBuffer(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	packet := &releaseRecorder{IDataPack: zpack.Factory().NewPack(ziface.ZinxDataPack)}
+	c := newTestConn(1)
+	c.conn = server
+	c.packet = packet
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	defer c.cancel()
+
+	assert.Nil(t, c.SendBuffMsg(1, []byte("hello")))
+	buf := make([]byte, packet.GetHeadLen()+5)
+	_, err := io.ReadFull(client, buf)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return packet.count() == 1 }, time.Second, time.Millisecond)
+
+	// The data queued by the caller is not handed back (调用方放入队列的数据不会被归还)
+	assert.Nil(t, c.SendToQueue([]byte("raw")))
+	_, err = io.ReadFull(client, buf[:3])
+	assert.Nil(t, err)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 1, packet.count())
+}
@@ -3,14 +3,21 @@ package znet
 import (
 	"errors"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
 	"github.com/aceld/zinx/zutils"
 )
 
+// ConnManager keeps the connections in a sharded map so that Accept and Close on different shards do not contend,
+// Len is a counter and Range iterates over a copy of each shard, neither locks the whole map
+// (ConnManager将连接保存在分片map中，不同分片上的Accept和Close互不竞争，
+// Len为计数器，Range遍历每个分片的副本，都不会锁住整个map)
 type ConnManager struct {
 	connections zutils.ShardLockMaps
+	count       int64
+	tenants     tenants
 }
 
 func newConnManager() *ConnManager {
@@ -21,14 +28,27 @@ func newConnManager() *ConnManager {
 
 func (connMgr *ConnManager) Add(conn ziface.IConnection) {
 
-	connMgr.connections.Set(conn.GetConnIdStr(), conn) // 将conn连接添加到ConnManager中
+	// 将conn连接添加到ConnManager中
+	if connMgr.connections.SetNX(conn.GetConnIdStr(), conn) {
+		atomic.AddInt64(&connMgr.count, 1)
+	} else {
+		connMgr.connections.Set(conn.GetConnIdStr(), conn)
+	}
+	connAdded(conn)
 
 	zlog.Ins().DebugF("connection add to ConnManager successfully: conn num = %d", connMgr.Len())
 }
 
 func (connMgr *ConnManager) Remove(conn ziface.IConnection) {
 
-	connMgr.connections.Remove(conn.GetConnIdStr()) // 删除连接信息
+	// 删除连接信息
+	if _, ok := connMgr.connections.Pop(conn.GetConnIdStr()); ok {
+		atomic.AddInt64(&connMgr.count, -1)
+	}
+	connRemoved(conn)
+	if t, ok := conn.(trafficCounter); ok {
+		t.setTenant(nil)
+	}
 
 	zlog.Ins().DebugF("connection Remove ConnID=%d successfully: conn num = %d", conn.GetConnID(), connMgr.Len())
 }
@@ -54,23 +74,19 @@ func (connMgr *ConnManager) Get2(strConnId string) (ziface.IConnection, error) {
 }
 
 func (connMgr *ConnManager) Len() int {
-
-	length := connMgr.connections.Count()
-
-	return length
+	return int(atomic.LoadInt64(&connMgr.count))
 }
 
 func (connMgr *ConnManager) ClearConn() {
 
 	// Stop and delete all connection information
-	for item := range connMgr.connections.IterBuffered() {
-		val := item.Val
+	connMgr.connections.IterCbSnapshot(func(key string, val interface{}) {
 		if conn, ok := val.(ziface.IConnection); ok {
 			// stop will eventually trigger the deletion of the connection,
 			// no additional deletion is required
 			conn.Stop()
 		}
-	}
+	})
 
 	zlog.Ins().InfoF("Clear All Connections successfully: conn num = %d", connMgr.Len())
 }
@@ -96,9 +112,12 @@ func (connMgr *ConnManager) GetAllConnIdStr() []string {
 	return connMgr.connections.Keys()
 }
 
+// Range calls cb for every connection without holding the lock of its shard, so a broadcast does not block Add and
+// Remove, a connection added or removed meanwhile may be missed or still be seen
+// (对每个连接调用cb，期间不持有其分片的锁，因此广播不会阻塞Add和Remove，期间增删的连接可能被遗漏或仍被看到)
 func (connMgr *ConnManager) Range(cb func(uint64, ziface.IConnection, interface{}) error, args interface{}) (err error) {
 
-	connMgr.connections.IterCb(func(key string, v interface{}) {
+	connMgr.connections.IterCbSnapshot(func(key string, v interface{}) {
 		conn, _ := v.(ziface.IConnection)
 		connId, _ := strconv.ParseUint(key, 10, 64)
 		err = cb(connId, conn, args)
@@ -110,10 +129,11 @@ func (connMgr *ConnManager) Range(cb func(uint64, ziface.IConnection, interface{
 	return err
 }
 
-// Range2 It is recommended to use this method to 'Range'
+// Range2 It is recommended to use this method to 'Range',
+// like Range, cb runs without holding any lock (与Range一样，cb运行时不持有锁)
 func (connMgr *ConnManager) Range2(cb func(string, ziface.IConnection, interface{}) error, args interface{}) (err error) {
 
-	connMgr.connections.IterCb(func(key string, v interface{}) {
+	connMgr.connections.IterCbSnapshot(func(key string, v interface{}) {
 		conn, _ := v.(ziface.IConnection)
 		err = cb(conn.GetConnIdStr(), conn, args)
 		if err != nil {
@@ -0,0 +1,45 @@
+package znet
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnManagerRange(t *testing.T) {
+	mgr := newConnManager()
+	for i := uint64(1); i <= 100; i++ {
+		mgr.Add(newTestConn(i))
+	}
+	// Adding a connection again does not count it twice (重复添加连接不会重复计数)
+	c1, _ := mgr.Get(1)
+	mgr.Add(c1)
+	assert.Equal(t, 100, mgr.Len())
+
+	// No lock is held while the callback runs, it can remove and add connections
+	// (回调运行时不持有锁，可以删除和添加连接)
+	var ranged int
+	err := mgr.Range(func(connID uint64, conn ziface.IConnection, _ interface{}) error {
+		ranged++
+		mgr.Remove(conn)
+		mgr.Add(newTestConn(connID + 1000))
+		return nil
+	}, nil)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, ranged, 100)
+	assert.Equal(t, 100, mgr.Len())
+	_, err = mgr.Get(1)
+	assert.NotNil(t, err)
+
+	mgr.Range2(func(_ string, conn ziface.IConnection, _ interface{}) error {
+		mgr.Remove(conn)
+		return nil
+	}, nil)
+	assert.Equal(t, 0, mgr.Len())
+}
+
+func newTestConn(connID uint64) *Connection {
+	return &Connection{connID: connID, connIdStr: strconv.FormatUint(connID, 10)}
+}
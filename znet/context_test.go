@@ -0,0 +1,216 @@
+package znet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartWorkerPoolContext(t *testing.T) {
+	mh := newMsgHandle()
+	assert.Nil(t, mh.ResizeWorkerPool(2))
+	mh.AddWorkerPool("slow", 1, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	mh.StartWorkerPoolContext(ctx)
+	workers := func() int { return len(mh.GetWorkerStats()) }
+	assert.Eventually(t, func() bool { return workers() == 3 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.Eventually(t, func() bool { return workers() == 0 }, time.Second, 10*time.Millisecond)
+
+	// Starting again restarts the dedicated pools too (再次启动时专属池也会重新启动)
+	mh.StartWorkerPoolContext(context.Background())
+	assert.Eventually(t, func() bool { return workers() == 3 }, time.Second, 10*time.Millisecond)
+}
+
+func TestStartContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := newServerWithConfig(zconf.DefaultConfig(), "tcp", WithListener(listener)).(*Server)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.StartContext(ctx)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	assert.Eventually(t, func() bool { return s.GetConnMgr().Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	// Canceling ctx stops the server and closes its connections (取消ctx会停止服务并关闭其连接)
+	cancel()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+	assert.NotNil(t, s.Context().Err())
+	assert.Eventually(t, func() bool { return s.GetConnMgr().Len() == 0 }, time.Second, 10*time.Millisecond)
+	// A stopped server is stopped once (已停止的服务只停止一次)
+	s.Stop()
+}
+
+func TestServeContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := newServerWithConfig(zconf.DefaultConfig(), "tcp", WithListener(listener)).(*Server)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		s.ServeContext(ctx)
+		close(served)
+	}()
+
+	var conn net.Conn
+	assert.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", listener.Addr().String())
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer conn.Close()
+	assert.Eventually(t, func() bool { return s.GetConnMgr().Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	// Canceling ctx stops the server and returns from ServeContext (取消ctx会停止服务并从ServeContext返回)
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("ServeContext does not return")
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+	assert.Eventually(t, func() bool { return s.GetConnMgr().Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+// A server whose port is busy stops and Serve returns with the error (端口被占用的服务停止，Serve返回并给出错误)
+func TestServeBusyPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	config := zconf.DefaultConfig()
+	config.Host = "127.0.0.1"
+	config.TCPPort = listener.Addr().(*net.TCPAddr).Port
+	config.Mode = zconf.ServerModeTcp
+	s := newServerWithConfig(config, "tcp").(*Server)
+
+	served := make(chan struct{})
+	go func() {
+		s.Serve()
+		close(served)
+	}()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("Serve does not return")
+	}
+	assert.NotNil(t, s.Err())
+	assert.NotNil(t, s.Context().Err())
+}
+
+func TestServeBadCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "zinx"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		file := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(file, data, 0600))
+		return file
+	}
+	certFile := write("cert.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyFile := write("key.pem", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	garbage := write("garbage.pem", []byte("not a certificate"))
+
+	for name, files := range map[string][3]string{
+		"cert": {garbage, keyFile, ""},
+		"ca":   {certFile, keyFile, garbage},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := zconf.DefaultConfig()
+			config.Host = "127.0.0.1"
+			config.Mode = zconf.ServerModeTcp
+			config.CertFile, config.PrivateKeyFile, config.ClientCAFile = files[0], files[1], files[2]
+			s := newServerWithConfig(config, "tcp").(*Server)
+
+			served := make(chan struct{})
+			go func() {
+				s.Serve()
+				close(served)
+			}()
+			select {
+			case <-served:
+			case <-time.After(time.Second):
+				t.Fatal("Serve does not return")
+			}
+			assert.NotNil(t, s.Err())
+		})
+	}
+}
+
+func TestClientStartContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := NewClient("127.0.0.1", 1).(*Client)
+	c.StartContext(ctx)
+	select {
+	case err := <-c.ErrChan:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("the dial is not canceled")
+	}
+}
+
+func TestStartContextChainOnce(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.FragmentMode = true
+	config.ReplayWindow = 1000
+	s := newServerWithConfig(config, "tcp").(*Server)
+	mh := s.msgHandler.(*MsgHandle)
+	s.initChain(mh)
+	body, sources := len(mh.builder.body), mh.sources
+
+	// A restarted server keeps its chain (重启的服务保留其责任链)
+	s.initChain(mh)
+	assert.Equal(t, body, len(mh.builder.body))
+	assert.Same(t, sources, mh.sources)
+}
+
+func TestHeartbeatCheckerContext(t *testing.T) {
+	conn := newTestConn(1)
+	var cancel context.CancelFunc
+	conn.ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	h := NewHeartbeatChecker(time.Hour).(*HeartbeatChecker)
+	h.conn = conn
+
+	// Stopping a checker that is not running does not block (停止未运行的检测不会阻塞)
+	stopped := make(chan struct{})
+	go func() {
+		h.Stop()
+		h.Start()
+		h.Stop()
+		h.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop blocks")
+	}
+	assert.Nil(t, h.cancel)
+}
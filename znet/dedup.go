@@ -0,0 +1,193 @@
+package znet
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// DedupKeyFunc returns the identity of a request, requests with the same key within the window are duplicates,
+// returning "" skips deduplication of the request
+// (返回请求的标识，窗口期内标识相同的请求视为重复请求，返回空字符串表示该请求不参与去重)
+type DedupKeyFunc func(request ziface.IRequest) string
+
+// DefaultDedupKey identifies a request by ConnID, MsgID and the FNV hash of its data
+// (默认以ConnID、MsgID和数据的FNV哈希作为请求标识)
+func DefaultDedupKey(request ziface.IRequest) string {
+	h := fnv.New64a()
+	_, _ = h.Write(request.GetData())
+	return strconv.FormatUint(connIDOf(request.GetConnection()), 10) + ":" +
+		strconv.FormatUint(uint64(request.GetMsgID()), 10) + ":" +
+		strconv.FormatUint(h.Sum64(), 16)
+}
+
+// DedupKeyByValue identifies a request by the client provided request ID stored under key,
+// e.g. set by a decoder from a header field
+// (以客户端提供的请求ID作为标识，请求ID由解码器等通过request.Set保存在key下)
+func DedupKeyByValue(key string) DedupKeyFunc {
+	return func(request ziface.IRequest) string {
+		id := request.GetString(key)
+		if id == "" {
+			return ""
+		}
+		return strconv.FormatUint(connIDOf(request.GetConnection()), 10) + ":" + id
+	}
+}
+
+// Deduplicator drops identical requests within a window, e.g. retry storms of a costly operation
+// (在窗口期内丢弃相同的请求，例如对耗时操作的重试风暴)
+type Deduplicator struct {
+	window    time.Duration
+	key       DedupKeyFunc
+	seen      map[string]time.Time
+	lastSweep time.Time
+	sync.Mutex
+}
+
+// NewDeduplicator creates a Deduplicator, key defaults to DefaultDedupKey
+// (创建请求去重器，key默认为DefaultDedupKey)
+func NewDeduplicator(window time.Duration, key DedupKeyFunc) *Deduplicator {
+	if key == nil {
+		key = DefaultDedupKey
+	}
+	return &Deduplicator{
+		window:    window,
+		key:       key,
+		seen:      make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow reports whether the request is the first one with its key within the window
+// (判断请求是否是窗口期内该标识的第一个请求)
+func (d *Deduplicator) Allow(request ziface.IRequest) bool {
+	key := d.key(request)
+	if key == "" {
+		return true
+	}
+
+	now := time.Now()
+
+	d.Lock()
+	defer d.Unlock()
+
+	// Expired keys are swept at most once per window (过期的标识每个窗口期最多清理一次)
+	if now.Sub(d.lastSweep) > d.window {
+		for k, t := range d.seen {
+			if now.Sub(t) > d.window {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	if t, ok := d.seen[key]; ok && now.Sub(t) <= d.window {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
+
+// duplicate reports whether the request is a duplicate to drop, counting and logging it
+// (判断请求是否为需丢弃的重复请求，并进行计数与日志记录)
+func (d *Deduplicator) duplicate(request ziface.IRequest) bool {
+	if d.Allow(request) {
+		return false
+	}
+	metricsOf(request.GetConnection()).RequestsDropped.With(dropReasonDuplicate).Inc()
+	LoggerOf(request.GetConnection()).DebugFX(requestLogCtx(request), "drop duplicate request msgID = %d", request.GetMsgID())
+	return true
+}
+
+// Handler returns the RouterSlicesMode middleware of the Deduplicator (返回RouterSlicesMode下的去重中间件)
+func (d *Deduplicator) Handler() ziface.RouterHandler {
+	return func(request ziface.IRequest) {
+		if d.duplicate(request) {
+			request.Abort()
+		}
+	}
+}
+
+// Wrap returns router dropping the duplicates in PreHandle, for both the IRouter mode and RouterSlicesMode
+// (返回在PreHandle中丢弃重复请求的路由，IRouter模式与RouterSlicesMode均适用)
+func (d *Deduplicator) Wrap(router ziface.IRouter) ziface.IRouter {
+	return &dropRouter{IRouter: router, drop: d.duplicate}
+}
+
+// RouterDedup is a RouterSlicesMode middleware dropping identical requests within window
+// (RouterSlicesMode下的中间件，丢弃窗口期内相同的请求)
+func RouterDedup(window time.Duration, key DedupKeyFunc) ziface.RouterHandler {
+	return NewDeduplicator(window, key).Handler()
+}
+
+// DedupRouter returns router dropping identical requests within window, the IRouter counterpart of RouterDedup
+// (返回丢弃窗口期内相同请求的路由，对应RouterDedup的IRouter版本)
+func DedupRouter(router ziface.IRouter, window time.Duration, key DedupKeyFunc) ziface.IRouter {
+	return NewDeduplicator(window, key).Wrap(router)
+}
+
+// IsStale reports whether the request has waited longer than ttl since it was read from the connection
+// (判断请求从连接读取后等待的时间是否超过ttl)
+func IsStale(request ziface.IRequest, ttl time.Duration) bool {
+	created := request.GetCreateTime()
+	return !created.IsZero() && time.Since(created) > ttl
+}
+
+// staleAfter returns whether a request is stale to drop, counting the drops and logging them sampled,
+// a backlog makes every queued request stale at once
+// (返回判断请求是否已过期需丢弃的函数，对丢弃进行计数并采样记录日志，积压时排队的请求会同时过期)
+func staleAfter(ttl time.Duration) func(request ziface.IRequest) bool {
+	return func(request ziface.IRequest) bool {
+		if !IsStale(request, ttl) {
+			return false
+		}
+		metricsOf(request.GetConnection()).RequestsDropped.With(dropReasonStale).Inc()
+		if ctx, ok := dropLog.Allow(requestLogCtx(request)); ok {
+			LoggerOf(request.GetConnection()).ErrorFX(ctx, "drop stale request msgID = %d, queued %v", request.GetMsgID(), time.Since(request.GetCreateTime()))
+		}
+		return true
+	}
+}
+
+// RouterDropStale is a RouterSlicesMode middleware dropping requests which have been queued longer than ttl,
+// the client has most likely given up on them already
+// (RouterSlicesMode下的中间件，丢弃排队时间超过ttl的请求，客户端很可能已经放弃了这些请求)
+func RouterDropStale(ttl time.Duration) ziface.RouterHandler {
+	stale := staleAfter(ttl)
+	return func(request ziface.IRequest) {
+		if stale(request) {
+			request.Abort()
+		}
+	}
+}
+
+// DropStaleRouter returns router dropping requests queued longer than ttl, the IRouter counterpart of RouterDropStale
+// (返回丢弃排队时间超过ttl的请求的路由，对应RouterDropStale的IRouter版本)
+func DropStaleRouter(router ziface.IRouter, ttl time.Duration) ziface.IRouter {
+	return &dropRouter{IRouter: router, drop: staleAfter(ttl)}
+}
+
+// The reasons of RequestsDropped (RequestsDropped的丢弃原因)
+const (
+	dropReasonDuplicate = "duplicate"
+	dropReasonStale     = "stale"
+)
+
+// dropRouter aborts the requests drop reports before the PreHandle of the router, Abort ends the handle steps in
+// the IRouter mode and RouterToHandler checks it in RouterSlicesMode
+// (在路由的PreHandle之前中止drop判定需丢弃的请求，IRouter模式下Abort会结束处理步骤，RouterSlicesMode下由RouterToHandler检查)
+type dropRouter struct {
+	ziface.IRouter
+	drop func(request ziface.IRequest) bool
+}
+
+func (r *dropRouter) PreHandle(request ziface.IRequest) {
+	if r.drop(request) {
+		request.Abort()
+		return
+	}
+	r.IRouter.PreHandle(request)
+}
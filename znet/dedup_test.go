@@ -0,0 +1,72 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := NewDeduplicator(50*time.Millisecond, nil)
+
+	assert.True(t, d.Allow(NewRequest(nil, zpack.NewMsgPackage(1, []byte("pay")))))
+	assert.False(t, d.Allow(NewRequest(nil, zpack.NewMsgPackage(1, []byte("pay")))))
+	assert.True(t, d.Allow(NewRequest(nil, zpack.NewMsgPackage(1, []byte("other")))))
+	assert.True(t, d.Allow(NewRequest(nil, zpack.NewMsgPackage(2, []byte("pay")))))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, d.Allow(NewRequest(nil, zpack.NewMsgPackage(1, []byte("pay")))))
+}
+
+func TestIsStale(t *testing.T) {
+	req := NewRequest(nil, zpack.NewMsgPackage(1, nil))
+	assert.False(t, IsStale(req, 20*time.Millisecond))
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, IsStale(req, 20*time.Millisecond))
+}
+
+func TestDedupRouter(t *testing.T) {
+	zmetrics.Enable()
+	mh := newMsgHandle()
+	router := &blockRouter{handled: make(chan uint32, 4)}
+	mh.AddRouter(1, DedupRouter(router, time.Minute, nil))
+	mh.AddRouter(2, DropStaleRouter(router, 20*time.Millisecond))
+	conn := &principalConn{mh: mh}
+	duplicates := mh.Metrics().RequestsDropped.With("duplicate").Value()
+	stale := mh.Metrics().RequestsDropped.With("stale").Value()
+
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(1, []byte("pay"))), 0)
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(1, []byte("pay"))), 0)
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(2, nil)), 0)
+	old := NewRequest(conn, zpack.NewMsgPackage(2, nil))
+	time.Sleep(30 * time.Millisecond)
+	mh.doMsgHandler(old, 0)
+
+	assert.Equal(t, []uint32{1, 2}, []uint32{<-router.handled, <-router.handled})
+	assert.Len(t, router.handled, 0)
+	assert.Equal(t, duplicates+1, mh.Metrics().RequestsDropped.With("duplicate").Value())
+	assert.Equal(t, stale+1, mh.Metrics().RequestsDropped.With("stale").Value())
+}
+
+func TestDedupRouterSlices(t *testing.T) {
+	config := *zconf.GlobalObject
+	config.RouterSlicesMode = true
+	mh := newMsgHandleWithConfig(&config)
+	router := &blockRouter{handled: make(chan uint32, 4)}
+	var posted []uint32
+	mh.AddRouterSlices(1, RouterToHandler(DedupRouter(router, time.Minute, nil)), func(request ziface.IRequest) {
+		posted = append(posted, request.GetMsgID())
+	})
+	conn := &principalConn{mh: mh}
+
+	mh.doMsgHandlerSlices(NewRequest(conn, zpack.NewMsgPackage(1, []byte("pay"))), 0)
+	mh.doMsgHandlerSlices(NewRequest(conn, zpack.NewMsgPackage(1, []byte("pay"))), 0)
+
+	assert.Len(t, router.handled, 1)
+	assert.Equal(t, []uint32{1}, posted)
+}
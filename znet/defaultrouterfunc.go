@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/aceld/zinx/ziface"
-	"github.com/aceld/zinx/zlog"
 )
 
 const (
@@ -34,7 +33,7 @@ func RouterRecovery(request ziface.IRequest) {
 		if err := recover(); err != nil {
 			panicInfo := getInfo(StackBegin)
 			// Record the error
-			zlog.Ins().ErrorF("MsgId:%d Handler panic: info:%s err:%v", request.GetMsgID(), panicInfo, err)
+			LoggerOf(request.GetConnection()).ErrorFX(requestLogCtx(request), "MsgId:%d Handler panic: info:%s err:%v", request.GetMsgID(), panicInfo, err)
 
 			//fmt.Printf("MsgId:%d Handler panic: info:%s err:%v", request.GetMsgID(), panicInfo, err)
 
@@ -52,7 +51,7 @@ func RouterTime(request ziface.IRequest) {
 	now := time.Now()
 	request.RouterSlicesNext()
 	duration := time.Since(now)
-	fmt.Println(duration.String())
+	LoggerOf(request.GetConnection()).InfoFX(requestLogCtx(request), "router slices take %s", duration)
 }
 
 func getInfo(ship int) (infoStr string) {
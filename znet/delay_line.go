@@ -0,0 +1,123 @@
+package znet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+const delayLinesKey = "zinx.delay_lines"
+
+// delayed is a message held by a delay line until due (延迟线上保留到due的消息)
+type delayed struct {
+	due     time.Time
+	deliver func()
+}
+
+// delayLine delivers the messages of one direction of a connection after their delay, in order, on its own
+// goroutine which exits once the line is empty, so that a delayed message holds neither its sender nor the others
+// (在延迟之后按顺序投递连接一个方向上的消息，投递在自己的协程中进行，队列为空时协程退出，
+// 因此被延迟的消息既不阻塞发送方也不阻塞其他消息)
+type delayLine struct {
+	mu      sync.Mutex
+	queue   []delayed
+	last    time.Time
+	running bool
+}
+
+// schedule delivers a message d after now, never before the messages scheduled earlier
+// (在now之后d投递消息，不会早于之前安排的消息)
+func (dl *delayLine) schedule(d time.Duration, now time.Time, deliver func()) {
+	due := now.Add(d)
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if due.Before(dl.last) {
+		due = dl.last
+	}
+	dl.last = due
+	dl.queue = append(dl.queue, delayed{due: due, deliver: deliver})
+	if !dl.running {
+		dl.running = true
+		go dl.run()
+	}
+}
+
+func (dl *delayLine) run() {
+	for {
+		dl.mu.Lock()
+		if len(dl.queue) == 0 {
+			dl.running = false
+			dl.mu.Unlock()
+			return
+		}
+		next := dl.queue[0]
+		dl.queue[0] = delayed{}
+		dl.queue = dl.queue[1:]
+		dl.mu.Unlock()
+
+		if d := time.Until(next.due); d > 0 {
+			time.Sleep(d)
+		}
+		next.deliver()
+	}
+}
+
+// delayLines are the delay lines of a connection, one per direction (连接的延迟线，每个方向一条)
+type delayLines struct {
+	inbound, outbound delayLine
+}
+
+// line returns the delay line of conn in a direction, created on first use
+// (返回conn在一个方向上的延迟线，首次使用时创建)
+func (fi *faultInjector) line(conn ziface.IConnection, inbound bool) *delayLine {
+	fi.linesMu.Lock()
+	defer fi.linesMu.Unlock()
+	var dls *delayLines
+	if value, err := conn.GetProperty(delayLinesKey); err == nil {
+		dls = value.(*delayLines)
+	} else {
+		dls = new(delayLines)
+		conn.SetProperty(delayLinesKey, dls)
+	}
+	if inbound {
+		return &dls.inbound
+	}
+	return &dls.outbound
+}
+
+// packedQueue is implemented by the connections whose writer hands the pack buffers back once written
+// (写协程在写出后归还封包缓冲的连接)
+type packedQueue interface {
+	sendToQueue(data []byte, packed bool) error
+}
+
+// deferSend hands the packed buf of conn to its outbound delay line when an injected send delay or its simulated
+// network holds it, the line then queues it to the writer of conn, so that neither the sender nor the other
+// connections served by the same worker wait. It returns false if buf is not held and the caller sends it.
+// (注入的发送延迟或conn模拟的网络需要延迟封包后的buf时，将其交给conn的出站延迟线，再由延迟线放入conn的写队列，
+// 发送方与同一worker处理的其他连接都无需等待。buf不需要延迟时返回false，由调用方发送)
+func deferSend(conn ziface.IConnection, buf []byte) bool {
+	mh, ok := conn.GetMsgHandler().(*MsgHandle)
+	if !ok || mh.faults == nil {
+		return false
+	}
+	d := mh.faults.shape(conn, false, len(buf)) + mh.faults.sendDelay()
+	if d <= 0 {
+		return false
+	}
+	mh.faults.line(conn, false).schedule(d, time.Now(), func() {
+		var err error
+		if pq, ok := conn.(packedQueue); ok {
+			err = pq.sendToQueue(buf, true)
+		} else {
+			err = conn.SendToQueue(buf)
+		}
+		if err != nil {
+			if ctx, ok := sendErrLog.Allow(connLogCtx(conn)); ok {
+				LoggerOf(conn).ErrorFX(ctx, "connID = %d send delayed msg err: %v", conn.GetConnID(), err)
+			}
+		}
+	})
+	return true
+}
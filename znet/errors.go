@@ -0,0 +1,33 @@
+package znet
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+)
+
+// sendBuffWait is how long SendBuffMsg waits for room in the send queue (SendBuffMsg等待发送队列空间的时间)
+const sendBuffWait = 5 * time.Millisecond
+
+// connError wraps err into a zerrors.ConnError of conn during op (将err包装为conn在op操作中的zerrors.ConnError)
+func connError(conn ziface.IConnection, op string, err error) error {
+	return &zerrors.ConnError{ConnID: conn.GetConnID(), Op: op, Err: err}
+}
+
+// decodeFrames splits data into frames with the frame decoder of conn, it returns false once the stream cannot be
+// decoded and the connection has to close, the frames discarded for their length are logged by the decoders
+// (使用conn的帧解码器将data拆分为帧，数据流无法解码、连接需要关闭时返回false，因长度被丢弃的帧由解码器输出日志)
+func decodeFrames(conn ziface.IConnection, decoder ziface.IFrameDecoder, data []byte) ([][]byte, bool) {
+	errDecoder, ok := decoder.(ziface.IFrameErrDecoder)
+	if !ok {
+		return decoder.Decode(data), true
+	}
+	frames, err := errDecoder.DecodeFrames(data)
+	if err != nil && !errors.Is(err, zerrors.ErrFrameTooLong) {
+		LoggerOf(conn).ErrorFX(connLogCtx(conn), "connID = %d decode err: %v, closing", conn.GetConnID(), err)
+		return frames, false
+	}
+	return frames, true
+}
@@ -0,0 +1,60 @@
+package znet
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendErrors(t *testing.T) {
+	// A connection which has not started is closed (未启动的连接处于关闭状态)
+	conn := &Connection{connID: 3, connIdStr: "3", msgHandler: newMsgHandle()}
+	err := conn.Send([]byte("ping"))
+	assert.True(t, errors.Is(err, zerrors.ErrConnClosed))
+	var connErr *zerrors.ConnError
+	assert.True(t, errors.As(err, &connErr))
+	assert.Equal(t, uint64(3), connErr.ConnID)
+	assert.Equal(t, "send msg", connErr.Op)
+}
+
+func TestDecodeFrames(t *testing.T) {
+	conn := &Connection{connID: 3, connIdStr: "3", msgHandler: newMsgHandle()}
+	frame := func(data string) []byte {
+		buf := make([]byte, 2, 2+len(data))
+		binary.BigEndian.PutUint16(buf, uint16(len(data)))
+		return append(buf, data...)
+	}
+	decoder := zinterceptor.NewFrameDecoder(ziface.LengthField{
+		MaxFrameLength:    8,
+		LengthFieldLength: 2,
+	})
+
+	// The frame over MaxFrameLength is discarded, the next one is decoded (超过MaxFrameLength的帧被丢弃，之后的帧被解码)
+	data := append(frame("too long frame"), frame("ok")...)
+	frames, ok := decodeFrames(conn, decoder, data)
+	assert.True(t, ok)
+	assert.Equal(t, [][]byte{frame("ok")}, frames)
+	_, err := decoder.(ziface.IFrameErrDecoder).DecodeFrames(frame("long frame"))
+	assert.True(t, errors.Is(err, zerrors.ErrFrameTooLong))
+
+	// A frame shorter than InitialBytesToStrip cannot be decoded (短于InitialBytesToStrip的帧无法解码)
+	decoder = zinterceptor.NewFrameDecoder(ziface.LengthField{
+		MaxFrameLength:      64,
+		LengthFieldLength:   2,
+		LengthAdjustment:    -4,
+		InitialBytesToStrip: 4,
+	})
+	frames, ok = decodeFrames(conn, decoder, []byte{0, 3, 'a', 'b', 'c'})
+	assert.False(t, ok)
+	assert.Empty(t, frames)
+
+	delimiter := zinterceptor.NewDelimiterFrameDecoder([]byte("\n"), 4)
+	frames, err = delimiter.(ziface.IFrameErrDecoder).DecodeFrames([]byte("too long\nok\n"))
+	assert.True(t, errors.Is(err, zerrors.ErrFrameTooLong))
+	assert.Equal(t, [][]byte{[]byte("ok")}, frames)
+}
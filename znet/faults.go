@@ -0,0 +1,112 @@
+package znet
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// faultInjector injects the faults of a server into its connections, the faults change at runtime
+// (将服务的故障注入其连接，故障可以在运行时修改)
+type faultInjector struct {
+	faults atomic.Value // ziface.Faults
+	// shaping is set once a connection simulates a network condition, see SetNetCondition
+	// (有连接模拟网络状况后被设置，见SetNetCondition)
+	shaping int32
+
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	// linesMu creates the delay lines of a connection once, see line (保证连接的延迟线只创建一次，见line)
+	linesMu sync.Mutex
+}
+
+func newFaultInjector(faults ziface.Faults) *faultInjector {
+	fi := &faultInjector{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	fi.faults.Store(faults)
+	return fi
+}
+
+func (fi *faultInjector) get() ziface.Faults {
+	return fi.faults.Load().(ziface.Faults)
+}
+
+func (fi *faultInjector) set(faults ziface.Faults) {
+	fi.faults.Store(faults)
+}
+
+// chance reports true with the probability ratio (以ratio的概率返回true)
+func (fi *faultInjector) chance(ratio float64) bool {
+	if ratio <= 0 {
+		return false
+	}
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	return fi.rand.Float64() < ratio
+}
+
+// intn returns a random number in [0, n) (返回[0, n)中的随机数)
+func (fi *faultInjector) intn(n int64) int64 {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	return fi.rand.Int63n(n)
+}
+
+// sendDelay returns a random delay of a send if it is chosen, the send is then held by a delay line, see deferSend
+// (发送被选中时返回随机的延迟时长，之后由延迟线保留该发送，见deferSend)
+func (fi *faultInjector) sendDelay() time.Duration {
+	faults := fi.get()
+	if faults.SendDelay <= 0 || !fi.chance(faults.SendDelayRatio) {
+		return 0
+	}
+	return time.Duration(fi.intn(int64(faults.SendDelay)) + 1)
+}
+
+// inbound injects the faults into a frame not decoded yet, it returns false if the frame is not handled
+// (向尚未解码的帧注入故障，帧不再被处理时返回false)
+func (fi *faultInjector) inbound(request ziface.IRequest) bool {
+	faults := fi.get()
+	if !faults.Enabled() {
+		return true
+	}
+	conn := request.GetConnection()
+	switch {
+	case fi.chance(faults.CloseRatio):
+		LoggerOf(conn).DebugFX(connLogCtx(conn), "fault injection: closing connID = %d", conn.GetConnID())
+		conn.Stop()
+		return false
+	case fi.chance(faults.DropRatio):
+		LoggerOf(conn).DebugFX(connLogCtx(conn), "fault injection: dropping a frame of connID = %d", conn.GetConnID())
+		return false
+	case fi.chance(faults.CorruptRatio):
+		if data := request.GetMessage().GetData(); len(data) > 0 {
+			// XOR with a non-zero byte always changes it (与非零字节异或一定会改变该字节)
+			data[fi.intn(int64(len(data)))] ^= byte(1 + fi.intn(255))
+			LoggerOf(conn).DebugFX(connLogCtx(conn), "fault injection: corrupting a frame of connID = %d", conn.GetConnID())
+		}
+	}
+	return true
+}
+
+// SetFaults injects faults into the connections of the server, see ziface.Faults
+// (向服务的连接注入故障，见ziface.Faults)
+func (s *Server) SetFaults(faults ziface.Faults) error {
+	if err := faults.Validate(); err != nil {
+		return err
+	}
+	s.faults.set(faults)
+	if faults.Enabled() {
+		s.log().InfoF("[FAULTS] server %s injects %+v", s.Name, faults)
+	} else {
+		s.log().InfoF("[FAULTS] server %s stops injecting faults", s.Name)
+	}
+	return nil
+}
+
+// GetFaults returns the faults injected into the connections of the server (返回注入服务连接的故障)
+func (s *Server) GetFaults() ziface.Faults {
+	return s.faults.get()
+}
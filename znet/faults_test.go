@@ -0,0 +1,98 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjector(t *testing.T) {
+	fi := newFaultInjector(ziface.Faults{})
+	conn := newAuthConn()
+	frame := func() ziface.IRequest { return NewRequest(conn, zpack.NewMsgPackage(1, []byte("frame"))) }
+	assert.True(t, fi.inbound(frame()))
+
+	fi.set(ziface.Faults{DropRatio: 1})
+	assert.False(t, fi.inbound(frame()))
+	assert.False(t, conn.isStopped())
+
+	fi.set(ziface.Faults{CorruptRatio: 1})
+	request := frame()
+	assert.True(t, fi.inbound(request))
+	assert.NotEqual(t, "frame", string(request.GetData()))
+	assert.Len(t, request.GetData(), 5)
+
+	fi.set(ziface.Faults{CloseRatio: 1})
+	assert.False(t, fi.inbound(frame()))
+	assert.True(t, conn.isStopped())
+
+	fi.set(ziface.Faults{SendDelayRatio: 1, SendDelay: 20 * time.Millisecond})
+	for i := 0; i < 5; i++ {
+		d := fi.sendDelay()
+		assert.True(t, d > 0 && d <= 20*time.Millisecond, d)
+	}
+	fi.set(ziface.Faults{SendDelayRatio: 0, SendDelay: 20 * time.Millisecond})
+	assert.Equal(t, time.Duration(0), fi.sendDelay())
+}
+
+// queueConn records the buffers queued to its writer (记录放入写队列的缓冲)
+type queueConn struct {
+	propConn
+	mh     *MsgHandle
+	queued chan []byte
+}
+
+func (c *queueConn) GetMsgHandler() ziface.IMsgHandle { return c.mh }
+
+func (c *queueConn) SendToQueue(data []byte) error {
+	c.queued <- data
+	return nil
+}
+
+func TestDeferSend(t *testing.T) {
+	mh := newMsgHandle()
+	mh.faults = newFaultInjector(ziface.Faults{})
+	conn := &queueConn{propConn: propConn{props: map[string]interface{}{}}, mh: mh, queued: make(chan []byte, 5)}
+	assert.False(t, deferSend(conn, []byte("now")))
+
+	// The delayed sends neither hold the sender nor each other (被延迟的发送既不阻塞发送方也不互相阻塞)
+	mh.faults.set(ziface.Faults{SendDelayRatio: 1, SendDelay: 50 * time.Millisecond})
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.True(t, deferSend(conn, []byte{byte(i)}))
+	}
+	assert.True(t, time.Since(start) < 10*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		select {
+		case buf := <-conn.queued:
+			assert.Equal(t, []byte{byte(i)}, buf)
+		case <-time.After(time.Second):
+			t.Fatal("delayed send is not queued")
+		}
+	}
+	assert.True(t, time.Since(start) < 5*50*time.Millisecond)
+}
+
+func TestServerFaults(t *testing.T) {
+	// A private config, the global one is shared by the other tests
+	config := zconf.DefaultConfig()
+	config.FaultDropRatio = 0.5
+	config.FaultSendDelay = 100
+	s := newServerWithConfig(config, "tcp4").(*Server)
+	assert.Equal(t, ziface.Faults{DropRatio: 0.5, SendDelay: 100 * time.Millisecond}, s.GetFaults())
+	assert.Nil(t, s.Validate())
+
+	invalid := zconf.DefaultConfig()
+	invalid.FaultDropRatio = 2
+	assert.Contains(t, invalid.Validate().Error(), "FaultDropRatio")
+
+	assert.NotNil(t, s.SetFaults(ziface.Faults{CloseRatio: 2}))
+	assert.Nil(t, s.SetFaults(ziface.Faults{CorruptRatio: 0.1}))
+	assert.Equal(t, ziface.Faults{CorruptRatio: 0.1}, s.GetFaults())
+	assert.True(t, s.GetFaults().Enabled())
+	assert.False(t, ziface.Faults{SendDelayRatio: 1}.Enabled())
+}
@@ -0,0 +1,139 @@
+package znet
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+// fingerprintKey is the connection property holding the class given by the Fingerprinter
+// (保存Fingerprinter给出的分类的连接属性)
+const fingerprintKey = "zinx.fingerprint"
+
+// ConnClass returns the class the Fingerprinter of WithFingerprint gave conn, "" without one
+// (返回WithFingerprint的Fingerprinter为conn给出的分类，没有时返回"")
+func ConnClass(conn ziface.IConnection) string {
+	if value, err := conn.GetProperty(fingerprintKey); err == nil {
+		class, _ := value.(string)
+		return class
+	}
+	return ""
+}
+
+// tlsStater is implemented by *tls.Conn and by the connections wrapping one (由*tls.Conn及包装它的连接实现)
+type tlsStater interface {
+	ConnectionState() tls.ConnectionState
+}
+
+// peekedConn returns the peeked bytes before reading the connection again (先返回预读的字节，再继续读取连接)
+type peekedConn struct {
+	net.Conn
+	peek []byte
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	if len(c.peek) > 0 {
+		n := copy(b, c.peek)
+		c.peek = c.peek[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+// ConnectionState keeps the TLS state of the connection reachable, e.g. for TLSAuthenticator
+// (使连接的TLS状态仍可访问，例如用于TLSAuthenticator)
+func (c *peekedConn) ConnectionState() tls.ConnectionState {
+	if s, ok := c.Conn.(tlsStater); ok {
+		return s.ConnectionState()
+	}
+	return tls.ConnectionState{}
+}
+
+// fingerprinter runs the Fingerprinter of WithFingerprint on the accepted TCP connections
+// (对接受的TCP连接执行WithFingerprint的Fingerprinter)
+type fingerprinter struct {
+	// The accepted connections being fingerprinted, they count toward MaxConn (正在进行指纹识别的已接受连接，计入MaxConn)
+	pending int32
+
+	classify ziface.Fingerprinter
+	peek     int
+	timeout  time.Duration
+
+	// The ClientHellos of the handshakes in progress by their socket (进行中握手的ClientHello，以套接字为键)
+	hellos sync.Map
+}
+
+// wrapTLS records the ClientHellos of config (记录config的ClientHello)
+func (f *fingerprinter) wrapTLS(config *tls.Config) {
+	next := config.GetConfigForClient
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		f.hellos.Store(hello.Conn, hello)
+		if next != nil {
+			return next(hello)
+		}
+		return nil, nil
+	}
+}
+
+// inFlight returns the number of the connections being fingerprinted (返回正在进行指纹识别的连接数)
+func (f *fingerprinter) inFlight() int {
+	if f == nil {
+		return 0
+	}
+	return int(atomic.LoadInt32(&f.pending))
+}
+
+// accept fingerprints conn, it returns the connection to serve and its class, ok is false if conn was rejected and
+// closed, logger is the one of the server (对conn进行指纹识别，返回要服务的连接及其分类，conn被拒绝并关闭时ok为false，
+// logger为服务的日志)
+func (f *fingerprinter) accept(conn net.Conn, connID uint64, m *zmetrics.Metrics, logger ziface.ILogger) (net.Conn, string, bool) {
+	fp := &ziface.Fingerprint{ConnID: connID, RemoteAddr: conn.RemoteAddr(), LocalAddr: conn.LocalAddr(), Socket: conn}
+	logCtx := zlog.WithFields(context.Background(), zlog.F(zlog.FieldConnID, connID), zlog.F(zlog.FieldRemoteAddr, conn.RemoteAddr().String()))
+
+	_ = conn.SetReadDeadline(time.Now().Add(f.timeout))
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		fp.Socket = tlsConn.NetConn()
+		err := tlsConn.Handshake()
+		if hello, ok := f.hellos.LoadAndDelete(fp.Socket); ok {
+			fp.ClientHello = hello.(*tls.ClientHelloInfo)
+		}
+		if err != nil {
+			logger.ErrorFX(logCtx, "connID = %d tls handshake err: %v", connID, err)
+			_ = conn.Close()
+			return nil, "", false
+		}
+	}
+	if f.peek > 0 {
+		fp.Peek = make([]byte, f.peek)
+		n := 0
+		for n < f.peek {
+			m, err := conn.Read(fp.Peek[n:])
+			n += m
+			if err != nil {
+				break
+			}
+		}
+		fp.Peek = fp.Peek[:n]
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	class, err := f.classify(fp)
+	if err != nil {
+		logger.InfoFX(logCtx, "connID = %d rejected at accept: %v", connID, err)
+		zlog.Security(logCtx, zlog.SecurityRejected, err.Error(), zlog.F("class", class))
+		m.ConnectionsRejected.With(zmetrics.RejectReasonFingerprint).Inc()
+		_ = conn.Close()
+		return nil, "", false
+	}
+	if len(fp.Peek) > 0 {
+		conn = &peekedConn{Conn: conn, peek: fp.Peek}
+	}
+	return conn, class, true
+}
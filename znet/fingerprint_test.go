@@ -0,0 +1,129 @@
+package znet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprintPeek(t *testing.T) {
+	var seen *ziface.Fingerprint
+	f := &fingerprinter{peek: 4, timeout: time.Second, classify: func(fp *ziface.Fingerprint) (string, error) {
+		seen = fp
+		if string(fp.Peek) == "BOT!" {
+			return "bot", errors.New("bot")
+		}
+		return "v" + string(fp.Peek[3]), nil
+	}}
+
+	server, client := net.Pipe()
+	go func() { _, _ = client.Write([]byte("GV/2 hello")) }()
+	conn, class, ok := f.accept(server, 7, zmetrics.Zinx, zlog.Ins())
+	assert.True(t, ok)
+	assert.Equal(t, "v2", class)
+	assert.Equal(t, uint64(7), seen.ConnID)
+	// The peeked bytes are read again by the connection (预读的字节仍会被连接读到)
+	buf := make([]byte, 10)
+	_, err := io.ReadFull(conn, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "GV/2 hello", string(buf))
+
+	// A rejected connection is closed (被拒绝的连接会被关闭)
+	server, client = net.Pipe()
+	go func() { _, _ = client.Write([]byte("BOT!")) }()
+	_, _, ok = f.accept(server, 8, zmetrics.Zinx, zlog.Ins())
+	assert.False(t, ok)
+	_, err = client.Read(buf)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFingerprintSilentClient(t *testing.T) {
+	f := &fingerprinter{peek: 4, timeout: 20 * time.Millisecond, classify: func(fp *ziface.Fingerprint) (string, error) {
+		assert.Empty(t, fp.Peek)
+		return "", nil
+	}}
+	server, client := net.Pipe()
+	defer client.Close()
+	conn, _, ok := f.accept(server, 1, zmetrics.Zinx, zlog.Ins())
+	assert.True(t, ok)
+	_, isPeeked := conn.(*peekedConn)
+	assert.False(t, isPeeked)
+}
+
+func TestFingerprintClientHello(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "zinx"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour), DNSNames: []string{"zinx"}}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	assert.Nil(t, err)
+
+	var hello *tls.ClientHelloInfo
+	f := &fingerprinter{peek: 2, timeout: time.Second, classify: func(fp *ziface.Fingerprint) (string, error) {
+		hello = fp.ClientHello
+		return string(fp.Peek), nil
+	}}
+	config := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	f.wrapTLS(config)
+
+	server, client := net.Pipe()
+	go func() {
+		c := tls.Client(client, &tls.Config{ServerName: "zinx", InsecureSkipVerify: true, NextProtos: []string{"zinx/1"}})
+		_, _ = c.Write([]byte("ok"))
+	}()
+	conn, class, ok := f.accept(tls.Server(server, config), 1, zmetrics.Zinx, zlog.Ins())
+	assert.True(t, ok)
+	assert.Equal(t, "ok", class)
+	assert.Equal(t, "zinx", hello.ServerName)
+	assert.Equal(t, []string{"zinx/1"}, hello.SupportedProtos)
+	assert.True(t, conn.(tlsStater).ConnectionState().HandshakeComplete)
+}
+
+func TestFingerprintMaxConn(t *testing.T) {
+	var classified int32
+	config := zconf.DefaultConfig()
+	config.MaxConn = 1
+	s := newServerWithConfig(config, "tcp", WithFingerprint(func(fp *ziface.Fingerprint) (string, error) {
+		atomic.AddInt32(&classified, 1)
+		return "", nil
+	}, 4, 200*time.Millisecond)).(*Server)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.ctx = ctx
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go s.acceptTcp(listener, s.nextConnID)
+
+	// Two silent clients, the second waits while the first is fingerprinted then served
+	// (两个不发送数据的客户端，第一个进行指纹识别及之后被服务期间，第二个一直等待)
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		assert.Nil(t, err)
+		defer conn.Close()
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, s.fingerprint.inFlight())
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&classified))
+	assert.Equal(t, 0, s.fingerprint.inFlight())
+	assert.Equal(t, 1, s.ConnMgr.Len())
+}
@@ -0,0 +1,283 @@
+package znet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+// FragmentHeaderLen is the length of the fragment header, msgID(4 bytes) + fragmentID(4 bytes) + index(2 bytes) + count(2 bytes)
+// (分片头长度，原始MsgID 4字节 + 分片组ID 4字节 + 分片序号2字节 + 分片总数2字节)
+const FragmentHeaderLen = 12
+
+// ErrFragmentedTooLarge is returned for a fragmented message exceeding MaxFragmentedSize
+// (分片消息超过MaxFragmentedSize时返回的错误)
+var ErrFragmentedTooLarge = errors.New("zinx: fragmented message exceeds MaxFragmentedSize")
+
+// SplitFragments splits msg into messages with ziface.FragmentMsgID carrying at most size bytes of data each,
+// it returns nil if the data of msg fits into size. The fragmentID tells the fragments of the messages of a
+// connection apart, it must differ from the ones of the messages still being reassembled by the peer.
+// (将msg拆分为MsgID为ziface.FragmentMsgID的分片消息，每个分片最多携带size字节数据，数据不超过size时返回nil。
+// fragmentID用于区分一个连接上各消息的分片，不能与对端仍在重组的消息相同)
+func SplitFragments(msg ziface.IMessage, size int, fragmentID uint32) ([]ziface.IMessage, error) {
+	data := msg.GetData()
+	if size <= 0 || len(data) <= size {
+		return nil, nil
+	}
+
+	count := (len(data) + size - 1) / size
+	if count > math.MaxUint16 {
+		return nil, fmt.Errorf("zinx: msgID = %d too large to be fragmented, len = %d", msg.GetMsgID(), len(data))
+	}
+
+	fragments := make([]ziface.IMessage, 0, count)
+	for i := 0; i < count; i++ {
+		chunk := data[i*size:]
+		if len(chunk) > size {
+			chunk = chunk[:size]
+		}
+
+		frame := make([]byte, FragmentHeaderLen+len(chunk))
+		binary.LittleEndian.PutUint32(frame[0:4], msg.GetMsgID())
+		binary.LittleEndian.PutUint32(frame[4:8], fragmentID)
+		binary.LittleEndian.PutUint16(frame[8:10], uint16(i))
+		binary.LittleEndian.PutUint16(frame[10:12], uint16(count))
+		copy(frame[FragmentHeaderLen:], chunk)
+		fragments = append(fragments, zpack.NewMsgPackage(ziface.FragmentMsgID, frame))
+	}
+	return fragments, nil
+}
+
+// fragmentSize returns the data size of one fragment when FragmentMode is on, 0 otherwise
+// (开启FragmentMode时返回单个分片的数据长度，否则返回0)
+func fragmentSize(config *zconf.Config) int {
+	if !config.FragmentMode || config.MaxPacketSize <= FragmentHeaderLen {
+		return 0
+	}
+	return int(config.MaxPacketSize) - FragmentHeaderLen
+}
+
+// sendFragments sends msg in fragments of the IDs of conn if it is larger than MaxPacketSize, handled is false
+// if msg has to be sent as it is.
+// (msg超过MaxPacketSize时以conn的分片ID分片发送，msg需要原样发送时handled为false)
+func sendFragments(conn fragmentSender, msg ziface.IMessage, send func(fragment ziface.IMessage) error) (handled bool, err error) {
+	size := fragmentSize(configOf(conn))
+	if size <= 0 || len(msg.GetData()) <= size {
+		return false, nil
+	}
+	fragments, err := SplitFragments(msg, size, conn.nextFragmentID())
+	if err != nil {
+		return true, err
+	}
+	if fragments == nil {
+		return false, nil
+	}
+
+	for _, fragment := range fragments {
+		if err := send(fragment); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// fragmentBuffer collects the fragments of one message (收集同一条消息的分片)
+type fragmentBuffer struct {
+	msgID    uint32
+	seq      uint64
+	parts    [][]byte
+	received int
+	size     int
+}
+
+// fragmentBuffers holds the messages of one connection being reassembled
+// (一个连接上正在重组的消息)
+type fragmentBuffers struct {
+	buffers map[uint32]*fragmentBuffer
+	seq     uint64
+	config  *zconf.Config  // The config of the connection, zconf.GlobalObject if nil (连接的配置，为nil时使用zconf.GlobalObject)
+	logger  ziface.ILogger // The logger of the connection, zlog.Ins() if nil (连接的日志，为nil时使用zlog.Ins())
+	sync.Mutex
+}
+
+func (fb *fragmentBuffers) log() ziface.ILogger {
+	if fb.logger != nil {
+		return fb.logger
+	}
+	return zlog.Ins()
+}
+
+// feed adds one fragment, done is true and data holds the whole payload once all fragments are received.
+// When MaxFragmentBuffers messages are already being reassembled the oldest one is discarded.
+// (添加一个分片，收齐所有分片后done为true，data为完整数据。重组中的消息数达到MaxFragmentBuffers时丢弃最早的一条)
+func (fb *fragmentBuffers) feed(frame []byte) (msgID uint32, data []byte, done bool, err error) {
+	if len(frame) < FragmentHeaderLen {
+		return 0, nil, false, fmt.Errorf("zinx: fragment too short, len = %d", len(frame))
+	}
+	msgID = binary.LittleEndian.Uint32(frame[0:4])
+	config := fb.config
+	if config == nil {
+		config = zconf.GlobalObject
+	}
+	fragmentID := binary.LittleEndian.Uint32(frame[4:8])
+	index := int(binary.LittleEndian.Uint16(frame[8:10]))
+	count := int(binary.LittleEndian.Uint16(frame[10:12]))
+	if index >= count {
+		return msgID, nil, false, fmt.Errorf("zinx: invalid fragment %d/%d of msgID = %d", index, count, msgID)
+	}
+
+	fb.Lock()
+	defer fb.Unlock()
+
+	buf, ok := fb.buffers[fragmentID]
+	if !ok {
+		if max := config.Live().MaxFragmentBuffers; max > 0 && len(fb.buffers) >= max {
+			fb.evictOldest()
+		}
+		fb.seq++
+		buf = &fragmentBuffer{msgID: msgID, seq: fb.seq, parts: make([][]byte, count)}
+		fb.buffers[fragmentID] = buf
+	}
+	if buf.msgID != msgID || len(buf.parts) != count {
+		delete(fb.buffers, fragmentID)
+		return msgID, nil, false, fmt.Errorf("zinx: fragment %d/%d does not match msgID = %d", index, count, buf.msgID)
+	}
+	if buf.parts[index] != nil {
+		return msgID, nil, false, nil
+	}
+
+	chunk := frame[FragmentHeaderLen:]
+	buf.size += len(chunk)
+	if max := config.Live().FragmentedSizeLimit(); max > 0 && uint64(buf.size) > max {
+		delete(fb.buffers, fragmentID)
+		return msgID, nil, false, fmt.Errorf("%w %d, msgID = %d", ErrFragmentedTooLarge, max, msgID)
+	}
+
+	// The frame may share memory with the read buffer of the connection (帧可能与连接的读缓冲共享内存)
+	buf.parts[index] = append([]byte(nil), chunk...)
+	buf.received++
+	if buf.received < count {
+		return msgID, nil, false, nil
+	}
+
+	delete(fb.buffers, fragmentID)
+	data = make([]byte, 0, buf.size)
+	for _, part := range buf.parts {
+		data = append(data, part...)
+	}
+	return msgID, data, true, nil
+}
+
+func (fb *fragmentBuffers) evictOldest() {
+	var oldestID uint32
+	var oldest *fragmentBuffer
+	for id, buf := range fb.buffers {
+		if oldest == nil || buf.seq < oldest.seq {
+			oldestID, oldest = id, buf
+		}
+	}
+	if oldest != nil {
+		fb.log().ErrorF("discard fragmented msgID = %d, too many messages being reassembled", oldest.msgID)
+		delete(fb.buffers, oldestID)
+	}
+}
+
+// connFragments is embedded in the connections, it holds their messages being reassembled out of the reach of the
+// connection properties (嵌入在连接中，保存其正在重组的消息，连接属性无法访问)
+type connFragments struct {
+	fragments     *fragmentBuffers
+	fragmentsOnce sync.Once
+	// The ID of the latest message sent in fragments (最近一条分片发送的消息的ID)
+	fragmentID uint32
+}
+
+func (c *connFragments) nextFragmentID() uint32 {
+	return atomic.AddUint32(&c.fragmentID, 1)
+}
+
+type fragmentSender interface {
+	ziface.IConnection
+	nextFragmentID() uint32
+}
+
+func (c *connFragments) reassembly(config *zconf.Config, logger ziface.ILogger) *fragmentBuffers {
+	c.fragmentsOnce.Do(func() {
+		c.fragments = &fragmentBuffers{buffers: make(map[uint32]*fragmentBuffer), config: config, logger: logger}
+	})
+	return c.fragments
+}
+
+type reassembler interface {
+	reassembly(config *zconf.Config, logger ziface.ILogger) *fragmentBuffers
+}
+
+// fragmentInterceptor reassembles fragments right after the decoder, the routers and the
+// following interceptors only see whole messages.
+// (紧跟在解码器之后重组分片，路由和之后的拦截器只会看到完整的消息)
+type fragmentInterceptor struct {
+	// others holds the buffers of the connections not implemented by znet until they close
+	// (保存非znet实现的连接的缓冲，直到连接关闭)
+	others     map[ziface.IConnection]*fragmentBuffers
+	othersLock sync.Mutex
+}
+
+// fragmentsOf returns the messages of conn being reassembled (返回conn正在重组的消息)
+func (f *fragmentInterceptor) fragmentsOf(conn ziface.IConnection) *fragmentBuffers {
+	if r, ok := conn.(reassembler); ok {
+		return r.reassembly(configOf(conn), LoggerOf(conn))
+	}
+
+	f.othersLock.Lock()
+	defer f.othersLock.Unlock()
+	if fb, ok := f.others[conn]; ok {
+		return fb
+	}
+	if f.others == nil {
+		f.others = make(map[ziface.IConnection]*fragmentBuffers)
+	}
+	fb := &fragmentBuffers{buffers: make(map[uint32]*fragmentBuffer), config: configOf(conn), logger: LoggerOf(conn)}
+	f.others[conn] = fb
+	conn.AddCloseCallback(f, conn, func() {
+		f.othersLock.Lock()
+		delete(f.others, conn)
+		f.othersLock.Unlock()
+	})
+	return fb
+}
+
+func (f *fragmentInterceptor) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetMsgID() != ziface.FragmentMsgID || request.GetConnection() == nil {
+		return chain.Proceed(chain.Request())
+	}
+
+	msgID, data, done, err := f.fragmentsOf(request.GetConnection()).feed(request.GetData())
+	if err != nil {
+		metricsOf(request.GetConnection()).DecodeErrors.Inc()
+		if ctx, ok := decodeErrLog.Allow(requestLogCtx(request)); ok {
+			LoggerOf(request.GetConnection()).ErrorFX(ctx, "connID = %d reassemble fragment err: %v", request.GetConnection().GetConnID(), err)
+		}
+		reportAbuse(request.GetConnection(), ziface.AbuseDecodeError)
+		if errors.Is(err, ErrFragmentedTooLarge) {
+			zlog.Security(requestLogCtx(request), zlog.SecurityOversized, err.Error())
+		}
+	}
+	if !done {
+		PutRequest(request)
+		return nil
+	}
+
+	msg := request.GetMessage()
+	msg.SetMsgID(msgID)
+	msg.SetData(data)
+	msg.SetDataLen(uint32(len(data)))
+	return chain.Proceed(request)
+}
@@ -0,0 +1,92 @@
+package znet
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitFragments(t *testing.T) {
+	payload := bytes.Repeat([]byte("firmware"), 100)
+
+	fragments, err := SplitFragments(zpack.NewMsgPackage(7, payload), 64, 1)
+	assert.Nil(t, err)
+	assert.Len(t, fragments, 13)
+
+	fb := &fragmentBuffers{buffers: make(map[uint32]*fragmentBuffer)}
+	// Fragments may arrive in any order
+	for i := len(fragments) - 1; i > 0; i-- {
+		assert.Equal(t, ziface.FragmentMsgID, fragments[i].GetMsgID())
+		_, _, done, err := fb.feed(fragments[i].GetData())
+		assert.Nil(t, err)
+		assert.False(t, done)
+	}
+	msgID, data, done, err := fb.feed(fragments[0].GetData())
+	assert.Nil(t, err)
+	assert.True(t, done)
+	assert.Equal(t, uint32(7), msgID)
+	assert.Equal(t, payload, data)
+	assert.Empty(t, fb.buffers)
+
+	fragments, _ = SplitFragments(zpack.NewMsgPackage(7, payload), len(payload), 2)
+	assert.Nil(t, fragments)
+}
+
+func TestFragmentBuffersLimit(t *testing.T) {
+	old := zconf.GlobalObject.MaxFragmentBuffers
+	zconf.GlobalObject.MaxFragmentBuffers = 2
+	defer func() { zconf.GlobalObject.MaxFragmentBuffers = old }()
+
+	logger := &countLogger{}
+	fb := &fragmentBuffers{buffers: make(map[uint32]*fragmentBuffer), logger: logger}
+	var first []ziface.IMessage
+	for i := 0; i < 3; i++ {
+		fragments, _ := SplitFragments(zpack.NewMsgPackage(uint32(i), make([]byte, 10)), 4, uint32(i))
+		if i == 0 {
+			first = fragments
+		}
+		_, _, _, err := fb.feed(fragments[0].GetData())
+		assert.Nil(t, err)
+	}
+	assert.Len(t, fb.buffers, 2)
+	// The eviction is logged by the logger of the connection (淘汰由连接的日志记录)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logger.lines))
+
+	// The oldest message has been discarded, its remaining fragments start a new buffer which never completes
+	_, _, done, _ := fb.feed(first[1].GetData())
+	assert.False(t, done)
+}
+
+func TestConnFragments(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.MaxPacketSize = 16
+	conn := &Connection{connID: 1, connIdStr: "1", msgHandler: newMsgHandleWithConfig(config)}
+	f := &fragmentInterceptor{}
+
+	// The reassembly state is not a property of the connection (重组状态不是连接的属性)
+	fb := f.fragmentsOf(conn)
+	assert.Same(t, fb, f.fragmentsOf(conn))
+	_, err := conn.GetProperty("zinx.fragments")
+	assert.NotNil(t, err)
+
+	// The messages are bounded by DefaultFragmentsPerMessage times MaxPacketSize (消息长度以MaxPacketSize的DefaultFragmentsPerMessage倍为上限)
+	fragments, _ := SplitFragments(zpack.NewMsgPackage(7, make([]byte, zconf.DefaultFragmentsPerMessage*16+1)), 64, conn.nextFragmentID())
+	for _, fragment := range fragments {
+		if _, _, _, err = fb.feed(fragment.GetData()); err != nil {
+			break
+		}
+	}
+	assert.ErrorIs(t, err, ErrFragmentedTooLarge)
+
+	// The state of other connections is dropped once they close (其它连接关闭后丢弃其状态)
+	other := newAuthConn()
+	assert.Same(t, f.fragmentsOf(other), f.fragmentsOf(other))
+	assert.Len(t, f.others, 1)
+	other.onClose()
+	assert.Empty(t, f.others)
+}
@@ -0,0 +1,118 @@
+package znet
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+// The states of a server, for the readiness (服务的状态，用于就绪检查)
+const (
+	stateStopped int32 = iota
+	stateStarting
+	stateAccepting
+	stateDraining
+)
+
+var stateNames = [...]string{"stopped", "starting", "accepting", "draining"}
+
+// The readiness checks (就绪检查项)
+const (
+	checkAccepting    = "accepting"
+	checkWorkerQueues = "worker_queues"
+	checkDecodeErrors = "decode_errors"
+)
+
+func (s *Server) setState(state int32) {
+	atomic.StoreInt32(&s.state, state)
+}
+
+// accepting marks the server accepting once a listener is up, unless it is already draining
+// (监听建立后将服务标记为接受连接，已经在排空时除外)
+func (s *Server) accepting() {
+	atomic.CompareAndSwapInt32(&s.state, stateStarting, stateAccepting)
+}
+
+// decodeErrorRate measures the decode errors per second between two health checks at least a second apart
+// (测量间隔至少一秒的两次健康检查之间每秒的解码错误数)
+type decodeErrorRate struct {
+	mu    sync.Mutex
+	time  time.Time
+	count uint64
+	rate  float64
+}
+
+func (r *decodeErrorRate) measure(counter *zmetrics.Counter) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now, count := time.Now(), counter.Value()
+	if r.time.IsZero() {
+		r.time, r.count = now, count
+		return 0
+	}
+	if elapsed := now.Sub(r.time); elapsed >= time.Second {
+		r.rate = float64(count-r.count) / elapsed.Seconds()
+		r.time, r.count = now, count
+	}
+	return r.rate
+}
+
+// GetHealth returns the liveness and readiness of the server (返回服务的存活与就绪状态)
+func (s *Server) GetHealth() ziface.HealthStatus {
+	state := atomic.LoadInt32(&s.state)
+	status := ziface.HealthStatus{
+		Server: s.Name,
+		State:  stateNames[state],
+		Live:   state != stateStopped,
+	}
+
+	// A server at MaxConn does not accept more connections (达到MaxConn的服务不再接受连接)
+	conns := s.ConnMgr.Len()
+	status.Checks = append(status.Checks, ziface.HealthCheck{
+		Name:   checkAccepting,
+		OK:     state == stateAccepting && conns < s.config.Live().MaxConn,
+		Detail: fmt.Sprintf("%s, %d of %d connections", stateNames[state], conns, s.config.Live().MaxConn),
+	})
+
+	if threshold := s.config.HealthQueueThreshold; threshold > 0 {
+		longest := 0
+		for _, stats := range s.msgHandler.GetWorkerStats() {
+			if stats.QueueLen > longest {
+				longest = stats.QueueLen
+			}
+		}
+		status.Checks = append(status.Checks, ziface.HealthCheck{
+			Name:   checkWorkerQueues,
+			OK:     longest <= threshold,
+			Detail: fmt.Sprintf("longest queue %d, threshold %d", longest, threshold),
+		})
+	}
+
+	if threshold := s.config.HealthDecodeErrorRate; threshold > 0 {
+		rate := s.decodeErrors.measure(s.metrics.DecodeErrors)
+		status.Checks = append(status.Checks, ziface.HealthCheck{
+			Name:   checkDecodeErrors,
+			OK:     rate <= threshold,
+			Detail: fmt.Sprintf("%.2f/s, threshold %.2f/s", rate, threshold),
+		})
+	}
+
+	status.Ready = true
+	for _, check := range status.Checks {
+		status.Ready = status.Ready && check.OK
+	}
+	return status
+}
+
+// drain fails the readiness and waits ShutdownDelay seconds, so that the load balancers stop routing new
+// connections to the server before it stops (使就绪检查失败并等待ShutdownDelay秒，以便负载均衡在服务停止前不再转发新连接)
+func (s *Server) drain() {
+	s.setState(stateDraining)
+	if delay := s.config.ShutdownDelay; delay > 0 {
+		time.Sleep(time.Duration(delay) * time.Second)
+	}
+}
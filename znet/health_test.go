@@ -0,0 +1,54 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHealth(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.HealthQueueThreshold = 10
+	s := newServerWithConfig(config, "tcp4").(*Server)
+
+	status := s.GetHealth()
+	assert.Equal(t, "stopped", status.State)
+	assert.False(t, status.Live)
+	assert.False(t, status.Ready)
+
+	s.setState(stateStarting)
+	status = s.GetHealth()
+	assert.True(t, status.Live)
+	assert.False(t, status.Ready)
+
+	s.accepting()
+	status = s.GetHealth()
+	assert.True(t, status.Ready)
+	assert.Len(t, status.Checks, 2)
+	assert.Equal(t, checkWorkerQueues, status.Checks[1].Name)
+
+	s.drain()
+	s.accepting()
+	status = s.GetHealth()
+	assert.Equal(t, "draining", status.State)
+	assert.True(t, status.Live)
+	assert.False(t, status.Ready)
+}
+
+func TestDecodeErrorRate(t *testing.T) {
+	zmetrics.Enable()
+	var r decodeErrorRate
+	assert.Equal(t, float64(0), r.measure(zmetrics.DecodeErrors))
+
+	r.time = r.time.Add(-2 * time.Second)
+	zmetrics.DecodeErrors.Add(10)
+	rate := r.measure(zmetrics.DecodeErrors)
+	assert.InDelta(t, 5, rate, 0.1)
+
+	// Measured again within a second, the rate is kept (一秒内再次测量时保持原速率)
+	zmetrics.DecodeErrors.Add(10)
+	assert.Equal(t, rate, r.measure(zmetrics.DecodeErrors))
+}
@@ -1,16 +1,21 @@
 package znet
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aceld/zinx/ziface"
-	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/aceld/zinx/ztimer"
 )
 
 type HeartbeatChecker struct {
 	interval time.Duration //  Heartbeat detection interval(心跳检测时间间隔)
-	quitChan chan bool     // Quit signal(退出信号)
+
+	lock   sync.Mutex
+	cancel context.CancelFunc // Stops the running checker(停止运行中的检测)
 
 	makeMsg ziface.HeartBeatMsgFunc //User-defined heartbeat message processing method(用户自定义的心跳检测消息处理方法)
 
@@ -33,12 +38,12 @@ type HeatBeatDefaultRouter struct {
 }
 
 func (r *HeatBeatDefaultRouter) Handle(req ziface.IRequest) {
-	zlog.Ins().DebugF("Recv Heartbeat from %s, MsgID = %+v, Data = %s",
+	LoggerOf(req.GetConnection()).DebugFX(heartbeatLogCtx(req.GetConnection()), "Recv Heartbeat from %s, MsgID = %+v, Data = %s",
 		req.GetConnection().RemoteAddr(), req.GetMsgID(), string(req.GetData()))
 }
 
 func HeatBeatDefaultHandle(req ziface.IRequest) {
-	zlog.Ins().DebugF("Recv Heartbeat from %s, MsgID = %+v, Data = %s",
+	LoggerOf(req.GetConnection()).DebugFX(heartbeatLogCtx(req.GetConnection()), "Recv Heartbeat from %s, MsgID = %+v, Data = %s",
 		req.GetConnection().RemoteAddr(), req.GetMsgID(), string(req.GetData()))
 }
 
@@ -48,14 +53,13 @@ func makeDefaultMsg(conn ziface.IConnection) []byte {
 }
 
 func notAliveDefaultFunc(conn ziface.IConnection) {
-	zlog.Ins().InfoF("Remote connection %s is not alive, stop it", conn.RemoteAddr())
+	LoggerOf(conn).InfoFX(heartbeatLogCtx(conn), "Remote connection %s is not alive, stop it", conn.RemoteAddr())
 	conn.Stop()
 }
 
 func NewHeartbeatChecker(interval time.Duration) ziface.IHeartbeatChecker {
 	heartbeat := &HeartbeatChecker{
 		interval: interval,
-		quitChan: make(chan bool),
 
 		// Use default heartbeat message generation function and remote connection not alive handling method
 		// (均使用默认的心跳消息生成函数和远程连接不存活时的处理方法)
@@ -102,26 +106,48 @@ func (h *HeartbeatChecker) BindRouterSlices(msgID uint32, handlers ...ziface.Rou
 	}
 }
 
-func (h *HeartbeatChecker) start() {
-	ticker := time.NewTicker(h.interval)
+// start checks the connection every interval on the clock of ztimer until ctx is done
+// (在ztimer的时钟上每隔interval检查一次连接，直到ctx结束)
+func (h *HeartbeatChecker) start(ctx context.Context) {
 	for {
 		select {
-		case <-ticker.C:
+		case <-ztimer.GetClock().After(h.interval):
 			h.check()
-		case <-h.quitChan:
-			ticker.Stop()
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// Start starts checking the bound connection, the checker stops with Stop or with the context of the connection
+// (开始检测绑定的连接，检测随Stop或连接的context停止)
 func (h *HeartbeatChecker) Start() {
-	go h.start()
+	parent := context.Background()
+	if h.conn != nil && h.conn.Context() != nil {
+		parent = h.conn.Context()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	h.lock.Lock()
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.cancel = cancel
+	h.lock.Unlock()
+
+	go h.start(ctx)
 }
 
+// Stop stops the checker, stopping a checker that is not running does nothing
+// (停止检测，停止未运行的检测不做任何事)
 func (h *HeartbeatChecker) Stop() {
-	zlog.Ins().InfoF("heartbeat checker stop, connID=%+v", h.conn.GetConnID())
-	h.quitChan <- true
+	LoggerOf(h.conn).InfoFX(heartbeatLogCtx(h.conn), "heartbeat checker stop, connID=%+v", h.conn.GetConnID())
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
 }
 
 func (h *HeartbeatChecker) SendHeartBeatMsg() error {
@@ -130,7 +156,7 @@ func (h *HeartbeatChecker) SendHeartBeatMsg() error {
 
 	err := h.conn.SendMsg(h.msgID, msg)
 	if err != nil {
-		zlog.Ins().ErrorF("send heartbeat msg error: %v, msgId=%+v msg=%+v", err, h.msgID, msg)
+		LoggerOf(h.conn).ErrorFX(heartbeatLogCtx(h.conn), "send heartbeat msg error: %v, msgId=%+v msg=%+v", err, h.msgID, msg)
 		return err
 	}
 
@@ -144,6 +170,8 @@ func (h *HeartbeatChecker) check() (err error) {
 	}
 
 	if !h.conn.IsAlive() {
+		metricsOf(h.conn).HeartbeatTimeouts.Inc()
+		setCloseReason(h.conn, zmetrics.CloseReasonHeartbeat)
 		h.onRemoteNotAlive(h.conn)
 	} else {
 		if h.beatFunc != nil {
@@ -167,7 +195,6 @@ func (h *HeartbeatChecker) Clone() ziface.IHeartbeatChecker {
 
 	heartbeat := &HeartbeatChecker{
 		interval:         h.interval,
-		quitChan:         make(chan bool),
 		beatFunc:         h.beatFunc,
 		makeMsg:          h.makeMsg,
 		onRemoteNotAlive: h.onRemoteNotAlive,
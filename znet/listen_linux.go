@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+package znet
+
+import (
+	"context"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenTuned listens with the socket options of opts: SO_REUSEPORT so that every shard has its own listener on the
+// same port and the kernel balances the connections between them, TCP_DEFER_ACCEPT so that the connections are only
+// accepted once they send data, and the backlog of the accept queue
+// (按opts的套接字选项监听：SO_REUSEPORT使每个分片在同一端口上拥有独立的监听，由内核在它们之间均衡连接；
+// TCP_DEFER_ACCEPT使连接在发送数据之后才被接受；以及accept队列的长度)
+func listenTuned(network, address string, opts listenOptions) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				if opts.reusePort {
+					if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); sockErr != nil {
+						return
+					}
+				}
+				if seconds := int(opts.deferAccept.Seconds()); seconds > 0 {
+					sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_DEFER_ACCEPT, seconds)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	listener, err := lc.Listen(context.Background(), network, address)
+	if err != nil || opts.backlog <= 0 {
+		return listener, err
+	}
+
+	// Go listens with the somaxconn backlog, listening again on the socket resizes its queue
+	// (Go以somaxconn作为backlog监听，在套接字上再次listen即可调整队列长度)
+	rawConn, err := listener.(*net.TCPListener).SyscallConn()
+	if err == nil {
+		var listenErr error
+		err = rawConn.Control(func(fd uintptr) {
+			listenErr = unix.Listen(int(fd), opts.backlog)
+		})
+		if err == nil {
+			err = listenErr
+		}
+	}
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// reusePortSupported reports whether every shard can have its own listener (是否每个分片都可以拥有独立的监听)
+const reusePortSupported = true
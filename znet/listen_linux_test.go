@@ -0,0 +1,39 @@
+//go:build linux
+// +build linux
+
+package znet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestListenTuned(t *testing.T) {
+	listener, err := listenTuned("tcp4", "127.0.0.1:0", listenOptions{reusePort: true, backlog: 16, deferAccept: 3 * time.Second})
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	rawConn, err := listener.(*net.TCPListener).SyscallConn()
+	assert.Nil(t, err)
+	var reusePort, deferAccept int
+	assert.Nil(t, rawConn.Control(func(fd uintptr) {
+		reusePort, _ = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT)
+		deferAccept, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_DEFER_ACCEPT)
+	}))
+	assert.Equal(t, 1, reusePort)
+	// The kernel rounds the seconds up to its retransmission steps (内核将秒数向上取整到其重传间隔)
+	assert.GreaterOrEqual(t, deferAccept, 3)
+
+	// A deferred connection is accepted once it sends data (延迟接受的连接在发送数据之后被接受)
+	client, err := net.Dial("tcp4", listener.Addr().String())
+	assert.Nil(t, err)
+	defer client.Close()
+	_, _ = client.Write([]byte("hi"))
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	_ = conn.Close()
+}
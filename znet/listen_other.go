@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package znet
+
+import (
+	"net"
+)
+
+// listenTuned falls back to a plain listener, the shards then share one listener, the backlog and the deferred
+// accept are left to the system (回退为普通监听，此时各分片共享同一个监听，backlog与延迟接受由系统决定)
+func listenTuned(network, address string, opts listenOptions) (net.Listener, error) {
+	return net.Listen(network, address)
+}
+
+// reusePortSupported reports whether every shard can have its own listener (是否每个分片都可以拥有独立的监听)
+const reusePortSupported = false
@@ -0,0 +1,61 @@
+package znet
+
+import (
+	"context"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// connLogCtx returns a context carrying the standard log fields of conn, for the context variants of zlog.Ins()
+// (返回携带conn标准日志字段的context，用于zlog.Ins()的context版本方法)
+func connLogCtx(conn ziface.IConnection) context.Context {
+	return zlog.WithFields(context.Background(), zlog.ConnFields(conn)...)
+}
+
+// requestLogCtx adds the msgID and, when the request already has one, the traceID to the fields of its connection
+// (在连接字段之外加上msgID，以及请求已有的traceID)
+func requestLogCtx(request ziface.IRequest) context.Context {
+	ctx := zlog.WithFields(connLogCtx(request.GetConnection()), zlog.F(zlog.FieldMsgID, request.GetMsgID()))
+	if traceID := existingTraceID(request); traceID != "" {
+		ctx = zlog.WithFields(ctx, zlog.F(zlog.FieldTraceID, traceID))
+	}
+	return ctx
+}
+
+// Samplers of the error lines a client can trigger at will, so that one abusive client cannot flood the log
+// (客户端可随意触发的错误日志的采样器，避免单个恶意客户端刷爆日志)
+var (
+	sendErrLog   = zlog.PerSecond(10)
+	decodeErrLog = zlog.PerSecond(10)
+	notFoundLog  = zlog.PerSecond(10)
+	authErrLog   = zlog.PerSecond(10)
+	floodLog     = zlog.PerSecond(10)
+	replayLog    = zlog.PerSecond(10)
+	signatureLog = zlog.PerSecond(10)
+	dropLog      = zlog.PerSecond(10)
+)
+
+// dispatcherLogCtx is the log context of the dispatcher lines without a request (没有请求的消息分发日志的context)
+var dispatcherLogCtx = zlog.WithModule(context.Background(), zlog.ModuleDispatcher)
+
+// dispatchLogCtx is requestLogCtx for the lines of the dispatcher (用于消息分发日志的requestLogCtx)
+func dispatchLogCtx(request ziface.IRequest) context.Context {
+	return zlog.WithModule(requestLogCtx(request), zlog.ModuleDispatcher)
+}
+
+// heartbeatLogCtx is connLogCtx for the lines of the heartbeat checker (用于心跳日志的connLogCtx)
+func heartbeatLogCtx(conn ziface.IConnection) context.Context {
+	return zlog.WithModule(connLogCtx(conn), zlog.ModuleHeartbeat)
+}
+
+// existingTraceID returns the trace ID of the request without generating one (返回请求的追踪ID，不会生成新的ID)
+func existingTraceID(request ziface.IRequest) string {
+	if tm, ok := request.GetMessage().(ziface.ITraceMessage); ok && tm.GetTraceID() != "" {
+		return tm.GetTraceID()
+	}
+	if r, ok := request.(*Request); ok {
+		return r.GetString(traceKey)
+	}
+	return ""
+}
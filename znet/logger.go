@@ -0,0 +1,53 @@
+package znet
+
+import (
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// WithLogger logs the server and its connections with logger instead of zlog.Ins(), a server whose config has log
+// settings of its own gets one by default, see zconf.Config.NewLogger
+// (使用logger代替zlog.Ins()输出服务及其连接的日志，配置中有自身日志设置的服务默认获得一个，见zconf.Config.NewLogger)
+func WithLogger(logger ziface.ILogger) Option {
+	return func(s *Server) {
+		s.setLogger(logger)
+	}
+}
+
+func (s *Server) setLogger(logger ziface.ILogger) {
+	s.logger = logger
+	if mh, ok := s.msgHandler.(*MsgHandle); ok {
+		mh.logger = logger
+	}
+}
+
+// GetLogger returns the logger of the server (返回服务的日志)
+func (s *Server) GetLogger() ziface.ILogger {
+	return s.log()
+}
+
+func (s *Server) log() ziface.ILogger {
+	if s != nil && s.logger != nil {
+		return s.logger
+	}
+	return zlog.Ins()
+}
+
+func (mh *MsgHandle) log() ziface.ILogger {
+	if mh != nil && mh.logger != nil {
+		return mh.logger
+	}
+	return zlog.Ins()
+}
+
+// LoggerOf returns the logger of the server owning conn, zlog.Ins() for clients
+// (返回conn所属服务的日志，客户端返回zlog.Ins())
+func LoggerOf(conn ziface.IConnection) ziface.ILogger {
+	if conn == nil {
+		return zlog.Ins()
+	}
+	if mh, ok := conn.GetMsgHandler().(*MsgHandle); ok {
+		return mh.log()
+	}
+	return zlog.Ins()
+}
@@ -0,0 +1,71 @@
+package znet
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type countLogger struct {
+	lines int32
+}
+
+func (l *countLogger) InfoF(format string, v ...interface{})  { atomic.AddInt32(&l.lines, 1) }
+func (l *countLogger) ErrorF(format string, v ...interface{}) { atomic.AddInt32(&l.lines, 1) }
+func (l *countLogger) DebugF(format string, v ...interface{}) { atomic.AddInt32(&l.lines, 1) }
+func (l *countLogger) InfoFX(ctx context.Context, format string, v ...interface{}) {
+	atomic.AddInt32(&l.lines, 1)
+}
+func (l *countLogger) ErrorFX(ctx context.Context, format string, v ...interface{}) {
+	atomic.AddInt32(&l.lines, 1)
+}
+func (l *countLogger) DebugFX(ctx context.Context, format string, v ...interface{}) {
+	atomic.AddInt32(&l.lines, 1)
+}
+
+func TestWithLogger(t *testing.T) {
+	logger := &countLogger{}
+	s := newServerWithConfig(zconf.DefaultConfig(), "tcp", WithLogger(logger)).(*Server)
+	assert.Equal(t, logger, s.GetLogger())
+
+	conn := &Connection{connID: 3, connIdStr: "3", msgHandler: s.msgHandler}
+	assert.Equal(t, logger, LoggerOf(conn))
+	LoggerOf(conn).InfoF("connID = %d", conn.GetConnID())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logger.lines))
+
+	// Clients and servers without a logger of their own use zlog.Ins() (客户端与没有自身日志的服务使用zlog.Ins())
+	assert.Equal(t, zlog.Ins(), LoggerOf(nil))
+	other := newServerWithConfig(zconf.DefaultConfig(), "tcp").(*Server)
+	assert.Equal(t, zlog.Ins(), other.GetLogger())
+}
+
+func TestWithConnIDs(t *testing.T) {
+	var next uint64 = 100
+	ids := func() uint64 { return atomic.AddUint64(&next, 1) }
+	a := newServerWithConfig(zconf.DefaultConfig(), "tcp", WithConnIDs(ids)).(*Server)
+	b := newServerWithConfig(zconf.DefaultConfig(), "tcp", WithConnIDs(ids)).(*Server)
+	assert.Equal(t, uint64(101), a.nextConnID())
+	assert.Equal(t, uint64(102), b.nextConnID())
+
+	c := newServerWithConfig(zconf.DefaultConfig(), "tcp").(*Server)
+	assert.Equal(t, uint64(1), c.nextConnID())
+}
+
+func TestServerMaxPacketSize(t *testing.T) {
+	small, large := zconf.DefaultConfig(), zconf.DefaultConfig()
+	small.MaxPacketSize, large.MaxPacketSize = 16, 4096
+	a := newServerWithConfig(small, "tcp").(*Server)
+	b := newServerWithConfig(large, "tcp").(*Server)
+
+	frame, _ := zpack.NewDataPack().Pack(zpack.NewMsgPackage(1, make([]byte, 32)))
+	head := frame[:a.packet.GetHeadLen()]
+	_, err := a.packet.Unpack(head)
+	assert.NotNil(t, err)
+	_, err = b.packet.Unpack(head)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,107 @@
+package znet
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/gorilla/websocket"
+)
+
+// closeReason records why a connection was closed, the first reason set wins, see zmetrics.CloseReasonStopped
+// (记录连接关闭的原因，以第一次设置的原因为准，见zmetrics.CloseReasonStopped)
+type closeReason struct {
+	v atomic.Value
+}
+
+func (r *closeReason) setCloseReason(reason string) {
+	r.v.CompareAndSwap(nil, reason)
+}
+
+func (r *closeReason) getCloseReason() string {
+	if reason, ok := r.v.Load().(string); ok {
+		return reason
+	}
+	return zmetrics.CloseReasonStopped
+}
+
+type closeReasoner interface {
+	setCloseReason(reason string)
+	getCloseReason() string
+}
+
+// setCloseReason records why conn is about to be closed (记录conn即将关闭的原因)
+func setCloseReason(conn ziface.IConnection, reason string) {
+	if r, ok := conn.(closeReasoner); ok {
+		r.setCloseReason(reason)
+	}
+}
+
+// readCloseReason is the close reason of a failed read (读取失败时的关闭原因)
+func readCloseReason(err error) string {
+	if errors.Is(err, io.EOF) || websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return zmetrics.CloseReasonClient
+	}
+	return zmetrics.CloseReasonReadError
+}
+
+// metricsOf returns the metrics of the server or the client owning conn, zmetrics.Zinx if it has none
+// (返回conn所属服务或客户端的指标，没有时返回zmetrics.Zinx)
+func metricsOf(conn ziface.IConnection) *zmetrics.Metrics {
+	if conn == nil {
+		return zmetrics.Zinx
+	}
+	return zmetrics.Of(conn.GetMsgHandler())
+}
+
+// connAdded and connRemoved count the connections of the ConnManager (统计ConnManager中的连接)
+func connAdded(conn ziface.IConnection) {
+	m := metricsOf(conn)
+	m.Connections.Add(1)
+	m.ConnectionsAccepted.Inc()
+}
+
+func connRemoved(conn ziface.IConnection) {
+	m := metricsOf(conn)
+	m.Connections.Add(-1)
+	reason := zmetrics.CloseReasonStopped
+	if r, ok := conn.(closeReasoner); ok {
+		reason = r.getCloseReason()
+	}
+	m.ConnectionsClosed.With(reason).Inc()
+}
+
+// sent counts a message written to the connection (统计写入连接的消息)
+func sent(m *zmetrics.Metrics, data []byte) {
+	m.MessagesSent.Inc()
+	m.BytesSent.Add(uint64(len(data)))
+}
+
+// collectWorkerQueues reports the queue depth of the workers of mh to m at each scrape, the returned function stops it
+// (在每次采集时向m上报mh中各worker的队列长度，返回的函数用于停止上报)
+func collectWorkerQueues(m *zmetrics.Metrics, server string, mh ziface.IMsgHandle) (stop func()) {
+	var last [][]string
+	remove := m.Registry.OnCollect(func() {
+		for _, labels := range last {
+			m.WorkerQueueDepth.Delete(labels...)
+		}
+		last = last[:0]
+		if !zmetrics.Enabled() {
+			return
+		}
+		for _, stats := range mh.GetWorkerStats() {
+			labels := []string{server, stats.Pool, strconv.Itoa(stats.WorkerID)}
+			m.WorkerQueueDepth.With(labels...).Set(float64(stats.QueueLen))
+			last = append(last, labels)
+		}
+	})
+	return func() {
+		remove()
+		for _, labels := range last {
+			m.WorkerQueueDepth.Delete(labels...)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package znet
+
+import (
+	"io"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnMetrics(t *testing.T) {
+	zmetrics.Enable()
+	accepted := zmetrics.ConnectionsAccepted.Value()
+	heartbeat := zmetrics.ConnectionsClosed.With(zmetrics.CloseReasonHeartbeat).Value()
+	stopped := zmetrics.ConnectionsClosed.With(zmetrics.CloseReasonStopped).Value()
+
+	mgr := newConnManager()
+	c1 := &Connection{connID: 1, connIdStr: "1"}
+	c2 := &Connection{connID: 2, connIdStr: "2"}
+	mgr.Add(c1)
+	mgr.Add(c2)
+	assert.Equal(t, accepted+2, zmetrics.ConnectionsAccepted.Value())
+
+	setCloseReason(c1, zmetrics.CloseReasonHeartbeat)
+	c1.setCloseReason(readCloseReason(io.EOF))
+	mgr.Remove(c1)
+	mgr.Remove(c2)
+	assert.Equal(t, heartbeat+1, zmetrics.ConnectionsClosed.With(zmetrics.CloseReasonHeartbeat).Value())
+	assert.Equal(t, stopped+1, zmetrics.ConnectionsClosed.With(zmetrics.CloseReasonStopped).Value())
+	assert.Equal(t, zmetrics.CloseReasonClient, readCloseReason(io.EOF))
+	assert.Equal(t, zmetrics.CloseReasonReadError, readCloseReason(io.ErrClosedPipe))
+}
+
+func TestServerMetrics(t *testing.T) {
+	zmetrics.Enable()
+	m := zmetrics.NewMetrics(zmetrics.NewRegistry())
+	s := newServerWithConfig(zconf.DefaultConfig(), "tcp", WithMetrics(m)).(*Server)
+	accepted := zmetrics.ConnectionsAccepted.Value()
+
+	// The connections of the server count into its own metrics (服务的连接计入其自身的指标)
+	conn := &Connection{connID: 1, connIdStr: "1", msgHandler: s.msgHandler}
+	s.ConnMgr.Add(conn)
+	assert.Equal(t, uint64(1), m.ConnectionsAccepted.Value())
+	assert.Equal(t, accepted, zmetrics.ConnectionsAccepted.Value())
+	assert.Same(t, m, metricsOf(conn))
+	assert.Same(t, zmetrics.Zinx, metricsOf(&Connection{connID: 2, connIdStr: "2"}))
+	s.ConnMgr.Remove(conn)
+	assert.Equal(t, float64(0), m.Connections.Value())
+}
@@ -1,13 +1,18 @@
 package znet
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
 )
 
 const (
@@ -24,11 +29,108 @@ type MsgHandle struct {
 	// A map property that stores the processing methods for each MsgID
 	// (存放每个MsgID 所对应的处理方法的map属性)
 	Apis map[uint32]ziface.IRouter
+	// The requests in flight on the router of each MsgID, see ReplaceRouterDrain (每个MsgID的路由上正在处理的请求，见ReplaceRouterDrain)
+	epochs map[uint32]*routeEpoch
+
+	// Routers bound to MsgID ranges, used when no exact MsgID router is found
+	// (绑定到MsgID区间的路由，在没有精确匹配的MsgID路由时使用)
+	rangeApis msgIDRanges
+
+	// Router used when no router matches the MsgID
+	// (所有路由都未匹配时使用的默认路由)
+	notFoundRouter ziface.IRouter
+
+	// Routers bound to string topics and the method extracting the topic of a request
+	// (绑定到字符串主题的路由，以及提取请求主题的方法)
+	topicApis      topicRoutes
+	topicExtractor ziface.TopicExtractor
+
+	// Max handler durations of routes and the number of timed out requests
+	// (路由处理的最长时间，以及处理超时的请求数量)
+	timeouts     map[uint32]*routeTimeout
+	timeoutCount uint64
+
+	// Hook called after a handler panic is recovered (处理函数panic被恢复后的回调)
+	onHandlerPanic ziface.PanicHandler
+
+	// Route priorities, the hook called when a request is dropped on a full queue and the overflow counters
+	// (路由优先级、队列已满丢弃请求时的回调以及溢出计数)
+	priorities  map[uint32]int
+	onQueueFull ziface.QueueFullHandler
+	dropCount   uint64
+	spillCount  uint64
+
+	// Routes opted out of per-connection ordering, dispatched round-robin to all workers
+	// (不要求按连接顺序处理的路由，轮询分发给所有worker)
+	unordered    map[uint32]struct{}
+	nextWorkerID uint32
+
+	// Dedicated worker pools by name, and the routes bound to them
+	// (按名称区分的专属worker池，以及绑定到这些池的路由)
+	pools      map[string]*routePool
+	routePools map[uint32]*routePool
+
+	// Payload validators of routes, the failure counters per route and the failure handler
+	// (路由的消息体校验器、每个路由的校验失败计数以及校验失败的处理函数)
+	validators         map[uint32][]ziface.Validator
+	validationFailures map[uint32]*uint64
+	onValidationFailed ziface.ValidationFailedHandler
+	// The handler of the requests denied by the authorization and their count
+	// (被授权检查拒绝的请求的处理函数及其数量)
+	onForbidden    ziface.ValidationFailedHandler
+	forbiddenCount uint64
+
+	// The inbound rate limits of the routes and the hook of the messages over the limits
+	// (路由的入站速率限制以及超限消息的回调)
+	rateLimits    map[uint32]ziface.RateLimit
+	onRateLimited ziface.RateLimitHandler
+	// The IPs paused for abuse, see AbuseThreshold (因滥用被暂停的IP，见AbuseThreshold)
+	sources *sourceGuard
+	// The signing of the messages, see WithMessageSigning (消息签名，见WithMessageSigning)
+	signing *messageSigning
+	// The faults injected, see Server.SetFaults (注入的故障，见Server.SetFaults)
+	faults *faultInjector
+
+	// Routers can be added, removed or replaced while the server is running,
+	// apisLock protects Apis, rangeApis and notFoundRouter
+	// (服务运行期间也可以增删替换路由，apisLock保护Apis、rangeApis和notFoundRouter)
+	apisLock sync.RWMutex
+
+	// The logger of the server owning the handler, zlog.Ins() if nil (所属服务的日志，为nil时使用zlog.Ins())
+	logger ziface.ILogger
+
+	// The config of the server owning the handler, zconf.GlobalObject unless the server has its own
+	// (所属服务的配置，除非服务有自己的配置，否则为zconf.GlobalObject)
+	config *zconf.Config
+	// The metrics of the server or the client owning the handler, see Metrics (所属服务或客户端的指标，见Metrics)
+	metrics *zmetrics.Metrics
+	// The timer wheels of the shards of the server or the client owning the handler, see shardScheduler
+	// (所属服务或客户端各分片的时间轮，见shardScheduler)
+	timers *shardTimers
 
 	// The number of worker goroutines in the business work Worker pool
 	// (业务工作Worker池的数量)
 	WorkerPoolSize uint32
 
+	// workersLock protects WorkerPoolSize and TaskQueue once ResizeWorkerPool may run,
+	// workersStarted records whether StartWorkerPool has been called
+	// (workersLock在可能调用ResizeWorkerPool后保护WorkerPoolSize和TaskQueue，workersStarted记录是否已调用StartWorkerPool)
+	workersLock    sync.RWMutex
+	workersStarted bool
+	// sending counts the requests picked a queue under workersLock and are being sent to it,
+	// ResizeWorkerPool replaces it and closes the removed queues once the old one is done
+	// (sending统计在workersLock下选定队列、正在发送的请求，ResizeWorkerPool替换它，并在旧的计数归零后关闭被移除的队列)
+	sending *sync.WaitGroup
+	// quit is closed once the context of StartWorkerPoolContext is done (StartWorkerPoolContext的context结束时关闭)
+	quit *quitSignal
+
+	// The work-stealing scheduler used instead of TaskQueue in zconf.WorkerModeSteal
+	// (zconf.WorkerModeSteal模式下代替TaskQueue使用的工作窃取调度器)
+	stealer *stealScheduler
+
+	// Counters of the running workers by pool and worker ID (按池和worker ID区分的运行中worker的统计数据)
+	workerStats sync.Map
+
 	// A collection of idle workers, used for zconf.WorkerModeBind
 	// 空闲worker集合，用于zconf.WorkerModeBind
 	freeWorkers  map[uint32]struct{}
@@ -38,6 +140,14 @@ type MsgHandle struct {
 	// (Worker负责取任务的消息队列)
 	TaskQueue []chan ziface.IRequest
 
+	// The handler whose worker pool runs the requests instead of an own one, see WithSharedWorkerPool
+	// (代替自身的worker池运行请求的处理器，见WithSharedWorkerPool)
+	workers *MsgHandle
+
+	// The priority lanes of the workers, requests of routes with a priority above 0 are taken from them
+	// before the TaskQueue (worker的优先通道，优先级大于0的路由的请求会先于TaskQueue被取出)
+	priorityQueue []chan ziface.IRequest
+
 	// A collection of extra workers, used for zconf.WorkerModeDynamicBind
 	// (池里的工作线程不够用的时候, 可临时额外分配workerID集合, 用于zconf.WorkerModeDynamicBind)
 	extraFreeWorkers  map[uint32]struct{}
@@ -47,55 +157,70 @@ type MsgHandle struct {
 	// (责任链构造器)
 	builder      *chainBuilder
 	RouterSlices *RouterSlices
+
+	// The recorder tapping the messages, see Server.SetRecorder (截取消息的记录器，见Server.SetRecorder)
+	recorder atomic.Value // recorderBox
+	// The raw message taps, see Server.AddMessageTap (原始消息的截取，见Server.AddMessageTap)
+	taps     atomic.Value // []*messageTap
+	tapsLock sync.Mutex
 }
 
 // newMsgHandle creates MsgHandle
 // zinxRole: IServer
 func newMsgHandle() *MsgHandle {
+	return newMsgHandleWithConfig(zconf.GlobalObject)
+}
+
+// newMsgHandleWithConfig creates the MsgHandle of a server with its own config
+// (使用服务自己的配置创建MsgHandle)
+func newMsgHandleWithConfig(config *zconf.Config) *MsgHandle {
 	var freeWorkers map[uint32]struct{}
 	var extraFreeWorkers map[uint32]struct{}
 
-	if zconf.GlobalObject.WorkerMode == zconf.WorkerModeBind {
+	if config.WorkerMode == zconf.WorkerModeBind {
 		// Assign a workder to each link, avoid interactions when multiple links are processed by the same worker
 		// MaxWorkerTaskLen can also be reduced, for example, 50
 		// 为每个链接分配一个workder，避免同一worker处理多个链接时的互相影响
 		// 同时可以减小MaxWorkerTaskLen，比如50，因为每个worker的负担减轻了
-		zconf.GlobalObject.WorkerPoolSize = uint32(zconf.GlobalObject.MaxConn)
-		freeWorkers = make(map[uint32]struct{}, zconf.GlobalObject.WorkerPoolSize)
-		for i := uint32(0); i < zconf.GlobalObject.WorkerPoolSize; i++ {
+		config.WorkerPoolSize = uint32(config.MaxConn)
+		freeWorkers = make(map[uint32]struct{}, config.WorkerPoolSize)
+		for i := uint32(0); i < config.WorkerPoolSize; i++ {
 			freeWorkers[i] = struct{}{}
 		}
 	}
 
-	TaskQueueLen := zconf.GlobalObject.WorkerPoolSize
+	TaskQueueLen := config.WorkerPoolSize
 
-	if zconf.GlobalObject.WorkerMode == zconf.WorkerModeDynamicBind {
-		zlog.Ins().DebugF("WorkerMode = %s", zconf.WorkerModeDynamicBind)
-		freeWorkers = make(map[uint32]struct{}, zconf.GlobalObject.WorkerPoolSize)
-		for i := uint32(0); i < zconf.GlobalObject.WorkerPoolSize; i++ {
+	if config.WorkerMode == zconf.WorkerModeDynamicBind {
+		freeWorkers = make(map[uint32]struct{}, config.WorkerPoolSize)
+		for i := uint32(0); i < config.WorkerPoolSize; i++ {
 			freeWorkers[i] = struct{}{}
 		}
 
-		extraFreeWorkers = make(map[uint32]struct{}, zconf.GlobalObject.MaxConn-int(zconf.GlobalObject.WorkerPoolSize))
-		for i := zconf.GlobalObject.WorkerPoolSize; i < uint32(zconf.GlobalObject.MaxConn); i++ {
+		extraFreeWorkers = make(map[uint32]struct{}, config.MaxConn-int(config.WorkerPoolSize))
+		for i := config.WorkerPoolSize; i < uint32(config.MaxConn); i++ {
 			extraFreeWorkers[i] = struct{}{}
 		}
-		TaskQueueLen = uint32(zconf.GlobalObject.MaxConn)
+		TaskQueueLen = uint32(config.MaxConn)
 	}
 
 	handle := &MsgHandle{
+		sending:      &sync.WaitGroup{},
 		Apis:         make(map[uint32]ziface.IRouter),
 		RouterSlices: NewRouterSlices(),
 		freeWorkers:  freeWorkers,
 		builder:      newChainBuilder(),
+		config:       config,
+		quit:         newQuitSignal(),
 		// 可额外临时分配的workerID集合
 		extraFreeWorkers: extraFreeWorkers,
 	}
 
 	// server
-	handle.WorkerPoolSize = zconf.GlobalObject.WorkerPoolSize
+	handle.WorkerPoolSize = config.WorkerPoolSize
 	// One worker corresponds to one queue (一个worker对应一个queue)
 	handle.TaskQueue = make([]chan ziface.IRequest, TaskQueueLen)
+	handle.priorityQueue = make([]chan ziface.IRequest, TaskQueueLen)
 
 	// It is necessary to add the MsgHandle to the responsibility chain here, and it is the last link in the responsibility chain. After decoding in the MsgHandle, data distribution is done by router
 	// (此处必须把 msghandler 添加到责任链中，并且是责任链最后一环，在msghandler中进行解码后由router做数据分发)
@@ -103,57 +228,12 @@ func newMsgHandle() *MsgHandle {
 	return handle
 }
 
-// newCliMsgHandle creates MsgHandle
+// newCliMsgHandle creates the MsgHandle of a client with its config
 // zinxRole: IClient
-func newCliMsgHandle() *MsgHandle {
-	var freeWorkers map[uint32]struct{}
-	var extraFreeWorkers map[uint32]struct{}
-
-	if zconf.GlobalObject.WorkerMode == zconf.WorkerModeBind {
-		// Assign a workder to each link, avoid interactions when multiple links are processed by the same worker
-		// MaxWorkerTaskLen can also be reduced, for example, 50
-		// 为每个链接分配一个workder，避免同一worker处理多个链接时的互相影响
-		// 同时可以减小MaxWorkerTaskLen，比如50，因为每个worker的负担减轻了
-		zconf.GlobalObject.WorkerPoolSize = uint32(zconf.GlobalObject.MaxConn)
-		freeWorkers = make(map[uint32]struct{}, zconf.GlobalObject.WorkerPoolSize)
-		for i := uint32(0); i < zconf.GlobalObject.WorkerPoolSize; i++ {
-			freeWorkers[i] = struct{}{}
-		}
-	}
-
-	TaskQueueLen := zconf.GlobalObject.WorkerPoolSize
-
-	if zconf.GlobalObject.WorkerMode == zconf.WorkerModeDynamicBind {
-		zlog.Ins().DebugF("WorkerMode = %s", zconf.WorkerModeDynamicBind)
-		freeWorkers = make(map[uint32]struct{}, zconf.GlobalObject.WorkerPoolSize)
-		for i := uint32(0); i < zconf.GlobalObject.WorkerPoolSize; i++ {
-			freeWorkers[i] = struct{}{}
-		}
-
-		extraFreeWorkers = make(map[uint32]struct{}, zconf.GlobalObject.MaxConn-int(zconf.GlobalObject.WorkerPoolSize))
-		for i := zconf.GlobalObject.WorkerPoolSize; i < uint32(zconf.GlobalObject.MaxConn); i++ {
-			extraFreeWorkers[i] = struct{}{}
-		}
-		TaskQueueLen = uint32(zconf.GlobalObject.MaxConn)
-	}
-
-	handle := &MsgHandle{
-		Apis:         make(map[uint32]ziface.IRouter),
-		RouterSlices: NewRouterSlices(),
-		freeWorkers:  freeWorkers,
-		builder:      newChainBuilder(),
-		// 可额外临时分配的workerID集合
-		extraFreeWorkers: extraFreeWorkers,
-	}
-
+func newCliMsgHandle(config *zconf.Config) *MsgHandle {
+	handle := newMsgHandleWithConfig(config)
 	// client: Set worker pool size to 0 to turn off the worker pool in the client (客户端将协程池关闭)
 	handle.WorkerPoolSize = 0
-	// One worker corresponds to one queue (一个worker对应一个queue)
-	handle.TaskQueue = make([]chan ziface.IRequest, TaskQueueLen)
-
-	// It is necessary to add the MsgHandle to the responsibility chain here, and it is the last link in the responsibility chain. After decoding in the MsgHandle, data distribution is done by router
-	// (此处必须把 msghandler 添加到责任链中，并且是责任链最后一环，在msghandler中进行解码后由router做数据分发)
-	handle.builder.Tail(handle)
 	return handle
 }
 
@@ -164,11 +244,11 @@ func useWorker(conn ziface.IConnection) uint32 {
 
 	mh, _ := conn.GetMsgHandler().(*MsgHandle)
 	if mh == nil {
-		zlog.Ins().ErrorF("useWorker failed, mh is nil")
+		LoggerOf(conn).ErrorF("useWorker failed, mh is nil")
 		return 0
 	}
 
-	if zconf.GlobalObject.WorkerMode == zconf.WorkerModeBind {
+	if mh.config.WorkerMode == zconf.WorkerModeBind {
 		mh.freeWorkerMu.Lock()
 		defer mh.freeWorkerMu.Unlock()
 
@@ -178,7 +258,7 @@ func useWorker(conn ziface.IConnection) uint32 {
 		}
 	}
 
-	if zconf.GlobalObject.WorkerMode == zconf.WorkerModeDynamicBind {
+	if mh.config.WorkerMode == zconf.WorkerModeDynamicBind {
 		mh.freeWorkerMu.Lock()
 		// try to get workerID from workerPool first
 		// 首先尝试从工作线程池里获取一个空闲的workerID
@@ -193,8 +273,8 @@ func useWorker(conn ziface.IConnection) uint32 {
 		mh.extraFreeWorkerMu.Lock()
 		defer mh.extraFreeWorkerMu.Unlock()
 		for workerID := range mh.extraFreeWorkers {
-			zlog.Ins().DebugF("start extra worker, workerID=%d", workerID)
-			mh.TaskQueue[workerID] = make(chan ziface.IRequest, zconf.GlobalObject.MaxWorkerTaskLen)
+			LoggerOf(conn).DebugF("start extra worker, workerID=%d", workerID)
+			mh.TaskQueue[workerID] = make(chan ziface.IRequest, mh.config.Live().MaxWorkerTaskLen)
 			go mh.StartOneWorker(int(workerID), mh.TaskQueue[workerID])
 			return workerID
 		}
@@ -202,6 +282,9 @@ func useWorker(conn ziface.IConnection) uint32 {
 
 	//Compatible with the situation where the client has no worker, and solve the situation divide 0
 	//(兼容client没有worker情况，解决除0的情况)
+	mh = mh.pool()
+	mh.workersLock.RLock()
+	defer mh.workersLock.RUnlock()
 	if mh.WorkerPoolSize == 0 {
 		workerId = 0
 	} else {
@@ -221,18 +304,18 @@ func useWorker(conn ziface.IConnection) uint32 {
 func freeWorker(conn ziface.IConnection) {
 	mh, _ := conn.GetMsgHandler().(*MsgHandle)
 	if mh == nil {
-		zlog.Ins().ErrorF("useWorker failed, mh is nil")
+		LoggerOf(conn).ErrorF("useWorker failed, mh is nil")
 		return
 	}
 
-	if zconf.GlobalObject.WorkerMode == zconf.WorkerModeBind {
+	if mh.config.WorkerMode == zconf.WorkerModeBind {
 		mh.freeWorkerMu.Lock()
 		defer mh.freeWorkerMu.Unlock()
 
 		mh.freeWorkers[conn.GetWorkerID()] = struct{}{}
 	}
 
-	if zconf.GlobalObject.WorkerMode == zconf.WorkerModeDynamicBind {
+	if mh.config.WorkerMode == zconf.WorkerModeDynamicBind {
 		workerID := conn.GetWorkerID()
 		if workerID < mh.WorkerPoolSize {
 			// 说明这个是工作线程池里的workerID，回收这个workerID, workerID对应的worker不需要销毁
@@ -258,21 +341,20 @@ func (mh *MsgHandle) Intercept(chain ziface.IChain) ziface.IcResp {
 		switch request.(type) {
 		case ziface.IRequest:
 			iRequest := request.(ziface.IRequest)
-			if mh.WorkerPoolSize > 0 {
-				// If the worker pool mechanism has been started, hand over the message to the worker for processing
-				// (已经启动工作池机制，将消息交给Worker处理)
-				mh.SendMsgToTaskQueue(iRequest)
-			} else {
-
-				// Execute the corresponding Handle method from the bound message and its corresponding processing method
-				// (从绑定好的消息和对应的处理方法中执行对应的Handle方法)
-				if !zconf.GlobalObject.RouterSlicesMode {
-					go mh.doMsgHandler(iRequest, WorkerIDWithoutWorkerPool)
-				} else if zconf.GlobalObject.RouterSlicesMode {
-					go mh.doMsgHandlerSlices(iRequest, WorkerIDWithoutWorkerPool)
-				}
-
+			assignTraceID(iRequest)
+			traceDispatch(iRequest)
+			observeMessage(iRequest.GetConnection(), directionInbound, iRequest.GetMsgID(), iRequest.GetData())
+			// The draining connections get no new work, see Server.DrainConns (正在排空的连接不再接受新请求，见Server.DrainConns)
+			if !beginWork(iRequest.GetConnection()) {
+				mh.log().DebugFX(dispatchLogCtx(iRequest), "connection is draining, refuse msgID = %d", iRequest.GetMsgID())
+				// The request has been recycled, it must not go further down the chain (请求已被回收，不能继续传递)
+				PutRequest(iRequest)
+				return nil
 			}
+			// Hand over the message to the dedicated pool of the route or to the shared worker pool,
+			// it runs on its own goroutine if the worker pool mechanism has not been started
+			// (将消息交给路由的专属池或共享worker池处理，未启动工作池机制时在单独的协程中处理)
+			mh.SendMsgToTaskQueue(iRequest)
 		}
 	}
 
@@ -296,19 +378,89 @@ func (mh *MsgHandle) AddInterceptor(interceptor ziface.IInterceptor) {
 
 // SendMsgToTaskQueue sends the message to the TaskQueue for processing by the worker
 // (将消息交给TaskQueue,由worker进行处理)
+//
+// Ordering guarantees (顺序保证):
+//   - Every connection is bound to one worker (by ConnID in WorkerModeHash, exclusively in WorkerModeBind
+//     and WorkerModeDynamicBind), so the messages of a connection are handled one by one in arrival order,
+//     while different connections run in parallel on different workers.
+//     (每个连接绑定一个worker，同一连接的消息按到达顺序逐个处理，不同连接在不同worker上并行处理)
+//   - In WorkerModeSteal the messages of a connection are still handled one by one in arrival order,
+//     but an idle worker can take over the whole queue of a connection from a busy one.
+//     (WorkerModeSteal模式下同一连接的消息仍按到达顺序逐个处理，但空闲的worker可以从繁忙的worker接管整个连接的队列)
+//   - Routes set by SetRouterUnordered give up that guarantee, their messages are dispatched round-robin
+//     to all workers of the pool so a burst on one connection can use the whole pool.
+//     (通过SetRouterUnordered设置的路由放弃该保证，消息轮询分发给池中所有worker)
+//   - Routes bound to a dedicated pool by SetRouterPool follow the same rules inside that pool.
+//     (通过SetRouterPool绑定到专属worker池的路由在该池内遵循相同的规则)
+//   - Without a worker pool (WorkerPoolSize = 0) every message runs on its own goroutine, there is no ordering at all.
+//     (不启用worker池时每条消息在单独的协程中处理，没有任何顺序保证)
 func (mh *MsgHandle) SendMsgToTaskQueue(request ziface.IRequest) {
+	if pool := mh.getRoutePool(request.GetMsgID()); pool != nil {
+		mh.enqueue(pool.queue(request, mh.isUnordered(request.GetMsgID()), mh.getRouterPriority(request.GetMsgID()) > 0), request)
+		return
+	}
+
+	pool := mh.pool()
+	queue, sending, stealer := mh.pickQueue(pool, request)
+	if stealer != nil {
+		stealer.dispatch(request, mh.isUnordered(request.GetMsgID()))
+		return
+	}
+	if queue == nil {
+		return
+	}
+	// The blocking send runs without workersLock, sending keeps ResizeWorkerPool from closing the queue meanwhile
+	// (阻塞发送时不持有workersLock，sending保证期间ResizeWorkerPool不会关闭该队列)
+	defer sending.Done()
+	mh.enqueue(queue, request)
+}
+
+// pickQueue picks the queue of the shared pool for the request under workersLock and registers the send
+// on the returned WaitGroup. It returns the stealer instead in WorkerModeSteal, which is never resized,
+// and a nil queue if the request already runs without a worker.
+// (在workersLock下为请求选定共享池的队列，并在返回的WaitGroup上登记本次发送。WorkerModeSteal模式下返回不会被调整大小的窃取调度器，
+// 请求已在无worker模式下执行时返回nil队列)
+func (mh *MsgHandle) pickQueue(pool *MsgHandle, request ziface.IRequest) (chan ziface.IRequest, *sync.WaitGroup, *stealScheduler) {
+	pool.workersLock.RLock()
+	defer pool.workersLock.RUnlock()
+
+	if pool.WorkerPoolSize == 0 {
+		// Execute the corresponding Handle method from the bound message and its corresponding processing method
+		// (从绑定好的消息和对应的处理方法中执行对应的Handle方法)
+		mh.runWithoutWorker(request)
+		return nil, nil, nil
+	}
+
+	if pool.stealer != nil {
+		return nil, nil, pool.stealer
+	}
+
 	workerID := request.GetConnection().GetWorkerID()
-	// zlog.Ins().DebugF("Add ConnID=%d request msgID=%d to workerID=%d", request.GetConnection().GetConnID(), request.GetMsgID(), workerID)
-	// Send the request message to the task queue
-	mh.TaskQueue[workerID] <- request
-	zlog.Ins().DebugF("SendMsgToTaskQueue-->%s", hex.EncodeToString(request.GetData()))
+	if mh.isUnordered(request.GetMsgID()) {
+		workerID = atomic.AddUint32(&pool.nextWorkerID, 1) % pool.WorkerPoolSize
+	} else if isHashWorkerMode(pool.config.WorkerMode) {
+		// Recomputed from the ConnID as the pool may have been resized since the connection started
+		// (连接建立后worker池可能被调整过大小，因此根据ConnID重新计算)
+		workerID = uint32(request.GetConnection().GetConnID() % uint64(pool.WorkerPoolSize))
+	}
+	// mh.log().DebugF("Add ConnID=%d request msgID=%d to workerID=%d", request.GetConnection().GetConnID(), request.GetMsgID(), workerID)
+	if zlog.DebugEnabled(mh.log()) {
+		mh.log().DebugFX(dispatchLogCtx(request), "SendMsgToTaskQueue-->%s", hex.EncodeToString(request.GetData()))
+	}
+
+	queue := pool.TaskQueue[workerID]
+	if lane := mh.priorityLane(workerID, request); lane != nil {
+		queue = lane
+	}
+	pool.sending.Add(1)
+	return queue, pool.sending, nil
 }
 
 // doFuncHandler handles functional requests (执行函数式请求)
 func (mh *MsgHandle) doFuncHandler(request ziface.IFuncRequest, workerID int) {
 	defer func() {
 		if err := recover(); err != nil {
-			zlog.Ins().ErrorF("workerID: %d doFuncRequest panic: %v", workerID, err)
+			mh.log().ErrorFX(dispatcherLogCtx, "workerID: %d doFuncRequest panic: %v", workerID, err)
 		}
 	}()
 	// Execute the functional request (执行函数式请求)
@@ -318,17 +470,24 @@ func (mh *MsgHandle) doFuncHandler(request ziface.IFuncRequest, workerID int) {
 // doMsgHandler immediately handles messages in a non-blocking manner
 // (立即以非阻塞方式处理消息)
 func (mh *MsgHandle) doMsgHandler(request ziface.IRequest, workerID int) {
+	// A request which timed out is finished by its handler goroutine, see callWithTimeout
+	// (超时的请求由其处理协程结束，见callWithTimeout)
+	conn, timedOut := request.GetConnection(), false
 	defer func() {
-		if err := recover(); err != nil {
-			zlog.Ins().ErrorF("workerID: %d doMsgHandler panic: %v", workerID, err)
+		if !timedOut {
+			endWork(conn)
 		}
 	}()
+	defer mh.recoverHandler(request, workerID)
 
-	msgId := request.GetMsgID()
-	handler, ok := mh.Apis[msgId]
-
+	handler, epoch, ok := mh.requestRouter(request, true)
 	if !ok {
-		zlog.Ins().ErrorF("api msgID = %d is not FOUND!", request.GetMsgID())
+		return
+	}
+
+	if !mh.validate(request) {
+		epoch.release()
+		PutRequest(request)
 		return
 	}
 
@@ -336,14 +495,47 @@ func (mh *MsgHandle) doMsgHandler(request ziface.IRequest, workerID int) {
 	// (Request请求绑定Router对应关系)
 	request.BindRouter(handler)
 
-	// Execute the corresponding processing method
-	request.Call()
+	// Execute the corresponding processing method, the epoch is released once the handler returns
+	// (执行对应的处理方法，处理函数返回后释放epoch)
+	if !mh.callWithTimeout(request, workerID, mh.watchHandler(request, workerID, ziface.IRequest.Call), epoch) {
+		timedOut = true
+		return
+	}
 
 	// 执行完成后回收 Request 对象回对象池
 	PutRequest(request)
 }
 
+// Metrics returns the metrics of the server or the client owning the handler, see zmetrics.Of
+// (返回所属服务或客户端的指标，见zmetrics.Of)
+func (mh *MsgHandle) Metrics() *zmetrics.Metrics {
+	if mh.metrics == nil {
+		return zmetrics.Zinx
+	}
+	return mh.metrics
+}
+
 func (mh *MsgHandle) Execute(request ziface.IRequest) {
+	mh.Metrics().MessagesReceived.Inc()
+	if mh.faults != nil {
+		// The latency of a simulated network delivers the request from the delay line of its connection, so that
+		// the reader and the other connections go on meanwhile
+		// (模拟网络的延迟由连接的延迟线稍后投递请求，期间读协程与其他连接继续工作)
+		conn := request.GetConnection()
+		if d := mh.faults.shape(conn, true, len(request.GetData())); d > 0 {
+			mh.faults.line(conn, true).schedule(d, time.Now(), func() { mh.execute(request) })
+			return
+		}
+	}
+	mh.execute(request)
+}
+
+// execute injects the faults into the request and passes it to the chain (向请求注入故障并将其交给责任链)
+func (mh *MsgHandle) execute(request ziface.IRequest) {
+	if mh.faults != nil && !mh.faults.inbound(request) {
+		return
+	}
+	traceDecode(request)
 	// Pass the message to the responsibility chain to handle it through interceptors layer by layer and pass it on layer by layer.
 	// (将消息丢到责任链，通过责任链里拦截器层层处理层层传递)
 	mh.builder.Execute(request)
@@ -352,6 +544,9 @@ func (mh *MsgHandle) Execute(request ziface.IRequest) {
 // AddRouter adds specific processing logic for messages
 // (为消息添加具体的处理逻辑)
 func (mh *MsgHandle) AddRouter(msgID uint32, router ziface.IRouter) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
 	// 1. Check whether the current API processing method bound to the msgID already exists
 	// (判断当前msg绑定的API处理方法是否已经存在)
 	if _, ok := mh.Apis[msgID]; ok {
@@ -361,7 +556,188 @@ func (mh *MsgHandle) AddRouter(msgID uint32, router ziface.IRouter) {
 	// 2. Add the binding relationship between msg and API
 	// (添加msg与api的绑定关系)
 	mh.Apis[msgID] = router
-	zlog.Ins().InfoF("Add Router msgID = %d", msgID)
+	mh.setRouterEpoch(msgID, router)
+	mh.log().InfoF("Add Router msgID = %d", msgID)
+}
+
+// SetRouterUnordered opts msgID out of per-connection ordering, its messages are handled by any worker
+// in parallel, even messages from the same connection. It suits stateless, idempotent routes.
+// (设置msgID不要求按连接顺序处理，其消息可由任意worker并行处理，即使来自同一个连接。适用于无状态、幂等的路由)
+func (mh *MsgHandle) SetRouterUnordered(msgID uint32, unordered bool) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	if !unordered {
+		delete(mh.unordered, msgID)
+		return
+	}
+	if mh.unordered == nil {
+		mh.unordered = make(map[uint32]struct{})
+	}
+	mh.unordered[msgID] = struct{}{}
+}
+
+func (mh *MsgHandle) isUnordered(msgID uint32) bool {
+	mh.apisLock.RLock()
+	defer mh.apisLock.RUnlock()
+	_, ok := mh.unordered[msgID]
+	return ok
+}
+
+// RemoveRouter removes the router of msgID, it is safe to call while the server is running,
+// requests already dispatched to the router will still be handled by it.
+// (删除msgID对应的路由，服务运行期间可以安全调用，已经分发给该路由的请求仍会由其处理完)
+func (mh *MsgHandle) RemoveRouter(msgID uint32) {
+	mh.apisLock.Lock()
+	delete(mh.Apis, msgID)
+	old := mh.setRouterEpoch(msgID, nil)
+	mh.apisLock.Unlock()
+
+	mh.log().InfoF("Remove Router msgID = %d", msgID)
+	if old != nil {
+		old.retire(nil)
+	}
+}
+
+// ReplaceRouter binds router to msgID whether or not a router already exists,
+// it is safe to call while the server is running.
+// (无论msgID是否已有路由都替换为router，服务运行期间可以安全调用)
+func (mh *MsgHandle) ReplaceRouter(msgID uint32, router ziface.IRouter) {
+	mh.ReplaceRouterDrain(msgID, router, nil)
+}
+
+// AddRouterRange binds a router to all MsgIDs in [start, end]
+// An exact MsgID router registered by AddRouter always takes precedence over a range.
+// (为MsgID区间[start, end]绑定一个路由，AddRouter注册的精确MsgID路由优先于区间路由)
+func (mh *MsgHandle) AddRouterRange(start, end uint32, router ziface.IRouter) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.rangeApis.add(msgIDRange{start: start, end: end, router: router})
+	mh.log().InfoF("Add Router msgID range = [%d, %d]", start, end)
+}
+
+// SetNotFoundRouter sets the router which handles messages that no router matches
+// (设置处理未匹配任何路由的消息的默认路由)
+func (mh *MsgHandle) SetNotFoundRouter(router ziface.IRouter) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.notFoundRouter = router
+}
+
+// AddTopicRouter binds router to a topic or a topic pattern, "+" matches one level,
+// a trailing "*" or "#" matches all remaining levels, e.g. "device/+/telemetry", "rpc/*".
+// Requests carrying a topic are routed by topic, the others by MsgID.
+// (为主题或主题模式绑定路由，"+"匹配单个层级，末尾的"*"或"#"匹配剩余所有层级。
+// 携带主题的请求按主题路由，其他请求仍按MsgID路由)
+func (mh *MsgHandle) AddTopicRouter(topic string, router ziface.IRouter) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.topicApis.add(&topicRoute{pattern: topic, router: router})
+	mh.log().InfoF("Add Router topic = %s", topic)
+}
+
+// AddTopicRouterSlices binds handlers to a topic or a topic pattern (为主题或主题模式绑定切片路由)
+func (mh *MsgHandle) AddTopicRouterSlices(topic string, handler ...ziface.RouterHandler) ziface.IRouterSlices {
+	mh.RouterSlices.AddTopicHandler(topic, handler...)
+	return mh.RouterSlices
+}
+
+// SetTopicExtractor sets how the topic of a request is extracted, DefaultTopicExtractor is used by default
+// (设置提取请求主题的方法，默认使用DefaultTopicExtractor)
+func (mh *MsgHandle) SetTopicExtractor(extractor ziface.TopicExtractor) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.topicExtractor = extractor
+}
+
+// getTopic extracts the topic of the request, topic routing is skipped when no topic router is registered
+// (提取请求的主题，没有注册任何主题路由时跳过)
+func (mh *MsgHandle) getTopic(request ziface.IRequest) (string, bool) {
+	mh.apisLock.RLock()
+	extractor := mh.topicExtractor
+	hasTopicApis := !mh.topicApis.empty()
+	mh.apisLock.RUnlock()
+
+	if !hasTopicApis && (mh.RouterSlices == nil || !mh.RouterSlices.hasTopicHandlers()) {
+		return "", false
+	}
+
+	if extractor == nil {
+		extractor = DefaultTopicExtractor
+	}
+	return extractor(request)
+}
+
+// getRequestRouter finds the router of the request, by topic if the request carries one, otherwise by MsgID
+// (查找请求对应的路由，请求携带主题时按主题查找，否则按MsgID查找)
+func (mh *MsgHandle) getRequestRouter(request ziface.IRequest) (ziface.IRouter, bool) {
+	router, _, ok := mh.requestRouter(request, false)
+	return router, ok
+}
+
+// requestRouter is getRequestRouter also returning the epoch of an exact MsgID router, the request is counted in
+// it if acquire is set and has to be released once done
+// (在getRequestRouter的基础上还返回精确MsgID路由的epoch，acquire为true时请求被计入其中，完成后需要释放)
+func (mh *MsgHandle) requestRouter(request ziface.IRequest, acquire bool) (ziface.IRouter, *routeEpoch, bool) {
+	if topic, ok := mh.getTopic(request); ok {
+		mh.apisLock.RLock()
+		r, found := mh.topicApis.find(topic)
+		notFound := mh.notFoundRouter
+		mh.apisLock.RUnlock()
+
+		if found {
+			return r.router, nil, true
+		}
+		if notFound != nil {
+			return notFound, nil, true
+		}
+		if ctx, allowed := notFoundLog.Allow(dispatchLogCtx(request)); allowed {
+			mh.log().ErrorFX(ctx, "api topic = %s is not FOUND!", topic)
+		}
+		return nil, nil, false
+	}
+
+	router, epoch, ok := mh.routerOf(request.GetMsgID(), acquire)
+	if !ok {
+		if ctx, allowed := notFoundLog.Allow(dispatchLogCtx(request)); allowed {
+			mh.log().ErrorFX(ctx, "api msgID = %d is not FOUND!", request.GetMsgID())
+		}
+	}
+	return router, epoch, ok
+}
+
+// getRouter finds the router of msgID, exact MsgID first, then MsgID ranges, then the not found router
+// (查找msgID对应的路由，依次为精确MsgID、MsgID区间、默认路由)
+func (mh *MsgHandle) getRouter(msgID uint32) (ziface.IRouter, bool) {
+	router, _, ok := mh.routerOf(msgID, false)
+	return router, ok
+}
+
+// routerOf is getRouter also returning the epoch of an exact MsgID router, see requestRouter
+// (在getRouter的基础上还返回精确MsgID路由的epoch，见requestRouter)
+func (mh *MsgHandle) routerOf(msgID uint32, acquire bool) (ziface.IRouter, *routeEpoch, bool) {
+	mh.apisLock.RLock()
+	defer mh.apisLock.RUnlock()
+
+	if router, ok := mh.Apis[msgID]; ok {
+		if !acquire {
+			return router, nil, true
+		}
+		epoch := mh.epochs[msgID]
+		epoch.acquire()
+		return router, epoch, true
+	}
+	if r, ok := mh.rangeApis.find(msgID); ok {
+		return r.router, nil, true
+	}
+	if mh.notFoundRouter != nil {
+		return mh.notFoundRouter, nil, true
+	}
+	return nil, nil, false
 }
 
 // AddRouterSlices adds router handlers using slices
@@ -371,37 +747,88 @@ func (mh *MsgHandle) AddRouterSlices(msgId uint32, handler ...ziface.RouterHandl
 	return mh.RouterSlices
 }
 
+// AddRouterSlicesRange adds router handlers for all MsgIDs in [start, end]
+// (为MsgID区间添加切片路由)
+func (mh *MsgHandle) AddRouterSlicesRange(start, end uint32, handler ...ziface.RouterHandler) ziface.IRouterSlices {
+	mh.RouterSlices.AddHandlerRange(start, end, handler...)
+	return mh.RouterSlices
+}
+
+// SetNotFoundRouterSlices sets the handlers of messages that no router matches
+// (设置未匹配任何路由的消息的切片处理器)
+func (mh *MsgHandle) SetNotFoundRouterSlices(handler ...ziface.RouterHandler) {
+	mh.RouterSlices.NotFound(handler...)
+}
+
+// RemoveRouterSlices removes the handlers of msgId at runtime (运行时删除切片路由)
+func (mh *MsgHandle) RemoveRouterSlices(msgId uint32) {
+	mh.RouterSlices.RemoveHandler(msgId)
+}
+
+// ReplaceRouterSlices replaces the handlers of msgId at runtime (运行时替换切片路由)
+func (mh *MsgHandle) ReplaceRouterSlices(msgId uint32, handler ...ziface.RouterHandler) ziface.IRouterSlices {
+	mh.RouterSlices.ReplaceHandler(msgId, handler...)
+	return mh.RouterSlices
+}
+
 // Group routes into a group (路由分组)
 func (mh *MsgHandle) Group(start, end uint32, Handlers ...ziface.RouterHandler) ziface.IGroupRouterSlices {
 	return NewGroup(start, end, mh.RouterSlices, Handlers...)
 }
+
+// RouterGroup creates a group covering all msgIDs (创建一个覆盖全部msgID的路由分组)
+func (mh *MsgHandle) RouterGroup(Handlers ...ziface.RouterHandler) ziface.IGroupRouterSlices {
+	return NewGroup(0, math.MaxUint32, mh.RouterSlices, Handlers...)
+}
+
 func (mh *MsgHandle) Use(Handlers ...ziface.RouterHandler) ziface.IRouterSlices {
 	mh.RouterSlices.Use(Handlers...)
 	return mh.RouterSlices
 }
 
 func (mh *MsgHandle) doMsgHandlerSlices(request ziface.IRequest, workerID int) {
+	// A request which timed out is finished by its handler goroutine, see callWithTimeout
+	// (超时的请求由其处理协程结束，见callWithTimeout)
+	conn, timedOut := request.GetConnection(), false
 	defer func() {
-		if err := recover(); err != nil {
-			zlog.Ins().ErrorF("workerID: %d doMsgHandler panic: %v", workerID, err)
+		if !timedOut {
+			endWork(conn)
 		}
 	}()
+	defer mh.recoverHandler(request, workerID)
+
+	var handlers []ziface.RouterHandler
+	var ok bool
+	if topic, hasTopic := mh.getTopic(request); hasTopic {
+		if handlers, ok = mh.RouterSlices.GetTopicHandlers(topic); !ok {
+			if ctx, allowed := notFoundLog.Allow(dispatchLogCtx(request)); allowed {
+				mh.log().ErrorFX(ctx, "api topic = %s is not FOUND!", topic)
+			}
+			return
+		}
+	} else if handlers, ok = mh.RouterSlices.GetHandlers(request.GetMsgID()); !ok {
+		if ctx, allowed := notFoundLog.Allow(dispatchLogCtx(request)); allowed {
+			mh.log().ErrorFX(ctx, "api msgID = %d is not FOUND!", request.GetMsgID())
+		}
+		return
+	}
 
-	msgId := request.GetMsgID()
-	handlers, ok := mh.RouterSlices.GetHandlers(msgId)
-	if !ok {
-		zlog.Ins().ErrorF("api msgID = %d is not FOUND!", request.GetMsgID())
+	if !mh.validate(request) {
+		PutRequest(request)
 		return
 	}
 
 	request.BindRouterSlices(handlers)
-	request.RouterSlicesNext()
+	if !mh.callWithTimeout(request, workerID, mh.watchHandler(request, workerID, ziface.IRequest.RouterSlicesNext), nil) {
+		timedOut = true
+		return
+	}
 	// 执行完成后回收 Request 对象回对象池
 	PutRequest(request)
 }
 
 func (mh *MsgHandle) StopOneWorker(workerID int) {
-	zlog.Ins().DebugF("stop Worker ID = %d ", workerID)
+	mh.log().DebugFX(dispatcherLogCtx, "stop Worker ID = %d ", workerID)
 	// Stop the worker by closing the corresponding taskQueue
 	// (停止一个Worker，通过关闭对应的taskQueue)
 	close(mh.TaskQueue[workerID])
@@ -410,51 +837,171 @@ func (mh *MsgHandle) StopOneWorker(workerID int) {
 // StartOneWorker starts a worker workflow
 // (启动一个Worker工作流程)
 func (mh *MsgHandle) StartOneWorker(workerID int, taskQueue chan ziface.IRequest) {
-	zlog.Ins().DebugF("Worker ID = %d is started.", workerID)
+	mh.startWorker("", workerID, taskQueue, nil)
+}
+
+// startWorker runs a worker of the pool named pool, "" for the shared pool, the requests of the
+// priority lane are taken first
+// (运行名为pool的池中的一个worker，共享池为""，优先取出优先通道中的请求)
+func (mh *MsgHandle) startWorker(pool string, workerID int, taskQueue, priority chan ziface.IRequest) {
+	mh.log().DebugFX(dispatcherLogCtx, "Worker ID = %d is started.", workerID)
+	stats := mh.addWorkerStats(pool, workerID, func() int { return len(taskQueue) })
+	defer mh.removeWorkerStats(stats)
+	quit := mh.workerQuit()
+
 	// Continuously wait for messages in the queue
 	// (不断地等待队列中的消息)
 	for {
+		// Prioritized requests go first (优先处理高优先级的请求)
+		select {
+		case request, ok := <-priority:
+			if ok {
+				stats.handle(mh, request)
+				continue
+			}
+			priority = nil
+		default:
+		}
+
 		select {
+		case request, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			stats.handle(mh, request)
 		// If there is a message, take out the Request from the queue and execute the bound business method
 		// (有消息则取出队列的Request，并执行绑定的业务方法)
+		case <-quit:
+			mh.log().DebugFX(dispatcherLogCtx, "Worker ID = %d quit", workerID)
+			return
 		case request, ok := <-taskQueue:
 			if !ok {
 				// DynamicBind Mode, destroy current worker by close the taskQueue
 				// (DynamicBind模式下，临时创建的worker, 是通过关闭taskQueue 来销毁当前worker)
-				zlog.Ins().ErrorF(" taskQueue is closed, Worker ID = %d quit", workerID)
+				mh.log().ErrorFX(dispatcherLogCtx, " taskQueue is closed, Worker ID = %d quit", workerID)
+				mh.drainPriority(stats, priority)
 				return
 			}
-			switch req := request.(type) {
+			stats.handle(mh, request)
+		}
+	}
+}
 
-			case ziface.IFuncRequest:
-				// Internal function call request (内部函数调用request)
+// handleTask runs a request taken from a task queue on the worker workerID
+// (在workerID对应的worker上执行从任务队列取出的请求)
+func (mh *MsgHandle) handleTask(request ziface.IRequest, workerID int) {
+	switch req := request.(type) {
 
-				mh.doFuncHandler(req, workerID)
+	case ziface.IFuncRequest:
+		// Internal function call request (内部函数调用request)
 
-			case ziface.IRequest: // Client message request
+		mh.doFuncHandler(req, workerID)
 
-				if !zconf.GlobalObject.RouterSlicesMode {
-					mh.doMsgHandler(req, workerID)
-				} else if zconf.GlobalObject.RouterSlicesMode {
-					mh.doMsgHandlerSlices(req, workerID)
-				}
-			}
+	case ziface.IRequest: // Client message request
+
+		if !mh.config.RouterSlicesMode {
+			mh.doMsgHandler(req, workerID)
+		} else if mh.config.RouterSlicesMode {
+			mh.doMsgHandlerSlices(req, workerID)
 		}
 	}
 }
 
 // StartWorkerPool starts the worker pool
 func (mh *MsgHandle) StartWorkerPool() {
+	mh.StartWorkerPoolContext(context.Background())
+}
+
+// StartWorkerPoolContext starts the worker pool, the workers of the handler and of its dedicated pools quit once ctx
+// is done, the requests still queued are not handled. Starting it again restarts the dedicated pools.
+// (启动worker池，ctx结束后处理器及其专属池的worker退出，仍在队列中的请求不会被处理。再次启动时重新启动专属池)
+func (mh *MsgHandle) StartWorkerPoolContext(ctx context.Context) {
+	if mh.watchQuit(ctx) {
+		mh.restartPools()
+	}
+	mh.startWorkers()
+}
+
+// watchQuit lets the workers quit once ctx is done, it returns true if they quit before and need a new signal
+// (ctx结束时让worker退出，worker之前已退出并需要新的信号时返回true)
+func (mh *MsgHandle) watchQuit(ctx context.Context) bool {
+	mh.workersLock.Lock()
+	defer mh.workersLock.Unlock()
+	stopped := mh.quit.closed()
+	if stopped {
+		mh.quit = newQuitSignal()
+	}
+	if done := ctx.Done(); done != nil {
+		go func(quit *quitSignal) {
+			select {
+			case <-done:
+				quit.close()
+			case <-quit.ch:
+			}
+		}(mh.quit)
+	}
+	return stopped
+}
+
+// workerQuit returns the channel closed when the workers quit (返回worker退出时关闭的通道)
+func (mh *MsgHandle) workerQuit() <-chan struct{} {
+	mh.workersLock.RLock()
+	defer mh.workersLock.RUnlock()
+	return mh.quit.ch
+}
+
+// quitSignal is closed once to let the workers quit (关闭一次以让worker退出)
+type quitSignal struct {
+	ch   chan struct{}
+	once sync.Once
+}
+
+func newQuitSignal() *quitSignal {
+	return &quitSignal{ch: make(chan struct{})}
+}
+
+func (q *quitSignal) close() {
+	q.once.Do(func() { close(q.ch) })
+}
+
+func (q *quitSignal) closed() bool {
+	select {
+	case <-q.ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func (mh *MsgHandle) startWorkers() {
+	mh.workersLock.Lock()
+	defer mh.workersLock.Unlock()
+	mh.workersStarted = true
+	// The workers of the shared pool are started by its owner (共享池的worker由其所有者启动)
+	if mh.workers != nil {
+		return
+	}
+
+	if mh.config.WorkerMode == zconf.WorkerModeSteal {
+		mh.stealer = newStealScheduler(mh, int(mh.WorkerPoolSize))
+		return
+	}
+	if mh.config.WorkerMode == zconf.WorkerModeDynamicBind {
+		mh.log().DebugF("WorkerMode = %s", zconf.WorkerModeDynamicBind)
+	}
+
 	// Iterate through the required number of workers and start them one by one
 	// (遍历需要启动worker的数量，依此启动)
 	for i := 0; i < int(mh.WorkerPoolSize); i++ {
 		// A worker is started
 		// Allocate space for the corresponding task queue for the current worker
 		// (给当前worker对应的任务队列开辟空间)
-		mh.TaskQueue[i] = make(chan ziface.IRequest, zconf.GlobalObject.MaxWorkerTaskLen)
+		mh.TaskQueue[i] = make(chan ziface.IRequest, mh.config.Live().MaxWorkerTaskLen)
+		mh.priorityQueue[i] = make(chan ziface.IRequest, mh.config.Live().MaxWorkerTaskLen)
 
 		// Start the current worker, blocking and waiting for messages to be passed in the corresponding task queue
 		// (启动当前Worker，阻塞的等待对应的任务队列是否有消息传递进来)
-		go mh.StartOneWorker(i, mh.TaskQueue[i])
+		go mh.startWorker("", i, mh.TaskQueue[i], mh.priorityQueue[i])
 	}
 }
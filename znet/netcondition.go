@@ -0,0 +1,122 @@
+package znet
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+const netConditionKey = "zinx.net_condition"
+
+// netShaper delays the messages of a connection to simulate its network condition
+// (延迟连接的消息以模拟其网络状况)
+type netShaper struct {
+	condition ziface.NetCondition
+
+	mu sync.Mutex
+	// The bandwidth of each direction, nil if unlimited (每个方向的带宽，不限制时为nil)
+	inbound, outbound *tokenBucket
+}
+
+func newNetShaper(condition ziface.NetCondition, now time.Time) *netShaper {
+	ns := &netShaper{condition: condition}
+	if condition.Bandwidth > 0 {
+		// A burst of 100ms of the bandwidth, like the buffers of a slow link (100ms带宽的突发量，类似慢速链路的缓冲区)
+		rate := float64(condition.Bandwidth)
+		burst := rate / 10
+		if burst < 1 {
+			burst = 1
+		}
+		ns.inbound, ns.outbound = newTokenBucket(rate, burst, now), newTokenBucket(rate, burst, now)
+	}
+	return ns
+}
+
+// MarshalJSON shows the condition in the properties listed by the admin API (在管理接口列出的属性中显示网络状况)
+func (ns *netShaper) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ns.condition)
+}
+
+// latency returns how long the simulated link takes to carry a message (返回模拟链路传输一条消息的耗时)
+func (ns *netShaper) latency(fi *faultInjector) time.Duration {
+	d := ns.condition.Latency
+	if ns.condition.Jitter > 0 {
+		d += time.Duration(fi.intn(int64(ns.condition.Jitter) + 1))
+	}
+	return d
+}
+
+// throttle returns how long a message of size bytes waits for the bandwidth of a direction
+// (返回一条size字节的消息在一个方向上等待带宽的时间)
+func (ns *netShaper) throttle(inbound bool, size int, now time.Time) time.Duration {
+	bucket := ns.outbound
+	if inbound {
+		bucket = ns.inbound
+	}
+	if bucket == nil {
+		return 0
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	return bucket.reserve(float64(size), now)
+}
+
+// shape throttles a message of conn to the bandwidth of its simulated network by holding the caller, as a slow
+// link pushes back on its sender, and returns the latency the message is then delayed by on a delay line of conn
+// (将conn的一条消息限制在模拟网络的带宽内，像慢速链路反压发送方一样阻塞调用方，
+// 并返回该消息随后在conn的延迟线上被延迟的时长)
+func (fi *faultInjector) shape(conn ziface.IConnection, inbound bool, size int) time.Duration {
+	// Most servers never simulate a network, they skip the property lookup (大多数服务从不模拟网络，跳过属性查找)
+	if atomic.LoadInt32(&fi.shaping) == 0 || conn == nil {
+		return 0
+	}
+	value, err := conn.GetProperty(netConditionKey)
+	if err != nil {
+		return 0
+	}
+	ns := value.(*netShaper)
+	if d := ns.throttle(inbound, size, time.Now()); d > 0 {
+		time.Sleep(d)
+	}
+	return ns.latency(fi)
+}
+
+// SetNetCondition simulates the network condition of the connection connID until it closes, see ziface.NetCondition
+// (模拟连接connID的网络状况直到连接关闭，见ziface.NetCondition)
+func (s *Server) SetNetCondition(connID uint64, condition ziface.NetCondition) error {
+	if err := condition.Validate(); err != nil {
+		return err
+	}
+	conn, err := s.ConnMgr.Get(connID)
+	if err != nil {
+		return fmt.Errorf("connection %d not found", connID)
+	}
+	if !condition.Enabled() {
+		conn.RemoveProperty(netConditionKey)
+		s.log().InfoF("[FAULTS] connID = %d stops simulating a network condition", connID)
+		return nil
+	}
+	atomic.StoreInt32(&s.faults.shaping, 1)
+	conn.SetProperty(netConditionKey, newNetShaper(condition, time.Now()))
+	s.log().InfoF("[FAULTS] connID = %d simulates %+v", connID, condition)
+	return nil
+}
+
+// GetNetConditions returns the network conditions simulated by connID (按connID返回模拟的网络状况)
+func (s *Server) GetNetConditions() map[uint64]ziface.NetCondition {
+	conditions := make(map[uint64]ziface.NetCondition)
+	if atomic.LoadInt32(&s.faults.shaping) == 0 {
+		return conditions
+	}
+	_ = s.ConnMgr.Range(func(connID uint64, conn ziface.IConnection, _ interface{}) error {
+		if value, err := conn.GetProperty(netConditionKey); err == nil {
+			conditions[connID] = value.(*netShaper).condition
+		}
+		return nil
+	}, nil)
+	return conditions
+}
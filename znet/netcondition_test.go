@@ -0,0 +1,86 @@
+package znet
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetShaper(t *testing.T) {
+	fi := newFaultInjector(ziface.Faults{})
+	now := time.Now()
+	ns := newNetShaper(ziface.NetCondition{Latency: 100 * time.Millisecond, Jitter: 20 * time.Millisecond}, now)
+	for i := 0; i < 20; i++ {
+		d := ns.latency(fi)
+		assert.True(t, d >= 100*time.Millisecond && d <= 120*time.Millisecond, d)
+	}
+	assert.Equal(t, time.Duration(0), ns.throttle(true, 1000, now))
+
+	// 1000 bytes per second with a burst of 100 bytes (每秒1000字节，突发量100字节)
+	ns = newNetShaper(ziface.NetCondition{Bandwidth: 1000}, now)
+	assert.Equal(t, time.Duration(0), ns.throttle(false, 100, now))
+	assert.Equal(t, 500*time.Millisecond, ns.throttle(false, 500, now))
+	assert.Equal(t, time.Second, ns.throttle(false, 500, now))
+	// The directions are throttled apart (两个方向分别限速)
+	assert.Equal(t, time.Duration(0), ns.throttle(true, 100, now))
+
+	data, err := json.Marshal(ns)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"latency":0,"jitter":0,"bandwidth":1000}`, string(data))
+}
+
+func TestSetNetCondition(t *testing.T) {
+	s := newServerWithConfig(zconf.DefaultConfig(), "tcp").(*Server)
+	conn := &Connection{connID: 3, connIdStr: "3", msgHandler: s.msgHandler}
+	s.ConnMgr.Add(conn)
+	defer s.ConnMgr.Remove(conn)
+	assert.Empty(t, s.GetNetConditions())
+
+	// Nothing is shaped before a condition is set (设置网络状况之前不做任何整形)
+	start := time.Now()
+	assert.Equal(t, time.Duration(0), s.faults.shape(conn, true, 1<<20))
+	assert.True(t, time.Since(start) < 10*time.Millisecond)
+
+	assert.NotNil(t, s.SetNetCondition(4, ziface.NetCondition{Latency: time.Second}))
+	assert.NotNil(t, s.SetNetCondition(3, ziface.NetCondition{Jitter: -time.Second}))
+
+	condition := ziface.NetCondition{Latency: 30 * time.Millisecond}
+	assert.Nil(t, s.SetNetCondition(3, condition))
+	assert.Equal(t, map[uint64]ziface.NetCondition{3: condition}, s.GetNetConditions())
+	// The latency is returned for the delay line, only the bandwidth holds the caller (延迟交给延迟线，只有带宽会阻塞调用方)
+	start = time.Now()
+	assert.Equal(t, 30*time.Millisecond, s.faults.shape(conn, false, 10))
+	assert.True(t, time.Since(start) < 10*time.Millisecond)
+
+	assert.Nil(t, s.SetNetCondition(3, ziface.NetCondition{}))
+	assert.Empty(t, s.GetNetConditions())
+}
+
+func TestDelayLine(t *testing.T) {
+	var line delayLine
+	delivered := make(chan int, 10)
+	now := time.Now()
+	// The messages are delayed together, not one after another, and keep their order
+	// (消息一起被延迟而不是逐条累加，并保持顺序)
+	for i := 0; i < 10; i++ {
+		d := 50 * time.Millisecond
+		if i == 5 {
+			d = 10 * time.Millisecond
+		}
+		i := i
+		line.schedule(d, now, func() { delivered <- i })
+	}
+	for i := 0; i < 10; i++ {
+		select {
+		case got := <-delivered:
+			assert.Equal(t, i, got)
+		case <-time.After(time.Second):
+			t.Fatal("message is not delivered")
+		}
+	}
+	assert.True(t, time.Since(now) < 300*time.Millisecond)
+}
@@ -1,9 +1,13 @@
 package znet
 
 import (
+	"crypto/tls"
+	"net"
 	"net/url"
+	"time"
 
 	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zmetrics"
 )
 
 // Options for Server
@@ -19,6 +23,107 @@ func WithPacket(pack ziface.IDataPack) Option {
 	}
 }
 
+// WithStatsHook calls hook with a snapshot of the metrics every StatsInterval seconds while the server runs,
+// for the telemetry systems that can't scrape the Prometheus endpoint
+// (服务运行期间每StatsInterval秒以指标快照调用hook，用于无法采集Prometheus端点的监控系统)
+func WithStatsHook(hook zmetrics.StatsHook) Option {
+	return func(s *Server) {
+		s.statsHooks = append(s.statsHooks, hook)
+	}
+}
+
+// WithMetrics counts the metrics of the server into m instead of zmetrics.Zinx, e.g. to serve the metrics of each
+// server of a process on its own MetricsAddr (将服务的指标计入m而不是zmetrics.Zinx，例如让进程中的每个服务在各自的MetricsAddr上提供指标)
+func WithMetrics(m *zmetrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// WithPubSubControl lets the clients subscribe to topics with subscribeMsgID messages and unsubscribe with
+// unsubscribeMsgID messages, the data of the messages is the topic, see GetPubSub
+// (允许客户端通过subscribeMsgID消息订阅主题，通过unsubscribeMsgID消息取消订阅，消息数据为主题，见GetPubSub)
+func WithPubSubControl(subscribeMsgID, unsubscribeMsgID uint32) Option {
+	return func(s *Server) {
+		handler := s.pubSubControl(subscribeMsgID)
+		AddRouterHandler(s, subscribeMsgID, handler)
+		AddRouterHandler(s, unsubscribeMsgID, handler)
+	}
+}
+
+// WithAuth starts the connections unauthenticated, only their messages with authMsgIDs are routed, to authenticator,
+// until it returns a principal, see GetPrincipal. Connections not authenticated within AuthTimeout are closed.
+// (连接以未认证状态开始，在authenticator返回身份之前只有authMsgIDs消息会被路由给authenticator，见GetPrincipal。
+// 在AuthTimeout内未认证的连接会被关闭)
+func WithAuth(authenticator ziface.IAuthenticator, authMsgIDs ...uint32) Option {
+	return func(s *Server) {
+		s.auth = newAuthInterceptor(authenticator, authMsgIDs)
+	}
+}
+
+// WithNonceStore keeps the nonces of the replay protection in store, e.g. shared by the nodes behind a load
+// balancer, they are kept in memory by default, see ReplayWindow
+// (将防重放的nonce保存在store中，例如由负载均衡后的节点共享，默认保存在内存中，见ReplayWindow)
+func WithNonceStore(store ziface.INonceStore) Option {
+	return func(s *Server) {
+		s.nonceStore = store
+	}
+}
+
+// WithTLSConfig serves TLS with config instead of CertFile and PrivateKeyFile, e.g. the config of zacme, the
+// ClientCAFile is still applied (使用config提供TLS而不是CertFile与PrivateKeyFile，例如zacme的配置，ClientCAFile仍然生效)
+func WithTLSConfig(config *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = config
+	}
+}
+
+// WithFingerprint classifies the accepted TCP connections with classify before their read loop starts, from up to
+// peek first bytes waited for at most timeout (5s if 0), their TLS ClientHello and their socket, see ConnClass.
+// A rejected connection is closed at once.
+// (在读循环开始之前用classify对接受的TCP连接分类，依据最多等待timeout(为0时5秒)读到的最多peek个首字节、TLS ClientHello
+// 及其套接字，见ConnClass。被拒绝的连接会被立即关闭)
+func WithFingerprint(classify ziface.Fingerprinter, peek int, timeout time.Duration) Option {
+	if classify == nil {
+		panic("zinx: WithFingerprint needs a Fingerprinter")
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return func(s *Server) {
+		s.fingerprint = &fingerprinter{classify: classify, peek: peek, timeout: timeout}
+	}
+}
+
+// WithMessageSigning rejects the inbound messages whose signature does not verify with the signer lookup returns for
+// their connection and signs the outbound ones with it, the unsignedMsgIDs are accepted and sent unsigned, e.g. the
+// auth messages, it needs the signature HeaderField
+// (拒绝签名无法通过lookup为其连接返回的签名器校验的入站消息，并用其对出站消息签名，unsignedMsgIDs无需签名，例如认证消息，
+// 需要signature头部字段)
+func WithMessageSigning(lookup ziface.SignerLookup, unsignedMsgIDs ...uint32) Option {
+	return func(s *Server) {
+		s.signing = newMessageSigning(lookup, unsignedMsgIDs)
+	}
+}
+
+// WithListener accepts the TCP connections from listener instead of listening to IP and Port, e.g. an inherited socket
+// or the in-memory listener of ztest, it is closed when the server stops, Shards and TLS do not apply to it
+// (从listener接受TCP连接而不是监听IP与Port，例如继承的套接字或ztest的内存监听器，服务停止时将其关闭，Shards与TLS对其不生效)
+func WithListener(listener net.Listener) Option {
+	return func(s *Server) {
+		s.listener = listener
+	}
+}
+
+// WithConnIDs allocates the ConnIDs with next instead of a counter of the server, e.g. one counter shared by the
+// servers of a ServerGroup keeps the ConnIDs unique in the process, Shards allocate their own
+// (使用next代替服务自身的计数器分配ConnID，例如ServerGroup中的服务共享一个计数器使ConnID在进程内唯一，Shards自行分配)
+func WithConnIDs(next func() uint64) Option {
+	return func(s *Server) {
+		s.connIDs = next
+	}
+}
+
 // Options for Client
 type ClientOption func(c ziface.IClient)
 
@@ -42,3 +147,26 @@ func WithUrl(url *url.URL) ClientOption {
 		c.SetUrl(url)
 	}
 }
+
+// WithMetricsClient counts the metrics of the client into m instead of zmetrics.Zinx
+// (将客户端的指标计入m而不是zmetrics.Zinx)
+func WithMetricsClient(m *zmetrics.Metrics) ClientOption {
+	return func(c ziface.IClient) {
+		if client, ok := c.(*Client); ok {
+			client.metrics = m
+		}
+	}
+}
+
+// WithSigningClient signs the messages of the client with signer and rejects those of the server it does not verify,
+// the unsignedMsgIDs are sent and accepted unsigned, it needs the signature HeaderField
+// (使用signer对客户端的消息签名并拒绝其无法校验的服务端消息，unsignedMsgIDs无需签名，需要signature头部字段)
+func WithSigningClient(signer ziface.IMessageSigner, unsignedMsgIDs ...uint32) ClientOption {
+	return func(c ziface.IClient) {
+		if client, ok := c.(*Client); ok {
+			client.signing = newMessageSigning(func(ziface.IConnection) (ziface.IMessageSigner, error) {
+				return signer, nil
+			}, unsignedMsgIDs)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package znet
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+var (
+	pluginFactories     = map[string]func() ziface.IPlugin{}
+	pluginFactoriesLock sync.RWMutex
+)
+
+// RegisterPlugin makes a plugin available to the Plugins of the config under name, factory creates the instance of
+// each server enabling it, usually called from the init of the package of the plugin
+// (以name注册插件，使其可以在配置的Plugins中开启，factory为每个开启它的服务创建实例，通常在插件包的init中调用)
+func RegisterPlugin(name string, factory func() ziface.IPlugin) {
+	pluginFactoriesLock.Lock()
+	defer pluginFactoriesLock.Unlock()
+	pluginFactories[name] = factory
+}
+
+func pluginFactory(name string) (func() ziface.IPlugin, bool) {
+	pluginFactoriesLock.RLock()
+	defer pluginFactoriesLock.RUnlock()
+	factory, ok := pluginFactories[name]
+	return factory, ok
+}
+
+// WithPlugin enables plugins which are not registered, they run after the Plugins of the config
+// (开启未注册的插件，在配置的Plugins之后运行)
+func WithPlugin(plugins ...ziface.IPlugin) Option {
+	return func(s *Server) {
+		s.plugins = append(s.plugins, plugins...)
+	}
+}
+
+// validatePlugins checks the Plugins of the config are registered and the plugins have distinct names
+// (检查配置的Plugins均已注册，且插件名称互不相同)
+func (s *Server) validatePlugins() []string {
+	var problems []string
+	names := make(map[string]bool)
+	for _, name := range s.config.Plugins {
+		if _, ok := pluginFactory(name); !ok {
+			problems = append(problems, fmt.Sprintf("unknown plugin %q, see RegisterPlugin", name))
+		}
+		if names[name] {
+			problems = append(problems, fmt.Sprintf("plugin %q is enabled twice", name))
+		}
+		names[name] = true
+	}
+	if !s.pluginsReady {
+		for _, p := range s.plugins {
+			if names[p.Name()] {
+				problems = append(problems, fmt.Sprintf("plugin %q is enabled twice", p.Name()))
+			}
+			names[p.Name()] = true
+		}
+	}
+	return problems
+}
+
+// initPlugins creates the plugins of the config and initializes all of them once, then registers their routers
+// and interceptors (创建配置中的插件并对所有插件初始化一次，然后注册它们的路由与拦截器)
+func (s *Server) initPlugins() error {
+	if s.pluginsReady {
+		return nil
+	}
+	s.pluginsReady = true
+
+	plugins := make([]ziface.IPlugin, 0, len(s.config.Plugins)+len(s.plugins))
+	for _, name := range s.config.Plugins {
+		factory, _ := pluginFactory(name)
+		plugins = append(plugins, factory())
+	}
+	s.plugins = append(plugins, s.plugins...)
+
+	for _, p := range s.plugins {
+		if err := p.Init(s); err != nil {
+			return fmt.Errorf("init plugin %s: %w", p.Name(), err)
+		}
+		if rp, ok := p.(ziface.IRouterPlugin); ok {
+			for msgID, router := range rp.Routers() {
+				s.AddRouter(msgID, router)
+			}
+		}
+		if ip, ok := p.(ziface.IInterceptorPlugin); ok {
+			for _, interceptor := range ip.Interceptors() {
+				s.AddInterceptor(interceptor)
+			}
+		}
+		s.log().InfoF("[PLUGIN] server %s initialized plugin %s", s.Name, p.Name())
+	}
+	return nil
+}
+
+func (s *Server) startPlugins() {
+	for _, p := range s.plugins {
+		p.OnStart(s)
+	}
+}
+
+// stopPlugins stops the plugins in reverse order, so a plugin can rely on the ones before it
+// (按逆序停止插件，插件可以依赖在其之前的插件)
+func (s *Server) stopPlugins() {
+	for i := len(s.plugins) - 1; i >= 0; i-- {
+		s.plugins[i].OnStop(s)
+	}
+}
+
+// Plugins returns the plugins of the server, those of the config are created on the first Start
+// (返回服务的插件，配置中的插件在首次Start时创建)
+func (s *Server) Plugins() []ziface.IPlugin {
+	return s.plugins
+}
@@ -0,0 +1,78 @@
+package znet
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordPlugin struct {
+	name    string
+	events  *[]string
+	initErr error
+	msgID   uint32
+}
+
+func (p *recordPlugin) Name() string { return p.name }
+
+func (p *recordPlugin) Init(server ziface.IServer) error {
+	*p.events = append(*p.events, p.name+".Init")
+	return p.initErr
+}
+
+func (p *recordPlugin) OnStart(server ziface.IServer) {
+	*p.events = append(*p.events, p.name+".OnStart")
+}
+
+func (p *recordPlugin) OnStop(server ziface.IServer) { *p.events = append(*p.events, p.name+".OnStop") }
+
+func (p *recordPlugin) Routers() map[uint32]ziface.IRouter {
+	if p.msgID == 0 {
+		return nil
+	}
+	return map[uint32]ziface.IRouter{p.msgID: &echoRouter{}}
+}
+
+func TestServerPlugins(t *testing.T) {
+	var events []string
+	RegisterPlugin("test.record", func() ziface.IPlugin {
+		return &recordPlugin{name: "config", events: &events, msgID: 7}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	config := zconf.DefaultConfig()
+	config.Mode = zconf.ServerModeTcp
+	config.StartupReport = zconf.StartupReportOff
+	config.Plugins = []string{"test.record"}
+	s := newServerWithConfig(config, "tcp", WithListener(listener),
+		WithPlugin(&recordPlugin{name: "option", events: &events})).(*Server)
+
+	s.Start()
+	assert.Len(t, s.Plugins(), 2)
+	_, ok := s.GetMsgHandler().(*MsgHandle).getRouter(7)
+	assert.True(t, ok)
+	s.Stop()
+
+	// The plugins of the config come first, and stop last (配置中的插件先初始化，最后停止)
+	assert.Equal(t, []string{"config.Init", "option.Init", "config.OnStart", "option.OnStart",
+		"option.OnStop", "config.OnStop"}, events)
+}
+
+func TestServerPluginsInvalid(t *testing.T) {
+	var events []string
+	config := zconf.DefaultConfig()
+	config.Plugins = []string{"test.missing"}
+	s := newServerWithConfig(config, "tcp").(*Server)
+	assert.ErrorContains(t, s.Validate(), `unknown plugin "test.missing"`)
+
+	config.Plugins = nil
+	s = newServerWithConfig(config, "tcp", WithPlugin(&recordPlugin{name: "broken", events: &events,
+		initErr: errors.New("no license")})).(*Server)
+	assert.Nil(t, s.Validate())
+	assert.PanicsWithError(t, "init plugin broken: no license", s.Start)
+}
@@ -0,0 +1,35 @@
+package znet
+
+import (
+	"github.com/aceld/zinx/ziface"
+)
+
+// priorityLane returns the priority lane of workerID if the route of the request has a priority above 0,
+// nil if the request goes to the TaskQueue. The caller holds workersLock.
+// (请求的路由优先级大于0时返回workerID的优先通道，请求进入TaskQueue时返回nil。调用方持有workersLock)
+func (mh *MsgHandle) priorityLane(workerID uint32, request ziface.IRequest) chan ziface.IRequest {
+	pool := mh.pool()
+	if int(workerID) >= len(pool.priorityQueue) || pool.priorityQueue[workerID] == nil {
+		return nil
+	}
+	if mh.getRouterPriority(request.GetMsgID()) <= 0 {
+		return nil
+	}
+	return pool.priorityQueue[workerID]
+}
+
+// drainPriority handles the requests left in the priority lane of a quitting worker
+// (处理退出的worker优先通道中剩余的请求)
+func (mh *MsgHandle) drainPriority(stats *workerStats, priority chan ziface.IRequest) {
+	for {
+		select {
+		case request, ok := <-priority:
+			if !ok {
+				return
+			}
+			stats.handle(mh, request)
+		default:
+			return
+		}
+	}
+}
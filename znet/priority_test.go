@@ -0,0 +1,51 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMsgHandlePriorityLane(t *testing.T) {
+	mh := newMsgHandle()
+	mh.WorkerPoolSize = 1
+	mh.TaskQueue = []chan ziface.IRequest{make(chan ziface.IRequest, 8)}
+	mh.priorityQueue = []chan ziface.IRequest{make(chan ziface.IRequest, 8)}
+	order := &orderRouter{received: make(chan byte, 8)}
+	mh.AddRouter(1, order)
+	mh.AddRouter(2, order)
+	mh.SetRouterPriority(2, 10)
+
+	conn := &workerConn{}
+	for i, id := range []uint32{1, 1, 2, 1, 2} {
+		mh.SendMsgToTaskQueue(NewRequest(conn, zpack.NewMsgPackage(id, []byte{byte(i)})))
+	}
+	assert.Len(t, mh.TaskQueue[0], 3)
+	assert.Len(t, mh.priorityQueue[0], 2)
+
+	// The worker quits once the closed queue is drained (队列关闭后worker处理完剩余请求退出)
+	close(mh.TaskQueue[0])
+	mh.startWorker("", 0, mh.TaskQueue[0], mh.priorityQueue[0])
+
+	var received []byte
+	for len(order.received) > 0 {
+		received = append(received, <-order.received)
+	}
+	assert.Equal(t, []byte{2, 4, 0, 1, 3}, received)
+}
+
+func TestConnQueuePriority(t *testing.T) {
+	cq := &connQueue{}
+	for i, prioritized := range []bool{false, true, false, true} {
+		cq.add(NewRequest(nil, zpack.NewMsgPackage(uint32(i), nil)), prioritized)
+	}
+
+	var ids []uint32
+	for _, request := range cq.requests {
+		ids = append(ids, request.GetMsgID())
+	}
+	assert.Equal(t, []uint32{1, 3, 0, 2}, ids)
+	assert.Equal(t, 2, cq.urgent)
+}
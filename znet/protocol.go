@@ -0,0 +1,59 @@
+package znet
+
+import (
+	"bytes"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// protocolKey is the connection property holding the *ziface.Protocol selected for the connection
+// (保存连接所选*ziface.Protocol的连接属性)
+const protocolKey = "zinx.protocol"
+
+// matchProtocol picks the first protocol matching the first bytes of a connection,
+// the Hello of the matched protocol is stripped from data.
+// (选择第一个与连接最先读到的字节匹配的协议，匹配协议的Hello会从data中去掉)
+func matchProtocol(protocols []ziface.Protocol, data []byte) (*ziface.Protocol, []byte) {
+	for i := range protocols {
+		p := &protocols[i]
+		if len(p.Hello) > 0 {
+			if bytes.HasPrefix(data, p.Hello) {
+				return p, data[len(p.Hello):]
+			}
+			continue
+		}
+		if p.Match(data) {
+			return p, data
+		}
+	}
+	return nil, data
+}
+
+// ConnProtocol returns the protocol selected for conn, nil if it uses the datapack of the server
+// (返回为conn选择的协议，使用服务器数据包时返回nil)
+func ConnProtocol(conn ziface.IConnection) *ziface.Protocol {
+	if value, err := conn.GetProperty(protocolKey); err == nil {
+		p, _ := value.(*ziface.Protocol)
+		return p
+	}
+	return nil
+}
+
+// protocolDecoder is the head interceptor when protocols are registered, it runs the decoder
+// of the protocol of each connection.
+// (注册了协议时的头拦截器，为每个连接执行其协议的解码器)
+type protocolDecoder struct {
+	fallback ziface.IDecoder
+}
+
+func (pd *protocolDecoder) Intercept(chain ziface.IChain) ziface.IcResp {
+	if request, ok := chain.Request().(ziface.IRequest); ok && request.GetConnection() != nil {
+		if p := ConnProtocol(request.GetConnection()); p != nil && p.Decoder != nil {
+			return p.Decoder.Intercept(chain)
+		}
+	}
+	if pd.fallback != nil {
+		return pd.fallback.Intercept(chain)
+	}
+	return chain.Proceed(chain.Request())
+}
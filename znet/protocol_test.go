@@ -0,0 +1,44 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectProtocol(t *testing.T) {
+	v1 := ziface.Protocol{
+		Name:    "v1",
+		Match:   func(head []byte) bool { return len(head) > 0 && head[0] == 0x01 },
+		Packet:  zpack.NewDataPackLtv(),
+		Decoder: zdecoder.NewLTV_Little_Decoder(),
+	}
+	v2 := ziface.Protocol{
+		Name:    "v2",
+		Hello:   []byte("ZINXv2"),
+		Packet:  zpack.NewDataPackTrace(),
+		Decoder: zdecoder.NewTraceTLVDecoder(),
+	}
+	protocols := []ziface.Protocol{v1, v2}
+
+	c := &Connection{protocols: protocols, packet: zpack.NewDataPack()}
+	data := c.selectProtocol([]byte("ZINXv2\x00\x00"))
+	assert.Equal(t, []byte{0, 0}, data)
+	assert.Nil(t, c.protocols)
+	assert.Equal(t, "v2", ConnProtocol(c).Name)
+	assert.IsType(t, &zpack.DataPackTrace{}, c.packet)
+	assert.NotNil(t, c.frameDecoder)
+
+	c = &Connection{protocols: protocols, packet: zpack.NewDataPack()}
+	c.selectProtocol([]byte{0x01, 0x02})
+	assert.Equal(t, "v1", ConnProtocol(c).Name)
+
+	// No match keeps the datapack of the server
+	c = &Connection{protocols: protocols, packet: zpack.NewDataPack()}
+	assert.Equal(t, []byte{0x09}, c.selectProtocol([]byte{0x09}))
+	assert.Nil(t, ConnProtocol(c))
+	assert.IsType(t, &zpack.DataPack{}, c.packet)
+}
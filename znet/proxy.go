@@ -0,0 +1,171 @@
+package znet
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// proxyDialTimeout bounds the dial of the upstream server (拨号上游服务的超时时间)
+const proxyDialTimeout = 5 * time.Second
+
+// proxyLogBytes is the number of bytes of a frame payload written to the log (日志中输出的帧负载字节数)
+const proxyLogBytes = 64
+
+// ListenProxyConn accepts the TCP connections and relays each of them to ProxyUpstream unchanged, the frames are
+// decoded with the datapack of the server in both directions, logged and recorded by the recorder of the server,
+// see SetRecorder and CaptureFile. The routers and the interceptors are not involved.
+// (接受TCP连接并将其原样转发到ProxyUpstream，双向的帧使用服务的数据包解码、输出日志并交给服务的记录器，
+// 见SetRecorder与CaptureFile。不经过路由与拦截器)
+func (s *Server) ListenProxyConn() {
+	s.log().InfoF("[START] Proxy Server name: %s, listener at IP: %s, Port %d relays to %s",
+		s.Name, s.IP, s.Port, s.config.ProxyUpstream)
+
+	listener := s.listener
+	if listener == nil {
+		var err error
+		if listener, err = s.listenTcp(false); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+
+	go s.acceptProxy(listener)
+	select {
+	case <-s.ctx.Done():
+		err := listener.Close()
+		if err != nil {
+			s.log().ErrorF("listener close err: %v", err)
+		}
+	}
+}
+
+func (s *Server) acceptProxy(listener net.Listener) {
+	s.accepting()
+	delay := &acceptDelay{}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				s.log().ErrorF("Listener closed")
+				return
+			}
+			s.log().ErrorF("Accept err: %v", err)
+			delay.Delay()
+			continue
+		}
+		delay.Reset()
+		go s.proxy(conn, s.nextConnID())
+	}
+}
+
+// proxy relays client to the upstream server until either side closes (在client与上游服务之间转发，直到任意一方关闭)
+func (s *Server) proxy(client net.Conn, connID uint64) {
+	defer client.Close()
+	upstream, err := net.DialTimeout("tcp", s.config.ProxyUpstream, proxyDialTimeout)
+	if err != nil {
+		s.log().ErrorF("[PROXY] connID = %d dial upstream %s err: %v", connID, s.config.ProxyUpstream, err)
+		return
+	}
+	defer upstream.Close()
+	s.log().InfoF("[PROXY] connID = %d relays %s to %s", connID, client.RemoteAddr(), upstream.RemoteAddr())
+
+	var wg sync.WaitGroup
+	relay := func(dst, src net.Conn, inbound bool) {
+		defer wg.Done()
+		tap := &frameTap{server: s, connID: connID, inbound: inbound}
+		_, _ = io.Copy(dst, io.TeeReader(src, tap))
+		// Closing both sides ends the other direction too (关闭两端使另一个方向也结束)
+		client.Close()
+		upstream.Close()
+	}
+	wg.Add(2)
+	go relay(upstream, client, true)
+	go relay(client, upstream, false)
+	wg.Wait()
+	s.log().InfoF("[PROXY] connID = %d closed", connID)
+}
+
+// frameTap decodes the frames of one direction of a proxied connection from the bytes written to it, the inbound
+// direction is from the client to the upstream server
+// (从写入的字节中解码代理连接一个方向上的帧，inbound方向为客户端到上游服务)
+type frameTap struct {
+	server  *Server
+	connID  uint64
+	inbound bool
+
+	buf []byte
+	// broken stops the decoding after the stream cannot be unpacked, the bytes are still relayed
+	// (数据流无法解包后停止解码，字节仍然会被转发)
+	broken bool
+}
+
+func (t *frameTap) Write(p []byte) (int, error) {
+	if t.broken {
+		return len(p), nil
+	}
+	t.buf = append(t.buf, p...)
+	packet := t.server.packet
+	headLen := int(packet.GetHeadLen())
+	maxLen := t.server.config.MaxPacketSize
+	for len(t.buf) >= headLen {
+		msg, err := packet.Unpack(t.buf[:headLen])
+		// A length over MaxPacketSize is not buffered, the stream is most likely not of this datapack
+		// (不缓冲超过MaxPacketSize的长度，该数据流很可能不是此数据包格式)
+		if err == nil && maxLen > 0 && msg.GetDataLen() > maxLen {
+			err = fmt.Errorf("data length %d over MaxPacketSize %d", msg.GetDataLen(), maxLen)
+		}
+		if err != nil {
+			t.server.log().ErrorF("[PROXY] connID = %d %s unpack err: %v, stop decoding", t.connID, t.direction(), err)
+			t.broken, t.buf = true, nil
+			break
+		}
+		end := headLen + int(msg.GetDataLen())
+		if len(t.buf) < end {
+			break
+		}
+		t.frame(msg.GetMsgID(), t.buf[headLen:end])
+		t.buf = t.buf[end:]
+	}
+	// Do not keep the underlying array of the frames consumed (不保留已消费的帧的底层数组)
+	if len(t.buf) == 0 {
+		t.buf = nil
+	}
+	return len(p), nil
+}
+
+func (t *frameTap) direction() string {
+	if t.inbound {
+		return "client -> upstream"
+	}
+	return "upstream -> client"
+}
+
+// frame logs and records a decoded frame (输出并记录解码后的帧)
+func (t *frameTap) frame(msgID uint32, data []byte) {
+	dump := data
+	if len(dump) > proxyLogBytes {
+		dump = dump[:proxyLogBytes]
+	}
+	t.server.log().InfoF("[PROXY] connID = %d %s msgID = %d len = %d data = %s",
+		t.connID, t.direction(), msgID, len(data), hex.EncodeToString(dump))
+	if recorder := t.server.proxyRecorder(); recorder != nil {
+		recorder.Record(t.inbound, t.connID, msgID, append([]byte(nil), data...))
+	}
+}
+
+// proxyRecorder returns the recorder of the server, or nil (返回服务的记录器，没有时返回nil)
+func (s *Server) proxyRecorder() ziface.IRecorder {
+	mh, ok := s.msgHandler.(*MsgHandle)
+	if !ok {
+		return nil
+	}
+	box, _ := mh.recorder.Load().(recorderBox)
+	return box.recorder
+}
@@ -0,0 +1,110 @@
+package znet
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type syncRecorder struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (r *syncRecorder) Record(inbound bool, connID uint64, msgID uint32, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	direction := directionOutbound
+	if inbound {
+		direction = directionInbound
+	}
+	r.records = append(r.records, fmt.Sprintf("%s:%d:%s", direction, msgID, data))
+}
+
+func (r *syncRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.records...)
+}
+
+func TestProxy(t *testing.T) {
+	// The upstream server echoes the bytes (上游服务回显收到的字节)
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	config := zconf.DefaultConfig()
+	config.Mode = zconf.ServerModeProxy
+	config.ProxyUpstream = upstream.Addr().String()
+	s := newServerWithConfig(config, "tcp", WithListener(listener)).(*Server)
+	recorder := &syncRecorder{}
+	s.SetRecorder(recorder)
+	s.Start()
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	ping, _ := s.packet.Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	pong, _ := s.packet.Pack(zpack.NewMsgPackage(2, []byte("pong")))
+	sent := append(ping, pong...)
+	// A frame split across writes is decoded once complete (跨多次写入的帧在完整后被解码)
+	_, err = conn.Write(sent[:3])
+	assert.Nil(t, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = conn.Write(sent[3:])
+	assert.Nil(t, err)
+
+	received := make([]byte, len(sent))
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(conn, received)
+	assert.Nil(t, err)
+	assert.Equal(t, sent, received)
+
+	assert.Eventually(t, func() bool { return len(recorder.get()) == 4 }, time.Second, 10*time.Millisecond)
+	records := recorder.get()
+	assert.ElementsMatch(t, []string{"inbound:1:ping", "inbound:2:pong", "outbound:1:ping", "outbound:2:pong"}, records)
+}
+
+func TestFrameTapBroken(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.MaxPacketSize = 16
+	s := newServerWithConfig(config, "tcp").(*Server)
+	recorder := &syncRecorder{}
+	s.SetRecorder(recorder)
+	tap := &frameTap{server: s, connID: 1, inbound: true}
+
+	frame, _ := s.packet.Pack(zpack.NewMsgPackage(1, []byte("ok")))
+	n, err := tap.Write(frame)
+	assert.Nil(t, err)
+	assert.Equal(t, len(frame), n)
+
+	// A frame over MaxPacketSize stops the decoding, the bytes are still accepted (超过MaxPacketSize的帧停止解码，字节仍被接受)
+	large, _ := s.packet.Pack(zpack.NewMsgPackage(2, make([]byte, 32)))
+	n, err = tap.Write(append(large, frame...))
+	assert.Nil(t, err)
+	assert.Equal(t, len(large)+len(frame), n)
+	assert.True(t, tap.broken)
+	assert.Equal(t, []string{"inbound:1:ok"}, recorder.get())
+}
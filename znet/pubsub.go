@@ -0,0 +1,276 @@
+package znet
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/aceld/zinx/zutils"
+)
+
+var (
+	ErrEmptyTopic = errors.New("zinx: empty topic")
+	ErrConnClosed = errors.New("zinx: connection closed")
+)
+
+// pubSubCloseKey is the key of the close callback removing the subscriptions of a connection
+// (移除连接订阅的关闭回调的key)
+const pubSubCloseKey = "zinx.pubsub"
+
+// subscription holds the subscribers of a topic or a topic pattern (主题或主题模式的订阅者)
+type subscription struct {
+	topic string
+	conns map[uint64]ziface.IConnection
+
+	published, delivered, dropped uint64
+
+	metricPublished, metricDelivered, metricDropped *zmetrics.Counter
+}
+
+func newSubscription(topic string, m *zmetrics.Metrics) *subscription {
+	return &subscription{
+		topic:           topic,
+		conns:           make(map[uint64]ziface.IConnection),
+		metricPublished: m.PubSubPublished.With(topic),
+		metricDelivered: m.PubSubDelivered.With(topic),
+		metricDropped:   m.PubSubDropped.With(topic),
+	}
+}
+
+// pubSub implements ziface.IPubSub, exact topics are looked up in a map and patterns are matched one by one,
+// as the topic routers do (实现ziface.IPubSub，与主题路由相同，精确主题通过map查找，模式主题逐个匹配)
+type pubSub struct {
+	mu       sync.RWMutex
+	exact    map[string]*subscription
+	patterns map[string]*subscription
+	// The topics of each connection (每个连接订阅的主题)
+	byConn map[uint64]map[string]struct{}
+	// The metrics of the server (服务的指标)
+	metrics *zmetrics.Metrics
+}
+
+func newPubSub() *pubSub {
+	return &pubSub{
+		exact:    make(map[string]*subscription),
+		patterns: make(map[string]*subscription),
+		byConn:   make(map[uint64]map[string]struct{}),
+		metrics:  zmetrics.Zinx,
+	}
+}
+
+func (ps *pubSub) subscriptions(topic string) map[string]*subscription {
+	if zutils.IsTopicPattern(topic) {
+		return ps.patterns
+	}
+	return ps.exact
+}
+
+func (ps *pubSub) Subscribe(conn ziface.IConnection, topic string) error {
+	if topic == "" {
+		return ErrEmptyTopic
+	}
+	connID := conn.GetConnID()
+
+	ps.mu.Lock()
+	topics, ok := ps.byConn[connID]
+	if !ok {
+		topics = make(map[string]struct{})
+		ps.byConn[connID] = topics
+	}
+	topics[topic] = struct{}{}
+	subs := ps.subscriptions(topic)
+	sub, exists := subs[topic]
+	if !exists {
+		sub = newSubscription(topic, ps.metrics)
+		subs[topic] = sub
+	}
+	sub.conns[connID] = conn
+	ps.mu.Unlock()
+
+	// Outside of ps.mu, the close callbacks are invoked with the lock of the callbacks held
+	// (在ps.mu之外调用，关闭回调执行时持有回调列表的锁)
+	if !ok {
+		conn.AddCloseCallback(ps, pubSubCloseKey, func() {
+			ps.removeConn(connID)
+		})
+		if !conn.IsAlive() {
+			ps.removeConn(connID)
+			return ErrConnClosed
+		}
+	}
+	return nil
+}
+
+func (ps *pubSub) Unsubscribe(conn ziface.IConnection, topic string) {
+	connID := conn.GetConnID()
+
+	ps.mu.Lock()
+	topics := ps.byConn[connID]
+	if _, ok := topics[topic]; !ok {
+		ps.mu.Unlock()
+		return
+	}
+	ps.unsubscribe(connID, topic)
+	delete(topics, topic)
+	last := len(topics) == 0
+	if last {
+		delete(ps.byConn, connID)
+	}
+	ps.mu.Unlock()
+
+	if last {
+		conn.RemoveCloseCallback(ps, pubSubCloseKey)
+	}
+}
+
+func (ps *pubSub) UnsubscribeAll(conn ziface.IConnection) {
+	if ps.removeConn(conn.GetConnID()) {
+		conn.RemoveCloseCallback(ps, pubSubCloseKey)
+	}
+}
+
+// removeConn removes the subscriptions of connID, it is also the close callback so it must not touch the callbacks
+// (移除connID的订阅，同时也是关闭回调，因此不能操作回调列表)
+func (ps *pubSub) removeConn(connID uint64) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	topics, ok := ps.byConn[connID]
+	if !ok {
+		return false
+	}
+	for topic := range topics {
+		ps.unsubscribe(connID, topic)
+	}
+	delete(ps.byConn, connID)
+	return true
+}
+
+// unsubscribe removes connID from the subscribers of topic, ps.mu is held (将connID从topic的订阅者中移除，调用时持有ps.mu)
+func (ps *pubSub) unsubscribe(connID uint64, topic string) {
+	subs := ps.subscriptions(topic)
+	if sub, ok := subs[topic]; ok {
+		delete(sub.conns, connID)
+		if len(sub.conns) == 0 {
+			delete(subs, topic)
+		}
+	}
+}
+
+func (ps *pubSub) Subscriptions(conn ziface.IConnection) []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	topics := make([]string, 0, len(ps.byConn[conn.GetConnID()]))
+	for topic := range ps.byConn[conn.GetConnID()] {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// recipient is a connection to publish to and the subscription it was selected by (要发布到的连接及选中它的订阅)
+type recipient struct {
+	conn ziface.IConnection
+	sub  *subscription
+}
+
+func (ps *pubSub) Publish(topic string, msgID uint32, data []byte) int {
+	recipients := ps.recipients(topic)
+	delivered := 0
+	for _, r := range recipients {
+		if err := r.conn.SendBuffMsg(msgID, data); err != nil {
+			atomic.AddUint64(&r.sub.dropped, 1)
+			if zmetrics.Enabled() {
+				r.sub.metricDropped.Inc()
+			}
+			LoggerOf(r.conn).DebugF("publish topic = %s to connID = %d err: %v", topic, r.conn.GetConnID(), err)
+			continue
+		}
+		delivered++
+		atomic.AddUint64(&r.sub.delivered, 1)
+		if zmetrics.Enabled() {
+			r.sub.metricDelivered.Inc()
+		}
+	}
+	return delivered
+}
+
+// recipients collects the subscribers of the subscriptions matching topic, each connection once
+// (收集匹配topic的订阅的订阅者，每个连接只收集一次)
+func (ps *pubSub) recipients(topic string) []recipient {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	var matched []*subscription
+	if sub, ok := ps.exact[topic]; ok {
+		matched = append(matched, sub)
+	}
+	for pattern, sub := range ps.patterns {
+		if zutils.MatchTopic(pattern, topic) {
+			matched = append(matched, sub)
+		}
+	}
+
+	var recipients []recipient
+	var seen map[uint64]struct{}
+	if len(matched) > 1 {
+		seen = make(map[uint64]struct{})
+	}
+	for _, sub := range matched {
+		atomic.AddUint64(&sub.published, 1)
+		if zmetrics.Enabled() {
+			sub.metricPublished.Inc()
+		}
+		for connID, conn := range sub.conns {
+			if seen != nil {
+				if _, ok := seen[connID]; ok {
+					continue
+				}
+				seen[connID] = struct{}{}
+			}
+			recipients = append(recipients, recipient{conn: conn, sub: sub})
+		}
+	}
+	return recipients
+}
+
+func (ps *pubSub) Topics() []ziface.TopicStats {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	stats := make([]ziface.TopicStats, 0, len(ps.exact)+len(ps.patterns))
+	for _, subs := range []map[string]*subscription{ps.exact, ps.patterns} {
+		for _, sub := range subs {
+			stats = append(stats, ziface.TopicStats{
+				Topic:       sub.topic,
+				Subscribers: len(sub.conns),
+				Published:   atomic.LoadUint64(&sub.published),
+				Delivered:   atomic.LoadUint64(&sub.delivered),
+				Dropped:     atomic.LoadUint64(&sub.dropped),
+			})
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Topic < stats[j].Topic })
+	return stats
+}
+
+// GetPubSub returns the topic subscriptions of the connections of the server (返回服务连接的主题订阅)
+func (s *Server) GetPubSub() ziface.IPubSub {
+	return s.pubSub
+}
+
+// pubSubControl handles the control messages subscribing or unsubscribing the connection to the topic in
+// their data (处理控制消息，为连接订阅或取消订阅消息数据中的主题)
+func (s *Server) pubSubControl(subscribeMsgID uint32) ziface.RouterHandler {
+	return func(request ziface.IRequest) {
+		conn, topic := request.GetConnection(), string(request.GetData())
+		if request.GetMsgID() != subscribeMsgID {
+			s.pubSub.Unsubscribe(conn, topic)
+			return
+		}
+		if err := s.pubSub.Subscribe(conn, topic); err != nil {
+			s.log().ErrorF("connID = %d subscribe topic = %s err: %v", conn.GetConnID(), topic, err)
+		}
+	}
+}
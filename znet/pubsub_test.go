@@ -0,0 +1,83 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type subscriberConn struct {
+	callbackConn
+	id     uint64
+	closed bool
+}
+
+func (c *subscriberConn) GetConnID() uint64 { return c.id }
+
+func (c *subscriberConn) IsAlive() bool { return !c.closed }
+
+func (c *subscriberConn) SendBuffMsg(msgID uint32, data []byte) error {
+	if c.closed {
+		return ErrConnClosed
+	}
+	return c.SendMsg(msgID, data)
+}
+
+func TestPubSub(t *testing.T) {
+	ps := newPubSub()
+	a, b, c := &subscriberConn{id: 1}, &subscriberConn{id: 2}, &subscriberConn{id: 3}
+
+	assert.Nil(t, ps.Subscribe(a, "room/1/chat"))
+	assert.Nil(t, ps.Subscribe(a, "room/+/chat"))
+	assert.Nil(t, ps.Subscribe(b, "room/#"))
+	assert.Nil(t, ps.Subscribe(c, "room/2/chat"))
+	assert.ErrorIs(t, ps.Subscribe(c, ""), ErrEmptyTopic)
+	assert.Equal(t, []string{"room/+/chat", "room/1/chat"}, ps.Subscriptions(a))
+	assert.Equal(t, 1, a.callbacks.Count())
+
+	// a matches twice but receives the message once (a匹配两次但只收到一次消息)
+	assert.Equal(t, 2, ps.Publish("room/1/chat", 10, []byte("hi")))
+	assert.Equal(t, 1, a.sentCount())
+	assert.Equal(t, 1, b.sentCount())
+	assert.Equal(t, 0, c.sentCount())
+	assert.Equal(t, 0, ps.Publish("lobby", 10, []byte("hi")))
+
+	ps.Unsubscribe(a, "room/1/chat")
+	assert.Equal(t, 1, a.callbacks.Count())
+	ps.Unsubscribe(a, "room/+/chat")
+	assert.Equal(t, 0, a.callbacks.Count())
+	assert.Empty(t, ps.Subscriptions(a))
+
+	// Closing a connection removes its subscriptions (关闭连接会移除其订阅)
+	c.closed = true
+	assert.Equal(t, 1, ps.Publish("room/2/chat", 10, []byte("hi")))
+	c.callbacks.Invoke()
+	assert.Empty(t, ps.Subscriptions(c))
+	assert.ErrorIs(t, ps.Subscribe(c, "room/2/chat"), ErrConnClosed)
+
+	assert.Equal(t, []ziface.TopicStats{
+		{Topic: "room/#", Subscribers: 1, Published: 2, Delivered: 2},
+	}, ps.Topics())
+
+	ps.UnsubscribeAll(b)
+	assert.Empty(t, ps.Topics())
+}
+
+func TestPubSubControl(t *testing.T) {
+	s := NewServer(WithPubSubControl(1, 2)).(*Server)
+	conn := &subscriberConn{id: 7}
+
+	control := func(msgID uint32, topic string) {
+		request := NewRequest(conn, zpack.NewMsgPackage(msgID, []byte(topic)))
+		router, ok := s.msgHandler.(*MsgHandle).getRouter(msgID)
+		assert.True(t, ok)
+		router.Handle(request)
+	}
+	control(1, "news/+")
+	assert.Equal(t, []string{"news/+"}, s.GetPubSub().Subscriptions(conn))
+	assert.Equal(t, 1, s.GetPubSub().Publish("news/sport", 3, []byte("goal")))
+	control(2, "news/+")
+	assert.Empty(t, s.GetPubSub().Subscriptions(conn))
+}
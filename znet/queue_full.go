@@ -0,0 +1,137 @@
+package znet
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
+
+// QueueFullReply returns a QueueFullHandler which replies msgID with data to the client
+// (返回一个在请求被丢弃后向客户端回复msgID和data的QueueFullHandler)
+func QueueFullReply(msgID uint32, data []byte) ziface.QueueFullHandler {
+	return func(request ziface.IRequest) {
+		conn := request.GetConnection()
+		if conn == nil {
+			return
+		}
+		if err := conn.SendMsg(msgID, data); err != nil {
+			LoggerOf(conn).ErrorF("send queue full reply msgID = %d error: %v", msgID, err)
+		}
+	}
+}
+
+// SetRouterPriority sets the priority of msgID, 0 by default. The requests of routes with a priority above 0
+// go through the priority lane of their worker and are handled ahead of the other queued requests, even those
+// of the same connection which arrived earlier. Under QueueFullShed the requests of routes with a priority
+// of 0 or lower are dropped when the queue is full, the others wait.
+// (设置msgID的优先级，默认为0。优先级大于0的路由的请求经由其worker的优先通道，先于其他排队的请求处理，
+// 即使是同一连接中更早到达的请求。QueueFullShed策略下队列已满时丢弃优先级不大于0的路由的请求，其他请求等待)
+func (mh *MsgHandle) SetRouterPriority(msgID uint32, priority int) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	if priority == 0 {
+		delete(mh.priorities, msgID)
+		return
+	}
+	if mh.priorities == nil {
+		mh.priorities = make(map[uint32]int)
+	}
+	mh.priorities[msgID] = priority
+}
+
+func (mh *MsgHandle) getRouterPriority(msgID uint32) int {
+	mh.apisLock.RLock()
+	defer mh.apisLock.RUnlock()
+	return mh.priorities[msgID]
+}
+
+// SetOnQueueFull sets the hook called when a request is dropped on a full task queue, e.g. QueueFullReply
+// (设置请求因任务队列已满被丢弃时的回调，例如QueueFullReply)
+func (mh *MsgHandle) SetOnQueueFull(handler ziface.QueueFullHandler) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.onQueueFull = handler
+}
+
+// GetDropCount returns how many requests have been dropped on full task queues (获取因任务队列已满被丢弃的请求数量)
+func (mh *MsgHandle) GetDropCount() uint64 {
+	return atomic.LoadUint64(&mh.dropCount)
+}
+
+// GetSpillCount returns how many requests have run on a spillover goroutine (获取在溢出协程中执行的请求数量)
+func (mh *MsgHandle) GetSpillCount() uint64 {
+	return atomic.LoadUint64(&mh.spillCount)
+}
+
+// GetQueueDepths returns the number of requests waiting in the task queue of each worker of the shared pool
+// (返回共享worker池中每个worker任务队列里等待的请求数量)
+func (mh *MsgHandle) GetQueueDepths() []int {
+	mh = mh.pool()
+	mh.workersLock.RLock()
+	defer mh.workersLock.RUnlock()
+
+	depths := make([]int, len(mh.TaskQueue))
+	for i, queue := range mh.TaskQueue {
+		depths[i] = len(queue)
+	}
+	return depths
+}
+
+// enqueue sends the request to the task queue, applying the QueueFullPolicy of its config if it is full
+// (将请求放入任务队列，队列已满时按配置的QueueFullPolicy处理)
+func (mh *MsgHandle) enqueue(queue chan ziface.IRequest, request ziface.IRequest) {
+	select {
+	case queue <- request:
+		return
+	default:
+	}
+
+	// Internal function calls are never dropped (内部函数调用请求不会被丢弃)
+	if _, ok := request.(ziface.IFuncRequest); ok {
+		queue <- request
+		return
+	}
+
+	switch mh.config.Live().QueueFullPolicy {
+	case zconf.QueueFullBlockTimeout:
+		timer := time.NewTimer(mh.config.Live().QueueFullTimeoutDuration())
+		defer timer.Stop()
+		select {
+		case queue <- request:
+		case <-timer.C:
+			mh.drop(request)
+		}
+	case zconf.QueueFullDrop:
+		mh.drop(request)
+	case zconf.QueueFullSpill:
+		atomic.AddUint64(&mh.spillCount, 1)
+		mh.runWithoutWorker(request)
+	case zconf.QueueFullShed:
+		if mh.getRouterPriority(request.GetMsgID()) <= 0 {
+			mh.drop(request)
+			return
+		}
+		queue <- request
+	default:
+		queue <- request
+	}
+}
+
+func (mh *MsgHandle) drop(request ziface.IRequest) {
+	atomic.AddUint64(&mh.dropCount, 1)
+	mh.log().ErrorFX(dispatchLogCtx(request), "task queue is full, drop msgID = %d", request.GetMsgID())
+
+	mh.apisLock.RLock()
+	onQueueFull := mh.onQueueFull
+	mh.apisLock.RUnlock()
+
+	if onQueueFull != nil && request.GetConnection() != nil {
+		onQueueFull(request)
+	}
+	endWork(request.GetConnection())
+	PutRequest(request)
+}
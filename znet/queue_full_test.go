@@ -0,0 +1,60 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMsgHandleQueueFullPolicy(t *testing.T) {
+	defer func() { zconf.GlobalObject.QueueFullPolicy = zconf.QueueFullBlock }()
+
+	mh := newMsgHandle()
+	router := &countRouter{handled: make(chan uint32, 4)}
+	mh.AddRouter(1, router)
+	mh.AddRouter(2, router)
+	mh.SetRouterPriority(2, 10)
+	mh.SetOnQueueFull(QueueFullReply(503, []byte("busy")))
+
+	conn := &recordConn{}
+	queue := make(chan ziface.IRequest, 1)
+	queue <- NewRequest(conn, zpack.NewMsgPackage(1, nil))
+
+	zconf.GlobalObject.QueueFullPolicy = zconf.QueueFullDrop
+	mh.enqueue(queue, NewRequest(conn, zpack.NewMsgPackage(1, nil)))
+	assert.Equal(t, uint64(1), mh.GetDropCount())
+	assert.Equal(t, []sentMsg{{msgID: 503, data: []byte("busy")}}, conn.sent)
+
+	zconf.GlobalObject.QueueFullPolicy = zconf.QueueFullBlockTimeout
+	zconf.GlobalObject.QueueFullTimeout = 10
+	mh.enqueue(queue, NewRequest(conn, zpack.NewMsgPackage(1, nil)))
+	assert.Equal(t, uint64(2), mh.GetDropCount())
+
+	zconf.GlobalObject.QueueFullPolicy = zconf.QueueFullShed
+	mh.enqueue(queue, NewRequest(conn, zpack.NewMsgPackage(1, nil)))
+	assert.Equal(t, uint64(3), mh.GetDropCount())
+	go func() {
+		// The prioritized request waits for room (有优先级的请求等待队列空出)
+		time.Sleep(10 * time.Millisecond)
+		<-queue
+	}()
+	mh.enqueue(queue, NewRequest(conn, zpack.NewMsgPackage(2, nil)))
+	assert.Equal(t, uint64(3), mh.GetDropCount())
+	assert.Equal(t, uint32(2), (<-queue).GetMsgID())
+
+	queue <- NewRequest(conn, zpack.NewMsgPackage(1, nil))
+	zconf.GlobalObject.QueueFullPolicy = zconf.QueueFullSpill
+	mh.enqueue(queue, NewRequest(conn, zpack.NewMsgPackage(1, nil)))
+	assert.Equal(t, uint64(1), mh.GetSpillCount())
+	select {
+	case id := <-router.handled:
+		assert.Equal(t, uint32(1), id)
+	case <-time.After(time.Second):
+		t.Fatal("spilled request is not handled")
+	}
+	assert.Len(t, conn.sent, 3)
+}
@@ -0,0 +1,295 @@
+package znet
+
+import (
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+const rateLimitKey = "zinx.ratelimit"
+
+// tokenBucket refills rate tokens per second up to burst (每秒补充rate个令牌，最多burst个)
+type tokenBucket struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// has reports whether n tokens can be taken, n larger than the burst only needs a full bucket
+// (判断能否取走n个令牌，n大于突发量时只需令牌桶已满)
+func (b *tokenBucket) has(n float64, now time.Time) bool {
+	b.refill(now)
+	if n > b.burst {
+		n = b.burst
+	}
+	return b.tokens >= n
+}
+
+// reserve takes n tokens, going into debt if needed, and returns the wait until the debt is paid
+// (取走n个令牌，不足时记为欠账，返回还清欠账需要等待的时间)
+func (b *tokenBucket) reserve(n float64, now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// limitBuckets are the buckets of a RateLimit, nil for the unlimited ones (RateLimit的令牌桶，不限制时为nil)
+type limitBuckets struct {
+	limit           ziface.RateLimit
+	messages, bytes *tokenBucket
+}
+
+func newLimitBuckets(limit ziface.RateLimit, now time.Time) *limitBuckets {
+	lb := &limitBuckets{limit: limit}
+	if limit.Messages > 0 {
+		burst := float64(limit.Burst)
+		if burst <= 0 {
+			burst = limit.Messages
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		lb.messages = newTokenBucket(limit.Messages, burst, now)
+	}
+	if limit.Bytes > 0 {
+		lb.bytes = newTokenBucket(limit.Bytes, limit.Bytes, now)
+	}
+	return lb
+}
+
+func (lb *limitBuckets) has(size float64, now time.Time) bool {
+	return (lb.messages == nil || lb.messages.has(1, now)) && (lb.bytes == nil || lb.bytes.has(size, now))
+}
+
+func (lb *limitBuckets) reserve(size float64, now time.Time) time.Duration {
+	var wait time.Duration
+	if lb.messages != nil {
+		wait = lb.messages.reserve(1, now)
+	}
+	if lb.bytes != nil {
+		if w := lb.bytes.reserve(size, now); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
+// connLimits is the rate limit state of a connection, only used by its reader goroutine
+// (连接的速率限制状态，只由其读协程使用)
+type connLimits struct {
+	conn       *limitBuckets
+	routes     map[uint32]*limitBuckets
+	violations int
+}
+
+// rateLimiter enforces the inbound rate limits before the requests are dispatched to the workers
+// (在请求分发给worker之前执行入站速率限制)
+type rateLimiter struct {
+	mh     *MsgHandle
+	config *zconf.Config
+}
+
+// rateLimitSettings are the RateLimit* fields of the config in effect (生效中配置的RateLimit*字段)
+type rateLimitSettings struct {
+	limit    ziface.RateLimit
+	action   string
+	warnings int
+}
+
+func rateLimitSettingsOf(config *zconf.Config) rateLimitSettings {
+	config = config.Live()
+	settings := rateLimitSettings{
+		limit:    ziface.RateLimit{Messages: config.RateLimitMessages, Bytes: float64(config.RateLimitBytes), Burst: config.RateLimitBurst},
+		action:   config.RateLimitAction,
+		warnings: config.RateLimitWarnings,
+	}
+	if settings.action == "" {
+		settings.action = zconf.RateLimitDrop
+	}
+	return settings
+}
+
+func (s rateLimitSettings) limited() bool {
+	return s.limit.Messages > 0 || s.limit.Bytes > 0
+}
+
+// newRateLimiter returns the rate limiter of the server, nil if neither the connections nor the routes are limited
+// and config is not GlobalObject, whose RateLimit* fields are hot reloaded
+// (返回服务的速率限制器，连接与路由都没有限制且config不是GlobalObject时返回nil，GlobalObject的RateLimit*字段可以热加载)
+func newRateLimiter(config *zconf.Config, mh *MsgHandle) *rateLimiter {
+	if config != zconf.GlobalObject && !rateLimitSettingsOf(config).limited() && !mh.routesLimited() {
+		return nil
+	}
+	return &rateLimiter{mh: mh, config: config}
+}
+
+// routesLimited tells whether a route has a rate limit (判断是否有路由设置了速率限制)
+func (mh *MsgHandle) routesLimited() bool {
+	mh.apisLock.RLock()
+	defer mh.apisLock.RUnlock()
+	return len(mh.rateLimits) > 0
+}
+
+// limitsOf returns the limits of conn, its buckets are rebuilt once the limit of the connections is reloaded
+// (返回conn的限制状态，连接的限制被重新加载后重建其令牌桶)
+func (rl *rateLimiter) limitsOf(conn ziface.IConnection, limit ziface.RateLimit, now time.Time) *connLimits {
+	if value, err := conn.GetProperty(rateLimitKey); err == nil {
+		if limits, ok := value.(*connLimits); ok {
+			if limits.conn.limit != limit {
+				limits.conn = newLimitBuckets(limit, now)
+			}
+			return limits
+		}
+	}
+	limits := &connLimits{conn: newLimitBuckets(limit, now)}
+	conn.SetProperty(rateLimitKey, limits)
+	return limits
+}
+
+// buckets returns the buckets msgID is limited by on the connection (返回连接上msgID受限的令牌桶)
+func (rl *rateLimiter) buckets(limits *connLimits, msgID uint32, now time.Time) []*limitBuckets {
+	route, ok := limits.routes[msgID]
+	if !ok {
+		// Only the limited routes are kept, a client cannot grow the map with made up msgIDs
+		// (只保存受限的路由，客户端无法通过随意的msgID撑大map)
+		rl.mh.apisLock.RLock()
+		limit, limited := rl.mh.rateLimits[msgID]
+		rl.mh.apisLock.RUnlock()
+		if !limited {
+			return []*limitBuckets{limits.conn}
+		}
+		route = newLimitBuckets(limit, now)
+		if limits.routes == nil {
+			limits.routes = make(map[uint32]*limitBuckets)
+		}
+		limits.routes[msgID] = route
+	}
+	return []*limitBuckets{limits.conn, route}
+}
+
+func (rl *rateLimiter) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetConnection() == nil {
+		return chain.Proceed(chain.Request())
+	}
+	settings := rateLimitSettingsOf(rl.config)
+	conn := request.GetConnection()
+	if !settings.limited() && !rl.mh.routesLimited() {
+		if _, err := conn.GetProperty(rateLimitKey); err != nil {
+			return chain.Proceed(chain.Request())
+		}
+	}
+	now := time.Now()
+	limits := rl.limitsOf(conn, settings.limit, now)
+	buckets := rl.buckets(limits, request.GetMsgID(), now)
+	size := float64(len(request.GetData()))
+
+	// Delay holds the reader, the client is slowed down by the TCP flow control (Delay阻塞读协程，通过TCP流控减慢客户端)
+	if settings.action == zconf.RateLimitDelay {
+		var wait time.Duration
+		for _, lb := range buckets {
+			if w := lb.reserve(size, now); w > wait {
+				wait = w
+			}
+		}
+		if wait > 0 {
+			rl.violate(limits, request, settings.action)
+			time.Sleep(wait)
+		}
+		return chain.Proceed(chain.Request())
+	}
+
+	for _, lb := range buckets {
+		if !lb.has(size, now) {
+			rl.violate(limits, request, settings.action)
+			if settings.action == zconf.RateLimitClose && limits.violations > settings.warnings {
+				LoggerOf(conn).InfoFX(connLogCtx(conn), "connID = %d over the rate limits %d times, closing", conn.GetConnID(), limits.violations)
+				zlog.Security(connLogCtx(conn), zlog.SecurityKicked, zmetrics.CloseReasonRateLimited, zlog.F("violations", limits.violations))
+				setCloseReason(conn, zmetrics.CloseReasonRateLimited)
+				conn.Stop()
+			}
+			PutRequest(request)
+			return nil
+		}
+	}
+	for _, lb := range buckets {
+		lb.reserve(size, now)
+	}
+	return chain.Proceed(chain.Request())
+}
+
+// violate counts a message over the limits and calls the hook (统计一条超限消息并调用回调)
+func (rl *rateLimiter) violate(limits *connLimits, request ziface.IRequest, action string) {
+	limits.violations++
+	rl.mh.Metrics().RateLimited.With(action).Inc()
+	if ctx, ok := floodLog.Allow(requestLogCtx(request)); ok {
+		LoggerOf(request.GetConnection()).InfoFX(ctx, "connID = %d msgID = %d over the rate limits, %s", request.GetConnection().GetConnID(), request.GetMsgID(), action)
+	}
+	zlog.Security(requestLogCtx(request), zlog.SecurityRateLimited, action, zlog.F("violations", limits.violations))
+	rl.mh.apisLock.RLock()
+	onRateLimited := rl.mh.onRateLimited
+	rl.mh.apisLock.RUnlock()
+	if onRateLimited != nil {
+		onRateLimited(request, limits.violations)
+	}
+}
+
+// RateLimitReply returns a RateLimitHandler which replies msgID with data to the client, e.g. a warning before
+// RateLimitClose closes the connection (返回一个向客户端回复msgID和data的RateLimitHandler，例如在RateLimitClose关闭连接之前发出警告)
+func RateLimitReply(msgID uint32, data []byte) ziface.RateLimitHandler {
+	return func(request ziface.IRequest, violations int) {
+		conn := request.GetConnection()
+		if conn == nil {
+			return
+		}
+		if err := conn.SendMsg(msgID, data); err != nil {
+			LoggerOf(conn).ErrorF("send rate limit reply msgID = %d error: %v", msgID, err)
+		}
+	}
+}
+
+// SetRouterRateLimit limits the inbound messages of msgID per connection, on top of the RateLimit* limits of the
+// connection, a zero limit removes it. Unless the server runs with GlobalObject, whose RateLimit* fields are hot
+// reloaded, the limits are only enforced if the connections or a route are limited before Start.
+// (按连接限制msgID的入站消息，在连接的RateLimit*限制之外，零值表示移除限制。除非服务使用GlobalObject(其RateLimit*字段可以热加载)，
+// 只有在Start之前限制了连接或某个路由时才会执行限制)
+func (mh *MsgHandle) SetRouterRateLimit(msgID uint32, limit ziface.RateLimit) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	if limit.Messages <= 0 && limit.Bytes <= 0 {
+		delete(mh.rateLimits, msgID)
+		return
+	}
+	if mh.rateLimits == nil {
+		mh.rateLimits = make(map[uint32]ziface.RateLimit)
+	}
+	mh.rateLimits[msgID] = limit
+}
+
+// SetOnRateLimited sets the hook called with the messages over the rate limits, e.g. RateLimitReply
+// (设置超过速率限制的消息的回调，例如RateLimitReply)
+func (mh *MsgHandle) SetOnRateLimited(handler ziface.RateLimitHandler) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.onRateLimited = handler
+}
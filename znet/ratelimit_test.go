@@ -0,0 +1,141 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(10, 2, now)
+	assert.True(t, b.has(1, now))
+	assert.Equal(t, time.Duration(0), b.reserve(1, now))
+	assert.Equal(t, time.Duration(0), b.reserve(1, now))
+	assert.False(t, b.has(1, now))
+	// 100ms refill one token (100毫秒补充一个令牌)
+	assert.True(t, b.has(1, now.Add(100*time.Millisecond)))
+
+	// Larger than the burst it needs a full bucket, then the debt is waited for (大于突发量时需要令牌桶已满，之后等待欠账还清)
+	bytes := newTokenBucket(100, 100, now)
+	assert.True(t, bytes.has(150, now))
+	assert.Equal(t, 500*time.Millisecond, bytes.reserve(150, now))
+	assert.False(t, bytes.has(1, now.Add(400*time.Millisecond)))
+}
+
+func newTestRateLimiter(t *testing.T, mh *MsgHandle, setup func(config *zconf.Config)) (func(conn ziface.IConnection, msgID uint32, size int), *captureInterceptor) {
+	config := *zconf.GlobalObject
+	setup(&config)
+	rl := newRateLimiter(&config, mh)
+	assert.NotNil(t, rl)
+	capture := &captureInterceptor{}
+	return func(conn ziface.IConnection, msgID uint32, size int) {
+		request := NewRequest(conn, zpack.NewMsgPackage(msgID, make([]byte, size)))
+		zinterceptor.NewChain([]ziface.IInterceptor{rl, capture}, 0, request).Proceed(request)
+	}, capture
+}
+
+func TestRateLimiterDrop(t *testing.T) {
+	mh := newMsgHandle()
+	config := *zconf.GlobalObject
+	assert.Nil(t, newRateLimiter(&config, mh))
+	// The limits of GlobalObject may be hot reloaded (GlobalObject的限制可能被热加载)
+	assert.NotNil(t, newRateLimiter(zconf.GlobalObject, mh))
+	mh.SetRouterRateLimit(7, ziface.RateLimit{Bytes: 10})
+	var violations []int
+	mh.SetOnRateLimited(func(request ziface.IRequest, n int) { violations = append(violations, n) })
+	send, capture := newTestRateLimiter(t, mh, func(config *zconf.Config) {
+		config.RateLimitMessages = 1
+		config.RateLimitBurst = 3
+	})
+
+	conn, other := newAuthConn(), newAuthConn()
+	send(conn, 1, 0)
+	send(conn, 1, 0)
+	send(conn, 1, 0)
+	send(conn, 1, 0)
+	// Every connection has its own limits (每个连接有各自的限制)
+	send(other, 7, 8)
+	send(other, 7, 8)
+
+	assert.Len(t, capture.requests, 4)
+	assert.Equal(t, []int{1, 1}, violations)
+	assert.False(t, conn.isStopped())
+}
+
+func TestRateLimiterClose(t *testing.T) {
+	mh := newMsgHandle()
+	send, capture := newTestRateLimiter(t, mh, func(config *zconf.Config) {
+		config.RateLimitMessages = 1
+		config.RateLimitAction = zconf.RateLimitClose
+		config.RateLimitWarnings = 2
+	})
+
+	var events []string
+	zlog.SetSecuritySink(zlog.SecuritySinkFunc(func(event zlog.SecurityEvent) { events = append(events, event.Type) }))
+	defer zlog.SetSecuritySink(nil)
+
+	conn := newAuthConn()
+	for i := 0; i < 3; i++ {
+		send(conn, 1, 0)
+	}
+	assert.False(t, conn.isStopped())
+	send(conn, 1, 0)
+	assert.True(t, conn.isStopped())
+	assert.Len(t, capture.requests, 1)
+	assert.Equal(t, []string{zlog.SecurityRateLimited, zlog.SecurityRateLimited, zlog.SecurityRateLimited, zlog.SecurityKicked}, events)
+}
+
+func TestRateLimiterDelay(t *testing.T) {
+	mh := newMsgHandle()
+	mh.SetRouterRateLimit(2, ziface.RateLimit{Messages: 50, Burst: 1})
+	send, capture := newTestRateLimiter(t, mh, func(config *zconf.Config) {
+		config.RateLimitAction = zconf.RateLimitDelay
+	})
+
+	conn := newAuthConn()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		send(conn, 2, 0)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+	assert.Len(t, capture.requests, 3)
+}
+
+func TestRateLimiterReload(t *testing.T) {
+	mh := newMsgHandle()
+	config := *zconf.GlobalObject
+	config.RateLimitMessages = 1
+	rl := newRateLimiter(&config, mh)
+	capture := &captureInterceptor{}
+	send := func(conn ziface.IConnection) {
+		request := NewRequest(conn, zpack.NewMsgPackage(1, nil))
+		zinterceptor.NewChain([]ziface.IInterceptor{rl, capture}, 0, request).Proceed(request)
+	}
+
+	conn := newAuthConn()
+	send(conn)
+	send(conn)
+	assert.Len(t, capture.requests, 1)
+
+	// The buckets of the running connections follow the reloaded limits (运行中连接的令牌桶随重新加载的限制变化)
+	config.RateLimitMessages = 100
+	config.RateLimitBurst = 2
+	send(conn)
+	send(conn)
+	send(conn)
+	assert.Len(t, capture.requests, 3)
+
+	config.RateLimitMessages, config.RateLimitBurst = 0, 0
+	for i := 0; i < 5; i++ {
+		send(conn)
+	}
+	assert.Len(t, capture.requests, 8)
+	assert.False(t, conn.isStopped())
+}
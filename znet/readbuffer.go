@@ -0,0 +1,80 @@
+package znet
+
+import "github.com/aceld/zinx/zconf"
+
+// readBuffer is the buffer a connection reads into, adaptive between IOReadBuffMin and IOReadBuffMax when they are
+// set: it starts at IOReadBuffMin, doubles as soon as a read fills it and halves after two reads in a row fitting in
+// half of it, like the adaptive receive buffer of Netty. A read that does not fill the buffer means the read loop
+// has caught up and waits for the peer, so the loop waits on a buffer of IOReadBuffMin and gets the adaptive size
+// back once a read fills it again, idle connections only hold the minimum.
+// (连接读取数据的缓冲，设置了IOReadBuffMin和IOReadBuffMax时在两者间自适应：初始为IOReadBuffMin，读满时立即加倍，
+// 连续两次读取不超过一半时减半，与Netty的自适应接收缓冲相同。读取未填满缓冲说明读循环已读完数据、开始等待对端，
+// 此时使用IOReadBuffMin大小的缓冲等待，再次读满时恢复自适应大小，空闲连接只占用最小的缓冲)
+type readBuffer struct {
+	buf      []byte
+	min, max int
+	// size is the adaptive size, buf is smaller while the read loop is idle (自适应大小，读循环空闲时buf小于它)
+	size int
+	// idle is the buffer of IOReadBuffMin the read loop waits on (读循环等待时使用的IOReadBuffMin大小的缓冲)
+	idle []byte
+	// shrink is set by a read fitting in half of the buffer, the next one halves it (读取不超过一半时置位，下一次时减半)
+	shrink bool
+}
+
+func newReadBuffer(config *zconf.Config) *readBuffer {
+	if config.IOReadBuffMin > 0 && config.IOReadBuffMax > config.IOReadBuffMin {
+		b := &readBuffer{min: int(config.IOReadBuffMin), max: int(config.IOReadBuffMax), size: int(config.IOReadBuffMin)}
+		b.idle = make([]byte, b.min)
+		b.buf = b.idle
+		return b
+	}
+	return &readBuffer{buf: make([]byte, config.IOReadBuffSize)}
+}
+
+// bytes returns the buffer to read into (返回用于读取的缓冲)
+func (b *readBuffer) bytes() []byte {
+	return b.buf
+}
+
+// record resizes the buffer after a read of n bytes, the bytes read stay valid as a larger buffer is a new one
+// (在读取n个字节后调整缓冲大小，更大的缓冲是新分配的，因此已读取的字节仍然有效)
+func (b *readBuffer) record(n int) {
+	if b.max == 0 {
+		return
+	}
+	if n >= len(b.buf) {
+		b.shrink = false
+		// A full buffer doubles, the idle one first gets the adaptive size back (读满时加倍，空闲缓冲先恢复自适应大小)
+		if len(b.buf) == b.size {
+			b.resize(b.size * 2)
+		}
+		if len(b.buf) < b.size {
+			b.buf = make([]byte, b.size)
+		}
+		return
+	}
+
+	switch {
+	case n <= b.size/2 && b.size > b.min:
+		if b.shrink {
+			b.resize(b.size / 2)
+		} else {
+			b.shrink = true
+		}
+	default:
+		b.shrink = false
+	}
+	// The read loop has caught up and waits for the peer (读循环已读完数据，开始等待对端)
+	b.buf = b.idle
+}
+
+func (b *readBuffer) resize(size int) {
+	if size > b.max {
+		size = b.max
+	}
+	if size < b.min {
+		size = b.min
+	}
+	b.size = size
+	b.shrink = false
+}
@@ -0,0 +1,56 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBuffer(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.IOReadBuffSize = 256
+
+	// Fixed by default (默认固定大小)
+	b := newReadBuffer(config)
+	b.record(256)
+	b.record(1)
+	b.record(1)
+	assert.Len(t, b.bytes(), 256)
+
+	config.IOReadBuffMin = 64
+	config.IOReadBuffMax = 1024
+	b = newReadBuffer(config)
+
+	// Starts at the min, a full read doubles the buffer up to the max (初始为下限，读满时加倍，直到上限)
+	assert.Len(t, b.bytes(), 64)
+	for _, n := range []int{64, 128, 256, 512, 1024} {
+		b.record(n)
+	}
+	assert.Len(t, b.bytes(), 1024)
+
+	// Waiting for the peer only holds the min, a full read gets the adaptive size back
+	// (等待对端时只占用下限大小，读满后恢复自适应大小)
+	b.record(600)
+	assert.Len(t, b.bytes(), 64)
+	b.record(64)
+	assert.Len(t, b.bytes(), 1024)
+
+	// Two small reads in a row halve it down to the min (连续两次小的读取时减半，直到下限)
+	b.record(100)
+	b.record(10)
+	assert.Equal(t, 512, b.size)
+	b.record(64)
+	assert.Len(t, b.bytes(), 512)
+	b.record(300)
+	assert.Equal(t, 512, b.size)
+	for i := 0; i < 10; i++ {
+		b.record(1)
+	}
+	assert.Equal(t, 64, b.size)
+	assert.Len(t, b.bytes(), 64)
+
+	// Idle at the min, a full read doubles again (在下限空闲时，读满后再次加倍)
+	b.record(64)
+	assert.Len(t, b.bytes(), 128)
+}
@@ -0,0 +1,59 @@
+package znet
+
+import (
+	"runtime/debug"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// PanicReply returns a PanicHandler which replies msgID with data to the client
+// (返回一个在panic后向客户端回复msgID和data的PanicHandler)
+func PanicReply(msgID uint32, data []byte) ziface.PanicHandler {
+	return func(request ziface.IRequest, err interface{}, stack []byte) {
+		conn := request.GetConnection()
+		if conn == nil {
+			return
+		}
+		if sendErr := conn.SendMsg(msgID, data); sendErr != nil {
+			LoggerOf(conn).ErrorF("send panic reply msgID = %d error: %v", msgID, sendErr)
+		}
+	}
+}
+
+// SetOnHandlerPanic sets the hook called after a handler panic is recovered
+// (设置处理函数panic被恢复后的回调)
+func (mh *MsgHandle) SetOnHandlerPanic(handler ziface.PanicHandler) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.onHandlerPanic = handler
+}
+
+// recoverHandler must be deferred directly around handler invocation, it recovers the panic,
+// logs the stack trace and fires the OnHandlerPanic hook
+// (必须在处理函数调用处直接defer，恢复panic，记录堆栈并调用OnHandlerPanic回调)
+func (mh *MsgHandle) recoverHandler(request ziface.IRequest, workerID int) {
+	err := recover()
+	if err == nil {
+		return
+	}
+
+	stack := debug.Stack()
+	mh.log().ErrorFX(dispatchLogCtx(request), "workerID: %d msgID: %d doMsgHandler panic: %v\n%s", workerID, request.GetMsgID(), err, stack)
+
+	mh.apisLock.RLock()
+	onPanic := mh.onHandlerPanic
+	mh.apisLock.RUnlock()
+
+	if onPanic == nil {
+		return
+	}
+
+	// The hook itself must not kill the worker either (回调本身的panic也不能导致worker退出)
+	defer func() {
+		if hookErr := recover(); hookErr != nil {
+			mh.log().ErrorF("workerID: %d OnHandlerPanic panic: %v", workerID, hookErr)
+		}
+	}()
+	onPanic(request, err, stack)
+}
@@ -0,0 +1,35 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type panicRouter struct {
+	BaseRouter
+}
+
+func (r *panicRouter) Handle(request ziface.IRequest) {
+	panic("boom")
+}
+
+func TestMsgHandleOnHandlerPanic(t *testing.T) {
+	mh := newMsgHandle()
+	mh.AddRouter(1, &panicRouter{})
+
+	var recovered interface{}
+	var stack []byte
+	mh.SetOnHandlerPanic(func(request ziface.IRequest, err interface{}, s []byte) {
+		recovered = err
+		stack = s
+	})
+
+	assert.NotPanics(t, func() {
+		mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)), 0)
+	})
+	assert.Equal(t, "boom", recovered)
+	assert.Contains(t, string(stack), "panicRouter")
+}
@@ -0,0 +1,109 @@
+package znet
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// Reasons of zinx_replay_rejected_total (zinx_replay_rejected_total的拒绝原因)
+const (
+	replayMissing   = "missing"   // No timestamp or nonce in the header (头部没有时间戳或nonce)
+	replayStale     = "stale"     // The timestamp is out of the window (时间戳超出窗口)
+	replayDuplicate = "duplicate" // The nonce was seen within the window (窗口内出现过该nonce)
+	replayStore     = "store"     // The nonce store failed (nonce存储出错)
+)
+
+// MemoryNonceStore is a ziface.INonceStore within the process (进程内的ziface.INonceStore)
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryNonceStore creates a MemoryNonceStore (创建MemoryNonceStore)
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{seen: make(map[string]time.Time), lastSweep: time.Now()}
+}
+
+func (s *MemoryNonceStore) Add(nonce string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Expired nonces are swept at most once per ttl (过期的nonce每个ttl最多清理一次)
+	if now.Sub(s.lastSweep) > ttl {
+		for n, deadline := range s.seen {
+			if !now.Before(deadline) {
+				delete(s.seen, n)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if deadline, ok := s.seen[nonce]; ok && now.Before(deadline) {
+		return false, nil
+	}
+	s.seen[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// replayGuard rejects the messages out of the window and those repeating the nonce of a message within it, it
+// runs right after the decoder (拒绝超出窗口的消息以及与窗口内消息nonce重复的消息，紧跟在解码器之后执行)
+type replayGuard struct {
+	window time.Duration
+	store  ziface.INonceStore
+}
+
+func newReplayGuard(window time.Duration, store ziface.INonceStore) *replayGuard {
+	if store == nil {
+		store = NewMemoryNonceStore()
+	}
+	return &replayGuard{window: window, store: store}
+}
+
+func (rg *replayGuard) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetConnection() == nil {
+		return chain.Proceed(chain.Request())
+	}
+
+	if reason := rg.check(request); reason != "" {
+		metricsOf(request.GetConnection()).ReplayRejected.With(reason).Inc()
+		if ctx, ok := replayLog.Allow(requestLogCtx(request)); ok {
+			LoggerOf(request.GetConnection()).ErrorFX(ctx, "connID = %d reject msgID = %d as a replay: %s", request.GetConnection().GetConnID(), request.GetMsgID(), reason)
+		}
+		zlog.Security(requestLogCtx(request), zlog.SecurityReplay, reason)
+		PutRequest(request)
+		return nil
+	}
+	return chain.Proceed(chain.Request())
+}
+
+// check returns why the request is rejected, "" if it is accepted (返回请求被拒绝的原因，接受时返回"")
+func (rg *replayGuard) check(request ziface.IRequest) string {
+	hm, ok := request.GetMessage().(ziface.IHeaderMessage)
+	if !ok || hm.GetTimestamp() == 0 || hm.GetNonce() == 0 {
+		return replayMissing
+	}
+	skew := time.Since(time.UnixMilli(hm.GetTimestamp()))
+	if skew > rg.window || skew < -rg.window {
+		return replayStale
+	}
+	// A message within the window is never older than two windows, its nonce is kept that long
+	// (窗口内的消息不会早于两个窗口，其nonce保存同样长的时间)
+	fresh, err := rg.store.Add(strconv.FormatUint(hm.GetNonce(), 16), 2*rg.window)
+	if err != nil {
+		// Fail closed, a replay must not get through while the store is down (失败时拒绝，存储不可用时不能放过重放)
+		LoggerOf(request.GetConnection()).ErrorFX(requestLogCtx(request), "nonce store err: %v", err)
+		return replayStore
+	}
+	if !fresh {
+		return replayDuplicate
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package znet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingNonceStore struct{}
+
+func (failingNonceStore) Add(string, time.Duration) (bool, error) { return false, errors.New("down") }
+
+func TestReplayGuard(t *testing.T) {
+	rg := newReplayGuard(50*time.Millisecond, nil)
+	capture := &captureInterceptor{}
+	send := func(guard *replayGuard, ts time.Time, nonce uint64) {
+		msg := zpack.NewMsgPackage(1, []byte("transfer"))
+		if !ts.IsZero() {
+			msg.SetTimestamp(ts.UnixMilli())
+		}
+		msg.SetNonce(nonce)
+		request := NewRequest(&recordConn{}, msg)
+		zinterceptor.NewChain([]ziface.IInterceptor{guard, capture}, 0, request).Proceed(request)
+	}
+
+	now := time.Now()
+	send(rg, now, 1)
+	send(rg, now, 2)
+	assert.Len(t, capture.requests, 2)
+
+	send(rg, now, 1)                                               // Duplicate (重复)
+	send(rg, now.Add(-time.Second), 3)                             // Stale (过期)
+	send(rg, now.Add(time.Second), 4)                              // From the future (来自未来)
+	send(rg, time.Time{}, 5)                                       // No timestamp (没有时间戳)
+	send(rg, now, 0)                                               // No nonce (没有nonce)
+	send(newReplayGuard(time.Minute, failingNonceStore{}), now, 6) // Fails closed (失败时拒绝)
+	assert.Len(t, capture.requests, 2)
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+	fresh, _ := store.Add("a", 20*time.Millisecond)
+	assert.True(t, fresh)
+	fresh, _ = store.Add("a", 20*time.Millisecond)
+	assert.False(t, fresh)
+	time.Sleep(30 * time.Millisecond)
+	fresh, _ = store.Add("a", 20*time.Millisecond)
+	assert.True(t, fresh)
+	fresh, _ = store.Add("b", 20*time.Millisecond)
+	assert.True(t, fresh)
+	// The expired ones are swept (过期的nonce被清理)
+	assert.Len(t, store.seen, 2)
+}
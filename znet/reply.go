@@ -0,0 +1,66 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+var (
+	ErrAlreadyReplied = errors.New("zinx: deferred request already replied")
+	ErrNoConnection   = errors.New("zinx: deferred request has no connection")
+)
+
+// deferredReply keeps only the connection and MsgID of the request, not the request itself,
+// so the request can be recycled into the pool as soon as the handler returns.
+// (只保存请求的连接和MsgID而不引用请求本身，处理函数返回后请求即可回收到对象池)
+type deferredReply struct {
+	conn    ziface.IConnection
+	msgID   uint32
+	replied int32
+}
+
+func newDeferredReply(conn ziface.IConnection, msgID uint32) *deferredReply {
+	return &deferredReply{conn: conn, msgID: msgID}
+}
+
+func (d *deferredReply) Reply(msgID uint32, data []byte) error {
+	if !atomic.CompareAndSwapInt32(&d.replied, 0, 1) {
+		return ErrAlreadyReplied
+	}
+	if d.conn == nil {
+		return ErrNoConnection
+	}
+	return d.conn.SendMsg(msgID, data)
+}
+
+func (d *deferredReply) GetConnection() ziface.IConnection {
+	return d.conn
+}
+
+func (d *deferredReply) GetMsgID() uint32 {
+	return d.msgID
+}
+
+func (d *deferredReply) Context() context.Context {
+	if d.conn == nil {
+		return context.Background()
+	}
+	return d.conn.Context()
+}
+
+// SendTraced replies msgID with data on the connection of request, carrying the trace ID of the request
+// so that datapacks supporting tracing (zpack.DataPackTrace) propagate it to the peer
+// (在请求所属连接上回复msgID和data并携带请求的链路追踪ID，支持链路追踪的数据包(zpack.DataPackTrace)会将其传递给对端)
+func SendTraced(request ziface.IRequest, msgID uint32, data []byte) error {
+	conn := request.GetConnection()
+	if conn == nil {
+		return ErrNoConnection
+	}
+	msg := zpack.NewMsgPackage(msgID, data)
+	msg.SetTraceID(request.GetTraceID())
+	return conn.SendMessage(msg)
+}
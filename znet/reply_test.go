@@ -0,0 +1,38 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type deferRouter struct {
+	BaseRouter
+	replies chan ziface.IReply
+}
+
+func (r *deferRouter) Handle(request ziface.IRequest) {
+	r.replies <- request.Defer()
+}
+
+func TestDeferredReply(t *testing.T) {
+	mh := newMsgHandle()
+	router := &deferRouter{replies: make(chan ziface.IReply, 1)}
+	mh.AddRouter(1, router)
+
+	conn := &recordConn{}
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(1, nil)), 0)
+
+	reply := <-router.replies
+	assert.Equal(t, uint32(1), reply.GetMsgID())
+
+	done := make(chan error)
+	go func() {
+		done <- reply.Reply(2, []byte("done"))
+	}()
+	assert.NoError(t, <-done)
+	assert.Equal(t, ErrAlreadyReplied, reply.Reply(2, []byte("again")))
+	assert.Equal(t, []sentMsg{{msgID: 2, data: []byte("done")}}, conn.sent)
+}
@@ -1,12 +1,15 @@
 package znet
 
 import (
+	"context"
 	"math"
 	"sync"
+	"time"
 
-	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zpack"
+	"github.com/aceld/zinx/ztrace"
+	"github.com/aceld/zinx/zutils"
 )
 
 const (
@@ -38,6 +41,9 @@ type Request struct {
 	handlers []ziface.RouterHandler // router function slice(路由函数切片)
 	index    int8                   // router function slice index(路由函数切片索引)
 	keys     map[string]interface{} // keys 路由处理时可能会存取的上下文信息
+	ctx      context.Context        // the context of the request, canceled when the route times out(请求的Context，路由超时时会被取消)
+	reply    ziface.IReply          // the reply token of a deferred request(延迟回复的凭证)
+	created  time.Time              // when the request was read from the connection(请求从连接读取的时间)
 }
 
 func (r *Request) GetResponse() ziface.IcResp {
@@ -56,13 +62,14 @@ func NewRequest(conn ziface.IConnection, msg ziface.IMessage) ziface.IRequest {
 	req.stepLock = sync.RWMutex{}
 	req.needNext = true
 	req.index = -1
+	req.created = time.Now()
 	return req
 }
 
 func GetRequest(conn ziface.IConnection, msg ziface.IMessage) ziface.IRequest {
 
 	// 根据当前模式判断是否使用对象池
-	if zconf.GlobalObject.RequestPoolMode {
+	if configOf(conn).RequestPoolMode {
 		// 从对象池中取得一个 Request 对象,如果池子中没有可用的 Request 对象则会调用 allocateRequest 函数构造一个新的对象分配
 		r := RequestPool.Get().(*Request)
 		// 因为取出的 Request 对象可能是已存在也可能是新构造的,无论是哪种情况都应该初始化再返回使用
@@ -74,11 +81,33 @@ func GetRequest(conn ziface.IConnection, msg ziface.IMessage) ziface.IRequest {
 
 func PutRequest(request ziface.IRequest) {
 	// 判断是否开启了对象池模式
-	if zconf.GlobalObject.RequestPoolMode {
+	if configOf(request.GetConnection()).RequestPoolMode {
+		// The pooled message is recycled together with the request (池化的消息随Request一起回收)
+		if r, ok := request.(*Request); ok {
+			if msg, ok := r.msg.(ziface.IPooledMessage); ok {
+				msg.Release()
+			}
+			r.msg = nil
+		}
 		RequestPool.Put(request)
 	}
 }
 
+// GetMessage wraps a frame read from a connection into a message, taken from the message
+// object pool in RequestPoolMode and recycled by PutRequest.
+// (将从连接读取的帧包装为消息，对象池模式下从消息对象池中取出，并由PutRequest回收)
+func GetMessage(data []byte) ziface.IMessage {
+	return getMessage(nil, data)
+}
+
+// getMessage is GetMessage in the RequestPoolMode of the server of conn (按conn所属服务的RequestPoolMode执行GetMessage)
+func getMessage(conn ziface.IConnection, data []byte) ziface.IMessage {
+	if configOf(conn).RequestPoolMode {
+		return zpack.AcquireMessage(0, data)
+	}
+	return zpack.NewMessage(uint32(len(data)), data)
+}
+
 func allocateRequest() ziface.IRequest {
 	req := new(Request)
 	req.steps = PRE_HANDLE
@@ -94,6 +123,9 @@ func (r *Request) Reset(conn ziface.IConnection, msg ziface.IMessage) {
 	r.needNext = true
 	r.index = -1
 	r.keys = nil
+	r.ctx = nil
+	r.reply = nil
+	r.created = time.Now()
 
 }
 
@@ -111,6 +143,8 @@ func (r *Request) Copy() ziface.IRequest {
 		icResp:   nil,
 		handlers: nil,
 		index:    math.MaxInt8,
+		ctx:      r.ctx,
+		created:  r.created,
 	}
 
 	// 复制原本的上下文信息
@@ -151,6 +185,47 @@ func (r *Request) Get(key string) (value interface{}, exists bool) {
 	return
 }
 
+// MustGet 在 Request 中取出一个上下文信息，不存在时panic
+// (returns the value of key, panics if it does not exist)
+func (r *Request) MustGet(key string) interface{} {
+	if value, exists := r.Get(key); exists {
+		return value
+	}
+	panic("Key \"" + key + "\" does not exist")
+}
+
+// GetString 以string类型取出上下文信息，不存在或类型不符时返回空字符串
+// (returns the value of key as a string, "" if it does not exist or is not a string)
+func (r *Request) GetString(key string) (s string) {
+	if value, exists := r.Get(key); exists {
+		s, _ = value.(string)
+	}
+	return
+}
+
+// GetInt 以int类型取出上下文信息 (returns the value of key as an int)
+func (r *Request) GetInt(key string) (i int) {
+	if value, exists := r.Get(key); exists {
+		i, _ = value.(int)
+	}
+	return
+}
+
+// GetBool 以bool类型取出上下文信息 (returns the value of key as a bool)
+func (r *Request) GetBool(key string) (b bool) {
+	if value, exists := r.Get(key); exists {
+		b, _ = value.(bool)
+	}
+	return
+}
+
+// Delete 删除 Request 中的一个上下文信息 (removes the value of key)
+func (r *Request) Delete(key string) {
+	r.stepLock.Lock()
+	delete(r.keys, key)
+	r.stepLock.Unlock()
+}
+
 func (r *Request) GetMessage() ziface.IMessage {
 	return r.msg
 }
@@ -212,7 +287,7 @@ func (r *Request) Call() {
 }
 
 func (r *Request) Abort() {
-	if zconf.GlobalObject.RouterSlicesMode {
+	if configOf(r.conn).RouterSlicesMode {
 		r.index = int8(len(r.handlers))
 	} else {
 		r.stepLock.Lock()
@@ -221,6 +296,12 @@ func (r *Request) Abort() {
 	}
 }
 
+// aborted reports whether the handlers bound by BindRouterSlices were aborted (判断BindRouterSlices绑定的处理器是否已被中止)
+func aborted(request ziface.IRequest) bool {
+	r, ok := request.(*Request)
+	return ok && r.handlers != nil && int(r.index) >= len(r.handlers)
+}
+
 // BindRouterSlices New version
 func (r *Request) BindRouterSlices(handlers []ziface.RouterHandler) {
 	r.handlers = handlers
@@ -233,3 +314,70 @@ func (r *Request) RouterSlicesNext() {
 		r.index++
 	}
 }
+
+// GetContext returns the context of the request, context.Background() if none is set
+// (获取请求的Context，未设置时返回context.Background())
+func (r *Request) GetContext() context.Context {
+	r.stepLock.RLock()
+	defer r.stepLock.RUnlock()
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+func (r *Request) SetContext(ctx context.Context) {
+	r.stepLock.Lock()
+	r.ctx = ctx
+	r.stepLock.Unlock()
+}
+
+func (r *Request) Defer() ziface.IReply {
+	r.stepLock.Lock()
+	defer r.stepLock.Unlock()
+	if r.reply == nil {
+		r.reply = newDeferredReply(r.conn, r.msg.GetMsgID())
+	}
+	return r.reply
+}
+
+func (r *Request) IsDeferred() bool {
+	r.stepLock.RLock()
+	defer r.stepLock.RUnlock()
+	return r.reply != nil
+}
+
+func (r *Request) Stream() ziface.IStreamWriter {
+	return newStreamWriter(r.conn, r.msg.GetMsgID())
+}
+
+func (r *Request) GetCreateTime() time.Time {
+	return r.created
+}
+
+// traceKey stores generated trace IDs of messages which cannot carry one (消息无法携带追踪ID时保存生成的ID)
+const traceKey = "zinx.trace_id"
+
+func (r *Request) GetTraceID() string {
+	return existingTraceID(r)
+}
+
+// assignTraceID gives the request a trace ID once when it is dispatched, if its datapack carries none: that of the
+// span started at decode, or a generated one
+// (请求分发时，如果数据包没有携带追踪ID，为其设置一次追踪ID：解码时开始的span的追踪ID，或者生成一个)
+func assignTraceID(request ziface.IRequest) {
+	if existingTraceID(request) != "" {
+		return
+	}
+	var traceID string
+	if span := ztrace.SpanFromContext(request.GetContext()); span != nil {
+		traceID = span.TraceID
+	} else {
+		traceID = zutils.NewTraceID()
+	}
+	if tm, ok := request.GetMessage().(ziface.ITraceMessage); ok {
+		tm.SetTraceID(traceID)
+	} else {
+		request.Set(traceKey, traceID)
+	}
+}
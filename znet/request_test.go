@@ -0,0 +1,27 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestValues(t *testing.T) {
+	r := GetRequest(nil, zpack.NewMsgPackage(1, nil)).(*Request)
+	r.Set("principal", "user-1")
+	r.Set("tenant", 7)
+
+	assert.Equal(t, "user-1", r.GetString("principal"))
+	assert.Equal(t, 7, r.GetInt("tenant"))
+	assert.Equal(t, "", r.GetString("tenant"))
+	assert.Panics(t, func() { r.MustGet("missing") })
+
+	r.Delete("tenant")
+	_, ok := r.Get("tenant")
+	assert.False(t, ok)
+
+	r.Reset(nil, zpack.NewMsgPackage(2, nil))
+	_, ok = r.Get("principal")
+	assert.False(t, ok)
+}
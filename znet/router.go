@@ -42,6 +42,12 @@ func (br *BaseRouter) PostHandle(req ziface.IRequest) {}
 type RouterSlices struct {
 	Apis     map[uint32][]ziface.RouterHandler
 	Handlers []ziface.RouterHandler
+	// Handlers bound to MsgID ranges (绑定到MsgID区间的处理器)
+	rangeApis msgIDRanges
+	// Handlers of messages that no router matches (未匹配任何路由的消息的处理器)
+	notFound []ziface.RouterHandler
+	// Handlers bound to topics (绑定到主题的处理器)
+	topicApis topicRoutes
 	sync.RWMutex
 }
 
@@ -53,33 +59,128 @@ func NewRouterSlices() *RouterSlices {
 }
 
 func (r *RouterSlices) Use(handles ...ziface.RouterHandler) {
+	r.Lock()
+	defer r.Unlock()
 	r.Handlers = append(r.Handlers, handles...)
 }
 
 func (r *RouterSlices) AddHandler(msgId uint32, Handlers ...ziface.RouterHandler) {
+	r.Lock()
+	defer r.Unlock()
+
 	// 1. Check if the API handler method bound to the current msg already exists
 	if _, ok := r.Apis[msgId]; ok {
 		panic("repeated api , msgId = " + strconv.Itoa(int(msgId)))
 	}
 
-	finalSize := len(r.Handlers) + len(Handlers)
-	mergedHandlers := make([]ziface.RouterHandler, finalSize)
+	r.Apis[msgId] = r.mergeHandlers(Handlers)
+}
+
+// RemoveHandler removes the handlers of msgId, it is safe to call while the server is running
+// (删除msgId的处理器，服务运行期间可以安全调用)
+func (r *RouterSlices) RemoveHandler(msgId uint32) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.Apis, msgId)
+}
+
+// ReplaceHandler binds handlers to msgId whether or not handlers already exist,
+// it is safe to call while the server is running
+// (无论msgId是否已有处理器都进行替换，服务运行期间可以安全调用)
+func (r *RouterSlices) ReplaceHandler(msgId uint32, Handlers ...ziface.RouterHandler) {
+	r.Lock()
+	defer r.Unlock()
+	r.Apis[msgId] = r.mergeHandlers(Handlers)
+}
+
+// AddHandlerRange binds handlers to all MsgIDs in [start, end], exact MsgIDs take precedence
+// (为MsgID区间[start, end]绑定处理器，精确MsgID优先)
+func (r *RouterSlices) AddHandlerRange(start, end uint32, Handlers ...ziface.RouterHandler) {
+	r.Lock()
+	defer r.Unlock()
+	r.rangeApis.add(msgIDRange{start: start, end: end, handlers: r.mergeHandlers(Handlers)})
+}
+
+// AddTopicHandler binds handlers to a topic or a topic pattern (为主题或主题模式绑定处理器)
+func (r *RouterSlices) AddTopicHandler(topic string, Handlers ...ziface.RouterHandler) {
+	r.Lock()
+	defer r.Unlock()
+	r.topicApis.add(&topicRoute{pattern: topic, handlers: r.mergeHandlers(Handlers)})
+}
+
+// GetTopicHandlers finds the handlers of topic, falls back to the not found handlers
+// (查找主题对应的处理器，未找到时使用NotFound处理器)
+func (r *RouterSlices) GetTopicHandlers(topic string) ([]ziface.RouterHandler, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	if route, ok := r.topicApis.find(topic); ok {
+		return route.handlers, true
+	}
+	if r.notFound != nil {
+		return r.notFound, true
+	}
+	return nil, false
+}
+
+func (r *RouterSlices) hasTopicHandlers() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return !r.topicApis.empty()
+}
+
+// NotFound sets the handlers of messages that no router matches
+// (设置未匹配任何路由的消息的处理器)
+func (r *RouterSlices) NotFound(Handlers ...ziface.RouterHandler) {
+	r.Lock()
+	defer r.Unlock()
+	r.notFound = r.mergeHandlers(Handlers)
+}
+
+// mergeHandlers prepends the global components to the handlers
+// (将全局组件合并到处理器之前)
+func (r *RouterSlices) mergeHandlers(Handlers []ziface.RouterHandler) []ziface.RouterHandler {
+	mergedHandlers := make([]ziface.RouterHandler, len(r.Handlers)+len(Handlers))
 	copy(mergedHandlers, r.Handlers)
 	copy(mergedHandlers[len(r.Handlers):], Handlers)
-	r.Apis[msgId] = append(r.Apis[msgId], mergedHandlers...)
+	return mergedHandlers
 }
 
 func (r *RouterSlices) GetHandlers(MsgId uint32) ([]ziface.RouterHandler, bool) {
 	r.RLock()
 	defer r.RUnlock()
-	handlers, ok := r.Apis[MsgId]
-	return handlers, ok
+	if handlers, ok := r.Apis[MsgId]; ok {
+		return handlers, true
+	}
+	if rg, ok := r.rangeApis.find(MsgId); ok {
+		return rg.handlers, true
+	}
+	if r.notFound != nil {
+		return r.notFound, true
+	}
+	return nil, false
 }
 
 func (r *RouterSlices) Group(start, end uint32, Handlers ...ziface.RouterHandler) ziface.IGroupRouterSlices {
 	return NewGroup(start, end, r, Handlers...)
 }
 
+// RouterToHandler converts an IRouter into a RouterHandler, so that the IRouter can be
+// composed with the components of RouterSlices, an Abort in PreHandle or Handle skips its remaining steps
+// (将IRouter转换为RouterHandler，使其可以与切片路由的组件组合使用，在PreHandle或Handle中Abort会跳过其余步骤)
+func RouterToHandler(router ziface.IRouter) ziface.RouterHandler {
+	return func(request ziface.IRequest) {
+		router.PreHandle(request)
+		if aborted(request) {
+			return
+		}
+		router.Handle(request)
+		if aborted(request) {
+			return
+		}
+		router.PostHandle(request)
+	}
+}
+
 type GroupRouter struct {
 	start    uint32
 	end      uint32
@@ -102,6 +203,27 @@ func (g *GroupRouter) Use(Handlers ...ziface.RouterHandler) {
 	g.Handlers = append(g.Handlers, Handlers...)
 }
 
+// Group creates a sub group which shares the msgID range of the current group,
+// the components of the current group are copied into the sub group when it is created.
+// (创建一个子分组，子分组与当前分组共享msgID区间，创建时会继承当前分组已有的组件)
+func (g *GroupRouter) Group(Handlers ...ziface.RouterHandler) ziface.IGroupRouterSlices {
+	sub := &GroupRouter{
+		start:    g.start,
+		end:      g.end,
+		Handlers: make([]ziface.RouterHandler, 0, len(g.Handlers)+len(Handlers)),
+		router:   g.router,
+	}
+	sub.Handlers = append(sub.Handlers, g.Handlers...)
+	sub.Handlers = append(sub.Handlers, Handlers...)
+	return sub
+}
+
+// AddRouter adds an IRouter behind the components of the group
+// (在分组组件之后添加一个IRouter路由)
+func (g *GroupRouter) AddRouter(MsgId uint32, router ziface.IRouter) {
+	g.AddHandler(MsgId, RouterToHandler(router))
+}
+
 func (g *GroupRouter) AddHandler(MsgId uint32, Handlers ...ziface.RouterHandler) {
 	if MsgId < g.start || MsgId > g.end {
 		panic("add router to group err in msgId:" + strconv.Itoa(int(MsgId)))
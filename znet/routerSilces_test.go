@@ -6,6 +6,7 @@ import (
 
 	"github.com/aceld/zinx/zconf"
 	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
 )
 
 func A1(request ziface.IRequest) {
@@ -41,3 +42,56 @@ func TestRouterAdd(t *testing.T) {
 	server.Serve()
 
 }
+
+func TestNestedGroup(t *testing.T) {
+	r := NewRouterSlices()
+	var called []string
+	mark := func(name string) ziface.RouterHandler {
+		return func(request ziface.IRequest) { called = append(called, name) }
+	}
+
+	g := r.Group(100, 200, mark("auth"))
+	sub := g.Group(mark("logging"))
+	sub.AddHandler(101, mark("h"))
+	g.AddHandler(102, mark("g"))
+
+	handlers, ok := r.GetHandlers(101)
+	if !ok {
+		t.Fatal("msgID 101 not found")
+	}
+	for _, h := range handlers {
+		h(nil)
+	}
+	if fmt.Sprint(called) != "[auth logging h]" {
+		t.Errorf("unexpected call order %v", called)
+	}
+
+	handlers, _ = r.GetHandlers(102)
+	if len(handlers) != 2 {
+		t.Errorf("sub group components leaked into parent group, handlers = %d", len(handlers))
+	}
+}
+
+type abortRouter struct {
+	BaseRouter
+	handled, posted bool
+}
+
+func (r *abortRouter) PreHandle(request ziface.IRequest) { request.Abort() }
+
+func (r *abortRouter) Handle(request ziface.IRequest) { r.handled = true }
+
+func (r *abortRouter) PostHandle(request ziface.IRequest) { r.posted = true }
+
+func TestGroupRouterAbort(t *testing.T) {
+	mh := newMsgHandleWithConfig(&zconf.Config{RouterSlicesMode: true})
+	conn := newTestConn(1)
+	conn.msgHandler = mh
+
+	router := &abortRouter{}
+	mh.Group(1, 10).AddRouter(1, router)
+	mh.doMsgHandlerSlices(NewRequest(conn, zpack.NewMsgPackage(1, nil)), 0)
+	if router.handled || router.posted {
+		t.Errorf("Abort in PreHandle does not stop the router, handled = %v, posted = %v", router.handled, router.posted)
+	}
+}
@@ -0,0 +1,85 @@
+package znet
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// routeEpoch counts the requests in flight on the router of an exact MsgID, a new epoch starts whenever the router
+// is replaced and the retired one calls its hook once its last request is done
+// (统计精确MsgID路由上正在处理的请求，每次替换路由时开始新的epoch，旧epoch在其最后一个请求完成后调用回调)
+type routeEpoch struct {
+	msgID     uint32
+	router    ziface.IRouter
+	inflight  int64
+	retired   int32
+	onDrained func(msgID uint32, old ziface.IRouter)
+	once      sync.Once
+}
+
+// acquire counts a request dispatched to the router, it is called under the lock of the routes so that a retired
+// epoch gets no new requests (统计分发给路由的请求，在路由锁内调用，因此已退役的epoch不会再有新请求)
+func (e *routeEpoch) acquire() {
+	if e != nil {
+		atomic.AddInt64(&e.inflight, 1)
+	}
+}
+
+// release counts a request done, whether handled, rejected or panicked (统计完成的请求，无论是处理完成、被拒绝还是panic)
+func (e *routeEpoch) release() {
+	if e != nil && atomic.AddInt64(&e.inflight, -1) == 0 && atomic.LoadInt32(&e.retired) == 1 {
+		e.drained()
+	}
+}
+
+// retire ends the epoch, onDrained is called at once if no request is in flight
+// (结束该epoch，没有正在处理的请求时立即调用onDrained)
+func (e *routeEpoch) retire(onDrained func(msgID uint32, old ziface.IRouter)) {
+	e.onDrained = onDrained
+	atomic.StoreInt32(&e.retired, 1)
+	if atomic.LoadInt64(&e.inflight) == 0 {
+		e.drained()
+	}
+}
+
+func (e *routeEpoch) drained() {
+	e.once.Do(func() {
+		if e.onDrained != nil {
+			e.onDrained(e.msgID, e.router)
+		}
+	})
+}
+
+// setRouterEpoch starts the epoch of router on msgID and returns the previous one, called with apisLock held
+// (在msgID上开始router的epoch并返回之前的epoch，调用时需持有apisLock)
+func (mh *MsgHandle) setRouterEpoch(msgID uint32, router ziface.IRouter) *routeEpoch {
+	if mh.epochs == nil {
+		mh.epochs = make(map[uint32]*routeEpoch)
+	}
+	old := mh.epochs[msgID]
+	if router == nil {
+		delete(mh.epochs, msgID)
+	} else {
+		mh.epochs[msgID] = &routeEpoch{msgID: msgID, router: router}
+	}
+	return old
+}
+
+// ReplaceRouterDrain binds router to msgID like ReplaceRouter, the requests already dispatched to the old router
+// still finish on it while the new ones go to router, onDrained is called with the old router once they are all
+// done, e.g. to unload the old version of a plugin. It is not called when msgID had no router.
+// (像ReplaceRouter一样为msgID绑定router，已分发给旧路由的请求仍由其处理完，新请求交给router，
+// 全部完成后以旧路由调用onDrained，例如卸载旧版本的插件。msgID之前没有路由时不会调用)
+func (mh *MsgHandle) ReplaceRouterDrain(msgID uint32, router ziface.IRouter, onDrained func(msgID uint32, old ziface.IRouter)) {
+	mh.apisLock.Lock()
+	mh.Apis[msgID] = router
+	old := mh.setRouterEpoch(msgID, router)
+	mh.apisLock.Unlock()
+
+	mh.log().InfoF("Replace Router msgID = %d", msgID)
+	if old != nil {
+		old.retire(onDrained)
+	}
+}
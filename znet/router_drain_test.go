@@ -0,0 +1,69 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingRouter struct {
+	BaseRouter
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRouter) Handle(request ziface.IRequest) {
+	close(r.started)
+	<-r.release
+}
+
+type handledRouter struct {
+	BaseRouter
+	handled chan struct{}
+}
+
+func (r *handledRouter) Handle(request ziface.IRequest) {
+	r.handled <- struct{}{}
+}
+
+func TestReplaceRouterDrain(t *testing.T) {
+	mh := newMsgHandle()
+	old := &blockingRouter{started: make(chan struct{}), release: make(chan struct{})}
+	mh.AddRouter(1, old)
+
+	done := make(chan struct{})
+	go func() {
+		mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)), 0)
+		close(done)
+	}()
+	<-old.started
+
+	drained := make(chan ziface.IRouter, 1)
+	next := &handledRouter{handled: make(chan struct{}, 1)}
+	mh.ReplaceRouterDrain(1, next, func(msgID uint32, router ziface.IRouter) {
+		assert.Equal(t, uint32(1), msgID)
+		drained <- router
+	})
+
+	// New requests go to the new router while the old one is still busy
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)), 0)
+	assert.Len(t, next.handled, 1)
+	assert.Len(t, drained, 0)
+
+	close(old.release)
+	<-done
+	select {
+	case router := <-drained:
+		assert.Equal(t, old, router)
+	case <-time.After(time.Second):
+		t.Fatal("onDrained is not called")
+	}
+
+	// Nothing in flight on the replaced router, the hook is called at once
+	called := false
+	mh.ReplaceRouterDrain(1, &handledRouter{}, func(uint32, ziface.IRouter) { called = true })
+	assert.True(t, called)
+}
@@ -0,0 +1,55 @@
+package znet
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// msgIDRange is a router bound to a closed interval of MsgIDs [start, end]
+// (绑定到一个MsgID闭区间[start, end]的路由)
+type msgIDRange struct {
+	start    uint32
+	end      uint32
+	router   ziface.IRouter
+	handlers []ziface.RouterHandler
+}
+
+// msgIDRanges keeps the registered MsgID ranges sorted by start, ranges never overlap
+// so that a MsgID can be resolved with a binary search.
+// (按start排序的MsgID区间集合，区间之间不允许重叠，查找时使用二分查找)
+type msgIDRanges []msgIDRange
+
+// add inserts a new range, it panics if the range is invalid or overlaps an existing one,
+// the same way a repeated msgID panics in AddRouter.
+// (添加一个区间，区间非法或与已有区间重叠时panic，与AddRouter重复注册时的处理保持一致)
+func (rs *msgIDRanges) add(r msgIDRange) {
+	if r.start > r.end {
+		panic(fmt.Sprintf("invalid msgID range [%d, %d]", r.start, r.end))
+	}
+
+	for _, exist := range *rs {
+		if r.start <= exist.end && exist.start <= r.end {
+			panic(fmt.Sprintf("repeated api range, msgID range [%d, %d] overlaps [%d, %d]",
+				r.start, r.end, exist.start, exist.end))
+		}
+	}
+
+	*rs = append(*rs, r)
+	sort.Slice(*rs, func(i, j int) bool {
+		return (*rs)[i].start < (*rs)[j].start
+	})
+}
+
+// find returns the range which contains msgID
+// (查找包含msgID的区间)
+func (rs msgIDRanges) find(msgID uint32) (*msgIDRange, bool) {
+	i := sort.Search(len(rs), func(i int) bool {
+		return rs[i].end >= msgID
+	})
+	if i < len(rs) && rs[i].start <= msgID {
+		return &rs[i], true
+	}
+	return nil, false
+}
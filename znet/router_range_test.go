@@ -0,0 +1,113 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type rangeTestRouter struct {
+	BaseRouter
+	name string
+}
+
+func TestMsgHandleRangeRouter(t *testing.T) {
+	mh := newMsgHandle()
+	exact := &rangeTestRouter{name: "exact"}
+	device := &rangeTestRouter{name: "device"}
+	notFound := &rangeTestRouter{name: "notFound"}
+
+	mh.AddRouter(0x1001, exact)
+	mh.AddRouterRange(0x1000, 0x1FFF, device)
+
+	router, ok := mh.getRouter(0x1001)
+	assert.True(t, ok)
+	assert.Equal(t, exact, router)
+
+	router, ok = mh.getRouter(0x1FFF)
+	assert.True(t, ok)
+	assert.Equal(t, device, router)
+
+	_, ok = mh.getRouter(0x2000)
+	assert.False(t, ok)
+
+	mh.SetNotFoundRouter(notFound)
+	router, ok = mh.getRouter(0x2000)
+	assert.True(t, ok)
+	assert.Equal(t, notFound, router)
+
+	assert.Panics(t, func() {
+		mh.AddRouterRange(0x1F00, 0x2FFF, device)
+	})
+}
+
+func TestRouterSlicesRange(t *testing.T) {
+	r := NewRouterSlices()
+	var called []string
+	r.Use(func(request ziface.IRequest) { called = append(called, "use") })
+	r.AddHandlerRange(10, 20, func(request ziface.IRequest) { called = append(called, "range") })
+	r.NotFound(func(request ziface.IRequest) { called = append(called, "notFound") })
+
+	handlers, ok := r.GetHandlers(15)
+	assert.True(t, ok)
+	for _, h := range handlers {
+		h(nil)
+	}
+	assert.Equal(t, []string{"use", "range"}, called)
+
+	handlers, ok = r.GetHandlers(21)
+	assert.True(t, ok)
+	assert.Len(t, handlers, 2)
+}
+
+func TestMsgHandleReplaceRouter(t *testing.T) {
+	mh := newMsgHandle()
+	v1 := &rangeTestRouter{name: "v1"}
+	v2 := &rangeTestRouter{name: "v2"}
+
+	mh.AddRouter(1, v1)
+	mh.ReplaceRouter(1, v2)
+	router, _ := mh.getRouter(1)
+	assert.Equal(t, v2, router)
+
+	mh.RemoveRouter(1)
+	_, ok := mh.getRouter(1)
+	assert.False(t, ok)
+}
+
+func TestMsgHandleUnorderedRouter(t *testing.T) {
+	mh := newMsgHandle()
+	mh.WorkerPoolSize = 4
+	mh.TaskQueue = make([]chan ziface.IRequest, mh.WorkerPoolSize)
+	for i := range mh.TaskQueue {
+		mh.TaskQueue[i] = make(chan ziface.IRequest, 8)
+	}
+	conn := &workerConn{workerID: 1}
+	mh.SetRouterUnordered(2, true)
+
+	for i := 0; i < 4; i++ {
+		mh.SendMsgToTaskQueue(NewRequest(conn, zpack.NewMsgPackage(1, nil)))
+		mh.SendMsgToTaskQueue(NewRequest(conn, zpack.NewMsgPackage(2, nil)))
+	}
+
+	// Ordered route stays on the worker of the connection, the unordered one spreads over all workers
+	assert.Len(t, mh.TaskQueue[1], 5)
+	for _, id := range []int{0, 2, 3} {
+		assert.Len(t, mh.TaskQueue[id], 1)
+	}
+}
+
+type workerConn struct {
+	ziface.IConnection
+	workerID uint32
+}
+
+func (c *workerConn) GetWorkerID() uint32 { return c.workerID }
+
+func (c *workerConn) GetMsgHandler() ziface.IMsgHandle { return nil }
+
+func (c *workerConn) RemoteAddrString() string { return "" }
+
+func (c *workerConn) GetConnID() uint64 { return uint64(c.workerID) }
@@ -0,0 +1,130 @@
+package znet
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// routeTimeout is the max handler duration of a route and what to do on expiry
+// (路由处理的最长时间，以及超时后的处理)
+type routeTimeout struct {
+	timeout   time.Duration
+	onTimeout ziface.TimeoutHandler
+}
+
+// TimeoutReply returns a TimeoutHandler which replies msgID with data to the client
+// (返回一个超时后向客户端回复msgID和data的TimeoutHandler)
+func TimeoutReply(msgID uint32, data []byte) ziface.TimeoutHandler {
+	return func(request ziface.IRequest) {
+		conn := request.GetConnection()
+		if conn == nil {
+			return
+		}
+		if err := conn.SendMsg(msgID, data); err != nil {
+			LoggerOf(conn).ErrorF("send timeout reply msgID = %d error: %v", msgID, err)
+		}
+	}
+}
+
+// SetRouterTimeout limits the duration of the handler of msgID, on expiry the request context is canceled,
+// onTimeout (e.g. TimeoutReply) is called and the worker moves on to the next request.
+// A timeout of 0 removes the limit.
+// (限制msgID处理函数的执行时长，超时后取消请求的Context，调用onTimeout(例如TimeoutReply)，worker继续处理下一个请求。
+// timeout为0时取消限制)
+func (mh *MsgHandle) SetRouterTimeout(msgID uint32, timeout time.Duration, onTimeout ...ziface.TimeoutHandler) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	if timeout <= 0 {
+		delete(mh.timeouts, msgID)
+		return
+	}
+
+	rt := &routeTimeout{timeout: timeout}
+	if len(onTimeout) > 0 {
+		rt.onTimeout = onTimeout[0]
+	}
+	if mh.timeouts == nil {
+		mh.timeouts = make(map[uint32]*routeTimeout)
+	}
+	mh.timeouts[msgID] = rt
+}
+
+// GetTimeoutCount returns how many requests have timed out (获取处理超时的请求数量)
+func (mh *MsgHandle) GetTimeoutCount() uint64 {
+	return atomic.LoadUint64(&mh.timeoutCount)
+}
+
+func (mh *MsgHandle) getRouteTimeout(msgID uint32) *routeTimeout {
+	mh.apisLock.RLock()
+	defer mh.apisLock.RUnlock()
+	return mh.timeouts[msgID]
+}
+
+// callWithTimeout runs call on the current goroutine if the route has no timeout,
+// otherwise on a new goroutine which the worker waits for at most the route timeout.
+// It returns false if the route timed out, the request is then still in use by the handler, its work is ended
+// and it is recycled by the handler goroutine once call returns, so the caller must do neither.
+// call takes the request so that a method expression such as ziface.IRequest.Call is passed without allocating,
+// epoch, if any, is released once call returns.
+// (路由没有超时限制时直接执行call，否则在新协程中执行，worker最多等待路由超时时长。
+// 返回false表示已超时，此时请求仍被处理函数使用，由处理协程在call返回后结束其工作计数并回收，调用方不能再做这两件事。
+// call接收请求，以便无需分配即可传入ziface.IRequest.Call等方法表达式，call返回后释放epoch(如果有))
+func (mh *MsgHandle) callWithTimeout(request ziface.IRequest, workerID int, call func(ziface.IRequest), epoch *routeEpoch) bool {
+	defer mh.Metrics().ObserveHandler(request.GetMsgID(), time.Now())
+	span := traceHandle(request, workerID)
+	defer span.End()
+	rt := mh.getRouteTimeout(request.GetMsgID())
+	if rt == nil {
+		defer epoch.release()
+		call(request)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(request.GetContext(), rt.timeout)
+	defer cancel()
+	request.SetContext(ctx)
+
+	// settled is closed once the worker knows whether the request timed out, timedOut is written before
+	// (worker确定请求是否超时后关闭settled，timedOut在此之前写入)
+	done, settled := make(chan struct{}), make(chan struct{})
+	timedOut := false
+	go func() {
+		defer func() {
+			<-settled
+			if timedOut {
+				finishRequest(request)
+			}
+		}()
+		defer close(done)
+		defer epoch.release()
+		defer mh.recoverHandler(request, workerID)
+		call(request)
+	}()
+
+	select {
+	case <-done:
+		close(settled)
+		return true
+	case <-ctx.Done():
+		atomic.AddUint64(&mh.timeoutCount, 1)
+		span.SetError(ctx.Err())
+		mh.log().ErrorFX(dispatchLogCtx(request), "workerID: %d api msgID = %d handle timeout after %v", workerID, request.GetMsgID(), rt.timeout)
+		if rt.onTimeout != nil {
+			rt.onTimeout(request)
+		}
+		timedOut = true
+		close(settled)
+		return false
+	}
+}
+
+// finishRequest ends the work of a request whose handler returned after its route timed out and recycles it
+// (路由超时后处理函数才返回的请求，结束其工作计数并回收)
+func finishRequest(request ziface.IRequest) {
+	endWork(request.GetConnection())
+	PutRequest(request)
+}
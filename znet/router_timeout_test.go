@@ -0,0 +1,46 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type slowRouter struct {
+	BaseRouter
+	canceled chan struct{}
+}
+
+func (r *slowRouter) Handle(request ziface.IRequest) {
+	select {
+	case <-request.GetContext().Done():
+		close(r.canceled)
+	case <-time.After(time.Second):
+	}
+}
+
+func TestMsgHandleRouterTimeout(t *testing.T) {
+	mh := newMsgHandle()
+	router := &slowRouter{canceled: make(chan struct{})}
+	mh.AddRouter(1, router)
+
+	timedOut := make(chan struct{}, 1)
+	mh.SetRouterTimeout(1, 20*time.Millisecond, func(request ziface.IRequest) {
+		timedOut <- struct{}{}
+	})
+
+	start := time.Now()
+	mh.doMsgHandler(NewRequest(nil, zpack.NewMsgPackage(1, nil)), 0)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, uint64(1), mh.GetTimeoutCount())
+	assert.Len(t, timedOut, 1)
+
+	select {
+	case <-router.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("request context is not canceled")
+	}
+}
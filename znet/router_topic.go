@@ -0,0 +1,91 @@
+package znet
+
+import (
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zutils"
+)
+
+// topicRoute is a router bound to a topic or a topic pattern
+// (绑定到主题或主题模式的路由)
+type topicRoute struct {
+	pattern  string
+	router   ziface.IRouter
+	handlers []ziface.RouterHandler
+	// levels of a pattern, split once at registration (模式的层级，注册时拆分一次)
+	levels []string
+}
+
+// topicRoutes keeps the topic routers, exact topics are looked up in a map,
+// patterns are matched in registration order.
+// (主题路由集合，精确主题通过map查找，模式主题按注册顺序匹配)
+type topicRoutes struct {
+	exact    map[string]*topicRoute
+	patterns []*topicRoute
+}
+
+// add registers a topic route, it panics if the topic is already registered
+// (注册主题路由，重复注册时panic)
+func (ts *topicRoutes) add(r *topicRoute) {
+	if r.pattern == "" {
+		panic("empty topic")
+	}
+	if ts.exact == nil {
+		ts.exact = make(map[string]*topicRoute)
+	}
+
+	if !zutils.IsTopicPattern(r.pattern) {
+		if _, ok := ts.exact[r.pattern]; ok {
+			panic("repeated api , topic = " + r.pattern)
+		}
+		ts.exact[r.pattern] = r
+		return
+	}
+
+	for _, exist := range ts.patterns {
+		if exist.pattern == r.pattern {
+			panic("repeated api , topic = " + r.pattern)
+		}
+	}
+	r.levels = zutils.SplitTopic(r.pattern)
+	ts.patterns = append(ts.patterns, r)
+}
+
+// find returns the route matching topic, exact topics take precedence over patterns
+// (查找匹配topic的路由，精确主题优先于模式主题)
+func (ts *topicRoutes) find(topic string) (*topicRoute, bool) {
+	if r, ok := ts.exact[topic]; ok {
+		return r, true
+	}
+	if len(ts.patterns) == 0 {
+		return nil, false
+	}
+	levels := zutils.SplitTopic(topic)
+	for _, r := range ts.patterns {
+		if zutils.MatchTopicLevels(r.levels, levels) {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (ts *topicRoutes) empty() bool {
+	return len(ts.exact) == 0 && len(ts.patterns) == 0
+}
+
+// DefaultTopicExtractor takes the topic from messages implementing ziface.ITopicMessage, such as the messages of
+// the JSON envelopes carrying a "topic", or from the ziface.TopicKey attribute set by a decoder or interceptor.
+// (默认的主题提取方法，优先从实现了ITopicMessage的消息中获取，例如携带"topic"的JSON信封的消息，
+// 其次从解码器或拦截器设置的TopicKey属性中获取)
+func DefaultTopicExtractor(request ziface.IRequest) (string, bool) {
+	if msg, ok := request.GetMessage().(ziface.ITopicMessage); ok {
+		if topic := msg.GetTopic(); topic != "" {
+			return topic, true
+		}
+	}
+	if v, ok := request.Get(ziface.TopicKey); ok {
+		if topic, ok := v.(string); ok && topic != "" {
+			return topic, true
+		}
+	}
+	return "", false
+}
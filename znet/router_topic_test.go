@@ -0,0 +1,74 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type topicTestMessage struct {
+	*zpack.Message
+	topic string
+}
+
+func (m *topicTestMessage) GetTopic() string { return m.topic }
+
+func TestMsgHandleTopicRouter(t *testing.T) {
+	mh := newMsgHandle()
+	telemetry := &rangeTestRouter{name: "telemetry"}
+	rpc := &rangeTestRouter{name: "rpc"}
+	login := &rangeTestRouter{name: "login"}
+
+	mh.AddTopicRouter("device/+/telemetry", telemetry)
+	mh.AddTopicRouter("rpc/*", rpc)
+	mh.AddTopicRouter("rpc/user/login", login)
+
+	newReq := func(topic string) ziface.IRequest {
+		return NewRequest(nil, &topicTestMessage{Message: zpack.NewMsgPackage(0, nil), topic: topic})
+	}
+
+	router, ok := mh.getRequestRouter(newReq("device/1001/telemetry"))
+	assert.True(t, ok)
+	assert.Equal(t, telemetry, router)
+
+	router, ok = mh.getRequestRouter(newReq("rpc/user/login"))
+	assert.True(t, ok)
+	assert.Equal(t, login, router)
+
+	router, ok = mh.getRequestRouter(newReq("rpc/user/logout"))
+	assert.True(t, ok)
+	assert.Equal(t, rpc, router)
+
+	_, ok = mh.getRequestRouter(newReq("device/1001/status"))
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		mh.AddTopicRouter("rpc/*", rpc)
+	})
+}
+
+func TestJSONEnvelopeTopicRouter(t *testing.T) {
+	mh := newMsgHandle()
+	telemetry := &rangeTestRouter{name: "telemetry"}
+	mh.AddTopicRouter("device/+/telemetry", telemetry)
+
+	// The topic of a JSON envelope routes the request through the JSON decoder (JSON信封的主题经JSON解码器路由请求)
+	msg := zpack.NewMsgPackage(0, []byte(`{"temp":21}`))
+	msg.SetTopic("device/1001/telemetry")
+	frame, err := zpack.NewDataPackJSONLine().Pack(msg)
+	assert.Nil(t, err)
+
+	capture := &captureInterceptor{}
+	request := NewRequest(nil, zpack.NewMsgPackage(0, frame[:len(frame)-1]))
+	zinterceptor.NewChain([]ziface.IInterceptor{zdecoder.NewJSONLineDecoder(), capture}, 0, request).Proceed(request)
+	assert.Len(t, capture.requests, 1)
+	assert.Equal(t, `{"temp":21}`, string(capture.requests[0].GetData()))
+
+	router, ok := mh.getRequestRouter(capture.requests[0])
+	assert.True(t, ok)
+	assert.Equal(t, telemetry, router)
+}
@@ -0,0 +1,70 @@
+package znet
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/ztimer"
+)
+
+const (
+	scheduledPending int32 = iota
+	scheduledSent
+	scheduledCanceled
+)
+
+// scheduledSend is registered as a close callback of its connection so that it is canceled on close
+// (注册为连接的关闭回调，连接关闭时取消发送)
+type scheduledSend struct {
+	conn  ziface.IConnection
+	tID   uint32
+	state int32
+}
+
+func (s *scheduledSend) Cancel() bool {
+	if !s.stop() {
+		return false
+	}
+	s.conn.RemoveCloseCallback(s, s)
+	return true
+}
+
+// stop cancels the timer, it is also the close callback so it must not touch the callbacks
+// (取消定时器，同时也是关闭回调，因此不能操作回调列表)
+func (s *scheduledSend) stop() bool {
+	if !atomic.CompareAndSwapInt32(&s.state, scheduledPending, scheduledCanceled) {
+		return false
+	}
+	shardScheduler(s.conn).CancelTimer(atomic.LoadUint32(&s.tID))
+	return true
+}
+
+func (s *scheduledSend) fire(msgID uint32, data []byte) {
+	if !atomic.CompareAndSwapInt32(&s.state, scheduledPending, scheduledSent) {
+		return
+	}
+	s.conn.RemoveCloseCallback(s, s)
+	if err := s.conn.SendMsg(msgID, data); err != nil {
+		LoggerOf(s.conn).DebugF("scheduled send msgID = %d err: %v", msgID, err)
+	}
+}
+
+// scheduleSend sends msgID on conn at the given time (在指定时间通过conn发送msgID)
+func scheduleSend(conn ziface.IConnection, at time.Time, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	s := &scheduledSend{conn: conn}
+	df := ztimer.NewDelayFunc(func(v ...interface{}) {
+		s.fire(msgID, data)
+	}, nil)
+
+	conn.AddCloseCallback(s, s, func() {
+		s.stop()
+	})
+	tID, err := shardScheduler(conn).CreateTimerAt(df, at.UnixNano())
+	if err != nil {
+		conn.RemoveCloseCallback(s, s)
+		return nil, err
+	}
+	atomic.StoreUint32(&s.tID, tID)
+	return s, nil
+}
@@ -0,0 +1,61 @@
+package znet
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type callbackConn struct {
+	recordConn
+	callbacks callbacks
+	sync.Mutex
+}
+
+func (c *callbackConn) SendMsg(msgID uint32, data []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	return c.recordConn.SendMsg(msgID, data)
+}
+
+func (c *callbackConn) AddCloseCallback(handler, key interface{}, f func()) {
+	c.Lock()
+	defer c.Unlock()
+	c.callbacks.Add(handler, key, f)
+}
+
+func (c *callbackConn) RemoveCloseCallback(handler, key interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	c.callbacks.Remove(handler, key)
+}
+
+func (c *callbackConn) GetConnID() uint64 {
+	return 0
+}
+
+func (c *callbackConn) sentCount() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.sent)
+}
+
+func TestScheduleSend(t *testing.T) {
+	conn := &callbackConn{}
+
+	sent, err := scheduleSend(conn, time.Now().Add(200*time.Millisecond), 1, []byte("poll"))
+	assert.Nil(t, err)
+	canceled, err := scheduleSend(conn, time.Now().Add(200*time.Millisecond), 2, []byte("never"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, conn.callbacks.Count())
+
+	assert.True(t, canceled.Cancel())
+	assert.False(t, canceled.Cancel())
+
+	assert.Eventually(t, func() bool { return conn.sentCount() == 1 }, 3*time.Second, 50*time.Millisecond)
+	assert.Equal(t, sentMsg{msgID: 1, data: []byte("poll")}, conn.sent[0])
+	assert.False(t, sent.Cancel())
+	assert.Equal(t, 0, conn.callbacks.Count())
+}
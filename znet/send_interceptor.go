@@ -0,0 +1,32 @@
+package znet
+
+import (
+	"errors"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// ErrSendVetoed can be returned by a ziface.SendInterceptor to veto a send
+// (ziface.SendInterceptor可以返回该错误否决本次发送)
+var ErrSendVetoed = errors.New("zinx: send vetoed by interceptor")
+
+// reservedMsgIDStart is the first MsgID reserved for framework messages, e.g. ziface.FragmentMsgID
+// (框架消息保留的起始MsgID，例如ziface.FragmentMsgID)
+const reservedMsgIDStart uint32 = 0xFFFFFFF0
+
+// interceptSend runs the send interceptors over msg in order (按顺序对msg执行发送拦截器)
+func interceptSend(conn ziface.IConnection, interceptors []ziface.SendInterceptor, msg ziface.IMessage) (ziface.IMessage, error) {
+	if msg.GetMsgID() >= reservedMsgIDStart {
+		return msg, nil
+	}
+	for _, interceptor := range interceptors {
+		var err error
+		if msg, err = interceptor(conn, msg); err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			return nil, ErrSendVetoed
+		}
+	}
+	return msg, nil
+}
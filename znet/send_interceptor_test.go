@@ -0,0 +1,42 @@
+package znet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptSend(t *testing.T) {
+	var audited []uint32
+	audit := func(conn ziface.IConnection, msg ziface.IMessage) (ziface.IMessage, error) {
+		audited = append(audited, msg.GetMsgID())
+		return msg, nil
+	}
+	redact := func(conn ziface.IConnection, msg ziface.IMessage) (ziface.IMessage, error) {
+		return zpack.NewMsgPackage(msg.GetMsgID(), bytes.ReplaceAll(msg.GetData(), []byte("secret"), []byte("******"))), nil
+	}
+	veto := func(conn ziface.IConnection, msg ziface.IMessage) (ziface.IMessage, error) {
+		if msg.GetMsgID() == 403 {
+			return nil, ErrSendVetoed
+		}
+		return msg, nil
+	}
+	interceptors := []ziface.SendInterceptor{audit, redact, veto}
+
+	original := zpack.NewMsgPackage(1, []byte(`{"token":"secret"}`))
+	msg, err := interceptSend(nil, interceptors, original)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"token":"******"}`, string(msg.GetData()))
+	assert.Equal(t, `{"token":"secret"}`, string(original.GetData()))
+
+	_, err = interceptSend(nil, interceptors, zpack.NewMsgPackage(403, nil))
+	assert.Equal(t, ErrSendVetoed, err)
+
+	// Framework messages are not intercepted
+	_, err = interceptSend(nil, interceptors, zpack.NewMsgPackage(ziface.FragmentMsgID, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 403}, audited)
+}
@@ -1,29 +1,32 @@
 package znet
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/websocket"
 
-	"github.com/aceld/zinx/logo"
+	"github.com/aceld/zinx/zadmin"
 	"github.com/aceld/zinx/zconf"
-	"github.com/aceld/zinx/zdecoder"
-	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
 
 	"github.com/xtaci/kcp-go"
 
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zpack"
+	"github.com/aceld/zinx/ztls"
 )
 
 // Server interface implementation, defines a Server service class
@@ -42,6 +45,14 @@ type Server struct {
 	// 服务绑定的kcp 端口 (kcp port the server is bound to)
 	KcpPort int
 
+	// The logger of the server and its connections, zlog.Ins() if nil, see WithLogger
+	// (服务及其连接的日志，为nil时使用zlog.Ins()，见WithLogger)
+	logger ziface.ILogger
+
+	// The config of the server, zconf.GlobalObject unless it was created by NewServerWithOptions
+	// (服务的配置，除非通过NewServerWithOptions创建，否则为zconf.GlobalObject)
+	config *zconf.Config
+
 	// Current server's message handler module, used to bind MsgID to corresponding processing methods
 	// (当前Server的消息管理模块，用来绑定MsgID和对应的处理方法)
 	msgHandler ziface.IMsgHandle
@@ -65,14 +76,25 @@ type Server struct {
 	// (数据报文封包方式)
 	packet ziface.IDataPack
 
-	// Asynchronous capture of connection closing status
-	// (异步捕获链接关闭状态)
-	exitChan chan struct{}
+	// The context of the running server, canceled by Stop, see StartContext
+	// (运行中服务的context，由Stop取消，见StartContext)
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopping makes Stop run once per Start (使每次Start后Stop只执行一次)
+	stopping int32
 
 	// Decoder for dealing with message fragmentation and reassembly
 	// (断粘包解码器)
 	decoder ziface.IDecoder
 
+	// Datapack versions selected per connection
+	// (按连接选择的数据包版本)
+	protocols []ziface.Protocol
+
+	// Hooks called with every outbound message before it is packed
+	// (每条发出消息封包前调用的钩子)
+	sendInterceptors []ziface.SendInterceptor
+
 	// Heartbeat checker
 	// (心跳检测器)
 	hc ziface.IHeartbeatChecker
@@ -87,6 +109,69 @@ type Server struct {
 
 	// connection id
 	cID uint64
+	// connIDs allocates the ConnIDs instead of cID, see WithConnIDs (代替cID分配ConnID，见WithConnIDs)
+	connIDs func() uint64
+
+	// Registers onConfigChanged once (只注册一次onConfigChanged)
+	watchConfigOnce sync.Once
+
+	// Stops reporting the worker queues to the metrics (停止向指标上报worker队列)
+	stopMetrics func()
+	// Removes the server from the admin endpoints (从管理端点中移除服务)
+	removeAdmin func()
+
+	// The recorder set by SetRecorder, or opened from CaptureFile (SetRecorder设置的记录器，或根据CaptureFile打开)
+	recorder ziface.IRecorder
+	// Closes the recorder opened from CaptureFile (关闭根据CaptureFile打开的记录器)
+	closeCapture func()
+
+	// The periodic traffic snapshots (定期的流量快照)
+	traffic trafficSnapshots
+	// Stops taking the traffic snapshots (停止获取流量快照)
+	stopTraffic func()
+
+	// The state for the readiness, see GetHealth (用于就绪检查的状态，见GetHealth)
+	state int32
+	// The decode error rate for the readiness (用于就绪检查的解码错误率)
+	decodeErrors decodeErrorRate
+
+	// The metrics of the server, zmetrics.Zinx unless set by WithMetrics (服务的指标，除非通过WithMetrics设置，否则为zmetrics.Zinx)
+	metrics *zmetrics.Metrics
+	// The timer wheels of the shards, stopped with the server (各分片的时间轮，随服务停止)
+	timers *shardTimers
+
+	// The hooks set by WithStatsHook and the functions removing them (WithStatsHook设置的钩子及移除它们的函数)
+	statsHooks       []zmetrics.StatsHook
+	removeStatsHooks []func()
+
+	// The topic subscriptions of the connections (连接的主题订阅)
+	pubSub *pubSub
+
+	// The auth phase set by WithAuth (WithAuth设置的认证阶段)
+	auth *authInterceptor
+	// The nonces of the replay protection, see WithNonceStore (防重放的nonce存储，见WithNonceStore)
+	nonceStore ziface.INonceStore
+	// The TLS config of the listener set by WithTLSConfig (WithTLSConfig设置的监听器TLS配置)
+	tlsConfig *tls.Config
+	// The accept time classification set by WithFingerprint (WithFingerprint设置的接受时分类)
+	fingerprint *fingerprinter
+	// The message signing set by WithMessageSigning (WithMessageSigning设置的消息签名)
+	signing *messageSigning
+	// The listener set by WithListener (WithListener设置的监听器)
+	listener net.Listener
+	// The faults injected into the connections, see SetFaults (注入连接的故障，见SetFaults)
+	faults *faultInjector
+
+	// The plugins of the config and of WithPlugin, see initPlugins (配置与WithPlugin中的插件，见initPlugins)
+	plugins      []ziface.IPlugin
+	pluginsReady bool
+	// Whether the interceptors of the server were added to the chain, see initChain
+	// (服务的拦截器是否已添加到责任链中，见initChain)
+	chainReady bool
+
+	// The error which stopped the server, see Err (使服务停止的错误，见Err)
+	err     error
+	errLock sync.Mutex
 }
 
 type KcpConfig struct {
@@ -125,8 +210,6 @@ type KcpConfig struct {
 // newServerWithConfig creates a server handle based on config
 // (根据config创建一个服务器句柄)
 func newServerWithConfig(config *zconf.Config, ipVersion string, opts ...Option) ziface.IServer {
-	logo.PrintLogo()
-
 	s := &Server{
 		Name:             config.Name,
 		IPVersion:        ipVersion,
@@ -134,15 +217,20 @@ func newServerWithConfig(config *zconf.Config, ipVersion string, opts ...Option)
 		Port:             config.TCPPort,
 		WsPort:           config.WsPort,
 		KcpPort:          config.KcpPort,
-		msgHandler:       newMsgHandle(),
+		config:           config,
+		msgHandler:       newMsgHandleWithConfig(config),
 		RouterSlicesMode: config.RouterSlicesMode,
 		RequestPoolMode:  config.RequestPoolMode,
 		ConnMgr:          newConnManager(),
-		exitChan:         nil,
-		// Default to using Zinx's TLV data pack format
-		// (默认使用zinx的TLV封包方式)
-		packet:  zpack.Factory().NewPack(ziface.ZinxDataPack),
-		decoder: zdecoder.NewTLVDecoder(), // Default to using TLV decode (默认使用TLV的解码方式)
+		pubSub:           newPubSub(),
+		metrics:          zmetrics.Zinx,
+		timers:           newShardTimers(config),
+		faults:           newFaultInjector(config.Faults()),
+		ctx:              context.Background(),
+		// The datapack and decoder of the config, Zinx's TLV format by default
+		// (使用配置中的数据包和解码器，默认使用zinx的TLV格式)
+		packet:  newConfigPacket(config),
+		decoder: newConfigDecoder(config),
 		upgrader: &websocket.Upgrader{
 			ReadBufferSize: int(config.IOReadBuffSize),
 			CheckOrigin: func(r *http.Request) bool {
@@ -162,10 +250,17 @@ func newServerWithConfig(config *zconf.Config, ipVersion string, opts ...Option)
 			KcpFecParityShards: config.KcpFecParityShards,
 		},
 	}
+	if config.HasOwnLog() {
+		s.setLogger(config.NewLogger())
+	}
 
 	for _, opt := range opts {
 		opt(s)
 	}
+	if mh, ok := s.msgHandler.(*MsgHandle); ok {
+		mh.metrics, mh.timers = s.metrics, s.timers
+	}
+	s.pubSub.metrics = s.metrics
 
 	// Display current configuration information
 	// (提示当前配置信息)
@@ -219,6 +314,7 @@ func NewUserConfDefaultRouterSlicesServer(config *zconf.Config, opts ...Option)
 }
 
 func (s *Server) StartConn(conn ziface.IConnection) {
+	span := traceAccept(conn)
 	// HeartBeat check
 	if s.hc != nil {
 		// Clone a heart-beat checker from the server side
@@ -227,97 +323,221 @@ func (s *Server) StartConn(conn ziface.IConnection) {
 		// Bind current connection
 		heartBeatChecker.BindConn(conn)
 	}
+	span.End()
+
+	// Close the connection if it does not authenticate in time (连接未及时认证时将其关闭)
+	if s.auth != nil {
+		s.auth.expire(conn, s.config.AuthTimeoutDuration())
+	}
 
 	// Start processing business for the current connection
-	conn.Start()
+	if starter, ok := conn.(contextStarter); ok {
+		starter.StartContext(s.ctx)
+	} else {
+		conn.Start()
+	}
+}
+
+// contextStarter is implemented by the connections of znet, which run with the context of their server
+// (由znet的连接实现，连接使用其服务的context运行)
+type contextStarter interface {
+	StartContext(ctx context.Context)
 }
 
 func (s *Server) ListenTcpConn() {
-	zlog.Ins().InfoF("[START] TCP Server name: %s,listener at IP: %s, Port %d is starting", s.Name, s.IP, s.Port)
+	s.log().InfoF("[START] TCP Server name: %s,listener at IP: %s, Port %d is starting", s.Name, s.IP, s.Port)
+
+	listener := s.listener
+	if listener == nil {
+		if shards := s.config.Shards; shards > 1 {
+			s.listenTcpShards(shards)
+			return
+		}
+
+		var err error
+		if listener, err = s.listenTcp(false); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+
+	// 3. Start server network connection business
+	go s.acceptTcp(listener, func() uint64 {
+		return s.nextConnID()
+	})
+	select {
+	case <-s.ctx.Done():
+		err := listener.Close()
+		if err != nil {
+			s.log().ErrorF("listener close err: %v", err)
+		}
+	}
+}
+
+// nextConnID allocates the ConnID of a new connection (为新连接分配ConnID)
+func (s *Server) nextConnID() uint64 {
+	if s.connIDs != nil {
+		return s.connIDs()
+	}
+	return atomic.AddUint64(&s.cID, 1)
+}
+
+// listenOptions are the socket options of a listener (监听的套接字选项)
+type listenOptions struct {
+	reusePort   bool
+	backlog     int
+	deferAccept time.Duration
+}
+
+// listenTcp listens to the TCP address of the server, with SO_REUSEPORT set if reusePort is true, the caller
+// decides what a failure means (监听服务器的TCP地址，reusePort为true时设置SO_REUSEPORT，失败时由调用方决定如何处理)
+func (s *Server) listenTcp(reusePort bool) (net.Listener, error) {
 	// 1. Get a TCP address
 	addr, err := net.ResolveTCPAddr(s.IPVersion, fmt.Sprintf("%s:%d", s.IP, s.Port))
 	if err != nil {
-		zlog.Ins().ErrorF("[START] resolve tcp addr err: %v\n", err)
-		return
+		return nil, err
+	}
+
+	// A bad certificate fails like a busy port, before the port is taken (证书错误与端口占用一样失败，且在占用端口之前)
+	tlsConfig, err := s.serverTLSConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	// 2. Listen to the server address
-	var listener net.Listener
-	if zconf.GlobalObject.CertFile != "" && zconf.GlobalObject.PrivateKeyFile != "" {
-		// Read certificate and private key
-		crt, err := tls.LoadX509KeyPair(zconf.GlobalObject.CertFile, zconf.GlobalObject.PrivateKeyFile)
+	listener, err := listenTuned(s.IPVersion, addr.String(), listenOptions{
+		reusePort:   reusePort,
+		backlog:     s.config.ListenBacklog,
+		deferAccept: s.config.TCPDeferAcceptDuration(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	return listener, nil
+}
+
+// serverTLSConfig returns the TLS config of the listener, nil without TLS. The certificate of CertFile is reloaded
+// when the file changes, see CertReloadInterval.
+// (返回监听器的TLS配置，不使用TLS时返回nil。CertFile的证书在文件变化时重新加载，见CertReloadInterval)
+func (s *Server) serverTLSConfig() (*tls.Config, error) {
+	var tlsConfig *tls.Config
+	switch {
+	case s.tlsConfig != nil:
+		tlsConfig = s.tlsConfig.Clone()
+	case s.config.CertFile != "" && s.config.PrivateKeyFile != "":
+		opts := []ztls.Option{ztls.WithReloadInterval(s.config.CertReloadIntervalDuration())}
+		if s.config.OCSPStapling {
+			opts = append(opts, ztls.WithOCSPStapling(nil))
+		}
+		reloader, err := ztls.NewCertReloader(s.config.CertFile, s.config.PrivateKeyFile, opts...)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-
-		// TLS connection
-		tlsConfig := &tls.Config{}
-		tlsConfig.Certificates = []tls.Certificate{crt}
+		tlsConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
 		tlsConfig.Time = time.Now
 		tlsConfig.Rand = rand.Reader
-		listener, err = tls.Listen(s.IPVersion, fmt.Sprintf("%s:%d", s.IP, s.Port), tlsConfig)
+	default:
+		return nil, nil
+	}
+
+	if s.fingerprint != nil {
+		s.fingerprint.wrapTLS(tlsConfig)
+	}
+	// Require the client certificates for the mutual TLS (双向TLS要求客户端证书)
+	if s.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(s.config.ClientCAFile)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-	} else {
-		listener, err = net.ListenTCP(s.IPVersion, addr)
-		if err != nil {
-			panic(err)
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("zinx: no certificate found in %s", s.config.ClientCAFile)
 		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 	}
+	return tlsConfig, nil
+}
 
-	// 3. Start server network connection business
-	go func() {
-		for {
-			// 3.1 Set the maximum connection control for the server. If it exceeds the maximum connection, wait.
-			// (设置服务器最大连接控制,如果超过最大连接，则等待)
-			if s.ConnMgr.Len() >= zconf.GlobalObject.MaxConn {
-				zlog.Ins().InfoF("Exceeded the maxConnNum:%d, Wait:%d", zconf.GlobalObject.MaxConn, AcceptDelay.duration)
-				AcceptDelay.Delay()
-				continue
-			}
-			// 3.2 Block and wait for a client to establish a connection request.
-			// (阻塞等待客户端建立连接请求)
-			conn, err := listener.Accept()
-			if err != nil {
-				//Go 1.17+
-				if errors.Is(err, net.ErrClosed) {
-					zlog.Ins().ErrorF("Listener closed")
-					return
-				}
-				zlog.Ins().ErrorF("Accept err: %v", err)
-				AcceptDelay.Delay()
-				continue
+// acceptTcp accepts the connections of listener until it is closed, nextID allocates the ConnIDs
+// (接受listener上的连接直到其被关闭，nextID分配ConnID)
+func (s *Server) acceptTcp(listener net.Listener, nextID func() uint64) {
+	s.accepting()
+	delay := &acceptDelay{}
+	for {
+		// 3.1 Set the maximum connection control for the server. If it exceeds the maximum connection, wait.
+		// (设置服务器最大连接控制,如果超过最大连接，则等待)
+		// The connections being fingerprinted count, a client stalling them cannot get around MaxConn
+		// (正在进行指纹识别的连接也计入，客户端拖延识别无法绕过MaxConn)
+		if s.ConnMgr.Len()+s.fingerprint.inFlight() >= s.config.Live().MaxConn {
+			s.log().InfoF("Exceeded the maxConnNum:%d, Wait:%d", s.config.Live().MaxConn, delay.get())
+			delay.Delay()
+			continue
+		}
+		// 3.2 Block and wait for a client to establish a connection request.
+		// (阻塞等待客户端建立连接请求)
+		conn, err := listener.Accept()
+		if err != nil {
+			//Go 1.17+
+			if errors.Is(err, net.ErrClosed) {
+				s.log().ErrorF("Listener closed")
+				return
 			}
+			s.log().ErrorF("Accept err: %v", err)
+			delay.Delay()
+			continue
+		}
 
-			AcceptDelay.Reset()
-
-			// 3.4 Handle the business method for this new connection request. At this time, the handler and conn should be bound.
-			// (处理该新连接请求的 业务 方法， 此时应该有 handler 和 conn是绑定的)
-			newCid := atomic.AddUint64(&s.cID, 1)
-			dealConn := newServerConn(s, conn, newCid)
-
-			go s.StartConn(dealConn)
+		delay.Reset()
 
+		// The IPs paused for abuse are refused right away (因滥用被暂停的IP被立即拒绝)
+		if mh, ok := s.msgHandler.(*MsgHandle); ok && mh.sources.refused(conn, mh.Metrics()) {
+			continue
 		}
-	}()
-	select {
-	case <-s.exitChan:
-		err := listener.Close()
-		if err != nil {
-			zlog.Ins().ErrorF("listener close err: %v", err)
+
+		// The fingerprinter reads from the connection, it must not hold up the accept loop (指纹识别需要读取连接，不能阻塞接受循环)
+		if s.fingerprint != nil {
+			atomic.AddInt32(&s.fingerprint.pending, 1)
+			go s.startFingerprinted(conn, nextID())
+			continue
 		}
+
+		// 3.4 Handle the business method for this new connection request. At this time, the handler and conn should be bound.
+		// (处理该新连接请求的 业务 方法， 此时应该有 handler 和 conn是绑定的)
+		dealConn := newServerConn(s, conn, nextID())
+
+		go s.StartConn(dealConn)
+	}
+}
+
+// startFingerprinted starts conn unless the fingerprinter rejects it (除非被指纹识别拒绝，否则启动conn)
+func (s *Server) startFingerprinted(conn net.Conn, connID uint64) {
+	conn, class, ok := s.fingerprint.accept(conn, connID, s.metrics, s.log())
+	if !ok {
+		atomic.AddInt32(&s.fingerprint.pending, -1)
+		return
+	}
+	// Counted by the connection manager from now on (此后由连接管理器计数)
+	dealConn := newServerConn(s, conn, connID)
+	atomic.AddInt32(&s.fingerprint.pending, -1)
+	if class != "" {
+		dealConn.SetProperty(fingerprintKey, class)
 	}
+	s.StartConn(dealConn)
 }
 
 func (s *Server) ListenWebsocketConn() {
-	zlog.Ins().InfoF("[START] WEBSOCKET Server name: %s,listener at IP: %s, Port %d is starting", s.Name, s.IP, s.WsPort)
+	s.log().InfoF("[START] WEBSOCKET Server name: %s,listener at IP: %s, Port %d is starting", s.Name, s.IP, s.WsPort)
+	delay := &acceptDelay{}
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		// 1. Check if the server has reached the maximum allowed number of connections
 		// (设置服务器最大连接控制,如果超过最大连接，则等待)
-		if s.ConnMgr.Len() >= zconf.GlobalObject.MaxConn {
-			zlog.Ins().InfoF("Exceeded the maxConnNum:%d, Wait:%d", zconf.GlobalObject.MaxConn, AcceptDelay.duration)
-			AcceptDelay.Delay()
+		if s.ConnMgr.Len() >= s.config.Live().MaxConn {
+			s.log().InfoF("Exceeded the maxConnNum:%d, Wait:%d", s.config.Live().MaxConn, delay.get())
+			delay.Delay()
 			return
 		}
 		// 2. If websocket authentication is required, set the authentication information
@@ -325,9 +545,9 @@ func (s *Server) ListenWebsocketConn() {
 		if s.websocketAuth != nil {
 			err := s.websocketAuth(r)
 			if err != nil {
-				zlog.Ins().ErrorF(" websocket auth err:%v", err)
+				s.log().ErrorF(" websocket auth err:%v", err)
 				w.WriteHeader(401)
-				AcceptDelay.Delay()
+				delay.Delay()
 				return
 			}
 		}
@@ -340,20 +560,21 @@ func (s *Server) ListenWebsocketConn() {
 		// (升级成 websocket 连接)
 		conn, err := s.upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			zlog.Ins().ErrorF("new websocket err:%v", err)
+			s.log().ErrorF("new websocket err:%v", err)
 			w.WriteHeader(500)
-			AcceptDelay.Delay()
+			delay.Delay()
 			return
 		}
-		AcceptDelay.Reset()
+		delay.Reset()
 		// 5. Handle the business logic of the new connection, which should already be bound to a handler and conn
 		// 5. 处理该新连接请求的 业务 方法， 此时应该有 handler 和 conn是绑定的
-		newCid := atomic.AddUint64(&s.cID, 1)
+		newCid := s.nextConnID()
 		wsConn := newWebsocketConn(s, conn, newCid, r)
 		go s.StartConn(wsConn)
 
 	})
 
+	s.accepting()
 	err := http.ListenAndServe(fmt.Sprintf("%s:%d", s.IP, s.WsPort), nil)
 	if err != nil {
 		panic(err)
@@ -365,35 +586,37 @@ func (s *Server) ListenKcpConn() {
 	// 1. Listen to the server address
 	listener, err := kcp.ListenWithOptions(fmt.Sprintf("%s:%d", s.IP, s.KcpPort), nil, s.kcpConfig.KcpFecDataShards, s.kcpConfig.KcpFecParityShards)
 	if err != nil {
-		zlog.Ins().ErrorF("[START] resolve KCP addr err: %v\n", err)
+		s.fail(err)
 		return
 	}
 
-	zlog.Ins().InfoF("[START] KCP server listening at IP: %s, Port %d, Addr %s", s.IP, s.KcpPort, listener.Addr().String())
+	s.log().InfoF("[START] KCP server listening at IP: %s, Port %d, Addr %s", s.IP, s.KcpPort, listener.Addr().String())
+	s.accepting()
+	delay := &acceptDelay{}
 	// 2. Start server network connection business
 	go func() {
 		for {
 			// 2.1 Set the maximum connection control for the server. If it exceeds the maximum connection, wait.
 			// (设置服务器最大连接控制,如果超过最大连接，则等待)
-			if s.ConnMgr.Len() >= zconf.GlobalObject.MaxConn {
-				zlog.Ins().InfoF("Exceeded the maxConnNum:%d, Wait:%d", zconf.GlobalObject.MaxConn, AcceptDelay.duration)
-				AcceptDelay.Delay()
+			if s.ConnMgr.Len() >= s.config.Live().MaxConn {
+				s.log().InfoF("Exceeded the maxConnNum:%d, Wait:%d", s.config.Live().MaxConn, delay.get())
+				delay.Delay()
 				continue
 			}
 			// 2.2 Block and wait for a client to establish a connection request.
 			// (阻塞等待客户端建立连接请求)
 			conn, err := listener.Accept()
 			if err != nil {
-				zlog.Ins().ErrorF("Accept KCP err: %v", err)
-				AcceptDelay.Delay()
+				s.log().ErrorF("Accept KCP err: %v", err)
+				delay.Delay()
 				continue
 			}
 
-			AcceptDelay.Reset()
+			delay.Reset()
 
 			// 3.4 Handle the business method for this new connection request. At this time, the handler and conn should be bound.
 			// (处理该新连接请求的 业务 方法， 此时应该有 handler 和 conn 是绑定的)
-			newCid := atomic.AddUint64(&s.cID, 1)
+			newCid := s.nextConnID()
 
 			kcpConn := conn.(*kcp.UDPSession)
 			kcpConn.SetACKNoDelay(s.kcpConfig.KcpACKNoDelay)
@@ -407,64 +630,283 @@ func (s *Server) ListenKcpConn() {
 		}
 	}()
 	select {
-	case <-s.exitChan:
+	case <-s.ctx.Done():
 		err := listener.Close()
 		if err != nil {
-			zlog.Ins().ErrorF("KCP listener close err: %v", err)
+			s.log().ErrorF("KCP listener close err: %v", err)
 		}
 	}
 }
 
+// Validate checks the config of the server together with its datapack and decoder, it returns
+// zconf.ConfigErrors listing every problem, or nil. Start panics with this error.
+// (结合数据包和解码器检查服务的配置，返回列出所有问题的zconf.ConfigErrors，没有问题时返回nil。Start会以该错误panic)
+func (s *Server) Validate() error {
+	errs, _ := s.config.Validate().(zconf.ConfigErrors)
+
+	if lengthField := s.GetLengthField(); lengthField != nil && s.config.MaxPacketSize > 0 && lengthField.MaxFrameLength > 0 {
+		if frame := uint64(s.config.MaxPacketSize) + uint64(s.packet.GetHeadLen()); frame > lengthField.MaxFrameLength {
+			errs.Add("MaxPacketSize", "frames of up to %d bytes exceed the MaxFrameLength %d of the decoder", frame, lengthField.MaxFrameLength)
+		}
+	}
+	if _, err := zpack.NewHeaderSchema(s.config.HeaderFields, s.config.HeaderVersion); err != nil {
+		errs.Add("HeaderFields", "%v", err)
+	}
+	if schema := headerSchemaOf(s.config); s.signing != nil && (schema == nil || !schema.Signature) {
+		errs.Add("HeaderFields", "message signing needs the %q field", zpack.HeaderFieldSignature)
+	}
+	if len(s.config.Compression) > 0 && !packetHasFlags(s.packet) {
+		errs.Add("Compression", "needs a datapack carrying flags, e.g. HeaderFields with %q", zpack.HeaderFieldFlags)
+	}
+	if s.config.FragmentMode && s.config.MaxPacketSize > 0 && s.config.MaxPacketSize <= FragmentHeaderLen {
+		errs.Add("MaxPacketSize", "%d leaves no room for data after the %d bytes fragment header", s.config.MaxPacketSize, FragmentHeaderLen)
+	}
+	if len(s.statsHooks) > 0 && s.config.StatsInterval <= 0 {
+		errs.Add("StatsInterval", "must be positive for the stats hooks, got %d", s.config.StatsInterval)
+	}
+	for _, problem := range s.validatePlugins() {
+		errs.Add("Plugins", "%s", problem)
+	}
+	return errs.Err()
+}
+
+// initChain adds the interceptors of the server options and the config to the chain of mh once, a restarted
+// server keeps its chain. Right after the decoder, decompress and then reassemble fragments, fragments are
+// compressed one by one, then apply the rate limits and only let the auth messages of the unauthenticated
+// connections through
+// (将服务选项与配置中的拦截器仅添加一次到mh的责任链中，重启的服务保留其责任链。紧跟在解码器之后先解压再重组分片，
+// 分片是逐个压缩的，之后执行速率限制，并对未认证的连接只放行认证消息)
+func (s *Server) initChain(mh *MsgHandle) {
+	if s.chainReady {
+		return
+	}
+	s.chainReady = true
+
+	mh.sources = newSourceGuard(s.config)
+	if s.auth != nil {
+		mh.builder.prepend(s.auth)
+	}
+	if limiter := newRateLimiter(s.config, mh); limiter != nil {
+		mh.builder.prepend(limiter)
+	}
+	if s.config.FragmentMode {
+		mh.builder.prepend(&fragmentInterceptor{})
+	}
+	if len(s.config.Compression) > 0 {
+		mh.builder.prepend(&compressionInterceptor{packet: s.packet})
+	}
+	// Replays are rejected first, the fragments and the compressed messages carry their own nonces
+	// (最先拒绝重放，分片与压缩消息各自携带nonce)
+	if s.config.ReplayWindow > 0 {
+		mh.builder.prepend(newReplayGuard(s.config.ReplayWindowDuration(), s.nonceStore))
+	}
+	// Even before, so that forged messages cannot fill the nonce store (更早地校验签名，伪造的消息无法占用nonce存储)
+	if s.signing != nil {
+		s.signing.schema = headerSchemaOf(s.config)
+		mh.signing = s.signing
+		mh.builder.prepend(s.signing)
+	}
+}
+
 // Start the network service
 // (开启网络服务)
 func (s *Server) Start() {
-	s.exitChan = make(chan struct{})
+	s.StartContext(context.Background())
+}
+
+// StartContext starts the server, which stops once ctx is done as if Stop was called. The connections and the
+// workers run with contexts derived from it, see Context.
+// (启动服务，ctx结束时服务如同调用了Stop一样停止。连接与worker使用由其派生的context运行，见Context)
+func (s *Server) StartContext(ctx context.Context) {
+	// Fail fast on an invalid config instead of misbehaving at runtime (配置无效时立即失败，而不是在运行时出现异常)
+	if err := s.Validate(); err != nil {
+		panic(err)
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	atomic.StoreInt32(&s.stopping, 0)
+	s.setState(stateStarting)
+	if err := s.initPlugins(); err != nil {
+		panic(err)
+	}
 
 	// Add decoder to interceptors head
 	// (将解码器添加到拦截器最前面)
-	if s.decoder != nil {
+	if len(s.protocols) > 0 {
+		// Every connection is decoded by the decoder of its protocol (每个连接使用其协议的解码器)
+		s.msgHandler.SetHeadInterceptor(&protocolDecoder{fallback: s.decoder})
+	} else if s.decoder != nil {
 		s.msgHandler.SetHeadInterceptor(s.decoder)
 	}
+	if mh, ok := s.msgHandler.(*MsgHandle); ok {
+		// The faults are injected into the frames before the decoder (故障在解码器之前注入帧)
+		mh.faults = s.faults
+		if faults := s.faults.get(); faults.Enabled() {
+			s.log().InfoF("[FAULTS] server %s injects %+v", s.Name, faults)
+		}
+		s.initChain(mh)
+	}
 	// Start worker pool mechanism
 	// (启动worker工作池机制)
-	s.msgHandler.StartWorkerPool()
+	s.msgHandler.StartWorkerPoolContext(s.ctx)
+	if s.config.MetricsAddr != "" {
+		s.metrics.Registry.ListenAndServe(s.config.MetricsAddr)
+	}
+	// The decode errors are counted by the metrics (解码错误由指标统计)
+	if s.config.HealthDecodeErrorRate > 0 {
+		zmetrics.Enable()
+	}
+	s.stopMetrics = collectWorkerQueues(s.metrics, s.Name, s.msgHandler)
+	for _, hook := range s.statsHooks {
+		s.removeStatsHooks = append(s.removeStatsHooks, s.metrics.Registry.AddStatsHook(hook, s.config.StatsIntervalDuration()))
+	}
+	startTracing(s.config, s.log())
+	if s.config.AdminAddr != "" {
+		zadmin.ListenAndServeWithToken(s.config.AdminAddr, s.config.AdminToken)
+	}
+	s.removeAdmin = zadmin.AddServer(s)
+	if s.config.CaptureFile != "" && s.recorder == nil {
+		s.startCapture()
+	}
+	s.stopTraffic = s.startTrafficSnapshots()
+	// Only the global config is hot reloaded (只有全局配置会被热加载)
+	if s.config == zconf.GlobalObject {
+		s.watchConfigOnce.Do(func() {
+			zconf.OnConfigChanged(s.onConfigChanged)
+		})
+	}
+
+	s.startPlugins()
+	s.printStartupReport(os.Stdout)
 
 	// Start a goroutine to handle server listener business
 	// (开启一个go去做服务端Listener业务)
-	switch zconf.GlobalObject.Mode {
+	switch s.config.Mode {
 	case zconf.ServerModeTcp:
 		go s.ListenTcpConn()
 	case zconf.ServerModeWebsocket:
 		go s.ListenWebsocketConn()
 	case zconf.ServerModeKcp:
 		go s.ListenKcpConn()
+	case zconf.ServerModeProxy:
+		go s.ListenProxyConn()
 	default:
 		go s.ListenTcpConn()
 		go s.ListenWebsocketConn()
 	}
 
+	// The server stops with ctx, a server stopped by Stop first leaves it alone (服务随ctx停止，先被Stop停止时不再理会ctx)
+	go func(run context.Context) {
+		<-run.Done()
+		if ctx.Err() != nil {
+			s.Stop()
+		}
+	}(s.ctx)
+}
+
+// onConfigChanged applies the reloaded config which needs the server, see zconf.HotReload
+// (应用需要由服务处理的重新加载的配置，见zconf.HotReload)
+func (s *Server) onConfigChanged(changes []zconf.ConfigChange) {
+	for _, change := range changes {
+		if change.Field == "WorkerPoolSize" && change.Applied {
+			if err := s.msgHandler.ResizeWorkerPool(change.New.(uint32)); err != nil {
+				s.log().ErrorF("resize worker pool to %v err: %v", change.New, err)
+			}
+		}
+	}
 }
 
 // Stop stops the server (停止服务)
 func (s *Server) Stop() {
-	zlog.Ins().InfoF("[STOP] Zinx server , name %s", s.Name)
+	if s.cancel == nil || !atomic.CompareAndSwapInt32(&s.stopping, 0, 1) {
+		return
+	}
+	s.log().InfoF("[STOP] Zinx server , name %s", s.Name)
+	s.setState(stateDraining)
+	defer s.setState(stateStopped)
+	// The plugins stop while the connections are still open (插件在连接关闭前停止)
+	s.stopPlugins()
 
 	// Clear other connection information or other information that needs to be cleaned up
 	// (将其他需要清理的连接信息或者其他信息 也要一并停止或者清理)
 	s.ConnMgr.ClearConn()
-	s.exitChan <- struct{}{}
-	close(s.exitChan)
+	if s.stopMetrics != nil {
+		s.stopMetrics()
+	}
+	for _, remove := range s.removeStatsHooks {
+		remove()
+	}
+	s.removeStatsHooks = nil
+	if s.removeAdmin != nil {
+		s.removeAdmin()
+	}
+	if s.closeCapture != nil {
+		s.closeCapture()
+		s.closeCapture = nil
+	}
+	if s.stopTraffic != nil {
+		s.stopTraffic()
+	}
+	s.timers.stop()
+	s.cancel()
 }
 
-// Serve runs the server (运行服务)
+// fail stops the server which cannot run, e.g. its listener failed to bind, Err returns err and ServeContext returns
+// (停止无法运行的服务，例如其监听绑定失败，Err返回err，ServeContext返回)
+func (s *Server) fail(err error) {
+	s.log().ErrorF("[START] Zinx server , name %s, failed: %v", s.Name, err)
+	s.errLock.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.errLock.Unlock()
+	s.Stop()
+}
+
+// Err returns the error which stopped the server, e.g. its listener failed to bind, nil otherwise
+// (返回使服务停止的错误，例如其监听绑定失败，否则返回nil)
+func (s *Server) Err() error {
+	s.errLock.Lock()
+	defer s.errLock.Unlock()
+	return s.err
+}
+
+// Serve runs the server until SIGINT or SIGTERM (运行服务直到收到SIGINT或SIGTERM)
 func (s *Server) Serve() {
-	s.Start()
+	s.ServeContext(context.Background())
+}
+
+// ServeContext runs the server with ctx until SIGINT or SIGTERM, then drains it for ShutdownDelay and stops it. The
+// server is started with StartContext, so it stops as soon as ctx is done and the requests run with contexts
+// derived from ctx. It also returns once the server stopped otherwise, e.g. its listener failed, see Err.
+// (以ctx运行服务直到收到SIGINT或SIGTERM，之后按ShutdownDelay排空并停止服务。服务以StartContext启动，
+// 因此ctx结束时立即停止，请求的context也由ctx派生。服务因其他原因停止时(例如监听失败，见Err)同样返回)
+func (s *Server) ServeContext(ctx context.Context) {
+	s.StartContext(ctx)
+	run := s.ctx
 	// Block, otherwise the listener's goroutine will exit when the main Go exits (阻塞,否则主Go退出， listenner的go将会退出)
 	c := make(chan os.Signal, 1)
 	// Listen for specified signals: ctrl+c or kill signal (监听指定信号 ctrl+c kill信号)
 	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-c
-	zlog.Ins().InfoF("[SERVE] Zinx server , name %s, Serve Interrupt, signal = %v", s.Name, sig)
+	defer signal.Stop(c)
+	select {
+	case sig := <-c:
+		s.log().InfoF("[SERVE] Zinx server , name %s, Serve Interrupt, signal = %v", s.Name, sig)
+	case <-ctx.Done():
+		// The requests are already canceled with ctx, there is nothing to drain (请求已随ctx取消，无需排空)
+		s.log().InfoF("[SERVE] Zinx server , name %s, Serve Interrupt, %v", s.Name, ctx.Err())
+		s.Stop()
+		return
+	case <-run.Done():
+		s.log().InfoF("[SERVE] Zinx server , name %s, stopped", s.Name)
+		return
+	}
+	s.drain()
+	s.Stop()
+}
+
+// Context returns the context of the server, canceled when it stops (返回服务的context，服务停止时取消)
+func (s *Server) Context() context.Context {
+	return s.ctx
 }
 
 func (s *Server) AddRouter(msgID uint32, router ziface.IRouter) {
@@ -481,6 +923,135 @@ func (s *Server) AddRouterSlices(msgID uint32, router ...ziface.RouterHandler) z
 	return s.msgHandler.AddRouterSlices(msgID, router...)
 }
 
+func (s *Server) RemoveRouter(msgID uint32) {
+	if s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is true ")
+	}
+	s.msgHandler.RemoveRouter(msgID)
+}
+
+func (s *Server) ReplaceRouter(msgID uint32, router ziface.IRouter) {
+	if s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is true ")
+	}
+	s.msgHandler.ReplaceRouter(msgID, router)
+}
+
+func (s *Server) ReplaceRouterDrain(msgID uint32, router ziface.IRouter, onDrained func(msgID uint32, old ziface.IRouter)) {
+	if s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is true ")
+	}
+	s.msgHandler.ReplaceRouterDrain(msgID, router, onDrained)
+}
+
+func (s *Server) RemoveRouterSlices(msgID uint32) {
+	if !s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is false ")
+	}
+	s.msgHandler.RemoveRouterSlices(msgID)
+}
+
+func (s *Server) ReplaceRouterSlices(msgID uint32, router ...ziface.RouterHandler) ziface.IRouterSlices {
+	if !s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is false ")
+	}
+	return s.msgHandler.ReplaceRouterSlices(msgID, router...)
+}
+
+func (s *Server) AddRouterRange(start, end uint32, router ziface.IRouter) {
+	if s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is true ")
+	}
+	s.msgHandler.AddRouterRange(start, end, router)
+}
+
+func (s *Server) AddRouterSlicesRange(start, end uint32, router ...ziface.RouterHandler) ziface.IRouterSlices {
+	if !s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is false ")
+	}
+	return s.msgHandler.AddRouterSlicesRange(start, end, router...)
+}
+
+func (s *Server) SetNotFoundRouter(router ziface.IRouter) {
+	if s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is true ")
+	}
+	s.msgHandler.SetNotFoundRouter(router)
+}
+
+func (s *Server) SetNotFoundRouterSlices(router ...ziface.RouterHandler) {
+	if !s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is false ")
+	}
+	s.msgHandler.SetNotFoundRouterSlices(router...)
+}
+
+func (s *Server) AddTopicRouter(topic string, router ziface.IRouter) {
+	if s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is true ")
+	}
+	s.msgHandler.AddTopicRouter(topic, router)
+}
+
+func (s *Server) AddTopicRouterSlices(topic string, router ...ziface.RouterHandler) ziface.IRouterSlices {
+	if !s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is false ")
+	}
+	return s.msgHandler.AddTopicRouterSlices(topic, router...)
+}
+
+func (s *Server) SetTopicExtractor(extractor ziface.TopicExtractor) {
+	s.msgHandler.SetTopicExtractor(extractor)
+}
+
+func (s *Server) SetRouterTimeout(msgID uint32, timeout time.Duration, onTimeout ...ziface.TimeoutHandler) {
+	s.msgHandler.SetRouterTimeout(msgID, timeout, onTimeout...)
+}
+
+func (s *Server) SetRouterUnordered(msgID uint32, unordered bool) {
+	s.msgHandler.SetRouterUnordered(msgID, unordered)
+}
+
+func (s *Server) AddWorkerPool(name string, size uint32, queueLen uint32) {
+	s.msgHandler.AddWorkerPool(name, size, queueLen)
+}
+
+func (s *Server) SetRouterPool(msgID uint32, name string) {
+	s.msgHandler.SetRouterPool(msgID, name)
+}
+
+func (s *Server) AddValidator(msgID uint32, validators ...ziface.Validator) {
+	s.msgHandler.AddValidator(msgID, validators...)
+}
+
+func (s *Server) SetValidationFailedHandler(handler ziface.ValidationFailedHandler) {
+	s.msgHandler.SetValidationFailedHandler(handler)
+}
+
+func (s *Server) SetForbiddenHandler(handler ziface.ValidationFailedHandler) {
+	s.msgHandler.SetForbiddenHandler(handler)
+}
+
+func (s *Server) SetRouterPriority(msgID uint32, priority int) {
+	s.msgHandler.SetRouterPriority(msgID, priority)
+}
+
+func (s *Server) SetOnQueueFull(handler ziface.QueueFullHandler) {
+	s.msgHandler.SetOnQueueFull(handler)
+}
+
+func (s *Server) SetRouterRateLimit(msgID uint32, limit ziface.RateLimit) {
+	s.msgHandler.SetRouterRateLimit(msgID, limit)
+}
+
+func (s *Server) SetOnRateLimited(handler ziface.RateLimitHandler) {
+	s.msgHandler.SetOnRateLimited(handler)
+}
+
+func (s *Server) SetOnHandlerPanic(handler ziface.PanicHandler) {
+	s.msgHandler.SetOnHandlerPanic(handler)
+}
+
 func (s *Server) Group(start, end uint32, Handlers ...ziface.RouterHandler) ziface.IGroupRouterSlices {
 	if !s.RouterSlicesMode {
 		panic("Server RouterSlicesMode is false")
@@ -488,6 +1059,13 @@ func (s *Server) Group(start, end uint32, Handlers ...ziface.RouterHandler) zifa
 	return s.msgHandler.Group(start, end, Handlers...)
 }
 
+func (s *Server) RouterGroup(Handlers ...ziface.RouterHandler) ziface.IGroupRouterSlices {
+	if !s.RouterSlicesMode {
+		panic("Server RouterSlicesMode is false")
+	}
+	return s.msgHandler.RouterGroup(Handlers...)
+}
+
 func (s *Server) Use(Handlers ...ziface.RouterHandler) ziface.IRouterSlices {
 	if !s.RouterSlicesMode {
 		panic("Server RouterSlicesMode is false")
@@ -520,6 +1098,7 @@ func (s *Server) GetPacket() ziface.IDataPack {
 }
 
 func (s *Server) SetPacket(packet ziface.IDataPack) {
+	bindPacket(packet, s.config)
 	s.packet = packet
 }
 
@@ -593,6 +1172,25 @@ func (s *Server) GetLengthField() *ziface.LengthField {
 	return nil
 }
 
+func (s *Server) AddProtocol(protocol ziface.Protocol) {
+	if protocol.Name == "" || (len(protocol.Hello) == 0 && protocol.Match == nil) {
+		panic("protocol needs a name and a Hello or a Match")
+	}
+	s.protocols = append(s.protocols, protocol)
+}
+
+func (s *Server) GetProtocols() []ziface.Protocol {
+	return s.protocols
+}
+
+func (s *Server) AddSendInterceptor(interceptors ...ziface.SendInterceptor) {
+	s.sendInterceptors = append(s.sendInterceptors, interceptors...)
+}
+
+func (s *Server) GetSendInterceptors() []ziface.SendInterceptor {
+	return s.sendInterceptors
+}
+
 func (s *Server) AddInterceptor(interceptor ziface.IInterceptor) {
 	s.msgHandler.AddInterceptor(interceptor)
 }
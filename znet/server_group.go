@@ -0,0 +1,246 @@
+package znet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// ServerGroup runs several servers of one process together, e.g. a TCP game port, a websocket port for the browsers
+// and a KCP port, starting and stopping them at once and aggregating their stats. The servers should have their own
+// configs, see NewScopedServerGroup and NewServerWithOptions, NewUserConfServer overwrites the global config shared
+// by the others.
+// (在一个进程中一起运行多个服务，例如TCP游戏端口、浏览器使用的websocket端口和KCP端口，统一启动与停止并汇总其统计数据。
+// 各服务应使用自己的配置，见NewScopedServerGroup与NewServerWithOptions，NewUserConfServer会覆盖其他服务共享的全局配置)
+type ServerGroup struct {
+	mu      sync.Mutex
+	servers []ziface.IServer
+	started bool
+
+	sharedWorkers bool
+}
+
+// GroupOption configures a ServerGroup (配置ServerGroup)
+type GroupOption func(g *ServerGroup)
+
+// WithSharedWorkerPool runs the requests of every server on the worker pool of the first one, instead of a pool per
+// server sized for its peak. The routes keep their own servers, dedicated pools, priorities and queue full policies.
+// It panics on Start unless every server assigns the workers by WorkerModeHash.
+// (所有服务的请求都在第一个服务的worker池中运行，而不是每个服务各自按峰值配置一个池。路由仍使用各自服务的专属池、优先级与
+// 队列满策略。除非所有服务都使用WorkerModeHash分配worker，否则Start时panic)
+func WithSharedWorkerPool() GroupOption {
+	return func(g *ServerGroup) {
+		g.sharedWorkers = true
+	}
+}
+
+// NewServerGroup creates a group of servers (创建一组服务)
+func NewServerGroup(servers []ziface.IServer, opts ...GroupOption) *ServerGroup {
+	g := &ServerGroup{}
+	for _, opt := range opts {
+		opt(g)
+	}
+	for _, s := range servers {
+		g.Add(s)
+	}
+	return g
+}
+
+// NewScopedServerGroup creates a group of the server instances of zconf.GlobalObject.Servers, see NewScopedServer
+// (创建由zconf.GlobalObject.Servers中的服务实例组成的组，见NewScopedServer)
+func NewScopedServerGroup(opts ...GroupOption) *ServerGroup {
+	g := NewServerGroup(nil, opts...)
+	for _, name := range zconf.GlobalObject.ScopeNames() {
+		g.Add(NewScopedServer(name))
+	}
+	return g
+}
+
+// Add adds a server to the group before it starts, it panics if the group has started or the name is taken
+// (在组启动之前添加服务，组已启动或名称已被使用时panic)
+func (g *ServerGroup) Add(s ziface.IServer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.started {
+		panic("zinx: server added to a started group")
+	}
+	for _, other := range g.servers {
+		if other.ServerName() == s.ServerName() {
+			panic(fmt.Sprintf("zinx: server %q is already in the group", s.ServerName()))
+		}
+	}
+	g.servers = append(g.servers, s)
+}
+
+// Servers returns the servers of the group in the order they were added (按添加顺序返回组内的服务)
+func (g *ServerGroup) Servers() []ziface.IServer {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]ziface.IServer(nil), g.servers...)
+}
+
+// Server returns the server named name, nil if there is none (返回名为name的服务，不存在时返回nil)
+func (g *ServerGroup) Server(name string) ziface.IServer {
+	for _, s := range g.Servers() {
+		if s.ServerName() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Start starts the servers (启动所有服务)
+func (g *ServerGroup) Start() {
+	g.StartContext(context.Background())
+}
+
+// StartContext starts the servers, which stop once ctx is done (启动所有服务，ctx结束时服务停止)
+func (g *ServerGroup) StartContext(ctx context.Context) {
+	g.mu.Lock()
+	if g.started {
+		g.mu.Unlock()
+		return
+	}
+	g.started = true
+	servers := append([]ziface.IServer(nil), g.servers...)
+	g.mu.Unlock()
+
+	if g.sharedWorkers && len(servers) > 1 {
+		shareWorkerPool(servers)
+	}
+	for _, s := range servers {
+		s.StartContext(ctx)
+	}
+	zlog.Ins().InfoF("[START] server group of %d servers", len(servers))
+}
+
+// Stop stops the servers at the same time (同时停止所有服务)
+func (g *ServerGroup) Stop() {
+	g.mu.Lock()
+	if !g.started {
+		g.mu.Unlock()
+		return
+	}
+	g.started = false
+	servers := append([]ziface.IServer(nil), g.servers...)
+	g.mu.Unlock()
+
+	g.each(servers, ziface.IServer.Stop)
+}
+
+// Serve runs the servers until SIGINT or SIGTERM, then drains them for their ShutdownDelay and stops them
+// (运行所有服务直到收到SIGINT或SIGTERM，之后按各自的ShutdownDelay排空并停止)
+func (g *ServerGroup) Serve() {
+	g.ServeContext(context.Background())
+}
+
+// ServeContext runs the servers until ctx is done or SIGINT or SIGTERM, then drains and stops them like Serve
+// (运行所有服务直到ctx结束或收到SIGINT或SIGTERM，之后像Serve一样排空并停止)
+func (g *ServerGroup) ServeContext(ctx context.Context) {
+	g.Start()
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(c)
+	select {
+	case sig := <-c:
+		zlog.Ins().InfoF("[SERVE] server group, Serve Interrupt, signal = %v", sig)
+	case <-ctx.Done():
+		zlog.Ins().InfoF("[SERVE] server group, Serve Interrupt, %v", ctx.Err())
+	}
+	g.each(g.Servers(), func(s ziface.IServer) {
+		if s, ok := s.(*Server); ok {
+			s.drain()
+		}
+	})
+	g.Stop()
+}
+
+// each calls f with the servers concurrently and waits for it (并发地对每个服务调用f并等待完成)
+func (g *ServerGroup) each(servers []ziface.IServer, f func(s ziface.IServer)) {
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s ziface.IServer) {
+			defer wg.Done()
+			f(s)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// GroupStats aggregates the servers of a group (汇总组内的服务)
+type GroupStats struct {
+	// Ready is true when every server is ready (所有服务都就绪时为true)
+	Ready       bool                  `json:"ready"`
+	Connections int                   `json:"connections"`
+	Dropped     uint64                `json:"dropped"` // Requests dropped on full task queues (因任务队列已满被丢弃的请求数)
+	Traffic     ziface.TrafficStats   `json:"traffic"` // Of the current connections, as of the latest snapshots (当前连接的流量，截至最近一次快照)
+	Servers     []ziface.HealthStatus `json:"servers"`
+}
+
+// Stats returns the aggregated stats of the servers (返回所有服务汇总的统计数据)
+func (g *ServerGroup) Stats() GroupStats {
+	stats := GroupStats{Ready: true}
+	for _, s := range g.Servers() {
+		health := s.GetHealth()
+		stats.Ready = stats.Ready && health.Ready
+		stats.Servers = append(stats.Servers, health)
+		stats.Connections += s.GetConnMgr().Len()
+		if mh := s.GetMsgHandler(); mh != nil {
+			stats.Dropped += mh.GetDropCount()
+		}
+		for _, conn := range s.GetTrafficSnapshot().Connections {
+			stats.Traffic.BytesIn += conn.Total.BytesIn
+			stats.Traffic.BytesOut += conn.Total.BytesOut
+			stats.Traffic.MsgsIn += conn.Total.MsgsIn
+			stats.Traffic.MsgsOut += conn.Total.MsgsOut
+		}
+	}
+	return stats
+}
+
+// shareWorkerPool lets the first server run the requests of the others, see WithSharedWorkerPool
+// (让第一个服务运行其他服务的请求，见WithSharedWorkerPool)
+func shareWorkerPool(servers []ziface.IServer) {
+	var owner *MsgHandle
+	for _, s := range servers {
+		mh, ok := s.GetMsgHandler().(*MsgHandle)
+		if !ok {
+			panic(fmt.Sprintf("zinx: server %q cannot share a worker pool", s.ServerName()))
+		}
+		if !isHashWorkerMode(mh.config.WorkerMode) {
+			panic(fmt.Sprintf("zinx: server %q of WorkerMode %q cannot share a worker pool", s.ServerName(), mh.config.WorkerMode))
+		}
+		if owner == nil {
+			owner = mh
+			continue
+		}
+		mh.workers = owner
+	}
+}
+
+// pool returns the handler owning the worker pool running the requests of mh (返回运行mh请求的worker池所属的处理器)
+func (mh *MsgHandle) pool() *MsgHandle {
+	if mh.workers != nil {
+		return mh.workers
+	}
+	return mh
+}
+
+// ownerOf returns the handler of the server of request, which is not mh when mh shares its worker pool
+// (返回request所属服务的处理器，mh共享其worker池时不是mh)
+func ownerOf(mh *MsgHandle, request ziface.IRequest) *MsgHandle {
+	if conn := request.GetConnection(); conn != nil {
+		if owner, ok := conn.GetMsgHandler().(*MsgHandle); ok && owner.workers == mh {
+			return owner
+		}
+	}
+	return mh
+}
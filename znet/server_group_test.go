@@ -0,0 +1,86 @@
+package znet
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type groupEchoRouter struct {
+	BaseRouter
+}
+
+func (r *groupEchoRouter) Handle(request ziface.IRequest) {
+	_ = request.GetConnection().SendMsg(request.GetMsgID()+100, request.GetData())
+}
+
+func newGroupServer(t *testing.T, name string, opts ...ServerOption) (ziface.IServer, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	opts = append(opts, WithName(name), WithWorkerPool(2, 16), WithServerOptions(WithListener(listener)))
+	s := NewServerWithOptions(opts...)
+	return s, listener.Addr().String()
+}
+
+func groupRoundTrip(t *testing.T, addr string, msgID uint32) {
+	conn, err := net.Dial("tcp", addr)
+	assert.Nil(t, err)
+	defer conn.Close()
+	dp := zpack.NewDataPack()
+	data, _ := dp.Pack(zpack.NewMsgPackage(msgID, []byte("ping")))
+	_, err = conn.Write(data)
+	assert.Nil(t, err)
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	head := make([]byte, dp.GetHeadLen())
+	_, err = io.ReadFull(conn, head)
+	assert.Nil(t, err)
+	msg, err := dp.Unpack(head)
+	assert.Nil(t, err)
+	assert.Equal(t, msgID+100, msg.GetMsgID())
+}
+
+func TestServerGroup(t *testing.T) {
+	game, gameAddr := newGroupServer(t, "game")
+	chat, chatAddr := newGroupServer(t, "chat", WithWorkerPool(0, 0))
+	game.AddRouter(1, &groupEchoRouter{})
+	chat.AddRouter(2, &groupEchoRouter{})
+
+	g := NewServerGroup([]ziface.IServer{game, chat}, WithSharedWorkerPool())
+	assert.Panics(t, func() { g.Add(NewServerWithOptions(WithName("game"))) })
+	assert.Equal(t, chat, g.Server("chat"))
+	assert.Nil(t, g.Server("admin"))
+
+	g.Start()
+	defer g.Stop()
+	assert.Panics(t, func() { g.Add(NewServerWithOptions(WithName("admin"))) })
+	groupRoundTrip(t, gameAddr, 1)
+	groupRoundTrip(t, chatAddr, 2)
+
+	// Both requests ran on the workers of the first server (两个请求都在第一个服务的worker中运行)
+	var handled uint64
+	for _, w := range game.GetMsgHandler().GetWorkerStats() {
+		handled += w.Handled
+	}
+	assert.Equal(t, uint64(2), handled)
+	assert.Empty(t, chat.GetMsgHandler().GetWorkerStats())
+	assert.Equal(t, uint32(2), chat.GetMsgHandler().GetWorkerPoolSize())
+
+	stats := g.Stats()
+	assert.True(t, stats.Ready)
+	assert.Len(t, stats.Servers, 2)
+	assert.Equal(t, "chat", stats.Servers[1].Server)
+}
+
+func TestServerGroupSharedWorkerMode(t *testing.T) {
+	game, _ := newGroupServer(t, "game")
+	chat, _ := newGroupServer(t, "chat", WithWorkerMode(zconf.WorkerModeBind))
+	g := NewServerGroup([]ziface.IServer{game, chat}, WithSharedWorkerPool())
+	assert.Panics(t, g.Start)
+}
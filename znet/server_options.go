@@ -0,0 +1,161 @@
+package znet
+
+import (
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
+
+// ServerOption configures a server created by NewServerWithOptions
+// (配置NewServerWithOptions创建的服务)
+type ServerOption func(o *serverOptions)
+
+type serverOptions struct {
+	config *zconf.Config
+	opts   []Option
+}
+
+// NewServerWithOptions creates a server with its own config, starting from zconf.DefaultConfig, so neither the config
+// file nor zconf.GlobalObject are used, e.g.
+// znet.NewServerWithOptions(znet.WithPort(8999), znet.WithWorkerPool(8, 256), znet.WithTLS("server.crt", "server.key")).
+// Several servers configured differently can run in one process, with their own MaxPacketSize, RequestPoolMode and
+// logger when their log settings differ from the global config, see WithLogger. The config is not hot reloaded.
+// (使用服务自己的配置创建服务，配置从zconf.DefaultConfig开始，不使用配置文件和zconf.GlobalObject。
+// 一个进程中可以运行多个配置不同的服务，各自使用自己的MaxPacketSize、RequestPoolMode，日志设置与全局配置不同时使用自己的日志，
+// 见WithLogger。配置不会被热加载)
+func NewServerWithOptions(opts ...ServerOption) ziface.IServer {
+	o := &serverOptions{config: zconf.DefaultConfig()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s := newServerWithConfig(o.config, "tcp", o.opts...)
+	if o.config.RouterSlicesMode {
+		s.Use(RouterRecovery)
+	}
+	return s
+}
+
+// GetConfig returns the config of the server (返回服务的配置)
+func (s *Server) GetConfig() *zconf.Config {
+	return s.config
+}
+
+// WithConfig edits the config directly, for the fields without a dedicated option
+// (直接修改配置，用于没有专门选项的字段)
+func WithConfig(edit func(config *zconf.Config)) ServerOption {
+	return func(o *serverOptions) {
+		edit(o.config)
+	}
+}
+
+// WithServerOptions applies the options of NewServer, e.g. WithPacket, once the server is created
+// (服务创建后应用NewServer的选项，例如WithPacket)
+func WithServerOptions(opts ...Option) ServerOption {
+	return func(o *serverOptions) {
+		o.opts = append(o.opts, opts...)
+	}
+}
+
+// WithName sets the name of the server (设置服务名称)
+func WithName(name string) ServerOption {
+	return func(o *serverOptions) {
+		o.config.Name = name
+	}
+}
+
+// WithHost sets the IP address the server listens on (设置服务监听的IP地址)
+func WithHost(host string) ServerOption {
+	return func(o *serverOptions) {
+		o.config.Host = host
+	}
+}
+
+// WithPort sets the TCP port (设置TCP端口)
+func WithPort(port int) ServerOption {
+	return func(o *serverOptions) {
+		o.config.TCPPort = port
+	}
+}
+
+// WithWsPort sets the websocket port (设置websocket端口)
+func WithWsPort(port int) ServerOption {
+	return func(o *serverOptions) {
+		o.config.WsPort = port
+	}
+}
+
+// WithKcpPort sets the KCP port (设置KCP端口)
+func WithKcpPort(port int) ServerOption {
+	return func(o *serverOptions) {
+		o.config.KcpPort = port
+	}
+}
+
+// WithMode sets the listening mode, see zconf.ServerMode* (设置监听模式，见zconf.ServerMode*)
+func WithMode(mode string) ServerOption {
+	return func(o *serverOptions) {
+		o.config.Mode = mode
+	}
+}
+
+// WithMaxConn sets the maximum number of connections (设置最大连接数)
+func WithMaxConn(maxConn int) ServerOption {
+	return func(o *serverOptions) {
+		o.config.MaxConn = maxConn
+	}
+}
+
+// WithMaxPacketSize sets the maximum size of a packet (设置数据包的最大长度)
+func WithMaxPacketSize(size uint32) ServerOption {
+	return func(o *serverOptions) {
+		o.config.MaxPacketSize = size
+	}
+}
+
+// WithWorkerPool sets the number of workers and the length of their task queues, size 0 disables the worker pool
+// (设置worker数量及其任务队列长度，size为0时不使用worker池)
+func WithWorkerPool(size, taskLen uint32) ServerOption {
+	return func(o *serverOptions) {
+		o.config.WorkerPoolSize = size
+		o.config.MaxWorkerTaskLen = taskLen
+	}
+}
+
+// WithWorkerMode sets how connections are assigned to workers, see zconf.WorkerMode*
+// (设置为连接分配worker的方式，见zconf.WorkerMode*)
+func WithWorkerMode(mode string) ServerOption {
+	return func(o *serverOptions) {
+		o.config.WorkerMode = mode
+	}
+}
+
+// WithHeartbeatMax sets the maximum heartbeat interval in seconds (设置最长心跳间隔，单位秒)
+func WithHeartbeatMax(seconds int) ServerOption {
+	return func(o *serverOptions) {
+		o.config.HeartbeatMax = seconds
+	}
+}
+
+// WithTLS enables TLS with the certificate and private key files (使用证书和私钥文件开启TLS)
+func WithTLS(certFile, privateKeyFile string) ServerOption {
+	return func(o *serverOptions) {
+		o.config.CertFile = certFile
+		o.config.PrivateKeyFile = privateKeyFile
+	}
+}
+
+// WithDataPack sets the datapack, one of the ziface.ZinxDataPack* kinds, and the matching decoder
+// (设置数据包及与之匹配的解码器，取值为ziface.ZinxDataPack*之一)
+func WithDataPack(kind string) ServerOption {
+	return func(o *serverOptions) {
+		o.config.DataPack = kind
+	}
+}
+
+// WithRouterSlices uses the router slices mode with a RouterRecovery handler
+// (使用RouterSlices路由模式，并带有RouterRecovery处理器)
+func WithRouterSlices() ServerOption {
+	return func(o *serverOptions) {
+		o.config.RouterSlicesMode = true
+	}
+}
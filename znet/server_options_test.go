@@ -0,0 +1,139 @@
+package znet
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type echoRouter struct {
+	BaseRouter
+}
+
+func (r *echoRouter) Handle(request ziface.IRequest) {
+	_ = request.GetConnection().SendMsg(request.GetMsgID(), request.GetData())
+}
+
+func TestNewServerWithOptions(t *testing.T) {
+	globalPool := zconf.GlobalObject.WorkerPoolSize
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.Nil(t, listener.Close())
+
+	s1 := NewServerWithOptions(WithName("s1"), WithHost("127.0.0.1"), WithPort(port),
+		WithMode(zconf.ServerModeTcp), WithWorkerPool(2, 8))
+	s2 := NewServerWithOptions(WithName("s2"), WithWorkerPool(4, 8), WithConfig(func(c *zconf.Config) {
+		c.MaxConn = 3
+	}))
+
+	assert.Equal(t, uint32(2), s1.GetMsgHandler().GetWorkerPoolSize())
+	assert.Equal(t, uint32(4), s2.GetMsgHandler().GetWorkerPoolSize())
+	assert.Equal(t, 3, s2.(*Server).GetConfig().MaxConn)
+	assert.NotSame(t, s1.(*Server).GetConfig(), s2.(*Server).GetConfig())
+	assert.Equal(t, globalPool, zconf.GlobalObject.WorkerPoolSize)
+
+	s1.AddRouter(1, &echoRouter{})
+	s1.Start()
+	defer s1.Stop()
+
+	var conn net.Conn
+	assert.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer conn.Close()
+
+	dp := zpack.NewDataPack()
+	frame, err := dp.Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	assert.Nil(t, err)
+	_, err = conn.Write(frame)
+	assert.Nil(t, err)
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	reply := make([]byte, int(dp.GetHeadLen())+4)
+	_, err = io.ReadFull(conn, reply)
+	assert.Nil(t, err)
+	assert.Equal(t, "ping", string(reply[dp.GetHeadLen():]))
+}
+
+func TestServerValidate(t *testing.T) {
+	assert.Nil(t, NewServerWithOptions().(*Server).Validate())
+
+	s := NewServerWithOptions(WithPort(70000), WithWorkerPool(4, 0), WithMaxPacketSize(1024), WithConfig(func(c *zconf.Config) {
+		c.Compression = []string{"gzip"}
+	}), WithServerOptions(func(s *Server) {
+		s.SetDecoder(zdecoder.NewHTLVCRCDecoder())
+	}))
+	err := s.(*Server).Validate()
+	errs, ok := err.(zconf.ConfigErrors)
+	assert.True(t, ok)
+	var fields []string
+	for _, p := range errs {
+		fields = append(fields, p.Field)
+	}
+	assert.Equal(t, []string{"TCPPort", "MaxWorkerTaskLen", "MaxPacketSize", "Compression"}, fields)
+	assert.Panics(t, s.Start)
+}
+
+func TestNewScopedServer(t *testing.T) {
+	saved := zconf.GlobalObject.Servers
+	defer func() { zconf.GlobalObject.Servers = saved }()
+	zconf.GlobalObject.Servers = map[string]json.RawMessage{
+		"game": json.RawMessage(`{"TCPPort": 9100, "MaxPacketSize": 8192}`),
+		"chat": json.RawMessage(`{"DataPack": "zinx_pack_json_line"}`),
+		"raw":  json.RawMessage(`{"Decoder": "none"}`),
+	}
+
+	game := NewScopedServer("game").(*Server)
+	assert.Equal(t, "game", game.Name)
+	assert.Equal(t, 9100, game.Port)
+	assert.Equal(t, uint32(8192), game.GetConfig().MaxPacketSize)
+	assert.NotEqual(t, uint32(8192), zconf.GlobalObject.MaxPacketSize)
+	assert.IsType(t, zpack.NewDataPack(), game.GetPacket())
+	assert.IsType(t, zdecoder.NewTLVDecoder(), game.decoder)
+
+	chat := NewScopedServer("chat").(*Server)
+	assert.IsType(t, zpack.NewDataPackJSONLine(), chat.GetPacket())
+	assert.IsType(t, zdecoder.NewJSONLineDecoder(), chat.decoder)
+
+	assert.Nil(t, NewScopedServer("raw").(*Server).decoder)
+	assert.Panics(t, func() { NewScopedServer("missing") })
+}
+
+func TestNewClientWithConfig(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.FragmentMode = true
+	config.MaxPacketSize = 16
+	c := NewClientWithConfig(config, "127.0.0.1", 1).(*Client)
+	mh := c.msgHandler.(*MsgHandle)
+
+	// The client uses its own config, not zconf.GlobalObject (客户端使用自己的配置，而不是zconf.GlobalObject)
+	assert.Same(t, config, mh.config)
+	assert.Equal(t, uint32(0), mh.WorkerPoolSize)
+	conn := &Connection{connID: 1, connIdStr: "1", msgHandler: mh}
+	assert.Same(t, config, configOf(conn))
+	assert.Same(t, c.timers, mh.timers)
+
+	// The fragments are numbered by their connection (分片按其连接编号)
+	var ids []uint32
+	handled, err := sendFragments(conn, zpack.NewMsgPackage(7, make([]byte, 10)), func(fragment ziface.IMessage) error {
+		ids = append(ids, binary.LittleEndian.Uint32(fragment.GetData()[4:8]))
+		return nil
+	})
+	assert.True(t, handled)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 1, 1}, ids)
+	assert.Equal(t, uint32(1), (&Connection{}).nextFragmentID())
+}
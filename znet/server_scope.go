@@ -0,0 +1,104 @@
+package znet
+
+import (
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+)
+
+// NewScopedServer creates the server instance name of zconf.GlobalObject.Servers, its config is the global
+// one overridden by the settings of the instance, see zconf.Config.Scope. It panics if there is no such instance.
+// Like NewServerWithOptions, the scoped config is not hot reloaded.
+// (创建zconf.GlobalObject.Servers中名为name的服务实例，其配置为被实例设置覆盖的全局配置，见zconf.Config.Scope。
+// 实例不存在时panic。与NewServerWithOptions一样，作用域配置不会被热加载)
+func NewScopedServer(name string, opts ...Option) ziface.IServer {
+	config, err := zconf.GlobalObject.Scope(name)
+	if err != nil {
+		panic(err)
+	}
+	s := newServerWithConfig(config, "tcp", opts...)
+	if config.RouterSlicesMode {
+		s.Use(RouterRecovery)
+	}
+	return s
+}
+
+// headerSchemaOf returns the schema of the optional header fields of config, nil if there is none or they are invalid
+// (返回config中可选头部字段的格式，没有或无效时返回nil)
+func headerSchemaOf(config *zconf.Config) *zpack.HeaderSchema {
+	if len(config.HeaderFields) == 0 {
+		return nil
+	}
+	schema, _ := zpack.NewHeaderSchema(config.HeaderFields, config.HeaderVersion)
+	return schema
+}
+
+// newConfigPacket creates the datapack selected by config.DataPack, bounded by config.MaxPacketSize
+// (创建config.DataPack选择的数据包，以config.MaxPacketSize为上限)
+func newConfigPacket(config *zconf.Config) ziface.IDataPack {
+	var packet ziface.IDataPack
+	switch config.DataPack {
+	case "", ziface.ZinxDataPack:
+		if schema := headerSchemaOf(config); schema != nil {
+			packet = zpack.NewDataPackSchema(schema)
+		} else {
+			packet = zpack.NewDataPack()
+		}
+	default:
+		packet = zpack.Factory().NewPack(config.DataPack)
+	}
+	bindPacket(packet, config)
+	return packet
+}
+
+// bindPacket bounds packet by the MaxPacketSize of config, following its hot reloads
+// (以config的MaxPacketSize限制packet，并跟随其热加载)
+func bindPacket(packet ziface.IDataPack, config *zconf.Config) {
+	if binder, ok := packet.(zpack.ConfigBinder); ok {
+		binder.BindConfig(config)
+	}
+}
+
+// newConfigDecoder creates the decoder selected by config.Decoder, or the one matching config.DataPack
+// (创建config.Decoder选择的解码器，或与config.DataPack匹配的解码器)
+func newConfigDecoder(config *zconf.Config) ziface.IDecoder {
+	decoder := config.Decoder
+	if decoder == "" {
+		switch config.DataPack {
+		case ziface.ZinxDataPackOld:
+			decoder = zconf.DecoderLTV
+		case ziface.ZinxDataPackTrace:
+			decoder = zconf.DecoderTrace
+		case ziface.ZinxDataPackProtobuf:
+			decoder = zconf.DecoderProtobuf
+		case ziface.ZinxDataPackJSON:
+			decoder = zconf.DecoderJSON
+		case ziface.ZinxDataPackJSONLine:
+			decoder = zconf.DecoderJSONLine
+		default:
+			decoder = zconf.DecoderTLV
+		}
+	}
+
+	switch decoder {
+	case zconf.DecoderLTV:
+		return zdecoder.NewLTV_Little_Decoder()
+	case zconf.DecoderHTLVCRC:
+		return zdecoder.NewHTLVCRCDecoder()
+	case zconf.DecoderTrace:
+		return zdecoder.NewTraceTLVDecoder()
+	case zconf.DecoderJSON:
+		return zdecoder.NewJSONDecoder()
+	case zconf.DecoderJSONLine:
+		return zdecoder.NewJSONLineDecoder()
+	case zconf.DecoderProtobuf:
+		return zdecoder.NewProtobufDecoder()
+	case zconf.DecoderNone:
+		return nil
+	}
+	if schema := headerSchemaOf(config); schema != nil {
+		return zdecoder.NewSchemaTLVDecoder(schema)
+	}
+	return zdecoder.NewTLVDecoder()
+}
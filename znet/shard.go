@@ -0,0 +1,161 @@
+package znet
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/ztimer"
+)
+
+// ShardOf returns the shard a connection belongs to when zconf.GlobalObject.Shards is set,
+// the ConnIDs of shard i are i modulo Shards. See Server.ShardOf for the servers with their own config.
+// (设置了zconf.GlobalObject.Shards时返回连接所属的分片，分片i的ConnID对Shards取模等于i。使用自身配置的服务见Server.ShardOf)
+func ShardOf(connID uint64) int {
+	return shardOf(zconf.GlobalObject, connID)
+}
+
+// ShardOf returns the shard a connection of the server belongs to when the Shards of its config is set
+// (服务配置了Shards时返回其连接所属的分片)
+func (s *Server) ShardOf(connID uint64) int {
+	return shardOf(s.config, connID)
+}
+
+func shardOf(config *zconf.Config, connID uint64) int {
+	if shards := config.Shards; shards > 1 {
+		return int(connID % uint64(shards))
+	}
+	return 0
+}
+
+// listenTcpShards runs one listener and accept loop per shard, the ConnIDs of shard i are allocated
+// as i + k*shards, which keeps the connections of a shard on its own workers
+// (每个分片运行独立的监听和Accept循环，分片i的ConnID按i + k*shards分配，使分片的连接只使用自己的worker)
+func (s *Server) listenTcpShards(shards int) {
+	if isHashWorkerMode(s.config.WorkerMode) && s.msgHandler.GetWorkerPoolSize()%uint32(shards) != 0 {
+		panic(fmt.Sprintf("WorkerPoolSize %d must be a multiple of Shards %d", s.msgHandler.GetWorkerPoolSize(), shards))
+	}
+
+	listeners := make([]net.Listener, 0, shards)
+	for i := 0; i < shards; i++ {
+		if !reusePortSupported && i > 0 {
+			// The shards share the listener but keep their own accept loop (各分片共享监听，但保留独立的Accept循环)
+			listeners = append(listeners, listeners[0])
+			continue
+		}
+		listener, err := s.listenTcp(true)
+		if err != nil && i > 0 {
+			// The shard shares the listener of the first one, e.g. SO_REUSEPORT is refused
+			// (该分片共享第一个分片的监听，例如SO_REUSEPORT被拒绝)
+			s.log().ErrorF("[START] shard %d listen tcp err: %v, sharing the listener of shard 0", i, err)
+			listeners = append(listeners, listeners[0])
+			continue
+		}
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		listeners = append(listeners, listener)
+	}
+
+	for i, listener := range listeners {
+		shard, seq := uint64(i), new(uint64)
+		go s.acceptTcp(listener, func() uint64 {
+			return atomic.AddUint64(seq, 1)*uint64(shards) + shard
+		})
+	}
+	s.log().InfoF("[START] TCP Server name: %s, %d shards are accepting", s.Name, shards)
+
+	<-s.ctx.Done()
+	closed := make(map[net.Listener]struct{})
+	for _, listener := range listeners {
+		if _, ok := closed[listener]; ok {
+			continue
+		}
+		closed[listener] = struct{}{}
+		if err := listener.Close(); err != nil {
+			s.log().ErrorF("listener close err: %v", err)
+		}
+	}
+}
+
+// shardTimers holds the timer wheels of the shards of a server or a client, they are started on first use and
+// stopped with the server (保存服务或客户端各分片的时间轮，首次使用时启动，随服务停止)
+type shardTimers struct {
+	config     *zconf.Config
+	lock       sync.Mutex
+	schedulers []*ztimer.TimerScheduler
+}
+
+func newShardTimers(config *zconf.Config) *shardTimers {
+	return &shardTimers{config: config}
+}
+
+// defaultTimers are the wheels of the connections without a server or a client, e.g. in the tests
+// (没有服务或客户端的连接使用的时间轮，例如测试中)
+var defaultTimers = newShardTimers(zconf.GlobalObject)
+
+// get returns the wheel of the shard of connID (返回connID所属分片的时间轮)
+func (t *shardTimers) get(connID uint64) *ztimer.TimerScheduler {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.schedulers == nil {
+		n := t.config.Shards
+		if n < 1 {
+			n = 1
+		}
+		timers := timerConfig(t.config)
+		t.schedulers = make([]*ztimer.TimerScheduler, n)
+		for i := range t.schedulers {
+			t.schedulers[i] = ztimer.NewAutoExecTimerSchedulerWithConfig(timers)
+		}
+	}
+	return t.schedulers[int(connID%uint64(len(t.schedulers)))]
+}
+
+// stop stops the wheels, a restarted server starts new ones (停止时间轮，重启的服务会启动新的时间轮)
+func (t *shardTimers) stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, scheduler := range t.schedulers {
+		scheduler.Stop()
+	}
+	t.schedulers = nil
+}
+
+// timerConfig returns the wheels configured by TimerTick, TimerScales and TimerLevels
+// (返回TimerTick、TimerScales和TimerLevels配置的时间轮)
+func timerConfig(config *zconf.Config) ztimer.SchedulerConfig {
+	timers := ztimer.DefaultSchedulerConfig()
+	if config.TimerTick <= 0 {
+		return timers
+	}
+	scales, levels := config.TimerScales, config.TimerLevels
+	if scales <= 0 {
+		scales = 60
+	}
+	if levels <= 0 {
+		levels = 3
+	}
+	timers.Levels = ztimer.UniformLevels(time.Duration(config.TimerTick)*time.Millisecond, scales, levels)
+	timers.Precision = 0
+	return timers
+}
+
+// shardScheduler returns the timer wheel of the shard of conn, from the wheels of the server or the client owning it
+// (从conn所属服务或客户端的时间轮中返回conn所属分片的时间轮)
+func shardScheduler(conn ziface.IConnection) *ztimer.TimerScheduler {
+	timers := defaultTimers
+	var connID uint64
+	if conn != nil {
+		if mh, ok := conn.GetMsgHandler().(*MsgHandle); ok && mh.timers != nil {
+			timers = mh.timers
+		}
+		connID = conn.GetConnID()
+	}
+	return timers.get(connID)
+}
@@ -0,0 +1,123 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/zpack"
+	"github.com/aceld/zinx/ztimer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerShards(t *testing.T) {
+	zconf.GlobalObject.Shards = 2
+	defer func() { zconf.GlobalObject.Shards = 0 }()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.Nil(t, listener.Close())
+
+	s := &Server{
+		Name:       "shards",
+		IPVersion:  "tcp",
+		IP:         "127.0.0.1",
+		Port:       port,
+		config:     zconf.GlobalObject,
+		msgHandler: newMsgHandle(),
+		ConnMgr:    newConnManager(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	s.msgHandler.(*MsgHandle).WorkerPoolSize = 3
+	assert.Panics(t, func() { s.listenTcpShards(2) })
+	s.msgHandler.(*MsgHandle).WorkerPoolSize = 4
+	go s.listenTcpShards(2)
+	defer cancel()
+
+	var conns []net.Conn
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			return false
+		}
+		conns = append(conns, conn)
+		return len(conns) == 4
+	}, 2*time.Second, 10*time.Millisecond)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+
+	assert.Eventually(t, func() bool { return s.ConnMgr.Len() == 4 }, 2*time.Second, 10*time.Millisecond)
+	for _, id := range s.ConnMgr.GetAllConnID() {
+		assert.True(t, id >= 2)
+		assert.Equal(t, int(id%2), ShardOf(id))
+	}
+}
+
+func TestScopedShards(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.Shards = 4
+	config.MaxPacketSize = 16
+	s := newServerWithConfig(config, "tcp", WithPacket(zpack.NewDataPack())).(*Server)
+
+	// The shards and the packet limit are the ones of the server, not of the global config
+	assert.Equal(t, 3, s.ShardOf(7))
+	assert.Equal(t, 0, ShardOf(7))
+	head, _ := s.GetPacket().Pack(zpack.NewMsgPackage(1, make([]byte, 32)))
+	_, err := s.GetPacket().Unpack(head[:s.GetPacket().GetHeadLen()])
+	assert.True(t, errors.Is(err, zerrors.ErrFrameTooLong))
+
+	conn := &Connection{connID: 5, connIdStr: "5", msgHandler: s.msgHandler}
+	assert.NotSame(t, shardScheduler(conn), shardScheduler(&Connection{connID: 6, connIdStr: "6", msgHandler: s.msgHandler}))
+	assert.Len(t, s.timers.schedulers, 4)
+	assert.Same(t, shardScheduler(conn), s.timers.schedulers[1])
+
+	// The wheels are stopped with the server (时间轮随服务停止)
+	wheel := shardScheduler(conn)
+	s.timers.stop()
+	assert.Nil(t, s.timers.schedulers)
+	_, err = wheel.CreateTimerAfter(ztimer.NewDelayFunc(func(...interface{}) {}, nil), time.Second)
+	assert.Equal(t, ztimer.ErrSchedulerStopped, err)
+	assert.NotSame(t, wheel, shardScheduler(conn))
+	s.timers.stop()
+}
+
+func TestListenTcpError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	s := &Server{
+		Name:       "busy",
+		IPVersion:  "tcp",
+		IP:         "127.0.0.1",
+		Port:       listener.Addr().(*net.TCPAddr).Port,
+		config:     zconf.GlobalObject,
+		msgHandler: newMsgHandle(),
+		ConnMgr:    newConnManager(),
+		ctx:        context.Background(),
+	}
+	// The port in use is reported instead of panicking (端口被占用时返回错误而不是panic)
+	_, err = s.listenTcp(false)
+	assert.Error(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		s.listenTcpShards(2)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the shards kept running without a listener")
+	}
+}
@@ -0,0 +1,184 @@
+package znet
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+)
+
+// Reasons of zinx_signature_rejected_total (zinx_signature_rejected_total的拒绝原因)
+const (
+	signatureMissing = "missing" // The message carries no header fields (消息不携带头部字段)
+	signatureNoKey   = "no_key"  // No signer was found for the connection (未找到连接的签名器)
+	signatureInvalid = "invalid" // The signature does not match the message (签名与消息不符)
+)
+
+// ErrVerifyOnly is returned by the signers holding a public key only (仅持有公钥的签名器返回的错误)
+var ErrVerifyOnly = errors.New("zinx signature: the signer only verifies")
+
+// hmacSigner signs with HMAC-SHA512 (使用HMAC-SHA512签名)
+type hmacSigner struct {
+	key []byte
+}
+
+// HMACSigner signs the messages with HMAC-SHA512 of key, shared by both ends
+// (使用key的HMAC-SHA512对消息签名，两端共享同一密钥)
+func HMACSigner(key []byte) ziface.IMessageSigner {
+	return &hmacSigner{key: key}
+}
+
+func (s *hmacSigner) Sign(payload []byte) ([]byte, error) {
+	mac := hmac.New(sha512.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+func (s *hmacSigner) Verify(payload, signature []byte) bool {
+	expected, _ := s.Sign(payload)
+	return hmac.Equal(expected, signature)
+}
+
+// ed25519Signer signs with an Ed25519 private key, it only verifies without one
+// (使用Ed25519私钥签名，没有私钥时仅校验)
+type ed25519Signer struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// Ed25519Signer signs the messages with key and verifies them with its public key
+// (使用key对消息签名，并使用其公钥校验)
+func Ed25519Signer(key ed25519.PrivateKey) ziface.IMessageSigner {
+	return &ed25519Signer{private: key, public: key.Public().(ed25519.PublicKey)}
+}
+
+// Ed25519Verifier verifies the messages signed by the private key of key, e.g. by a client
+// (校验由key对应私钥签名的消息，例如客户端签名的消息)
+func Ed25519Verifier(key ed25519.PublicKey) ziface.IMessageSigner {
+	return &ed25519Signer{public: key}
+}
+
+func (s *ed25519Signer) Sign(payload []byte) ([]byte, error) {
+	if s.private == nil {
+		return nil, ErrVerifyOnly
+	}
+	return ed25519.Sign(s.private, payload), nil
+}
+
+func (s *ed25519Signer) Verify(payload, signature []byte) bool {
+	return len(signature) == ed25519.SignatureSize && ed25519.Verify(s.public, payload, signature)
+}
+
+// messageSigning signs the outbound messages and rejects the inbound ones whose signature is invalid, it runs
+// right after the decoder so that a tampered message reaches neither the other interceptors nor the handlers
+// (对出站消息签名并拒绝签名无效的入站消息，紧跟在解码器之后执行，被篡改的消息既不会到达其他拦截器也不会到达处理器)
+type messageSigning struct {
+	schema *zpack.HeaderSchema
+	lookup ziface.SignerLookup
+	// The MsgIDs accepted unsigned, e.g. the auth messages before the key is known
+	// (无需签名即可接受的MsgID，例如密钥确定之前的认证消息)
+	unsigned map[uint32]struct{}
+}
+
+func newMessageSigning(lookup ziface.SignerLookup, unsignedMsgIDs []uint32) *messageSigning {
+	if lookup == nil {
+		panic("zinx: message signing needs a SignerLookup")
+	}
+	unsigned := make(map[uint32]struct{}, len(unsignedMsgIDs))
+	for _, msgID := range unsignedMsgIDs {
+		unsigned[msgID] = struct{}{}
+	}
+	return &messageSigning{lookup: lookup, unsigned: unsigned}
+}
+
+func (ms *messageSigning) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetConnection() == nil {
+		return chain.Proceed(chain.Request())
+	}
+	if _, ok := ms.unsigned[request.GetMsgID()]; ok {
+		return chain.Proceed(chain.Request())
+	}
+
+	if reason := ms.check(request); reason != "" {
+		conn := request.GetConnection()
+		metricsOf(conn).SignatureRejected.With(reason).Inc()
+		if ctx, ok := signatureLog.Allow(requestLogCtx(request)); ok {
+			LoggerOf(conn).ErrorFX(ctx, "connID = %d reject msgID = %d by its signature: %s", conn.GetConnID(), request.GetMsgID(), reason)
+		}
+		zlog.Security(requestLogCtx(request), zlog.SecurityTampered, reason)
+		reportAbuse(conn, ziface.AbuseBadSignature)
+		PutRequest(request)
+		return nil
+	}
+	return chain.Proceed(chain.Request())
+}
+
+// check returns why the request is rejected, "" if it is accepted (返回请求被拒绝的原因，接受时返回"")
+func (ms *messageSigning) check(request ziface.IRequest) string {
+	hm, ok := request.GetMessage().(ziface.IHeaderMessage)
+	if !ok {
+		return signatureMissing
+	}
+	signer, err := ms.lookup(request.GetConnection())
+	if err != nil || signer == nil {
+		return signatureNoKey
+	}
+	if !signer.Verify(ms.schema.SigningPayload(hm), hm.GetSignature()) {
+		return signatureInvalid
+	}
+	return ""
+}
+
+// sign returns a copy of msg signed with the key of conn, the timestamp and nonce covered by the signature are
+// set first (返回使用conn的密钥签名的msg副本，先设置签名覆盖的时间戳与nonce)
+func (ms *messageSigning) sign(conn ziface.IConnection, msg ziface.IMessage) (ziface.IMessage, error) {
+	if _, ok := ms.unsigned[msg.GetMsgID()]; ok {
+		return msg, nil
+	}
+	signer, err := ms.lookup(conn)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("zinx signature: no signer for connID = %d", conn.GetConnID())
+	}
+
+	signed := cloneMessage(msg, msg.GetData())
+	if signed.GetTimestamp() == 0 {
+		signed.SetTimestamp(time.Now().UnixMilli())
+	}
+	if signed.GetNonce() == 0 {
+		signed.SetNonce(zpack.NextNonce())
+	}
+	signature, err := signer.Sign(ms.schema.SigningPayload(signed))
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) > zpack.SignatureLen {
+		return nil, fmt.Errorf("zinx signature: %d bytes signature longer than %d", len(signature), zpack.SignatureLen)
+	}
+	signed.SetSignature(signature)
+	return signed, nil
+}
+
+// sealMessage compresses msg and then signs it for conn, as the receiver checks the signature before
+// decompressing, it is the last step before packing
+// (压缩msg后为conn签名，接收方在解压之前校验签名，这是封包前的最后一步)
+func sealMessage(conn ziface.IConnection, msg ziface.IMessage) (ziface.IMessage, error) {
+	msg = compressMessage(conn, msg)
+	mh, ok := conn.GetMsgHandler().(*MsgHandle)
+	if !ok {
+		return msg, nil
+	}
+	if mh.signing != nil {
+		return mh.signing.sign(conn, msg)
+	}
+	return msg, nil
+}
@@ -0,0 +1,91 @@
+package znet
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageSigning(t *testing.T) {
+	schema, _ := zpack.NewHeaderSchema([]string{zpack.HeaderFieldTimestamp, zpack.HeaderFieldNonce, zpack.HeaderFieldSignature}, 0)
+	dp := zpack.NewDataPackSchema(schema)
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	sender := newMessageSigning(func(ziface.IConnection) (ziface.IMessageSigner, error) { return Ed25519Signer(private), nil }, []uint32{9})
+	sender.schema = schema
+	receiver := newMessageSigning(func(conn ziface.IConnection) (ziface.IMessageSigner, error) {
+		if _, err := conn.GetProperty("tenant"); err != nil {
+			return nil, errors.New("unknown tenant")
+		}
+		return Ed25519Verifier(public), nil
+	}, []uint32{9})
+	receiver.schema = schema
+
+	conn := newAuthConn()
+	conn.SetProperty("tenant", "a")
+	capture := &captureInterceptor{}
+	receive := func(conn ziface.IConnection, msg ziface.IMessage, tamper bool) {
+		buf, err := dp.Pack(msg)
+		assert.Nil(t, err)
+		if tamper {
+			buf[len(buf)-1] ^= 1
+		}
+		decoded, err := schema.Decode(buf)
+		assert.Nil(t, err)
+		request := NewRequest(conn, decoded)
+		zinterceptor.NewChain([]ziface.IInterceptor{receiver, capture}, 0, request).Proceed(request)
+	}
+
+	original := zpack.NewMsgPackage(1, []byte("transfer"))
+	signed, err := sender.sign(conn, original)
+	assert.Nil(t, err)
+	// The message of the caller is left as it is (调用方的消息保持不变)
+	assert.Nil(t, original.GetSignature())
+	assert.Zero(t, original.GetNonce())
+
+	receive(conn, signed, false)
+	assert.Len(t, capture.requests, 1)
+
+	receive(conn, signed, true)                               // Tampered (被篡改)
+	receive(conn, zpack.NewMsgPackage(1, []byte("x")), false) // Unsigned (未签名)
+	receive(newAuthConn(), signed, false)                     // No key (没有密钥)
+	assert.Len(t, capture.requests, 1)
+
+	// The unsigned MsgIDs are neither signed nor checked (无需签名的MsgID既不签名也不校验)
+	unsigned, err := sender.sign(conn, zpack.NewMsgPackage(9, nil))
+	assert.Nil(t, err)
+	receive(newAuthConn(), unsigned, false)
+	assert.Len(t, capture.requests, 2)
+
+	_, err = Ed25519Verifier(public).Sign(nil)
+	assert.Equal(t, ErrVerifyOnly, err)
+}
+
+func TestHMACSigner(t *testing.T) {
+	signer := HMACSigner([]byte("secret"))
+	signature, err := signer.Sign([]byte("payload"))
+	assert.Nil(t, err)
+	assert.Len(t, signature, zpack.SignatureLen)
+	assert.True(t, signer.Verify([]byte("payload"), signature))
+	assert.False(t, signer.Verify([]byte("payload!"), signature))
+	assert.False(t, HMACSigner([]byte("other")).Verify([]byte("payload"), signature))
+}
+
+func TestMessageSigningValidate(t *testing.T) {
+	config := *zconf.GlobalObject
+	config.HeaderFields = []string{zpack.HeaderFieldNonce}
+	s := NewUserConfServer(&config, WithMessageSigning(func(ziface.IConnection) (ziface.IMessageSigner, error) {
+		return nil, nil
+	})).(*Server)
+	assert.NotNil(t, s.Validate())
+	s.config.HeaderFields = append(s.config.HeaderFields, zpack.HeaderFieldSignature)
+	assert.Nil(t, s.Validate())
+}
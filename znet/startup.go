@@ -0,0 +1,135 @@
+package znet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"github.com/aceld/zinx/zconf"
+)
+
+// StartupReport describes the effective setup of a server: its listeners, the subsystems enabled and the resolved
+// config, printed on Start according to zconf.Config.StartupReport
+// (描述服务的实际配置：监听地址、开启的子系统以及解析后的配置，启动时按zconf.Config.StartupReport打印)
+type StartupReport struct {
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	GoVersion string   `json:"go_version"`
+	Mode      string   `json:"mode"`
+	Listeners []string `json:"listeners"`
+	// Features maps every optional subsystem to whether it is enabled (每个可选子系统是否开启)
+	Features map[string]bool `json:"features"`
+	// Config is the resolved config with the secrets redacted (解析后的配置，其中的密钥已隐去)
+	Config map[string]interface{} `json:"config"`
+}
+
+// redacted replaces the values of the secret config fields in the report (报告中替换密钥配置项的值)
+const redacted = "******"
+
+// StartupReport returns the report of the server, it can be asserted on after Start
+// (返回服务的启动报告，可以在Start之后校验)
+func (s *Server) StartupReport() StartupReport {
+	report := StartupReport{
+		Name:      s.Name,
+		Version:   s.config.Version,
+		GoVersion: runtime.Version(),
+		Mode:      s.config.Mode,
+		Listeners: s.listenerAddrs(),
+		Features: map[string]bool{
+			"tls":           s.tlsConfig != nil || (s.config.CertFile != "" && s.config.PrivateKeyFile != ""),
+			"shards":        s.config.Shards > 1,
+			"heartbeat":     s.hc != nil,
+			"auth":          s.auth != nil,
+			"signing":       s.signing != nil,
+			"replay_guard":  s.config.ReplayWindow > 0,
+			"rate_limit":    s.config.RateLimitMessages > 0 || s.config.RateLimitBytes > 0,
+			"compression":   len(s.config.Compression) > 0,
+			"fragments":     s.config.FragmentMode,
+			"protocols":     len(s.protocols) > 0,
+			"request_pool":  s.config.RequestPoolMode,
+			"router_slices": s.config.RouterSlicesMode,
+			"metrics":       s.config.MetricsAddr != "",
+			"tracing":       s.config.TraceExporter != "",
+			"admin":         s.config.AdminAddr != "",
+			"capture":       s.config.CaptureFile != "" || s.recorder != nil,
+			"faults":        s.faults.get().Enabled(),
+			"plugins":       len(s.plugins) > 0 || len(s.config.Plugins) > 0,
+		},
+	}
+	if s.config.Mode == "" {
+		report.Mode = zconf.ServerModeTcp + "," + zconf.ServerModeWebsocket
+	}
+
+	// The config goes through JSON so that the report is the same printed or asserted on (配置经过JSON转换，打印与校验时一致)
+	if data, err := json.Marshal(s.config); err == nil {
+		_ = json.Unmarshal(data, &report.Config)
+	}
+	if s.config.AdminToken != "" {
+		report.Config["AdminToken"] = redacted
+	}
+	return report
+}
+
+// listenerAddrs returns the addresses the server listens on, by the mode of its config
+// (按配置的模式返回服务监听的地址)
+func (s *Server) listenerAddrs() []string {
+	tcp, ws := "tcp://", "ws://"
+	if s.tlsConfig != nil || (s.config.CertFile != "" && s.config.PrivateKeyFile != "") {
+		tcp, ws = "tls://", "wss://"
+	}
+	hostPort := func(port int) string {
+		return net.JoinHostPort(s.IP, strconv.Itoa(port))
+	}
+	tcpAddr := hostPort(s.Port)
+	if s.listener != nil {
+		tcpAddr = s.listener.Addr().String()
+	}
+
+	switch s.config.Mode {
+	case zconf.ServerModeTcp, zconf.ServerModeProxy:
+		return []string{tcp + tcpAddr}
+	case zconf.ServerModeWebsocket:
+		return []string{ws + hostPort(s.WsPort)}
+	case zconf.ServerModeKcp:
+		return []string{"kcp://" + hostPort(s.KcpPort)}
+	default:
+		return []string{tcp + tcpAddr, ws + hostPort(s.WsPort)}
+	}
+}
+
+// printStartupReport writes the report of the server to w as configured by zconf.Config.StartupReport
+// (按zconf.Config.StartupReport的配置将服务的报告写入w)
+func (s *Server) printStartupReport(w io.Writer) {
+	switch s.config.StartupReport {
+	case zconf.StartupReportOff:
+		return
+	case zconf.StartupReportJSON:
+		data, err := json.Marshal(s.StartupReport())
+		if err != nil {
+			s.log().ErrorF("startup report err: %v", err)
+			return
+		}
+		fmt.Fprintln(w, string(data))
+		return
+	}
+
+	report := s.StartupReport()
+	fmt.Fprintf(w, "[Zinx] %s Version: %s, Go: %s, Mode: %s\n", report.Name, report.Version, report.GoVersion, report.Mode)
+	for _, listener := range report.Listeners {
+		fmt.Fprintf(w, "[Zinx] Listening on %s\n", listener)
+	}
+	var enabled []string
+	for feature, on := range report.Features {
+		if on {
+			enabled = append(enabled, feature)
+		}
+	}
+	sort.Strings(enabled)
+	fmt.Fprintf(w, "[Zinx] Enabled: %v\n", enabled)
+	fmt.Fprintf(w, "[Zinx] MaxConn: %d, MaxPacketSize: %d, WorkerPoolSize: %d, WorkerMode: %s\n",
+		s.config.MaxConn, s.config.MaxPacketSize, s.config.WorkerPoolSize, s.config.WorkerMode)
+}
@@ -0,0 +1,53 @@
+package znet
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartupReport(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	config := zconf.DefaultConfig()
+	config.Name = "report"
+	config.Mode = zconf.ServerModeTcp
+	config.FragmentMode = true
+	config.AdminToken = "secret"
+	s := newServerWithConfig(config, "tcp", WithListener(listener)).(*Server)
+
+	report := s.StartupReport()
+	assert.Equal(t, "report", report.Name)
+	assert.Equal(t, []string{"tcp://" + listener.Addr().String()}, report.Listeners)
+	assert.True(t, report.Features["fragments"])
+	assert.False(t, report.Features["tls"])
+	assert.Equal(t, "******", report.Config["AdminToken"])
+	assert.Equal(t, float64(config.MaxConn), report.Config["MaxConn"])
+
+	// The JSON report can be asserted on by deployments (部署时可以校验JSON报告)
+	var out bytes.Buffer
+	config.StartupReport = zconf.StartupReportJSON
+	s.printStartupReport(&out)
+	var printed StartupReport
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &printed))
+	assert.Equal(t, report.Listeners, printed.Listeners)
+	assert.True(t, printed.Features["fragments"])
+
+	out.Reset()
+	config.StartupReport = zconf.StartupReportText
+	s.printStartupReport(&out)
+	assert.Contains(t, out.String(), "Listening on tcp://"+listener.Addr().String())
+	assert.Contains(t, out.String(), "Enabled: [fragments]")
+	assert.NotContains(t, out.String(), "secret")
+
+	out.Reset()
+	config.StartupReport = zconf.StartupReportOff
+	s.printStartupReport(&out)
+	assert.Empty(t, out.String())
+}
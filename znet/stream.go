@@ -0,0 +1,321 @@
+package znet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// StreamHeaderLen is the length of the frame header, flag(1 byte) + streamID(4 bytes)
+// (流式帧头长度，标志位1字节 + 流ID 4字节)
+const StreamHeaderLen = 5
+
+var (
+	ErrStreamClosed = errors.New("zinx: stream already closed")
+
+	streamIDSeq uint32
+)
+
+// EncodeStreamFrame encodes one stream frame (编码一个流式帧)
+func EncodeStreamFrame(flag byte, streamID uint32, data []byte) []byte {
+	frame := make([]byte, StreamHeaderLen+len(data))
+	frame[0] = flag
+	binary.LittleEndian.PutUint32(frame[1:StreamHeaderLen], streamID)
+	copy(frame[StreamHeaderLen:], data)
+	return frame
+}
+
+// DecodeStreamFrame decodes one stream frame, data shares memory with frame
+// (解码一个流式帧，data与frame共享内存)
+func DecodeStreamFrame(frame []byte) (flag byte, streamID uint32, data []byte, err error) {
+	if len(frame) < StreamHeaderLen {
+		return 0, 0, nil, fmt.Errorf("zinx: stream frame too short, len = %d", len(frame))
+	}
+	return frame[0], binary.LittleEndian.Uint32(frame[1:StreamHeaderLen]), frame[StreamHeaderLen:], nil
+}
+
+// streamWriter sends every frame as a message with the MsgID of the request
+// (每一帧都以请求的MsgID作为消息发送)
+type streamWriter struct {
+	conn     ziface.IConnection
+	msgID    uint32
+	streamID uint32
+	started  bool
+	closed   bool
+	sync.Mutex
+}
+
+func newStreamWriter(conn ziface.IConnection, msgID uint32) *streamWriter {
+	return &streamWriter{
+		conn:     conn,
+		msgID:    msgID,
+		streamID: atomic.AddUint32(&streamIDSeq, 1),
+	}
+}
+
+func (w *streamWriter) StreamID() uint32 {
+	return w.streamID
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.closed {
+		return 0, ErrStreamClosed
+	}
+	if err := w.send(ziface.StreamFlagChunk, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *streamWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.closed {
+		return ErrStreamClosed
+	}
+	w.closed = true
+	return w.send(ziface.StreamFlagEnd, nil)
+}
+
+func (w *streamWriter) send(flag byte, p []byte) error {
+	if !w.started {
+		flag |= ziface.StreamFlagStart
+		w.started = true
+	}
+	return w.conn.SendMsg(w.msgID, EncodeStreamFrame(flag, w.streamID, p))
+}
+
+// The default limits of a StreamAssembler (StreamAssembler的默认限制)
+const (
+	DefaultMaxStreams        = 16               // Streams open at the same time on one connection (每个连接同时打开的流数)
+	DefaultMaxStreamSize     = 4 * 1024 * 1024  // Size of one stream (单个流的长度)
+	DefaultMaxStreamBuffered = 64 * 1024 * 1024 // Size of all the unfinished streams (所有未完成的流的总长度)
+)
+
+var (
+	// ErrStreamTooLarge is returned for a stream exceeding its size limit, the stream is discarded
+	// (流超过长度限制时返回的错误，该流被丢弃)
+	ErrStreamTooLarge = errors.New("zinx: stream exceeds the max stream size")
+	// ErrStreamBufferFull is returned when the unfinished streams exceed the buffered limit, the stream is discarded
+	// (未完成的流超过缓冲总长度限制时返回的错误，该流被丢弃)
+	ErrStreamBufferFull = errors.New("zinx: streams exceed the max buffered size")
+)
+
+// StreamOption configures a StreamAssembler (流重组器的配置项)
+type StreamOption func(a *StreamAssembler)
+
+// WithMaxStreams limits the streams open at the same time on one connection, the oldest one is discarded when a new
+// one starts, 0 means no limit (限制每个连接同时打开的流数，新的流开始时丢弃最早的流，0表示不限制)
+func WithMaxStreams(n int) StreamOption {
+	return func(a *StreamAssembler) {
+		a.maxStreams = n
+	}
+}
+
+// WithMaxStreamSize limits the size of one stream, 0 means no limit (限制单个流的长度，0表示不限制)
+func WithMaxStreamSize(size int) StreamOption {
+	return func(a *StreamAssembler) {
+		a.maxSize = size
+	}
+}
+
+// WithMaxStreamBuffered limits the size of all the unfinished streams of every connection, 0 means no limit
+// (限制所有连接未完成的流的总长度，0表示不限制)
+func WithMaxStreamBuffered(size int) StreamOption {
+	return func(a *StreamAssembler) {
+		a.maxBuffered = size
+	}
+}
+
+// StreamAssembler reassembles the frames of streams on the receiving side, it can be used as
+// a RouterHandler or from an IRouter's Handle, onComplete is called with the whole payload after the end frame.
+// The streams are limited by DefaultMaxStreams, DefaultMaxStreamSize and DefaultMaxStreamBuffered unless
+// configured otherwise.
+// (在接收端重组流式帧，可以作为RouterHandler使用，也可以在IRouter的Handle中调用，收到end帧后以完整数据回调onComplete。
+// 未另行配置时，流受DefaultMaxStreams、DefaultMaxStreamSize和DefaultMaxStreamBuffered限制)
+type StreamAssembler struct {
+	onComplete  func(request ziface.IRequest, streamID uint32, data []byte)
+	streams     map[streamKey]*streamBuffer
+	conns       map[uint64]int      // Number of open streams by connection (每个连接打开的流数)
+	watched     map[uint64]struct{} // Connections discarded on close (关闭时丢弃其流的连接)
+	seq         uint64
+	buffered    int
+	maxStreams  int
+	maxSize     int
+	maxBuffered int
+	sync.Mutex
+}
+
+type streamKey struct {
+	connID   uint64
+	streamID uint32
+}
+
+type streamBuffer struct {
+	data []byte
+	seq  uint64
+}
+
+// NewStreamAssembler creates a StreamAssembler (创建流重组器)
+func NewStreamAssembler(onComplete func(request ziface.IRequest, streamID uint32, data []byte), opts ...StreamOption) *StreamAssembler {
+	a := &StreamAssembler{
+		onComplete:  onComplete,
+		streams:     make(map[streamKey]*streamBuffer),
+		conns:       make(map[uint64]int),
+		watched:     make(map[uint64]struct{}),
+		maxStreams:  DefaultMaxStreams,
+		maxSize:     DefaultMaxStreamSize,
+		maxBuffered: DefaultMaxStreamBuffered,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Handle feeds one frame into the assembler, the unfinished streams of the connection are discarded once it is
+// closed (将一帧数据交给重组器，连接关闭后丢弃其未完成的流)
+func (a *StreamAssembler) Handle(request ziface.IRequest) {
+	conn := request.GetConnection()
+	if !a.watch(conn) {
+		// A frame left in the queue of a closed connection (已关闭连接的队列中剩余的帧)
+		a.Discard(conn.GetConnID())
+		return
+	}
+	data, streamID, done, err := a.feed(conn, connIDOf(conn), request.GetData())
+	if err != nil {
+		LoggerOf(conn).ErrorF("connID = %d stream err: %v", connIDOf(conn), err)
+		return
+	}
+	if !done {
+		return
+	}
+	a.onComplete(request, streamID, data)
+}
+
+// watch discards the streams of conn on its close, registered with the first frame of conn. It returns false if
+// conn is already closed. (在conn关闭时丢弃其流，收到conn的第一帧时注册。conn已关闭时返回false)
+func (a *StreamAssembler) watch(conn ziface.IConnection) bool {
+	if conn == nil {
+		return true
+	}
+	if ctx := conn.Context(); ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	connID := conn.GetConnID()
+	a.Lock()
+	_, ok := a.watched[connID]
+	a.watched[connID] = struct{}{}
+	a.Unlock()
+	// Outside the lock, the close callbacks are invoked under the lock of the connection
+	// (在锁外注册，关闭回调在连接的锁内调用)
+	if !ok {
+		conn.AddCloseCallback(a, connID, func() { a.Discard(connID) })
+	}
+	return true
+}
+
+// Feed appends one frame to its stream, done is true and data holds the whole payload after the end frame.
+// A stream exceeding the limits is discarded with ErrStreamTooLarge or ErrStreamBufferFull. Unlike Handle, the
+// caller discards the streams of a closed connection.
+// (将一帧追加到对应的流，收到end帧后done为true，data为完整数据。超过限制的流被丢弃并返回ErrStreamTooLarge或ErrStreamBufferFull。
+// 与Handle不同，由调用方丢弃已关闭连接的流)
+func (a *StreamAssembler) Feed(connID uint64, frame []byte) (data []byte, streamID uint32, done bool, err error) {
+	return a.feed(nil, connID, frame)
+}
+
+// feed is Feed, conn is nil unless known, for the logs (即Feed，conn仅在已知时不为nil，用于日志)
+func (a *StreamAssembler) feed(conn ziface.IConnection, connID uint64, frame []byte) (data []byte, streamID uint32, done bool, err error) {
+	flag, streamID, chunk, err := DecodeStreamFrame(frame)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	key := streamKey{connID: connID, streamID: streamID}
+	buf, ok := a.streams[key]
+	if flag&ziface.StreamFlagStart != 0 {
+		if ok {
+			a.remove(key, buf)
+		}
+		if a.maxStreams > 0 && a.conns[connID] >= a.maxStreams {
+			a.evictOldest(conn, connID)
+		}
+		a.seq++
+		buf = &streamBuffer{seq: a.seq}
+		a.streams[key] = buf
+		a.conns[connID]++
+	} else if !ok {
+		return nil, streamID, false, fmt.Errorf("zinx: unknown stream, streamID = %d", streamID)
+	}
+
+	if a.maxSize > 0 && len(buf.data)+len(chunk) > a.maxSize {
+		a.remove(key, buf)
+		return nil, streamID, false, fmt.Errorf("%w %d, streamID = %d", ErrStreamTooLarge, a.maxSize, streamID)
+	}
+	if a.maxBuffered > 0 && a.buffered+len(chunk) > a.maxBuffered {
+		a.remove(key, buf)
+		return nil, streamID, false, fmt.Errorf("%w %d, streamID = %d", ErrStreamBufferFull, a.maxBuffered, streamID)
+	}
+	buf.data = append(buf.data, chunk...)
+	a.buffered += len(chunk)
+
+	if flag&ziface.StreamFlagEnd == 0 {
+		return nil, streamID, false, nil
+	}
+	a.remove(key, buf)
+	return buf.data, streamID, true, nil
+}
+
+// Discard drops the unfinished streams of a connection, e.g. when it is closed, which Handle does itself
+// (丢弃某个连接未完成的流，例如连接关闭时，Handle会自行处理)
+func (a *StreamAssembler) Discard(connID uint64) {
+	a.Lock()
+	defer a.Unlock()
+	delete(a.watched, connID)
+	for key, buf := range a.streams {
+		if key.connID == connID {
+			a.remove(key, buf)
+		}
+	}
+}
+
+// remove drops a stream from the assembler, called with the lock held (从重组器中移除一个流，调用时需持有锁)
+func (a *StreamAssembler) remove(key streamKey, buf *streamBuffer) {
+	delete(a.streams, key)
+	a.buffered -= len(buf.data)
+	if a.conns[key.connID]--; a.conns[key.connID] <= 0 {
+		delete(a.conns, key.connID)
+	}
+}
+
+func (a *StreamAssembler) evictOldest(conn ziface.IConnection, connID uint64) {
+	var oldestKey streamKey
+	var oldest *streamBuffer
+	for key, buf := range a.streams {
+		if key.connID == connID && (oldest == nil || buf.seq < oldest.seq) {
+			oldestKey, oldest = key, buf
+		}
+	}
+	if oldest != nil {
+		LoggerOf(conn).ErrorF("discard streamID = %d of connID = %d, too many streams open", oldestKey.streamID, connID)
+		a.remove(oldestKey, oldest)
+	}
+}
+
+func connIDOf(conn ziface.IConnection) uint64 {
+	if conn == nil {
+		return 0
+	}
+	return conn.GetConnID()
+}
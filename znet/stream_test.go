@@ -0,0 +1,108 @@
+package znet
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamWriterAndAssembler(t *testing.T) {
+	conn := &recordConn{}
+	w := NewRequest(conn, zpack.NewMsgPackage(3, nil)).Stream()
+	_, _ = io.WriteString(w, "hello ")
+	_, _ = io.WriteString(w, "stream")
+	assert.NoError(t, w.Close())
+	_, err := w.Write([]byte("late"))
+	assert.Equal(t, ErrStreamClosed, err)
+	assert.Len(t, conn.sent, 3)
+
+	var got string
+	var gotID uint32
+	assembler := NewStreamAssembler(func(request ziface.IRequest, streamID uint32, data []byte) {
+		got = string(data)
+		gotID = streamID
+	})
+	for _, msg := range conn.sent {
+		assert.Equal(t, uint32(3), msg.msgID)
+		assembler.Handle(NewRequest(nil, zpack.NewMsgPackage(msg.msgID, msg.data)))
+	}
+	assert.Equal(t, "hello stream", got)
+	assert.Equal(t, w.StreamID(), gotID)
+}
+
+func TestStreamAssemblerLimits(t *testing.T) {
+	a := NewStreamAssembler(nil, WithMaxStreams(2), WithMaxStreamSize(8), WithMaxStreamBuffered(12))
+	feed := func(connID uint64, flag byte, streamID uint32, data string) ([]byte, bool, error) {
+		payload, _, done, err := a.Feed(connID, EncodeStreamFrame(flag, streamID, []byte(data)))
+		return payload, done, err
+	}
+
+	// A stream past its size is discarded
+	_, _, err := feed(1, ziface.StreamFlagStart|ziface.StreamFlagChunk, 1, "12345")
+	assert.NoError(t, err)
+	_, _, err = feed(1, ziface.StreamFlagChunk, 1, "6789")
+	assert.ErrorIs(t, err, ErrStreamTooLarge)
+	_, _, err = feed(1, ziface.StreamFlagChunk, 1, "x")
+	assert.Error(t, err)
+	assert.Equal(t, 0, a.buffered)
+
+	// Starting a third stream on a connection discards its oldest one
+	for id := uint32(1); id <= 3; id++ {
+		_, _, err = feed(1, ziface.StreamFlagStart|ziface.StreamFlagChunk, id, "ab")
+		assert.NoError(t, err)
+	}
+	assert.Len(t, a.streams, 2)
+	_, _, err = feed(1, ziface.StreamFlagChunk, 1, "c")
+	assert.Error(t, err)
+	data, done, err := feed(1, ziface.StreamFlagEnd, 2, "c")
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, "abc", string(data))
+
+	// The streams of every connection share the buffered limit
+	_, _, err = feed(2, ziface.StreamFlagStart|ziface.StreamFlagChunk, 1, "12345678")
+	assert.NoError(t, err)
+	_, _, err = feed(3, ziface.StreamFlagStart|ziface.StreamFlagChunk, 1, "1234")
+	assert.ErrorIs(t, err, ErrStreamBufferFull)
+
+	a.Discard(1)
+	a.Discard(2)
+	assert.Equal(t, 0, a.buffered)
+	assert.Empty(t, a.conns)
+}
+
+type streamConn struct {
+	callbackConn
+	ctx context.Context
+}
+
+func (c *streamConn) Context() context.Context { return c.ctx }
+
+func TestStreamAssemblerDiscardOnClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := &streamConn{ctx: ctx}
+	a := NewStreamAssembler(nil)
+	handle := func(flag byte, streamID uint32) {
+		a.Handle(NewRequest(conn, zpack.NewMsgPackage(3, EncodeStreamFrame(flag, streamID, []byte("ab")))))
+	}
+
+	handle(ziface.StreamFlagStart|ziface.StreamFlagChunk, 1)
+	handle(ziface.StreamFlagStart|ziface.StreamFlagChunk, 2)
+	assert.Len(t, a.streams, 2)
+	assert.Equal(t, 1, conn.callbacks.Count())
+
+	// Closing the connection discards its unfinished streams (关闭连接时丢弃其未完成的流)
+	cancel()
+	conn.callbacks.Invoke()
+	assert.Empty(t, a.streams)
+	assert.Empty(t, a.watched)
+	assert.Equal(t, 0, a.buffered)
+
+	// The frames left in the queue are dropped (丢弃队列中剩余的帧)
+	handle(ziface.StreamFlagStart|ziface.StreamFlagChunk, 3)
+	assert.Empty(t, a.streams)
+}
@@ -0,0 +1,112 @@
+package znet
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// messageTap is a tap fed without blocking, the lock keeps the messages off its closed channel
+// (非阻塞投递的截取通道，锁保证不会向已关闭的通道发送消息)
+type messageTap struct {
+	c       chan ziface.TapMessage
+	dropped uint64
+	lock    sync.RWMutex
+	closed  bool
+	remove  func(*messageTap)
+}
+
+func (t *messageTap) C() <-chan ziface.TapMessage {
+	return t.c
+}
+
+func (t *messageTap) Dropped() uint64 {
+	return atomic.LoadUint64(&t.dropped)
+}
+
+func (t *messageTap) Close() {
+	t.remove(t)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.c)
+	}
+}
+
+func (t *messageTap) offer(msg ziface.TapMessage) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.closed {
+		return
+	}
+	select {
+	case t.c <- msg:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// addTap adds a tap buffering up to buffer messages, the taps are copied on write so that the connections read them
+// without a lock (添加最多缓冲buffer条消息的截取，截取列表写时复制，连接读取时无需加锁)
+func (mh *MsgHandle) addTap(buffer int) *messageTap {
+	t := &messageTap{c: make(chan ziface.TapMessage, buffer), remove: mh.removeTap}
+
+	mh.tapsLock.Lock()
+	defer mh.tapsLock.Unlock()
+	taps, _ := mh.taps.Load().([]*messageTap)
+	mh.taps.Store(append(append([]*messageTap(nil), taps...), t))
+	return t
+}
+
+func (mh *MsgHandle) removeTap(t *messageTap) {
+	mh.tapsLock.Lock()
+	defer mh.tapsLock.Unlock()
+	taps, _ := mh.taps.Load().([]*messageTap)
+	kept := make([]*messageTap, 0, len(taps))
+	for _, tap := range taps {
+		if tap != t {
+			kept = append(kept, tap)
+		}
+	}
+	mh.taps.Store(kept)
+}
+
+// tapMessage fans a copy of the message out to the taps of the server of conn, the data is copied once as the
+// buffer of the request is reused (将消息的副本分发给conn所属服务的截取，请求的缓冲会被复用，因此数据复制一次)
+func tapMessage(conn ziface.IConnection, msgID uint32, data []byte) {
+	if conn == nil {
+		return
+	}
+	mh, ok := conn.GetMsgHandler().(*MsgHandle)
+	if !ok {
+		return
+	}
+	taps, _ := mh.taps.Load().([]*messageTap)
+	if len(taps) == 0 {
+		return
+	}
+	msg := ziface.TapMessage{
+		Time:   time.Now(),
+		ConnID: conn.GetConnID(),
+		MsgID:  msgID,
+		Data:   append([]byte(nil), data...),
+	}
+	for _, t := range taps {
+		t.offer(msg)
+	}
+}
+
+// AddMessageTap taps every decoded inbound message before it is routed into a channel buffering up to buffer
+// messages, the messages are shared by the taps and must not be modified
+// (将每条解码后收到的消息在路由之前交给最多缓冲buffer条消息的通道，消息由各截取共享，不能修改)
+func (s *Server) AddMessageTap(buffer int) ziface.IMessageTap {
+	mh, ok := s.msgHandler.(*MsgHandle)
+	if !ok {
+		panic("AddMessageTap needs the MsgHandle of znet")
+	}
+	return mh.addTap(buffer)
+}
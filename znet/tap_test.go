@@ -0,0 +1,39 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageTap(t *testing.T) {
+	config := zconf.DefaultConfig()
+	s := newServerWithConfig(config, "tcp").(*Server)
+	mh := s.msgHandler.(*MsgHandle)
+	conn := &Connection{connID: 3, connIdStr: "3", msgHandler: mh}
+
+	first := s.AddMessageTap(1)
+	second := s.AddMessageTap(4)
+	data := []byte("ping")
+	observeMessage(conn, directionInbound, 1, data)
+	observeMessage(conn, directionOutbound, 2, []byte("pong"))
+	observeMessage(conn, directionInbound, 3, []byte("full"))
+	data[0] = 'P'
+
+	// The full tap drops without holding up the others (已满的截取丢弃消息，不影响其它截取)
+	assert.Equal(t, uint64(1), first.Dropped())
+	assert.Equal(t, uint64(0), second.Dropped())
+	msg := <-first.C()
+	assert.Equal(t, uint64(3), msg.ConnID)
+	assert.Equal(t, uint32(1), msg.MsgID)
+	assert.Equal(t, "ping", string(msg.Data))
+	assert.Len(t, second.C(), 2)
+
+	first.Close()
+	first.Close()
+	observeMessage(conn, directionInbound, 4, []byte("after"))
+	_, open := <-first.C()
+	assert.False(t, open)
+	assert.Len(t, second.C(), 3)
+}
@@ -0,0 +1,119 @@
+package znet
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/ztrace"
+)
+
+// The spans recorded for every connection and message when tracing is on (开启追踪时为每个连接和消息记录的span)
+const (
+	spanAccept   = "zinx.accept"
+	spanDecode   = "zinx.decode"
+	spanDispatch = "zinx.dispatch"
+	spanHandle   = "zinx.handle"
+	spanSend     = "zinx.send"
+)
+
+// The attributes of the spans (span的属性)
+const (
+	attrConnID     = "zinx.conn_id"
+	attrMsgID      = "zinx.msg_id"
+	attrRemoteAddr = "zinx.remote_addr"
+	attrWorkerID   = "zinx.worker_id"
+	attrDataLen    = "zinx.data_len"
+)
+
+var tracingOnce sync.Once
+
+// startTracing sets the exporter of config.TraceExporter, tracing is process-wide so the first server started
+// with it configures it, logging with its logger (设置config.TraceExporter指定的导出器，追踪是进程级的，由第一个配置了它的服务启动，
+// 使用该服务的日志)
+func startTracing(config *zconf.Config, logger ziface.ILogger) {
+	if config.TraceExporter == "" {
+		return
+	}
+	tracingOnce.Do(func() {
+		exporter, err := ztrace.NewExporter(config.TraceExporter, config.TraceEndpoint, config.Name)
+		if err != nil {
+			logger.ErrorF("start tracing err: %v", err)
+			return
+		}
+		ztrace.SetSampleRatio(config.TraceSampleRatio)
+		ztrace.SetExporter(exporter)
+		logger.InfoF("tracing is exported by %s, sample ratio %v", config.TraceExporter, config.TraceSampleRatio)
+	})
+}
+
+// traceAccept records the accept of conn (记录conn的接入)
+func traceAccept(conn ziface.IConnection) *ztrace.Span {
+	if !ztrace.Enabled() {
+		return nil
+	}
+	_, span := ztrace.Start(context.Background(), spanAccept, ztrace.SpanKindServer,
+		ztrace.Attr(attrConnID, conn.GetConnID()), ztrace.Attr(attrRemoteAddr, conn.RemoteAddrString()))
+	return span
+}
+
+// traceDecode starts the span of the request in the trace of its message, carried by the datapack header
+// or generated, it lasts until the request reaches the dispatcher which assigns the trace to the request
+// (在消息的链路中开始请求的span，追踪ID由数据包头部携带或生成，持续到请求到达分发器，分发器将该链路设置到请求上)
+func traceDecode(request ziface.IRequest) {
+	if !ztrace.Enabled() {
+		return
+	}
+	ctx, span := ztrace.StartWithTraceID(request.GetContext(), request.GetTraceID(), spanDecode, ztrace.SpanKindServer,
+		ztrace.Attr(attrConnID, request.GetConnection().GetConnID()), ztrace.Attr(attrDataLen, len(request.GetData())))
+	if span != nil {
+		request.SetContext(ctx)
+	}
+}
+
+// traceDispatch ends the decode span of the request and starts its dispatch span, which lasts until a worker
+// picks the request up (结束请求的解码span并开始分发span，持续到worker取出请求)
+func traceDispatch(request ziface.IRequest) {
+	if !ztrace.Enabled() {
+		return
+	}
+	next(request, spanDispatch, ztrace.SpanKindInternal, ztrace.Attr(attrMsgID, request.GetMsgID()))
+}
+
+// traceHandle ends the dispatch span of the request and starts the span of its handler, the caller ends it
+// (结束请求的分发span并开始处理函数的span，由调用方结束)
+func traceHandle(request ziface.IRequest, workerID int) *ztrace.Span {
+	if !ztrace.Enabled() {
+		return nil
+	}
+	return next(request, spanHandle, ztrace.SpanKindInternal,
+		ztrace.Attr(attrMsgID, request.GetMsgID()), ztrace.Attr(attrWorkerID, workerID))
+}
+
+// next ends the current span of the request and starts its child name (结束请求当前的span并开始其子span name)
+func next(request ziface.IRequest, name string, kind ztrace.SpanKind, attrs ...ztrace.Attribute) *ztrace.Span {
+	parent := ztrace.SpanFromContext(request.GetContext())
+	if parent == nil {
+		return nil
+	}
+	parent.End()
+	ctx, span := ztrace.Start(request.GetContext(), name, kind, attrs...)
+	request.SetContext(ctx)
+	return span
+}
+
+// traceSend records the send of msg when it belongs to a trace, such as the replies to traced requests
+// (msg属于某条链路时记录其发送，例如对被追踪请求的回复)
+func traceSend(conn ziface.IConnection, msg ziface.IMessage) *ztrace.Span {
+	if !ztrace.Enabled() {
+		return nil
+	}
+	tm, ok := msg.(ziface.ITraceMessage)
+	if !ok || tm.GetTraceID() == "" {
+		return nil
+	}
+	_, span := ztrace.StartWithTraceID(nil, tm.GetTraceID(), spanSend, ztrace.SpanKindProducer,
+		ztrace.Attr(attrConnID, conn.GetConnID()), ztrace.Attr(attrMsgID, msg.GetMsgID()), ztrace.Attr(attrDataLen, len(msg.GetData())))
+	return span
+}
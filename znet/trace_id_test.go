@@ -0,0 +1,137 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zdecoder"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTraceID = "0af7651916cd43dd8448eb211c80319c"
+
+// plainMessage is a message which cannot carry a trace ID (无法携带追踪ID的消息)
+type plainMessage struct {
+	ziface.IMessage
+}
+
+// traceConn records the messages sent with SendMessage (记录通过SendMessage发送的消息)
+type traceConn struct {
+	recordConn
+	messages []ziface.IMessage
+}
+
+func (c *traceConn) SendMessage(msg ziface.IMessage) error {
+	c.messages = append(c.messages, msg)
+	return nil
+}
+
+func TestRequestGetTraceID(t *testing.T) {
+	// The trace ID carried by the message (消息携带的追踪ID)
+	msg := zpack.NewMsgPackage(1, nil)
+	msg.SetTraceID(testTraceID)
+	assert.Equal(t, testTraceID, NewRequest(nil, msg).GetTraceID())
+
+	// A message without one is given an ID at dispatch, kept on the message (没有追踪ID的消息在分发时获得一个并保存在消息上)
+	msg = zpack.NewMsgPackage(1, nil)
+	request := NewRequest(nil, msg)
+	assert.Empty(t, request.GetTraceID())
+	assignTraceID(request)
+	traceID := request.GetTraceID()
+	assert.Len(t, traceID, 32)
+	assert.Equal(t, traceID, msg.GetTraceID())
+	assignTraceID(request)
+	assert.Equal(t, traceID, request.GetTraceID())
+
+	// A message which cannot carry one keeps it on the request (无法携带追踪ID的消息将其保存在请求上)
+	request = NewRequest(nil, &plainMessage{IMessage: zpack.NewMsgPackage(1, nil)})
+	assignTraceID(request)
+	traceID = request.GetTraceID()
+	assert.Len(t, traceID, 32)
+	assert.Equal(t, traceID, request.GetString(traceKey))
+	other := NewRequest(nil, &plainMessage{IMessage: zpack.NewMsgPackage(1, nil)})
+	assignTraceID(other)
+	assert.NotEqual(t, traceID, other.GetTraceID())
+}
+
+func TestHeaderSchemaTraceID(t *testing.T) {
+	schema, err := zpack.NewHeaderSchema([]string{zpack.HeaderFieldNonce, zpack.HeaderFieldTraceID, zpack.HeaderFieldSignature}, 0)
+	assert.Nil(t, err)
+	dp := zpack.NewDataPackSchema(schema)
+	assert.Equal(t, uint32(8+8+16+zpack.SignatureLen), dp.GetHeadLen())
+
+	msg := zpack.NewMsgPackage(1, []byte("ping"))
+	msg.SetTraceID(testTraceID)
+	frame, err := dp.Pack(msg)
+	assert.Nil(t, err)
+	decoded, err := schema.Decode(frame)
+	assert.Nil(t, err)
+	request := NewRequest(nil, decoded)
+	assignTraceID(request)
+	assert.Equal(t, testTraceID, request.GetTraceID())
+
+	// A frame without a trace ID is given one at dispatch (没有追踪ID的帧在分发时获得一个)
+	frame, err = dp.Pack(zpack.NewMsgPackage(1, []byte("ping")))
+	assert.Nil(t, err)
+	decoded, err = schema.Decode(frame)
+	assert.Nil(t, err)
+	assert.Empty(t, decoded.GetTraceID())
+	request = NewRequest(nil, decoded)
+	assignTraceID(request)
+	assert.Len(t, request.GetTraceID(), 32)
+}
+
+func TestSendTraced(t *testing.T) {
+	msg := zpack.NewMsgPackage(1, nil)
+	msg.SetTraceID(testTraceID)
+	assert.Equal(t, ErrNoConnection, SendTraced(NewRequest(nil, msg), 2, []byte("pong")))
+
+	conn := &traceConn{}
+	assert.Nil(t, SendTraced(NewRequest(conn, msg), 2, []byte("pong")))
+	assert.Len(t, conn.messages, 1)
+	reply := conn.messages[0].(ziface.ITraceMessage)
+	assert.Equal(t, uint32(2), reply.GetMsgID())
+	assert.Equal(t, []byte("pong"), reply.GetData())
+	assert.Equal(t, testTraceID, reply.GetTraceID())
+}
+
+func TestTraceTLVDecoder(t *testing.T) {
+	decoder := zdecoder.NewTraceTLVDecoder()
+	assert.Equal(t, 16, decoder.GetLengthField().LengthAdjustment)
+
+	decode := func(data []byte) ziface.IRequest {
+		capture := &captureInterceptor{}
+		request := NewRequest(nil, zpack.NewMsgPackage(0, data))
+		zinterceptor.NewChain([]ziface.IInterceptor{decoder, capture}, 0, request).Proceed(request)
+		assert.Len(t, capture.requests, 1)
+		return capture.requests[0]
+	}
+
+	msg := zpack.NewMsgPackage(7, []byte("hello"))
+	msg.SetTraceID(testTraceID)
+	frame, err := zpack.NewDataPackTrace().Pack(msg)
+	assert.Nil(t, err)
+	request := decode(frame)
+	assert.Equal(t, uint32(7), request.GetMsgID())
+	assert.Equal(t, []byte("hello"), request.GetData())
+	assert.Equal(t, testTraceID, request.GetTraceID())
+
+	// A frame without a trace ID is given one at dispatch (没有追踪ID的帧在分发时获得一个)
+	frame, _ = zpack.NewDataPackTrace().Pack(zpack.NewMsgPackage(8, nil))
+	request = decode(frame)
+	assert.Equal(t, uint32(8), request.GetMsgID())
+	assert.Empty(t, request.GetData())
+	assert.Empty(t, request.GetTraceID())
+	assignTraceID(request)
+	assert.Len(t, request.GetTraceID(), 32)
+
+	// Truncated frames are passed on untouched (截断的帧原样传递)
+	full, _ := zpack.NewDataPackTrace().Pack(msg)
+	for _, data := range [][]byte{full[:len(full)-1], full[:zdecoder.TRACE_TLV_HEADER_SIZE-1], nil} {
+		request = decode(data)
+		assert.Equal(t, uint32(0), request.GetMsgID())
+		assert.Equal(t, data, request.GetData())
+	}
+}
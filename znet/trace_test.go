@@ -0,0 +1,57 @@
+package znet
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/aceld/zinx/ztrace"
+	"github.com/stretchr/testify/assert"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*ztrace.Span
+}
+
+func (r *spanRecorder) ExportSpans(_ context.Context, spans []*ztrace.Span) error {
+	r.mu.Lock()
+	r.spans = append(r.spans, spans...)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func TestTraceRequest(t *testing.T) {
+	recorder := &spanRecorder{}
+	ztrace.SetExporter(recorder)
+	defer ztrace.Shutdown(context.Background())
+
+	traceID := "0af7651916cd43dd8448eb211c80319c"
+	msg := zpack.NewMsgPackage(3, []byte("ping"))
+	msg.SetTraceID(traceID)
+	request := NewRequest(&Connection{connID: 5, connIdStr: "5"}, msg)
+
+	traceDecode(request)
+	traceDispatch(request)
+	span := traceHandle(request, 1)
+	span.End()
+	ztrace.Flush()
+
+	assert.Len(t, recorder.spans, 3)
+	var names []string
+	for i, s := range recorder.spans {
+		names = append(names, s.Name)
+		assert.Equal(t, traceID, s.TraceID)
+		if i > 0 {
+			assert.Equal(t, recorder.spans[i-1].SpanID, s.ParentSpanID)
+		}
+	}
+	assert.Equal(t, []string{spanDecode, spanDispatch, spanHandle}, names)
+	assert.Equal(t, ztrace.SpanFromContext(request.GetContext()), span)
+
+	assert.Nil(t, traceSend(&Connection{}, zpack.NewMsgPackage(3, nil)))
+	assert.Equal(t, traceID, traceSend(&Connection{}, msg).TraceID)
+}
@@ -0,0 +1,271 @@
+package znet
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+// traffic counts bytes and messages (统计字节数与消息数)
+type traffic struct {
+	bytesIn  uint64
+	bytesOut uint64
+	msgsIn   uint64
+	msgsOut  uint64
+}
+
+func (t *traffic) stats() ziface.TrafficStats {
+	return ziface.TrafficStats{
+		BytesIn:  atomic.LoadUint64(&t.bytesIn),
+		BytesOut: atomic.LoadUint64(&t.bytesOut),
+		MsgsIn:   atomic.LoadUint64(&t.msgsIn),
+		MsgsOut:  atomic.LoadUint64(&t.msgsOut),
+	}
+}
+
+// tenantTraffic is the traffic of a tenant, with its series in zmetrics so that counting does not look them up
+// (租户的流量，保存其在zmetrics中的序列，计数时无需查找)
+type tenantTraffic struct {
+	traffic
+	name  string
+	conns int32
+
+	metricBytesIn  *zmetrics.Counter
+	metricBytesOut *zmetrics.Counter
+	metricMsgsIn   *zmetrics.Counter
+	metricMsgsOut  *zmetrics.Counter
+}
+
+// tenants is the tenant registry of a ConnManager (ConnManager的租户表)
+type tenants struct {
+	mu   sync.Mutex
+	byID map[string]*tenantTraffic
+}
+
+func (ts *tenants) get(name string, m *zmetrics.Metrics) *tenantTraffic {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.byID == nil {
+		ts.byID = make(map[string]*tenantTraffic)
+	}
+	t, ok := ts.byID[name]
+	if !ok {
+		t = &tenantTraffic{
+			name:           name,
+			metricBytesIn:  m.TenantBytesReceived.With(name),
+			metricBytesOut: m.TenantBytesSent.With(name),
+			metricMsgsIn:   m.TenantMessagesReceived.With(name),
+			metricMsgsOut:  m.TenantMessagesSent.With(name),
+		}
+		ts.byID[name] = t
+	}
+	return t
+}
+
+func (ts *tenants) list() []*tenantTraffic {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	list := make([]*tenantTraffic, 0, len(ts.byID))
+	for _, t := range ts.byID {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// connTraffic is embedded in the connections, it counts their traffic and the traffic of their tenant
+// (嵌入在连接中，统计连接及其租户的流量)
+type connTraffic struct {
+	traffic
+	tenant atomic.Value // *tenantTraffic
+}
+
+func (t *connTraffic) getTenant() *tenantTraffic {
+	tenant, _ := t.tenant.Load().(*tenantTraffic)
+	return tenant
+}
+
+// setTenant moves the connection to tenant, nil when it is closed (将连接移到tenant，关闭时为nil)
+func (t *connTraffic) setTenant(tenant *tenantTraffic) {
+	if tenant != nil {
+		atomic.AddInt32(&tenant.conns, 1)
+	}
+	if old, _ := t.tenant.Swap(tenant).(*tenantTraffic); old != nil {
+		atomic.AddInt32(&old.conns, -1)
+	}
+}
+
+func (t *connTraffic) tenantName() string {
+	if tenant := t.getTenant(); tenant != nil {
+		return tenant.name
+	}
+	return ""
+}
+
+// received counts n bytes read from the connection into m (将从连接读取的n个字节计入m)
+func (t *connTraffic) received(m *zmetrics.Metrics, n int) {
+	m.BytesReceived.Add(uint64(n))
+	atomic.AddUint64(&t.bytesIn, uint64(n))
+	if tenant := t.getTenant(); tenant != nil {
+		atomic.AddUint64(&tenant.bytesIn, uint64(n))
+		if zmetrics.Enabled() {
+			tenant.metricBytesIn.Add(uint64(n))
+		}
+	}
+}
+
+// sent counts data written to the connection into m (将写入连接的数据计入m)
+func (t *connTraffic) sent(m *zmetrics.Metrics, data []byte) {
+	sent(m, data)
+	atomic.AddUint64(&t.bytesOut, uint64(len(data)))
+	if tenant := t.getTenant(); tenant != nil {
+		atomic.AddUint64(&tenant.bytesOut, uint64(len(data)))
+		if zmetrics.Enabled() {
+			tenant.metricBytesOut.Add(uint64(len(data)))
+		}
+	}
+}
+
+// message counts a decoded inbound message or an outbound message (统计一条解码后收到或发出的消息)
+func (t *connTraffic) message(inbound bool) {
+	tenant := t.getTenant()
+	if inbound {
+		atomic.AddUint64(&t.msgsIn, 1)
+		if tenant != nil {
+			atomic.AddUint64(&tenant.msgsIn, 1)
+			if zmetrics.Enabled() {
+				tenant.metricMsgsIn.Inc()
+			}
+		}
+		return
+	}
+	atomic.AddUint64(&t.msgsOut, 1)
+	if tenant != nil {
+		atomic.AddUint64(&tenant.msgsOut, 1)
+		if zmetrics.Enabled() {
+			tenant.metricMsgsOut.Inc()
+		}
+	}
+}
+
+type trafficCounter interface {
+	message(inbound bool)
+	setTenant(tenant *tenantTraffic)
+}
+
+// countMessage counts a message of conn (统计conn的一条消息)
+func countMessage(conn ziface.IConnection, inbound bool) {
+	if t, ok := conn.(trafficCounter); ok {
+		t.message(inbound)
+	}
+}
+
+// setConnTenant accounts the traffic of conn to the tenant name of connMgr (将conn的流量计入connMgr中名为name的租户)
+func setConnTenant(connMgr ziface.IConnManager, conn trafficCounter, name string) {
+	mgr, ok := connMgr.(*ConnManager)
+	if !ok || name == "" {
+		conn.setTenant(nil)
+		return
+	}
+	m := zmetrics.Zinx
+	if c, ok := conn.(ziface.IConnection); ok {
+		m = metricsOf(c)
+	}
+	conn.setTenant(mgr.tenants.get(name, m))
+}
+
+// trafficSnapshots takes the periodic traffic snapshots of a server (定期获取服务的流量快照)
+type trafficSnapshots struct {
+	mu     sync.Mutex
+	latest *ziface.TrafficSnapshot
+	conns  map[uint64]ziface.TrafficStats
+	tenant map[string]ziface.TrafficStats
+}
+
+// take takes a snapshot of connMgr, the deltas are relative to the previous one taken with save
+// (获取connMgr的快照，增量相对于上一次以save获取的快照)
+func (ts *trafficSnapshots) take(connMgr ziface.IConnManager, save bool) ziface.TrafficSnapshot {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := time.Now()
+	snapshot := ziface.TrafficSnapshot{Time: now}
+	if ts.latest != nil {
+		snapshot.Interval = now.Sub(ts.latest.Time)
+	}
+	conns := make(map[uint64]ziface.TrafficStats)
+	_ = connMgr.Range(func(connID uint64, conn ziface.IConnection, _ interface{}) error {
+		total := conn.GetTraffic()
+		conns[connID] = total
+		snapshot.Connections = append(snapshot.Connections, ziface.ConnTraffic{
+			ConnID: connID,
+			Tenant: conn.GetTenant(),
+			Total:  total,
+			Delta:  total.Sub(ts.conns[connID]),
+		})
+		return nil
+	}, nil)
+	sort.Slice(snapshot.Connections, func(i, j int) bool {
+		return snapshot.Connections[i].ConnID < snapshot.Connections[j].ConnID
+	})
+
+	tenants := make(map[string]ziface.TrafficStats)
+	if mgr, ok := connMgr.(*ConnManager); ok {
+		for _, t := range mgr.tenants.list() {
+			total := t.stats()
+			tenants[t.name] = total
+			snapshot.Tenants = append(snapshot.Tenants, ziface.TenantTraffic{
+				Tenant:      t.name,
+				Connections: int(atomic.LoadInt32(&t.conns)),
+				Total:       total,
+				Delta:       total.Sub(ts.tenant[t.name]),
+			})
+		}
+	}
+
+	if save {
+		ts.latest, ts.conns, ts.tenant = &snapshot, conns, tenants
+	}
+	return snapshot
+}
+
+// startTrafficSnapshots takes a snapshot every TrafficInterval seconds until stop is called
+// (每TrafficInterval秒获取一次快照，直到调用stop)
+func (s *Server) startTrafficSnapshots() (stop func()) {
+	interval := time.Duration(s.config.TrafficInterval) * time.Second
+	if interval <= 0 {
+		return func() {}
+	}
+	s.traffic.take(s.ConnMgr, true)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.traffic.take(s.ConnMgr, true)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// GetTrafficSnapshot returns the latest periodic snapshot, or a live one when there is none
+// (返回最近一次定期快照，没有时返回实时快照)
+func (s *Server) GetTrafficSnapshot() ziface.TrafficSnapshot {
+	s.traffic.mu.Lock()
+	latest := s.traffic.latest
+	s.traffic.mu.Unlock()
+	if latest != nil && s.config.TrafficInterval > 0 {
+		return *latest
+	}
+	return s.traffic.take(s.ConnMgr, false)
+}
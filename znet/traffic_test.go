@@ -0,0 +1,51 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraffic(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.TrafficInterval = 0
+	s := newServerWithConfig(config, "tcp4").(*Server)
+	conn := &Connection{connID: 1, connIdStr: "1", msgHandler: s.msgHandler, connManager: s.ConnMgr}
+	s.ConnMgr.Add(conn)
+
+	conn.received(zmetrics.Zinx, 6)
+	conn.SetTenant("acme")
+	assert.Equal(t, "acme", conn.GetTenant())
+	conn.received(zmetrics.Zinx, 10)
+	observeMessage(conn, directionInbound, 1, []byte("ping"))
+	observeMessage(conn, directionOutbound, ziface.FragmentMsgID, []byte("fragment"))
+	conn.sent(zmetrics.Zinx, []byte("pong"))
+	observeMessage(conn, directionOutbound, 2, []byte("pong"))
+
+	assert.Equal(t, ziface.TrafficStats{BytesIn: 16, BytesOut: 4, MsgsIn: 1, MsgsOut: 1}, conn.GetTraffic())
+
+	snapshot := s.traffic.take(s.ConnMgr, true)
+	assert.Len(t, snapshot.Connections, 1)
+	assert.Equal(t, conn.GetTraffic(), snapshot.Connections[0].Delta)
+	assert.Equal(t, []ziface.TenantTraffic{{
+		Tenant:      "acme",
+		Connections: 1,
+		Total:       ziface.TrafficStats{BytesIn: 10, BytesOut: 4, MsgsIn: 1, MsgsOut: 1},
+		Delta:       ziface.TrafficStats{BytesIn: 10, BytesOut: 4, MsgsIn: 1, MsgsOut: 1},
+	}}, snapshot.Tenants)
+
+	conn.received(zmetrics.Zinx, 5)
+	snapshot = s.traffic.take(s.ConnMgr, true)
+	assert.Equal(t, ziface.TrafficStats{BytesIn: 5}, snapshot.Connections[0].Delta)
+	assert.Equal(t, ziface.TrafficStats{BytesIn: 5}, snapshot.Tenants[0].Delta)
+	assert.True(t, snapshot.Interval > 0)
+
+	s.ConnMgr.Remove(conn)
+	snapshot = s.GetTrafficSnapshot()
+	assert.Empty(t, snapshot.Connections)
+	assert.Equal(t, 0, snapshot.Tenants[0].Connections)
+	assert.Equal(t, uint64(15), snapshot.Tenants[0].Total.BytesIn)
+}
@@ -0,0 +1,177 @@
+package znet
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/aceld/zinx/zcodec"
+	"github.com/aceld/zinx/ziface"
+)
+
+// TypedHandlerFunc is a business handler working on decoded values instead of raw bytes
+// (直接处理解码后的值而不是原始字节的业务处理函数)
+type TypedHandlerFunc[TReq any, TResp any] func(ctx context.Context, conn ziface.IConnection, req TReq) (TResp, error)
+
+// TypedErrorReply builds the reply sent when the handler or the codec returns an error,
+// ok is false if nothing should be sent
+// (处理函数或编解码出错时构造回复内容，ok为false表示不回复)
+type TypedErrorReply func(request ziface.IRequest, err error) (msgID uint32, data []byte, ok bool)
+
+type typedOptions struct {
+	codec      ziface.ICodec
+	replyMsgID *uint32
+	noReply    bool
+	errorReply TypedErrorReply
+}
+
+// TypedOption configures a typed handler (类型化处理函数的配置项)
+type TypedOption func(o *typedOptions)
+
+// WithCodec sets the payload codec, zcodec.JSON by default (设置消息体编解码器，默认zcodec.JSON)
+func WithCodec(codec ziface.ICodec) TypedOption {
+	return func(o *typedOptions) {
+		o.codec = codec
+	}
+}
+
+// WithReplyMsgID sets the MsgID of the reply, the MsgID of the request by default
+// (设置回复的MsgID，默认与请求的MsgID相同)
+func WithReplyMsgID(msgID uint32) TypedOption {
+	return func(o *typedOptions) {
+		o.replyMsgID = &msgID
+	}
+}
+
+// WithNoReply discards the returned value instead of replying it (不回复处理函数的返回值)
+func WithNoReply() TypedOption {
+	return func(o *typedOptions) {
+		o.noReply = true
+	}
+}
+
+// WithErrorReply sets how errors are replied to the client, errors are only logged by default
+// (设置出错时如何回复客户端，默认只记录日志)
+func WithErrorReply(reply TypedErrorReply) TypedOption {
+	return func(o *typedOptions) {
+		o.errorReply = reply
+	}
+}
+
+// TypedHandler wraps fn into a RouterHandler which unmarshals the payload into TReq, calls fn
+// and marshals the returned TResp as the reply.
+// (将fn包装为RouterHandler：把消息体解码为TReq，调用fn，并将返回的TResp编码后回复)
+func TypedHandler[TReq any, TResp any](fn TypedHandlerFunc[TReq, TResp], opts ...TypedOption) ziface.RouterHandler {
+	o := &typedOptions{codec: zcodec.JSON}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(request ziface.IRequest) {
+		req := newTypedValue[TReq]()
+		if err := o.codec.Unmarshal(request.GetData(), typedTarget(&req)); err != nil {
+			o.fail(request, err)
+			return
+		}
+
+		resp, err := fn(request.GetContext(), request.GetConnection(), req)
+		if err != nil {
+			o.fail(request, err)
+			return
+		}
+		if o.noReply {
+			return
+		}
+
+		data, err := o.codec.Marshal(resp)
+		if err != nil {
+			o.fail(request, err)
+			return
+		}
+
+		msgID := request.GetMsgID()
+		if o.replyMsgID != nil {
+			msgID = *o.replyMsgID
+		}
+		if err := request.GetConnection().SendMsg(msgID, data); err != nil {
+			LoggerOf(request.GetConnection()).ErrorFX(requestLogCtx(request), "typed handler msgID = %d send reply error: %v", request.GetMsgID(), err)
+		}
+	}
+}
+
+// TypedRouter wraps fn into an IRouter, see TypedHandler (将fn包装为IRouter，参见TypedHandler)
+func TypedRouter[TReq any, TResp any](fn TypedHandlerFunc[TReq, TResp], opts ...TypedOption) ziface.IRouter {
+	return &handlerRouter{handler: TypedHandler(fn, opts...)}
+}
+
+// Handle registers fn for msgID on s, as a RouterHandler in RouterSlicesMode, otherwise as an IRouter.
+// (在s上为msgID注册fn，RouterSlicesMode下注册为RouterHandler，否则注册为IRouter)
+//
+//	znet.Handle(s, 1, func(ctx context.Context, conn ziface.IConnection, req *LoginReq) (*LoginResp, error) {
+//		return &LoginResp{Token: "..."}, nil
+//	})
+func Handle[TReq any, TResp any](s ziface.IServer, msgID uint32, fn TypedHandlerFunc[TReq, TResp], opts ...TypedOption) {
+	AddRouterHandler(s, msgID, TypedHandler(fn, opts...))
+}
+
+// AddRouterHandler registers handler for msgID on s whichever routing mode s uses,
+// as a RouterHandler in RouterSlicesMode, otherwise wrapped into an IRouter
+// (无论s使用哪种路由模式都为msgID注册handler，RouterSlicesMode下直接注册，否则包装为IRouter)
+func AddRouterHandler(s ziface.IServer, msgID uint32, handler ziface.RouterHandler) {
+	if srv, ok := s.(*Server); ok && srv.RouterSlicesMode {
+		s.AddRouterSlices(msgID, handler)
+		return
+	}
+	s.AddRouter(msgID, &handlerRouter{handler: handler})
+}
+
+// SetNotFoundHandler sets handler for the messages no router matches on s whichever routing mode s uses
+// (无论s使用哪种路由模式都将handler设置为未匹配任何路由的消息的处理函数)
+func SetNotFoundHandler(s ziface.IServer, handler ziface.RouterHandler) {
+	if srv, ok := s.(*Server); ok && srv.RouterSlicesMode {
+		s.SetNotFoundRouterSlices(handler)
+		return
+	}
+	s.SetNotFoundRouter(&handlerRouter{handler: handler})
+}
+
+// handlerRouter adapts a RouterHandler to an IRouter (将RouterHandler适配为IRouter)
+type handlerRouter struct {
+	BaseRouter
+	handler ziface.RouterHandler
+}
+
+func (r *handlerRouter) Handle(request ziface.IRequest) {
+	r.handler(request)
+}
+
+func (o *typedOptions) fail(request ziface.IRequest, err error) {
+	LoggerOf(request.GetConnection()).ErrorFX(requestLogCtx(request), "typed handler msgID = %d codec = %s error: %v", request.GetMsgID(), o.codec.Name(), err)
+	if o.errorReply == nil || request.GetConnection() == nil {
+		return
+	}
+	if msgID, data, ok := o.errorReply(request, err); ok {
+		if sendErr := request.GetConnection().SendMsg(msgID, data); sendErr != nil {
+			LoggerOf(request.GetConnection()).ErrorFX(requestLogCtx(request), "typed handler msgID = %d send error reply error: %v", request.GetMsgID(), sendErr)
+		}
+	}
+}
+
+// newTypedValue allocates the value pointed to when T is a pointer type,
+// so that *pb.Message style request types can be unmarshaled directly
+// (T为指针类型时分配其指向的值，使*pb.Message形式的请求类型可以直接解码)
+func newTypedValue[T any]() T {
+	var v T
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(T)
+	}
+	return v
+}
+
+// typedTarget returns the value to unmarshal into, the pointer itself for pointer types
+// (返回解码目标，指针类型直接返回指针本身)
+func typedTarget[T any](v *T) interface{} {
+	if t := reflect.TypeOf(*v); t != nil && t.Kind() == reflect.Ptr {
+		return *v
+	}
+	return v
+}
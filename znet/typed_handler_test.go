@@ -0,0 +1,62 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type sentMsg struct {
+	msgID uint32
+	data  []byte
+}
+
+type recordConn struct {
+	ziface.IConnection
+	sent []sentMsg
+}
+
+func (c *recordConn) GetMsgHandler() ziface.IMsgHandle { return nil }
+
+func (c *recordConn) RemoteAddrString() string { return "" }
+
+func (c *recordConn) GetConnID() uint64 { return 0 }
+
+func (c *recordConn) SendMsg(msgID uint32, data []byte) error {
+	c.sent = append(c.sent, sentMsg{msgID: msgID, data: data})
+	return nil
+}
+
+type loginReq struct {
+	Name string `json:"name"`
+}
+
+type loginResp struct {
+	Token string `json:"token"`
+}
+
+func TestTypedHandler(t *testing.T) {
+	handler := TypedHandler(func(ctx context.Context, conn ziface.IConnection, req *loginReq) (loginResp, error) {
+		if req.Name == "" {
+			return loginResp{}, errors.New("empty name")
+		}
+		return loginResp{Token: "token-" + req.Name}, nil
+	}, WithReplyMsgID(2), WithErrorReply(func(request ziface.IRequest, err error) (uint32, []byte, bool) {
+		return 500, []byte(err.Error()), true
+	}))
+
+	conn := &recordConn{}
+	handler(NewRequest(conn, zpack.NewMsgPackage(1, []byte(`{"name":"zinx"}`))))
+	handler(NewRequest(conn, zpack.NewMsgPackage(1, []byte(`{}`))))
+	handler(NewRequest(conn, zpack.NewMsgPackage(1, []byte(`not json`))))
+
+	assert.Len(t, conn.sent, 3)
+	assert.Equal(t, uint32(2), conn.sent[0].msgID)
+	assert.JSONEq(t, `{"token":"token-zinx"}`, string(conn.sent[0].data))
+	assert.Equal(t, sentMsg{msgID: 500, data: []byte("empty name")}, conn.sent[1])
+	assert.Equal(t, uint32(500), conn.sent[2].msgID)
+}
@@ -0,0 +1,138 @@
+package znet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"google.golang.org/protobuf/proto"
+)
+
+// ValidationError wraps the error returned by a validator with the MsgID of the rejected request
+// (校验失败的错误，包含被拒绝请求的MsgID)
+type ValidationError struct {
+	MsgID uint32
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("msgID = %d validation failed: %v", e.MsgID, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateJSON returns a Validator which requires the payload to be valid JSON of the type of v,
+// unknown fields are rejected
+// (返回一个校验器，要求消息体为v类型的合法JSON，不允许未知字段)
+func ValidateJSON(v interface{}) ziface.Validator {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return func(request ziface.IRequest) error {
+		dec := json.NewDecoder(bytes.NewReader(request.GetData()))
+		dec.DisallowUnknownFields()
+		return dec.Decode(reflect.New(t).Interface())
+	}
+}
+
+// ValidateProto returns a Validator which requires the payload to decode as the message type of m,
+// with all proto2 required fields set
+// (返回一个校验器，要求消息体可以解码为m的消息类型，并且proto2的required字段都已设置)
+func ValidateProto(m proto.Message) ziface.Validator {
+	return func(request ziface.IRequest) error {
+		msg := m.ProtoReflect().New().Interface()
+		return proto.Unmarshal(request.GetData(), msg)
+	}
+}
+
+// ValidationReply returns a ValidationFailedHandler which replies msgID with the error text
+// (返回一个校验失败时以msgID回复错误信息的处理函数)
+func ValidationReply(msgID uint32) ziface.ValidationFailedHandler {
+	return func(request ziface.IRequest, err error) {
+		conn := request.GetConnection()
+		if conn == nil {
+			return
+		}
+		if sendErr := conn.SendMsg(msgID, []byte(err.Error())); sendErr != nil {
+			LoggerOf(conn).ErrorF("send validation reply msgID = %d error: %v", msgID, sendErr)
+		}
+	}
+}
+
+// AddValidator registers validators run in order before the handler of msgID
+// (为msgID注册校验器，在处理函数之前按顺序执行)
+func (mh *MsgHandle) AddValidator(msgID uint32, validators ...ziface.Validator) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	if mh.validators == nil {
+		mh.validators = make(map[uint32][]ziface.Validator)
+		mh.validationFailures = make(map[uint32]*uint64)
+	}
+	mh.validators[msgID] = append(mh.validators[msgID], validators...)
+	if _, ok := mh.validationFailures[msgID]; !ok {
+		mh.validationFailures[msgID] = new(uint64)
+	}
+}
+
+// SetValidationFailedHandler sets what happens when a request is rejected, failures are only logged by default
+// (设置请求校验失败后的处理，默认只记录日志)
+func (mh *MsgHandle) SetValidationFailedHandler(handler ziface.ValidationFailedHandler) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	mh.onValidationFailed = handler
+}
+
+// GetValidationFailures returns the number of rejected requests of msgID
+// (获取msgID校验失败的请求数量)
+func (mh *MsgHandle) GetValidationFailures(msgID uint32) uint64 {
+	mh.apisLock.RLock()
+	defer mh.apisLock.RUnlock()
+
+	if counter, ok := mh.validationFailures[msgID]; ok {
+		return atomic.LoadUint64(counter)
+	}
+	return 0
+}
+
+// validate runs the validators of the request's route, it returns false if the request is rejected
+// (执行请求对应路由的校验器，返回false表示请求被拒绝)
+func (mh *MsgHandle) validate(request ziface.IRequest) bool {
+	msgID := request.GetMsgID()
+
+	mh.apisLock.RLock()
+	validators := mh.validators[msgID]
+	counter := mh.validationFailures[msgID]
+	onFailed := mh.onValidationFailed
+	mh.apisLock.RUnlock()
+
+	for _, v := range validators {
+		err := v(request)
+		if err == nil {
+			continue
+		}
+		// Denied by the authorization rather than invalid (被授权检查拒绝而非无效)
+		var ferr *ForbiddenError
+		if errors.As(err, &ferr) {
+			mh.forbid(request, ferr)
+			return false
+		}
+
+		atomic.AddUint64(counter, 1)
+		verr := &ValidationError{MsgID: msgID, Err: err}
+		mh.log().ErrorFX(requestLogCtx(request), "%v", verr)
+		if onFailed != nil {
+			onFailed(request, verr)
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMsgHandleValidator(t *testing.T) {
+	mh := newMsgHandle()
+	router := &blockRouter{handled: make(chan uint32, 2)}
+	mh.AddRouter(1, router)
+	mh.AddValidator(1, ValidateJSON(loginReq{}))
+	mh.SetValidationFailedHandler(ValidationReply(400))
+
+	conn := &recordConn{}
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(1, []byte(`{"name":"zinx"}`))), 0)
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(1, []byte(`{"unknown":1}`))), 0)
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(1, []byte(`oops`))), 0)
+
+	assert.Len(t, router.handled, 1)
+	assert.Equal(t, uint64(2), mh.GetValidationFailures(1))
+	assert.Len(t, conn.sent, 2)
+	assert.Equal(t, uint32(400), conn.sent[0].msgID)
+}
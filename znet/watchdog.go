@@ -0,0 +1,109 @@
+package znet
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
+)
+
+// Directions of the messages checked by the watchdog (看门狗检查的消息方向)
+const (
+	directionInbound  = "inbound"
+	directionOutbound = "outbound"
+)
+
+// Samplers of the watchdog lines, a regression makes every request slow or large
+// (看门狗日志的采样器，出现退化时每个请求都会变慢或变大)
+var (
+	slowHandlerLog  = zlog.PerSecond(1)
+	largeMessageLog = zlog.PerSecond(10)
+)
+
+// watchHandler wraps call with the slow handler watchdog: when call is still running after SlowHandlerThreshold,
+// the stack of its goroutine is logged, so that a handler stuck on a lock or a slow backend is found before
+// it takes the whole pool down
+// (为call加上慢处理函数看门狗：call在SlowHandlerThreshold后仍在运行时记录其协程的调用栈，
+// 以便在卡在锁或慢后端上的处理函数拖垮整个工作池之前发现它)
+func (mh *MsgHandle) watchHandler(request ziface.IRequest, workerID int, call func(ziface.IRequest)) func(ziface.IRequest) {
+	if mh.config == nil || mh.config.SlowHandlerThreshold <= 0 {
+		return call
+	}
+	threshold := time.Duration(mh.config.SlowHandlerThreshold) * time.Millisecond
+	return func(request ziface.IRequest) {
+		// The request may be recycled as soon as call returns, the watchdog only uses copies
+		// (call返回后请求可能被回收，看门狗只使用副本)
+		ctx := dispatchLogCtx(request)
+		msgID := request.GetMsgID()
+		gid := goroutineID()
+		start := time.Now()
+		timer := time.AfterFunc(threshold, func() {
+			mh.Metrics().SlowHandler(msgID)
+			if ctx, ok := slowHandlerLog.Allow(ctx); ok {
+				mh.log().ErrorFX(ctx, "workerID: %d msgID: %d handler still running after %v\n%s",
+					workerID, msgID, threshold, goroutineStack(gid))
+			}
+		})
+		call(request)
+		if !timer.Stop() {
+			mh.log().InfoFX(ctx, "workerID: %d msgID: %d slow handler finished after %v", workerID, msgID, time.Since(start))
+		}
+	}
+}
+
+// checkMessageSize logs the messages larger than LargeMessageSize (记录超过LargeMessageSize的消息)
+func checkMessageSize(conn ziface.IConnection, direction string, msgID uint32, size int) {
+	limit := configOf(conn).LargeMessageSize
+	if limit <= 0 || size <= limit {
+		return
+	}
+	if zmetrics.Enabled() {
+		metricsOf(conn).LargeMessages.With(direction).Inc()
+	}
+	if ctx, ok := largeMessageLog.Allow(connLogCtx(conn)); ok {
+		LoggerOf(conn).ErrorFX(zlog.WithFields(ctx, zlog.F(zlog.FieldMsgID, msgID)),
+			"%s message of %d bytes exceeds LargeMessageSize %d", direction, size, limit)
+	}
+	// Only the peer is to blame for the inbound messages (只有入站消息由对端造成)
+	if direction == directionInbound {
+		zlog.Security(zlog.WithFields(connLogCtx(conn), zlog.F(zlog.FieldMsgID, msgID)), zlog.SecurityOversized,
+			"exceeds LargeMessageSize", zlog.F("size", size), zlog.F("limit", limit))
+	}
+}
+
+// goroutineID returns the ID of the current goroutine, as printed in its stack (返回当前协程的ID，与调用栈中打印的一致)
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		id, _ := strconv.ParseUint(string(b[:i]), 10, 64)
+		return id
+	}
+	return 0
+}
+
+// goroutineStack returns the stack of the goroutine gid, it dumps every goroutine and is only used on slow handlers
+// (返回协程gid的调用栈，需要导出所有协程，只用于慢处理函数)
+func goroutineStack(gid uint64) []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	prefix := []byte("goroutine " + strconv.FormatUint(gid, 10) + " ")
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, prefix) {
+			return stack
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package znet
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdog(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	zlog.StdZinxLog.SetLogHook(func(b []byte) {
+		if !strings.Contains(string(b), "watchdog.go") {
+			return
+		}
+		mu.Lock()
+		lines = append(lines, string(b))
+		mu.Unlock()
+	})
+	defer zlog.StdZinxLog.SetLogHook(nil)
+
+	config := zconf.DefaultConfig()
+	config.SlowHandlerThreshold = 10
+	config.LargeMessageSize = 4
+	mh := newMsgHandleWithConfig(config)
+	conn := &Connection{connID: 9, connIdStr: "9", msgHandler: mh}
+	request := NewRequest(conn, zpack.NewMsgPackage(5, []byte("ping")))
+
+	mh.watchHandler(request, 1, func(ziface.IRequest) { time.Sleep(50 * time.Millisecond) })(request)
+	mh.watchHandler(request, 1, func(ziface.IRequest) {})(request)
+	checkMessageSize(conn, directionInbound, 5, 4)
+	checkMessageSize(conn, directionOutbound, 6, 5)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "msgID: 5 handler still running after 10ms")
+	assert.Contains(t, lines[0], "TestWatchdog")
+	assert.Contains(t, lines[1], "slow handler finished after")
+	assert.True(t, strings.Contains(lines[2], "outbound message of 5 bytes exceeds LargeMessageSize 4"), lines[2])
+	assert.Contains(t, lines[2], "connID=9")
+}
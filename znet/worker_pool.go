@@ -0,0 +1,107 @@
+package znet
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// routePool is a dedicated worker pool which specific routes are bound to (bulkhead),
+// so expensive routes cannot exhaust the shared pool.
+// (路由专属的worker池(舱壁隔离)，避免耗时的路由耗尽共享worker池)
+type routePool struct {
+	name      string
+	taskQueue []chan ziface.IRequest
+	priority  []chan ziface.IRequest
+	next      uint32
+}
+
+// queue returns the task queue of the worker of the pool handling the request, by ConnID to keep
+// per-connection ordering, or round-robin if the route is unordered
+// (返回池中处理该请求的worker的任务队列，按ConnID分配以保持连接内顺序，无序路由则轮询分配)
+func (p *routePool) queue(request ziface.IRequest, unordered, prioritized bool) chan ziface.IRequest {
+	size := uint64(len(p.taskQueue))
+	var idx uint64
+	if unordered || request.GetConnection() == nil {
+		idx = uint64(atomic.AddUint32(&p.next, 1)) % size
+	} else {
+		idx = request.GetConnection().GetConnID() % size
+	}
+	if prioritized {
+		return p.priority[idx]
+	}
+	return p.taskQueue[idx]
+}
+
+// AddWorkerPool creates a dedicated worker pool with its own size and queue length, routes are bound
+// to it with SetRouterPool. It panics if a pool with the same name already exists.
+// (创建一个拥有独立大小和队列长度的专属worker池，通过SetRouterPool将路由绑定到该池。同名池已存在时panic)
+func (mh *MsgHandle) AddWorkerPool(name string, size uint32, queueLen uint32) {
+	if size == 0 {
+		panic("worker pool " + name + " size must be greater than 0")
+	}
+
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	if _, ok := mh.pools[name]; ok {
+		panic("repeated worker pool , name = " + name)
+	}
+	if mh.pools == nil {
+		mh.pools = make(map[string]*routePool)
+	}
+
+	pool := &routePool{
+		name:      name,
+		taskQueue: make([]chan ziface.IRequest, size),
+		priority:  make([]chan ziface.IRequest, size),
+	}
+	for i := range pool.taskQueue {
+		pool.taskQueue[i] = make(chan ziface.IRequest, queueLen)
+		pool.priority[i] = make(chan ziface.IRequest, queueLen)
+		go mh.startWorker(name, i, pool.taskQueue[i], pool.priority[i])
+	}
+	mh.pools[name] = pool
+	mh.log().InfoF("Add worker pool name = %s, size = %d, queueLen = %d", name, size, queueLen)
+}
+
+// SetRouterPool binds msgID to the worker pool name, an empty name binds it back to the shared pool.
+// It panics if the pool does not exist.
+// (将msgID绑定到名为name的worker池，name为空时恢复使用共享worker池。池不存在时panic)
+func (mh *MsgHandle) SetRouterPool(msgID uint32, name string) {
+	mh.apisLock.Lock()
+	defer mh.apisLock.Unlock()
+
+	if name == "" {
+		delete(mh.routePools, msgID)
+		return
+	}
+
+	pool, ok := mh.pools[name]
+	if !ok {
+		panic(fmt.Sprintf("worker pool %s not found, msgID = %d", name, msgID))
+	}
+	if mh.routePools == nil {
+		mh.routePools = make(map[uint32]*routePool)
+	}
+	mh.routePools[msgID] = pool
+}
+
+func (mh *MsgHandle) getRoutePool(msgID uint32) *routePool {
+	mh.apisLock.RLock()
+	defer mh.apisLock.RUnlock()
+	return mh.routePools[msgID]
+}
+
+// restartPools starts the workers of the dedicated pools again after they quit, see StartWorkerPoolContext
+// (专属池的worker退出后重新启动它们，见StartWorkerPoolContext)
+func (mh *MsgHandle) restartPools() {
+	mh.apisLock.RLock()
+	defer mh.apisLock.RUnlock()
+	for name, pool := range mh.pools {
+		for i := range pool.taskQueue {
+			go mh.startWorker(name, i, pool.taskQueue[i], pool.priority[i])
+		}
+	}
+}
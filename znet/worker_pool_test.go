@@ -0,0 +1,51 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type blockRouter struct {
+	BaseRouter
+	release chan struct{}
+	handled chan uint32
+}
+
+func (r *blockRouter) Handle(request ziface.IRequest) {
+	if request.GetMsgID() == 100 {
+		<-r.release
+	}
+	r.handled <- request.GetMsgID()
+}
+
+func TestMsgHandleWorkerPool(t *testing.T) {
+	mh := newMsgHandle()
+	router := &blockRouter{release: make(chan struct{}), handled: make(chan uint32, 4)}
+	mh.AddRouter(100, router)
+	mh.AddRouter(1, router)
+
+	mh.AddWorkerPool("report", 1, 4)
+	mh.SetRouterPool(100, "report")
+	assert.Panics(t, func() { mh.SetRouterPool(1, "missing") })
+	assert.Panics(t, func() { mh.AddWorkerPool("report", 1, 4) })
+
+	conn := &workerConn{}
+	mh.SendMsgToTaskQueue(NewRequest(conn, zpack.NewMsgPackage(100, nil)))
+
+	// The report worker is blocked, the shared route still goes through
+	// (report池的worker被阻塞，共享池的路由仍然可以执行)
+	mh.doMsgHandler(NewRequest(conn, zpack.NewMsgPackage(1, nil)), 0)
+	assert.Equal(t, uint32(1), <-router.handled)
+
+	close(router.release)
+	select {
+	case id := <-router.handled:
+		assert.Equal(t, uint32(100), id)
+	case <-time.After(time.Second):
+		t.Fatal("report route is not handled")
+	}
+}
@@ -0,0 +1,111 @@
+package znet
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
+
+// ErrWorkerPoolNotResizable is returned by ResizeWorkerPool when the workers are bound to connections
+// (worker绑定到连接时ResizeWorkerPool返回的错误)
+var ErrWorkerPoolNotResizable = errors.New("zinx: worker pool can only be resized in WorkerModeHash")
+
+// isHashWorkerMode reports whether connections are assigned to workers by ConnID in WorkerMode mode
+// (WorkerMode为mode时是否按ConnID为连接分配worker)
+func isHashWorkerMode(mode string) bool {
+	return mode != zconf.WorkerModeBind && mode != zconf.WorkerModeDynamicBind && mode != zconf.WorkerModeSteal
+}
+
+// runWithoutWorker handles the request on its own goroutine, used when there is no worker pool
+// (在单独的协程中处理请求，用于没有worker池的情况)
+func (mh *MsgHandle) runWithoutWorker(request ziface.IRequest) {
+	if req, ok := request.(ziface.IFuncRequest); ok {
+		go mh.doFuncHandler(req, WorkerIDWithoutWorkerPool)
+		return
+	}
+
+	if !mh.config.RouterSlicesMode {
+		go mh.doMsgHandler(request, WorkerIDWithoutWorkerPool)
+	} else {
+		go mh.doMsgHandlerSlices(request, WorkerIDWithoutWorkerPool)
+	}
+}
+
+// GetWorkerPoolSize returns the current number of workers of the shared pool (返回共享worker池当前的worker数量)
+func (mh *MsgHandle) GetWorkerPoolSize() uint32 {
+	mh = mh.pool()
+	mh.workersLock.RLock()
+	defer mh.workersLock.RUnlock()
+	return mh.WorkerPoolSize
+}
+
+// ResizeWorkerPool changes the number of workers of the shared pool while the server is running.
+// Removed workers stop taking new requests and exit once their queue is drained, size 0 switches
+// to one goroutine per request and a size greater than 0 switches back to the pool.
+// While resizing, the requests of a connection may move to another worker, so a request already
+// queued on the old worker can run concurrently with the following ones.
+// It is only supported in WorkerModeHash, the dedicated pools of AddWorkerPool are not affected.
+// (在服务运行期间调整共享worker池的worker数量。被移除的worker不再接收新请求，处理完队列中的请求后退出，
+// size为0时切换为每个请求一个协程，大于0时切换回worker池。
+// 调整期间连接的请求可能被分配到另一个worker，旧worker队列中的请求可能与后续请求并发执行。
+// 仅支持WorkerModeHash，不影响AddWorkerPool创建的专属worker池)
+func (mh *MsgHandle) ResizeWorkerPool(size uint32) error {
+	mh = mh.pool()
+	if !isHashWorkerMode(mh.config.WorkerMode) {
+		return ErrWorkerPoolNotResizable
+	}
+
+	mh.workersLock.Lock()
+	defer mh.workersLock.Unlock()
+
+	old := mh.WorkerPoolSize
+	if size == old {
+		return nil
+	}
+
+	if !mh.workersStarted {
+		// StartWorkerPool creates the queues (由StartWorkerPool创建队列)
+		mh.TaskQueue = make([]chan ziface.IRequest, size)
+		mh.priorityQueue = make([]chan ziface.IRequest, size)
+		mh.WorkerPoolSize = size
+		return nil
+	}
+
+	queues := make([]chan ziface.IRequest, size)
+	lanes := make([]chan ziface.IRequest, size)
+	copy(queues, mh.TaskQueue)
+	copy(lanes, mh.priorityQueue)
+	for i := old; i < size; i++ {
+		queues[i] = make(chan ziface.IRequest, mh.config.Live().MaxWorkerTaskLen)
+		lanes[i] = make(chan ziface.IRequest, mh.config.Live().MaxWorkerTaskLen)
+		go mh.startWorker("", int(i), queues[i], lanes[i])
+	}
+	// Closing the queues lets the worker drain them and quit, once the requests that picked them
+	// before the resize have been sent (等待调整前已选定这些队列的请求发送完成后关闭队列，worker处理完剩余请求后退出)
+	if size < old {
+		go closeQueues(mh.sending, mh.TaskQueue[size:old], mh.priorityQueue[size:old])
+		mh.sending = &sync.WaitGroup{}
+	}
+
+	mh.TaskQueue = queues
+	mh.priorityQueue = lanes
+	mh.WorkerPoolSize = size
+	mh.log().InfoF("Resize worker pool from %d to %d", old, size)
+	return nil
+}
+
+// closeQueues closes the queues and lanes of removed workers once the sends registered on sending are done
+// (sending上登记的发送完成后关闭被移除worker的队列和优先通道)
+func closeQueues(sending *sync.WaitGroup, queues, lanes []chan ziface.IRequest) {
+	sending.Wait()
+	for _, lane := range lanes {
+		if lane != nil {
+			close(lane)
+		}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+}
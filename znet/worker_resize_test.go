@@ -0,0 +1,111 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type countRouter struct {
+	BaseRouter
+	handled chan uint32
+}
+
+func (r *countRouter) Handle(request ziface.IRequest) {
+	r.handled <- request.GetMsgID()
+}
+
+func TestMsgHandleResizeWorkerPool(t *testing.T) {
+	mh := newMsgHandle()
+	router := &countRouter{handled: make(chan uint32, 16)}
+	mh.AddRouter(1, router)
+
+	assert.Nil(t, mh.ResizeWorkerPool(2))
+	mh.StartWorkerPool()
+
+	receive := func(n int) {
+		for i := 0; i < n; i++ {
+			select {
+			case <-router.handled:
+			case <-time.After(time.Second):
+				t.Fatal("request is not handled")
+			}
+		}
+	}
+
+	for _, size := range []uint32{4, 1, 0, 3} {
+		assert.Nil(t, mh.ResizeWorkerPool(size))
+		assert.Equal(t, size, mh.GetWorkerPoolSize())
+		assert.Len(t, mh.TaskQueue, int(size))
+		for id := uint32(0); id < 4; id++ {
+			mh.SendMsgToTaskQueue(NewRequest(&workerConn{workerID: id}, zpack.NewMsgPackage(1, nil)))
+		}
+		receive(4)
+	}
+
+	zconf.GlobalObject.WorkerMode = zconf.WorkerModeBind
+	defer func() { zconf.GlobalObject.WorkerMode = "" }()
+	assert.Equal(t, ErrWorkerPoolNotResizable, mh.ResizeWorkerPool(8))
+}
+
+type gateRouter struct {
+	BaseRouter
+	gate    chan struct{}
+	handled chan uint32
+}
+
+func (r *gateRouter) Handle(request ziface.IRequest) {
+	<-r.gate
+	r.handled <- request.GetMsgID()
+}
+
+func TestMsgHandleResizeWhileSendBlocks(t *testing.T) {
+	config := zconf.DefaultConfig()
+	config.WorkerPoolSize = 2
+	config.MaxWorkerTaskLen = 1
+	mh := newMsgHandleWithConfig(config)
+	router := &gateRouter{gate: make(chan struct{}), handled: make(chan uint32, 4)}
+	mh.AddRouter(1, router)
+	mh.StartWorkerPool()
+
+	// The worker of connection 1 blocks in the handler, the second request fills its queue
+	// and the third one blocks in the send
+	// (连接1的worker阻塞在处理函数中，第二个请求占满其队列，第三个请求阻塞在发送中)
+	send := func() {
+		mh.SendMsgToTaskQueue(NewRequest(&workerConn{workerID: 1}, zpack.NewMsgPackage(1, nil)))
+	}
+	send()
+	assert.Eventually(t, func() bool { return mh.GetQueueDepths()[1] == 0 }, time.Second, time.Millisecond)
+	send()
+	sent := make(chan struct{})
+	go func() {
+		send()
+		close(sent)
+	}()
+
+	resized := make(chan error)
+	go func() { resized <- mh.ResizeWorkerPool(1) }()
+	select {
+	case err := <-resized:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("ResizeWorkerPool waits for a blocked send")
+	}
+	assert.Equal(t, uint32(1), mh.GetWorkerPoolSize())
+
+	// The removed worker still handles the requests queued before the resize
+	// (被移除的worker仍会处理调整前入队的请求)
+	close(router.gate)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-router.handled:
+		case <-time.After(time.Second):
+			t.Fatal("request is not handled")
+		}
+	}
+	<-sent
+}
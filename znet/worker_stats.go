@@ -0,0 +1,136 @@
+package znet
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// latencyBounds are the upper bounds of the buckets of the worker latency histograms
+// (worker延迟直方图各桶的上界)
+var latencyBounds = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// latencyHistogram is a fixed bucket histogram updated with atomics (使用原子操作更新的固定分桶直方图)
+type latencyHistogram struct {
+	counts [11]uint64 // len(latencyBounds)+1
+	count  uint64
+	sum    int64
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := sort.Search(len(latencyBounds), func(i int) bool { return d <= latencyBounds[i] })
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+func (h *latencyHistogram) snapshot() ziface.HistogramSnapshot {
+	s := ziface.HistogramSnapshot{
+		Bounds: latencyBounds,
+		Counts: make([]uint64, len(h.counts)),
+		Count:  atomic.LoadUint64(&h.count),
+		Sum:    time.Duration(atomic.LoadInt64(&h.sum)),
+	}
+	for i := range h.counts {
+		s.Counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	return s
+}
+
+type workerKey struct {
+	pool string
+	id   int
+}
+
+// workerStats holds the counters of one worker, queueLen reports the requests waiting for it
+// (单个worker的统计数据，queueLen返回等待该worker处理的请求数)
+type workerStats struct {
+	workerKey
+	started  time.Time
+	queueLen func() int
+	handled  uint64
+	busy     int64
+	wait     latencyHistogram
+	exec     latencyHistogram
+}
+
+// addWorkerStats registers the counters of a started worker (注册已启动worker的统计数据)
+func (mh *MsgHandle) addWorkerStats(pool string, workerID int, queueLen func() int) *workerStats {
+	w := &workerStats{workerKey: workerKey{pool: pool, id: workerID}, started: time.Now(), queueLen: queueLen}
+	mh.workerStats.Store(w.workerKey, w)
+	return w
+}
+
+// removeWorkerStats drops the counters of a stopped worker, unless a new worker already took its place
+// (删除已停止worker的统计数据，除非已有新的worker取代了它)
+func (mh *MsgHandle) removeWorkerStats(w *workerStats) {
+	if current, ok := mh.workerStats.Load(w.workerKey); ok && current == w {
+		mh.workerStats.Delete(w.workerKey)
+	}
+}
+
+// handle runs the request on the worker and records its wait and execution time
+// (在worker上执行请求，并记录其等待时间和执行时间)
+func (w *workerStats) handle(mh *MsgHandle, request ziface.IRequest) {
+	start := time.Now()
+	if created := request.GetCreateTime(); !created.IsZero() {
+		w.wait.observe(start.Sub(created))
+	}
+
+	ownerOf(mh, request).handleTask(request, w.id)
+
+	elapsed := time.Since(start)
+	w.exec.observe(elapsed)
+	atomic.AddInt64(&w.busy, int64(elapsed))
+	atomic.AddUint64(&w.handled, 1)
+}
+
+func (w *workerStats) snapshot() ziface.WorkerStats {
+	s := ziface.WorkerStats{
+		Pool:     w.pool,
+		WorkerID: w.id,
+		Handled:  atomic.LoadUint64(&w.handled),
+		Busy:     time.Duration(atomic.LoadInt64(&w.busy)),
+		Uptime:   time.Since(w.started),
+		WaitTime: w.wait.snapshot(),
+		ExecTime: w.exec.snapshot(),
+	}
+	if w.queueLen != nil {
+		s.QueueLen = w.queueLen()
+	}
+	if s.Uptime > 0 {
+		s.BusyRatio = float64(s.Busy) / float64(s.Uptime)
+	}
+	return s
+}
+
+// GetWorkerStats returns a snapshot of the counters of every running worker, of the shared pool first
+// and then of the pools of AddWorkerPool, ordered by worker ID
+// (返回所有运行中worker的统计快照，先是共享池，然后是AddWorkerPool创建的池，按worker ID排序)
+func (mh *MsgHandle) GetWorkerStats() []ziface.WorkerStats {
+	var stats []ziface.WorkerStats
+	mh.workerStats.Range(func(_, value interface{}) bool {
+		stats = append(stats, value.(*workerStats).snapshot())
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Pool != stats[j].Pool {
+			return stats[i].Pool < stats[j].Pool
+		}
+		return stats[i].WorkerID < stats[j].WorkerID
+	})
+	return stats
+}
@@ -0,0 +1,46 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	h.observe(50 * time.Microsecond)
+	h.observe(time.Millisecond)
+	h.observe(time.Minute)
+
+	s := h.snapshot()
+	assert.Equal(t, uint64(3), s.Count)
+	assert.Equal(t, uint64(1), s.Counts[0])
+	assert.Equal(t, uint64(1), s.Counts[2])
+	assert.Equal(t, uint64(1), s.Counts[len(latencyBounds)])
+	assert.Equal(t, time.Minute+time.Millisecond+50*time.Microsecond, s.Sum)
+}
+
+func TestMsgHandleWorkerStats(t *testing.T) {
+	mh := newMsgHandle()
+	router := &countRouter{handled: make(chan uint32, 4)}
+	mh.AddRouter(1, router)
+	mh.AddWorkerPool("report", 2, 4)
+	mh.SetRouterPool(1, "report")
+
+	mh.SendMsgToTaskQueue(NewRequest(&workerConn{workerID: 1}, zpack.NewMsgPackage(1, nil)))
+	<-router.handled
+
+	assert.Eventually(t, func() bool {
+		stats := mh.GetWorkerStats()
+		return len(stats) == 2 && stats[1].Handled == 1
+	}, time.Second, 10*time.Millisecond)
+
+	stats := mh.GetWorkerStats()
+	assert.Equal(t, "report", stats[1].Pool)
+	assert.Equal(t, 1, stats[1].WorkerID)
+	assert.Equal(t, uint64(1), stats[1].WaitTime.Count)
+	assert.Equal(t, uint64(1), stats[1].ExecTime.Count)
+	assert.Equal(t, uint64(0), stats[0].Handled)
+}
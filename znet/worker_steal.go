@@ -0,0 +1,264 @@
+package znet
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+)
+
+// stealBatch is the number of requests of one connection a worker handles before the other
+// connections of its run queue get a turn (worker处理一个连接的请求数达到该值后轮到其运行队列中的其他连接)
+const stealBatch = 64
+
+// connQueue holds the pending requests of one connection, it is scheduled on at most one worker at a time
+// (一个连接待处理的请求，同一时刻最多被调度到一个worker上)
+type connQueue struct {
+	connID   uint64
+	requests []ziface.IRequest
+	urgent   int  // prioritized requests at the head of requests (位于requests头部的高优先级请求数)
+	detached bool // requests of unordered routes, not tracked by connection (无序路由的请求，不按连接跟踪)
+}
+
+// add queues the request, a prioritized one goes ahead of the requests which are not prioritized
+// (将请求排队，高优先级请求排在非高优先级请求之前)
+func (cq *connQueue) add(request ziface.IRequest, prioritized bool) {
+	if !prioritized || cq.urgent == len(cq.requests) {
+		if prioritized {
+			cq.urgent++
+		}
+		cq.requests = append(cq.requests, request)
+		return
+	}
+	cq.requests = append(cq.requests, nil)
+	copy(cq.requests[cq.urgent+1:], cq.requests[cq.urgent:])
+	cq.requests[cq.urgent] = request
+	cq.urgent++
+}
+
+// runQueue holds the connection queues scheduled on one worker (调度到一个worker上的连接队列)
+type runQueue struct {
+	queues []*connQueue
+	sync.Mutex
+}
+
+func (rq *runQueue) push(cq *connQueue) {
+	rq.Lock()
+	rq.queues = append(rq.queues, cq)
+	rq.Unlock()
+}
+
+// pop takes the oldest queue, used by the owner of the run queue (取出最早的队列，由运行队列的所有者使用)
+func (rq *runQueue) pop() *connQueue {
+	rq.Lock()
+	defer rq.Unlock()
+	if len(rq.queues) == 0 {
+		return nil
+	}
+	cq := rq.queues[0]
+	rq.queues[0] = nil
+	rq.queues = rq.queues[1:]
+	return cq
+}
+
+// steal takes the newest queue, used by the other workers (取出最新的队列，由其他worker使用)
+func (rq *runQueue) steal() *connQueue {
+	rq.Lock()
+	defer rq.Unlock()
+	n := len(rq.queues)
+	if n == 0 {
+		return nil
+	}
+	cq := rq.queues[n-1]
+	rq.queues[n-1] = nil
+	rq.queues = rq.queues[:n-1]
+	return cq
+}
+
+// stealScheduler is the work-stealing scheduler of zconf.WorkerModeSteal. Requests are queued per connection
+// and a connection queue is run by one worker at a time, which keeps the per-connection ordering, while idle
+// workers steal whole connection queues from the run queues of busy workers.
+// (zconf.WorkerModeSteal模式的工作窃取调度器。请求按连接排队，一个连接队列同一时刻只由一个worker执行，
+// 从而保持连接内的顺序，空闲的worker从繁忙worker的运行队列中窃取整个连接队列)
+type stealScheduler struct {
+	mh      *MsgHandle
+	workers []*runQueue
+	wake    []chan struct{}
+	idle    chan struct{}
+	next    uint32
+
+	// conns holds the connection queues which are scheduled, space is signaled when a full one shrinks
+	// (已被调度的连接队列，队列已满后有空间时通知space)
+	conns map[uint64]*connQueue
+	space *sync.Cond
+	sync.Mutex
+}
+
+func newStealScheduler(mh *MsgHandle, size int) *stealScheduler {
+	s := &stealScheduler{
+		mh:      mh,
+		workers: make([]*runQueue, size),
+		wake:    make([]chan struct{}, size),
+		idle:    make(chan struct{}, size),
+		conns:   make(map[uint64]*connQueue),
+	}
+	s.space = sync.NewCond(&s.Mutex)
+	for i := range s.workers {
+		s.workers[i] = new(runQueue)
+		s.wake[i] = make(chan struct{}, 1)
+		go s.run(i)
+	}
+	mh.log().InfoF("Work-stealing scheduler is started, size = %d", size)
+	return s
+}
+
+// dispatch queues the request on its connection and schedules the connection on the worker chosen by its ConnID
+// (将请求放入其连接的队列，并将连接调度到ConnID对应的worker上)
+func (s *stealScheduler) dispatch(request ziface.IRequest, unordered bool) {
+	if unordered || request.GetConnection() == nil {
+		id := atomic.AddUint32(&s.next, 1) % uint32(len(s.workers))
+		s.push(int(id), &connQueue{requests: []ziface.IRequest{request}, detached: true})
+		return
+	}
+
+	connID := request.GetConnection().GetConnID()
+	_, isFunc := request.(ziface.IFuncRequest)
+	prioritized := s.mh.getRouterPriority(request.GetMsgID()) > 0
+	max := int(s.mh.config.Live().MaxWorkerTaskLen)
+
+	s.Lock()
+	for {
+		cq, ok := s.conns[connID]
+		if !ok {
+			cq = &connQueue{connID: connID, requests: []ziface.IRequest{request}}
+			s.conns[connID] = cq
+			s.Unlock()
+			s.push(int(connID%uint64(len(s.workers))), cq)
+			return
+		}
+		if max == 0 || len(cq.requests) < max || isFunc {
+			cq.add(request, prioritized)
+			s.Unlock()
+			return
+		}
+		if s.mh.config.Live().QueueFullPolicy == zconf.QueueFullShed && prioritized {
+			cq.add(request, prioritized)
+			s.Unlock()
+			return
+		}
+		if policy := s.mh.config.Live().QueueFullPolicy; policy != "" && policy != zconf.QueueFullBlock {
+			s.Unlock()
+			s.overflow(request, policy)
+			return
+		}
+		s.space.Wait()
+	}
+}
+
+// overflow handles a request of a full connection queue, the queue cannot be waited on with a timeout
+// so QueueFullBlockTimeout drops at once
+// (处理连接队列已满时的请求，连接队列无法限时等待，因此QueueFullBlockTimeout会立即丢弃)
+func (s *stealScheduler) overflow(request ziface.IRequest, policy string) {
+	if policy == zconf.QueueFullSpill {
+		atomic.AddUint64(&s.mh.spillCount, 1)
+		s.mh.runWithoutWorker(request)
+		return
+	}
+	s.mh.drop(request)
+}
+
+func (s *stealScheduler) push(workerID int, cq *connQueue) {
+	s.workers[workerID].push(cq)
+	select {
+	case s.wake[workerID] <- struct{}{}:
+	default:
+	}
+	// Let an idle worker steal it if the owner is busy (所有者繁忙时让空闲的worker窃取)
+	select {
+	case s.idle <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the next connection queue of workerID, stolen from another worker if its own run queue is empty
+// (返回workerID的下一个连接队列，自己的运行队列为空时从其他worker窃取)
+func (s *stealScheduler) take(workerID int) *connQueue {
+	if cq := s.workers[workerID].pop(); cq != nil {
+		return cq
+	}
+	for i := 1; i < len(s.workers); i++ {
+		if cq := s.workers[(workerID+i)%len(s.workers)].steal(); cq != nil {
+			return cq
+		}
+	}
+	return nil
+}
+
+// queueLen returns the number of requests scheduled on workerID (返回调度到workerID上的请求数)
+func (s *stealScheduler) queueLen(workerID int) int {
+	rq := s.workers[workerID]
+	rq.Lock()
+	queues := append([]*connQueue(nil), rq.queues...)
+	rq.Unlock()
+
+	s.Lock()
+	defer s.Unlock()
+	n := 0
+	for _, cq := range queues {
+		n += len(cq.requests)
+	}
+	return n
+}
+
+func (s *stealScheduler) run(workerID int) {
+	s.mh.log().DebugFX(dispatcherLogCtx, "Worker ID = %d is started.", workerID)
+	stats := s.mh.addWorkerStats("", workerID, func() int { return s.queueLen(workerID) })
+	quit := s.mh.workerQuit()
+	for {
+		cq := s.take(workerID)
+		if cq == nil {
+			select {
+			case <-s.wake[workerID]:
+			case <-s.idle:
+			case <-quit:
+				s.mh.removeWorkerStats(stats)
+				return
+			}
+			continue
+		}
+		if !s.drain(stats, cq) {
+			// Give the other connections a turn (让其他连接有机会执行)
+			s.push(workerID, cq)
+		}
+	}
+}
+
+// drain handles up to stealBatch requests of cq, it returns true once cq is empty and no longer scheduled
+// (处理cq中最多stealBatch个请求，cq为空且不再被调度时返回true)
+func (s *stealScheduler) drain(stats *workerStats, cq *connQueue) bool {
+	max := int(s.mh.config.Live().MaxWorkerTaskLen)
+	for i := 0; i < stealBatch; i++ {
+		s.Lock()
+		if len(cq.requests) == 0 {
+			if !cq.detached {
+				delete(s.conns, cq.connID)
+			}
+			s.Unlock()
+			return true
+		}
+		request := cq.requests[0]
+		cq.requests[0] = nil
+		cq.requests = cq.requests[1:]
+		if cq.urgent > 0 {
+			cq.urgent--
+		}
+		if len(cq.requests)+1 == max {
+			s.space.Broadcast()
+		}
+		s.Unlock()
+
+		stats.handle(s.mh, request)
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package znet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderRouter struct {
+	BaseRouter
+	received chan byte
+}
+
+func (r *orderRouter) Handle(request ziface.IRequest) {
+	r.received <- request.GetData()[0]
+}
+
+func TestMsgHandleWorkStealing(t *testing.T) {
+	zconf.GlobalObject.WorkerMode = zconf.WorkerModeSteal
+	defer func() { zconf.GlobalObject.WorkerMode = "" }()
+
+	mh := newMsgHandle()
+	mh.WorkerPoolSize = 2
+	router := &blockRouter{release: make(chan struct{}), handled: make(chan uint32, 4)}
+	mh.AddRouter(100, router)
+	mh.AddRouter(1, router)
+	order := &orderRouter{received: make(chan byte, 64)}
+	mh.AddRouter(2, order)
+	mh.StartWorkerPool()
+	defer close(router.release)
+
+	// Both connections start on worker 0, which is blocked by the first one
+	// (两个连接都从worker 0开始，worker 0被第一个连接阻塞)
+	mh.SendMsgToTaskQueue(NewRequest(&workerConn{workerID: 0}, zpack.NewMsgPackage(100, nil)))
+	mh.SendMsgToTaskQueue(NewRequest(&workerConn{workerID: 2}, zpack.NewMsgPackage(1, nil)))
+	select {
+	case id := <-router.handled:
+		assert.Equal(t, uint32(1), id)
+	case <-time.After(time.Second):
+		t.Fatal("connection is not stolen by the idle worker")
+	}
+
+	conn := &workerConn{workerID: 3}
+	for i := 0; i < 50; i++ {
+		mh.SendMsgToTaskQueue(NewRequest(conn, zpack.NewMsgPackage(2, []byte{byte(i)})))
+	}
+	for i := 0; i < 50; i++ {
+		select {
+		case b := <-order.received:
+			assert.Equal(t, byte(i), b)
+		case <-time.After(time.Second):
+			t.Fatal("request is not handled")
+		}
+	}
+}
@@ -8,13 +8,15 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zerrors"
 	"github.com/aceld/zinx/ziface"
 	"github.com/aceld/zinx/zinterceptor"
 	"github.com/aceld/zinx/zlog"
 	"github.com/aceld/zinx/zpack"
+	"github.com/aceld/zinx/ztimer"
 	"github.com/gorilla/websocket"
 )
 
@@ -54,7 +56,7 @@ type WsConnection struct {
 
 	// msgBuffChan is a buffered channel used for message communication between the read and write goroutines.
 	// (有缓冲管道，用于读、写两个goroutine之间的消息通信)
-	msgBuffChan chan []byte
+	msgBuffChan chan queuedData
 
 	// msgLock is used for locking when users send and receive messages.
 	// (用户收发消息的Lock)
@@ -84,14 +86,22 @@ type WsConnection struct {
 	// (数据报文封包方式)
 	packet ziface.IDataPack
 
-	// lastActivityTime is the last time the connection was active.
-	// (最后一次活动时间)
-	lastActivityTime time.Time
+	// lastActivityTime is the last time the connection was active, a time.Time written by the reader and read by
+	// the heartbeat checker.
+	// (最后一次活动时间，由读协程写入、心跳检测器读取)
+	lastActivityTime atomic.Value
 
 	// frameDecoder is the decoder for splitting or splicing data packets.
 	// (断粘包解码器)
 	frameDecoder ziface.IFrameDecoder
 
+	// Protocols of the server to pick from with the first bytes read, nil once picked
+	// (根据最先读到的字节从中选择的服务器协议，选择后置为nil)
+	protocols []ziface.Protocol
+
+	// Hooks called with every outbound message before it is packed (每条发出消息封包前调用的钩子)
+	sendInterceptors []ziface.SendInterceptor
+
 	// hc is the Heartbeat Checker. (心跳检测器)
 	hc ziface.IHeartbeatChecker
 
@@ -110,6 +120,12 @@ type WsConnection struct {
 
 	// Close callback mutex
 	closeCallbackMutex sync.RWMutex
+
+	// Why the connection was closed, for the metrics (连接关闭的原因，用于指标统计)
+	closeReason
+	connTraffic
+	connDrain
+	connFragments
 }
 
 // newServerConn: for Server, a method to create a connection with Server characteristics
@@ -138,6 +154,8 @@ func newWebsocketConn(server ziface.IServer, conn *websocket.Conn, connID uint64
 
 	// Inherited attributes from server (从server继承过来的属性)
 	c.packet = server.GetPacket()
+	c.protocols = server.GetProtocols()
+	c.sendInterceptors = server.GetSendInterceptors()
 	c.onConnStart = server.GetOnConnStart()
 	c.onConnStop = server.GetOnConnStop()
 	c.msgHandler = server.GetMsgHandler()
@@ -173,6 +191,7 @@ func newWsClientConn(client ziface.IClient, conn *websocket.Conn) ziface.IConnec
 
 	// Inherit properties from client (从client继承过来的属性)
 	c.packet = client.GetPacket()
+	c.sendInterceptors = client.GetSendInterceptors()
 	c.onConnStart = client.GetOnConnStart()
 	c.onConnStop = client.GetOnConnStop()
 	c.msgHandler = client.GetMsgHandler()
@@ -183,20 +202,25 @@ func newWsClientConn(client ziface.IClient, conn *websocket.Conn) ziface.IConnec
 // StartWriter is a Goroutine that sends messages to the client
 // (StartWriter 写消息Goroutine， 用户将数据发送给客户端)
 func (c *WsConnection) StartWriter() {
-	zlog.Ins().InfoF("Writer Goroutine is running")
-	defer zlog.Ins().InfoF("%s [conn Writer exit!]", c.RemoteAddr().String())
+	LoggerOf(c).InfoFX(connLogCtx(c), "Writer Goroutine is running")
+	defer LoggerOf(c).InfoFX(connLogCtx(c), "%s [conn Writer exit!]", c.RemoteAddr().String())
 
 	for {
 		select {
-		case data, ok := <-c.msgBuffChan:
+		case queued, ok := <-c.msgBuffChan:
 			if ok {
-				if err := c.Send(data); err != nil {
-					zlog.Ins().ErrorF("Send Buff Data error:, %s Conn Writer exit", err)
+				err := c.Send(queued.data)
+				// Hand the pooled pack buffer back once it has been written (写出后归还池化的封包缓冲)
+				if queued.packed {
+					releasePacked(c.packet, queued.data)
+				}
+				if err != nil {
+					LoggerOf(c).ErrorFX(connLogCtx(c), "Send Buff Data error:, %s Conn Writer exit", err)
 					break
 				}
 
 			} else {
-				zlog.Ins().ErrorF("msgBuffChan is Closed")
+				LoggerOf(c).ErrorFX(connLogCtx(c), "msgBuffChan is Closed")
 				break
 			}
 		case <-c.ctx.Done():
@@ -208,8 +232,8 @@ func (c *WsConnection) StartWriter() {
 // StartReader is a Goroutine that reads messages from the client.
 // (StartReader 读消息Goroutine，用于从客户端中读取数据)
 func (c *WsConnection) StartReader() {
-	zlog.Ins().InfoF("[Reader Goroutine is running]")
-	defer zlog.Ins().InfoF("%s [conn Reader exit!]", c.RemoteAddr().String())
+	LoggerOf(c).InfoFX(connLogCtx(c), "[Reader Goroutine is running]")
+	defer LoggerOf(c).InfoFX(connLogCtx(c), "%s [conn Reader exit!]", c.RemoteAddr().String())
 	defer c.Stop()
 
 	// Create a pack-unpack object. (创建拆包解包的对象)
@@ -223,19 +247,23 @@ func (c *WsConnection) StartReader() {
 			// (从conn的IO中读取数据到内存缓冲buffer中)
 			messageType, buffer, err := c.conn.ReadMessage()
 			if err != nil {
+				c.setCloseReason(readCloseReason(err))
 				c.cancel()
 				return
 			}
+			c.received(metricsOf(c), len(buffer))
 			if messageType == websocket.PingMessage {
 				c.updateActivity()
 				continue
 			}
 			n := len(buffer)
 			if err != nil {
-				zlog.Ins().ErrorF("read msg head [read datalen=%d], error = %s", n, err.Error())
+				LoggerOf(c).ErrorFX(connLogCtx(c), "read msg head [read datalen=%d], error = %s", n, err.Error())
 				return
 			}
-			zlog.Ins().DebugF("read buffer %s \n", hex.EncodeToString(buffer[0:n]))
+			if zlog.DebugEnabled(LoggerOf(c)) {
+				LoggerOf(c).DebugFX(connLogCtx(c), "read buffer %s \n", hex.EncodeToString(buffer[0:n]))
+			}
 
 			// Update the Active status of heartbeat detection normally after reading data from the peer.
 			// (正常读取到对端数据，更新心跳检测Active状态)
@@ -243,25 +271,33 @@ func (c *WsConnection) StartReader() {
 				c.updateActivity()
 			}
 
+			data := buffer
+			// Pick the protocol of the connection from the first bytes read (根据最先读到的字节选择连接的协议)
+			if c.protocols != nil {
+				data = c.selectProtocol(data)
+			}
+
 			// Handle custom protocol fragmentation and packet sticking issues add by uuxia 2023-03-21
 			// (处理自定义协议断粘包问题)
 			if c.frameDecoder != nil {
 				// Decode the 0-n bytes of data read.
 				// (为读取到的0-n个字节的数据进行解码)
-				bufArrays := c.frameDecoder.Decode(buffer)
-				if bufArrays == nil {
-					continue
-				}
+				bufArrays, ok := decodeFrames(c, c.frameDecoder, data)
 				for _, bytes := range bufArrays {
-					zlog.Ins().DebugF("read buffer %s \n", hex.EncodeToString(bytes))
-					msg := zpack.NewMessage(uint32(len(bytes)), bytes)
+					if zlog.DebugEnabled(LoggerOf(c)) {
+						LoggerOf(c).DebugFX(connLogCtx(c), "read buffer %s \n", hex.EncodeToString(bytes))
+					}
+					msg := getMessage(c, bytes)
 					// Get the Request data requested by the current client.
 					// (得到当前客户端请求的Request数据)
 					req := GetRequest(c, msg)
 					c.msgHandler.Execute(req)
 				}
+				if !ok {
+					return
+				}
 			} else {
-				msg := zpack.NewMessage(uint32(n), buffer[0:n])
+				msg := getMessage(c, data)
 				// Get the Request data requested by the current client.
 				// (得到当前客户端请求的Request数据)
 				req := GetRequest(c, msg)
@@ -274,9 +310,16 @@ func (c *WsConnection) StartReader() {
 // Start starts the connection and makes it work.
 // (Start 启动连接，让当前连接开始工作)
 func (c *WsConnection) Start() {
-	ctx := c.ctx
-	if ctx == nil {
-		ctx = context.Background()
+	c.StartContext(context.Background())
+}
+
+// StartContext starts the connection, which stops once ctx is done, the context keeps the http request of the
+// handshake (启动连接，ctx结束时连接停止，context保留握手时的http请求)
+func (c *WsConnection) StartContext(ctx context.Context) {
+	if c.ctx != nil {
+		if r := c.ctx.Value(WsConnectionHttpReqCtxKey{}); r != nil {
+			ctx = context.WithValue(ctx, WsConnectionHttpReqCtxKey{}, r)
+		}
 	}
 	c.ctx, c.cancel = context.WithCancel(ctx)
 	// Execute the hook method according to the business needs of creating the connection passed in by the user.
@@ -350,24 +393,33 @@ func (c *WsConnection) Send(data []byte) error {
 	c.msgLock.Lock()
 	defer c.msgLock.Unlock()
 	if c.isClosed == true {
-		return errors.New("WsConnection closed when send msg")
+		return connError(c, "send msg", zerrors.ErrConnClosed)
 	}
 
 	err := c.conn.WriteMessage(websocket.BinaryMessage, data)
 	if err != nil {
-		zlog.Ins().ErrorF("SendMsg err data = %+v, err = %+v", data, err)
+		if ctx, ok := sendErrLog.Allow(connLogCtx(c)); ok {
+			LoggerOf(c).ErrorFX(ctx, "SendMsg err data = %+v, err = %+v", data, err)
+		}
 		return err
 	}
+	c.sent(metricsOf(c), data)
 
 	return nil
 }
 
 func (c *WsConnection) SendToQueue(data []byte) error {
+	return c.sendToQueue(data, false)
+}
+
+// sendToQueue queues data to the writer, packed data comes from Pack and is handed back to the datapack once written
+// (将data放入写队列，packed的数据来自Pack，写出后归还数据包)
+func (c *WsConnection) sendToQueue(data []byte, packed bool) error {
 	c.msgLock.Lock()
 	defer c.msgLock.Unlock()
 
 	if c.msgBuffChan == nil {
-		c.msgBuffChan = make(chan []byte, zconf.GlobalObject.MaxMsgChanLen)
+		c.msgBuffChan = make(chan queuedData, configOf(c).MaxMsgChanLen)
 		// Start a goroutine for writing data back to the client,
 		// which only reads data from MsgBuffChan and hasn't allocated memory or started the coroutine until SendBuffMsg is called
 		// (开启用于写回客户端数据流程的Goroutine
@@ -375,22 +427,22 @@ func (c *WsConnection) SendToQueue(data []byte) error {
 		go c.StartWriter()
 	}
 
-	idleTimeout := time.NewTimer(5 * time.Millisecond)
+	idleTimeout := time.NewTimer(sendBuffWait)
 	defer idleTimeout.Stop()
 
 	if c.isClosed == true {
-		return errors.New("WsConnection closed when send buff msg")
+		return connError(c, "send buff msg", zerrors.ErrConnClosed)
 	}
 
 	if data == nil {
-		zlog.Ins().ErrorF("Pack data is nil")
+		LoggerOf(c).ErrorFX(connLogCtx(c), "Pack data is nil")
 		return errors.New("Pack data is nil ")
 	}
 
 	select {
 	case <-idleTimeout.C:
-		return errors.New("send buff msg timeout")
-	case c.msgBuffChan <- data:
+		return connError(c, "send buff msg", &zerrors.SendQueueFullError{Wait: sendBuffWait})
+	case c.msgBuffChan <- queuedData{data: data, packed: packed}:
 		return nil
 	}
 }
@@ -398,24 +450,61 @@ func (c *WsConnection) SendToQueue(data []byte) error {
 // SendMsg directly sends the Message data to the remote TCP client.
 // (直接将Message数据发送数据给远程的TCP客户端)
 func (c *WsConnection) SendMsg(msgID uint32, data []byte) error {
+	return c.SendMessage(zpack.NewMsgPackage(msgID, data))
+}
+
+// SendMessage packs and sends msg directly, datapacks can carry extra header fields of msg such as the trace ID
+// (直接封包并发送msg，数据包可以携带msg的额外头部字段，例如链路追踪ID)
+func (c *WsConnection) SendMessage(msg ziface.IMessage) error {
+	msg, err := interceptSend(c, c.sendInterceptors, msg)
+	if err != nil {
+		return err
+	}
+	observeMessage(c, directionOutbound, msg.GetMsgID(), msg.GetData())
+	// Messages larger than MaxPacketSize are sent in fragments (超过MaxPacketSize的消息分片发送)
+	if handled, err := sendFragments(c, msg, c.SendMessage); handled {
+		return err
+	}
+	// Compress the data with the negotiated algorithm and sign it (使用协商的算法压缩数据并签名)
+	msg, err = sealMessage(c, msg)
+	if err != nil {
+		return err
+	}
+	msgID := msg.GetMsgID()
+	span := traceSend(c, msg)
+	defer span.End()
 	c.msgLock.Lock()
 	defer c.msgLock.Unlock()
 	if c.isClosed == true {
-		return errors.New("WsConnection closed when send msg")
+		return connError(c, "send msg", zerrors.ErrConnClosed)
 	}
 
 	// Package data and send
 	// (将data封包，并且发送)
-	msg, err := c.packet.Pack(zpack.NewMsgPackage(msgID, data))
+	buf, err := c.packet.Pack(msg)
 	if err != nil {
-		zlog.Ins().ErrorF("Pack error msg ID = %d", msgID)
-		return errors.New("Pack error msg ")
+		if ctx, ok := sendErrLog.Allow(connLogCtx(c)); ok {
+			LoggerOf(c).ErrorFX(ctx, "Pack error msg ID = %d", msgID)
+		}
+		span.SetError(err)
+		return connError(c, "pack msg", err)
+	}
+	// A simulated network delivers the message later through the writer (模拟的网络稍后通过写协程投递消息)
+	if deferSend(c, buf) {
+		return nil
+	}
+	// Hand the pooled pack buffer back once it has been written (写出后归还池化的封包缓冲)
+	if releaser, ok := c.packet.(ziface.IBufferReleaser); ok {
+		defer releaser.ReleaseBuffer(buf)
 	}
 
 	// Write back to the client
-	err = c.conn.WriteMessage(websocket.BinaryMessage, msg)
+	err = c.conn.WriteMessage(websocket.BinaryMessage, buf)
 	if err != nil {
-		zlog.Ins().ErrorF("SendMsg err msg ID = %d, data = %+v, err = %+v", msgID, string(msg), err)
+		if ctx, ok := sendErrLog.Allow(connLogCtx(c)); ok {
+			LoggerOf(c).ErrorFX(ctx, "SendMsg err msg ID = %d, data = %+v, err = %+v", msgID, string(buf), err)
+		}
+		span.SetError(err)
 		return err
 	}
 
@@ -423,12 +512,39 @@ func (c *WsConnection) SendMsg(msgID uint32, data []byte) error {
 }
 
 // SendBuffMsg sends BuffMsg
+func (c *WsConnection) SendMsgAfter(delay time.Duration, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	return scheduleSend(c, time.Now().Add(delay), msgID, data)
+}
+
+func (c *WsConnection) SendMsgAt(at time.Time, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	return scheduleSend(c, at, msgID, data)
+}
+
+func (c *WsConnection) AfterFunc(d time.Duration, f func()) ziface.IConnTimer {
+	return connAfterFunc(c.ctx, c, d, f)
+}
+
+func (c *WsConnection) Ticker(d time.Duration, f func()) ziface.IConnTimer {
+	return connTickerFunc(c.ctx, c, d, f)
+}
+
 func (c *WsConnection) SendBuffMsg(msgID uint32, data []byte) error {
+	intercepted, err := interceptSend(c, c.sendInterceptors, zpack.NewMsgPackage(msgID, data))
+	if err != nil {
+		return err
+	}
+	msgID = intercepted.GetMsgID()
+	observeMessage(c, directionOutbound, msgID, intercepted.GetData())
+	if handled, err := sendFragments(c, intercepted, func(fragment ziface.IMessage) error {
+		return c.SendBuffMsg(fragment.GetMsgID(), fragment.GetData())
+	}); handled {
+		return err
+	}
 	c.msgLock.Lock()
 	defer c.msgLock.Unlock()
 
 	if c.msgBuffChan == nil {
-		c.msgBuffChan = make(chan []byte, zconf.GlobalObject.MaxMsgChanLen)
+		c.msgBuffChan = make(chan queuedData, configOf(c).MaxMsgChanLen)
 		// Start the Goroutine for writing back to the client data stream
 		// This method only reads data from MsgBuffChan, allocating memory and starting Goroutine without calling SendBuffMsg
 		// (开启用于写回客户端数据流程的Goroutine
@@ -436,26 +552,35 @@ func (c *WsConnection) SendBuffMsg(msgID uint32, data []byte) error {
 		go c.StartWriter()
 	}
 
-	idleTimeout := time.NewTimer(5 * time.Millisecond)
+	idleTimeout := time.NewTimer(sendBuffWait)
 	defer idleTimeout.Stop()
 
 	if c.isClosed == true {
-		return errors.New("WsConnection closed when send buff msg")
+		return connError(c, "send buff msg", zerrors.ErrConnClosed)
 	}
 
 	// Package data and send
 	// (将data封包，并且发送)
-	msg, err := c.packet.Pack(zpack.NewMsgPackage(msgID, data))
+	sealed, err := sealMessage(c, intercepted)
 	if err != nil {
-		zlog.Ins().ErrorF("Pack error msg ID = %d", msgID)
-		return errors.New("Pack error msg ")
+		return err
+	}
+	msg, err := c.packet.Pack(sealed)
+	if err != nil {
+		if ctx, ok := sendErrLog.Allow(connLogCtx(c)); ok {
+			LoggerOf(c).ErrorFX(ctx, "Pack error msg ID = %d", msgID)
+		}
+		return connError(c, "pack msg", err)
+	}
+	if deferSend(c, msg) {
+		return nil
 	}
 
 	// Send timeout
 	select {
 	case <-idleTimeout.C:
-		return errors.New("send buff msg timeout")
-	case c.msgBuffChan <- msg:
+		return connError(c, "send buff msg", &zerrors.SendQueueFullError{Wait: sendBuffWait})
+	case c.msgBuffChan <- queuedData{data: msg, packed: true}:
 		return nil
 	}
 }
@@ -481,6 +606,18 @@ func (c *WsConnection) GetProperty(key string) (interface{}, error) {
 	return nil, errors.New("no property found")
 }
 
+// GetProperties returns a copy of the properties of the connection (返回连接属性的副本)
+func (c *WsConnection) GetProperties() map[string]interface{} {
+	c.propertyLock.Lock()
+	defer c.propertyLock.Unlock()
+
+	properties := make(map[string]interface{}, len(c.property))
+	for key, value := range c.property {
+		properties[key] = value
+	}
+	return properties
+}
+
 func (c *WsConnection) RemoveProperty(key string) {
 	c.propertyLock.Lock()
 	defer c.propertyLock.Unlock()
@@ -488,6 +625,19 @@ func (c *WsConnection) RemoveProperty(key string) {
 	delete(c.property, key)
 }
 
+// SetTenant accounts the traffic of the connection to tenant (将连接的流量计入租户tenant)
+func (c *WsConnection) SetTenant(tenant string) {
+	setConnTenant(c.connManager, c, tenant)
+}
+
+func (c *WsConnection) GetTenant() string {
+	return c.tenantName()
+}
+
+func (c *WsConnection) GetTraffic() ziface.TrafficStats {
+	return c.stats()
+}
+
 // Context returns the context for the connection, which can be used by user-defined goroutines to get the connection exit status.
 // (返回ctx，用于用户自定义的go程获取连接退出状态)
 func (c *WsConnection) Context() context.Context {
@@ -536,26 +686,26 @@ func (c *WsConnection) finalizer() {
 	go func() {
 		defer func() {
 			if err := recover(); err != nil {
-				zlog.Ins().ErrorF("Conn finalizer panic: %v", err)
+				LoggerOf(c).ErrorFX(connLogCtx(c), "Conn finalizer panic: %v", err)
 			}
 		}()
 
 		c.InvokeCloseCallbacks()
 	}()
 
-	zlog.Ins().InfoF("Conn Stop()...ConnID = %d", c.connID)
+	LoggerOf(c).InfoFX(connLogCtx(c), "Conn Stop()...ConnID = %d", c.connID)
 }
 
 func (c *WsConnection) callOnConnStart() {
 	if c.onConnStart != nil {
-		zlog.Ins().InfoF("ZINX CallOnConnStart....")
+		LoggerOf(c).InfoFX(connLogCtx(c), "ZINX CallOnConnStart....")
 		c.onConnStart(c)
 	}
 }
 
 func (c *WsConnection) callOnConnStop() {
 	if c.onConnStop != nil {
-		zlog.Ins().InfoF("ZINX CallOnConnStop....")
+		LoggerOf(c).InfoFX(connLogCtx(c), "ZINX CallOnConnStop....")
 		c.onConnStop(c)
 	}
 }
@@ -567,11 +717,12 @@ func (c *WsConnection) IsAlive() bool {
 	// Check the time duration since the last activity of the connection, if it exceeds the maximum heartbeat interval,
 	// then the connection is considered dead
 	// (检查连接最后一次活动时间，如果超过心跳间隔，则认为连接已经死亡)
-	return time.Now().Sub(c.lastActivityTime) < zconf.GlobalObject.HeartbeatMaxDuration()
+	last, _ := c.lastActivityTime.Load().(time.Time)
+	return ztimer.Now().Sub(last) < configOf(c).Live().HeartbeatMaxDuration()
 }
 
 func (c *WsConnection) updateActivity() {
-	c.lastActivityTime = time.Now()
+	c.lastActivityTime.Store(ztimer.Now())
 }
 
 func (c *WsConnection) SetHeartBeat(checker ziface.IHeartbeatChecker) {
@@ -617,3 +768,27 @@ func (s *WsConnection) InvokeCloseCallbacks() {
 	defer s.closeCallbackMutex.RUnlock()
 	s.closeCallback.Invoke()
 }
+
+// selectProtocol switches the connection to the protocol matching its first bytes, it returns
+// data without the Hello of the protocol.
+// (将连接切换为与最先读到的字节匹配的协议，返回去掉协议Hello之后的数据)
+func (c *WsConnection) selectProtocol(data []byte) []byte {
+	p, data := matchProtocol(c.protocols, data)
+	c.protocols = nil
+	if p == nil {
+		return data
+	}
+
+	if p.Packet != nil {
+		c.packet = p.Packet
+	}
+	if p.Decoder != nil {
+		c.frameDecoder = nil
+		if lengthField := p.Decoder.GetLengthField(); lengthField != nil {
+			c.frameDecoder = zinterceptor.NewFrameDecoder(*lengthField)
+		}
+	}
+	c.SetProperty(protocolKey, p)
+	LoggerOf(c).InfoFX(connLogCtx(c), "connID = %d selected protocol %s", c.GetConnID(), p.Name)
+	return data
+}
@@ -2,7 +2,6 @@ package znotify
 
 import (
 	"errors"
-	"fmt"
 	"strconv"
 
 	"github.com/aceld/zinx/ziface"
@@ -68,7 +67,7 @@ func (n *notify) NotifyToConnByID(Id uint64, MsgId uint32, data []byte) error {
 	}
 	err = Conn.SendMsg(MsgId, data)
 	if err != nil {
-		fmt.Printf("Notify to %d err:%s \n", Id, err)
+		zlog.Ins().ErrorF("Notify to %d err:%s", Id, err)
 		return err
 	}
 	return nil
@@ -0,0 +1,118 @@
+package zpack
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+)
+
+// jsonEnvelope is the wire format of DataPackJSON, data holds the body as is if it is valid JSON,
+// otherwise data_b64 holds it in base64, so that any body round-trips unchanged. The optional topic routes
+// the message by topic instead of id, see ziface.ITopicMessage.
+// (DataPackJSON的传输格式，消息体是合法JSON时原样放入data，否则以base64放入data_b64，任何消息体都能原样往返。
+// 可选的topic使消息按主题而不是id路由，见ziface.ITopicMessage)
+type jsonEnvelope struct {
+	ID      uint32          `json:"id"`
+	Topic   string          `json:"topic,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	DataB64 []byte          `json:"data_b64,omitempty"`
+}
+
+// DataPackJSON packs messages as {"id":1,"data":{...}} for scripting-language and browser clients,
+// framed by a newline or by a 4 bytes big endian length prefix. Use it with zdecoder.JSONDecoder.
+// (将消息打包为{"id":1,"data":{...}}，方便脚本语言和浏览器客户端使用，以换行或4字节大端长度前缀分帧，
+// 需要与zdecoder.JSONDecoder配合使用)
+type DataPackJSON struct {
+	packetLimit
+	newline bool
+}
+
+// NewDataPackJSON creates a length prefixed JSON datapack (创建长度前缀分帧的JSON数据包)
+func NewDataPackJSON() ziface.IDataPack {
+	return &DataPackJSON{}
+}
+
+// NewDataPackJSONLine creates a newline delimited JSON datapack (创建换行分隔的JSON数据包)
+func NewDataPackJSONLine() ziface.IDataPack {
+	return &DataPackJSON{newline: true}
+}
+
+// GetHeadLen returns 4 for length prefixed framing, 0 for newline framing which has no header
+// (长度前缀分帧返回4，换行分帧没有包头返回0)
+func (dp *DataPackJSON) GetHeadLen() uint32 {
+	if dp.newline {
+		return 0
+	}
+	return 4
+}
+
+func (dp *DataPackJSON) Pack(msg ziface.IMessage) ([]byte, error) {
+	body, err := MarshalJSONEnvelope(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if dp.newline {
+		return append(body, '\n'), nil
+	}
+
+	buf := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(len(body)))
+	return append(buf, body...), nil
+}
+
+// Unpack reads the length prefix for length prefixed framing, DataLen is the length of the envelope;
+// for newline framing binaryData is one whole line and is decoded completely
+// (长度前缀分帧时读取长度前缀，DataLen为信封长度；换行分帧时binaryData为完整的一行并被完整解码)
+func (dp *DataPackJSON) Unpack(binaryData []byte) (ziface.IMessage, error) {
+	if dp.newline {
+		return UnmarshalJSONEnvelope(binaryData)
+	}
+
+	if len(binaryData) < 4 {
+		return nil, zerrors.NewDecodeError("json msg head too short")
+	}
+	msg := &Message{DataLen: binary.BigEndian.Uint32(binaryData)}
+	if err := dp.checkLength(msg.GetDataLen()); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// MarshalJSONEnvelope encodes msg as {"id":...,"data":...}, or {"id":...,"data_b64":"..."} if its data is not
+// valid JSON
+// (将msg编码为{"id":...,"data":...}，数据不是合法JSON时编码为{"id":...,"data_b64":"..."})
+func MarshalJSONEnvelope(msg ziface.IMessage) ([]byte, error) {
+	env := jsonEnvelope{ID: msg.GetMsgID()}
+	if tm, ok := msg.(ziface.ITopicMessage); ok {
+		env.Topic = tm.GetTopic()
+	}
+	if data := msg.GetData(); len(data) > 0 {
+		if json.Valid(data) {
+			env.Data = data
+		} else {
+			env.DataB64 = data
+		}
+	}
+	return json.Marshal(env)
+}
+
+// UnmarshalJSONEnvelope decodes {"id":...,"data":...} keeping the JSON value of data as is, including the quotes
+// of a string, or {"id":...,"data_b64":"..."} decoding the base64
+// (解码{"id":...,"data":...}，data的JSON值原样保留，包括字符串的引号；或解码{"id":...,"data_b64":"..."}中的base64)
+func UnmarshalJSONEnvelope(data []byte) (*Message, error) {
+	var env jsonEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+
+	body := []byte(env.Data)
+	if env.DataB64 != nil {
+		body = env.DataB64
+	}
+	msg := NewMsgPackage(env.ID, body)
+	msg.SetTopic(env.Topic)
+	return msg, nil
+}
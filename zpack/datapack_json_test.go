@@ -0,0 +1,83 @@
+package zpack
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataPackJSONLine(t *testing.T) {
+	dp := Factory().NewPack(ziface.ZinxDataPackJSONLine)
+
+	a, err := dp.Pack(NewMsgPackage(1, []byte(`{"name":"zinx"}`)))
+	assert.NoError(t, err)
+	b, err := dp.Pack(NewMsgPackage(2, []byte("plain text")))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"data":{"name":"zinx"}}`+"\n", string(a))
+
+	// Two frames arriving split at arbitrary positions (两帧在任意位置被拆分到达)
+	stream := append(a, b...)
+	fd := zinterceptor.NewFrameDecoder(ziface.LengthField{MaxFrameLength: 1024, Delimiter: []byte("\n")})
+	frames := fd.Decode(stream[:10])
+	frames = append(frames, fd.Decode(stream[10:])...)
+	assert.Len(t, frames, 2)
+
+	msg, err := dp.Unpack(frames[1])
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), msg.GetMsgID())
+	assert.Equal(t, "plain text", string(msg.GetData()))
+}
+
+func TestDataPackJSON(t *testing.T) {
+	dp := Factory().NewPack(ziface.ZinxDataPackJSON)
+	buf, err := dp.Pack(NewMsgPackage(5, []byte(`[1,2]`)))
+	assert.NoError(t, err)
+
+	head, err := dp.Unpack(buf[:dp.GetHeadLen()])
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(len(buf))-dp.GetHeadLen(), head.GetDataLen())
+
+	msg, err := UnmarshalJSONEnvelope(buf[dp.GetHeadLen():])
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(5), msg.GetMsgID())
+	assert.Equal(t, `[1,2]`, string(msg.GetData()))
+}
+
+func TestJSONEnvelopeRoundTrip(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte(`{"name":"zinx"}`),
+		[]byte(`"ok"`),
+		[]byte("plain text"),
+		{0xff, 0xfe, 0x00, 0x80},
+		nil,
+	} {
+		buf, err := MarshalJSONEnvelope(NewMsgPackage(7, data))
+		assert.NoError(t, err)
+		msg, err := UnmarshalJSONEnvelope(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(7), msg.GetMsgID())
+		assert.Equal(t, string(data), string(msg.GetData()))
+	}
+
+	buf, err := MarshalJSONEnvelope(NewMsgPackage(1, []byte{0xff}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1,"data_b64":"/w=="}`, string(buf))
+}
+
+func TestJSONEnvelopeTopic(t *testing.T) {
+	msg := NewMsgPackage(0, []byte(`{"temp":21}`))
+	msg.SetTopic("device/1001/telemetry")
+	buf, err := MarshalJSONEnvelope(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":0,"topic":"device/1001/telemetry","data":{"temp":21}}`, string(buf))
+
+	got, err := UnmarshalJSONEnvelope(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "device/1001/telemetry", got.GetTopic())
+
+	got, err = UnmarshalJSONEnvelope([]byte(`{"id":1}`))
+	assert.NoError(t, err)
+	assert.Empty(t, got.GetTopic())
+}
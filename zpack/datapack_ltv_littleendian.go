@@ -1,18 +1,19 @@
 package zpack
 
 import (
-	"bytes"
 	"encoding/binary"
-	"errors"
+	"io"
 
-	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zerrors"
 	"github.com/aceld/zinx/ziface"
 )
 
 // DataPackLtv
 // LTV little-endian data packing and unpacking used by Zinx in its early days, compatible with previous applications
 // (Zinx早期使用的LTV 小端方式，兼容之前的应用)
-type DataPackLtv struct{}
+type DataPackLtv struct {
+	packetLimit
+}
 
 // NewDataPackLtv initializes a packing and unpacking instance
 // (封包拆包实例初始化方法)
@@ -30,52 +31,46 @@ func (dp *DataPackLtv) GetHeadLen() uint32 {
 // Pack packs the message (compresses the data)
 // (封包方法,压缩数据)
 func (dp *DataPackLtv) Pack(msg ziface.IMessage) ([]byte, error) {
-	// Create a buffer to store the bytes
-	// (创建一个存放bytes字节的缓冲)
-	dataBuff := bytes.NewBuffer([]byte{})
+	data := msg.GetData()
+	buf := AcquireBuffer(int(defaultHeaderLen) + len(data))
 
 	// Write the data length
-	if err := binary.Write(dataBuff, binary.LittleEndian, msg.GetDataLen()); err != nil {
-		return nil, err
-	}
+	binary.LittleEndian.PutUint32(buf[0:4], msg.GetDataLen())
 
 	// Write the message ID
-	if err := binary.Write(dataBuff, binary.LittleEndian, msg.GetMsgID()); err != nil {
-		return nil, err
-	}
+	binary.LittleEndian.PutUint32(buf[4:8], msg.GetMsgID())
 
 	// Write the data
-	if err := binary.Write(dataBuff, binary.LittleEndian, msg.GetData()); err != nil {
-		return nil, err
-	}
+	copy(buf[defaultHeaderLen:], data)
+
+	return buf, nil
+}
 
-	return dataBuff.Bytes(), nil
+// ReleaseBuffer hands a buffer returned by Pack back to the pool
+// (将Pack返回的缓冲归还缓冲池)
+func (dp *DataPackLtv) ReleaseBuffer(buf []byte) {
+	ReleaseBuffer(buf)
 }
 
 // Unpack unpacks the message (decompresses the data)
 // (拆包方法,解压数据)
 func (dp *DataPackLtv) Unpack(binaryData []byte) (ziface.IMessage, error) {
-	// Create an ioReader for the input binary data
-	dataBuff := bytes.NewReader(binaryData)
+	// The header is read in place, binary.Read would allocate (原地读取头部，binary.Read会分配内存)
+	if len(binaryData) < int(defaultHeaderLen) {
+		return nil, &zerrors.DecodeError{Err: io.ErrUnexpectedEOF}
+	}
 
 	// Only unpack the header information to obtain the data length and message ID
 	// (只解压head的信息，得到dataLen和msgID)
-	msg := &Message{}
-
-	// Read the data length
-	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.DataLen); err != nil {
-		return nil, err
-	}
-
-	// Read the message ID
-	if err := binary.Read(dataBuff, binary.LittleEndian, &msg.ID); err != nil {
-		return nil, err
+	msg := &Message{
+		DataLen: binary.LittleEndian.Uint32(binaryData[0:4]),
+		ID:      binary.LittleEndian.Uint32(binaryData[4:8]),
 	}
 
 	// Check whether the data length exceeds the maximum allowed packet size
 	// (判断dataLen的长度是否超出我们允许的最大包长度)
-	if zconf.GlobalObject.MaxPacketSize > 0 && msg.GetDataLen() > zconf.GlobalObject.MaxPacketSize {
-		return nil, errors.New("too large msg data received")
+	if err := dp.checkLength(msg.GetDataLen()); err != nil {
+		return nil, err
 	}
 
 	// Only the header data needs to be unpacked, and then another data read is performed from the connection based on the header length
@@ -0,0 +1,151 @@
+package zpack
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the Envelope in envelope.proto (envelope.proto中Envelope的字段编号)
+const (
+	envelopeMsgID         protowire.Number = 1
+	envelopeFlags         protowire.Number = 2
+	envelopeCorrelationID protowire.Number = 3
+	envelopeBody          protowire.Number = 4
+)
+
+// protobufHeaderLen is the length prefix of a frame, uint32(4 bytes)
+var protobufHeaderLen uint32 = 4
+
+// DataPackProtobuf packs messages into a protobuf Envelope (msg_id, flags, correlation_id, body),
+// see envelope.proto, framed by a 4 bytes big endian length prefix.
+// Use it with zdecoder.ProtobufDecoder.
+// (将消息打包为protobuf信封(msg_id, flags, correlation_id, body)，参见envelope.proto，
+// 以4字节大端长度前缀分帧。需要与zdecoder.ProtobufDecoder配合使用)
+//
+//	+---------------+--------------------------+
+//	|    Length     |   Envelope (protobuf)    |
+//	| uint32(4byte) |         n byte           |
+//	+---------------+--------------------------+
+type DataPackProtobuf struct {
+	packetLimit
+}
+
+// NewDataPackProtobuf initializes a protobuf envelope packing and unpacking instance
+// (protobuf信封封包拆包实例初始化方法)
+func NewDataPackProtobuf() ziface.IDataPack {
+	return &DataPackProtobuf{}
+}
+
+func (dp *DataPackProtobuf) GetHeadLen() uint32 {
+	return protobufHeaderLen
+}
+
+func (dp *DataPackProtobuf) Pack(msg ziface.IMessage) ([]byte, error) {
+	envelope := MarshalEnvelope(msg)
+	buf := make([]byte, protobufHeaderLen, protobufHeaderLen+uint32(len(envelope)))
+	binary.BigEndian.PutUint32(buf, uint32(len(envelope)))
+	return append(buf, envelope...), nil
+}
+
+// HasFlags reports that the Envelope carries flags (信封携带标志位)
+func (dp *DataPackProtobuf) HasFlags() bool {
+	return true
+}
+
+// Unpack only reads the length prefix, DataLen is the length of the Envelope,
+// the Envelope itself is decoded by UnmarshalEnvelope after it is read.
+// (只读取长度前缀，DataLen为信封长度，读取信封后再通过UnmarshalEnvelope解码)
+func (dp *DataPackProtobuf) Unpack(binaryData []byte) (ziface.IMessage, error) {
+	if uint32(len(binaryData)) < protobufHeaderLen {
+		return nil, zerrors.NewDecodeError("protobuf msg head too short")
+	}
+
+	msg := &Message{DataLen: binary.BigEndian.Uint32(binaryData)}
+	if err := dp.checkLength(msg.GetDataLen()); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// MarshalEnvelope serializes msg as an Envelope, flags and correlation ID are taken from ziface.IEnvelopeMessage
+// (将msg序列化为信封，flags和correlation ID取自ziface.IEnvelopeMessage)
+func MarshalEnvelope(msg ziface.IMessage) []byte {
+	var b []byte
+	if msg.GetMsgID() != 0 {
+		b = protowire.AppendTag(b, envelopeMsgID, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(msg.GetMsgID()))
+	}
+	if em, ok := msg.(ziface.IEnvelopeMessage); ok {
+		if em.GetFlags() != 0 {
+			b = protowire.AppendTag(b, envelopeFlags, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(em.GetFlags()))
+		}
+		if em.GetCorrelationID() != 0 {
+			b = protowire.AppendTag(b, envelopeCorrelationID, protowire.VarintType)
+			b = protowire.AppendVarint(b, em.GetCorrelationID())
+		}
+	}
+	if len(msg.GetData()) > 0 {
+		b = protowire.AppendTag(b, envelopeBody, protowire.BytesType)
+		b = protowire.AppendBytes(b, msg.GetData())
+	}
+	return b
+}
+
+// UnmarshalEnvelope decodes an Envelope into a Message, body shares memory with data,
+// unknown fields are skipped for forward compatibility
+// (将信封解码为Message，body与data共享内存，为了向前兼容会跳过未知字段)
+func UnmarshalEnvelope(data []byte) (*Message, error) {
+	msg := &Message{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case num == envelopeMsgID && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			msg.ID = uint32(v)
+			data = data[n:]
+		case num == envelopeFlags && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			msg.flags = uint32(v)
+			data = data[n:]
+		case num == envelopeCorrelationID && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			msg.correlationID = v
+			data = data[n:]
+		case num == envelopeBody && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			msg.Data = v
+			msg.rawData = v
+			msg.DataLen = uint32(len(v))
+			data = data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, &zerrors.DecodeError{Err: fmt.Errorf("envelope field %d: %v", num, protowire.ParseError(n))}
+			}
+			data = data[n:]
+		}
+	}
+	return msg, nil
+}
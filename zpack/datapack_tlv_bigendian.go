@@ -1,17 +1,18 @@
 package zpack
 
 import (
-	"bytes"
 	"encoding/binary"
-	"errors"
+	"io"
 
-	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zerrors"
 	"github.com/aceld/zinx/ziface"
 )
 
 var defaultHeaderLen uint32 = 8
 
-type DataPack struct{}
+type DataPack struct {
+	packetLimit
+}
 
 // NewDataPack initializes a packing and unpacking instance
 // (封包拆包实例初始化方法)
@@ -26,55 +27,49 @@ func (dp *DataPack) GetHeadLen() uint32 {
 	return defaultHeaderLen
 }
 
-// Pack packs the message (compresses the data)
-// (封包方法,压缩数据)
+// Pack packs the message (compresses the data), the buffer is taken from the pack buffer pool
+// (封包方法,压缩数据，缓冲取自封包缓冲池)
 func (dp *DataPack) Pack(msg ziface.IMessage) ([]byte, error) {
-	// Create a buffer to store the bytes
-	// (创建一个存放bytes字节的缓冲)
-	dataBuff := bytes.NewBuffer([]byte{})
+	data := msg.GetData()
+	buf := AcquireBuffer(int(defaultHeaderLen) + len(data))
 
 	// Write the message ID
-	if err := binary.Write(dataBuff, binary.BigEndian, msg.GetMsgID()); err != nil {
-		return nil, err
-	}
+	binary.BigEndian.PutUint32(buf[0:4], msg.GetMsgID())
 
 	// Write the data length
-	if err := binary.Write(dataBuff, binary.BigEndian, msg.GetDataLen()); err != nil {
-		return nil, err
-	}
+	binary.BigEndian.PutUint32(buf[4:8], msg.GetDataLen())
 
 	// Write the data
-	if err := binary.Write(dataBuff, binary.BigEndian, msg.GetData()); err != nil {
-		return nil, err
-	}
+	copy(buf[defaultHeaderLen:], data)
+
+	return buf, nil
+}
 
-	return dataBuff.Bytes(), nil
+// ReleaseBuffer hands a buffer returned by Pack back to the pool
+// (将Pack返回的缓冲归还缓冲池)
+func (dp *DataPack) ReleaseBuffer(buf []byte) {
+	ReleaseBuffer(buf)
 }
 
 // Unpack unpacks the message (decompresses the data)
 // (拆包方法,解压数据)
 func (dp *DataPack) Unpack(binaryData []byte) (ziface.IMessage, error) {
-	// Create an ioReader for the input binary data
-	dataBuff := bytes.NewReader(binaryData)
+	// The header is read in place, binary.Read would allocate (原地读取头部，binary.Read会分配内存)
+	if len(binaryData) < int(defaultHeaderLen) {
+		return nil, &zerrors.DecodeError{Err: io.ErrUnexpectedEOF}
+	}
 
 	// Only unpack the header information to obtain the data length and message ID
 	// (只解压head的信息，得到dataLen和msgID)
-	msg := &Message{}
-
-	// Read the data length
-	if err := binary.Read(dataBuff, binary.BigEndian, &msg.ID); err != nil {
-		return nil, err
-	}
-
-	// Read the message ID
-	if err := binary.Read(dataBuff, binary.BigEndian, &msg.DataLen); err != nil {
-		return nil, err
+	msg := &Message{
+		ID:      binary.BigEndian.Uint32(binaryData[0:4]),
+		DataLen: binary.BigEndian.Uint32(binaryData[4:8]),
 	}
 
 	// Check whether the data length exceeds the maximum allowed packet size
 	// (判断dataLen的长度是否超出我们允许的最大包长度)
-	if zconf.GlobalObject.MaxPacketSize > 0 && msg.GetDataLen() > zconf.GlobalObject.MaxPacketSize {
-		return nil, errors.New("too large msg data received")
+	if err := dp.checkLength(msg.GetDataLen()); err != nil {
+		return nil, err
 	}
 
 	// Only the header data needs to be unpacked, and then another data read is performed from the connection based on the header length
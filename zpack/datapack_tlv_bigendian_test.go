@@ -116,3 +116,156 @@ func TestDataPack(t *testing.T) {
 		return
 	}
 }
+
+func TestDataPackTrace(t *testing.T) {
+	dp := Factory().NewPack(ziface.ZinxDataPackTrace)
+	msg := NewMsgPackage(7, []byte("hello"))
+	msg.SetTraceID("0af7651916cd43dd8448eb211c80319c")
+
+	buf, err := dp.Pack(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint32(len(buf)) != dp.GetHeadLen()+5 {
+		t.Fatalf("unexpected packed length %d", len(buf))
+	}
+
+	head, err := dp.Unpack(buf[:dp.GetHeadLen()])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := head.(ziface.ITraceMessage)
+	if got.GetMsgID() != 7 || got.GetDataLen() != 5 || got.GetTraceID() != msg.GetTraceID() {
+		t.Fatalf("unexpected unpacked message %+v", got)
+	}
+}
+
+func TestDataPackProtobuf(t *testing.T) {
+	dp := Factory().NewPack(ziface.ZinxDataPackProtobuf)
+	msg := NewMsgPackage(300, []byte("body"))
+	msg.SetFlags(3)
+	msg.SetCorrelationID(1 << 40)
+
+	buf, err := dp.Pack(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := dp.Unpack(buf[:dp.GetHeadLen()])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(head.GetDataLen()) != len(buf)-int(dp.GetHeadLen()) {
+		t.Fatalf("unexpected envelope length %d", head.GetDataLen())
+	}
+
+	got, err := UnmarshalEnvelope(buf[dp.GetHeadLen():])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetMsgID() != 300 || string(got.GetData()) != "body" || got.GetFlags() != 3 || got.GetCorrelationID() != 1<<40 {
+		t.Fatalf("unexpected envelope %+v", got)
+	}
+}
+
+func TestDataPackSchema(t *testing.T) {
+	schema, err := NewHeaderSchema([]string{HeaderFieldVersion, HeaderFieldFlags, HeaderFieldCRC32, HeaderFieldTimestamp}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dp := NewDataPackSchema(schema)
+	if dp.GetHeadLen() != 8+1+2+4+8 {
+		t.Fatalf("unexpected head length %d", dp.GetHeadLen())
+	}
+
+	msg := NewMsgPackage(9, []byte("payload"))
+	msg.SetFlags(ziface.MessageFlagCompressed)
+	msg.SetTimestamp(1700000000000)
+	buf, err := dp.Pack(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := schema.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetMsgID() != 9 || string(got.GetData()) != "payload" || got.GetVersion() != 2 ||
+		got.GetFlags() != ziface.MessageFlagCompressed || got.GetTimestamp() != 1700000000000 {
+		t.Fatalf("unexpected decoded message %+v", got)
+	}
+
+	// A corrupted body fails the checksum
+	buf[len(buf)-1] ^= 0xFF
+	if _, err := schema.Decode(buf); err != ErrHeaderChecksum {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+
+	// Another protocol version is rejected by the header-only Unpack too
+	other, _ := NewHeaderSchema([]string{HeaderFieldVersion, HeaderFieldFlags, HeaderFieldCRC32, HeaderFieldTimestamp}, 3)
+	if _, err := NewDataPackSchema(other).Unpack(buf[:dp.GetHeadLen()]); err != ErrHeaderVersion {
+		t.Fatalf("expected version error, got %v", err)
+	}
+
+	if _, err := NewHeaderSchema([]string{"unknown"}, 0); err == nil {
+		t.Fatal("expected unknown field error")
+	}
+}
+
+func TestHeaderSchemaNonce(t *testing.T) {
+	schema, _ := NewHeaderSchema([]string{HeaderFieldTimestamp, HeaderFieldNonce}, 0)
+	dp := NewDataPackSchema(schema)
+	if dp.GetHeadLen() != 8+8+8 {
+		t.Fatalf("unexpected head length %d", dp.GetHeadLen())
+	}
+
+	// Every packed message gets its own nonce unless it carries one
+	seen := make(map[uint64]bool)
+	for i := 0; i < 3; i++ {
+		buf, _ := dp.Pack(NewMsgPackage(1, []byte("payload")))
+		got, err := schema.Decode(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GetNonce() == 0 || seen[got.GetNonce()] || got.GetTimestamp() == 0 {
+			t.Fatalf("unexpected nonce %d", got.GetNonce())
+		}
+		seen[got.GetNonce()] = true
+	}
+
+	msg := NewMsgPackage(1, nil)
+	msg.SetNonce(42)
+	buf, _ := dp.Pack(msg)
+	if got, _ := schema.Decode(buf); got.GetNonce() != 42 {
+		t.Fatalf("expected nonce 42, got %d", got.GetNonce())
+	}
+}
+
+func TestHeaderSchemaSignature(t *testing.T) {
+	schema, _ := NewHeaderSchema([]string{HeaderFieldNonce, HeaderFieldSignature}, 0)
+	dp := NewDataPackSchema(schema)
+	if dp.GetHeadLen() != 8+8+SignatureLen {
+		t.Fatalf("unexpected head length %d", dp.GetHeadLen())
+	}
+
+	msg := NewMsgPackage(1, []byte("payload"))
+	msg.SetNonce(42)
+	payload := schema.SigningPayload(msg)
+	msg.SetSignature([]byte("sig"))
+	buf, _ := dp.Pack(msg)
+	got, err := schema.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Shorter signatures are zero padded
+	if len(got.GetSignature()) != SignatureLen || string(got.GetSignature()[:3]) != "sig" || got.GetSignature()[3] != 0 {
+		t.Fatalf("unexpected signature %v", got.GetSignature())
+	}
+	// The signature does not cover itself, the rest of the message does
+	if string(schema.SigningPayload(got)) != string(payload) {
+		t.Fatal("the signing payload changed after decoding")
+	}
+	got.SetData([]byte("tampered"))
+	if string(schema.SigningPayload(got)) == string(payload) {
+		t.Fatal("the signing payload does not cover the data")
+	}
+}
@@ -0,0 +1,74 @@
+package zpack
+
+import (
+	"encoding/binary"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+)
+
+// DataPackSchema is the default big endian TLV datapack extended with the optional fields of a HeaderSchema.
+// Unpack only sees the header, so the CRC32 is checked by HeaderSchema.Verify once the data is read,
+// zdecoder.SchemaTLVDecoder does it for the server.
+// (扩展了HeaderSchema可选字段的默认大端TLV数据包。Unpack只能看到头部，因此CRC32需要在读取数据后
+// 通过HeaderSchema.Verify校验，服务端由zdecoder.SchemaTLVDecoder完成)
+type DataPackSchema struct {
+	packetLimit
+	schema *HeaderSchema
+}
+
+// NewDataPackSchema initializes a packing and unpacking instance with the given header schema
+// (使用指定头部格式的封包拆包实例初始化方法)
+func NewDataPackSchema(schema *HeaderSchema) ziface.IDataPack {
+	return &DataPackSchema{schema: schema}
+}
+
+// Schema returns the header schema of the datapack (返回数据包的头部格式)
+func (dp *DataPackSchema) Schema() *HeaderSchema {
+	return dp.schema
+}
+
+func (dp *DataPackSchema) GetHeadLen() uint32 {
+	return dp.schema.HeadLen()
+}
+
+func (dp *DataPackSchema) Pack(msg ziface.IMessage) ([]byte, error) {
+	headLen := dp.schema.HeadLen()
+	buf := AcquireBuffer(int(headLen) + len(msg.GetData()))
+	binary.BigEndian.PutUint32(buf[0:4], msg.GetMsgID())
+	binary.BigEndian.PutUint32(buf[4:8], uint32(len(msg.GetData())))
+	dp.schema.encode(buf[defaultHeaderLen:headLen], msg)
+	copy(buf[headLen:], msg.GetData())
+	return buf, nil
+}
+
+func (dp *DataPackSchema) ReleaseBuffer(buf []byte) {
+	ReleaseBuffer(buf)
+}
+
+func (dp *DataPackSchema) Unpack(binaryData []byte) (ziface.IMessage, error) {
+	headLen := dp.schema.HeadLen()
+	if uint32(len(binaryData)) < headLen {
+		return nil, zerrors.NewDecodeError("schema msg head too short")
+	}
+
+	msg := &Message{
+		ID:      binary.BigEndian.Uint32(binaryData[0:4]),
+		DataLen: binary.BigEndian.Uint32(binaryData[4:8]),
+	}
+
+	if err := dp.checkLength(msg.GetDataLen()); err != nil {
+		return nil, err
+	}
+
+	if err := dp.schema.decode(binaryData[defaultHeaderLen:headLen], msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// HasFlags reports whether the schema carries the flags field (头部格式是否包含flags字段)
+func (dp *DataPackSchema) HasFlags() bool {
+	return dp.schema.Flags
+}
@@ -0,0 +1,67 @@
+package zpack
+
+import (
+	"encoding/binary"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zutils"
+)
+
+// traceHeaderLen ID uint32(4 bytes) + DataLen uint32(4 bytes) + TraceID(16 bytes)
+var traceHeaderLen uint32 = 8 + zutils.TraceIDLen
+
+// DataPackTrace is the big endian TLV datapack with a trace ID after the length field,
+// messages without a trace ID are sent with 16 zero bytes.
+// (在长度字段之后携带链路追踪ID的大端TLV数据包，没有追踪ID的消息以16个0字节发送)
+//
+//	+---------------+---------------+----------------+-----------+
+//	|      ID       |    DataLen    |    TraceID     |   Data    |
+//	| uint32(4byte) | uint32(4byte) |    16 byte     |  n byte   |
+//	+---------------+---------------+----------------+-----------+
+type DataPackTrace struct {
+	packetLimit
+}
+
+// NewDataPackTrace initializes a tracing packing and unpacking instance
+// (支持链路追踪的封包拆包实例初始化方法)
+func NewDataPackTrace() ziface.IDataPack {
+	return &DataPackTrace{}
+}
+
+func (dp *DataPackTrace) GetHeadLen() uint32 {
+	return traceHeaderLen
+}
+
+func (dp *DataPackTrace) Pack(msg ziface.IMessage) ([]byte, error) {
+	buf := make([]byte, traceHeaderLen+uint32(len(msg.GetData())))
+	binary.BigEndian.PutUint32(buf[0:4], msg.GetMsgID())
+	binary.BigEndian.PutUint32(buf[4:8], uint32(len(msg.GetData())))
+
+	if tm, ok := msg.(ziface.ITraceMessage); ok {
+		if err := zutils.TraceIDToBytes(tm.GetTraceID(), buf[8:traceHeaderLen]); err != nil {
+			return nil, err
+		}
+	}
+
+	copy(buf[traceHeaderLen:], msg.GetData())
+	return buf, nil
+}
+
+func (dp *DataPackTrace) Unpack(binaryData []byte) (ziface.IMessage, error) {
+	if uint32(len(binaryData)) < traceHeaderLen {
+		return nil, zerrors.NewDecodeError("trace msg head too short")
+	}
+
+	msg := &Message{
+		ID:      binary.BigEndian.Uint32(binaryData[0:4]),
+		DataLen: binary.BigEndian.Uint32(binaryData[4:8]),
+		traceID: zutils.TraceIDFromBytes(binaryData[8:traceHeaderLen]),
+	}
+
+	if err := dp.checkLength(msg.GetDataLen()); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,72 @@
+package zpack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+// plainMsg is a message without a trace ID (不携带追踪ID的消息)
+type plainMsg struct {
+	ziface.IMessage
+	id   uint32
+	data []byte
+}
+
+func (m *plainMsg) GetMsgID() uint32 { return m.id }
+func (m *plainMsg) GetData() []byte  { return m.data }
+
+func TestDataPackTraceWithoutTraceID(t *testing.T) {
+	dp := NewDataPackTrace()
+
+	// Messages without a trace ID are sent with zeros and unpacked without one (无追踪ID的消息以0发送，拆包后同样没有)
+	for _, msg := range []ziface.IMessage{NewMsgPackage(3, []byte("hi")), &plainMsg{id: 3, data: []byte("hi")}} {
+		buf, err := dp.Pack(msg)
+		assert.Nil(t, err)
+		assert.Equal(t, make([]byte, 16), buf[8:dp.GetHeadLen()])
+		assert.Equal(t, []byte("hi"), buf[dp.GetHeadLen():])
+
+		head, err := dp.Unpack(buf[:dp.GetHeadLen()])
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(3), head.GetMsgID())
+		assert.Equal(t, uint32(2), head.GetDataLen())
+		assert.Equal(t, "", head.(ziface.ITraceMessage).GetTraceID())
+	}
+
+	// An empty body is only the header (空消息体只有包头)
+	buf, err := dp.Pack(NewMsgPackage(4, nil))
+	assert.Nil(t, err)
+	assert.Len(t, buf, int(dp.GetHeadLen()))
+}
+
+func TestDataPackTraceErrors(t *testing.T) {
+	dp := NewDataPackTrace()
+
+	msg := NewMsgPackage(1, []byte("x"))
+	msg.SetTraceID("not-a-trace-id")
+	buf, err := dp.Pack(msg)
+	assert.EqualError(t, err, "invalid trace id: not-a-trace-id")
+	assert.Nil(t, buf)
+
+	// A head shorter than the trace ID cannot be decoded (短于追踪ID的包头无法解码)
+	msg.SetTraceID("0af7651916cd43dd8448eb211c80319c")
+	buf, err = dp.Pack(msg)
+	assert.Nil(t, err)
+	_, err = dp.Unpack(buf[:dp.GetHeadLen()-1])
+	assert.True(t, errors.Is(err, zerrors.ErrDecode))
+	_, err = dp.Unpack(nil)
+	assert.True(t, errors.Is(err, zerrors.ErrDecode))
+
+	// The length is bounded like the other datapacks (长度与其它数据包一样受限)
+	buf, err = dp.Pack(NewMsgPackage(1, make([]byte, 32)))
+	assert.Nil(t, err)
+	dp.(MaxPacketSizer).SetMaxPacketSize(31)
+	_, err = dp.Unpack(buf[:dp.GetHeadLen()])
+	assert.True(t, errors.Is(err, zerrors.ErrFrameTooLong))
+	dp.(MaxPacketSizer).SetMaxPacketSize(32)
+	_, err = dp.Unpack(buf[:dp.GetHeadLen()])
+	assert.Nil(t, err)
+}
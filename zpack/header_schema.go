@@ -0,0 +1,302 @@
+package zpack
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zutils"
+)
+
+// Names of the optional header fields in zconf.Config.HeaderFields (zconf.Config.HeaderFields中可选头部字段的名称)
+const (
+	HeaderFieldVersion   = "version"   // uint8, protocol version (协议版本号)
+	HeaderFieldFlags     = "flags"     // uint16, ziface.MessageFlag* bitmask (标志位)
+	HeaderFieldCRC32     = "crc32"     // uint32, CRC32 (IEEE) of the data (数据的CRC32校验值)
+	HeaderFieldTimestamp = "timestamp" // int64, Unix milliseconds (毫秒时间戳)
+	HeaderFieldNonce     = "nonce"     // uint64, unique per message (每条消息唯一的随机数)
+	HeaderFieldTraceID   = "trace_id"  // [16]byte, see ziface.ITraceMessage, all zeros if absent (链路追踪ID，见ziface.ITraceMessage，没有时全为0)
+	HeaderFieldSignature = "signature" // [64]byte, see SigningPayload (消息签名，见SigningPayload)
+)
+
+// SignatureLen is the length of the signature field, that of an Ed25519 or HMAC-SHA512 signature, shorter
+// signatures are zero padded (signature字段的长度，即Ed25519或HMAC-SHA512签名的长度，较短的签名以0补齐)
+const SignatureLen = 64
+
+var (
+	// The header errors are zerrors.ErrDecode (头部错误属于zerrors.ErrDecode)
+	ErrHeaderVersion  = zerrors.NewDecodeError("zinx header: unsupported protocol version")
+	ErrHeaderChecksum = zerrors.NewDecodeError("zinx header: crc32 checksum mismatch")
+)
+
+// HeaderSchema describes the optional fields appended to the default ID|DataLen header,
+// the enabled fields are laid out in a fixed order, big endian:
+// (描述追加在默认ID|DataLen头部之后的可选字段，启用的字段按固定顺序以大端排列)
+//
+//	+-------+---------+---------+---------+-----------+--------+---------+----------+-----------+
+//	|  ID   | DataLen | Version |  Flags  |   CRC32   |  Time  |  Nonce  | TraceID  | Signature |  Data
+//	| 4byte |  4byte  | [1byte] | [2byte] |  [4byte]  | [8byte]| [8byte] | [16byte] | [64byte]  |  n byte
+//	+-------+---------+---------+---------+-----------+--------+---------+----------+-----------+
+type HeaderSchema struct {
+	Version   bool
+	Flags     bool
+	CRC32     bool
+	Timestamp bool
+	Nonce     bool
+	TraceID   bool
+	Signature bool
+
+	// ProtocolVersion is written into the version field, frames carrying another version are rejected,
+	// 0 accepts any version.
+	// (写入version字段的协议版本号，携带其他版本号的帧会被拒绝，为0时接受任意版本)
+	ProtocolVersion uint8
+}
+
+// NewHeaderSchema builds a schema from field names, see the HeaderField* constants
+// (根据字段名称构建头部格式，见HeaderField*常量)
+func NewHeaderSchema(fields []string, protocolVersion uint8) (*HeaderSchema, error) {
+	s := &HeaderSchema{ProtocolVersion: protocolVersion}
+	for _, field := range fields {
+		switch field {
+		case HeaderFieldVersion:
+			s.Version = true
+		case HeaderFieldFlags:
+			s.Flags = true
+		case HeaderFieldCRC32:
+			s.CRC32 = true
+		case HeaderFieldTimestamp:
+			s.Timestamp = true
+		case HeaderFieldNonce:
+			s.Nonce = true
+		case HeaderFieldTraceID:
+			s.TraceID = true
+		case HeaderFieldSignature:
+			s.Signature = true
+		default:
+			return nil, fmt.Errorf("unknown header field %q", field)
+		}
+	}
+	return s, nil
+}
+
+// HeaderSchemaFromConfig returns the schema configured by zconf.GlobalObject.HeaderFields,
+// nil if no optional field is configured. It panics on an unknown field name.
+// (返回zconf.GlobalObject.HeaderFields配置的头部格式，未配置可选字段时返回nil，字段名称未知时panic)
+func HeaderSchemaFromConfig() *HeaderSchema {
+	if len(zconf.GlobalObject.HeaderFields) == 0 {
+		return nil
+	}
+	s, err := NewHeaderSchema(zconf.GlobalObject.HeaderFields, zconf.GlobalObject.HeaderVersion)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// ExtLen returns the length of the optional fields (返回可选字段的总长度)
+func (s *HeaderSchema) ExtLen() uint32 {
+	var n uint32
+	if s.Version {
+		n += 1
+	}
+	if s.Flags {
+		n += 2
+	}
+	if s.CRC32 {
+		n += 4
+	}
+	if s.Timestamp {
+		n += 8
+	}
+	if s.Nonce {
+		n += 8
+	}
+	if s.TraceID {
+		n += zutils.TraceIDLen
+	}
+	if s.Signature {
+		n += SignatureLen
+	}
+	return n
+}
+
+// HeadLen returns the length of the whole header (返回完整头部长度)
+func (s *HeaderSchema) HeadLen() uint32 {
+	return defaultHeaderLen + s.ExtLen()
+}
+
+// encode writes the optional fields of msg into buf, len(buf) must be ExtLen()
+func (s *HeaderSchema) encode(buf []byte, msg ziface.IMessage) {
+	hm, _ := msg.(ziface.IHeaderMessage)
+	off := 0
+	if s.Version {
+		version := s.ProtocolVersion
+		if hm != nil && hm.GetVersion() != 0 {
+			version = hm.GetVersion()
+		}
+		buf[off] = version
+		off++
+	}
+	if s.Flags {
+		var flags uint32
+		if hm != nil {
+			flags = hm.GetFlags()
+		}
+		binary.BigEndian.PutUint16(buf[off:], uint16(flags))
+		off += 2
+	}
+	if s.CRC32 {
+		binary.BigEndian.PutUint32(buf[off:], crc32.ChecksumIEEE(msg.GetData()))
+		off += 4
+	}
+	if s.Timestamp {
+		ts := time.Now().UnixMilli()
+		if hm != nil && hm.GetTimestamp() != 0 {
+			ts = hm.GetTimestamp()
+		}
+		binary.BigEndian.PutUint64(buf[off:], uint64(ts))
+		off += 8
+	}
+	if s.Nonce {
+		nonce := NextNonce()
+		if hm != nil && hm.GetNonce() != 0 {
+			nonce = hm.GetNonce()
+		}
+		binary.BigEndian.PutUint64(buf[off:], nonce)
+		off += 8
+	}
+	if s.TraceID {
+		var traceID string
+		if tm, ok := msg.(ziface.ITraceMessage); ok {
+			traceID = tm.GetTraceID()
+		}
+		// An invalid trace ID is sent as absent (无效的追踪ID按没有追踪ID发送)
+		if zutils.TraceIDToBytes(traceID, buf[off:off+zutils.TraceIDLen]) != nil {
+			_ = zutils.TraceIDToBytes("", buf[off:off+zutils.TraceIDLen])
+		}
+		off += zutils.TraceIDLen
+	}
+	if s.Signature {
+		sig := buf[off : off+SignatureLen]
+		for i := range sig {
+			sig[i] = 0
+		}
+		if hm != nil {
+			copy(sig, hm.GetSignature())
+		}
+	}
+}
+
+// decode reads the optional fields from ext into msg and validates the version
+func (s *HeaderSchema) decode(ext []byte, msg ziface.IHeaderMessage) error {
+	off := 0
+	if s.Version {
+		msg.SetVersion(ext[off])
+		off++
+		if s.ProtocolVersion != 0 && msg.GetVersion() != s.ProtocolVersion {
+			return ErrHeaderVersion
+		}
+	}
+	if s.Flags {
+		msg.SetFlags(uint32(binary.BigEndian.Uint16(ext[off:])))
+		off += 2
+	}
+	if s.CRC32 {
+		msg.SetChecksum(binary.BigEndian.Uint32(ext[off:]))
+		off += 4
+	}
+	if s.Timestamp {
+		msg.SetTimestamp(int64(binary.BigEndian.Uint64(ext[off:])))
+		off += 8
+	}
+	if s.Nonce {
+		msg.SetNonce(binary.BigEndian.Uint64(ext[off:]))
+		off += 8
+	}
+	if s.TraceID {
+		if tm, ok := msg.(ziface.ITraceMessage); ok {
+			tm.SetTraceID(zutils.TraceIDFromBytes(ext[off : off+zutils.TraceIDLen]))
+		}
+		off += zutils.TraceIDLen
+	}
+	if s.Signature {
+		msg.SetSignature(append([]byte(nil), ext[off:off+SignatureLen]...))
+	}
+	return nil
+}
+
+// nonceSeq starts at a random number so that the nonces of the processes differ, the zero nonce is skipped
+// (nonceSeq从随机数开始，使各进程的nonce互不相同，跳过值为0的nonce)
+var nonceSeq = func() uint64 {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return binary.BigEndian.Uint64(b[:])
+}()
+
+// NextNonce returns a nonce unique within the process, never 0 (返回进程内唯一且不为0的nonce)
+func NextNonce() uint64 {
+	for {
+		if nonce := atomic.AddUint64(&nonceSeq, 1); nonce != 0 {
+			return nonce
+		}
+	}
+}
+
+// SigningPayload returns the bytes covered by the signature of msg: its MsgID, the header fields before the
+// signature, and its data. The timestamp and nonce of msg must be set before it is signed, they would be
+// generated when it is packed otherwise.
+// (返回msg的签名所覆盖的字节：MsgID、签名之前的头部字段及数据，签名前必须设置msg的时间戳与nonce，否则会在封包时生成)
+func (s *HeaderSchema) SigningPayload(msg ziface.IMessage) []byte {
+	extLen := s.ExtLen()
+	if s.Signature {
+		extLen -= SignatureLen
+	}
+	data := msg.GetData()
+	payload := make([]byte, 4+s.ExtLen(), 4+uint32(len(data))+s.ExtLen())
+	binary.BigEndian.PutUint32(payload, msg.GetMsgID())
+	s.encode(payload[4:], msg)
+	return append(payload[:4+extLen], data...)
+}
+
+// Verify checks the data of msg against the CRC32 carried in its header, it is a no-op
+// if the schema has no crc32 field.
+// (校验消息数据与头部携带的CRC32是否一致，未启用crc32字段时不做校验)
+func (s *HeaderSchema) Verify(msg ziface.IHeaderMessage) error {
+	if s.CRC32 && crc32.ChecksumIEEE(msg.GetData()) != msg.GetChecksum() {
+		return ErrHeaderChecksum
+	}
+	return nil
+}
+
+// Decode parses a complete frame, header and data, and validates the version and checksum
+// (解析完整的帧，包括头部和数据，并校验版本号和校验值)
+func (s *HeaderSchema) Decode(frame []byte) (*Message, error) {
+	headLen := s.HeadLen()
+	if uint32(len(frame)) < headLen {
+		return nil, zerrors.NewDecodeError("zinx header: frame too short")
+	}
+
+	msg := &Message{
+		ID:      binary.BigEndian.Uint32(frame[0:4]),
+		DataLen: binary.BigEndian.Uint32(frame[4:8]),
+	}
+	if uint64(len(frame)) < uint64(headLen)+uint64(msg.DataLen) {
+		return nil, zerrors.NewDecodeError("zinx header: frame too short")
+	}
+	if err := s.decode(frame[defaultHeaderLen:headLen], msg); err != nil {
+		return nil, err
+	}
+
+	msg.Data = frame[headLen : headLen+msg.DataLen]
+	msg.rawData = frame
+	if err := s.Verify(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
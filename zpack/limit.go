@@ -0,0 +1,55 @@
+package zpack
+
+import (
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zerrors"
+)
+
+// MaxPacketSizer is implemented by the datapacks of zpack, the servers set it to the MaxPacketSize of their own config
+// (由zpack的数据包实现，服务将其设置为自身配置中的MaxPacketSize)
+type MaxPacketSizer interface {
+	SetMaxPacketSize(size uint32)
+}
+
+// ConfigBinder is implemented by the datapacks of zpack, the servers bind them to their own config so that the
+// datapacks follow its MaxPacketSize, hot reloads included
+// (由zpack的数据包实现，服务将其绑定到自身的配置，数据包跟随其MaxPacketSize，包括热加载)
+type ConfigBinder interface {
+	BindConfig(config *zconf.Config)
+}
+
+// packetLimit bounds the data length unpacked by a datapack, the MaxPacketSize of the config it is bound to,
+// zconf.GlobalObject by default, until it is set
+// (限制数据包解出的数据长度，设置之前使用其绑定的配置(默认为zconf.GlobalObject)中的MaxPacketSize)
+type packetLimit struct {
+	size   uint32
+	set    bool
+	config *zconf.Config
+}
+
+// BindConfig bounds the data length of the frames unpacked by the MaxPacketSize of config unless it is set
+// (未设置限制时以config的MaxPacketSize限制解出的帧的数据长度)
+func (l *packetLimit) BindConfig(config *zconf.Config) {
+	l.config = config
+}
+
+// SetMaxPacketSize bounds the data length of the frames unpacked, 0 is unlimited (限制解出的帧的数据长度，0为不限制)
+func (l *packetLimit) SetMaxPacketSize(size uint32) {
+	l.size, l.set = size, true
+}
+
+// checkLength returns a zerrors.FrameTooLongError if dataLen is over the limit (dataLen超过限制时返回zerrors.FrameTooLongError)
+func (l *packetLimit) checkLength(dataLen uint32) error {
+	size := l.size
+	if !l.set {
+		config := l.config
+		if config == nil {
+			config = zconf.GlobalObject
+		}
+		size = config.MaxPacketSize
+	}
+	if size > 0 && dataLen > size {
+		return &zerrors.FrameTooLongError{Length: uint64(dataLen), Max: uint64(size)}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package zpack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/zerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMaxPacketSize(t *testing.T) {
+	dp := NewDataPack()
+	head, _ := dp.Pack(NewMsgPackage(1, make([]byte, 32)))
+	head = head[:dp.GetHeadLen()]
+
+	// The global config bounds the datapacks until they are set (设置之前由全局配置限制)
+	old := zconf.GlobalObject.MaxPacketSize
+	defer func() { zconf.GlobalObject.MaxPacketSize = old }()
+	zconf.GlobalObject.MaxPacketSize = 16
+	_, err := dp.Unpack(head)
+	assert.True(t, errors.Is(err, zerrors.ErrFrameTooLong))
+	var tooLong *zerrors.FrameTooLongError
+	assert.True(t, errors.As(err, &tooLong))
+	assert.Equal(t, uint64(32), tooLong.Length)
+
+	// A truncated head cannot be decoded (截断的头部无法解码)
+	_, err = dp.Unpack(head[:3])
+	assert.True(t, errors.Is(err, zerrors.ErrDecode))
+
+	dp.(MaxPacketSizer).SetMaxPacketSize(64)
+	_, err = dp.Unpack(head)
+	assert.Nil(t, err)
+
+	dp.(MaxPacketSizer).SetMaxPacketSize(8)
+	zconf.GlobalObject.MaxPacketSize = 0
+	_, err = dp.Unpack(head)
+	assert.NotNil(t, err)
+}
+
+func TestBindConfig(t *testing.T) {
+	dp := NewDataPack()
+	head, _ := dp.Pack(NewMsgPackage(1, make([]byte, 32)))
+	head = head[:dp.GetHeadLen()]
+
+	// The datapack follows the config it is bound to (数据包跟随其绑定的配置)
+	config := zconf.DefaultConfig()
+	config.MaxPacketSize = 16
+	dp.(ConfigBinder).BindConfig(config)
+	_, err := dp.Unpack(head)
+	assert.True(t, errors.Is(err, zerrors.ErrFrameTooLong))
+	config.MaxPacketSize = 64
+	_, err = dp.Unpack(head)
+	assert.Nil(t, err)
+
+	// A size set wins over the config (设置的限制优先于配置)
+	dp.(MaxPacketSizer).SetMaxPacketSize(8)
+	_, err = dp.Unpack(head)
+	assert.NotNil(t, err)
+}
@@ -6,6 +6,17 @@ type Message struct {
 	ID      uint32 // ID of the message
 	Data    []byte // Content of the message
 	rawData []byte // Raw data of the message
+	traceID string // Trace ID carried by tracing datapacks(支持链路追踪的数据包携带的追踪ID)
+	topic   string // Topic carried by JSON envelopes, see ziface.ITopicMessage(JSON信封携带的主题，见ziface.ITopicMessage)
+
+	flags         uint32 // Flags carried by envelope datapacks(信封数据包携带的标志位)
+	correlationID uint64 // Correlation ID carried by envelope datapacks(信封数据包携带的关联ID)
+
+	version   uint8  // Protocol version of the header schema(头部协议版本号)
+	checksum  uint32 // CRC32 of the data carried by the header schema(头部携带的数据CRC32校验值)
+	timestamp int64  // Unix milliseconds carried by the header schema(头部携带的毫秒时间戳)
+	nonce     uint64 // Unique number carried by the header schema(头部携带的唯一随机数)
+	signature []byte // Signature of the message carried by the header schema(头部携带的消息签名)
 }
 
 func NewMsgPackage(ID uint32, data []byte) *Message {
@@ -68,3 +79,75 @@ func (msg *Message) SetMsgID(msgID uint32) {
 func (msg *Message) SetData(data []byte) {
 	msg.Data = data
 }
+
+func (msg *Message) GetTraceID() string {
+	return msg.traceID
+}
+
+func (msg *Message) SetTraceID(traceID string) {
+	msg.traceID = traceID
+}
+
+func (msg *Message) GetTopic() string {
+	return msg.topic
+}
+
+func (msg *Message) SetTopic(topic string) {
+	msg.topic = topic
+}
+
+func (msg *Message) GetFlags() uint32 {
+	return msg.flags
+}
+
+func (msg *Message) SetFlags(flags uint32) {
+	msg.flags = flags
+}
+
+func (msg *Message) GetCorrelationID() uint64 {
+	return msg.correlationID
+}
+
+func (msg *Message) SetCorrelationID(id uint64) {
+	msg.correlationID = id
+}
+
+func (msg *Message) GetVersion() uint8 {
+	return msg.version
+}
+
+func (msg *Message) SetVersion(version uint8) {
+	msg.version = version
+}
+
+func (msg *Message) GetChecksum() uint32 {
+	return msg.checksum
+}
+
+func (msg *Message) SetChecksum(checksum uint32) {
+	msg.checksum = checksum
+}
+
+func (msg *Message) GetTimestamp() int64 {
+	return msg.timestamp
+}
+
+func (msg *Message) SetTimestamp(ms int64) {
+	msg.timestamp = ms
+}
+
+func (msg *Message) GetNonce() uint64 {
+	return msg.nonce
+}
+
+func (msg *Message) SetNonce(nonce uint64) {
+	msg.nonce = nonce
+}
+
+func (msg *Message) GetSignature() []byte {
+	return msg.signature
+}
+
+func (msg *Message) SetSignature(signature []byte) {
+	msg.signature = signature
+}
@@ -34,9 +34,23 @@ func (f *pack_factory) NewPack(kind string) ziface.IDataPack {
 	// Zinx standard default packaging and unpackaging method
 	// (Zinx 标准默认封包拆包方式)
 	case ziface.ZinxDataPack:
-		dataPack = NewDataPack()
+		// Optional header fields configured by zconf.Config.HeaderFields extend the default header
+		// (zconf.Config.HeaderFields配置的可选头部字段扩展默认头部)
+		if schema := HeaderSchemaFromConfig(); schema != nil {
+			dataPack = NewDataPackSchema(schema)
+		} else {
+			dataPack = NewDataPack()
+		}
 	case ziface.ZinxDataPackOld:
 		dataPack = NewDataPackLtv()
+	case ziface.ZinxDataPackTrace:
+		dataPack = NewDataPackTrace()
+	case ziface.ZinxDataPackProtobuf:
+		dataPack = NewDataPackProtobuf()
+	case ziface.ZinxDataPackJSON:
+		dataPack = NewDataPackJSON()
+	case ziface.ZinxDataPackJSONLine:
+		dataPack = NewDataPackJSONLine()
 		// case for custom packaging and unpackaging methods
 		// (case 自定义封包拆包方式case)
 	default:
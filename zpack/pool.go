@@ -0,0 +1,80 @@
+package zpack
+
+import (
+	"math/bits"
+	"sync"
+)
+
+var messagePool = sync.Pool{
+	New: func() interface{} {
+		return new(Message)
+	},
+}
+
+// AcquireMessage takes a Message from the object pool and initializes it,
+// it is handed back with Release once it is no longer used.
+// (从对象池中取出一个Message并初始化，不再使用时通过Release归还)
+func AcquireMessage(ID uint32, data []byte) *Message {
+	msg := messagePool.Get().(*Message)
+	msg.Init(ID, data)
+	return msg
+}
+
+// Release resets the message and puts it back into the object pool, the message
+// and its data must not be used afterwards.
+// (重置消息并放回对象池，之后不能再使用该消息及其数据)
+func (msg *Message) Release() {
+	*msg = Message{}
+	messagePool.Put(msg)
+}
+
+// Size classes of the pack buffer pools, powers of two from 64B to 64KB,
+// larger buffers are left to the GC.
+// (封包缓冲池的尺寸等级，从64B到64KB的2的幂，更大的缓冲交给GC)
+const (
+	minBufferClass = 6
+	maxBufferClass = 16
+)
+
+var bufferPools [maxBufferClass - minBufferClass + 1]sync.Pool
+
+// bufferHolders keeps the emptied *[]byte of bufferPools, putting a fresh pointer for every release would allocate
+// (保存bufferPools中取空的*[]byte，每次归还都新建指针会分配内存)
+var bufferHolders = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// AcquireBuffer returns a buffer of length n, taken from the pool when n fits a size class
+// (返回长度为n的缓冲，n在尺寸等级内时从缓冲池中取出)
+func AcquireBuffer(n int) []byte {
+	class := minBufferClass
+	if n > 1<<minBufferClass {
+		class = bits.Len(uint(n - 1))
+	}
+	if class > maxBufferClass {
+		return make([]byte, n)
+	}
+	if holder, ok := bufferPools[class-minBufferClass].Get().(*[]byte); ok {
+		buf := (*holder)[:n]
+		*holder = nil
+		bufferHolders.Put(holder)
+		return buf
+	}
+	return make([]byte, n, 1<<class)
+}
+
+// ReleaseBuffer hands a buffer returned by AcquireBuffer back to the pool,
+// buffers of any other capacity are ignored.
+// (将AcquireBuffer返回的缓冲归还缓冲池，其他容量的缓冲会被忽略)
+func ReleaseBuffer(buf []byte) {
+	c := cap(buf)
+	class := bits.Len(uint(c)) - 1
+	if class < minBufferClass || class > maxBufferClass || c != 1<<class {
+		return
+	}
+	holder := bufferHolders.Get().(*[]byte)
+	*holder = buf[:0]
+	bufferPools[class-minBufferClass].Put(holder)
+}
@@ -0,0 +1,75 @@
+package zpack
+
+import (
+	"testing"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireBuffer(t *testing.T) {
+	buf := AcquireBuffer(100)
+	assert.Len(t, buf, 100)
+	assert.Equal(t, 128, cap(buf))
+	ReleaseBuffer(buf)
+
+	assert.Equal(t, 64, cap(AcquireBuffer(0)))
+
+	// Larger than the biggest size class, not pooled
+	big := AcquireBuffer(1<<maxBufferClass + 1)
+	assert.Equal(t, 1<<maxBufferClass+1, cap(big))
+	ReleaseBuffer(big)
+}
+
+func TestDataPackPooledBuffer(t *testing.T) {
+	dp := NewDataPack()
+	msg := AcquireMessage(3, []byte("pooled"))
+	buf, err := dp.Pack(msg)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 3, 0, 0, 0, 6, 'p', 'o', 'o', 'l', 'e', 'd'}, buf)
+
+	releaser, ok := dp.(ziface.IBufferReleaser)
+	assert.True(t, ok)
+	releaser.ReleaseBuffer(buf)
+
+	msg.Release()
+	assert.Equal(t, Message{}, *msg)
+}
+
+func BenchmarkDataPackPack(b *testing.B) {
+	dp := NewDataPack()
+	releaser := dp.(ziface.IBufferReleaser)
+	data := make([]byte, 512)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msg := AcquireMessage(1, data)
+		buf, _ := dp.Pack(msg)
+		releaser.ReleaseBuffer(buf)
+		msg.Release()
+	}
+}
+
+func TestDataPackLtvPooledBuffer(t *testing.T) {
+	dp := NewDataPackLtv()
+	buf, err := dp.Pack(NewMsgPackage(3, []byte("ltv")))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{3, 0, 0, 0, 3, 0, 0, 0, 'l', 't', 'v'}, buf)
+
+	head, err := dp.Unpack(buf[:dp.GetHeadLen()])
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), head.GetMsgID())
+	assert.Equal(t, uint32(3), head.GetDataLen())
+
+	releaser, ok := dp.(ziface.IBufferReleaser)
+	assert.True(t, ok)
+	releaser.ReleaseBuffer(buf)
+}
+
+func BenchmarkDataPackUnpack(b *testing.B) {
+	dp := NewDataPack()
+	head := make([]byte, dp.GetHeadLen())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = dp.Unpack(head)
+	}
+}
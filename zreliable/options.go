@@ -0,0 +1,95 @@
+package zreliable
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+)
+
+// KeyFunc identifies the peer of a connection across reconnects, e.g. a device ID
+// (跨重连识别连接对端，例如设备ID)
+type KeyFunc func(conn ziface.IConnection) string
+
+type options struct {
+	maxPending  int
+	ttl         time.Duration
+	dedupWindow int
+	keyFunc     KeyFunc
+	onExpired   func(key string, msgID uint32, data []byte)
+}
+
+// Option configures a Reliable (Reliable的配置项)
+type Option func(o *options)
+
+// WithMaxPending bounds the unacknowledged messages retained per peer, 1024 by default
+// (每个对端保留的未确认消息上限，默认1024)
+func WithMaxPending(n int) Option {
+	return func(o *options) {
+		o.maxPending = n
+	}
+}
+
+// WithTTL sets how long an unacknowledged message is retained, 5 minutes by default
+// (未确认消息的保留时长，默认5分钟)
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithDedupWindow sets how many received sequence numbers are remembered per peer to drop redeliveries,
+// 1024 by default
+// (每个对端记录的已接收序号数量，用于丢弃重复投递，默认1024)
+func WithDedupWindow(n int) Option {
+	return func(o *options) {
+		o.dedupWindow = n
+	}
+}
+
+// WithKeyFunc sets how peers are identified, the key set by SetKey or the ConnID by default
+// (设置识别对端的方法，默认使用SetKey设置的标识，否则使用ConnID)
+func WithKeyFunc(keyFunc KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = keyFunc
+	}
+}
+
+// WithOnExpired sets the callback of messages dropped unacknowledged after their TTL
+// (设置超过TTL仍未确认而被丢弃的消息的回调)
+func WithOnExpired(onExpired func(key string, msgID uint32, data []byte)) Option {
+	return func(o *options) {
+		o.onExpired = onExpired
+	}
+}
+
+// keyProperty is the connection property set by SetKey (SetKey设置的连接属性)
+const keyProperty = "zinx.reliable.key"
+
+// SetKey sets the key identifying the peer of conn, call it in OnConnStart before Bind
+// (设置识别conn对端的标识，在OnConnStart中Bind之前调用)
+func SetKey(conn ziface.IConnection, key string) {
+	conn.SetProperty(keyProperty, key)
+}
+
+func defaultKey(conn ziface.IConnection) string {
+	if value, err := conn.GetProperty(keyProperty); err == nil {
+		if key, ok := value.(string); ok {
+			return key
+		}
+	}
+	return strconv.FormatUint(conn.GetConnID(), 10)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		maxPending:  1024,
+		ttl:         5 * time.Minute,
+		dedupWindow: 1024,
+		keyFunc:     defaultKey,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,259 @@
+// Package zreliable adds acknowledged delivery on top of zinx connections: reliable messages carry a
+// sequence number, are retained until the peer acks them and are redelivered when the peer reconnects.
+//
+//	r := zreliable.New(zreliable.WithTTL(time.Hour))
+//	s.AddInterceptor(r)
+//	s.SetOnConnStart(func(conn ziface.IConnection) {
+//		zreliable.SetKey(conn, deviceID(conn))
+//		r.Bind(conn)
+//	})
+//	r.Send(conn, 100, command)
+//
+// The peer must add the same interceptor to unwrap and acknowledge the messages.
+// (在zinx连接之上提供确认投递：可靠消息携带序号，在对端确认前一直保留，对端重连后重新投递。
+// 对端必须添加同样的拦截器来解包并确认消息)
+package zreliable
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+)
+
+// headerLen is the header of a reliable frame, epoch(8 bytes) + seq(8 bytes) + msgID(4 bytes),
+// an ack frame only holds the epoch and the seq
+// (可靠帧头长度，发送方纪元8字节 + 序号8字节 + MsgID 4字节，确认帧只包含纪元和序号)
+const (
+	headerLen = 20
+	ackLen    = 16
+)
+
+var (
+	ErrBufferFull = errors.New("zreliable: too many unacknowledged messages")
+	ErrNotBound   = errors.New("zreliable: peer has no connection")
+)
+
+type pending struct {
+	seq      uint64
+	msgID    uint32
+	data     []byte
+	deadline time.Time
+}
+
+// session is the state of one peer, it outlives its connections (单个对端的状态，生命周期长于其连接)
+type session struct {
+	conn    ziface.IConnection
+	nextSeq uint64
+	pending []*pending
+
+	// peerEpoch is the epoch of the peer the received seqs belong to (已接收序号所属的对端纪元)
+	peerEpoch uint64
+	received  map[uint64]struct{}
+	order     []uint64
+}
+
+// Reliable is the reliability layer, it is an interceptor to add on both peers
+// (可靠投递层，是需要在通信双方添加的拦截器)
+type Reliable struct {
+	opts     *options
+	sessions map[string]*session
+	// epoch is random per Reliable, so that the peer tells the seqs of a restarted sender from the old ones
+	// (每个Reliable随机生成，对端据此区分重启后的发送方序号与之前的序号)
+	epoch uint64
+	sync.Mutex
+}
+
+// New creates a Reliable (创建Reliable)
+func New(opts ...Option) *Reliable {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return &Reliable{
+		opts:     newOptions(opts),
+		sessions: make(map[string]*session),
+		epoch:    binary.LittleEndian.Uint64(b[:]),
+	}
+}
+
+func (r *Reliable) session(key string) *session {
+	s, ok := r.sessions[key]
+	if !ok {
+		s = &session{received: make(map[uint64]struct{})}
+		r.sessions[key] = s
+	}
+	return s
+}
+
+// expire drops the messages past their TTL, the caller holds the lock
+func (r *Reliable) expire(key string, s *session, now time.Time) {
+	kept := s.pending[:0]
+	for _, p := range s.pending {
+		if now.Before(p.deadline) {
+			kept = append(kept, p)
+		} else if r.opts.onExpired != nil {
+			r.opts.onExpired(key, p.msgID, p.data)
+		}
+	}
+	s.pending = kept
+}
+
+// Send sends data reliably, it is retained until acknowledged even if the send fails,
+// the message is then redelivered by the next Bind of the peer.
+// (可靠地发送数据，即使发送失败也会保留到被确认为止，之后由对端下一次Bind重新投递)
+func (r *Reliable) Send(conn ziface.IConnection, msgID uint32, data []byte) (seq uint64, err error) {
+	key := r.opts.keyFunc(conn)
+
+	r.Lock()
+	s := r.session(key)
+	s.conn = conn
+	r.expire(key, s, time.Now())
+	if len(s.pending) >= r.opts.maxPending {
+		r.Unlock()
+		return 0, ErrBufferFull
+	}
+	s.nextSeq++
+	p := &pending{seq: s.nextSeq, msgID: msgID, data: data, deadline: time.Now().Add(r.opts.ttl)}
+	s.pending = append(s.pending, p)
+	r.Unlock()
+
+	return p.seq, conn.SendMsg(ziface.ReliableMsgID, r.encode(p))
+}
+
+// Bind binds conn to its peer and redelivers the unacknowledged messages in order, call it once the peer
+// (re)connects, e.g. in OnConnStart
+// (将conn绑定到其对端并按顺序重新投递未确认的消息，在对端(重新)连接时调用，例如在OnConnStart中)
+func (r *Reliable) Bind(conn ziface.IConnection) error {
+	key := r.opts.keyFunc(conn)
+
+	r.Lock()
+	s := r.session(key)
+	s.conn = conn
+	r.expire(key, s, time.Now())
+	redeliver := append([]*pending(nil), s.pending...)
+	r.Unlock()
+
+	for _, p := range redeliver {
+		if err := conn.SendMsg(ziface.ReliableMsgID, r.encode(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pending returns the number of unacknowledged messages of a peer (返回对端未确认的消息数)
+func (r *Reliable) Pending(key string) int {
+	r.Lock()
+	defer r.Unlock()
+	if s, ok := r.sessions[key]; ok {
+		return len(s.pending)
+	}
+	return 0
+}
+
+// Forget drops all the state of a peer, e.g. when a device is deregistered (丢弃对端的全部状态，例如设备注销时)
+func (r *Reliable) Forget(key string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.sessions, key)
+}
+
+func (r *Reliable) ack(key string, epoch, seq uint64) {
+	r.Lock()
+	defer r.Unlock()
+	s, ok := r.sessions[key]
+	if !ok || epoch != r.epoch {
+		return
+	}
+	for i, p := range s.pending {
+		if p.seq == seq {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// firstDelivery records seq and reports whether it has not been received before,
+// the seqs received from a previous epoch of the peer are forgotten
+func (r *Reliable) firstDelivery(key string, epoch, seq uint64) bool {
+	r.Lock()
+	defer r.Unlock()
+	s := r.session(key)
+	if epoch != s.peerEpoch {
+		s.peerEpoch = epoch
+		s.received = make(map[uint64]struct{})
+		s.order = nil
+	}
+	if _, ok := s.received[seq]; ok {
+		return false
+	}
+	s.received[seq] = struct{}{}
+	s.order = append(s.order, seq)
+	if len(s.order) > r.opts.dedupWindow {
+		delete(s.received, s.order[0])
+		s.order = s.order[1:]
+	}
+	return true
+}
+
+// Intercept acknowledges and unwraps the reliable messages and consumes the acks
+// (确认并解包可靠消息，并消费确认帧)
+func (r *Reliable) Intercept(chain ziface.IChain) ziface.IcResp {
+	request, ok := chain.Request().(ziface.IRequest)
+	if !ok || request.GetConnection() == nil {
+		return chain.Proceed(chain.Request())
+	}
+	conn := request.GetConnection()
+
+	switch request.GetMsgID() {
+	case ziface.ReliableAckMsgID:
+		if data := request.GetData(); len(data) >= ackLen {
+			r.ack(r.opts.keyFunc(conn), binary.LittleEndian.Uint64(data[0:8]), binary.LittleEndian.Uint64(data[8:16]))
+		}
+		return nil
+	case ziface.ReliableMsgID:
+		epoch, seq, msgID, data, err := decode(request.GetData())
+		if err != nil {
+			zlog.Ins().ErrorF("connID = %d decode reliable msg err: %v", conn.GetConnID(), err)
+			return nil
+		}
+
+		ack := make([]byte, ackLen)
+		binary.LittleEndian.PutUint64(ack[0:8], epoch)
+		binary.LittleEndian.PutUint64(ack[8:16], seq)
+		if err := conn.SendMsg(ziface.ReliableAckMsgID, ack); err != nil {
+			zlog.Ins().ErrorF("connID = %d ack reliable msg seq = %d err: %v", conn.GetConnID(), seq, err)
+		}
+		if !r.firstDelivery(r.opts.keyFunc(conn), epoch, seq) {
+			return nil
+		}
+
+		msg := request.GetMessage()
+		msg.SetMsgID(msgID)
+		msg.SetData(data)
+		msg.SetDataLen(uint32(len(data)))
+		return chain.Proceed(request)
+	}
+	return chain.Proceed(chain.Request())
+}
+
+func (r *Reliable) encode(p *pending) []byte {
+	frame := make([]byte, headerLen+len(p.data))
+	binary.LittleEndian.PutUint64(frame[0:8], r.epoch)
+	binary.LittleEndian.PutUint64(frame[8:16], p.seq)
+	binary.LittleEndian.PutUint32(frame[16:20], p.msgID)
+	copy(frame[headerLen:], p.data)
+	return frame
+}
+
+func decode(frame []byte) (epoch, seq uint64, msgID uint32, data []byte, err error) {
+	if len(frame) < headerLen {
+		return 0, 0, 0, nil, fmt.Errorf("zreliable: frame too short, len = %d", len(frame))
+	}
+	return binary.LittleEndian.Uint64(frame[0:8]), binary.LittleEndian.Uint64(frame[8:16]),
+		binary.LittleEndian.Uint32(frame[16:20]), frame[headerLen:], nil
+}
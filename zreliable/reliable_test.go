@@ -0,0 +1,123 @@
+package zreliable
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zinterceptor"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordConn struct {
+	ziface.IConnection
+	sent []ziface.IMessage
+}
+
+func (c *recordConn) SendMsg(msgID uint32, data []byte) error {
+	c.sent = append(c.sent, zpack.NewMsgPackage(msgID, data))
+	return nil
+}
+
+func (c *recordConn) GetConnID() uint64 { return 1 }
+
+func (c *recordConn) GetProperty(key string) (interface{}, error) {
+	return nil, errors.New("no property found")
+}
+
+type capture struct {
+	msgs []ziface.IMessage
+}
+
+func (c *capture) Intercept(chain ziface.IChain) ziface.IcResp {
+	c.msgs = append(c.msgs, chain.Request().(ziface.IRequest).GetMessage())
+	return nil
+}
+
+func deliver(r *Reliable, conn ziface.IConnection, msg ziface.IMessage, next ziface.IInterceptor) {
+	// Every read produces a new message (每次读取都会产生新的消息)
+	request := znet.NewRequest(conn, zpack.NewMsgPackage(msg.GetMsgID(), msg.GetData()))
+	zinterceptor.NewChain([]ziface.IInterceptor{r, next}, 0, request).Proceed(request)
+}
+
+func TestReliableRedelivery(t *testing.T) {
+	byDevice := WithKeyFunc(func(conn ziface.IConnection) string { return "device-1" })
+	sender := New(byDevice)
+	receiver := New(byDevice)
+	handled := &capture{}
+
+	oldConn := &recordConn{}
+	_, err := sender.Send(oldConn, 100, []byte("reboot"))
+	assert.Nil(t, err)
+	_, err = sender.Send(oldConn, 101, []byte("upgrade"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, sender.Pending("device-1"))
+
+	// Only the first message reaches the device and is acknowledged
+	deviceConn := &recordConn{}
+	deliver(receiver, deviceConn, oldConn.sent[0], handled)
+	assert.Len(t, handled.msgs, 1)
+	assert.Equal(t, uint32(100), handled.msgs[0].GetMsgID())
+	assert.Equal(t, "reboot", string(handled.msgs[0].GetData()))
+	deliver(sender, oldConn, deviceConn.sent[0], handled)
+	assert.Equal(t, 1, sender.Pending("device-1"))
+
+	// The device reconnects, the unacknowledged message is redelivered
+	newConn := &recordConn{}
+	assert.Nil(t, sender.Bind(newConn))
+	assert.Len(t, newConn.sent, 1)
+	deliver(receiver, deviceConn, newConn.sent[0], handled)
+	assert.Equal(t, "upgrade", string(handled.msgs[1].GetData()))
+
+	// A duplicate is acknowledged again but not handled twice
+	deliver(receiver, deviceConn, newConn.sent[0], handled)
+	assert.Len(t, handled.msgs, 2)
+	assert.Len(t, deviceConn.sent, 3)
+}
+
+func TestReliableLimits(t *testing.T) {
+	var expired []uint32
+	r := New(WithMaxPending(1), WithTTL(10*time.Millisecond), WithOnExpired(func(key string, msgID uint32, data []byte) {
+		expired = append(expired, msgID)
+	}))
+	conn := &recordConn{}
+
+	_, err := r.Send(conn, 1, nil)
+	assert.Nil(t, err)
+	_, err = r.Send(conn, 2, nil)
+	assert.Equal(t, ErrBufferFull, err)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = r.Send(conn, 3, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1}, expired)
+}
+
+func TestReliableSenderRestart(t *testing.T) {
+	byDevice := WithKeyFunc(func(conn ziface.IConnection) string { return "device-1" })
+	receiver := New(byDevice)
+	handled := &capture{}
+	deviceConn := &recordConn{}
+
+	serverConn := &recordConn{}
+	_, err := New(byDevice).Send(serverConn, 100, []byte("reboot"))
+	assert.Nil(t, err)
+	deliver(receiver, deviceConn, serverConn.sent[0], handled)
+
+	// The restarted sender numbers its messages from 1 again, they are not taken for duplicates
+	restarted := New(byDevice)
+	_, err = restarted.Send(serverConn, 101, []byte("upgrade"))
+	assert.Nil(t, err)
+	deliver(receiver, deviceConn, serverConn.sent[1], handled)
+	assert.Len(t, handled.msgs, 2)
+	assert.Equal(t, "upgrade", string(handled.msgs[1].GetData()))
+
+	// An ack of the previous epoch does not ack the message of the same seq
+	deliver(restarted, serverConn, deviceConn.sent[0], handled)
+	assert.Equal(t, 1, restarted.Pending("device-1"))
+	deliver(restarted, serverConn, deviceConn.sent[1], handled)
+	assert.Equal(t, 0, restarted.Pending("device-1"))
+}
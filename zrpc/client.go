@@ -0,0 +1,184 @@
+package zrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+)
+
+var ErrNotConnected = errors.New("zrpc: client is not connected")
+
+type response struct {
+	err  string
+	body []byte
+}
+
+// Client calls the methods of a remote RPC Server over a zinx client connection
+// (通过zinx客户端连接调用远程RPC服务端的方法)
+type Client struct {
+	znet.BaseRouter
+	opts    *options
+	conn    func() ziface.IConnection
+	seq     uint32
+	pending map[uint32]chan *response
+	sync.Mutex
+}
+
+// NewClient creates an RPC Client on c and registers the reply router on it
+// (在c上创建RPC客户端并注册接收回复的路由)
+func NewClient(c ziface.IClient, opts ...Option) *Client {
+	rc := newClient(c.Conn, opts)
+	c.AddRouter(rc.opts.msgID, rc)
+	return rc
+}
+
+// NewConnClient creates an RPC Client on an accepted server-side connection, e.g. to call back the peer,
+// the caller must route opts' MsgID to the Client's Handle
+// (在服务端已建立的连接上创建RPC客户端，例如回调对端，调用者需要将MsgID路由到该Client的Handle)
+func NewConnClient(conn ziface.IConnection, opts ...Option) *Client {
+	return newClient(func() ziface.IConnection { return conn }, opts)
+}
+
+func newClient(conn func() ziface.IConnection, opts []Option) *Client {
+	return &Client{
+		opts:    newOptions(opts),
+		conn:    conn,
+		pending: make(map[uint32]chan *response),
+	}
+}
+
+// Call invokes the remote method "Service.Method" with req and decodes the reply into resp
+// (以req调用远程方法"Service.Method"，并将返回值解码到resp)
+func (c *Client) Call(ctx context.Context, method string, req interface{}, resp interface{}) error {
+	conn := c.conn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	body, err := c.opts.codec.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("zrpc: encode argument of %s: %v", method, err)
+	}
+
+	seq := atomic.AddUint32(&c.seq, 1)
+	frame, err := encodeFrame(seq, method, body)
+	if err != nil {
+		return err
+	}
+	ch := make(chan *response, 1)
+	c.Lock()
+	c.pending[seq] = ch
+	c.Unlock()
+
+	defer func() {
+		c.Lock()
+		delete(c.pending, seq)
+		c.Unlock()
+	}()
+
+	if err := conn.SendMsg(c.opts.msgID, frame); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case r := <-ch:
+		if r.err != "" {
+			return ServerError(r.err)
+		}
+		if resp == nil {
+			return nil
+		}
+		return c.opts.codec.Unmarshal(r.body, resp)
+	}
+}
+
+// Handle receives the replies of the Server (接收服务端的回复)
+func (c *Client) Handle(request ziface.IRequest) {
+	seq, errText, body, err := decodeFrame(request.GetData())
+	if err != nil {
+		znet.LoggerOf(request.GetConnection()).ErrorF("zrpc: decode reply error: %v", err)
+		return
+	}
+
+	c.Lock()
+	ch, ok := c.pending[seq]
+	c.Unlock()
+	if !ok {
+		// The caller has given up already (调用方已经放弃等待)
+		return
+	}
+
+	// body may share memory with a pooled buffer (body可能与对象池中的缓冲区共享内存)
+	select {
+	case ch <- &response{err: errText, body: append([]byte(nil), body...)}:
+	default:
+		// A duplicate reply must not block the reader, the first one is kept (重复的回复不能阻塞读协程，保留第一个)
+		znet.LoggerOf(request.GetConnection()).InfoF("zrpc: duplicate reply seq = %d", seq)
+	}
+}
+
+// Stub fills the func fields of the struct pointed to by stub with remote calls of "service.Field",
+// the field name can be overridden by a `zrpc:"Method"` tag. Fields must be of the form
+//
+//	Login func(ctx context.Context, req *LoginReq) (*LoginResp, error)
+//
+// (用远程调用"service.字段名"填充stub指向的结构体的函数字段，字段名可以通过`zrpc:"Method"`标签覆盖)
+func (c *Client) Stub(service string, stub interface{}) error {
+	v := reflect.ValueOf(stub)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("zrpc: stub must be a pointer to struct, got %T", stub)
+	}
+	v = v.Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		ft := field.Type
+		if ft.Kind() != reflect.Func || !field.IsExported() {
+			continue
+		}
+		if ft.NumIn() != 2 || ft.In(0) != typeOfContext || ft.NumOut() != 2 || ft.Out(1) != typeOfError {
+			return fmt.Errorf("zrpc: stub field %s has unsupported signature %s", field.Name, ft)
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("zrpc"); tag != "" {
+			name = tag
+		}
+		v.Field(i).Set(c.makeStubFunc(service+"."+name, ft))
+	}
+	return nil
+}
+
+func (c *Client) makeStubFunc(method string, ft reflect.Type) reflect.Value {
+	outType := ft.Out(0)
+	return reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		var resp reflect.Value
+		if outType.Kind() == reflect.Ptr {
+			resp = reflect.New(outType.Elem())
+		} else {
+			resp = reflect.New(outType)
+		}
+
+		err := c.Call(args[0].Interface().(context.Context), method, args[1].Interface(), resp.Interface())
+
+		errV := reflect.Zero(typeOfError)
+		if err != nil {
+			errV = reflect.ValueOf(&err).Elem()
+		}
+		if outType.Kind() != reflect.Ptr {
+			resp = resp.Elem()
+		}
+		if err != nil {
+			resp = reflect.Zero(outType)
+		}
+		return []reflect.Value{resp, errV}
+	})
+}
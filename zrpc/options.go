@@ -0,0 +1,39 @@
+package zrpc
+
+import (
+	"github.com/aceld/zinx/zcodec"
+	"github.com/aceld/zinx/ziface"
+)
+
+type options struct {
+	msgID uint32
+	codec ziface.ICodec
+}
+
+// Option configures an RPC Server or Client, both sides must use the same values
+// (RPC服务端或客户端的配置项，两端必须一致)
+type Option func(o *options)
+
+// WithMsgID sets the zinx MsgID carrying RPC frames, DefaultMsgID by default
+// (设置承载RPC帧的zinx MsgID，默认DefaultMsgID)
+func WithMsgID(msgID uint32) Option {
+	return func(o *options) {
+		o.msgID = msgID
+	}
+}
+
+// WithCodec sets the codec of arguments and replies, zcodec.JSON by default
+// (设置参数和返回值的编解码器，默认zcodec.JSON)
+func WithCodec(codec ziface.ICodec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{msgID: DefaultMsgID, codec: zcodec.JSON}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
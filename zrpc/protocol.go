@@ -0,0 +1,54 @@
+// Package zrpc is an optional RPC layer on top of zinx: exported methods of registered Go structs
+// are addressed by "Service.Method" names and carried by one zinx MsgID, the payload codec is pluggable.
+// (zinx之上的可选RPC层：注册的Go结构体的导出方法以"Service.Method"命名寻址，通过同一个zinx MsgID传输，消息体编解码器可替换)
+package zrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultMsgID is the zinx MsgID carrying RPC frames (承载RPC帧的默认zinx MsgID)
+const DefaultMsgID uint32 = 0xFFFF0001
+
+var errShortFrame = errors.New("zrpc: frame too short")
+
+// ErrHeadTooLong is returned for a method name or an error text longer than a frame can carry
+// (方法名或错误信息超过帧可以携带的长度时返回的错误)
+var ErrHeadTooLong = errors.New("zrpc: frame head too long")
+
+// ServerError is an error returned by the remote method (远程方法返回的错误)
+type ServerError string
+
+func (e ServerError) Error() string {
+	return string(e)
+}
+
+// encodeFrame encodes seq(4 bytes) + len(head)(2 bytes) + head + body, head is the method name
+// of a request or the error text of a response, it fails with ErrHeadTooLong if head exceeds 65535 bytes
+// (编码seq(4字节) + head长度(2字节) + head + body，请求的head为方法名，响应的head为错误信息，head超过65535字节时返回ErrHeadTooLong)
+func encodeFrame(seq uint32, head string, body []byte) ([]byte, error) {
+	if len(head) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w, len = %d", ErrHeadTooLong, len(head))
+	}
+	frame := make([]byte, 6+len(head)+len(body))
+	binary.LittleEndian.PutUint32(frame, seq)
+	binary.LittleEndian.PutUint16(frame[4:], uint16(len(head)))
+	copy(frame[6:], head)
+	copy(frame[6+len(head):], body)
+	return frame, nil
+}
+
+func decodeFrame(frame []byte) (seq uint32, head string, body []byte, err error) {
+	if len(frame) < 6 {
+		return 0, "", nil, errShortFrame
+	}
+	seq = binary.LittleEndian.Uint32(frame)
+	n := int(binary.LittleEndian.Uint16(frame[4:]))
+	if len(frame) < 6+n {
+		return 0, "", nil, errShortFrame
+	}
+	return seq, string(frame[6 : 6+n]), frame[6+n:], nil
+}
@@ -0,0 +1,132 @@
+package zrpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/znet"
+)
+
+var (
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type methodType struct {
+	rcvr    reflect.Value
+	method  reflect.Method
+	argType reflect.Type
+}
+
+// Server dispatches RPC frames to the methods of registered services
+// (将RPC帧分发到已注册服务的方法)
+type Server struct {
+	opts    *options
+	methods map[string]*methodType
+	sync.RWMutex
+}
+
+// NewServer creates an RPC Server and registers its handler on s
+// (创建RPC服务端并在s上注册处理函数)
+func NewServer(s ziface.IServer, opts ...Option) *Server {
+	rs := &Server{
+		opts:    newOptions(opts),
+		methods: make(map[string]*methodType),
+	}
+	znet.AddRouterHandler(s, rs.opts.msgID, rs.Handle)
+	return rs
+}
+
+// Register exposes the methods of rcvr under its type name, see RegisterName
+// (以rcvr的类型名注册其方法，参见RegisterName)
+func (s *Server) Register(rcvr interface{}) error {
+	return s.RegisterName(reflect.Indirect(reflect.ValueOf(rcvr)).Type().Name(), rcvr)
+}
+
+// RegisterName exposes the exported methods of rcvr of the form
+//
+//	func (t *T) Method(ctx context.Context, req *Req) (*Resp, error)
+//
+// as "name.Method", other methods are ignored. It returns an error if no method is suitable.
+// (将rcvr中符合上述形式的导出方法注册为"name.Method"，其他方法被忽略。没有合适的方法时返回错误)
+func (s *Server) RegisterName(name string, rcvr interface{}) error {
+	v := reflect.ValueOf(rcvr)
+	t := v.Type()
+
+	s.Lock()
+	defer s.Unlock()
+
+	count := 0
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if !suitableMethod(m.Type) {
+			continue
+		}
+		fullName := name + "." + m.Name
+		if _, ok := s.methods[fullName]; ok {
+			return fmt.Errorf("zrpc: method %s already registered", fullName)
+		}
+		s.methods[fullName] = &methodType{rcvr: v, method: m, argType: m.Type.In(2)}
+		zlog.Ins().InfoF("zrpc: register method %s", fullName)
+		count++
+	}
+
+	if count == 0 {
+		return fmt.Errorf("zrpc: type %s has no suitable methods", t)
+	}
+	return nil
+}
+
+// suitableMethod checks func(rcvr, context.Context, *Req) (Resp, error)
+func suitableMethod(mt reflect.Type) bool {
+	return mt.NumIn() == 3 && mt.In(1) == typeOfContext && mt.In(2).Kind() == reflect.Ptr &&
+		mt.NumOut() == 2 && mt.Out(1) == typeOfError
+}
+
+// Handle decodes one RPC request, calls the method and replies (解码一个RPC请求，调用方法并回复)
+func (s *Server) Handle(request ziface.IRequest) {
+	seq, name, body, err := decodeFrame(request.GetData())
+	if err != nil {
+		znet.LoggerOf(request.GetConnection()).ErrorF("zrpc: decode request error: %v", err)
+		return
+	}
+
+	reply, err := s.call(request.GetContext(), name, body)
+	errText := ""
+	if err != nil {
+		errText = err.Error()
+	}
+	frame, err := encodeFrame(seq, errText, reply)
+	if err != nil {
+		// The caller still gets a reply instead of waiting for its timeout (调用方仍会收到回复，而不是等到超时)
+		znet.LoggerOf(request.GetConnection()).ErrorF("zrpc: encode reply of %s error: %v", name, err)
+		frame, _ = encodeFrame(seq, ErrHeadTooLong.Error(), nil)
+	}
+	if sendErr := request.GetConnection().SendMsg(s.opts.msgID, frame); sendErr != nil {
+		znet.LoggerOf(request.GetConnection()).ErrorF("zrpc: send reply of %s error: %v", name, sendErr)
+	}
+}
+
+func (s *Server) call(ctx context.Context, name string, body []byte) ([]byte, error) {
+	s.RLock()
+	m, ok := s.methods[name]
+	s.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("zrpc: method %s not found", name)
+	}
+
+	arg := reflect.New(m.argType.Elem())
+	if err := s.opts.codec.Unmarshal(body, arg.Interface()); err != nil {
+		return nil, fmt.Errorf("zrpc: decode argument of %s: %v", name, err)
+	}
+
+	out := m.method.Func.Call([]reflect.Value{m.rcvr, reflect.ValueOf(ctx), arg})
+	if errV := out[1].Interface(); errV != nil {
+		return nil, errV.(error)
+	}
+	return s.opts.codec.Marshal(out[0].Interface())
+}
@@ -0,0 +1,145 @@
+package zrpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+	"github.com/stretchr/testify/assert"
+)
+
+type Arith struct{}
+
+type Args struct {
+	A, B int
+}
+
+type Quotient struct {
+	Quo, Rem int
+}
+
+func (t *Arith) Divide(ctx context.Context, args *Args) (*Quotient, error) {
+	if args.B == 0 {
+		return nil, errors.New("divide by zero")
+	}
+	return &Quotient{Quo: args.A / args.B, Rem: args.A % args.B}, nil
+}
+
+func (t *Arith) Fail(ctx context.Context, args *Args) (*Quotient, error) {
+	return nil, errors.New(strings.Repeat("x", args.A))
+}
+
+func (t *Arith) NotRPC(a int) int { return a }
+
+type ArithStub struct {
+	Divide func(ctx context.Context, args *Args) (*Quotient, error)
+	Div    func(ctx context.Context, args *Args) (*Quotient, error) `zrpc:"Divide"`
+}
+
+type fakeServer struct {
+	ziface.IServer
+	routers map[uint32]ziface.IRouter
+}
+
+func (s *fakeServer) AddRouter(msgID uint32, router ziface.IRouter) {
+	s.routers[msgID] = router
+}
+
+// pipeConn delivers every sent message to handle (将发送的消息交给handle处理)
+type pipeConn struct {
+	ziface.IConnection
+	handle func(request ziface.IRequest)
+	peer   *pipeConn
+}
+
+func (c *pipeConn) GetMsgHandler() ziface.IMsgHandle { return nil }
+
+func (c *pipeConn) SendMsg(msgID uint32, data []byte) error {
+	go c.handle(znet.NewRequest(c.peer, zpack.NewMsgPackage(msgID, data)))
+	return nil
+}
+
+func TestRPC(t *testing.T) {
+	fs := &fakeServer{routers: make(map[uint32]ziface.IRouter)}
+	server := NewServer(fs)
+	assert.NoError(t, server.Register(new(Arith)))
+	assert.Error(t, server.Register(new(Arith)))
+
+	serverSide := &pipeConn{}
+	clientSide := &pipeConn{peer: serverSide}
+	client := NewConnClient(clientSide)
+	clientSide.handle = fs.routers[DefaultMsgID].Handle
+	serverSide.peer = clientSide
+	serverSide.handle = client.Handle
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var q Quotient
+	assert.NoError(t, client.Call(ctx, "Arith.Divide", &Args{A: 7, B: 2}, &q))
+	assert.Equal(t, Quotient{Quo: 3, Rem: 1}, q)
+
+	err := client.Call(ctx, "Arith.Divide", &Args{A: 1}, &q)
+	assert.Equal(t, ServerError("divide by zero"), err)
+
+	err = client.Call(ctx, "Arith.NotRPC", &Args{}, &q)
+	assert.Error(t, err)
+
+	var stub ArithStub
+	assert.NoError(t, client.Stub("Arith", &stub))
+	res, err := stub.Divide(ctx, &Args{A: 9, B: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, &Quotient{Quo: 2, Rem: 1}, res)
+	_, err = stub.Div(ctx, &Args{A: 9})
+	assert.Error(t, err)
+
+	// A head too long for the frame fails instead of wrapping its length, on both sides
+	err = client.Call(ctx, strings.Repeat("m", math.MaxUint16+1), &Args{}, &q)
+	assert.ErrorIs(t, err, ErrHeadTooLong)
+	err = client.Call(ctx, "Arith.Fail", &Args{A: math.MaxUint16 + 1}, &q)
+	assert.Equal(t, ServerError(ErrHeadTooLong.Error()), err)
+}
+
+// replyConn replies every call twice from the reader, as a peer retrying would
+// (在读协程中对每个调用回复两次，如同重试的对端)
+type replyConn struct {
+	ziface.IConnection
+	client *Client
+}
+
+func (c *replyConn) GetMsgHandler() ziface.IMsgHandle { return nil }
+
+func (c *replyConn) SendMsg(msgID uint32, data []byte) error {
+	seq, _, _, err := decodeFrame(data)
+	if err != nil {
+		return err
+	}
+	body, _ := c.client.opts.codec.Marshal(&Quotient{Quo: 2})
+	frame, _ := encodeFrame(seq, "", body)
+	for i := 0; i < 2; i++ {
+		c.client.Handle(znet.NewRequest(c, zpack.NewMsgPackage(msgID, frame)))
+	}
+	return nil
+}
+
+func TestClientDuplicateReply(t *testing.T) {
+	conn := &replyConn{}
+	conn.client = NewConnClient(conn)
+
+	done := make(chan error, 1)
+	var quo Quotient
+	go func() { done <- conn.client.Call(context.Background(), "Arith.Divide", &Args{A: 4, B: 2}, &quo) }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Equal(t, 2, quo.Quo)
+	case <-time.After(time.Second):
+		t.Fatal("a duplicate reply blocked the reader")
+	}
+}
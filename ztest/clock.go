@@ -0,0 +1,114 @@
+package ztest
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// settleTimeout bounds the real time Advance waits for the goroutines woken at a deadline to wait on the clock again
+// (Advance等待在某个截止时间被唤醒的协程重新等待时钟的真实时间上限)
+const settleTimeout = 10 * time.Millisecond
+
+// clockWaiter is a channel returned by After (After返回的通道)
+type clockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// VirtualClock is a ztimer.Clock which only moves when Advance is called, set by ztimer.SetClock it drives the time
+// wheels, the connection timers and the heartbeats, so that minutes of timeouts are tested in milliseconds
+// (只有调用Advance时才会前进的ztimer.Clock，通过ztimer.SetClock设置后驱动时间轮、连接定时器与心跳，数分钟的超时可以在数毫秒内测完)
+type VirtualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*clockWaiter
+}
+
+// NewVirtualClock creates a VirtualClock starting at the current time (创建从当前时间开始的VirtualClock)
+func NewVirtualClock() *VirtualClock {
+	return &VirtualClock{now: time.Now()}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *VirtualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &clockWaiter{deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- c.now
+		return w.ch
+	}
+	c.waiters = append(c.waiters, w)
+	return w.ch
+}
+
+// Waiters returns the number of channels of After still to fire (返回After返回的尚未触发的通道数)
+func (c *VirtualClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// Advance moves the clock forward by d, stopping at every deadline on the way: the channels due fire, and the
+// goroutines they wake are given the time to wait on the clock again before it moves on. The goroutines just started,
+// e.g. the heartbeats of a new connection, are first given the time to wait on the clock
+// (将时钟向前推进d，途中在每个截止时间停下：触发到期的通道，并在继续推进之前等待被唤醒的协程重新等待时钟。
+// 刚启动的协程，例如新连接的心跳，会先获得开始等待时钟的时间)
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.quiesce()
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].deadline.Before(c.waiters[j].deadline) })
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(target) {
+			c.now = target
+			c.mu.Unlock()
+			break
+		}
+		c.now = c.waiters[0].deadline
+		due := 0
+		for due < len(c.waiters) && !c.waiters[due].deadline.After(c.now) {
+			c.waiters[due].ch <- c.now
+			due++
+		}
+		c.waiters = c.waiters[due:]
+		want := len(c.waiters) + due
+		c.mu.Unlock()
+
+		c.settle(want)
+	}
+	runtime.Gosched()
+}
+
+// quiesce waits until the number of channels waited on stops changing, or settleTimeout
+// (等待通道数不再变化，或等待settleTimeout)
+func (c *VirtualClock) quiesce() {
+	deadline := time.Now().Add(settleTimeout)
+	for n := c.Waiters(); time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		if m := c.Waiters(); m == n {
+			return
+		} else {
+			n = m
+		}
+	}
+}
+
+// settle waits until want channels are waited on, or settleTimeout (等待通道数回到want，或等待settleTimeout)
+func (c *VirtualClock) settle(want int) {
+	deadline := time.Now().Add(settleTimeout)
+	for c.Waiters() < want && time.Now().Before(deadline) {
+		runtime.Gosched()
+		time.Sleep(20 * time.Microsecond)
+	}
+}
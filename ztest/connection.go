@@ -0,0 +1,354 @@
+// Package ztest provides fakes of the connections and requests of zinx, so that the routers and handlers can be unit
+// tested without standing up a server:
+//
+//	conn := ztest.NewMockConnection(1)
+//	request := ztest.NewRequest(101).Conn(conn).String("ping").Build()
+//	ztest.Serve(&PingRouter{}, request)
+//	assert.Equal(t, "pong", string(conn.LastSent().GetData()))
+//
+// (ztest提供zinx连接与请求的仿冒实现，无需启动服务即可对路由与处理函数进行单元测试)
+package ztest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zerrors"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/zpack"
+	"github.com/gorilla/websocket"
+)
+
+// ErrClosed is returned by the sends on a stopped MockConnection, it is a zerrors.ErrConnClosed
+// (在已停止的MockConnection上发送时返回的错误，属于zerrors.ErrConnClosed)
+var ErrClosed = fmt.Errorf("ztest: %w", zerrors.ErrConnClosed)
+
+// closeCallback is a callback added by AddCloseCallback (通过AddCloseCallback添加的回调)
+type closeCallback struct {
+	handler, key interface{}
+	callback     func()
+}
+
+// MockConnection is a ziface.IConnection recording the messages sent instead of writing them to a socket, its
+// properties, send errors and stop are scriptable, it is safe for concurrent use
+// (记录发送的消息而不写入套接字的ziface.IConnection，其属性、发送错误与停止均可编排，可以并发使用)
+type MockConnection struct {
+	connID uint64
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	remoteAddr net.Addr
+	localAddr  net.Addr
+	msgHandler ziface.IMsgHandle
+	props      map[string]interface{}
+	tenant     string
+	traffic    ziface.TrafficStats
+	sent       []ziface.IMessage
+	frames     [][]byte
+	sendErr    error
+	onStop     func()
+	stopped    bool
+	callbacks  []closeCallback
+	timers     map[*mockTimer]struct{}
+}
+
+// NewMockConnection creates a MockConnection with connID, it comes from 127.0.0.1:50000 to 127.0.0.1:8999
+// (创建ID为connID的MockConnection，其远端地址为127.0.0.1:50000，本端地址为127.0.0.1:8999)
+func NewMockConnection(connID uint64) *MockConnection {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MockConnection{
+		connID:     connID,
+		ctx:        ctx,
+		cancel:     cancel,
+		remoteAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 50000},
+		localAddr:  &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8999},
+		props:      make(map[string]interface{}),
+		timers:     make(map[*mockTimer]struct{}),
+	}
+}
+
+// SetRemoteAddr sets the address the connection comes from (设置连接的远端地址)
+func (c *MockConnection) SetRemoteAddr(addr net.Addr) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remoteAddr = addr
+}
+
+// SetMsgHandler sets the message handler returned by GetMsgHandler, e.g. that of a server so that its config applies
+// (设置GetMsgHandler返回的消息处理器，例如某个服务的消息处理器，使其配置生效)
+func (c *MockConnection) SetMsgHandler(handler ziface.IMsgHandle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgHandler = handler
+}
+
+// FailSend makes the following sends fail with err, nil lets them succeed again
+// (使之后的发送返回err，为nil时恢复发送成功)
+func (c *MockConnection) FailSend(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sendErr = err
+}
+
+// OnStop calls f when the connection is stopped, before the close callbacks (连接停止时在关闭回调之前调用f)
+func (c *MockConnection) OnStop(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onStop = f
+}
+
+// Sent returns the messages sent so far, in order (按顺序返回目前已发送的消息)
+func (c *MockConnection) Sent() []ziface.IMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ziface.IMessage(nil), c.sent...)
+}
+
+// LastSent returns the last message sent, nil if none (返回最后发送的消息，没有时返回nil)
+func (c *MockConnection) LastSent() ziface.IMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.sent) == 0 {
+		return nil
+	}
+	return c.sent[len(c.sent)-1]
+}
+
+// SentFrames returns the raw frames given to Send and SendToQueue, in order
+// (按顺序返回通过Send与SendToQueue发送的原始帧)
+func (c *MockConnection) SentFrames() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.frames...)
+}
+
+// ClearSent forgets the messages and frames sent so far (清空目前已发送的消息与帧)
+func (c *MockConnection) ClearSent() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent, c.frames = nil, nil
+}
+
+func (c *MockConnection) Start() {}
+
+// Stop cancels the context and the timers of the connection and calls its close callbacks, once
+// (取消连接的context与定时器并调用其关闭回调，只执行一次)
+func (c *MockConnection) Stop() {
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.stopped = true
+	for t := range c.timers {
+		t.stop()
+	}
+	c.timers = make(map[*mockTimer]struct{})
+	onStop := c.onStop
+	c.mu.Unlock()
+
+	c.cancel()
+	if onStop != nil {
+		onStop()
+	}
+	c.InvokeCloseCallbacks()
+}
+
+func (c *MockConnection) Context() context.Context { return c.ctx }
+
+func (c *MockConnection) GetName() string { return "MockConnection" }
+
+func (c *MockConnection) GetConnection() net.Conn { return nil }
+
+func (c *MockConnection) GetWsConn() *websocket.Conn { return nil }
+
+func (c *MockConnection) GetTCPConnection() net.Conn { return nil }
+
+func (c *MockConnection) GetConnID() uint64 { return c.connID }
+
+func (c *MockConnection) GetConnIdStr() string { return strconv.FormatUint(c.connID, 10) }
+
+func (c *MockConnection) GetMsgHandler() ziface.IMsgHandle {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.msgHandler
+}
+
+func (c *MockConnection) GetWorkerID() uint32 { return 0 }
+
+func (c *MockConnection) RemoteAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remoteAddr
+}
+
+func (c *MockConnection) LocalAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.localAddr
+}
+
+func (c *MockConnection) LocalAddrString() string { return c.LocalAddr().String() }
+
+func (c *MockConnection) RemoteAddrString() string { return c.RemoteAddr().String() }
+
+// record checks that the connection can send and counts n bytes sent (检查连接能否发送并计入n字节的发送流量)
+func (c *MockConnection) record(n int) error {
+	if c.stopped {
+		return ErrClosed
+	}
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+	c.traffic.BytesOut += uint64(n)
+	c.traffic.MsgsOut++
+	return nil
+}
+
+func (c *MockConnection) Send(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(len(data)); err != nil {
+		return err
+	}
+	c.frames = append(c.frames, append([]byte(nil), data...))
+	return nil
+}
+
+func (c *MockConnection) SendToQueue(data []byte) error {
+	return c.Send(data)
+}
+
+// SendMsg records a message with a copy of data, the caller may reuse data (记录携带data副本的消息，调用方可以复用data)
+func (c *MockConnection) SendMsg(msgID uint32, data []byte) error {
+	return c.SendMessage(zpack.NewMsgPackage(msgID, append([]byte(nil), data...)))
+}
+
+func (c *MockConnection) SendBuffMsg(msgID uint32, data []byte) error {
+	return c.SendMsg(msgID, data)
+}
+
+// SendMessage records msg as it is, with its header fields (原样记录msg，包括其头部字段)
+func (c *MockConnection) SendMessage(msg ziface.IMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record(len(msg.GetData())); err != nil {
+		return err
+	}
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+// SendMsgAfter sends the message after delay on a time.Timer (使用time.Timer在delay之后发送消息)
+func (c *MockConnection) SendMsgAfter(delay time.Duration, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	data = append([]byte(nil), data...)
+	return c.AfterFunc(delay, func() { _ = c.SendMsg(msgID, data) }), nil
+}
+
+func (c *MockConnection) SendMsgAt(at time.Time, msgID uint32, data []byte) (ziface.IScheduledSend, error) {
+	return c.SendMsgAfter(time.Until(at), msgID, data)
+}
+
+// AfterFunc calls f after d on a time.Timer, the timer is canceled when the connection stops
+// (使用time.Timer在d之后调用f，连接停止时定时器被取消)
+func (c *MockConnection) AfterFunc(d time.Duration, f func()) ziface.IConnTimer {
+	return c.startTimer(d, f, false)
+}
+
+func (c *MockConnection) Ticker(d time.Duration, f func()) ziface.IConnTimer {
+	return c.startTimer(d, f, true)
+}
+
+func (c *MockConnection) SetProperty(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.props[key] = value
+}
+
+func (c *MockConnection) GetProperty(key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if value, ok := c.props[key]; ok {
+		return value, nil
+	}
+	return nil, errors.New("no property found")
+}
+
+func (c *MockConnection) RemoveProperty(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.props, key)
+}
+
+// GetProperties returns a copy of the properties, see ziface.IPropertyLister (返回属性的副本，见ziface.IPropertyLister)
+func (c *MockConnection) GetProperties() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	props := make(map[string]interface{}, len(c.props))
+	for key, value := range c.props {
+		props[key] = value
+	}
+	return props
+}
+
+func (c *MockConnection) IsAlive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.stopped
+}
+
+func (c *MockConnection) SetTenant(tenant string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tenant = tenant
+}
+
+func (c *MockConnection) GetTenant() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tenant
+}
+
+// GetTraffic returns the traffic sent, nothing is received by a MockConnection
+// (返回发送的流量，MockConnection不接收任何数据)
+func (c *MockConnection) GetTraffic() ziface.TrafficStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.traffic
+}
+
+func (c *MockConnection) SetHeartBeat(checker ziface.IHeartbeatChecker) {}
+
+func (c *MockConnection) AddCloseCallback(handler, key interface{}, callback func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callbacks = append(c.callbacks, closeCallback{handler: handler, key: key, callback: callback})
+}
+
+func (c *MockConnection) RemoveCloseCallback(handler, key interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, cb := range c.callbacks {
+		if cb.handler == handler && cb.key == key {
+			c.callbacks = append(c.callbacks[:i], c.callbacks[i+1:]...)
+			return
+		}
+	}
+}
+
+// InvokeCloseCallbacks calls the close callbacks in the calling goroutine, so that the tests see their effects at once
+// (在调用方协程中调用关闭回调，测试可以立即看到其效果)
+func (c *MockConnection) InvokeCloseCallbacks() {
+	c.mu.Lock()
+	callbacks := append([]closeCallback(nil), c.callbacks...)
+	c.mu.Unlock()
+	for _, cb := range callbacks {
+		cb.callback()
+	}
+}
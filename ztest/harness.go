@@ -0,0 +1,143 @@
+package ztest
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+	"github.com/aceld/zinx/ztimer"
+)
+
+// Harness runs a server through its whole pipeline, decoder, interceptors, workers and routers, on in-memory
+// connections, with a VirtualClock driving ztimer and the heartbeats:
+//
+//	h := ztest.NewHarness(znet.WithHeartbeatMax(3))
+//	defer h.Close()
+//	h.Server.StartHeartBeat(time.Second)
+//	h.Start()
+//	client := h.Dial()
+//	h.Advance(5 * time.Second)
+//	assert.True(t, client.WaitClosed(time.Second))
+//
+// (在内存连接上运行服务的完整流程，包括解码器、拦截器、worker与路由，并由VirtualClock驱动ztimer与心跳)
+type Harness struct {
+	Server   ziface.IServer
+	Clock    *VirtualClock
+	listener *MemListener
+	started  bool
+}
+
+// NewHarness creates a TCP server with its own config, see znet.NewServerWithOptions, serving a MemListener, the
+// clock of ztimer is a new VirtualClock until Close, only one harness runs at a time. Add the routers, then Start
+// (创建使用自己配置的TCP服务，见znet.NewServerWithOptions，在MemListener上提供服务，ztimer的时钟在Close之前为新的VirtualClock，
+// 同一时间只能运行一个Harness。添加路由之后调用Start)
+func NewHarness(opts ...znet.ServerOption) *Harness {
+	h := &Harness{Clock: NewVirtualClock(), listener: NewMemListener()}
+	ztimer.SetClock(h.Clock)
+	opts = append(opts, znet.WithMode(zconf.ServerModeTcp), znet.WithServerOptions(znet.WithListener(h.listener)))
+	h.Server = znet.NewServerWithOptions(opts...)
+	return h
+}
+
+// Start starts the server (启动服务)
+func (h *Harness) Start() {
+	h.Server.Start()
+	h.started = true
+}
+
+// Advance moves the virtual clock forward by d, see VirtualClock.Advance (将虚拟时钟向前推进d，见VirtualClock.Advance)
+func (h *Harness) Advance(d time.Duration) {
+	h.Clock.Advance(d)
+}
+
+// Dial connects a client to the server and waits for the server to read it, it panics if the harness is closed
+// (连接一个客户端到服务并等待服务开始读取，Harness已关闭时panic)
+func (h *Harness) Dial() *Client {
+	conn, server, err := h.listener.dial()
+	if err != nil {
+		panic(err)
+	}
+	// Wait for the server to start the connection, its timers are set before it reads
+	// (等待服务启动该连接，连接的定时器在开始读取之前设置)
+	select {
+	case <-server.reading:
+	case <-time.After(time.Second):
+	}
+	return &Client{conn: conn, packet: h.Server.GetPacket()}
+}
+
+// Close stops the server and restores the real clock of ztimer (停止服务并恢复ztimer的真实时钟)
+func (h *Harness) Close() {
+	if h.started {
+		h.Server.Stop()
+	}
+	h.listener.Close()
+	ztimer.SetClock(nil)
+}
+
+// Client is a connection to the server of a Harness, packing and unpacking with the datapack of the server
+// (连接到Harness服务的客户端，使用服务的数据包封包与拆包)
+type Client struct {
+	conn   net.Conn
+	packet ziface.IDataPack
+}
+
+// Conn returns the connection, e.g. to write malformed frames (返回连接，例如用于写入格式错误的帧)
+func (c *Client) Conn() net.Conn {
+	return c.conn
+}
+
+// SendMsg sends a message of msgID carrying data (发送携带data的msgID消息)
+func (c *Client) SendMsg(msgID uint32, data []byte) error {
+	return c.SendMessage(zpack.NewMsgPackage(msgID, data))
+}
+
+// SendMessage sends msg, e.g. one carrying header fields (发送msg，例如携带头部字段的消息)
+func (c *Client) SendMessage(msg ziface.IMessage) error {
+	buf, err := c.packet.Pack(msg)
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.Write(buf)
+	return err
+}
+
+// Recv returns the next message of the server, waiting at most timeout of real time, the server handles the
+// messages in its own goroutines (返回服务的下一条消息，最多等待timeout的真实时间，服务在自己的协程中处理消息)
+func (c *Client) Recv(timeout time.Duration) (ziface.IMessage, error) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	head := make([]byte, c.packet.GetHeadLen())
+	if _, err := io.ReadFull(c.conn, head); err != nil {
+		return nil, err
+	}
+	msg, err := c.packet.Unpack(head)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, msg.GetDataLen())
+	if _, err := io.ReadFull(c.conn, data); err != nil {
+		return nil, err
+	}
+	msg.SetData(data)
+	return msg, nil
+}
+
+// WaitClosed discards the messages of the server until it closes the connection, it reports false if it is still
+// open after timeout of real time (丢弃服务的消息直到其关闭连接，经过timeout的真实时间后仍未关闭时返回false)
+func (c *Client) WaitClosed(timeout time.Duration) bool {
+	_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+	_, err := io.Copy(io.Discard, c.conn)
+	return err == nil
+}
+
+// Close closes the connection (关闭连接)
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,85 @@
+package ztest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zconf"
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHarnessRouter(t *testing.T) {
+	h := NewHarness()
+	defer h.Close()
+	h.Server.AddRouter(1, &echoRouter{})
+	h.Start()
+
+	client := h.Dial()
+	assert.Nil(t, client.SendMsg(1, []byte("ping")))
+	msg, err := client.Recv(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), msg.GetMsgID())
+	assert.Equal(t, ":ping", string(msg.GetData()))
+}
+
+func TestHarnessHeartbeat(t *testing.T) {
+	h := NewHarness(znet.WithHeartbeatMax(3))
+	defer h.Close()
+	h.Server.StartHeartBeat(time.Second)
+	h.Start()
+	client := h.Dial()
+
+	// The heartbeats of the client keep the connection alive (客户端的心跳使连接保持存活)
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, client.SendMsg(ziface.HeartBeatDefaultMsgID, []byte("beat")))
+		h.Advance(time.Second)
+		msg, err := client.Recv(time.Second)
+		assert.Nil(t, err)
+		assert.Equal(t, ziface.HeartBeatDefaultMsgID, msg.GetMsgID())
+	}
+
+	// A silent client is closed once HeartbeatMax has passed, in virtual time (沉默的客户端在经过HeartbeatMax的虚拟时间后被关闭)
+	h.Advance(time.Second)
+	assert.Equal(t, 1, h.Server.GetConnMgr().Len())
+	h.Advance(3 * time.Second)
+	assert.True(t, client.WaitClosed(time.Second))
+}
+
+func TestHarnessAuthTimeout(t *testing.T) {
+	h := NewHarness(
+		znet.WithConfig(func(config *zconf.Config) { config.AuthTimeout = 60000 }),
+		znet.WithServerOptions(znet.WithAuth(znet.TokenAuthenticator(func(token string) (*ziface.Principal, error) {
+			return nil, errors.New("bad token")
+		}), 1)),
+	)
+	defer h.Close()
+	h.Start()
+	client := h.Dial()
+
+	// A minute of timeout in virtual time (虚拟时间中一分钟的超时)
+	h.Advance(59 * time.Second)
+	assert.False(t, client.WaitClosed(10*time.Millisecond))
+	h.Advance(2 * time.Second)
+	assert.True(t, client.WaitClosed(time.Second))
+}
+
+func TestHarnessFaults(t *testing.T) {
+	h := NewHarness()
+	defer h.Close()
+	h.Server.AddRouter(1, &echoRouter{})
+	h.Start()
+	client := h.Dial()
+
+	// The dropped frames never reach the router (被丢弃的帧不会到达路由)
+	assert.Nil(t, h.Server.SetFaults(ziface.Faults{DropRatio: 1}))
+	assert.Nil(t, client.SendMsg(1, []byte("lost")))
+	_, err := client.Recv(50 * time.Millisecond)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, h.Server.SetFaults(ziface.Faults{CloseRatio: 1}))
+	assert.Nil(t, client.SendMsg(1, []byte("bye")))
+	assert.True(t, client.WaitClosed(time.Second))
+}
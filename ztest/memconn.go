@@ -0,0 +1,180 @@
+package ztest
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// memAddr is the address of an in-memory connection (内存连接的地址)
+type memAddr string
+
+func (a memAddr) Network() string { return "mem" }
+
+func (a memAddr) String() string { return string(a) }
+
+// memBuffer is one direction of an in-memory connection, unbounded so that a writer never waits for the reader
+// (内存连接的一个方向，容量不限，写入方从不等待读取方)
+type memBuffer struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	data   []byte
+	closed bool
+}
+
+func newMemBuffer() *memBuffer {
+	b := &memBuffer{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *memBuffer) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.cond.Broadcast()
+}
+
+// memConn is a net.Conn whose peer is in the same process, its read deadlines are on the real clock
+// (对端位于同一进程中的net.Conn，其读超时基于真实时钟)
+type memConn struct {
+	in, out       *memBuffer
+	local, remote net.Addr
+
+	mu           sync.Mutex
+	readDeadline time.Time
+	deadlineWake *time.Timer
+
+	// reading is closed by the first Read (第一次Read时关闭)
+	reading     chan struct{}
+	readingOnce sync.Once
+}
+
+// MemPipe returns the two ends of an in-memory connection, unlike net.Pipe the writes never wait for the reads
+// (返回内存连接的两端，与net.Pipe不同，写入从不等待读取)
+func MemPipe(addr1, addr2 string) (net.Conn, net.Conn) {
+	a, b := newMemBuffer(), newMemBuffer()
+	return &memConn{in: a, out: b, local: memAddr(addr1), remote: memAddr(addr2), reading: make(chan struct{})},
+		&memConn{in: b, out: a, local: memAddr(addr2), remote: memAddr(addr1), reading: make(chan struct{})}
+}
+
+func (c *memConn) Read(p []byte) (int, error) {
+	c.readingOnce.Do(func() { close(c.reading) })
+	c.in.mu.Lock()
+	defer c.in.mu.Unlock()
+	for len(c.in.data) == 0 {
+		if c.in.closed {
+			return 0, io.EOF
+		}
+		c.mu.Lock()
+		deadline := c.readDeadline
+		c.mu.Unlock()
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		c.in.cond.Wait()
+	}
+	n := copy(p, c.in.data)
+	c.in.data = c.in.data[n:]
+	return n, nil
+}
+
+func (c *memConn) Write(p []byte) (int, error) {
+	c.out.mu.Lock()
+	defer c.out.mu.Unlock()
+	if c.out.closed {
+		return 0, io.ErrClosedPipe
+	}
+	c.out.data = append(c.out.data, p...)
+	c.out.cond.Broadcast()
+	return len(p), nil
+}
+
+// Close closes both directions, the peer reads what was written before io.EOF
+// (关闭两个方向，对端在读到io.EOF之前会读完已写入的数据)
+func (c *memConn) Close() error {
+	c.in.close()
+	c.out.close()
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr { return c.local }
+
+func (c *memConn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *memConn) SetDeadline(t time.Time) error { return c.SetReadDeadline(t) }
+
+func (c *memConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	if c.deadlineWake != nil {
+		c.deadlineWake.Stop()
+	}
+	if !t.IsZero() {
+		// Wake the reader up at the deadline (在截止时间唤醒读取方)
+		c.deadlineWake = time.AfterFunc(time.Until(t), func() {
+			c.in.mu.Lock()
+			defer c.in.mu.Unlock()
+			c.in.cond.Broadcast()
+		})
+	}
+	return nil
+}
+
+// SetWriteDeadline is a no-op, the writes never wait (空操作，写入从不等待)
+func (c *memConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// MemListener is a net.Listener of in-memory connections, see znet.WithListener
+// (内存连接的net.Listener，见znet.WithListener)
+type MemListener struct {
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+	lastPort  uint32
+}
+
+// NewMemListener creates a MemListener (创建MemListener)
+func NewMemListener() *MemListener {
+	return &MemListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+// Dial connects to the listener, it returns once the connection is accepted, every connection comes from its own
+// address, 127.0.0.1 with a port counting from 1
+// (连接到监听器，连接被接受后返回，每个连接的地址各不相同，为127.0.0.1加上从1开始计数的端口)
+func (l *MemListener) Dial() (net.Conn, error) {
+	client, _, err := l.dial()
+	return client, err
+}
+
+// dial is Dial also returning the accepted end (同Dial，并返回被接受的一端)
+func (l *MemListener) dial() (net.Conn, *memConn, error) {
+	port := atomic.AddUint32(&l.lastPort, 1)
+	client, server := MemPipe("127.0.0.1:"+strconv.FormatUint(uint64(port), 10), l.Addr().String())
+	select {
+	case l.conns <- server:
+		return client, server.(*memConn), nil
+	case <-l.closed:
+		return nil, nil, net.ErrClosed
+	}
+}
+
+func (l *MemListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *MemListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *MemListener) Addr() net.Addr { return memAddr("127.0.0.1:0") }
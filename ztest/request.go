@@ -0,0 +1,102 @@
+package ztest
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/aceld/zinx/zpack"
+)
+
+// RequestBuilder builds the requests handed to the routers under test (构建交给被测路由的请求)
+type RequestBuilder struct {
+	conn ziface.IConnection
+	msg  ziface.IMessage
+	keys map[string]interface{}
+	ctx  context.Context
+}
+
+// NewRequest starts building a request of msgID without data, on a new MockConnection with ID 1 unless Conn is called
+// (开始构建msgID的请求，不携带数据，未调用Conn时使用ID为1的新MockConnection)
+func NewRequest(msgID uint32) *RequestBuilder {
+	return &RequestBuilder{msg: zpack.NewMsgPackage(msgID, nil), keys: make(map[string]interface{})}
+}
+
+// Conn sets the connection the request comes from (设置请求所属的连接)
+func (b *RequestBuilder) Conn(conn ziface.IConnection) *RequestBuilder {
+	b.conn = conn
+	return b
+}
+
+// Data sets the data of the message (设置消息的数据)
+func (b *RequestBuilder) Data(data []byte) *RequestBuilder {
+	b.msg.SetData(data)
+	b.msg.SetDataLen(uint32(len(data)))
+	return b
+}
+
+// String sets the data of the message to s (将消息的数据设置为s)
+func (b *RequestBuilder) String(s string) *RequestBuilder {
+	return b.Data([]byte(s))
+}
+
+// JSON sets the data of the message to v encoded as JSON, it panics if v cannot be encoded
+// (将消息的数据设置为v的JSON编码，v无法编码时panic)
+func (b *RequestBuilder) JSON(v interface{}) *RequestBuilder {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b.Data(data)
+}
+
+// Message replaces the message of the request, e.g. one carrying header fields, the MsgID and data set so far are
+// dropped (替换请求的消息，例如携带头部字段的消息，之前设置的MsgID与数据被丢弃)
+func (b *RequestBuilder) Message(msg ziface.IMessage) *RequestBuilder {
+	b.msg = msg
+	return b
+}
+
+// Set sets a request-scoped value, as a middleware would before the handler (设置请求级上下文，如同处理函数之前的中间件)
+func (b *RequestBuilder) Set(key string, value interface{}) *RequestBuilder {
+	b.keys[key] = value
+	return b
+}
+
+// Context sets the context of the request (设置请求的Context)
+func (b *RequestBuilder) Context(ctx context.Context) *RequestBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// Build returns the request (返回构建的请求)
+func (b *RequestBuilder) Build() ziface.IRequest {
+	conn := b.conn
+	if conn == nil {
+		conn = NewMockConnection(1)
+	}
+	request := znet.NewRequest(conn, b.msg)
+	for key, value := range b.keys {
+		request.Set(key, value)
+	}
+	if b.ctx != nil {
+		request.SetContext(b.ctx)
+	}
+	return request
+}
+
+// Serve runs PreHandle, Handle and PostHandle of router on request, as a worker of the server does
+// (如同服务的worker一样，对request依次执行router的PreHandle、Handle与PostHandle)
+func Serve(router ziface.IRouter, request ziface.IRequest) {
+	request.BindRouter(router)
+	request.Call()
+}
+
+// ServeSlices runs the handlers on request as a RouterSlicesMode server does, Abort follows the RouterSlicesMode of
+// the config of the connection, see MockConnection.SetMsgHandler
+// (如同RouterSlicesMode的服务一样对request执行handlers，Abort遵循连接配置中的RouterSlicesMode，见MockConnection.SetMsgHandler)
+func ServeSlices(request ziface.IRequest, handlers ...ziface.RouterHandler) {
+	request.BindRouterSlices(handlers)
+	request.RouterSlicesNext()
+}
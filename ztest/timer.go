@@ -0,0 +1,93 @@
+package ztest
+
+import (
+	"sync"
+	"time"
+)
+
+// mockTimer is the ziface.IConnTimer of a MockConnection, backed by a time.Timer, the connection keeps its timers
+// until it stops (MockConnection的ziface.IConnTimer，基于time.Timer实现，连接在停止前一直持有其定时器)
+type mockTimer struct {
+	conn   *MockConnection
+	f      func()
+	ticker bool
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	period  time.Duration
+	pending bool
+}
+
+func (c *MockConnection) startTimer(d time.Duration, f func(), ticker bool) *mockTimer {
+	t := &mockTimer{conn: c, f: f, ticker: ticker}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return t
+	}
+	c.timers[t] = struct{}{}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.schedule(d)
+	return t
+}
+
+// schedule arms the timer, t.mu must be held (启动定时器，须持有t.mu)
+func (t *mockTimer) schedule(d time.Duration) {
+	t.period, t.pending = d, true
+	t.timer = time.AfterFunc(d, t.fire)
+}
+
+func (t *mockTimer) fire() {
+	t.mu.Lock()
+	if !t.pending {
+		t.mu.Unlock()
+		return
+	}
+	t.pending = t.ticker
+	t.mu.Unlock()
+
+	t.f()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending {
+		t.timer = time.AfterFunc(t.period, t.fire)
+	}
+}
+
+// stop cancels the timer, it reports whether it was pending (取消定时器，返回其是否在等待中)
+func (t *mockTimer) stop() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := t.pending
+	t.pending = false
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	return pending
+}
+
+func (t *mockTimer) Cancel() bool {
+	return t.stop()
+}
+
+func (t *mockTimer) Reset(d time.Duration) bool {
+	pending := t.stop()
+	t.conn.mu.Lock()
+	defer t.conn.mu.Unlock()
+	if t.conn.stopped {
+		return pending
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conn.timers[t] = struct{}{}
+	t.schedule(d)
+	return pending
+}
+
+func (t *mockTimer) Pending() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pending
+}
@@ -0,0 +1,105 @@
+package ztest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/ziface"
+	"github.com/aceld/zinx/znet"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ ziface.IConnection = (*MockConnection)(nil)
+
+type echoRouter struct {
+	znet.BaseRouter
+	steps []string
+}
+
+func (r *echoRouter) PreHandle(request ziface.IRequest) { r.steps = append(r.steps, "pre") }
+
+func (r *echoRouter) Handle(request ziface.IRequest) {
+	r.steps = append(r.steps, "handle")
+	_ = request.GetConnection().SendMsg(request.GetMsgID()+1, append([]byte(request.GetString("user")+":"), request.GetData()...))
+}
+
+func (r *echoRouter) PostHandle(request ziface.IRequest) { r.steps = append(r.steps, "post") }
+
+func TestServe(t *testing.T) {
+	conn := NewMockConnection(7)
+	router := &echoRouter{}
+	Serve(router, NewRequest(100).Conn(conn).String("ping").Set("user", "alice").Build())
+
+	assert.Equal(t, []string{"pre", "handle", "post"}, router.steps)
+	assert.Len(t, conn.Sent(), 1)
+	assert.Equal(t, uint32(101), conn.LastSent().GetMsgID())
+	assert.Equal(t, "alice:ping", string(conn.LastSent().GetData()))
+	assert.Equal(t, uint64(1), conn.GetTraffic().MsgsOut)
+
+	// The deferred replies go through the connection too (延迟回复同样经由该连接发送)
+	request := NewRequest(100).Conn(conn).JSON(map[string]int{"n": 1}).Build()
+	assert.Nil(t, request.Defer().Reply(102, request.GetData()))
+	assert.Equal(t, `{"n":1}`, string(conn.LastSent().GetData()))
+}
+
+func TestServeSlices(t *testing.T) {
+	var calls []string
+	request := NewRequest(1).Build()
+	ServeSlices(request,
+		func(request ziface.IRequest) {
+			calls = append(calls, "auth")
+			request.Set("user", "bob")
+			request.RouterSlicesNext()
+			calls = append(calls, "after")
+		},
+		func(request ziface.IRequest) { calls = append(calls, request.GetString("user")) },
+	)
+	assert.Equal(t, []string{"auth", "bob", "after"}, calls)
+	_, ok := request.GetConnection().(*MockConnection)
+	assert.True(t, ok)
+}
+
+func TestMockConnectionScript(t *testing.T) {
+	conn := NewMockConnection(1)
+	conn.SetProperty("role", "admin")
+	role, err := conn.GetProperty("role")
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", role)
+	assert.Equal(t, "127.0.0.1:50000", conn.RemoteAddrString())
+
+	conn.FailSend(errors.New("broken pipe"))
+	assert.EqualError(t, conn.SendMsg(1, nil), "broken pipe")
+	conn.FailSend(nil)
+	assert.Nil(t, conn.Send([]byte("raw")))
+	assert.Equal(t, [][]byte{[]byte("raw")}, conn.SentFrames())
+
+	var order []string
+	conn.OnStop(func() { order = append(order, "stop") })
+	conn.AddCloseCallback("h", "k", func() { order = append(order, "callback") })
+	timer := conn.AfterFunc(time.Hour, func() { order = append(order, "timer") })
+	conn.Stop()
+	conn.Stop()
+	assert.Equal(t, []string{"stop", "callback"}, order)
+	assert.False(t, conn.IsAlive())
+	assert.False(t, timer.Pending())
+	assert.Equal(t, ErrClosed, conn.SendMsg(1, nil))
+	assert.NotNil(t, conn.Context().Err())
+}
+
+func TestMockConnectionTimers(t *testing.T) {
+	conn := NewMockConnection(1)
+	defer conn.Stop()
+
+	_, err := conn.SendMsgAfter(10*time.Millisecond, 5, []byte("later"))
+	assert.Nil(t, err)
+	ticks := make(chan struct{}, 10)
+	ticker := conn.Ticker(5*time.Millisecond, func() { ticks <- struct{}{} })
+	<-ticks
+	<-ticks
+	assert.True(t, ticker.Cancel())
+	assert.False(t, ticker.Cancel())
+
+	assert.Eventually(t, func() bool { return len(conn.Sent()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "later", string(conn.LastSent().GetData()))
+}
@@ -0,0 +1,76 @@
+package ztimer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of time of the time wheels, of the connection timers and of the heartbeats of znet, a virtual
+// clock makes the tests of the timeouts deterministic, see ztest.VirtualClock
+// (时间轮、连接定时器与znet心跳的时间来源，虚拟时钟使超时相关的测试结果确定，见ztest.VirtualClock)
+type Clock interface {
+	Now() time.Time
+	// After returns a channel receiving the time once d has passed on the clock (返回在时钟经过d之后收到时间的通道)
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RealClock is the clock of the process, the default (进程的时钟，默认使用)
+var RealClock Clock = realClock{}
+
+// clockState is the current clock and the channel closed when it is replaced (当前时钟及其被替换时关闭的通道)
+type clockState struct {
+	clock   Clock
+	changed chan struct{}
+}
+
+var currentClock atomic.Value
+
+func init() {
+	currentClock.Store(&clockState{clock: RealClock, changed: make(chan struct{})})
+}
+
+// SetClock makes c the clock of zinx, nil restores RealClock, the sleeping wheels follow the new clock at once. It
+// is meant for the tests, set it before the servers start
+// (将c设置为zinx的时钟，为nil时恢复RealClock，休眠中的时间轮立即改用新时钟。用于测试，应在服务启动之前设置)
+func SetClock(c Clock) {
+	if c == nil {
+		c = RealClock
+	}
+	old := currentClock.Swap(&clockState{clock: c, changed: make(chan struct{})}).(*clockState)
+	close(old.changed)
+}
+
+// GetClock returns the clock of zinx (返回zinx的时钟)
+func GetClock() Clock {
+	return currentClock.Load().(*clockState).clock
+}
+
+// Now returns the current time on the clock of zinx (返回zinx时钟上的当前时间)
+func Now() time.Time {
+	return GetClock().Now()
+}
+
+// Sleep pauses the calling goroutine for d on the clock of zinx, it returns early when the clock is replaced
+// (在zinx的时钟上暂停当前协程d时长，时钟被替换时提前返回)
+func Sleep(d time.Duration) {
+	sleepUntil(d, nil)
+}
+
+// sleepUntil sleeps like Sleep, it returns false right away once done is closed
+// (与Sleep一样休眠，done关闭时立即返回false)
+func sleepUntil(d time.Duration, done <-chan struct{}) bool {
+	state := currentClock.Load().(*clockState)
+	select {
+	case <-state.clock.After(d):
+	case <-state.changed:
+	case <-done:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package ztimer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aceld/zinx/zmetrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniformLevels(t *testing.T) {
+	levels := UniformLevels(time.Millisecond, 1000, 3)
+	assert.Equal(t, []WheelLevel{
+		{Name: "LEVEL2", Interval: 1000 * time.Second, Scales: 1000},
+		{Name: "LEVEL1", Interval: time.Second, Scales: 1000},
+		{Name: "LEVEL0", Interval: time.Millisecond, Scales: 1000},
+	}, levels)
+}
+
+func TestSchedulerConfig(t *testing.T) {
+	assert.Panics(t, func() { NewTimerSchedulerWithConfig(SchedulerConfig{}) })
+	assert.Panics(t, func() {
+		NewTimerSchedulerWithConfig(SchedulerConfig{Levels: []WheelLevel{{Name: "US", Interval: time.Microsecond, Scales: 10}}})
+	})
+	assert.Panics(t, func() {
+		NewTimerSchedulerWithConfig(SchedulerConfig{Levels: []WheelLevel{
+			{Name: "MINUTE", Interval: time.Minute, Scales: 60},
+			{Name: "SECOND", Interval: time.Second, Scales: 30},
+		}})
+	})
+
+	zmetrics.Enable()
+	fired := zmetrics.TimerLateness.With().Count()
+	ts := NewAutoExecTimerSchedulerWithConfig(SchedulerConfig{Levels: UniformLevels(10*time.Millisecond, 100, 2)})
+	assert.Equal(t, 100*time.Second, ts.Span())
+	assert.Equal(t, time.Millisecond, ts.precision)
+
+	done := make(chan time.Time, 1)
+	start := time.Now()
+	ts.AfterFunc(30*time.Millisecond, func() { done <- time.Now() })
+	h := ts.AfterFunc(time.Minute, func() {})
+	assert.Equal(t, 2, ts.Len())
+	select {
+	case at := <-done:
+		assert.InDelta(t, 30*time.Millisecond, at.Sub(start), float64(30*time.Millisecond))
+	case <-time.After(time.Second):
+		t.Fatal("the timer did not fire")
+	}
+	h.Cancel()
+	assert.Equal(t, 0, ts.Len())
+	assert.True(t, zmetrics.TimerLateness.With().Count() > fired)
+}
+
+func TestSchedulerStop(t *testing.T) {
+	ts := NewAutoExecTimerSchedulerWithConfig(SchedulerConfig{Levels: UniformLevels(10*time.Millisecond, 100, 2)})
+	fired := make(chan struct{}, 1)
+	_, err := ts.CreateTimerAfter(NewDelayFunc(func(...interface{}) { fired <- struct{}{} }, nil), 30*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, ts.Len())
+
+	// The timers waiting never fire once stopped (停止后等待中的定时器不再触发)
+	ts.Stop()
+	ts.Stop()
+	assert.Equal(t, 0, ts.Len())
+	_, err = ts.CreateTimerAfter(NewDelayFunc(func(...interface{}) {}, nil), time.Millisecond)
+	assert.Equal(t, ErrSchedulerStopped, err)
+	select {
+	case <-fired:
+		t.Fatal("a timer fired after Stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,231 @@
+package ztimer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression (解析后的cron表达式)
+//
+// Standard 5 fields "minute hour day-of-month month day-of-week", or 6 fields with the seconds first,
+// every field accepts *, ?, lists "1,5", ranges "1-5", steps "*/10" or "10-40/5", the months and the days of the week
+// also accept their names "JAN" and "MON". The descriptors @yearly, @monthly, @weekly, @daily and @hourly are
+// accepted, and a leading "CRON_TZ=Asia/Shanghai " or "TZ=Asia/Shanghai " sets the time zone of the expression.
+// As in cron, when both the day of month and the day of week are restricted, a day matching either is selected.
+// (标准的5个字段"分 时 日 月 周"，或者秒在最前的6个字段，每个字段支持*、?、列表"1,5"、范围"1-5"、步长"*/10"或"10-40/5"，
+// 月份和星期也支持名称"JAN"和"MON"。支持@yearly、@monthly、@weekly、@daily和@hourly，
+// 以"CRON_TZ=Asia/Shanghai "或"TZ=Asia/Shanghai "开头可以设置表达式的时区。与cron相同，日和周都受限时，满足其一的日期即被选中)
+type CronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// domStar or dowStar are set when the field is * or ? (字段为*或?时设置)
+	domStar, dowStar bool
+
+	// Location is the time zone of the expression, nil uses the location of the times given to Next
+	// (表达式的时区，为nil时使用传入Next的时间的时区)
+	Location *time.Location
+}
+
+type cronField struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondField = cronField{name: "second", min: 0, max: 59}
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as Sunday, as in cron (与cron相同，7也表示周日)
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron parses a cron expression, see CronSchedule (解析cron表达式，见CronSchedule)
+func ParseCron(spec string) (*CronSchedule, error) {
+	schedule := &CronSchedule{}
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexByte(spec, ' ')
+		if i < 0 {
+			return nil, fmt.Errorf("ztimer: cron %q has no fields after the time zone", spec)
+		}
+		name := spec[strings.IndexByte(spec, '=')+1 : i]
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("ztimer: cron time zone %q: %v", name, err)
+		}
+		schedule.Location = loc
+		spec = strings.TrimSpace(spec[i:])
+	}
+	if strings.HasPrefix(spec, "@") {
+		fields, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("ztimer: unknown cron descriptor %q", spec)
+		}
+		spec = fields
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("ztimer: cron %q has %d fields, want 5 or 6", spec, len(fields))
+	}
+
+	var err error
+	if schedule.second, err = secondField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if schedule.minute, err = minuteField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = hourField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if schedule.dom, err = domField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = monthField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if schedule.dow, err = dowField.parse(fields[5]); err != nil {
+		return nil, err
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.domStar = fields[3] == "*" || fields[3] == "?"
+	schedule.dowStar = fields[5] == "*" || fields[5] == "?"
+	return schedule, nil
+}
+
+// parse returns the bitmask of the values selected by expr (返回expr所选值的位掩码)
+func (f *cronField) parse(expr string) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, step := part, uint(1)
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("ztimer: invalid step in cron %s %q", f.name, part)
+			}
+			rangeExpr, step = part[:i], uint(n)
+		}
+
+		var low, high uint
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+			low, high = f.min, f.max
+		case strings.Contains(rangeExpr, "-"):
+			i := strings.IndexByte(rangeExpr, '-')
+			var err error
+			if low, err = f.value(rangeExpr[:i]); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(rangeExpr[i+1:]); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("ztimer: empty range in cron %s %q", f.name, part)
+			}
+		default:
+			var err error
+			if low, err = f.value(rangeExpr); err != nil {
+				return 0, err
+			}
+			high = low
+			// "5/10" means from 5 to the end by 10 (表示从5开始到末尾，步长为10)
+			if step > 1 {
+				high = f.max
+			}
+		}
+		for v := low; v <= high; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+func (f *cronField) value(s string) (uint, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || uint(n) < f.min || uint(n) > f.max {
+		return 0, fmt.Errorf("ztimer: cron %s %q is not between %d and %d", f.name, s, f.min, f.max)
+	}
+	return uint(n), nil
+}
+
+// cronSearchYears bounds the search of Next for the expressions which never match, e.g. "0 0 30 2 *"
+// (限制Next的搜索范围，用于永远不匹配的表达式，例如"0 0 30 2 *")
+const cronSearchYears = 5
+
+// Next returns the first time matching the schedule after t, or the zero time when there is none within
+// five years (返回t之后第一个匹配的时间，五年内没有时返回零值)
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	origLoc := t.Location()
+	if s.Location != nil {
+		t = t.In(s.Location)
+	}
+	loc := t.Location()
+
+	// Start from the next whole second (从下一个整秒开始)
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Year() + cronSearchYears
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t.In(origLoc)
+	}
+	return time.Time{}
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
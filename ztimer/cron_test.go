@@ -0,0 +1,99 @@
+package ztimer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, spec := range []string{
+		"* * * * *",
+		"*/15 9-17 * * MON-FRI",
+		"0 30 2 1,15 jan,jul ?",
+		"5/10 * * * * *",
+		"@daily",
+		"CRON_TZ=Asia/Shanghai 0 8 * * *",
+	} {
+		_, err := ParseCron(spec)
+		assert.Nil(t, err, spec)
+	}
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@sometimes",
+		"TZ=Nowhere/Land * * * * *",
+	} {
+		_, err := ParseCron(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04:05", s)
+		assert.Nil(t, err)
+		return v
+	}
+	for _, c := range []struct {
+		spec, from, next string
+	}{
+		{"* * * * *", "2024-03-01 10:00:30", "2024-03-01 10:01:00"},
+		{"*/10 * * * * *", "2024-03-01 10:00:30", "2024-03-01 10:00:40"},
+		{"0 0 * * *", "2024-12-31 23:59:59", "2025-01-01 00:00:00"},
+		{"0 12 29 2 *", "2023-03-01 00:00:00", "2024-02-29 12:00:00"},
+		{"30 8 * * MON-FRI", "2024-03-01 09:00:00", "2024-03-04 08:30:00"},
+		// The day of month or the day of week (日或周满足其一)
+		{"0 0 13 * 5", "2024-03-01 00:00:00", "2024-03-08 00:00:00"},
+		{"0 0 * * 7", "2024-03-01 00:00:00", "2024-03-03 00:00:00"},
+		{"@monthly", "2024-01-31 12:00:00", "2024-02-01 00:00:00"},
+	} {
+		schedule, err := ParseCron(c.spec)
+		assert.Nil(t, err)
+		assert.Equal(t, utc(c.next), schedule.Next(utc(c.from)), c.spec)
+	}
+
+	never, _ := ParseCron("0 0 30 2 *")
+	assert.True(t, never.Next(time.Now()).IsZero())
+
+	// 08:00 in Shanghai is 00:00 UTC (上海时间8点为UTC 0点)
+	shanghai, _ := ParseCron("CRON_TZ=Asia/Shanghai 0 8 * * *")
+	assert.Equal(t, utc("2024-03-02 00:00:00"), shanghai.Next(utc("2024-03-01 01:00:00")))
+	assert.Equal(t, time.UTC, shanghai.Next(utc("2024-03-01 01:00:00")).Location())
+}
+
+func TestCronScheduler(t *testing.T) {
+	c := NewCronScheduler(nil, nil)
+	defer c.Stop()
+
+	var runs int32
+	assert.Nil(t, c.AddJob("tick", "* * * * * *", func() { atomic.AddInt32(&runs, 1) }))
+	assert.Nil(t, c.AddJob("boom", "* * * * * *", func() { panic("boom") }))
+	assert.ErrorIs(t, c.AddJob("tick", "* * * * * *", func() {}), ErrCronJobExists)
+	assert.NotNil(t, c.AddJob("bad", "* *", func() {}))
+	assert.ErrorIs(t, c.Pause("missing"), ErrCronJobNotFound)
+
+	time.Sleep(2500 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&runs) >= 1)
+
+	jobs := c.Jobs()
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, "boom", jobs[0].Name)
+	assert.True(t, jobs[0].Panics >= 1)
+	assert.False(t, jobs[1].Next.IsZero())
+
+	assert.Nil(t, c.Pause("tick"))
+	paused := atomic.LoadInt32(&runs)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, paused, atomic.LoadInt32(&runs))
+	assert.True(t, c.Jobs()[1].Paused)
+
+	assert.Nil(t, c.Resume("tick"))
+	assert.Nil(t, c.RemoveJob("boom"))
+	assert.Len(t, c.Jobs(), 1)
+}
@@ -0,0 +1,262 @@
+package ztimer
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// cronMaxWait bounds the timers of the jobs, to half the span of the wheels when it is shorter, a job due later
+// is looked at again after it (限制任务定时器的时长，时间轮跨度的一半更短时取其一半，更晚到期的任务在此之后重新检查)
+const cronMaxWait = time.Hour
+
+var (
+	// ErrCronJobExists is returned when a job of the same name is already added (同名任务已存在时返回)
+	ErrCronJobExists = errors.New("ztimer: cron job already exists")
+	// ErrCronJobNotFound is returned for an unknown job name (任务名称不存在时返回)
+	ErrCronJobNotFound = errors.New("ztimer: cron job not found")
+)
+
+// CronJobInfo describes a job of a CronScheduler (描述CronScheduler中的一个任务)
+type CronJobInfo struct {
+	Name    string
+	Spec    string
+	Paused  bool
+	Next    time.Time // Zero when paused or never due (暂停或永远不会到期时为零值)
+	Prev    time.Time // The last run, zero before the first (上一次运行的时间，第一次运行前为零值)
+	Runs    uint64    // Runs started (已开始的运行次数)
+	Skipped uint64    // Runs skipped because the previous one was still running (因上一次仍在运行而跳过的次数)
+	Panics  uint64    // Runs which panicked (发生panic的运行次数)
+}
+
+type cronJob struct {
+	name     string
+	spec     string
+	schedule *CronSchedule
+	f        func()
+
+	// The fields below are guarded by the mutex of the scheduler (以下字段由调度器的互斥锁保护)
+	paused bool
+	next   time.Time
+	prev   time.Time
+	tID    uint32
+	// gen invalidates the timers of a removed, paused or rescheduled job (使被移除、暂停或重新调度的任务的定时器失效)
+	gen uint64
+
+	running int32
+	runs    uint64
+	skipped uint64
+	panics  uint64
+}
+
+// CronScheduler runs named jobs on cron expressions, on top of a TimerScheduler.
+// A panicking job is logged with its stack and runs again at its next time, and a run is skipped while the
+// previous run of the same job is still going, so a slow maintenance task never piles up
+// (基于TimerScheduler按cron表达式运行命名任务。发生panic的任务会连同调用栈记录日志，并在下次照常运行；
+// 同一任务的上一次运行尚未结束时跳过本次运行，慢的维护任务不会堆积)
+type CronScheduler struct {
+	ts  *TimerScheduler
+	loc *time.Location
+
+	mu   sync.Mutex
+	jobs map[string]*cronJob
+}
+
+// NewCronScheduler returns a CronScheduler scheduling on ts, a new auto executing TimerScheduler when ts is nil.
+// The expressions without a time zone use loc, time.Local when loc is nil
+// (返回在ts上调度的CronScheduler，ts为nil时新建一个自动执行的TimerScheduler，没有时区的表达式使用loc，loc为nil时使用time.Local)
+func NewCronScheduler(ts *TimerScheduler, loc *time.Location) *CronScheduler {
+	if ts == nil {
+		ts = NewAutoExecTimerScheduler()
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	return &CronScheduler{ts: ts, loc: loc, jobs: make(map[string]*cronJob)}
+}
+
+// AddJob runs f on the cron expression spec under name, see ParseCron (以name为名按cron表达式spec运行f，见ParseCron)
+func (c *CronScheduler) AddJob(name, spec string, f func()) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	if schedule.Location == nil {
+		schedule.Location = c.loc
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrCronJobExists, name)
+	}
+	job := &cronJob{name: name, spec: spec, schedule: schedule, f: f}
+	c.jobs[name] = job
+	c.schedule(job, Now())
+	return nil
+}
+
+// RemoveJob removes the job name, a run already started is not interrupted (移除任务name，已开始的运行不会被中断)
+func (c *CronScheduler) RemoveJob(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	job, ok := c.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCronJobNotFound, name)
+	}
+	c.unschedule(job)
+	delete(c.jobs, name)
+	return nil
+}
+
+// Pause stops running the job name until Resume (暂停运行任务name，直到调用Resume)
+func (c *CronScheduler) Pause(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	job, ok := c.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCronJobNotFound, name)
+	}
+	if !job.paused {
+		job.paused = true
+		c.unschedule(job)
+	}
+	return nil
+}
+
+// Resume runs the paused job name again from its next time, the times missed while paused are not caught up
+// (从下一个时间起恢复运行被暂停的任务name，暂停期间错过的时间不会补跑)
+func (c *CronScheduler) Resume(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	job, ok := c.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCronJobNotFound, name)
+	}
+	if job.paused {
+		job.paused = false
+		c.schedule(job, Now())
+	}
+	return nil
+}
+
+// Jobs describes the jobs, by name (按名称描述所有任务)
+func (c *CronScheduler) Jobs() []CronJobInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	infos := make([]CronJobInfo, 0, len(c.jobs))
+	for _, job := range c.jobs {
+		infos = append(infos, CronJobInfo{
+			Name:    job.name,
+			Spec:    job.spec,
+			Paused:  job.paused,
+			Next:    job.next,
+			Prev:    job.prev,
+			Runs:    atomic.LoadUint64(&job.runs),
+			Skipped: atomic.LoadUint64(&job.skipped),
+			Panics:  atomic.LoadUint64(&job.panics),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Stop removes every job (移除所有任务)
+func (c *CronScheduler) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, job := range c.jobs {
+		c.unschedule(job)
+		delete(c.jobs, name)
+	}
+}
+
+// schedule sets the timer of the next run of job after now, c.mu is held (设置任务在now之后下一次运行的定时器，调用时持有c.mu)
+func (c *CronScheduler) schedule(job *cronJob, now time.Time) {
+	job.next = job.schedule.Next(now)
+	if job.next.IsZero() {
+		job.gen++
+		zlog.Ins().ErrorF("ztimer: cron job %s (%s) is never due", job.name, job.spec)
+		return
+	}
+	c.arm(job, Now())
+}
+
+// arm sets the timer of job for job.next, at most cronMaxWait after now, c.mu is held
+// (为任务设置job.next的定时器，最多在now之后cronMaxWait，调用时持有c.mu)
+func (c *CronScheduler) arm(job *cronJob, now time.Time) {
+	job.gen++
+	at, maxWait := job.next, cronMaxWait
+	if half := c.ts.Span() / 2; half < maxWait {
+		maxWait = half
+	}
+	if at.Sub(now) > maxWait {
+		at = now.Add(maxWait)
+	}
+	gen := job.gen
+	df := NewDelayFunc(func(...interface{}) {
+		c.fire(job, gen)
+	}, nil)
+	tID, err := c.ts.CreateTimerAt(df, at.UnixNano())
+	if err != nil {
+		zlog.Ins().ErrorF("ztimer: schedule cron job %s err: %v", job.name, err)
+		return
+	}
+	job.tID = tID
+}
+
+// unschedule cancels the timer of job, c.mu is held (取消任务的定时器，调用时持有c.mu)
+func (c *CronScheduler) unschedule(job *cronJob) {
+	job.gen++
+	job.next = time.Time{}
+	c.ts.CancelTimer(job.tID)
+}
+
+// fire runs job when it is due, or waits again when its timer was cut short by cronMaxWait, the scheduler
+// fires the timers up to its precision early (任务到期时运行任务，定时器因cronMaxWait提前触发时继续等待，调度器最多提前其精度触发定时器)
+func (c *CronScheduler) fire(job *cronJob, gen uint64) {
+	c.mu.Lock()
+	if job.gen != gen || job.paused {
+		c.mu.Unlock()
+		return
+	}
+	now := Now()
+	if job.next.Sub(now) > c.ts.precision {
+		c.arm(job, now)
+		c.mu.Unlock()
+		return
+	}
+	job.prev = job.next
+	// The times missed while the process was stalled are not caught up (进程停顿期间错过的时间不会补跑)
+	after := job.next
+	if now.After(after) {
+		after = now
+	}
+	c.schedule(job, after)
+	c.mu.Unlock()
+
+	c.run(job)
+}
+
+func (c *CronScheduler) run(job *cronJob) {
+	if !atomic.CompareAndSwapInt32(&job.running, 0, 1) {
+		atomic.AddUint64(&job.skipped, 1)
+		zlog.Ins().ErrorF("ztimer: cron job %s skipped, the previous run is still going", job.name)
+		return
+	}
+	defer atomic.StoreInt32(&job.running, 0)
+	atomic.AddUint64(&job.runs, 1)
+	defer func() {
+		if err := recover(); err != nil {
+			atomic.AddUint64(&job.panics, 1)
+			zlog.Ins().ErrorF("ztimer: cron job %s panic: %v\n%s", job.name, err, debug.Stack())
+		}
+	}()
+	job.f()
+}
@@ -0,0 +1,126 @@
+package ztimer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+const (
+	handlePending = iota
+	handleFired
+	handleCanceled
+)
+
+// TimerHandle controls a callback scheduled by AfterFunc, AtFunc or AfterFuncContext.
+// Once Cancel returns, the callback does not start any more, even when its timer was already taken out of the
+// wheel, a callback already started keeps running
+// (控制通过AfterFunc、AtFunc或AfterFuncContext调度的回调。Cancel返回后回调不会再开始执行，即使其定时器已经从时间轮中取出，已经开始的回调会继续运行)
+type TimerHandle struct {
+	ts  *TimerScheduler
+	f   func()
+	ctx context.Context
+
+	mu    sync.Mutex
+	state int
+	tID   uint32
+	// gen invalidates the timers replaced by Reset (使被Reset替换的定时器失效)
+	gen uint64
+}
+
+// AfterFunc calls f once d has elapsed (在d之后调用f)
+func (ts *TimerScheduler) AfterFunc(d time.Duration, f func()) *TimerHandle {
+	return ts.AfterFuncContext(context.Background(), d, f)
+}
+
+// AtFunc calls f at the time at (在at时刻调用f)
+func (ts *TimerScheduler) AtFunc(at time.Time, f func()) *TimerHandle {
+	return ts.AfterFunc(at.Sub(Now()), f)
+}
+
+// AfterFuncContext calls f once d has elapsed unless ctx is done first, the timer is canceled when ctx is done,
+// e.g. with the context of a connection its timers are torn down on close
+// (在d之后调用f，除非ctx先结束，ctx结束时取消定时器，例如使用连接的context时，连接关闭会清理其定时器)
+func (ts *TimerScheduler) AfterFuncContext(ctx context.Context, d time.Duration, f func()) *TimerHandle {
+	h := &TimerHandle{ts: ts, f: f, ctx: ctx}
+	if ctx.Err() != nil {
+		h.state = handleCanceled
+		return h
+	}
+	h.mu.Lock()
+	h.arm(d)
+	h.mu.Unlock()
+	if done := ctx.Done(); done != nil {
+		go func() {
+			<-done
+			h.Cancel()
+		}()
+	}
+	return h
+}
+
+// arm schedules the callback after d, h.mu is held (在d之后调度回调，调用时持有h.mu)
+func (h *TimerHandle) arm(d time.Duration) {
+	h.gen++
+	h.state = handlePending
+	gen := h.gen
+	df := NewDelayFunc(func(...interface{}) {
+		h.fire(gen)
+	}, nil)
+	tID, err := h.ts.CreateTimerAfter(df, d)
+	if err != nil {
+		zlog.Ins().ErrorF("ztimer: schedule timer err: %v", err)
+	}
+	h.tID = tID
+}
+
+func (h *TimerHandle) fire(gen uint64) {
+	h.mu.Lock()
+	if h.state != handlePending || h.gen != gen {
+		h.mu.Unlock()
+		return
+	}
+	h.state = handleFired
+	h.mu.Unlock()
+	h.f()
+}
+
+// Cancel stops the callback from starting, it returns false when the callback already started or the timer
+// was already canceled (阻止回调开始执行，回调已经开始或定时器已被取消时返回false)
+func (h *TimerHandle) Cancel() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state != handlePending {
+		return false
+	}
+	h.state = handleCanceled
+	h.ts.CancelTimer(h.tID)
+	return true
+}
+
+// Reset schedules the callback again after d, whether it fired, was canceled or is pending, it returns whether
+// the timer was pending. A timer whose context is done is not scheduled again
+// (在d之后重新调度回调，无论其已触发、已取消还是等待中，返回定时器是否处于等待中。context已结束的定时器不会被重新调度)
+func (h *TimerHandle) Reset(d time.Duration) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pending := h.state == handlePending
+	if pending {
+		h.ts.CancelTimer(h.tID)
+	}
+	if h.ctx.Err() != nil {
+		h.state = handleCanceled
+		return pending
+	}
+	h.arm(d)
+	return pending
+}
+
+// Pending reports whether the callback is still to be called (返回回调是否仍在等待调用)
+func (h *TimerHandle) Pending() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.state == handlePending
+}
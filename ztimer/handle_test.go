@@ -0,0 +1,81 @@
+package ztimer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimerHandle(t *testing.T) {
+	ts := NewAutoExecTimerScheduler()
+
+	fired := make(chan struct{}, 10)
+	h := ts.AfterFunc(50*time.Millisecond, func() { fired <- struct{}{} })
+	assert.True(t, h.Pending())
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("the timer did not fire")
+	}
+	assert.False(t, h.Pending())
+	assert.False(t, h.Cancel())
+
+	// Reset after firing schedules it again (触发后Reset会重新调度)
+	assert.False(t, h.Reset(50*time.Millisecond))
+	assert.True(t, h.Cancel())
+	assert.False(t, h.Cancel())
+	time.Sleep(300 * time.Millisecond)
+	assert.Empty(t, fired)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h = ts.AfterFuncContext(ctx, 200*time.Millisecond, func() { fired <- struct{}{} })
+	cancel()
+	time.Sleep(400 * time.Millisecond)
+	assert.Empty(t, fired)
+	assert.False(t, h.Pending())
+	assert.False(t, h.Reset(10*time.Millisecond))
+	assert.False(t, h.Pending())
+	assert.False(t, ts.AfterFuncContext(ctx, time.Millisecond, func() {}).Pending())
+}
+
+// A timer already taken out of the wheel does not call back once canceled (已从时间轮取出的定时器被取消后不会回调)
+func TestTimerHandleCancelAfterTrigger(t *testing.T) {
+	ts := NewTimerScheduler()
+	ts.Start()
+
+	var calls int32
+	h := ts.AfterFunc(10*time.Millisecond, func() { atomic.AddInt32(&calls, 1) })
+	df := <-ts.GetTriggerChan()
+	assert.True(t, h.Cancel())
+	df.Call()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+	// A timer replaced by Reset does not call back either (被Reset替换的定时器同样不会回调)
+	h.Reset(10 * time.Millisecond)
+	df = <-ts.GetTriggerChan()
+	h.Reset(time.Hour)
+	df.Call()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	h.Cancel()
+}
+
+func TestTickerHandle(t *testing.T) {
+	ts := NewAutoExecTimerScheduler()
+
+	var ticks int32
+	ticker := ts.TickFunc(30*time.Millisecond, func() { atomic.AddInt32(&ticks, 1) })
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&ticks) >= 3 }, 3*time.Second, 10*time.Millisecond)
+	assert.True(t, ticker.Cancel())
+	assert.False(t, ticker.Cancel())
+	stopped := atomic.LoadInt32(&ticks)
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, stopped, atomic.LoadInt32(&ticks))
+
+	assert.False(t, ticker.Reset(30*time.Millisecond))
+	assert.True(t, ticker.Pending())
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&ticks) > stopped }, 3*time.Second, 10*time.Millisecond)
+	ticker.Cancel()
+}
@@ -0,0 +1,40 @@
+package ztimer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stepWallClock makes the wall clock jump by d until the returned function is called
+// (使墙上时钟跳变d，直到调用返回的函数)
+func stepWallClock(d time.Duration) (restore func()) {
+	wallNow = func() time.Time { return time.Now().Add(d) }
+	return func() { wallNow = Now }
+}
+
+func TestWallClockJump(t *testing.T) {
+	ts := NewAutoExecTimerScheduler()
+	for _, step := range []time.Duration{10 * time.Minute, -10 * time.Minute} {
+		var fired int32
+		count := func(...interface{}) { atomic.AddInt32(&fired, 1) }
+		for i := 0; i < 20; i++ {
+			_, err := ts.CreateTimerAfter(NewDelayFunc(count, nil), 500*time.Millisecond)
+			assert.Nil(t, err)
+		}
+
+		restore := stepWallClock(step)
+		// A time read after the step is kept as is (跳变后读取的时间保持不变)
+		_, err := ts.CreateTimerAt(NewDelayFunc(count, nil), wallNow().Add(500*time.Millisecond).UnixNano())
+		assert.Nil(t, err)
+
+		// Neither a storm of timers firing at once, nor timers stalled for minutes (既没有定时器同时触发，也没有定时器停滞数分钟)
+		time.Sleep(200 * time.Millisecond)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&fired), step)
+		time.Sleep(1500 * time.Millisecond)
+		assert.Equal(t, int32(21), atomic.LoadInt32(&fired), step)
+		restore()
+	}
+}
@@ -0,0 +1,73 @@
+package ztimer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TickerHandle controls a callback called every d by TickFunc or TickFuncContext, the timer is armed again
+// once the callback returns so the calls never overlap, no call starts once Cancel returns
+// (控制通过TickFunc或TickFuncContext每隔d调用的回调，回调返回后才重新设置定时器，因此调用不会重叠，Cancel返回后不会再有调用开始)
+type TickerHandle struct {
+	h *TimerHandle
+	f func()
+
+	mu      sync.Mutex
+	period  time.Duration
+	stopped bool
+}
+
+// TickFunc calls f every d until canceled (每隔d调用f直到被取消)
+func (ts *TimerScheduler) TickFunc(d time.Duration, f func()) *TickerHandle {
+	return ts.TickFuncContext(context.Background(), d, f)
+}
+
+// TickFuncContext calls f every d until canceled or ctx is done (每隔d调用f直到被取消或ctx结束)
+func (ts *TimerScheduler) TickFuncContext(ctx context.Context, d time.Duration, f func()) *TickerHandle {
+	t := &TickerHandle{f: f, period: d}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.h = ts.AfterFuncContext(ctx, d, t.tick)
+	return t
+}
+
+func (t *TickerHandle) tick() {
+	defer func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !t.stopped {
+			t.h.Reset(t.period)
+		}
+	}()
+	t.f()
+}
+
+// Cancel stops the ticker, it returns false when it was already stopped (停止Ticker，已停止时返回false)
+func (t *TickerHandle) Cancel() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := !t.stopped && t.h.Pending()
+	t.stopped = true
+	t.h.Cancel()
+	return pending
+}
+
+// Reset ticks every d from now on, a stopped ticker is started again unless its context is done, it returns
+// whether the ticker was pending (从现在起每隔d触发，已停止的Ticker会被重新启动，除非其context已结束，返回Ticker是否在等待中)
+func (t *TickerHandle) Reset(d time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := !t.stopped && t.h.Pending()
+	t.period = d
+	t.stopped = false
+	t.h.Reset(d)
+	return pending
+}
+
+// Pending reports whether the ticker is still to call back (返回Ticker是否仍会回调)
+func (t *TickerHandle) Pending() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.stopped && t.h.Pending()
+}
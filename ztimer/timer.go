@@ -49,36 +49,57 @@ const (
 type Timer struct {
 	//延迟调用函数
 	delayFunc *DelayFunc
-	//调用时间(unix 时间， 单位ms)
-	unixts int64
+	//调用时间(单调时钟，单位ms，见monoMilli)
+	deadline int64
 }
 
 // UnixMilli 返回1970-1-1至今经历的毫秒数
 func UnixMilli() int64 {
-	return time.Now().UnixNano() / 1e6
+	return Now().UnixNano() / 1e6
+}
+
+// The wheels run on the monotonic clock, so that NTP corrections, suspend/resume or a VM migration stepping the
+// wall clock neither fire every timer at once nor stall them, only NewTimerAt reads the wall clock, once
+// (时间轮基于单调时钟运行，NTP校正、挂起恢复或虚拟机迁移导致的墙上时钟跳变既不会使所有定时器同时触发，也不会使其停滞，只有NewTimerAt会读取一次墙上时钟)
+var (
+	monoStart = time.Now()
+	// wallNow reads the wall clock, the tests step it (读取墙上时钟，测试中会使其跳变)
+	wallNow = Now
+)
+
+// monoMilli 返回单调时钟上自monoStart以来的毫秒数，不受墙上时钟跳变的影响
+// (returns the milliseconds since monoStart on the monotonic clock, unaffected by the wall clock steps)
+func monoMilli() int64 {
+	return int64(Now().Sub(monoStart) / time.Millisecond)
 }
 
 // NewTimerAt   创建一个定时器,在指定的时间触发 定时器方法 df: DelayFunc类型的延迟调用函数类型；unixNano: unix计算机从1970-1-1至今经历的纳秒数
+// 创建时换算为单调时钟，之后墙上时钟的跳变不会改变触发时刻
+// (the time is converted to the monotonic clock on creation, later wall clock steps don't move it)
 func NewTimerAt(df *DelayFunc, unixNano int64) *Timer {
-	return &Timer{
-		delayFunc: df,
-		unixts:    unixNano / 1e6, //将纳秒转换成对应的毫秒 ms ，定时器以ms为最小精度
-	}
+	return newTimer(df, time.Duration(unixNano-wallNow().UnixNano()))
 }
 
 // NewTimerAfter 创建一个定时器，在当前时间延迟duration之后触发 定时器方法
 func NewTimerAfter(df *DelayFunc, duration time.Duration) *Timer {
-	return NewTimerAt(df, time.Now().UnixNano()+int64(duration))
+	return newTimer(df, duration)
+}
+
+func newTimer(df *DelayFunc, duration time.Duration) *Timer {
+	return &Timer{
+		delayFunc: df,
+		deadline:  monoMilli() + int64(duration/time.Millisecond), //定时器以ms为最小精度
+	}
 }
 
 // Run 启动定时器，用一个go承载
 func (t *Timer) Run() {
 	go func() {
-		now := UnixMilli()
+		now := monoMilli()
 		//设置的定时器是否在当前时间之后
-		if t.unixts > now {
-			//睡眠，直至时间超时,已微秒为单位进行睡眠
-			time.Sleep(time.Duration(t.unixts-now) * time.Millisecond)
+		if t.deadline > now {
+			//睡眠，直至时间超时,已毫秒为单位进行睡眠
+			time.Sleep(time.Duration(t.deadline-now) * time.Millisecond)
 		}
 
 		//调用事先注册好的超时延迟方法
@@ -10,11 +10,15 @@ package ztimer
  */
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aceld/zinx/zlog"
+	"github.com/aceld/zinx/zmetrics"
 )
 
 const (
@@ -34,39 +38,180 @@ type TimerScheduler struct {
 	triggerChan chan *DelayFunc
 	//互斥锁
 	sync.RWMutex
+
+	//取出定时器的提前量，以及调度循环的间隔的两倍 (how early the timers are taken out, twice the period of the scheduling loop)
+	precision time.Duration
+	//时间轮的总跨度，更晚的定时器会绕回 (the span of the wheels, later timers wrap around)
+	span time.Duration
+	//等待中的定时器个数 (timers waiting in the wheels)
+	pending int64
+	//停止调度的信号，见Stop (closed to stop scheduling, see Stop)
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+// ErrSchedulerStopped 调度器已停止时创建定时器返回的错误 (returned for the timers created on a stopped scheduler)
+var ErrSchedulerStopped = errors.New("ztimer: the scheduler is stopped")
+
+// pendingTimers counts the timers waiting in every scheduler, for zmetrics.Timers (所有调度器中等待的定时器个数，用于zmetrics.Timers)
+var pendingTimers int64
+
+func init() {
+	zmetrics.Default.OnCollect(func() {
+		zmetrics.Timers.Set(float64(atomic.LoadInt64(&pendingTimers)))
+	})
+}
+
+// WheelLevel 分层时间轮中的一层 (a level of the hierarchical wheels)
+type WheelLevel struct {
+	Name     string
+	Interval time.Duration // 刻度的时间间隔，至少1ms (the time between two ticks, at least 1ms)
+	Scales   int           // 刻度数 (the number of ticks)
+}
+
+// SchedulerConfig 配置调度器的时间轮 (configures the wheels of a scheduler)
+type SchedulerConfig struct {
+	// 从最粗到最细的各层时间轮，每层的Interval等于下一层的Interval乘以其Scales
+	// (the levels from the coarsest to the finest, the Interval of a level is the Interval times the Scales of the next one)
+	Levels []WheelLevel
+	// 每个刻度预分配的定时器容量，默认TimersMaxCap (the timers preallocated per tick, TimersMaxCap by default)
+	SlotCap int
+	// 定时器最多提前触发的时间，默认为最细刻度的十分之一且不少于1ms
+	// (how early at most the timers fire, a tenth of the finest tick and at least 1ms by default)
+	Precision time.Duration
+	// 已触发定时器通道的缓冲，默认MaxChanBuff (the buffer of the trigger channel, MaxChanBuff by default)
+	TriggerBuffer int
+}
+
+// DefaultSchedulerConfig 秒、分钟、小时三层时间轮，跨度12小时，精度100ms
+// (the second, minute and hour wheels, spanning 12 hours with a precision of 100ms)
+func DefaultSchedulerConfig() SchedulerConfig {
+	return SchedulerConfig{
+		Levels: []WheelLevel{
+			{Name: HourName, Interval: HourInterval * time.Millisecond, Scales: HourScales},
+			{Name: MinuteName, Interval: MinuteInterval * time.Millisecond, Scales: MinuteScales},
+			{Name: SecondName, Interval: SecondInterval * time.Millisecond, Scales: SecondScales},
+		},
+		Precision: MaxTimeDelay * time.Millisecond,
+	}
+}
+
+// UniformLevels 返回levels层刻度为tick、每层scales个刻度的时间轮，例如游戏循环使用UniformLevels(time.Millisecond, 1000, 3)，
+// 设备集群使用UniformLevels(time.Second, 60, 3)
+// (returns levels wheels of scales ticks each, the finest ticking every tick, e.g. UniformLevels(time.Millisecond, 1000, 3)
+// for game loops, UniformLevels(time.Second, 60, 3) for device fleets)
+func UniformLevels(tick time.Duration, scales, levels int) []WheelLevel {
+	list := make([]WheelLevel, levels)
+	interval := tick
+	for i := levels - 1; i >= 0; i-- {
+		list[i] = WheelLevel{Name: fmt.Sprintf("LEVEL%d", levels-1-i), Interval: interval, Scales: scales}
+		interval *= time.Duration(scales)
+	}
+	return list
+}
+
+// validate 检查配置并填充默认值，配置无效时panic (checks the config and fills the defaults, it panics on an invalid config)
+func (c *SchedulerConfig) validate() {
+	if len(c.Levels) == 0 {
+		panic("ztimer: a scheduler needs at least one wheel level")
+	}
+	for i, level := range c.Levels {
+		if level.Interval < time.Millisecond || level.Interval%time.Millisecond != 0 {
+			panic(fmt.Sprintf("ztimer: the interval %v of wheel %s must be a whole number of milliseconds", level.Interval, level.Name))
+		}
+		if level.Scales < 1 {
+			panic(fmt.Sprintf("ztimer: wheel %s needs at least one scale, got %d", level.Name, level.Scales))
+		}
+		if i+1 < len(c.Levels) {
+			next := c.Levels[i+1]
+			if level.Interval != next.Interval*time.Duration(next.Scales) {
+				panic(fmt.Sprintf("ztimer: the interval %v of wheel %s must be the %d scales of %v of wheel %s",
+					level.Interval, level.Name, next.Scales, next.Interval, next.Name))
+			}
+		}
+	}
+	if c.SlotCap <= 0 {
+		c.SlotCap = TimersMaxCap
+	}
+	if c.Precision <= 0 {
+		c.Precision = c.Levels[len(c.Levels)-1].Interval / 10
+		if c.Precision < time.Millisecond {
+			c.Precision = time.Millisecond
+		}
+	}
+	if c.TriggerBuffer <= 0 {
+		c.TriggerBuffer = MaxChanBuff
+	}
 }
 
 // NewTimerScheduler 返回一个定时器调度器 ，主要创建分层定时器，并做关联，并依次启动
 func NewTimerScheduler() *TimerScheduler {
+	return NewTimerSchedulerWithConfig(DefaultSchedulerConfig())
+}
 
-	//创建秒级时间轮
-	secondTw := NewTimeWheel(SecondName, SecondInterval, SecondScales, TimersMaxCap)
-	//创建分钟级时间轮
-	minuteTw := NewTimeWheel(MinuteName, MinuteInterval, MinuteScales, TimersMaxCap)
-	//创建小时级时间轮
-	hourTw := NewTimeWheel(HourName, HourInterval, HourScales, TimersMaxCap)
+// NewTimerSchedulerWithConfig 按config创建分层时间轮的定时器调度器，配置无效时panic
+// (returns a scheduler with the wheels of config, it panics on an invalid config)
+func NewTimerSchedulerWithConfig(config SchedulerConfig) *TimerScheduler {
+	config.validate()
 
-	//将分层时间轮做关联
-	hourTw.AddTimeWheel(minuteTw)
-	minuteTw.AddTimeWheel(secondTw)
+	var top, prev *TimeWheel
+	wheels := make([]*TimeWheel, 0, len(config.Levels))
+	for _, level := range config.Levels {
+		tw := NewTimeWheel(level.Name, int64(level.Interval/time.Millisecond), level.Scales, config.SlotCap)
+		//将分层时间轮做关联
+		if prev == nil {
+			top = tw
+		} else {
+			prev.AddTimeWheel(tw)
+		}
+		prev = tw
+		wheels = append(wheels, tw)
+	}
 
-	//时间轮运行
-	secondTw.Run()
-	minuteTw.Run()
-	hourTw.Run()
+	//时间轮运行，从最细的一层开始
+	for i := len(wheels) - 1; i >= 0; i-- {
+		wheels[i].Run()
+	}
 
 	return &TimerScheduler{
-		tw:          hourTw,
-		triggerChan: make(chan *DelayFunc, MaxChanBuff),
+		tw:          top,
+		triggerChan: make(chan *DelayFunc, config.TriggerBuffer),
+		precision:   config.Precision,
+		span:        config.Levels[0].Interval * time.Duration(config.Levels[0].Scales),
+		quit:        make(chan struct{}),
 	}
 }
 
+// Span 返回时间轮的总跨度，更晚的定时器会提前触发 (returns the span of the wheels, later timers fire early)
+func (ts *TimerScheduler) Span() time.Duration {
+	return ts.span
+}
+
+// Len 返回等待中的定时器个数 (returns the number of timers waiting)
+func (ts *TimerScheduler) Len() int {
+	return int(atomic.LoadInt64(&ts.pending))
+}
+
+func (ts *TimerScheduler) added() {
+	atomic.AddInt64(&ts.pending, 1)
+	atomic.AddInt64(&pendingTimers, 1)
+}
+
+func (ts *TimerScheduler) removed() {
+	atomic.AddInt64(&ts.pending, -1)
+	atomic.AddInt64(&pendingTimers, -1)
+}
+
 // CreateTimerAt 创建一个定点Timer 并将Timer添加到分层时间轮中， 返回Timer的tID
 func (ts *TimerScheduler) CreateTimerAt(df *DelayFunc, unixNano int64) (uint32, error) {
 	ts.Lock()
 	defer ts.Unlock()
+	if ts.stopped() {
+		return 0, ErrSchedulerStopped
+	}
 
 	ts.IDGen++
+	ts.added()
 	return ts.IDGen, ts.tw.AddTimer(ts.IDGen, NewTimerAt(df, unixNano))
 }
 
@@ -74,8 +219,12 @@ func (ts *TimerScheduler) CreateTimerAt(df *DelayFunc, unixNano int64) (uint32,
 func (ts *TimerScheduler) CreateTimerAfter(df *DelayFunc, duration time.Duration) (uint32, error) {
 	ts.Lock()
 	defer ts.Unlock()
+	if ts.stopped() {
+		return 0, ErrSchedulerStopped
+	}
 
 	ts.IDGen++
+	ts.added()
 	return ts.IDGen, ts.tw.AddTimer(ts.IDGen, NewTimerAfter(df, duration))
 }
 
@@ -83,10 +232,15 @@ func (ts *TimerScheduler) CreateTimerAfter(df *DelayFunc, duration time.Duration
 func (ts *TimerScheduler) CancelTimer(tID uint32) {
 	ts.Lock()
 	defer ts.Unlock()
+	if ts.stopped() {
+		return
+	}
 
 	tw := ts.tw
 	for tw != nil {
-		tw.RemoveTimer(tID)
+		if tw.removeTimer(tID) {
+			ts.removed()
+		}
 		tw = tw.nextTimeWheel
 	}
 }
@@ -101,33 +255,89 @@ func (ts *TimerScheduler) Start() {
 	go func() {
 		for {
 			//当前时间
-			now := UnixMilli()
-			//获取最近MaxTimeDelay 毫秒的超时定时器集合
-			timerList := ts.tw.GetTimerWithIn(MaxTimeDelay * time.Millisecond)
+			now := monoMilli()
+			precision := int64(ts.precision / time.Millisecond)
+			//获取最近precision毫秒的超时定时器集合
+			timerList := ts.tw.GetTimerWithIn(ts.precision)
 			for _, timer := range timerList {
-				if math.Abs(float64(now-timer.unixts)) > MaxTimeDelay {
+				ts.removed()
+				late := now - timer.deadline
+				if math.Abs(float64(late)) > float64(precision) {
 					//已经超时的定时器，报警
-					zlog.Error("want call at ", timer.unixts, "; real call at", now, "; delay ", now-timer.unixts)
+					zlog.Ins().ErrorF("want call at %d; real call at %d; delay %d", timer.deadline, now, late)
+				}
+				if zmetrics.Enabled() {
+					zmetrics.TimerLateness.With().Observe((time.Duration(math.Max(0, float64(late))) * time.Millisecond).Seconds())
+				}
+				select {
+				case ts.triggerChan <- timer.delayFunc:
+				case <-ts.quit:
+					return
 				}
-				ts.triggerChan <- timer.delayFunc
 			}
-			time.Sleep(MaxTimeDelay / 2 * time.Millisecond)
+			if !sleepUntil(ts.precision/2, ts.quit) {
+				return
+			}
 		}
 	}()
 }
 
+// Stop 停止调度并停止各层时间轮，等待中的定时器不再触发，可以重复调用
+// (stops scheduling and the wheels, the timers waiting never fire, it may be called more than once)
+func (ts *TimerScheduler) Stop() {
+	ts.stopOnce.Do(func() {
+		ts.Lock()
+		defer ts.Unlock()
+		close(ts.quit)
+		for tw := ts.tw; tw != nil; tw = tw.nextTimeWheel {
+			tw.Stop()
+		}
+		atomic.AddInt64(&pendingTimers, -atomic.SwapInt64(&ts.pending, 0))
+	})
+}
+
+func (ts *TimerScheduler) stopped() bool {
+	select {
+	case <-ts.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// callObserved 执行延迟函数并记录其耗时 (calls df and records the time it takes)
+func callObserved(df *DelayFunc) {
+	if !zmetrics.Enabled() {
+		df.Call()
+		return
+	}
+	start := time.Now()
+	df.Call()
+	zmetrics.TimerCallbackDuration.With().Observe(time.Since(start).Seconds())
+}
+
 // NewAutoExecTimerScheduler 时间轮定时器 自动调度
 func NewAutoExecTimerScheduler() *TimerScheduler {
+	return NewAutoExecTimerSchedulerWithConfig(DefaultSchedulerConfig())
+}
+
+// NewAutoExecTimerSchedulerWithConfig 按config创建自动调度的时间轮定时器 (an auto executing scheduler with the wheels of config)
+func NewAutoExecTimerSchedulerWithConfig(config SchedulerConfig) *TimerScheduler {
 	//创建一个调度器
-	autoExecScheduler := NewTimerScheduler()
+	autoExecScheduler := NewTimerSchedulerWithConfig(config)
 	//启动调度器
 	autoExecScheduler.Start()
 
 	//永久从调度器中获取超时 触发的函数 并执行
 	go func() {
 		delayFuncChan := autoExecScheduler.GetTriggerChan()
-		for df := range delayFuncChan {
-			go df.Call()
+		for {
+			select {
+			case df := <-delayFuncChan:
+				go callObserved(df)
+			case <-autoExecScheduler.quit:
+				return
+			}
 		}
 	}()
 
@@ -45,6 +45,9 @@ type TimeWheel struct {
 	nextTimeWheel *TimeWheel
 	//互斥锁（继承RWMutex的 RWLock,UnLock 等方法）
 	sync.RWMutex
+	//停止转动的信号 (closed to stop turning)
+	quit     chan struct{}
+	stopOnce sync.Once
 }
 
 // NewTimeWheel  创建一个时间轮
@@ -60,6 +63,7 @@ func NewTimeWheel(name string, interval int64, scales int, maxCap int) *TimeWhee
 		scales:     scales,
 		maxCap:     maxCap,
 		timerQueue: make(map[int]map[uint32]*Timer, scales),
+		quit:       make(chan struct{}),
 	}
 	//初始化map
 	for i := 0; i < scales; i++ {
@@ -93,7 +97,7 @@ func (tw *TimeWheel) addTimer(tID uint32, t *Timer, forceNext bool) error {
 	}()
 
 	//得到当前的超时时间间隔(ms)毫秒为单位
-	delayInterval := t.unixts - UnixMilli()
+	delayInterval := t.deadline - monoMilli()
 
 	//如果当前的超时时间 大于一个刻度的时间间隔
 	if delayInterval >= tw.interval {
@@ -139,14 +143,22 @@ func (tw *TimeWheel) AddTimer(tID uint32, t *Timer) error {
 
 // RemoveTimer 删除一个定时器，根据定时器的ID
 func (tw *TimeWheel) RemoveTimer(tID uint32) {
+	tw.removeTimer(tID)
+}
+
+// removeTimer 删除一个定时器，返回定时器是否存在 (removes a timer, it returns whether it was there)
+func (tw *TimeWheel) removeTimer(tID uint32) bool {
 	tw.Lock()
 	defer tw.Unlock()
 
+	removed := false
 	for i := 0; i < tw.scales; i++ {
 		if _, ok := tw.timerQueue[i][tID]; ok {
 			delete(tw.timerQueue[i], tID)
+			removed = true
 		}
 	}
+	return removed
 }
 
 // AddTimeWheel 给一个时间轮添加下层时间轮 比如给小时时间轮添加分钟时间轮，给分钟时间轮添加秒时间轮
@@ -161,7 +173,9 @@ func (tw *TimeWheel) AddTimeWheel(next *TimeWheel) {
 func (tw *TimeWheel) run() {
 	for {
 		//时间轮每间隔interval一刻度时间，触发转动一次
-		time.Sleep(time.Duration(tw.interval) * time.Millisecond)
+		if !sleepUntil(time.Duration(tw.interval)*time.Millisecond, tw.quit) {
+			return
+		}
 
 		tw.Lock()
 		//取出挂载在当前刻度的全部定时器
@@ -187,6 +201,11 @@ func (tw *TimeWheel) run() {
 	}
 }
 
+// Stop 停止时间轮转动，可以重复调用 (stops turning the wheel, it may be called more than once)
+func (tw *TimeWheel) Stop() {
+	tw.stopOnce.Do(func() { close(tw.quit) })
+}
+
 // Run 非阻塞的方式让时间轮转起来
 func (tw *TimeWheel) Run() {
 	go tw.run()
@@ -207,11 +226,11 @@ func (tw *TimeWheel) GetTimerWithIn(duration time.Duration) map[uint32]*Timer {
 	//返回的Timer集合
 	timerList := make(map[uint32]*Timer)
 
-	now := UnixMilli()
+	now := monoMilli()
 
 	//取出当前时间轮刻度内全部Timer
 	for tID, timer := range leaftw.timerQueue[leaftw.curIndex] {
-		if timer.unixts-now < int64(duration/1e6) {
+		if timer.deadline-now < int64(duration/1e6) {
 			//当前定时器已经超时
 			timerList[tID] = timer
 			//定时器已经超时被取走，从当前时间轮上 摘除该定时器
@@ -0,0 +1,69 @@
+package ztls
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	ocspRetry   = 5 * time.Minute // Retry of a failed fetch (获取失败后的重试间隔)
+	ocspRefresh = time.Hour       // Refresh of a response without NextUpdate (没有NextUpdate的响应的刷新间隔)
+	ocspMaxBody = 1 << 20
+)
+
+// fetchOCSP returns the OCSP response of cert and when to fetch it again, a certificate without a responder or
+// issuer gets no staple (返回证书的OCSP响应以及再次获取的时间，没有OCSP服务或签发者的证书不装订)
+func fetchOCSP(client *http.Client, cert *tls.Certificate) ([]byte, time.Time, error) {
+	leaf := cert.Leaf
+	if len(leaf.OCSPServer) == 0 || len(cert.Certificate) < 2 {
+		return nil, leaf.NotAfter, nil
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	resp, err := client.Post(leaf.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("ocsp responder %s: %s", leaf.OCSPServer[0], resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, ocspMaxBody))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	switch parsed.Status {
+	case ocsp.Good:
+	case ocsp.Revoked:
+		return nil, time.Time{}, ErrRevoked
+	default:
+		return nil, time.Time{}, errors.New("ocsp status unknown")
+	}
+
+	// Refreshed half way through the validity of the response (在响应有效期过半时刷新)
+	next := time.Now().Add(ocspRefresh)
+	if !parsed.NextUpdate.IsZero() {
+		next = parsed.ThisUpdate.Add(parsed.NextUpdate.Sub(parsed.ThisUpdate) / 2)
+	}
+	return raw, next, nil
+}
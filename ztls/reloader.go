@@ -0,0 +1,176 @@
+// Package ztls keeps the TLS certificates of long running servers fresh: the certificate files are loaded again
+// when they change and the OCSP responses of the certificates are stapled to the handshakes.
+// (保持长期运行服务的TLS证书有效：证书文件变化时重新加载，并将证书的OCSP响应装订到握手中)
+package ztls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// Option configures a CertReloader (CertReloader的配置项)
+type Option func(r *CertReloader)
+
+// WithReloadInterval checks the files for changes at most every d, 30s by default
+// (最多每隔d检查一次文件是否变化，默认为30秒)
+func WithReloadInterval(d time.Duration) Option {
+	return func(r *CertReloader) {
+		r.interval = d
+	}
+}
+
+// WithOCSPStapling staples the OCSP response of the certificate, fetched from its responder with client, nil uses
+// a client with a 10s timeout (装订证书的OCSP响应，通过client从其OCSP服务获取，为nil时使用超时10秒的客户端)
+func WithOCSPStapling(client *http.Client) Option {
+	return func(r *CertReloader) {
+		if client == nil {
+			client = &http.Client{Timeout: 10 * time.Second}
+		}
+		r.ocspClient = client
+	}
+}
+
+// CertReloader serves the certificate of certFile and keyFile through tls.Config.GetCertificate, the files are
+// loaded again when they change, a broken pair keeps the previous certificate
+// (通过tls.Config.GetCertificate提供certFile与keyFile的证书，文件变化时重新加载，无效的文件保留之前的证书)
+type CertReloader struct {
+	certFile, keyFile string
+	interval          time.Duration
+	ocspClient        *http.Client
+
+	cert atomic.Value // *tls.Certificate
+
+	mu         sync.Mutex
+	modTime    time.Time
+	checked    time.Time
+	nextStaple time.Time
+	stapling   bool
+}
+
+// NewCertReloader loads certFile and keyFile (加载certFile与keyFile)
+func NewCertReloader(certFile, keyFile string, opts ...Option) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, interval: 30 * time.Second}
+	for _, opt := range opts {
+		opt(r)
+	}
+	modTime, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	r.checked = time.Now()
+	r.mu.Lock()
+	r.stapleLocked(r.checked)
+	r.mu.Unlock()
+	return r, nil
+}
+
+// stat returns the latest modification time of the files (返回文件的最新修改时间)
+func (r *CertReloader) stat() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *CertReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+	r.cert.Store(&cert)
+	r.modTime = modTime
+	r.nextStaple = time.Time{}
+	return nil
+}
+
+// Certificate returns the current certificate (返回当前证书)
+func (r *CertReloader) Certificate() *tls.Certificate {
+	return r.cert.Load().(*tls.Certificate)
+}
+
+// GetCertificate is the tls.Config.GetCertificate serving the current certificate (提供当前证书的tls.Config.GetCertificate)
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.check(time.Now())
+	return r.Certificate(), nil
+}
+
+// check loads the files again if they changed and refreshes the staple when it is due, at most once per interval
+// (文件变化时重新加载，并在到期时刷新装订的OCSP响应，每个间隔最多一次)
+func (r *CertReloader) check(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Sub(r.checked) < r.interval {
+		return
+	}
+	r.checked = now
+
+	modTime, err := r.stat()
+	switch {
+	case err != nil:
+		zlog.Ins().ErrorF("ztls: stat %s err: %v", r.certFile, err)
+	case !modTime.Equal(r.modTime):
+		// A pair written half way fails to load, it is tried again on the next check (写到一半的文件加载失败，下次检查时重试)
+		if err := r.load(modTime); err != nil {
+			zlog.Ins().ErrorF("ztls: reload %s err: %v, keeping the previous certificate", r.certFile, err)
+		} else {
+			zlog.Ins().InfoF("ztls: reloaded %s, expires at %v", r.certFile, r.Certificate().Leaf.NotAfter)
+		}
+	}
+	r.stapleLocked(now)
+}
+
+// stapleLocked fetches the OCSP response in the background when it is due, r.mu is held
+// (到期时在后台获取OCSP响应，调用时持有r.mu)
+func (r *CertReloader) stapleLocked(now time.Time) {
+	if r.ocspClient == nil || r.stapling || now.Before(r.nextStaple) {
+		return
+	}
+	r.stapling = true
+	cert := r.Certificate()
+	go func() {
+		staple, next, err := fetchOCSP(r.ocspClient, cert)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.stapling = false
+		if err != nil {
+			zlog.Ins().ErrorF("ztls: ocsp for %s err: %v", r.certFile, err)
+			next = time.Now().Add(ocspRetry)
+		}
+		// The certificate may have been reloaded meanwhile (期间证书可能已被重新加载)
+		if r.Certificate() != cert {
+			return
+		}
+		r.nextStaple = next
+		if staple != nil {
+			stapled := *cert
+			stapled.OCSPStaple = staple
+			r.cert.Store(&stapled)
+		}
+	}()
+}
+
+// ErrRevoked is returned for a certificate its OCSP responder reports revoked (OCSP服务报告证书已吊销时返回的错误)
+var ErrRevoked = errors.New("ztls: certificate revoked")
@@ -0,0 +1,160 @@
+package ztls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "zinx test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// writeLeaf writes a certificate with serial signed by the CA, followed by the CA, and its key
+// (写入由CA签发、序列号为serial的证书及CA证书，以及其私钥)
+func (ca *testCA) writeLeaf(t *testing.T, certFile, keyFile string, serial int64, ocspURL string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ocspURL != "" {
+		tmpl.OCSPServer = []string{ocspURL}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	assert.Nil(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})...)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(certFile, certPEM, 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+}
+
+// ocspResponder answers every request with status for the certificate asked (对每个请求以status应答所询问的证书)
+func (ca *testCA) ocspResponder(t *testing.T, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if !assert.Nil(t, err) {
+			return
+		}
+		resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, ca.key)
+		assert.Nil(t, err)
+		_, _ = w.Write(resp)
+	}))
+}
+
+func TestCertReloaderReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	ca.writeLeaf(t, certFile, keyFile, 10, "")
+
+	r, err := NewCertReloader(certFile, keyFile, WithReloadInterval(0))
+	assert.Nil(t, err)
+	cert, err := r.GetCertificate(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), cert.Leaf.SerialNumber.Int64())
+
+	// A rotated certificate is served on the next handshake (轮换后的证书在下一次握手时生效)
+	ca.writeLeaf(t, certFile, keyFile, 11, "")
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, later, later))
+	cert, _ = r.GetCertificate(nil)
+	assert.Equal(t, int64(11), cert.Leaf.SerialNumber.Int64())
+
+	// A broken file keeps the previous certificate (无效的文件保留之前的证书)
+	assert.Nil(t, os.WriteFile(certFile, []byte("broken"), 0600))
+	later = later.Add(time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, later, later))
+	cert, _ = r.GetCertificate(nil)
+	assert.Equal(t, int64(11), cert.Leaf.SerialNumber.Int64())
+
+	_, err = NewCertReloader(filepath.Join(dir, "missing.crt"), keyFile)
+	assert.NotNil(t, err)
+}
+
+func TestCertReloaderOCSPStapling(t *testing.T) {
+	ca := newTestCA(t)
+	responder := ca.ocspResponder(t, ocsp.Good)
+	defer responder.Close()
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	ca.writeLeaf(t, certFile, keyFile, 20, responder.URL)
+
+	r, err := NewCertReloader(certFile, keyFile, WithOCSPStapling(responder.Client()))
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		cert, _ := r.GetCertificate(nil)
+		return cert.OCSPStaple != nil
+	}, time.Second, 10*time.Millisecond)
+
+	resp, err := ocsp.ParseResponse(r.Certificate().OCSPStaple, ca.cert)
+	assert.Nil(t, err)
+	assert.Equal(t, ocsp.Good, resp.Status)
+	assert.Equal(t, int64(20), resp.SerialNumber.Int64())
+}
+
+func TestFetchOCSPRevoked(t *testing.T) {
+	ca := newTestCA(t)
+	responder := ca.ocspResponder(t, ocsp.Revoked)
+	defer responder.Close()
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	ca.writeLeaf(t, certFile, keyFile, 30, responder.URL)
+
+	r, err := NewCertReloader(certFile, keyFile)
+	assert.Nil(t, err)
+	staple, _, err := fetchOCSP(responder.Client(), r.Certificate())
+	assert.Equal(t, ErrRevoked, err)
+	assert.Nil(t, staple)
+}
@@ -0,0 +1,216 @@
+package ztrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// Names of the exporters built by NewExporter (NewExporter可构建的导出器名称)
+const (
+	ExporterLog  = "log"
+	ExporterOTLP = "otlp"
+)
+
+// NewExporter builds the exporter name, ExporterLog or ExporterOTLP sending to endpoint,
+// serviceName is reported as the service.name resource attribute
+// (构建名为name的导出器，ExporterLog或发送到endpoint的ExporterOTLP，serviceName作为service.name资源属性上报)
+func NewExporter(name, endpoint, serviceName string) (SpanExporter, error) {
+	switch name {
+	case ExporterLog:
+		return NewLogExporter(), nil
+	case ExporterOTLP:
+		if endpoint == "" {
+			return nil, fmt.Errorf("ztrace: the %s exporter needs an endpoint", name)
+		}
+		return NewOTLPExporter(endpoint, serviceName), nil
+	}
+	return nil, fmt.Errorf("ztrace: unknown exporter %q", name)
+}
+
+// logExporter writes the spans to zlog, it suits development (将span写入zlog，适合开发环境)
+type logExporter struct{}
+
+// NewLogExporter returns an exporter writing every span as a log line (返回将每个span写为一行日志的导出器)
+func NewLogExporter() SpanExporter {
+	return logExporter{}
+}
+
+func (logExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	for _, span := range spans {
+		fields := []zlog.Field{
+			zlog.F(zlog.FieldTraceID, span.TraceID),
+			zlog.F("spanID", span.SpanID),
+		}
+		if span.ParentSpanID != "" {
+			fields = append(fields, zlog.F("parentSpanID", span.ParentSpanID))
+		}
+		for _, attr := range span.Attributes {
+			fields = append(fields, zlog.F(attr.Key, attr.Value))
+		}
+		if span.Err != "" {
+			fields = append(fields, zlog.F("err", span.Err))
+		}
+		zlog.Ins().InfoFX(zlog.WithFields(ctx, fields...), "span %s took %s", span.Name, span.Duration())
+	}
+	return nil
+}
+
+func (logExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// otlpExporter posts the spans to an OTLP/HTTP receiver, such as the OpenTelemetry collector, in the JSON encoding
+// (以JSON编码将span发送到OTLP/HTTP接收端，例如OpenTelemetry collector)
+type otlpExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter returns an exporter posting the spans to endpoint/v1/traces, e.g. "http://localhost:4318"
+// (返回将span发送到endpoint/v1/traces的导出器，例如"http://localhost:4318")
+func NewOTLPExporter(endpoint, serviceName string) SpanExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	return &otlpExporter{
+		url:         url,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(otlpRequest(e.serviceName, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s responded %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+func (e *otlpExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// The OTLP/JSON messages, see opentelemetry/proto/collector/trace/v1 (OTLP/JSON消息)
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              SpanKind       `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+// otlpStatusError is STATUS_CODE_ERROR (即STATUS_CODE_ERROR)
+const otlpStatusError = 2
+
+func otlpRequest(serviceName string, spans []*Span) otlpTraces {
+	converted := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+		}
+		for _, attr := range span.Attributes {
+			s.Attributes = append(s.Attributes, otlpAttr(attr.Key, attr.Value))
+		}
+		if span.Err != "" {
+			s.Status = &otlpStatus{Code: otlpStatusError, Message: span.Err}
+		}
+		converted = append(converted, s)
+	}
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{otlpAttr("service.name", serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/aceld/zinx"}, Spans: converted}},
+	}}}
+}
+
+// otlpAttr converts value into an AnyValue, 64-bit integers are strings in OTLP/JSON
+// (将value转换为AnyValue，OTLP/JSON中64位整数为字符串)
+func otlpAttr(key string, value interface{}) otlpKeyValue {
+	var v map[string]interface{}
+	switch x := value.(type) {
+	case string:
+		v = map[string]interface{}{"stringValue": x}
+	case bool:
+		v = map[string]interface{}{"boolValue": x}
+	case int:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(int64(x), 10)}
+	case int32:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(int64(x), 10)}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(x, 10)}
+	case uint32:
+		v = map[string]interface{}{"intValue": strconv.FormatUint(uint64(x), 10)}
+	case uint64:
+		v = map[string]interface{}{"intValue": strconv.FormatUint(x, 10)}
+	case float32:
+		v = map[string]interface{}{"doubleValue": float64(x)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": x}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(x)}
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
@@ -0,0 +1,208 @@
+package ztrace
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aceld/zinx/zlog"
+)
+
+// SpanExporter sends the ended spans to a tracing backend, ExportSpans is called by one goroutine at a time
+// with batches of spans (将结束的span发送到追踪后端，ExportSpans每次只被一个协程调用，参数为一批span)
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []*Span) error
+	// Shutdown flushes and releases the exporter (刷新并释放导出器)
+	Shutdown(ctx context.Context) error
+}
+
+const (
+	queueSize     = 2048
+	maxBatchSize  = 512
+	batchInterval = time.Second
+)
+
+// batcher queues the ended spans and exports them in batches on its own goroutine, spans are dropped
+// when the queue is full so that tracing never blocks the message path
+// (batcher排队结束的span并在自己的协程中批量导出，队列满时丢弃span，追踪不会阻塞消息处理)
+type batcher struct {
+	exporter SpanExporter
+	queue    chan *Span
+	flush    chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	dropped  uint64
+}
+
+var (
+	provider struct {
+		sync.Mutex
+		batcher *batcher
+	}
+	// current is read on the message path without locking (消息处理路径上无锁读取)
+	current atomic.Value // *batcher
+
+	// sampleBound is the ratio of sampled traces scaled to the uint64 range (按uint64范围缩放的采样比例)
+	sampleBound uint64 = math.MaxUint64
+)
+
+// SetExporter starts tracing with exporter, replacing and shutting down the previous one, nil stops tracing
+// (使用exporter开启追踪，替换并关闭之前的导出器，nil关闭追踪)
+func SetExporter(exporter SpanExporter) {
+	provider.Lock()
+	defer provider.Unlock()
+	if old := provider.batcher; old != nil {
+		old.shutdown(context.Background())
+	}
+	provider.batcher = nil
+	if exporter != nil {
+		provider.batcher = newBatcher(exporter)
+	}
+	current.Store(provider.batcher)
+}
+
+// Enabled reports whether an exporter is set (返回是否设置了导出器)
+func Enabled() bool {
+	b, _ := current.Load().(*batcher)
+	return b != nil
+}
+
+// SetSampleRatio sets the ratio of the traces recorded, from 0 to 1, the decision only depends on the trace ID
+// so that every span of a trace is recorded or none
+// (设置被记录的链路比例，取值0到1，只由追踪ID决定，因此一条链路的span要么全部记录要么都不记录)
+func SetSampleRatio(ratio float64) {
+	var bound uint64
+	switch {
+	case ratio >= 1:
+		bound = math.MaxUint64
+	case ratio > 0:
+		bound = uint64(ratio * math.MaxUint64)
+	}
+	atomic.StoreUint64(&sampleBound, bound)
+}
+
+func sampled(traceID string) bool {
+	bound := atomic.LoadUint64(&sampleBound)
+	if bound == math.MaxUint64 {
+		return true
+	}
+	var b [8]byte
+	if len(traceID) < 16 {
+		return false
+	}
+	if _, err := hex.Decode(b[:], []byte(traceID[len(traceID)-16:])); err != nil {
+		return false
+	}
+	return binary.BigEndian.Uint64(b[:]) < bound
+}
+
+// Flush exports the spans ended so far (导出目前已结束的span)
+func Flush() {
+	if b, _ := current.Load().(*batcher); b != nil {
+		ack := make(chan struct{})
+		select {
+		case b.flush <- ack:
+			<-ack
+		case <-b.done:
+		}
+	}
+}
+
+// Shutdown exports the queued spans, shuts the exporter down and stops tracing
+// (导出排队的span，关闭导出器并停止追踪)
+func Shutdown(ctx context.Context) {
+	provider.Lock()
+	defer provider.Unlock()
+	if b := provider.batcher; b != nil {
+		b.shutdown(ctx)
+		provider.batcher = nil
+		current.Store(provider.batcher)
+	}
+}
+
+func export(span *Span) {
+	b, _ := current.Load().(*batcher)
+	if b == nil {
+		return
+	}
+	select {
+	case b.queue <- span:
+	default:
+		if atomic.AddUint64(&b.dropped, 1)%queueSize == 1 {
+			zlog.Ins().ErrorF("ztrace: span queue is full, %d spans dropped", atomic.LoadUint64(&b.dropped))
+		}
+	}
+}
+
+func newBatcher(exporter SpanExporter) *batcher {
+	b := &batcher{
+		exporter: exporter,
+		queue:    make(chan *Span, queueSize),
+		flush:    make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+func (b *batcher) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, maxBatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := b.exporter.ExportSpans(context.Background(), batch); err != nil {
+			zlog.Ins().ErrorF("ztrace: export %d spans err: %v", len(batch), err)
+		}
+		batch = make([]*Span, 0, maxBatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-b.queue:
+				batch = append(batch, span)
+				if len(batch) == maxBatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-b.queue:
+			batch = append(batch, span)
+			if len(batch) == maxBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-b.flush:
+			drain()
+			close(ack)
+		case <-b.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (b *batcher) shutdown(ctx context.Context) {
+	close(b.stop)
+	<-b.done
+	if err := b.exporter.Shutdown(ctx); err != nil {
+		zlog.Ins().ErrorF("ztrace: shutdown exporter err: %v", err)
+	}
+}
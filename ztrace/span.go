@@ -0,0 +1,176 @@
+// Package ztrace records the spans of the messages going through zinx: accept, decode, dispatch, handle and send,
+// and hands them to a pluggable SpanExporter, such as the OTLP exporter understood by OpenTelemetry collectors.
+// The trace ID of a span is the one carried by the datapack header (zinx_trace), or one generated by the server.
+// (ztrace记录经过zinx的消息的span：接入、解码、分发、处理和发送，并交给可插拔的SpanExporter，
+// 例如OpenTelemetry collector可以接收的OTLP导出器。span的追踪ID来自数据包头部(zinx_trace)，或由服务端生成)
+package ztrace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aceld/zinx/zutils"
+)
+
+// SpanKind is the role of a span, the values are the ones of OTLP (span的角色，取值与OTLP一致)
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+	SpanKindProducer SpanKind = 4
+	SpanKindConsumer SpanKind = 5
+)
+
+// Attribute is a key-value pair describing a span (描述span的键值对)
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr returns the Attribute key=value (返回key=value的Attribute)
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a timed operation of a trace, a nil *Span is valid and records nothing, which is what Start returns
+// when tracing is off or the trace is not sampled
+// (Span是链路中一段计时的操作，nil *Span也是有效的，不做任何记录，追踪关闭或链路未被采样时Start返回nil)
+type Span struct {
+	TraceID      string // 32 hex characters (32个十六进制字符)
+	SpanID       string // 16 hex characters (16个十六进制字符)
+	ParentSpanID string // Empty for the root span (根span为空)
+	Name         string
+	Kind         SpanKind
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   []Attribute
+	Err          string // The error which failed the operation, empty when it succeeded (导致操作失败的错误，成功时为空)
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// SetAttributes adds attrs to the span (为span添加属性)
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Attributes = append(s.Attributes, attrs...)
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed by err, a nil err does nothing (将span标记为因err失败，err为nil时不做任何事)
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.Err = err.Error()
+	s.mu.Unlock()
+}
+
+// End ends the span and queues it for the exporter, only the first call counts
+// (结束span并排队等待导出，只有第一次调用有效)
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+	export(s)
+}
+
+// Duration returns how long the span lasted (返回span的持续时间)
+func (s *Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+func (s *Span) String() string {
+	return fmt.Sprintf("%s trace=%s span=%s parent=%s", s.Name, s.TraceID, s.SpanID, s.ParentSpanID)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span, the spans started from it become its children
+// (返回携带span的ctx副本，由它开始的span成为其子span)
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil (返回ctx携带的span，没有时返回nil)
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start starts the span name as a child of the span carried by ctx, or as the root of a new trace,
+// and returns a copy of ctx carrying it, handlers use it to trace their own work:
+//
+//	ctx, span := ztrace.Start(request.GetContext(), "load user", ztrace.SpanKindInternal)
+//	defer span.End()
+//
+// (以ctx携带的span为父span开始名为name的span，没有时作为新链路的根，返回携带它的ctx副本，处理函数可以用它追踪自己的工作)
+func Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	if parent := SpanFromContext(ctx); parent != nil {
+		span := newSpan(parent.TraceID, parent.SpanID, name, kind, attrs)
+		return ContextWithSpan(ctx, span), span
+	}
+	return StartWithTraceID(ctx, "", name, kind, attrs...)
+}
+
+// StartWithTraceID starts the span name in the trace traceID, received from the peer, e.g. in the datapack header,
+// a new trace is started when traceID is empty
+// (在从对端收到(例如数据包头部)的链路traceID中开始名为name的span，traceID为空时开始新的链路)
+func StartWithTraceID(ctx context.Context, traceID string, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	if traceID == "" {
+		traceID = zutils.NewTraceID()
+	}
+	if !sampled(traceID) {
+		return ctx, nil
+	}
+	span := newSpan(traceID, "", name, kind, attrs)
+	return ContextWithSpan(ctx, span), span
+}
+
+func newSpan(traceID, parentSpanID, name string, kind SpanKind, attrs []Attribute) *Span {
+	return &Span{
+		TraceID:      traceID,
+		SpanID:       newSpanID(),
+		ParentSpanID: parentSpanID,
+		Name:         name,
+		Kind:         kind,
+		StartTime:    time.Now(),
+		Attributes:   attrs,
+	}
+}
+
+func newSpanID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,108 @@
+package ztrace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *recordExporter) ExportSpans(_ context.Context, spans []*Span) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *recordExporter) Shutdown(context.Context) error { return nil }
+
+func TestStart(t *testing.T) {
+	_, span := Start(context.Background(), "off", SpanKindInternal)
+	assert.Nil(t, span)
+	span.SetAttributes(Attr("k", 1))
+	span.End()
+
+	exporter := &recordExporter{}
+	SetExporter(exporter)
+	defer Shutdown(context.Background())
+
+	traceID := "0af7651916cd43dd8448eb211c80319c"
+	ctx, root := StartWithTraceID(context.Background(), traceID, "root", SpanKindServer, Attr("zinx.conn_id", uint64(1)))
+	_, child := Start(ctx, "child", SpanKindInternal)
+	child.SetError(errors.New("boom"))
+	child.End()
+	child.End()
+	root.End()
+	Flush()
+
+	assert.Len(t, exporter.spans, 2)
+	assert.Equal(t, traceID, child.TraceID)
+	assert.Equal(t, root.SpanID, child.ParentSpanID)
+	assert.Equal(t, "", root.ParentSpanID)
+	assert.Equal(t, "boom", child.Err)
+	assert.Len(t, root.SpanID, 16)
+
+	_, generated := Start(context.Background(), "new", SpanKindInternal)
+	assert.Len(t, generated.TraceID, 32)
+}
+
+func TestSampleRatio(t *testing.T) {
+	SetExporter(&recordExporter{})
+	defer Shutdown(context.Background())
+	defer SetSampleRatio(1)
+
+	SetSampleRatio(0)
+	_, span := StartWithTraceID(nil, "0af7651916cd43dd8448eb211c80319c", "none", SpanKindInternal)
+	assert.Nil(t, span)
+
+	SetSampleRatio(0.5)
+	_, low := StartWithTraceID(nil, "0af7651916cd43dd0000000000000001", "low", SpanKindInternal)
+	_, high := StartWithTraceID(nil, "0af7651916cd43ddffffffffffffffff", "high", SpanKindInternal)
+	assert.NotNil(t, low)
+	assert.Nil(t, high)
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &body)
+	}))
+	defer srv.Close()
+
+	exporter, err := NewExporter(ExporterOTLP, srv.URL, "game")
+	assert.Nil(t, err)
+	span := newSpan("0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", "zinx.handle", SpanKindInternal,
+		[]Attribute{Attr("zinx.msg_id", uint32(7))})
+	span.Err = "timeout"
+	assert.Nil(t, exporter.ExportSpans(context.Background(), []*Span{span}))
+	assert.Equal(t, "/v1/traces", path)
+
+	rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	service := rs["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "service.name", service["key"])
+	assert.Equal(t, "game", service["value"].(map[string]interface{})["stringValue"])
+	got := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "zinx.handle", got["name"])
+	assert.Equal(t, "b7ad6b7169203331", got["parentSpanId"])
+	assert.Equal(t, "7", got["attributes"].([]interface{})[0].(map[string]interface{})["value"].(map[string]interface{})["intValue"])
+	assert.Equal(t, float64(2), got["status"].(map[string]interface{})["code"])
+
+	_, err = NewExporter(ExporterOTLP, "", "game")
+	assert.NotNil(t, err)
+	_, err = NewExporter("jaeger", "", "game")
+	assert.NotNil(t, err)
+}
@@ -272,6 +272,24 @@ func (slm ShardLockMaps) IterCb(fn IterCb) {
 	}
 }
 
+// IterCbSnapshot is IterCb without holding any lock while fn runs: each shard is copied under RLock and fn is
+// called on the copy, so a slow fn, such as a broadcast, does not block Set and Remove.
+// fn may see an element that was removed meanwhile, or miss one that was added.
+func (slm ShardLockMaps) IterCbSnapshot(fn IterCb) {
+	var items []Tuple
+	for _, shard := range slm.shards {
+		items = items[:0]
+		shard.RLock()
+		for key, value := range shard.items {
+			items = append(items, Tuple{key, value})
+		}
+		shard.RUnlock()
+		for _, item := range items {
+			fn(item.Key, item.Val)
+		}
+	}
+}
+
 // MarshalJSON Reviles ConcurrentMap "private" variables to json marshal.
 func (slm ShardLockMaps) MarshalJSON() ([]byte, error) {
 	tmp := make(map[string]interface{})
@@ -294,6 +294,30 @@ func TestIterCb(t *testing.T) {
 	}
 }
 
+func TestIterCbSnapshot(t *testing.T) {
+	slm := NewShardLockMaps()
+
+	for i := 0; i < 100; i++ {
+		slm.Set(strconv.Itoa(i), TestUser{strconv.Itoa(i)})
+	}
+
+	// No lock is held while the callback runs, so it can change the map
+	counter := 0
+	slm.IterCbSnapshot(func(key string, v interface{}) {
+		if _, ok := v.(TestUser); !ok {
+			t.Error("Expecting an user object")
+		}
+		slm.Remove(key)
+		counter++
+	})
+	if counter != 100 {
+		t.Error("We should have counted 100 elements.")
+	}
+	if slm.Count() != 0 {
+		t.Error("should have 0 elements.")
+	}
+}
+
 func TestItems(t *testing.T) {
 	slm := NewShardLockMaps()
 
@@ -0,0 +1,54 @@
+package zutils
+
+import "strings"
+
+// TopicSeparator separates the levels of a topic, e.g. "device/1001/telemetry"
+// (主题层级分隔符)
+const TopicSeparator = "/"
+
+// MatchTopic reports whether topic matches pattern.
+// "+" matches exactly one level, "*" or "#" as the last level matches all remaining levels (at least one).
+// (判断topic是否匹配pattern，"+"匹配单个层级，末尾的"*"或"#"匹配剩余的所有层级)
+//
+//	MatchTopic("device/+/telemetry", "device/1001/telemetry") == true
+//	MatchTopic("rpc/*", "rpc/user/login") == true
+func MatchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	return MatchTopicLevels(SplitTopic(pattern), SplitTopic(topic))
+}
+
+// SplitTopic splits a topic or a pattern into its levels, so that they are matched many times without splitting
+// again, see MatchTopicLevels (将主题或模式拆分为层级，多次匹配时无需重复拆分，见MatchTopicLevels)
+func SplitTopic(topic string) []string {
+	return strings.Split(topic, TopicSeparator)
+}
+
+// MatchTopicLevels is MatchTopic on the levels returned by SplitTopic (对SplitTopic返回的层级执行MatchTopic)
+func MatchTopicLevels(ps, ts []string) bool {
+	for i, p := range ps {
+		if (p == "*" || p == "#") && i == len(ps)-1 {
+			return len(ts) > i
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if p != "+" && p != ts[i] {
+			return false
+		}
+	}
+
+	return len(ps) == len(ts)
+}
+
+// IsTopicPattern reports whether the topic contains wildcards
+// (判断主题中是否包含通配符)
+func IsTopicPattern(topic string) bool {
+	for _, level := range strings.Split(topic, TopicSeparator) {
+		if level == "+" || level == "*" || level == "#" {
+			return true
+		}
+	}
+	return false
+}
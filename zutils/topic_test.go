@@ -0,0 +1,21 @@
+package zutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchTopic(t *testing.T) {
+	assert.True(t, MatchTopic("device/+/telemetry", "device/1001/telemetry"))
+	assert.False(t, MatchTopic("device/+/telemetry", "device/1001/status"))
+	assert.False(t, MatchTopic("device/+/telemetry", "device/1001/telemetry/extra"))
+	assert.True(t, MatchTopic("rpc/*", "rpc/user/login"))
+	assert.True(t, MatchTopic("rpc/#", "rpc/user"))
+	assert.False(t, MatchTopic("rpc/*", "rpc"))
+	assert.True(t, MatchTopic("chat/room", "chat/room"))
+	assert.False(t, MatchTopic("chat/room", "chat/room2"))
+
+	assert.True(t, IsTopicPattern("device/+/telemetry"))
+	assert.False(t, IsTopicPattern("chat/room"))
+}
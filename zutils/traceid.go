@@ -0,0 +1,49 @@
+package zutils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+// TraceIDLen is the length of a binary trace ID, the same as a W3C trace-id
+// (二进制链路追踪ID的长度，与W3C trace-id一致)
+const TraceIDLen = 16
+
+// NewTraceID generates a random trace ID as 32 lowercase hex characters
+// (生成一个随机的链路追踪ID，32个小写十六进制字符)
+func NewTraceID() string {
+	var b [TraceIDLen]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// TraceIDToBytes converts a hex trace ID into its binary form, an empty ID becomes all zeros
+// (将十六进制的链路追踪ID转换为二进制形式，空ID转换为全0)
+func TraceIDToBytes(traceID string, dst []byte) error {
+	if len(dst) < TraceIDLen {
+		return errors.New("trace id buffer too short")
+	}
+	if traceID == "" {
+		for i := 0; i < TraceIDLen; i++ {
+			dst[i] = 0
+		}
+		return nil
+	}
+	if len(traceID) != TraceIDLen*2 {
+		return errors.New("invalid trace id: " + traceID)
+	}
+	_, err := hex.Decode(dst[:TraceIDLen], []byte(traceID))
+	return err
+}
+
+// TraceIDFromBytes converts a binary trace ID into hex, all zeros means absent and returns ""
+// (将二进制的链路追踪ID转换为十六进制，全0表示不存在，返回空字符串)
+func TraceIDFromBytes(b []byte) string {
+	for _, v := range b[:TraceIDLen] {
+		if v != 0 {
+			return hex.EncodeToString(b[:TraceIDLen])
+		}
+	}
+	return ""
+}
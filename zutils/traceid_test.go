@@ -0,0 +1,45 @@
+package zutils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTraceID(t *testing.T) {
+	traceID := NewTraceID()
+	assert.Len(t, traceID, TraceIDLen*2)
+	_, err := hex.DecodeString(traceID)
+	assert.Nil(t, err)
+	assert.NotEqual(t, traceID, NewTraceID())
+}
+
+func TestTraceIDBytes(t *testing.T) {
+	traceID := "0af7651916cd43dd8448eb211c80319c"
+	buf := make([]byte, TraceIDLen+2)
+	assert.Nil(t, TraceIDToBytes(traceID, buf))
+	assert.Equal(t, traceID, TraceIDFromBytes(buf))
+	// Only the first TraceIDLen bytes are used (只使用前TraceIDLen个字节)
+	assert.Equal(t, []byte{0, 0}, buf[TraceIDLen:])
+
+	// An empty trace ID clears the buffer and reads back as absent (空ID清零缓冲，读回时表示不存在)
+	assert.Nil(t, TraceIDToBytes("", buf))
+	assert.Equal(t, make([]byte, TraceIDLen), buf[:TraceIDLen])
+	assert.Equal(t, "", TraceIDFromBytes(buf))
+
+	// The last byte alone is enough to be present (仅最后一个字节非0也表示存在)
+	buf[TraceIDLen-1] = 1
+	assert.Equal(t, "00000000000000000000000000000001", TraceIDFromBytes(buf))
+}
+
+func TestTraceIDToBytesErrors(t *testing.T) {
+	dst := bytes.Repeat([]byte{0xff}, TraceIDLen)
+
+	assert.EqualError(t, TraceIDToBytes("0af7651916cd43dd8448eb211c80319c", make([]byte, TraceIDLen-1)), "trace id buffer too short")
+	assert.EqualError(t, TraceIDToBytes("", nil), "trace id buffer too short")
+	assert.EqualError(t, TraceIDToBytes("0af76519", dst), "invalid trace id: 0af76519")
+	assert.EqualError(t, TraceIDToBytes("0af7651916cd43dd8448eb211c80319c00", dst), "invalid trace id: 0af7651916cd43dd8448eb211c80319c00")
+	assert.NotNil(t, TraceIDToBytes("zzf7651916cd43dd8448eb211c80319c", dst))
+}
@@ -4,11 +4,13 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -20,23 +22,33 @@ const (
 	defMaxSize = 64 //MiB
 )
 
+// Compression of the rotated log files (切割后旧日志文件的压缩方式)
+const (
+	CompressZip  = "zip"  // app.<time>.zip, the default (默认)
+	CompressGzip = "gzip" // app.<time>.log.gz
+	CompressNone = "none" // app.<time>.log
+)
+
 var _ io.WriteCloser = (*Writer)(nil)
 
 type Writer struct {
-	maxAge    int       // 最大保留天数
-	maxSize   int64     // 单个日志最大容量 默认 64MB
-	size      int64     // 累计大小
-	fpath     string    // 文件目录 完整路径 fpath=fdir+fname+fsuffix
-	fdir      string    //
-	fname     string    // 文件名
-	fsuffix   string    // 文件后缀名 默认 .log
-	zipsuffix string    // 文件后缀名 默认 .log
-	created   time.Time // 文件创建日期
-	creates   []byte    // 文件创建日期
-	cons      bool      // 标准输出  默认 false
-	file      *os.File
-	bw        *bufio.Writer
-	mu        sync.Mutex
+	maxAge     int           // 最大保留天数
+	maxSize    int64         // 单个日志最大容量 默认 64MB
+	maxBackups int           // 最多保留的旧日志文件数 0表示不限制
+	compress   string        // 旧日志压缩方式 默认 zip
+	interval   time.Duration // 按时间切割的间隔 0表示按天切割
+	size       int64         // 累计大小
+	fpath      string        // 文件目录 完整路径 fpath=fdir+fname+fsuffix
+	fdir       string        //
+	fname      string        // 文件名
+	fsuffix    string        // 文件后缀名 默认 .log
+	zipsuffix  string        // 文件后缀名 默认 .log
+	created    time.Time     // 文件创建日期
+	creates    []byte        // 文件创建日期
+	cons       bool          // 标准输出  默认 false
+	file       *os.File
+	bw         *bufio.Writer
+	mu         sync.Mutex
 }
 
 func New(path string) *Writer {
@@ -55,6 +67,7 @@ func New(path string) *Writer {
 	}
 	w.maxSize = sizeMiB * defMaxSize
 	w.maxAge = defMaxAge
+	w.compress = CompressZip
 	os.MkdirAll(filepath.Dir(w.fpath), 0755)
 	go w.daemon()
 	return w
@@ -82,6 +95,36 @@ func (w *Writer) SetMaxSize(ms int64) {
 	w.mu.Unlock()
 }
 
+// SetMaxBackups 最多保留的旧日志文件数，0表示不限制
+func (w *Writer) SetMaxBackups(n int) {
+	if n < 0 {
+		return
+	}
+	w.mu.Lock()
+	w.maxBackups = n
+	w.mu.Unlock()
+}
+
+// SetCompress 旧日志的压缩方式 CompressZip、CompressGzip或CompressNone
+func (w *Writer) SetCompress(c string) {
+	if c != CompressZip && c != CompressGzip && c != CompressNone {
+		return
+	}
+	w.mu.Lock()
+	w.compress = c
+	w.mu.Unlock()
+}
+
+// SetRotateInterval 按时间切割的间隔，如time.Hour按小时切割，0表示按天切割
+func (w *Writer) SetRotateInterval(d time.Duration) {
+	if d < 0 {
+		return
+	}
+	w.mu.Lock()
+	w.interval = d
+	w.mu.Unlock()
+}
+
 // SetCons 同时输出控制台
 func (w *Writer) SetCons(b bool) {
 	w.mu.Lock()
@@ -103,11 +146,9 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 	}
 
 	t := time.Now()
-	b := t.AppendFormat(nil, time.RFC3339)
 
-	// 按天切割
-	if !bytes.Equal(w.creates[:10], b[:10]) { //2023-04-05
-		go w.delete() // 每天检测一次旧文件
+	// 按时间切割
+	if w.expired(t) {
 		if err := w.rotate(); err != nil {
 			return 0, err
 		}
@@ -127,6 +168,15 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 	return
 }
 
+// expired 当前文件是否已跨过切割时间点
+func (w *Writer) expired(t time.Time) bool {
+	if w.interval <= 0 {
+		b := t.AppendFormat(nil, time.RFC3339)
+		return !bytes.Equal(w.creates[:10], b[:10]) //2023-04-05
+	}
+	return !t.Truncate(w.interval).Equal(w.created.Truncate(w.interval))
+}
+
 // rotate 切割文件
 func (w *Writer) rotate() error {
 	now := time.Now()
@@ -139,15 +189,24 @@ func (w *Writer) rotate() error {
 		fbakname := fbak + w.fsuffix
 		err := os.Rename(w.fpath, filepath.Join(w.fdir, fbakname))
 		if err == nil {
-			err1 := ZipToFile(filepath.Join(w.fdir, fbak+".zip"), filepath.Join(w.fdir, fbakname))
+			var err1 error
+			switch w.compress {
+			case CompressZip:
+				err1 = ZipToFile(filepath.Join(w.fdir, fbak+w.zipsuffix), filepath.Join(w.fdir, fbakname))
+			case CompressGzip:
+				err1 = GzipToFile(filepath.Join(w.fdir, fbakname+".gz"), filepath.Join(w.fdir, fbakname))
+			}
 			if err1 == nil {
-				os.Remove(filepath.Join(w.fdir, fbakname))
+				if w.compress != CompressNone {
+					os.Remove(filepath.Join(w.fdir, fbakname))
+				}
 			} else {
-				fmt.Println(err1)
+				fmt.Fprintln(os.Stderr, err1)
 			}
 		}
 
 		w.size = 0
+		go w.delete(w.maxAge, w.maxBackups) // 每次切割后检测旧文件
 	}
 	finfo, err := os.Stat(w.fpath)
 	w.created = now
@@ -165,17 +224,22 @@ func (w *Writer) rotate() error {
 	return nil
 }
 
-// 删除旧日志
-func (w *Writer) delete() {
-	if w.maxAge <= 0 {
+// 删除超过maxAge天或超出maxBackups个数的旧日志
+func (w *Writer) delete(maxAge, maxBackups int) {
+	if maxAge <= 0 && maxBackups <= 0 {
 		return
 	}
 	dir := filepath.Dir(w.fpath)
-	fakeNow := time.Now().AddDate(0, 0, -w.maxAge)
+	fakeNow := time.Now().AddDate(0, 0, -maxAge)
 	dirs, err := os.ReadDir(dir)
 	if err != nil {
 		return
 	}
+	type backup struct {
+		name string
+		t    time.Time
+	}
+	var backups []backup
 	for _, path := range dirs {
 		name := path.Name()
 		if path.IsDir() {
@@ -183,15 +247,30 @@ func (w *Writer) delete() {
 		}
 		t, err := w.name2time(name)
 		// 只删除满足格式的文件
-		if err == nil && t.Before(fakeNow) {
+		if err != nil {
+			continue
+		}
+		if maxAge > 0 && t.Before(fakeNow) {
 			os.Remove(filepath.Join(dir, name))
+			continue
 		}
+		backups = append(backups, backup{name: name, t: t})
+	}
+	if maxBackups <= 0 || len(backups) <= maxBackups {
+		return
+	}
+	// 从最旧的开始删除
+	sort.Slice(backups, func(i, j int) bool { return backups[i].t.Before(backups[j].t) })
+	for _, b := range backups[:len(backups)-maxBackups] {
+		os.Remove(filepath.Join(dir, b.name))
 	}
 }
 
 func (w *Writer) name2time(name string) (time.Time, error) {
 	name = strings.TrimPrefix(name, filepath.Base(w.fname))
 	name = strings.TrimSuffix(name, w.zipsuffix)
+	name = strings.TrimSuffix(name, ".gz")
+	name = strings.TrimSuffix(name, w.fsuffix)
 	// 改为微秒级别的文件后缀，避免1s内大量写入造成多次rotate，而覆盖丢失之前的日志文件
 	return time.Parse(".2006-01-02-150405.000000", name)
 }
@@ -242,6 +321,31 @@ func ZipToFile(dst, src string) error {
 	return Zip(fw, src)
 }
 
+// GzipToFile gzip压缩单个文件
+// @params dst string 压缩文件目标路径
+// @params src string 待压缩源文件路径
+// @return     error  错误信息
+func GzipToFile(dst, src string) error {
+	sfr, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer sfr.Close()
+
+	fw, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	zw := gzip.NewWriter(fw)
+	zw.Name = filepath.Base(src)
+	if _, err = io.Copy(zw, sfr); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
 // Zip 压缩文件或目录
 // @params dst io.Writer 压缩文件可写流
 // @params src string    待压缩源文件/目录路径
@@ -0,0 +1,63 @@
+package zutils
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterRotate(t *testing.T) {
+	dir := t.TempDir()
+	w := New(filepath.Join(dir, "app.log"))
+	w.SetMaxSize(10)
+	w.SetCompress(CompressGzip)
+	w.SetMaxBackups(2)
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := w.Write([]byte("line 01\n"))
+		assert.Nil(t, err)
+		time.Sleep(time.Millisecond)
+	}
+
+	var backups []string
+	assert.Eventually(t, func() bool {
+		entries, _ := os.ReadDir(dir)
+		backups = backups[:0]
+		for _, e := range entries {
+			if strings.HasSuffix(e.Name(), ".log.gz") {
+				backups = append(backups, e.Name())
+			}
+		}
+		return len(backups) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	f, err := os.Open(filepath.Join(dir, backups[0]))
+	assert.Nil(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	assert.Nil(t, err)
+	data, err := io.ReadAll(zr)
+	assert.Nil(t, err)
+	assert.Equal(t, "line 01\n", string(data))
+}
+
+func TestWriterRotateInterval(t *testing.T) {
+	w := New(filepath.Join(t.TempDir(), "app.log"))
+	defer w.Close()
+	w.created = time.Date(2023, 4, 5, 10, 30, 0, 0, time.Local)
+	w.creates = w.created.AppendFormat(nil, time.RFC3339)
+
+	assert.False(t, w.expired(w.created.Add(time.Hour)))
+	assert.True(t, w.expired(w.created.Add(14*time.Hour)))
+
+	w.SetRotateInterval(time.Hour)
+	assert.False(t, w.expired(w.created.Add(20*time.Minute)))
+	assert.True(t, w.expired(w.created.Add(40*time.Minute)))
+}